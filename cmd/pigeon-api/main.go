@@ -0,0 +1,133 @@
+// Command pigeon-api serves the API. The config is read in layers, each overriding the ones
+// before: the defaults, the file of the -config flag, the server config secret, the environment
+// and the -set flags. The secret is read if there is no file, or if SERVER_CONFIG_SECRET is set.
+// The config is validated before the server starts.
+// On SIGINT or SIGTERM it drains the requests in flight, stops the scheduled jobs, relays the
+// outbox and flushes the NSQ producer before it exits.
+//
+// With -dev it runs without cloud credentials: the secret is not read, and the cloud
+// dependencies are replaced with the local stand-ins of package dev, writing to -dev-dir.
+// The ID tokens are issued by POST /dev/token for the uid or email of a user.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/di"
+	"github.com/VinothKuppanna/pigeon-go/internal/dev"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
+)
+
+const serviceName = "pigeon-api"
+
+func main() {
+	configFile := flag.String("config", "", "path of the YAML config file")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "time given to the shutdown before the exit")
+	devMode := flag.Bool("dev", false, "run with local stand-ins of the cloud dependencies")
+	devDir := flag.String("dev-dir", ".dev", "directory of the files written in dev mode")
+	var overrides configs.Overrides
+	flag.Var(&overrides, "set", "config value as key=value, e.g. smtp.port=587 (repeatable)")
+	flag.Parse()
+
+	loader := &configs.Loader{File: *configFile, Overrides: overrides}
+	if secret, set := configs.ServerConfigSecret(); !*devMode && (len(*configFile) == 0 || set) {
+		loader.Secret = secret
+	}
+	config, err := loader.Load()
+	if err != nil {
+		fatal("failed to read the config", err)
+	}
+	var dir string
+	if *devMode {
+		_ = config.Apply(dev.Source, func(config *configs.Config) error {
+			dev.Configure(config, *devDir)
+			return nil
+		})
+		dir = *devDir
+	}
+	if err = config.Validate(); err != nil {
+		fatal("invalid config", err)
+	}
+	level, _ := logger.ParseLevel(config.Logging.Level) // validated
+	logger.SetDefault(logger.New(os.Stdout, level))
+	logger.Default().Info(context.Background(), "config", "config", config.String())
+
+	if err := run(config, *shutdownTimeout, dir); err != nil {
+		fatal("the server failed", err)
+	}
+}
+
+// fatal logs the error and exits.
+func fatal(message string, err error) {
+	logger.Default().Error(context.Background(), message, "error", err)
+	os.Exit(1)
+}
+
+// run serves the API until it is interrupted. The dev profile is used if devDir is set.
+func run(config *configs.Config, shutdownTimeout time.Duration, devDir string) error {
+	var env *dev.Environment
+	if len(devDir) > 0 {
+		var err error
+		if env, err = dev.NewEnvironment(devDir); err != nil {
+			return fmt.Errorf("failed to set up the dev environment: %w", err)
+		}
+		defer env.Close()
+		// before tracing wraps it, so the clients built on it reach the stand-ins too
+		http.DefaultTransport = env.Transport
+	}
+
+	shutdownTracing, err := tracing.Setup(config.Tracing, serviceName)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var server *di.Server
+	var cleanup func()
+	if env != nil {
+		server, cleanup, err = di.InitDevServer(context.Background(), config, env)
+	} else {
+		server, cleanup, err = di.InitServer(context.Background(), config)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to build the server: %w", err)
+	}
+	defer cleanup()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+		server.Loggers.Info.Println("shutting down")
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil {
+		server.Loggers.Errors.Printf("failed to shut down gracefully. error: %v\n", shutdownErr)
+	}
+	if tracingErr := shutdownTracing(shutdownCtx); tracingErr != nil {
+		server.Loggers.Errors.Printf("failed to flush the spans. error: %v\n", tracingErr)
+	}
+	if err != nil {
+		return fmt.Errorf("server failed: %w", err)
+	}
+	server.Loggers.Info.Println("stopped")
+	return nil
+}
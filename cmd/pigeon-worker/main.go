@@ -0,0 +1,98 @@
+// Command pigeon-worker consumes the NSQ topics published by the API: the log entries of
+// api_requests and emails_requests, and the sign-ups of signup_event. It reads the config as
+// pigeon-api does, and connects to the nsqlookupd of worker.nsqLookupdAddresses, or to the
+// nsqd of nsqdAddress if there are none.
+// The failed messages are retried with a backoff, and moved to the <topic>.dead_letter topic
+// after worker.maxAttempts attempts.
+// On SIGINT or SIGTERM it stops receiving messages, finishes those in flight and flushes the
+// NSQ producer before it exits.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/di"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
+)
+
+const serviceName = "pigeon-worker"
+
+func main() {
+	configFile := flag.String("config", "", "path of the YAML config file")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "time given to the shutdown before the exit")
+	var overrides configs.Overrides
+	flag.Var(&overrides, "set", "config value as key=value, e.g. worker.concurrency=8 (repeatable)")
+	flag.Parse()
+
+	loader := &configs.Loader{File: *configFile, Overrides: overrides}
+	if secret, set := configs.ServerConfigSecret(); len(*configFile) == 0 || set {
+		loader.Secret = secret
+	}
+	config, err := loader.Load()
+	if err != nil {
+		fatal("failed to read the config", err)
+	}
+	if err = config.Validate(); err != nil {
+		fatal("invalid config", err)
+	}
+	level, _ := logger.ParseLevel(config.Logging.Level) // validated
+	logger.SetDefault(logger.New(os.Stdout, level))
+	logger.Default().Info(context.Background(), "config", "config", config.String())
+
+	if err := run(config, *shutdownTimeout); err != nil {
+		fatal("the worker failed", err)
+	}
+}
+
+// fatal logs the error and exits.
+func fatal(message string, err error) {
+	logger.Default().Error(context.Background(), message, "error", err)
+	os.Exit(1)
+}
+
+// run consumes the topics until it is interrupted.
+func run(config *configs.Config, shutdownTimeout time.Duration) error {
+	shutdownTracing, err := tracing.Setup(config.Tracing, serviceName)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	worker, cleanup, err := di.InitWorker(context.Background(), config)
+	if err != nil {
+		return fmt.Errorf("failed to build the worker: %w", err)
+	}
+	defer cleanup()
+
+	if err = worker.Start(); err != nil {
+		err = fmt.Errorf("failed to connect: %w", err)
+	} else {
+		<-ctx.Done()
+		worker.Loggers.Info.Println("shutting down")
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if shutdownErr := worker.Shutdown(shutdownCtx); shutdownErr != nil {
+		worker.Loggers.Errors.Printf("failed to shut down gracefully. error: %v\n", shutdownErr)
+	}
+	if tracingErr := shutdownTracing(shutdownCtx); tracingErr != nil {
+		worker.Loggers.Errors.Printf("failed to flush the spans. error: %v\n", tracingErr)
+	}
+	if err != nil {
+		return err
+	}
+	worker.Loggers.Info.Println("stopped")
+	return nil
+}
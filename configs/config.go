@@ -3,14 +3,11 @@ package configs
 import (
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"context"
-	"fmt"
-	"github.com/joeshaw/envdecode"
-	"github.com/kelseyhightower/envconfig"
 	secretspb "google.golang.org/genproto/googleapis/cloud/secretmanager/v1"
-	"gopkg.in/yaml.v2"
 	"html/template"
-	"log"
+	"io/ioutil"
 	"os"
+	"time"
 )
 
 const (
@@ -38,6 +35,7 @@ type DynamicTemplates struct {
 	Invitation             string `yaml:"invitation"`
 	NewBusiness            string `yaml:"newBusiness"`
 	VerifyEmail            string `yaml:"verifyEmail"`
+	SLAEscalation          string `yaml:"slaEscalation"`
 }
 
 type EmailFrom struct {
@@ -46,27 +44,32 @@ type EmailFrom struct {
 }
 
 type SendGrid struct {
-	APIKey    string            `yaml:"apiKey" env:"SENDGRID_API_KEY"`
+	APIKey    string            `yaml:"apiKey" env:"SENDGRID_API_KEY" secret:"true"`
 	Host      string            `yaml:"host"`
 	Endpoints SendGridEndpoints `yaml:"endpoints"`
 	Templates DynamicTemplates  `yaml:"templates"`
 	From      EmailFrom         `yaml:"from"`
-	Enabled   bool              `yaml:"enabled"`
+	Enabled   bool              `yaml:"enabled" env:"SENDGRID_ENABLED"`
 }
 
 type Hubspot struct {
-	ClientID     string `yaml:"clientId"`
-	ClientSecret string `yaml:"clientSecret"`
-	RedirectURI  string `yaml:"redirectUri"`
-	RefreshToken string `yaml:"refreshToken"`
-	Enabled      bool   `yaml:"enabled"`
+	ClientID     string `yaml:"clientId" env:"HUBSPOT_CLIENT_ID"`
+	ClientSecret string `yaml:"clientSecret" env:"HUBSPOT_CLIENT_SECRET" secret:"true"`
+	RedirectURI  string `yaml:"redirectUri" env:"HUBSPOT_REDIRECT_URI"`
+	RefreshToken string `yaml:"refreshToken" env:"HUBSPOT_REFRESH_TOKEN" secret:"true"`
+	Enabled      bool   `yaml:"enabled" env:"HUBSPOT_ENABLED"`
+	ApiURL       string `yaml:"apiUrl" env:"HUBSPOT_API_URL,default=https://api.hubapi.com"`
+	// the custom company property of the category of the business, not synced if empty
+	CategoryProperty string `yaml:"categoryProperty" env:"HUBSPOT_CATEGORY_PROPERTY,default=business_category"`
+	// of the watch of the businesses, which only the worker holding it runs
+	LeaseTTL time.Duration `yaml:"leaseTtl" env:"HUBSPOT_LEASE_TTL,default=30s"`
 }
 
 type Vonage struct {
-	AppID              string `yaml:"appId"`
-	ApiKey             string `yaml:"apiKey"`
-	ApiSecret          string `yaml:"apiSecret"`
-	SignatureSecret    string `yaml:"signatureSecret"`
+	AppID              string `yaml:"appId" env:"VONAGE_APP_ID"`
+	ApiKey             string `yaml:"apiKey" env:"VONAGE_API_KEY"`
+	ApiSecret          string `yaml:"apiSecret" env:"VONAGE_API_SECRET" secret:"true"`
+	SignatureSecret    string `yaml:"signatureSecret" env:"VONAGE_SIGNATURE_SECRET" secret:"true"`
 	BrandName          string `yaml:"brandName"`
 	FromNumber         string `yaml:"fromNumber"`
 	FromNumberTollFree string `yaml:"fromNumberTollFree"`
@@ -74,7 +77,7 @@ type Vonage struct {
 
 type Opentok struct {
 	ApiKey    string `yaml:"apiKey" env:"OPENTOK_API_KEY"`
-	ApiSecret string `yaml:"apiSecret" env:"OPENTOK_API_SECRET"`
+	ApiSecret string `yaml:"apiSecret" env:"OPENTOK_API_SECRET" secret:"true"`
 }
 
 type ActionCodeSettings struct {
@@ -93,23 +96,72 @@ type URLPrefixes struct {
 	ShareBusiness string `yaml:"shareBusiness"`
 }
 
+type RateLimit struct {
+	Store string `yaml:"store" env:"RATE_LIMIT_STORE,default=memory"` // memory, or firestore backing the memory buckets
+	// TrustedProxies is the number of proxies appending to X-Forwarded-For, e.g. 2 behind the Google load balancer.
+	TrustedProxies int `yaml:"trustedProxies" env:"RATE_LIMIT_TRUSTED_PROXIES,default=1"`
+}
+
+type Idempotency struct {
+	Store string        `yaml:"store" env:"IDEMPOTENCY_STORE,default=memory"` // memory or firestore
+	TTL   time.Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL,default=24h"`
+}
+
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER,default=none"` // none, stdout or otlp (built with -tags otlp)
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT"`              // of the OTLP/HTTP collector, e.g. http://otel-collector:4318
+	SampleRatio float64 `yaml:"sampleRatio" env:"TRACING_SAMPLE_RATIO,default=1"`
+}
+
+type Logging struct {
+	Level string `yaml:"level" env:"LOG_LEVEL,default=info"` // debug, info, warn or error
+}
+
+type Events struct {
+	// inprocess delivers the events to the subscribers of the API, nsq to those of pigeon-worker
+	Transport string `yaml:"transport" env:"EVENTS_TRANSPORT,default=nsq"`
+}
+
+// Outbox configures the relay publishing the entries of the outbox to NSQ. Only the instance
+// holding the lease relays.
+type Outbox struct {
+	Relay       bool          `yaml:"relay" env:"OUTBOX_RELAY,default=true"`
+	Interval    time.Duration `yaml:"interval" env:"OUTBOX_INTERVAL,default=1s"` // between the polls
+	BatchSize   int           `yaml:"batchSize" env:"OUTBOX_BATCH_SIZE,default=100"`
+	LeaseTTL    time.Duration `yaml:"leaseTtl" env:"OUTBOX_LEASE_TTL,default=30s"`
+	MaxAttempts int           `yaml:"maxAttempts" env:"OUTBOX_MAX_ATTEMPTS,default=10"` // of an entry while nsqd is reachable, then it is dead-lettered
+}
+
+// Worker configures the consumers of pigeon-worker.
+type Worker struct {
+	NsqLookupdAddresses []string      `yaml:"nsqLookupdAddresses" env:"NSQLOOKUPD_ADDRESSES"` // nsqdAddress is used if empty
+	Channel             string        `yaml:"channel" env:"WORKER_CHANNEL,default=pigeon-worker"`
+	Concurrency         int           `yaml:"concurrency" env:"WORKER_CONCURRENCY,default=4"` // per topic
+	MaxAttempts         int           `yaml:"maxAttempts" env:"WORKER_MAX_ATTEMPTS,default=5"`
+	Backoff             time.Duration `yaml:"backoff" env:"WORKER_BACKOFF,default=1s"` // doubled on each retry
+	MaxBackoff          time.Duration `yaml:"maxBackoff" env:"WORKER_MAX_BACKOFF,default=5m"`
+}
+
 type Config struct {
-	WebApiKey               string      `yaml:"webApiKey"`
+	WebApiKey               string      `yaml:"webApiKey" env:"WEB_API_KEY" secret:"true"`
 	DynamicLinksUrl         string      `yaml:"dlUrl"`
 	DynamicLinkDomain       string      `yaml:"dlDomain" env:"DL_DOMAIN"`
 	DynamicLinksURLPrefixes URLPrefixes `yaml:"dynamicLinksURLPrefixes"`
-	PlacesApiKey            string      `yaml:"placesApiKey" env:"PLACES_API_KEY"`
+	PlacesApiKey            string      `yaml:"placesApiKey" env:"PLACES_API_KEY" secret:"true"`
 	StorageBucket           string      `yaml:"storageBucket" env:"STORAGE_BUCKET"`
 	ArchiveTemplate         string      `yaml:"archiveTemplate" env:"ARCHIVE_TEMPLATE"`
+	ServiceAccountFile      string      `yaml:"serviceAccountFile" env:"SERVICE_ACCOUNT_FILE"` // read instead of the secret if set
 	//ServiceAccount  string `yaml:"serviceAccount" env:"SERVICE_ACCOUNT"`
 	Server struct {
 		Port int `yaml:"port" env:"SERVER_PORT,default=3030"`
+		// MetricsPort serves /metrics apart from the API, for the scrapers inside the network. 0 disables it.
+		MetricsPort int `yaml:"metricsPort" env:"SERVER_METRICS_PORT,default=9090"`
 	} `yaml:"server"`
 	Smtp struct {
 		Server       string `yaml:"server" env:"SMTP_SERVER"`
 		Port         int    `yaml:"port" env:"SMTP_PORT"`
 		Email        string `yaml:"email" env:"SMTP_EMAIL"`
-		Password     string `yaml:"password" env:"SMTP_PASSWORD"`
+		Password     string `yaml:"password" env:"SMTP_PASSWORD" secret:"true"`
 		Alias        string `yaml:"alias" env:"SMTP_ALIAS"`
 		Host         string `yaml:"host" env:"SMTP_HOST"`
 		SupportEmail string `yaml:"supportEmail" env:"SMTP_SUPPORT_EMAIL"`
@@ -122,99 +174,56 @@ type Config struct {
 	AlertEmails                []string           `yaml:"alertEmails"`
 	Hubspot                    Hubspot            `yaml:"hubspot"`
 	SendGrid                   SendGrid           `yaml:"sendgrid"`
+	RateLimit                  RateLimit          `yaml:"rateLimit"`
+	Idempotency                Idempotency        `yaml:"idempotency"`
+	Tracing                    Tracing            `yaml:"tracing"`
+	Worker                     Worker             `yaml:"worker"`
+	Events                     Events             `yaml:"events"`
+	Outbox                     Outbox             `yaml:"outbox"`
+	Logging                    Logging            `yaml:"logging"`
+
+	sources map[string]Source // by key, see Apply
 }
 
-func (c *Config) Read(configFile string) {
-	// YAML
-	f, err := os.Open(configFile)
-	if err != nil {
-		processError(err)
+func (c *Config) ReadServiceAccount() ([]byte, error) {
+	if len(c.ServiceAccountFile) > 0 {
+		return ioutil.ReadFile(c.ServiceAccountFile)
 	}
-	defer f.Close()
 
-	decoder := yaml.NewDecoder(f)
-	err = decoder.Decode(&c)
-	if err != nil {
-		processError(err)
+	if len(serviceAccountSecret) == 0 {
+		serviceAccountSecret = defaultServiceAccountSecret
 	}
-}
 
-func (c *Config) ReadEnv() {
-	err := envconfig.Process("", c)
-	if err != nil {
-		processError(err)
-	}
+	return accessSecret(serviceAccountSecret)
+}
 
-	err = envdecode.Decode(c)
-	if err != nil {
-		processError(err)
+// ServerConfigSecret is the name of the secret of the server config, from SERVER_CONFIG_SECRET.
+// It reports whether the variable is set, or the default is used.
+func ServerConfigSecret() (name string, set bool) {
+	if len(serverConfigSecret) == 0 {
+		return defaultServerConfigSecret, false
 	}
+	return serverConfigSecret, true
 }
 
-func (c *Config) ReadServiceAccount() ([]byte, error) {
+// accessSecret reads the latest payload of the secret from Secret Manager.
+func accessSecret(name string) ([]byte, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	if len(serviceAccountSecret) == 0 {
-		serviceAccountSecret = defaultServiceAccountSecret
-	}
-
 	client, err := secretmanager.NewClient(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer client.Close()
 
-	request := &secretspb.AccessSecretVersionRequest{
-		Name: serviceAccountSecret,
-	}
-
-	response, err := client.AccessSecretVersion(ctx, request)
+	response, err := client.AccessSecretVersion(ctx, &secretspb.AccessSecretVersionRequest{Name: name})
 	if err != nil {
 		return nil, err
 	}
-
 	return response.Payload.Data, nil
 }
 
-func (c *Config) ReadServerConfig() error {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	if len(serverConfigSecret) == 0 {
-		serverConfigSecret = defaultServerConfigSecret
-	}
-
-	client, err := secretmanager.NewClient(ctx)
-	if err != nil {
-		return err
-	}
-
-	request := &secretspb.AccessSecretVersionRequest{
-		Name: serverConfigSecret,
-	}
-
-	response, err := client.AccessSecretVersion(ctx, request)
-	if err != nil {
-		return err
-	}
-
-	err = yaml.Unmarshal(response.Payload.Data, c)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func processError(err error) {
-	log.Fatal(err)
-}
-
-func (c *Config) String() (s string) {
-	s = fmt.Sprintf("Alias:%s, Email:%s, Password:%s, Host:%s, Server:%s, Port:%d, StorageBucket:%s, PlacesAPI:%s, NSQD_ADDRESS:%s",
-		c.Smtp.Alias, c.Smtp.Email, c.Smtp.Password, c.Smtp.Host, c.Smtp.Server, c.Smtp.Port, c.StorageBucket, c.PlacesApiKey, c.NsqdAddress)
-	return
-}
-
 type EmailTemplates struct {
 	InviteUserTmpl             *template.Template
 	NewRequestAlertTmpl        *template.Template
@@ -225,6 +234,7 @@ type EmailTemplates struct {
 	ResetPasswordCustomerTmpl  *template.Template
 	NewBusinessAccountTmpl     *template.Template
 	VerifyEmailTmpl            *template.Template
+	SLAEscalationTmpl          *template.Template
 }
 
 func (et *EmailTemplates) Parse() (err error) {
@@ -264,5 +274,9 @@ func (et *EmailTemplates) Parse() (err error) {
 	if err != nil {
 		return
 	}
+	et.SLAEscalationTmpl, err = template.ParseFiles("./templates/sla_escalation.html")
+	if err != nil {
+		return
+	}
 	return
 }
@@ -0,0 +1,209 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	file := writeFile(t, `
+storageBucket: file-bucket
+placesApiKey: file-places
+smtp:
+  host: file-host
+  port: 25
+  password: file-password
+alertEmails: [a@example.com]
+`)
+	loader := &Loader{
+		File:   file,
+		Secret: "projects/p/secrets/s/versions/latest",
+		ReadSecret: func(name string) ([]byte, error) {
+			return []byte("smtp:\n  port: 465\n  password: secret-password\n"), nil
+		},
+		LookupEnv: env(map[string]string{
+			"SMTP_PORT":            "587",
+			"STORAGE_BUCKET":       "",
+			"IDEMPOTENCY_TTL":      "1h",
+			"TRACING_SAMPLE_RATIO": "0.5",
+		}),
+		Overrides: Overrides{"smtp.host=flag-host", "alertEmails=b@example.com, c@example.com"},
+	}
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Server.Port != 3030 || config.RateLimit.Store != "memory" {
+		t.Errorf("defaults not applied: port %d, rate limit store %q", config.Server.Port, config.RateLimit.Store)
+	}
+	if config.StorageBucket != "file-bucket" {
+		t.Errorf("empty variable overrode the file: %q", config.StorageBucket)
+	}
+	if config.Smtp.Password != "secret-password" {
+		t.Errorf("secret did not override the file: %q", config.Smtp.Password)
+	}
+	if config.Smtp.Port != 587 {
+		t.Errorf("env did not override the secret: %d", config.Smtp.Port)
+	}
+	if config.Smtp.Host != "flag-host" {
+		t.Errorf("flag did not override the file: %q", config.Smtp.Host)
+	}
+	if config.Idempotency.TTL != time.Hour || config.Tracing.SampleRatio != 0.5 {
+		t.Errorf("env not parsed: ttl %v, sample ratio %v", config.Idempotency.TTL, config.Tracing.SampleRatio)
+	}
+	if got := strings.Join(config.AlertEmails, ","); got != "b@example.com,c@example.com" {
+		t.Errorf("alert emails: %q", got)
+	}
+
+	sources := map[string]Source{}
+	for _, entry := range config.Effective() {
+		sources[entry.Key] = entry.Source
+	}
+	for key, want := range map[string]Source{
+		"server.port":      SourceDefault,
+		"storageBucket":    SourceFile,
+		"smtp.password":    SourceSecret,
+		"smtp.port":        SourceEnv,
+		"smtp.host":        SourceFlag,
+		"idempotency.ttl":  SourceEnv,
+		"smtp.alias":       "",
+		"alertEmails":      SourceFlag,
+		"placesApiKey":     SourceFile,
+		"tracing.exporter": SourceDefault,
+	} {
+		if sources[key] != want {
+			t.Errorf("source of %s: got %q, want %q", key, sources[key], want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		loader *Loader
+		want   string
+	}{
+		{"unknown flag key", &Loader{Overrides: Overrides{"smtp.nope=1"}, LookupEnv: env(nil)}, "unknown key smtp.nope"},
+		{"bad env value", &Loader{LookupEnv: env(map[string]string{"SMTP_PORT": "x"})}, "SMTP_PORT"},
+		{"missing file", &Loader{File: filepath.Join(t.TempDir(), "none.yaml"), LookupEnv: env(nil)}, "config file"},
+		{"secret error", &Loader{Secret: "s", LookupEnv: env(nil), ReadSecret: func(string) ([]byte, error) {
+			return nil, errors.New("denied")
+		}}, "config secret: denied"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.loader.Load()
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got %v, want an error with %q", err, test.want)
+			}
+		})
+	}
+}
+
+func validConfig(t *testing.T) *Config {
+	config, err := (&Loader{LookupEnv: env(nil)}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Vonage.AppID = "app"
+	config.Vonage.ApiKey = "key"
+	config.Vonage.ApiSecret = "secret"
+	config.Vonage.FromNumber = "15550000000"
+	return config
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig(t).Validate(); err != nil {
+		t.Fatalf("valid config: %v", err)
+	}
+
+	config := validConfig(t)
+	config.SendGrid.Enabled = true
+	config.SendGrid.APIKey = "key"
+	config.Hubspot.Enabled = true
+	config.Vonage.ApiSecret = ""
+	config.Tracing.Exporter = "jaeger"
+	config.Server.Port = 0
+	err := config.Validate()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got %v, want a *ValidationError", err)
+	}
+	want := []string{
+		"sendgrid is enabled, missing: sendgrid.host, sendgrid.endpoints.send, sendgrid.from.email",
+		"vonage is enabled, missing: vonage.apiSecret",
+		"hubspot is enabled, missing: hubspot.clientId, hubspot.clientSecret, hubspot.refreshToken",
+		"server.port: 0 is out of range",
+		`tracing.exporter: "jaeger" is not one of none, stdout, otlp`,
+	}
+	if got := strings.Join(validationErr.Problems, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestValidateOTLPEndpoint(t *testing.T) {
+	config := validConfig(t)
+	config.Tracing.Exporter = "otlp"
+	err := config.Validate()
+	if err == nil || !strings.Contains(err.Error(), "otlp tracing is enabled, missing: tracing.endpoint") {
+		t.Errorf("Validate() = %v, want the missing endpoint", err)
+	}
+	config.Tracing.Endpoint = "http://otel-collector:4318"
+	if err = config.Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+}
+
+func TestSecretsRedacted(t *testing.T) {
+	config := validConfig(t)
+	config.Smtp.Password = "smtp-password"
+	config.SendGrid.APIKey = "sendgrid-key"
+	config.Smtp.Email = "noreply@example.com"
+
+	for _, output := range []string{
+		config.String(),
+		fmt.Sprintf("%v", config),
+		fmt.Sprintf("%+v", config),
+		fmt.Sprintf("%#v", config),
+	} {
+		for _, secret := range []string{"smtp-password", "sendgrid-key", "secret"} {
+			if strings.Contains(output, secret) {
+				t.Errorf("%q leaked in %s", secret, output)
+			}
+		}
+		if !strings.Contains(output, "smtp.email:noreply@example.com") {
+			t.Errorf("missing non-secret value in %s", output)
+		}
+	}
+
+	for _, entry := range config.Effective() {
+		if entry.Key == "smtp.password" && (entry.Value != Redacted || !entry.Secret) {
+			t.Errorf("smtp.password entry: %+v", entry)
+		}
+		if entry.Key == "opentok.apiSecret" && entry.Value != "" {
+			t.Errorf("unset secret shown as %v", entry.Value)
+		}
+	}
+}
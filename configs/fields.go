@@ -0,0 +1,151 @@
+package configs
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Redacted replaces the values of the secret keys in the output of the config.
+const Redacted = "[REDACTED]"
+
+var typeOfDuration = reflect.TypeOf(time.Duration(0))
+
+// A field is a leaf value of the config. Its key is the dotted path of the YAML names,
+// e.g. smtp.password. The env tag names the variable and the default, as NAME,default=VALUE.
+type field struct {
+	key          string
+	value        reflect.Value
+	env          string
+	defaultValue string
+	hasDefault   bool
+	secret       bool
+}
+
+// fields lists the leaf values of the config in the order of declaration.
+func (c *Config) fields() []*field {
+	return walk(reflect.ValueOf(c).Elem(), "", nil)
+}
+
+func walk(v reflect.Value, prefix string, fields []*field) []*field {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if len(structField.PkgPath) > 0 { // unexported
+			continue
+		}
+		name := strings.Split(structField.Tag.Get("yaml"), ",")[0]
+		if len(name) == 0 || name == "-" {
+			continue
+		}
+		key := name
+		if len(prefix) > 0 {
+			key = prefix + "." + name
+		}
+		value := v.Field(i)
+		if structField.Type.Kind() == reflect.Struct {
+			fields = walk(value, key, fields)
+			continue
+		}
+		f := &field{key: key, value: value, secret: structField.Tag.Get("secret") == "true"}
+		if env, ok := structField.Tag.Lookup("env"); ok {
+			parts := strings.Split(env, ",")
+			f.env = parts[0]
+			for _, option := range parts[1:] {
+				if strings.HasPrefix(option, "default=") {
+					f.defaultValue = strings.TrimPrefix(option, "default=")
+					f.hasDefault = true
+				}
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// lookup finds the field of the key.
+func (c *Config) lookup(key string) (*field, bool) {
+	for _, f := range c.fields() {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// set parses s into the field. The lists are comma separated.
+func (f *field) set(s string) error {
+	v := f.value
+	switch {
+	case v.Type() == typeOfDuration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// isZero reports whether the field is not set.
+func (f *field) isZero() bool {
+	if f.value.Kind() == reflect.Slice {
+		return f.value.Len() == 0
+	}
+	return f.value.IsZero()
+}
+
+// String formats the value of the field, including the secrets.
+func (f *field) String() string {
+	if f.value.Kind() == reflect.Slice {
+		items := make([]string, f.value.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(f.value.Index(i).Interface())
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+// display is the value of the field to show, with the secrets redacted.
+func (f *field) display() interface{} {
+	if f.secret && !f.isZero() {
+		return Redacted
+	}
+	if f.value.Type() == typeOfDuration {
+		return f.String()
+	}
+	return f.value.Interface()
+}
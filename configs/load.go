@@ -0,0 +1,204 @@
+package configs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// A Source is the layer a config value comes from.
+type Source string
+
+// The layers of the config, from the lowest precedence to the highest.
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceSecret  Source = "secret"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Overrides are the values of a repeated flag, each as key=value, e.g. smtp.port=587.
+type Overrides []string
+
+func (o *Overrides) String() string {
+	return strings.Join(*o, " ")
+}
+
+func (o *Overrides) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("%q is not key=value", value)
+	}
+	*o = append(*o, value)
+	return nil
+}
+
+// A Loader reads the config in layers: the defaults of the env tags, then the YAML file,
+// the YAML of the secret, the environment and the overrides. Each layer keeps the values
+// it does not set.
+type Loader struct {
+	File       string // skipped if empty
+	Secret     string // skipped if empty
+	Overrides  Overrides
+	LookupEnv  func(key string) (string, bool)   // os.LookupEnv if nil
+	ReadSecret func(name string) ([]byte, error) // Secret Manager if nil
+}
+
+// Load reads the layers. It does not validate the config.
+func (l *Loader) Load() (*Config, error) {
+	lookupEnv := l.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	readSecret := l.ReadSecret
+	if readSecret == nil {
+		readSecret = accessSecret
+	}
+
+	c := &Config{}
+	if err := c.Apply(SourceDefault, (*Config).readDefaults); err != nil {
+		return nil, err
+	}
+	if len(l.File) > 0 {
+		err := c.Apply(SourceFile, func(c *Config) error {
+			data, err := ioutil.ReadFile(l.File)
+			if err != nil {
+				return err
+			}
+			return yaml.Unmarshal(data, c)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(l.Secret) > 0 {
+		err := c.Apply(SourceSecret, func(c *Config) error {
+			data, err := readSecret(l.Secret)
+			if err != nil {
+				return err
+			}
+			return yaml.Unmarshal(data, c)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := c.Apply(SourceEnv, func(c *Config) error {
+		return c.readEnv(lookupEnv)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = c.Apply(SourceFlag, l.Overrides.apply); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Apply runs the layer on the config, and records the source of the values it changed.
+// A value set again to the same value keeps its source.
+func (c *Config) Apply(source Source, layer func(c *Config) error) error {
+	before := c.snapshot()
+	if err := layer(c); err != nil {
+		return fmt.Errorf("config %s: %w", source, err)
+	}
+	if c.sources == nil {
+		c.sources = map[string]Source{}
+	}
+	for _, f := range c.fields() {
+		if f.String() != before[f.key] {
+			c.sources[f.key] = source
+		}
+	}
+	return nil
+}
+
+func (c *Config) snapshot() map[string]string {
+	values := map[string]string{}
+	for _, f := range c.fields() {
+		values[f.key] = f.String()
+	}
+	return values
+}
+
+func (c *Config) readDefaults() error {
+	for _, f := range c.fields() {
+		if !f.hasDefault {
+			continue
+		}
+		if err := f.set(f.defaultValue); err != nil {
+			return fmt.Errorf("%s: %w", f.key, err)
+		}
+	}
+	return nil
+}
+
+// readEnv sets the fields whose variable is set and not empty.
+func (c *Config) readEnv(lookupEnv func(key string) (string, bool)) error {
+	for _, f := range c.fields() {
+		if len(f.env) == 0 {
+			continue
+		}
+		value, ok := lookupEnv(f.env)
+		if !ok || len(value) == 0 {
+			continue
+		}
+		if err := f.set(value); err != nil {
+			return fmt.Errorf("%s: %w", f.env, err)
+		}
+	}
+	return nil
+}
+
+func (o Overrides) apply(c *Config) error {
+	for _, override := range o {
+		parts := strings.SplitN(override, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("%q is not key=value", override)
+		}
+		f, ok := c.lookup(parts[0])
+		if !ok {
+			return fmt.Errorf("unknown key %s", parts[0])
+		}
+		if err := f.set(parts[1]); err != nil {
+			return fmt.Errorf("%s: %w", f.key, err)
+		}
+	}
+	return nil
+}
+
+// An Entry is a value of the effective config.
+type Entry struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source Source      `json:"source,omitempty"` // empty if never set
+	Secret bool        `json:"secret,omitempty"`
+}
+
+// Effective lists the values of the config with their source. The secrets are redacted.
+func (c *Config) Effective() []Entry {
+	var entries []Entry
+	for _, f := range c.fields() {
+		entries = append(entries, Entry{Key: f.key, Value: f.display(), Source: c.sources[f.key], Secret: f.secret})
+	}
+	return entries
+}
+
+// String lists the values set, with the secrets redacted.
+func (c *Config) String() string {
+	var values []string
+	for _, f := range c.fields() {
+		if !f.isZero() {
+			values = append(values, fmt.Sprintf("%s:%v", f.key, f.display()))
+		}
+	}
+	return strings.Join(values, ", ")
+}
+
+// GoString keeps the secrets out of %#v too.
+func (c *Config) GoString() string {
+	return c.String()
+}
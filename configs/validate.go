@@ -0,0 +1,108 @@
+package configs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A ValidationError lists the problems of the config, one per line.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// feature lists the keys an enabled feature cannot run without.
+type feature struct {
+	name     string
+	enabled  func(c *Config) bool
+	required []string
+}
+
+var features = []feature{
+	{"sendgrid", func(c *Config) bool { return c.SendGrid.Enabled },
+		[]string{"sendgrid.apiKey", "sendgrid.host", "sendgrid.endpoints.send", "sendgrid.from.email"}},
+	// the SMS and the virtual numbers always use vonage
+	{"vonage", func(c *Config) bool { return true },
+		[]string{"vonage.appId", "vonage.apiKey", "vonage.apiSecret", "vonage.fromNumber"}},
+	{"hubspot", func(c *Config) bool { return c.Hubspot.Enabled },
+		[]string{"hubspot.clientId", "hubspot.clientSecret", "hubspot.refreshToken"}},
+	{"otlp tracing", func(c *Config) bool { return c.Tracing.Exporter == "otlp" },
+		[]string{"tracing.endpoint"}},
+}
+
+// Validate checks the keys of the enabled features are set and the values are in range.
+// It returns a *ValidationError listing all the problems.
+func (c *Config) Validate() error {
+	var problems []string
+	for _, feature := range features {
+		if !feature.enabled(c) {
+			continue
+		}
+		var missing []string
+		for _, key := range feature.required {
+			if f, ok := c.lookup(key); !ok || f.isZero() {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("%s is enabled, missing: %s", feature.name, strings.Join(missing, ", ")))
+		}
+	}
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("server.port: %d is out of range", c.Server.Port))
+	}
+	if c.Server.MetricsPort < 0 || c.Server.MetricsPort > 65535 || c.Server.MetricsPort == c.Server.Port {
+		problems = append(problems, fmt.Sprintf("server.metricsPort: %d is out of range or the port of the API", c.Server.MetricsPort))
+	}
+	problems = append(problems, oneOf("rateLimit.store", c.RateLimit.Store, "memory", "firestore")...)
+	problems = append(problems, oneOf("idempotency.store", c.Idempotency.Store, "memory", "firestore")...)
+	problems = append(problems, oneOf("tracing.exporter", c.Tracing.Exporter, "none", "stdout", "otlp")...)
+	problems = append(problems, oneOf("logging.level", c.Logging.Level, "debug", "info", "warn", "error")...)
+	problems = append(problems, oneOf("events.transport", c.Events.Transport, "inprocess", "nsq")...)
+	if c.RateLimit.TrustedProxies < 0 {
+		problems = append(problems, "rateLimit.trustedProxies: must not be negative")
+	}
+	if c.Idempotency.TTL <= 0 {
+		problems = append(problems, "idempotency.ttl: must be positive")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, fmt.Sprintf("tracing.sampleRatio: %v is not between 0 and 1", c.Tracing.SampleRatio))
+	}
+	if c.Worker.Concurrency <= 0 {
+		problems = append(problems, "worker.concurrency: must be positive")
+	}
+	if c.Worker.MaxAttempts <= 0 {
+		problems = append(problems, "worker.maxAttempts: must be positive")
+	}
+	if c.Worker.Backoff <= 0 || c.Worker.MaxBackoff < c.Worker.Backoff {
+		problems = append(problems, "worker.backoff: must be positive and at most worker.maxBackoff")
+	}
+	if c.Hubspot.LeaseTTL <= 0 {
+		problems = append(problems, "hubspot.leaseTtl: must be positive")
+	}
+	if c.Outbox.Interval <= 0 || c.Outbox.LeaseTTL <= c.Outbox.Interval {
+		problems = append(problems, "outbox.interval: must be positive and less than outbox.leaseTtl")
+	}
+	if c.Outbox.BatchSize <= 0 || c.Outbox.BatchSize > 500 {
+		problems = append(problems, fmt.Sprintf("outbox.batchSize: %d is not between 1 and 500", c.Outbox.BatchSize))
+	}
+	if c.Outbox.MaxAttempts <= 0 {
+		problems = append(problems, fmt.Sprintf("outbox.maxAttempts: %d is not positive", c.Outbox.MaxAttempts))
+	}
+	if len(problems) > 0 {
+		return &ValidationError{problems}
+	}
+	return nil
+}
+
+func oneOf(key string, value string, values ...string) []string {
+	for _, v := range values {
+		if value == v {
+			return nil
+		}
+	}
+	return []string{fmt.Sprintf("%s: %q is not one of %s", key, value, strings.Join(values, ", "))}
+}
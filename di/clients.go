@@ -0,0 +1,125 @@
+package di
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"cloud.google.com/go/firestore"
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"firebase.google.com/go/v4/messaging"
+	"firebase.google.com/go/v4/storage"
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/outbox"
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"github.com/VinothKuppanna/pigeon-go/internal/scheduler"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	opentok "github.com/VolodymyrPobochii/opentok-go/pkg"
+	"github.com/nsqio/go-nsq"
+	"github.com/vonage/vonage-go-sdk"
+	"google.golang.org/api/option"
+	"googlemaps.github.io/maps"
+)
+
+// Loggers are provided together, as wire tells the values apart by type. Info and Errors write
+// through the Logger, for the code logging with the log package.
+type Loggers struct {
+	Logger *logger.Logger
+	Info   *log.Logger
+	Errors *log.Logger
+}
+
+// NewLoggers uses the default logger, which the commands set up from the config.
+func NewLoggers() Loggers {
+	l := logger.Default()
+	return Loggers{
+		Logger: l,
+		Info:   l.Std(logger.LevelInfo),
+		Errors: l.Std(logger.LevelError),
+	}
+}
+
+// NewFirebaseApp uses the application default credentials if GOOGLE_APPLICATION_CREDENTIALS is set,
+// and the service account secret otherwise.
+func NewFirebaseApp(ctx context.Context, config *configs.Config) (*firebase.App, error) {
+	var options []option.ClientOption
+	if len(os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")) == 0 {
+		serviceAccount, err := config.ReadServiceAccount()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, option.WithCredentialsJSON(serviceAccount))
+	}
+	return firebase.NewApp(ctx, &firebase.Config{StorageBucket: config.StorageBucket}, options...)
+}
+
+func NewAuthClient(ctx context.Context, app *firebase.App) (*auth.Client, error) {
+	return app.Auth(ctx)
+}
+
+func NewFirestoreClient(ctx context.Context, app *firebase.App) (*firestore.Client, func(), error) {
+	client, err := app.Firestore(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, func() { _ = client.Close() }, nil
+}
+
+func NewFirestore(client *firestore.Client) *db.Firestore {
+	return &db.Firestore{Client: client}
+}
+
+func NewStorageClient(ctx context.Context, app *firebase.App) (*storage.Client, error) {
+	return app.Storage(ctx)
+}
+
+func NewMessagingClient(ctx context.Context, app *firebase.App) (*messaging.Client, error) {
+	return app.Messaging(ctx)
+}
+
+func NewMapsClient(config *configs.Config) (*maps.Client, error) {
+	return maps.NewClient(maps.WithAPIKey(config.PlacesApiKey))
+}
+
+func NewNumbersClient(config *configs.Config) *vonage.NumbersClient {
+	return vonage.NewNumbersClient(vonage.CreateAuthFromKeySecret(config.Vonage.ApiKey, config.Vonage.ApiSecret))
+}
+
+func NewSMSClient(config *configs.Config) *vonage.SMSClient {
+	return vonage.NewSMSClient(vonage.CreateAuthFromKeySecret(config.Vonage.ApiKey, config.Vonage.ApiSecret))
+}
+
+func NewOpenTok(config *configs.Config) *opentok.OpenTok {
+	return opentok.NewOpenTok(config.Opentok.ApiKey, config.Opentok.ApiSecret, nil)
+}
+
+// NewProducer does not connect to nsqd until the first message is published.
+func NewProducer(config *configs.Config) (*publishing.Producer, error) {
+	return publishing.New(config.NsqdAddress, nsq.NewConfig())
+}
+
+func NewOutbox(db *db.Firestore) *outbox.Outbox {
+	return outbox.New(db)
+}
+
+// NewRelay returns nil if the config does not run the relay of the outbox in this instance.
+func NewRelay(config *configs.Config, db *db.Firestore, producer *publishing.Producer, loggers Loggers) *outbox.Relay {
+	if !config.Outbox.Relay {
+		return nil
+	}
+	return outbox.NewRelay(db, producer, config.Outbox, loggers.Info, loggers.Errors)
+}
+
+func NewScheduler(loggers Loggers) scheduler.Scheduler {
+	return scheduler.New(loggers.Info)
+}
+
+func NewEmailTemplates() (*configs.EmailTemplates, error) {
+	templates := &configs.EmailTemplates{}
+	if err := templates.Parse(); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
@@ -0,0 +1,72 @@
+package di
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/dev"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/reset"
+	"github.com/VinothKuppanna/pigeon-go/internal/memfirestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/middleware/authenticator"
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/google/wire"
+	"github.com/gorilla/mux"
+	"google.golang.org/api/option"
+)
+
+// DevSet provides the stand-ins of the dev profile in place of CloudSet.
+var DevSet = wire.NewSet(
+	NewDevFirebaseApp,
+	NewDevFirestoreClient,
+	NewDevEmailsService,
+	NewDevEndpoints,
+	wire.FieldsOf(new(*dev.Environment), "Tokens", "Producer", "Push", "Links"),
+	wire.Bind(new(authenticator.TokenVerifier), new(*dev.Tokens)),
+	wire.Bind(new(Endpoint), new(*DevEndpoints)),
+)
+
+// NewDevFirebaseApp signs with the generated service account and sends the requests of the
+// Firebase clients to the stand-ins of env.
+func NewDevFirebaseApp(ctx context.Context, config *configs.Config, env *dev.Environment) (*firebase.App, error) {
+	return firebase.NewApp(ctx, &firebase.Config{ProjectID: dev.ProjectID, StorageBucket: config.StorageBucket},
+		option.WithCredentialsJSON(env.ServiceAccount),
+		option.WithHTTPClient(env.HTTPClient()))
+}
+
+// NewDevFirestoreClient serves Firestore from memory. The data is lost on shutdown.
+func NewDevFirestoreClient(ctx context.Context) (*firestore.Client, func(), error) {
+	return memfirestore.NewClient(ctx, dev.ProjectID)
+}
+
+// NewDevEmailsService writes the emails to the sink of env, and publishes the results as
+// NewEmailsService does.
+func NewDevEmailsService(env *dev.Environment, producer *publishing.Producer) definition.EmailsService {
+	return data.NewLoggingMiddleware(producer)(env.Emails)
+}
+
+// DevEndpoints are the endpoints of the API, the one issuing the dev ID tokens, and the one
+// clearing the collections, which must not be mounted in production.
+type DevEndpoints struct {
+	*Endpoints
+	Token *dev.TokenEndpoint
+	Reset Endpoint
+}
+
+func NewDevEndpoints(endpoints *Endpoints, env *dev.Environment, authClient *auth.Client, firestoreClient *firestore.Client) *DevEndpoints {
+	return &DevEndpoints{
+		Endpoints: endpoints,
+		Token:     dev.NewTokenEndpoint(env.Tokens, authClient),
+		Reset:     reset.NewHandler(firestoreClient),
+	}
+}
+
+func (e *DevEndpoints) SetupRouts(router *mux.Router) {
+	e.Endpoints.SetupRouts(router)
+	e.Token.SetupRouts(router)
+	e.Reset.SetupRouts(router)
+}
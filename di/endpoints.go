@@ -0,0 +1,373 @@
+package di
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"firebase.google.com/go/v4/auth"
+	"firebase.google.com/go/v4/storage"
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/appointments"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/archive"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/associates"
+	authEndpoint "github.com/VinothKuppanna/pigeon-go/internal/endpoints/auth"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses/channels"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses/customers"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses/directory"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/callbacks"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/cases"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/configuration"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/distances"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/feedback"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/healthcheck"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/invites"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/notes"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/queue"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/replies"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/signup"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/sla"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/sms"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/textsessions"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/textsessions/videocalls"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/users"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/verification"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/vnumbers"
+	"github.com/VinothKuppanna/pigeon-go/internal/identity"
+	"github.com/VinothKuppanna/pigeon-go/internal/middleware/hubspot"
+	"github.com/VinothKuppanna/pigeon-go/internal/outbox"
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"github.com/VinothKuppanna/pigeon-go/internal/scheduler"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+	"github.com/google/wire"
+	"github.com/gorilla/mux"
+	"github.com/vonage/vonage-go-sdk"
+)
+
+// An Endpoint registers the routes of a handler.
+type Endpoint interface {
+	SetupRouts(router *mux.Router)
+}
+
+// endpointFunc adapts the handlers whose method is named SetupRoutes.
+type endpointFunc func(router *mux.Router)
+
+func (f endpointFunc) SetupRouts(router *mux.Router) {
+	f(router)
+}
+
+// The endpoints have a type each, as wire tells the values apart by type.
+type (
+	AppointmentsEndpoint  Endpoint
+	ArchiveEndpoint       Endpoint
+	AssociatesEndpoint    Endpoint
+	AuthEndpoint          Endpoint
+	BusinessesEndpoint    Endpoint
+	ChannelsEndpoint      Endpoint
+	CustomersEndpoint     Endpoint
+	DirectoryEndpoint     Endpoint
+	TokboxEndpoint        Endpoint
+	InboundSMSEndpoint    Endpoint
+	CasesEndpoint         Endpoint
+	ConfigEndpoint        Endpoint
+	DistancesEndpoint     Endpoint
+	FeedbackEndpoint      Endpoint
+	HealthEndpoint        Endpoint
+	InvitesEndpoint       Endpoint
+	NotesEndpoint         Endpoint
+	QueueEndpoint         Endpoint
+	RepliesEndpoint       Endpoint
+	SignUpEndpoint        Endpoint
+	SLAEndpoint           Endpoint
+	SMSEndpoint           Endpoint
+	TextSessionsEndpoint  Endpoint
+	VideoCallsEndpoint    Endpoint
+	UsersEndpoint         Endpoint
+	VerificationEndpoint  Endpoint
+	VirtualNumberEndpoint Endpoint
+)
+
+// Endpoints are the handlers of the API.
+type Endpoints struct {
+	Appointments  AppointmentsEndpoint
+	Archive       ArchiveEndpoint
+	Associates    AssociatesEndpoint
+	Auth          AuthEndpoint
+	Businesses    BusinessesEndpoint
+	Channels      ChannelsEndpoint
+	Customers     CustomersEndpoint
+	Directory     DirectoryEndpoint
+	Tokbox        TokboxEndpoint
+	InboundSMS    InboundSMSEndpoint
+	Cases         CasesEndpoint
+	Config        ConfigEndpoint
+	Distances     DistancesEndpoint
+	Feedback      FeedbackEndpoint
+	Health        HealthEndpoint
+	Invites       InvitesEndpoint
+	Notes         NotesEndpoint
+	Queue         QueueEndpoint
+	Replies       RepliesEndpoint
+	SignUp        SignUpEndpoint
+	SLA           SLAEndpoint
+	SMS           SMSEndpoint
+	TextSessions  TextSessionsEndpoint
+	VideoCalls    VideoCallsEndpoint
+	Users         UsersEndpoint
+	Verification  VerificationEndpoint
+	VirtualNumber VirtualNumberEndpoint
+}
+
+// SetupRouts registers the routes of all the handlers.
+func (e *Endpoints) SetupRouts(router *mux.Router) {
+	for _, endpoint := range []Endpoint{
+		e.Health,
+		e.Appointments,
+		e.Archive,
+		e.Associates,
+		e.Auth,
+		e.Businesses,
+		e.Channels,
+		e.Customers,
+		e.Directory,
+		e.Tokbox,
+		e.InboundSMS,
+		e.Cases,
+		e.Config,
+		e.Distances,
+		e.Feedback,
+		e.Invites,
+		e.Notes,
+		e.Queue,
+		e.Replies,
+		e.SignUp,
+		e.SLA,
+		e.SMS,
+		e.TextSessions,
+		e.VideoCalls,
+		e.Users,
+		e.Verification,
+		e.VirtualNumber,
+	} {
+		endpoint.SetupRouts(router)
+	}
+}
+
+var EndpointsSet = wire.NewSet(
+	NewAppointmentsEndpoint,
+	NewArchiveEndpoint,
+	NewAssociatesEndpoint,
+	NewAuthEndpoint,
+	NewBusinessesEndpoint,
+	NewChannelsEndpoint,
+	NewCustomersEndpoint,
+	NewDirectoryEndpoint,
+	NewTokboxEndpoint,
+	NewInboundSMSEndpoint,
+	NewCasesEndpoint,
+	NewConfigEndpoint,
+	NewDistancesEndpoint,
+	NewFeedbackEndpoint,
+	NewHealthEndpoint,
+	NewInvitesEndpoint,
+	NewNotesEndpoint,
+	NewQueueEndpoint,
+	NewRepliesEndpoint,
+	NewHubspot,
+	NewSignUpEndpoint,
+	NewSLAEndpoint,
+	NewSMSEndpoint,
+	NewTextSessionsEndpoint,
+	NewVideoCallsEndpoint,
+	NewUsersEndpoint,
+	NewVerificationEndpoint,
+	NewVirtualNumberEndpoint,
+	wire.Struct(new(Endpoints), "*"),
+)
+
+func NewAppointmentsEndpoint(appointmentsRepository definition.AppointmentsRepository,
+	associateAssistantRepository data.AssociateAssistantRepository,
+	businessesRepository definition.BusinessesRepository,
+	settingsRepository definition.BusinessSettingsRepository,
+	directoryRepository definition.DirectoryRepository,
+	customersRepository definition.CustomersRepository,
+	publisher events.Publisher) AppointmentsEndpoint {
+	return appointments.NewHandler(appointmentsRepository, associateAssistantRepository, businessesRepository,
+		settingsRepository, directoryRepository, customersRepository, publisher)
+}
+
+func NewArchiveEndpoint(config *configs.Config,
+	casesRepository definition.CasesRepository,
+	messagesRepository definition.MessagesRepository,
+	notesRepository definition.NotesRepository,
+	storageClient *storage.Client) ArchiveEndpoint {
+	return archive.NewHandler(config, casesRepository, messagesRepository, notesRepository, storageClient,
+		config.StorageBucket, config.ArchiveTemplate)
+}
+
+func NewAssociatesEndpoint(config *configs.Config,
+	authClient *auth.Client,
+	firestoreClient *firestore.Client,
+	storageClient *storage.Client,
+	claimsService definition.ClaimsService) AssociatesEndpoint {
+	return associates.New(config, authClient, firestoreClient, storageClient, claimsService)
+}
+
+func NewAuthEndpoint(authService definition.AuthService,
+	emailService definition.EmailsService,
+	linkService definition.DynamicLinksService) AuthEndpoint {
+	return authEndpoint.NewHandler(authService, emailService, linkService)
+}
+
+func NewBusinessesEndpoint(config *configs.Config, db *db.Firestore) BusinessesEndpoint {
+	return businesses.NewHandler(db, config.PlacesApiKey)
+}
+
+func NewChannelsEndpoint(db *db.Firestore) ChannelsEndpoint {
+	return channels.NewHandler(db)
+}
+
+func NewCustomersEndpoint(customersRepository definition.CustomersRepository, identityResolver *identity.Resolver) CustomersEndpoint {
+	return customers.NewHandler(customersRepository, identityResolver)
+}
+
+func NewDirectoryEndpoint(directoryRepository definition.DirectoryRepository) DirectoryEndpoint {
+	return directory.NewHandler(directoryRepository)
+}
+
+func NewTokboxEndpoint(chatsRepository definition.TextSessionsRepository,
+	chatVideoCallsRepository data.VideoCallsRepository) TokboxEndpoint {
+	return callbacks.NewHandler(chatsRepository, chatVideoCallsRepository)
+}
+
+func NewInboundSMSEndpoint(loggers Loggers) InboundSMSEndpoint {
+	return callbacks.NewSMSHandler(loggers.Info, loggers.Errors)
+}
+
+// NewCasesEndpoint also schedules the expiry of the unanswered handoffs, until the scheduler stops.
+func NewCasesEndpoint(casesRepository definition.CasesRepository,
+	chatsRepository definition.TextSessionsRepository,
+	messagesRepository definition.MessagesRepository,
+	directoryRepository definition.DirectoryRepository,
+	customersRepository definition.CustomersRepository,
+	pushService definition.PushService,
+	jobScheduler scheduler.Scheduler,
+	publisher events.Publisher) CasesEndpoint {
+	handler := cases.NewHandler(casesRepository, chatsRepository, messagesRepository, directoryRepository, customersRepository,
+		pushService, publisher)
+	handler.Schedule(context.Background(), jobScheduler, cases.DefaultHandoffExpiryInterval)
+	return handler
+}
+
+func NewConfigEndpoint(config *configs.Config) ConfigEndpoint {
+	return configuration.NewHandler(config)
+}
+
+func NewDistancesEndpoint(service definition.DistancesService) DistancesEndpoint {
+	return distances.NewHandler(service)
+}
+
+func NewFeedbackEndpoint(firestoreClient *firestore.Client) FeedbackEndpoint {
+	return feedback.NewHandler(firestoreClient)
+}
+
+// NewHealthEndpoint serves the liveness and the readiness. The checks are run once at boot,
+// so the secret is read then.
+func NewHealthEndpoint(config *configs.Config,
+	firestoreClient *firestore.Client,
+	producer *publishing.Producer) HealthEndpoint {
+	checker := healthcheck.NewChecker(healthcheck.DefaultTTL,
+		healthcheck.Firestore(firestoreClient),
+		healthcheck.NSQ(producer),
+		healthcheck.SecretManager(config),
+		healthcheck.Templates(config),
+		healthcheck.SendGrid(config.SendGrid),
+		healthcheck.Vonage(config.Vonage),
+		healthcheck.OpenTok(config.Opentok),
+	)
+	go checker.Run(context.Background())
+	return healthcheck.NewHandler(checker)
+}
+
+func NewInvitesEndpoint(config *configs.Config,
+	authClient *auth.Client,
+	firestoreClient *firestore.Client,
+	inviteService definition.InvitesService,
+	publisher events.Publisher) InvitesEndpoint {
+	return invites.New(config, authClient, firestoreClient, inviteService, publisher)
+}
+
+func NewNotesEndpoint(db *db.Firestore, pushService definition.PushService) NotesEndpoint {
+	return notes.NewHandler(db, pushService)
+}
+
+func NewQueueEndpoint(queueService definition.QueueService) QueueEndpoint {
+	return queue.NewHandler(queueService)
+}
+
+func NewRepliesEndpoint(db *db.Firestore) RepliesEndpoint {
+	return replies.NewHandler(db)
+}
+
+func NewHubspot(outbox *outbox.Outbox) *hubspot.Handler {
+	return hubspot.New(outbox)
+}
+
+func NewSignUpEndpoint(config *configs.Config,
+	authClient *auth.Client,
+	firestoreClient *firestore.Client,
+	emailService definition.EmailsService,
+	mw *hubspot.Handler) SignUpEndpoint {
+	return signup.NewHandler(config, authClient, firestoreClient, emailService, mw)
+}
+
+// NewSLAEndpoint also schedules the evaluation of the SLA policies, until the scheduler stops.
+func NewSLAEndpoint(db *db.Firestore, slaService definition.SLAService, jobScheduler scheduler.Scheduler) SLAEndpoint {
+	handler := sla.NewHandler(db, slaService)
+	handler.Schedule(context.Background(), jobScheduler, sla.DefaultEvaluationInterval)
+	return handler
+}
+
+func NewSMSEndpoint(config *configs.Config,
+	smsClient *vonage.SMSClient,
+	dlService definition.DynamicLinksService,
+	db *db.Firestore) SMSEndpoint {
+	return endpointFunc(sms.NewHandler(config, smsClient, dlService, db).SetupRoutes)
+}
+
+func NewTextSessionsEndpoint(textSessionRepository definition.TextSessionsRepository,
+	messagesRepository definition.MessagesRepository,
+	directoryRepository definition.DirectoryRepository,
+	businessesRepository definition.BusinessesRepository,
+	bizSettingsRepository definition.BusinessSettingsRepository,
+	customersRepository definition.CustomersRepository,
+	publisher events.Publisher) TextSessionsEndpoint {
+	return textsessions.NewHandler(textSessionRepository, messagesRepository, directoryRepository, businessesRepository,
+		bizSettingsRepository, customersRepository, publisher)
+}
+
+func NewVideoCallsEndpoint(videoCallService *domain.VideoCallService) VideoCallsEndpoint {
+	return videocalls.NewHandler(videoCallService)
+}
+
+func NewUsersEndpoint(authClient *auth.Client, firestoreClient *firestore.Client, identityResolver *identity.Resolver) UsersEndpoint {
+	return users.NewHandler(authClient, firestoreClient, identityResolver)
+}
+
+func NewVerificationEndpoint(config *configs.Config,
+	emailService definition.EmailsService,
+	authClient *auth.Client,
+	verificationsRepository definition.VerificationsRepository) VerificationEndpoint {
+	return verification.NewHandler(config, emailService, authClient, verificationsRepository)
+}
+
+// NewVirtualNumberEndpoint builds the callback URLs of the numbers on the web host.
+func NewVirtualNumberEndpoint(config *configs.Config,
+	numbersClient *vonage.NumbersClient,
+	firestoreClient *firestore.Client) VirtualNumberEndpoint {
+	return endpointFunc(vnumbers.NewHandler(config.Vonage.AppID, config.Smtp.Host, numbersClient, firestoreClient).SetupRoutes)
+}
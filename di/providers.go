@@ -0,0 +1,104 @@
+// Package di assembles the API server from its providers with wire. wire.go declares the injectors
+// and wire_gen.go is generated from it by running wire in this directory.
+package di
+
+import (
+	"firebase.google.com/go/v4/auth"
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/identity"
+	"github.com/VinothKuppanna/pigeon-go/internal/middleware/authenticator"
+	"github.com/VinothKuppanna/pigeon-go/internal/outbox"
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+	"github.com/google/wire"
+)
+
+// CloudSet provides the dependencies the dev profile replaces with local stand-ins.
+var CloudSet = wire.NewSet(
+	NewFirebaseApp,
+	NewFirestoreClient,
+	NewProducer,
+	NewEmailsService,
+	domain.NewDynamicLinksService,
+	data.NewFCMPushService,
+	wire.Bind(new(authenticator.TokenVerifier), new(*auth.Client)),
+)
+
+var ClientsSet = wire.NewSet(
+	NewLoggers,
+	NewAuthClient,
+	NewFirestore,
+	NewStorageClient,
+	NewMessagingClient,
+	NewMapsClient,
+	NewNumbersClient,
+	NewSMSClient,
+	NewOpenTok,
+	NewOutbox,
+	NewRelay,
+	NewScheduler,
+	NewEmailTemplates,
+)
+
+var RepositoriesSet = wire.NewSet(
+	data.NewTextSessionsRepo,
+	data.NewMessagesRepo,
+	data.NewBusinessesRepository,
+	data.NewBusinessSettingsRepository,
+	data.NewVideoCallsRepo,
+	data.NewAppointmentsRepo,
+	data.NewAssociateAssistantRepository,
+	data.NewAssociatesRepository,
+	data.NewInvitesRepository,
+	data.NewCasesRepository,
+	data.NewCustomersRepository,
+	data.NewDirectoryRepository,
+	data.NewNotesRepository,
+	data.NewVerificationsRepository,
+)
+
+var ServicesSet = wire.NewSet(
+	domain.NewAuthService,
+	domain.NewClaimsService,
+	domain.NewInvitesService,
+	domain.NewQueueService,
+	domain.NewSLAService,
+	domain.NewVideoCallService,
+	data.NewDistancesService,
+	identity.NewFirestoreAuditor,
+	identity.NewResolver,
+	NewEventBus,
+	NewEventPublisher,
+)
+
+// NewEmailsService sends the emails with SendGrid if it is enabled, and with SMTP otherwise.
+// Either way the results are published to NSQ.
+func NewEmailsService(config *configs.Config, templates *configs.EmailTemplates, producer *publishing.Producer) definition.EmailsService {
+	var service definition.EmailsService
+	if config.SendGrid.Enabled {
+		service = data.NewSendgridEmailService(config)
+	} else {
+		service = domain.NewEmailService(config, templates)
+	}
+	return data.NewLoggingMiddleware(producer)(service)
+}
+
+// NewEventBus returns the bus of the subscribers to the domain events. The events are logged,
+// for analytics.
+func NewEventBus(loggers Loggers) *events.Bus {
+	bus := events.NewBus(loggers.Errors)
+	bus.Subscribe(events.Log(loggers.Info))
+	return bus
+}
+
+// NewEventPublisher delivers the events to the subscribers of bus with the inprocess transport,
+// and writes them to the outbox for pigeon-worker otherwise.
+func NewEventPublisher(config *configs.Config, bus *events.Bus, outbox *outbox.Outbox) events.Publisher {
+	if config.Events.Transport == "inprocess" {
+		return bus
+	}
+	return events.NewOutboxPublisher(outbox)
+}
@@ -0,0 +1,208 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/metrics"
+	"github.com/VinothKuppanna/pigeon-go/internal/middleware/authenticator"
+	"github.com/VinothKuppanna/pigeon-go/internal/middleware/authorizer"
+	"github.com/VinothKuppanna/pigeon-go/internal/middleware/idempotency"
+	"github.com/VinothKuppanna/pigeon-go/internal/middleware/instrumenting"
+	"github.com/VinothKuppanna/pigeon-go/internal/middleware/logging"
+	"github.com/VinothKuppanna/pigeon-go/internal/middleware/ratelimit"
+	"github.com/VinothKuppanna/pigeon-go/internal/middleware/requestid"
+	"github.com/VinothKuppanna/pigeon-go/internal/outbox"
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
+	"github.com/VinothKuppanna/pigeon-go/internal/scheduler"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/google/wire"
+	"github.com/gorilla/mux"
+)
+
+// A Middleware wraps the routes of the router.
+type Middleware interface {
+	Setup(router *mux.Router)
+}
+
+// The middlewares have a type each, as wire tells the values apart by type.
+type (
+	RequestIDMiddleware     Middleware
+	InstrumentingMiddleware Middleware
+	LoggingMiddleware       Middleware
+	AuthenticatorMiddleware Middleware
+	RateLimitMiddleware     Middleware
+	AuthorizerMiddleware    Middleware
+	IdempotencyMiddleware   Middleware
+)
+
+// Middlewares are the middlewares of the API.
+type Middlewares struct {
+	RequestID     RequestIDMiddleware
+	Instrumenting InstrumentingMiddleware
+	Logging       LoggingMiddleware
+	Authenticator AuthenticatorMiddleware
+	RateLimit     RateLimitMiddleware
+	Authorizer    AuthorizerMiddleware
+	Idempotency   IdempotencyMiddleware
+}
+
+// Setup mounts the middlewares in order. The request ID goes first, so every line logged for
+// the request carries it, then the instrumenting, so the others run within the trace of the request. The rate limiter and the authorizer need the uid of the
+// authenticator, and the idempotency keys are scoped by it. Only the authorized requests
+// are stored for replay.
+func (m *Middlewares) Setup(router *mux.Router) {
+	for _, middleware := range []Middleware{
+		m.RequestID,
+		m.Instrumenting,
+		m.Logging,
+		m.Authenticator,
+		m.RateLimit,
+		m.Authorizer,
+		m.Idempotency,
+	} {
+		middleware.Setup(router)
+	}
+}
+
+var MiddlewaresSet = wire.NewSet(
+	NewRequestIDMiddleware,
+	NewInstrumentingMiddleware,
+	NewLoggingMiddleware,
+	NewAuthenticatorMiddleware,
+	NewRateLimitMiddleware,
+	NewAuthorizerMiddleware,
+	NewIdempotencyMiddleware,
+	wire.Struct(new(Middlewares), "*"),
+)
+
+func NewRequestIDMiddleware(loggers Loggers) RequestIDMiddleware {
+	return requestid.New(loggers.Logger)
+}
+
+func NewInstrumentingMiddleware() InstrumentingMiddleware {
+	return instrumenting.New()
+}
+
+func NewLoggingMiddleware(outbox *outbox.Outbox, loggers Loggers) LoggingMiddleware {
+	return logging.New(outbox, loggers.Logger)
+}
+
+func NewAuthenticatorMiddleware(verifier authenticator.TokenVerifier,
+	claimsService definition.ClaimsService,
+	loggers Loggers) AuthenticatorMiddleware {
+	return authenticator.New(verifier, claimsService, loggers.Logger)
+}
+
+func NewRateLimitMiddleware(config *configs.Config, db *db.Firestore, loggers Loggers) RateLimitMiddleware {
+	return ratelimit.New(ratelimit.NewStore(config.RateLimit.Store, db), config.RateLimit.TrustedProxies, loggers.Logger)
+}
+
+func NewAuthorizerMiddleware(db *db.Firestore, loggers Loggers) AuthorizerMiddleware {
+	return authorizer.New(authorizer.NewFirestoreResolver(db), loggers.Logger)
+}
+
+func NewIdempotencyMiddleware(config *configs.Config, db *db.Firestore, loggers Loggers) IdempotencyMiddleware {
+	return idempotency.New(idempotency.NewStore(config.Idempotency.Store, db), config.Idempotency.TTL, loggers.Logger)
+}
+
+// Server is the API server with the resources it stops on shutdown. It also runs the relay of
+// the outbox, and serves the metrics on their own port, if the config enables them.
+type Server struct {
+	HTTP      *http.Server
+	Metrics   *http.Server
+	Scheduler scheduler.Scheduler
+	Relay     *outbox.Relay
+	Producer  *publishing.Producer
+	Loggers   Loggers
+
+	stopRelay func()
+	relaying  chan struct{}
+}
+
+// NewServer mounts the middlewares and the routes of the endpoints on the router of the server.
+func NewServer(config *configs.Config,
+	middlewares *Middlewares,
+	endpoints Endpoint,
+	jobScheduler scheduler.Scheduler,
+	relay *outbox.Relay,
+	producer *publishing.Producer,
+	loggers Loggers) *Server {
+	router := mux.NewRouter()
+	middlewares.Setup(router)
+	endpoints.SetupRouts(router)
+	routes.Report(router, loggers.Info)
+	var metricsServer *http.Server
+	if config.Server.MetricsPort > 0 {
+		metricsServer = metrics.NewServer(fmt.Sprintf(":%d", config.Server.MetricsPort))
+	}
+	return &Server{
+		HTTP:      &http.Server{Addr: fmt.Sprintf(":%d", config.Server.Port), Handler: router},
+		Metrics:   metricsServer,
+		Scheduler: jobScheduler,
+		Relay:     relay,
+		Producer:  producer,
+		Loggers:   loggers,
+	}
+}
+
+// ListenAndServe starts the relay and the metrics listener, and serves the API until the server
+// is shut down. The API keeps serving if the metrics listener fails.
+func (s *Server) ListenAndServe() error {
+	if s.Metrics != nil {
+		go func() {
+			s.Loggers.Info.Printf("serving the metrics on %s\n", s.Metrics.Addr)
+			if err := s.Metrics.ListenAndServe(); err != http.ErrServerClosed {
+				s.Loggers.Errors.Printf("failed to serve the metrics. error: %v\n", err)
+			}
+		}()
+	}
+	if s.Relay != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.stopRelay, s.relaying = cancel, make(chan struct{})
+		go func() {
+			defer close(s.relaying)
+			s.Relay.Run(ctx)
+		}()
+	}
+	s.Loggers.Info.Printf("listening on %s\n", s.HTTP.Addr)
+	if err := s.HTTP.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown drains the requests in flight, then stops the scheduled jobs and relays the outbox
+// entries they wrote, and at last flushes the messages they published and stops the producer.
+// It goes on with the next step if ctx is done first, and returns the first error.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.Loggers.Info.Println("draining the requests")
+	err := s.HTTP.Shutdown(ctx)
+	if s.Metrics != nil {
+		if metricsErr := s.Metrics.Shutdown(ctx); err == nil {
+			err = metricsErr
+		}
+	}
+	s.Loggers.Info.Println("stopping the scheduler")
+	s.Scheduler.Stop()
+	if s.stopRelay != nil {
+		s.Loggers.Info.Println("draining the outbox")
+		s.stopRelay()
+		select {
+		case <-s.relaying:
+		case <-ctx.Done():
+		}
+		if drainErr := s.Relay.Drain(ctx); err == nil {
+			err = drainErr
+		}
+	}
+	s.Loggers.Info.Println("flushing the producer")
+	if flushErr := s.Producer.Stop(ctx); err == nil {
+		err = flushErr
+	}
+	return err
+}
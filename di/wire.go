@@ -4,13 +4,31 @@
 package di
 
 import (
-	"cloud.google.com/go/firestore"
-	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/textsessions"
-	"github.com/VinothKuppanna/pigeon-go/pkg/data"
+	"context"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/dev"
 	"github.com/google/wire"
 )
 
-func InitChatsHandler(client *firestore.Client) *textsessions.Handler {
-	wire.Build(textsessions.NewHandler, data.NewTextSessionsRepo)
-	return &textsessions.Handler{}
+// InitServer builds the API server with all its handlers, services and repositories.
+// The cleanup closes the clients once the server is shut down.
+func InitServer(ctx context.Context, config *configs.Config) (*Server, func(), error) {
+	wire.Build(CloudSet, ClientsSet, RepositoriesSet, ServicesSet, EndpointsSet, MiddlewaresSet,
+		wire.Bind(new(Endpoint), new(*Endpoints)), NewServer)
+	return nil, nil, nil
+}
+
+// InitDevServer builds the API server of the dev profile, with the stand-ins of env in place of
+// the cloud dependencies.
+func InitDevServer(ctx context.Context, config *configs.Config, env *dev.Environment) (*Server, func(), error) {
+	wire.Build(DevSet, ClientsSet, RepositoriesSet, ServicesSet, EndpointsSet, MiddlewaresSet, NewServer)
+	return nil, nil, nil
+}
+
+// InitWorker builds the worker consuming the topics published by the API.
+// The cleanup closes the clients once the worker is shut down.
+func InitWorker(ctx context.Context, config *configs.Config) (*Worker, func(), error) {
+	wire.Build(WorkerSet)
+	return nil, nil, nil
 }
@@ -0,0 +1,121 @@
+package di
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/consumers"
+	"github.com/VinothKuppanna/pigeon-go/internal/consuming"
+	"github.com/VinothKuppanna/pigeon-go/internal/crm"
+	"github.com/VinothKuppanna/pigeon-go/internal/middleware/hubspot"
+	"github.com/VinothKuppanna/pigeon-go/internal/middleware/logging"
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+	"github.com/google/wire"
+)
+
+var WorkerSet = wire.NewSet(
+	NewLoggers,
+	NewProducer,
+	NewFirebaseApp,
+	NewFirestoreClient,
+	NewFirestore,
+	NewSyncer,
+	NewEventBus,
+	NewConsumer,
+	NewWorker,
+)
+
+// NewSyncer returns nil if the HubSpot sync is disabled.
+func NewSyncer(config *configs.Config, db *db.Firestore, loggers Loggers) *crm.Syncer {
+	if !config.Hubspot.Enabled {
+		return nil
+	}
+	client := crm.NewClient(config.Hubspot, http.DefaultClient)
+	return crm.NewSyncer(client, crm.NewFirestoreStore(db), config.Hubspot, loggers.Info, loggers.Errors)
+}
+
+// NewConsumer registers the handlers of the topics published by the API, and those of the
+// events with subscribers on bus.
+func NewConsumer(config *configs.Config,
+	producer *publishing.Producer,
+	syncer *crm.Syncer,
+	bus *events.Bus,
+	loggers Loggers) *consuming.Consumer {
+	consumer := consuming.New(consuming.Options{
+		Channel:     config.Worker.Channel,
+		Concurrency: config.Worker.Concurrency,
+		MaxAttempts: config.Worker.MaxAttempts,
+		Backoff:     config.Worker.Backoff,
+		MaxBackoff:  config.Worker.MaxBackoff,
+	}, producer, loggers.Info, loggers.Errors)
+
+	logs := consumers.NewLogs(os.Stdout)
+	consumer.Handle(logging.NSQApiRequestTopic, consuming.Options{Unwrap: consumers.UnwrapLogEntry}, logs.Handle)
+	consumer.Handle(data.TopicEmailsRequests, consuming.Options{Unwrap: consumers.UnwrapLogEntry}, logs.Handle)
+	consumer.Handle(hubspot.TopicSignUpEvent, consuming.Options{Unwrap: consumers.UnwrapSignUp}, consumers.NewSignUps(loggers.Info, syncer).Handle)
+	for _, name := range bus.Subscribed() {
+		consumer.Handle(events.Topic(name), consuming.Options{Unwrap: events.Unwrap}, consumers.Events(bus))
+	}
+	return consumer
+}
+
+// Worker consumes the topics published by the API, and publishes the dead letters. With the
+// HubSpot sync it also watches the changes of the businesses.
+type Worker struct {
+	config   *configs.Config
+	Consumer *consuming.Consumer
+	Syncer   *crm.Syncer
+	Producer *publishing.Producer
+	Loggers  Loggers
+
+	stopWatch func()
+	watching  chan struct{}
+}
+
+func NewWorker(config *configs.Config,
+	consumer *consuming.Consumer,
+	syncer *crm.Syncer,
+	producer *publishing.Producer,
+	loggers Loggers) *Worker {
+	return &Worker{config: config, Consumer: consumer, Syncer: syncer, Producer: producer, Loggers: loggers}
+}
+
+// Start connects the consumer to the nsqlookupd of the config, or to the nsqd if there are none,
+// and starts the watch of the businesses.
+func (w *Worker) Start() error {
+	if w.Syncer != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		w.stopWatch, w.watching = cancel, make(chan struct{})
+		go func() {
+			defer close(w.watching)
+			w.Syncer.Watch(ctx)
+		}()
+	}
+	return w.Consumer.Connect(w.config.NsqdAddress, w.config.Worker.NsqLookupdAddresses)
+}
+
+// Shutdown stops the watch of the businesses and drains the messages in flight, then flushes
+// the dead letters they published and stops the producer. It goes on with the next step if
+// ctx is done first, and returns the first error.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	if w.stopWatch != nil {
+		w.Loggers.Info.Println("stopping the watch of the businesses")
+		w.stopWatch()
+		select {
+		case <-w.watching:
+		case <-ctx.Done():
+		}
+	}
+	w.Loggers.Info.Println("draining the messages")
+	err := w.Consumer.Stop(ctx)
+	w.Loggers.Info.Println("flushing the producer")
+	if flushErr := w.Producer.Stop(ctx); err == nil {
+		err = flushErr
+	}
+	return err
+}
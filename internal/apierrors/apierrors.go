@@ -0,0 +1,202 @@
+// Package apierrors is the error model of the API. Every error response carries a stable
+// machine-readable code, so clients can branch on it instead of parsing the message.
+package apierrors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A Code identifies the kind of the error. Codes are part of the API and must not be renamed.
+type Code string
+
+const (
+	CodeInvalidArgument    Code = "invalid_argument"
+	CodeValidationFailed   Code = "validation_failed"
+	CodeUnauthenticated    Code = "unauthenticated"
+	CodeAccountDisabled    Code = "account_disabled"
+	CodePermissionDenied   Code = "permission_denied"
+	CodeNotFound           Code = "not_found"
+	CodeAlreadyExists      Code = "already_exists"
+	CodeConflict           Code = "conflict"
+	CodeGone               Code = "gone"
+	CodeFailedPrecondition Code = "failed_precondition"
+	CodeUnprocessable      Code = "unprocessable"
+	CodePayloadTooLarge    Code = "payload_too_large"
+	CodeRateLimited        Code = "rate_limited"
+	CodeTimeout            Code = "timeout"
+	CodeUpstreamFailed     Code = "upstream_failed"
+	CodeNotImplemented     Code = "not_implemented"
+	CodeUnavailable        Code = "unavailable"
+	CodeInternal           Code = "internal"
+)
+
+var statuses = map[Code]int{
+	CodeInvalidArgument:    http.StatusBadRequest,
+	CodeValidationFailed:   http.StatusBadRequest,
+	CodeUnauthenticated:    http.StatusUnauthorized,
+	CodeAccountDisabled:    http.StatusUnauthorized,
+	CodePermissionDenied:   http.StatusForbidden,
+	CodeNotFound:           http.StatusNotFound,
+	CodeAlreadyExists:      http.StatusConflict,
+	CodeConflict:           http.StatusConflict,
+	CodeGone:               http.StatusGone,
+	CodeFailedPrecondition: http.StatusPreconditionFailed,
+	CodeUnprocessable:      http.StatusUnprocessableEntity,
+	CodePayloadTooLarge:    http.StatusRequestEntityTooLarge,
+	CodeRateLimited:        http.StatusTooManyRequests,
+	CodeTimeout:            http.StatusGatewayTimeout,
+	CodeUpstreamFailed:     http.StatusBadGateway,
+	CodeNotImplemented:     http.StatusNotImplemented,
+	CodeUnavailable:        http.StatusServiceUnavailable,
+	CodeInternal:           http.StatusInternalServerError,
+}
+
+// Status returns the HTTP status code of the code. Unknown codes are internal errors.
+func (c Code) Status() int {
+	if statusCode, ok := statuses[c]; ok {
+		return statusCode
+	}
+	return http.StatusInternalServerError
+}
+
+var grpcCodes = map[codes.Code]Code{
+	codes.InvalidArgument:    CodeInvalidArgument,
+	codes.OutOfRange:         CodeInvalidArgument,
+	codes.Unauthenticated:    CodeUnauthenticated,
+	codes.PermissionDenied:   CodePermissionDenied,
+	codes.NotFound:           CodeNotFound,
+	codes.AlreadyExists:      CodeAlreadyExists,
+	codes.Aborted:            CodeConflict,
+	codes.FailedPrecondition: CodeFailedPrecondition,
+	codes.ResourceExhausted:  CodeRateLimited,
+	codes.Canceled:           CodeTimeout,
+	codes.DeadlineExceeded:   CodeTimeout,
+	codes.Unimplemented:      CodeNotImplemented,
+	codes.Unavailable:        CodeUnavailable,
+	codes.Internal:           CodeInternal,
+	codes.DataLoss:           CodeInternal,
+}
+
+// A FieldError describes an invalid field of the request payload.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// An Error is an API error. The cause is kept for logging and errors.Is, it is not sent to the client.
+type Error struct {
+	Code    Code
+	Message string
+	Details []FieldError
+	cause   error
+}
+
+// Error returns the message with the cause, for the logs. Clients get the Message only.
+func (e *Error) Error() string {
+	if e.cause == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.cause.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+func (e *Error) Status() int {
+	return e.Code.Status()
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func Newf(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap classifies the err under the code. The message of the err may reveal internals,
+// so the client gets the status text of the code and the err is kept as the cause.
+func Wrap(code Code, err error) *Error {
+	return &Error{Code: code, Message: http.StatusText(code.Status()), cause: err}
+}
+
+// Validation reports the invalid fields of the request payload.
+func Validation(details ...FieldError) *Error {
+	return &Error{Code: CodeValidationFailed, Message: "request validation failed", Details: details}
+}
+
+// From returns the err as an API error. An *Error in the chain is returned as is,
+// gRPC and context errors are classified by their code, anything else gets the fallback code.
+func From(err error, fallback Code) *Error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return Wrap(CodeTimeout, err)
+	}
+	if grpcStatus, ok := status.FromError(err); ok {
+		if code, ok := grpcCodes[grpcStatus.Code()]; ok {
+			return Wrap(code, err)
+		}
+	}
+	return Wrap(fallback, err)
+}
+
+// CodeOf returns the code of the err, or an empty code for nil.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	return From(err, CodeInternal).Code
+}
+
+// Response is the JSON body of the error responses. Status and message are kept for the older clients.
+type Response struct {
+	model.BaseResponse
+	Code    Code         `json:"code"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// Respond writes the err as the error response. Unclassified errors are internal. The err is
+// recorded on resp if it is a common.ErrorRecorder, for the logs.
+func Respond(resp http.ResponseWriter, err error) {
+	apiErr := From(err, CodeInternal)
+	if recorder, ok := resp.(common.ErrorRecorder); ok {
+		recorder.RecordError(apiErr)
+	}
+	statusCode := apiErr.Status()
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(statusCode)
+	_ = json.NewEncoder(resp).Encode(&Response{
+		BaseResponse: model.BaseResponse{
+			Status:  http.StatusText(statusCode),
+			Message: apiErr.Message,
+		},
+		Code:    apiErr.Code,
+		Details: apiErr.Details,
+	})
+}
+
+// RespondWithError writes the error response if err is not nil and reports whether it did.
+// The fallback code is used for the errors From cannot classify.
+func RespondWithError(err error, resp http.ResponseWriter, fallback Code) bool {
+	if err == nil {
+		return false
+	}
+	Respond(resp, From(err, fallback))
+	return true
+}
@@ -0,0 +1,75 @@
+package apierrors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFrom(t *testing.T) {
+	typed := New(CodeGone, "handoff has expired")
+	tests := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{"typed", typed, CodeGone},
+		{"wrapped typed", fmt.Errorf("accept: %w", typed), CodeGone},
+		{"grpc not found", status.Error(codes.NotFound, "no document"), CodeNotFound},
+		{"grpc already exists", status.Error(codes.AlreadyExists, "exists"), CodeAlreadyExists},
+		{"grpc unauthenticated", status.Error(codes.Unauthenticated, "no token"), CodeUnauthenticated},
+		{"grpc unknown", status.Error(codes.Unknown, "unknown"), CodeInvalidArgument},
+		{"plain", errors.New("bad json"), CodeInvalidArgument},
+	}
+	for _, tt := range tests {
+		if got := From(tt.err, CodeInvalidArgument).Code; got != tt.want {
+			t.Errorf("%s: From() code = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+	if From(nil, CodeInternal) != nil {
+		t.Error("From(nil) is not nil")
+	}
+}
+
+func TestRespondWithError(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	err := Validation(FieldError{Field: "startDate", Message: "is required"})
+	if !RespondWithError(err, recorder, CodeInternal) {
+		t.Fatal("RespondWithError() = false, want true")
+	}
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+	var body Response
+	if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != CodeValidationFailed || body.Status != "Bad Request" || len(body.Details) != 1 || body.Details[0].Field != "startDate" {
+		t.Errorf("body = %+v", body)
+	}
+	if RespondWithError(nil, httptest.NewRecorder(), CodeInternal) {
+		t.Error("RespondWithError(nil) = true, want false")
+	}
+}
+
+func TestRespondHidesCause(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	cause := errors.New("rpc error: projects/pigeon/databases/(default)/documents/users/u1")
+	Respond(recorder, cause)
+	var body Response
+	if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != CodeInternal || body.Message != "Internal Server Error" {
+		t.Errorf("body = %+v, want the generic message", body)
+	}
+	if err := Wrap(CodeInternal, cause); err.Error() != "Internal Server Error: "+cause.Error() || !errors.Is(err, cause) {
+		t.Errorf("Error() = %q, want the cause for the logs", err.Error())
+	}
+}
@@ -3,13 +3,10 @@ package common
 import (
 	"bytes"
 	"encoding/gob"
-	"encoding/json"
 	"io/ioutil"
 	"net/http"
 
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 // todo: eliminate
@@ -21,20 +18,6 @@ func CheckError(err error) bool {
 	}
 }
 
-// todo: eliminate
-func RespondWithError(err error, resp http.ResponseWriter, statusCode int) bool {
-	if CheckError(err) {
-		resp.WriteHeader(statusCode)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(statusCode),
-			Message: err.Error(),
-		})
-		return true
-	} else {
-		return false
-	}
-}
-
 func ArrayIncludes(array []interface{}, value interface{}) bool {
 	for _, item := range array {
 		if item == value {
@@ -102,54 +85,20 @@ func GetBytes(key interface{}) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func GRPCToHttpErrorCode(err error) int {
-	switch status.Code(err) {
-	case codes.OK:
-		return http.StatusOK
-	case codes.Canceled:
-		return http.StatusNotAcceptable
-	case codes.Unknown:
-		return http.StatusNotFound
-	case codes.InvalidArgument:
-		return http.StatusBadRequest
-	case codes.DeadlineExceeded:
-		return http.StatusRequestTimeout
-	case codes.NotFound:
-		return http.StatusNotFound
-	case codes.AlreadyExists:
-		return http.StatusNotModified
-	case codes.PermissionDenied:
-		return http.StatusUnauthorized
-	case codes.ResourceExhausted:
-		return http.StatusInsufficientStorage
-	case codes.FailedPrecondition:
-		return http.StatusPreconditionFailed
-	case codes.Aborted:
-		return http.StatusNotAcceptable
-	case codes.OutOfRange:
-		return http.StatusBadRequest
-	case codes.Unimplemented:
-		return http.StatusInternalServerError
-	case codes.Internal:
-		return http.StatusInternalServerError
-	case codes.Unavailable:
-		return http.StatusServiceUnavailable
-	case codes.DataLoss:
-		return http.StatusNoContent
-	case codes.Unauthenticated:
-		return http.StatusNetworkAuthenticationRequired
-	default:
-		return 0
-	}
-}
-
 type responseWriter struct {
 	http.ResponseWriter
 	status        int
 	error         []byte
+	err           error
 	headerWritten bool
 }
 
+// An ErrorRecorder keeps the error of the response, for the middlewares to log it with its cause.
+// The writers wrapping another pass the error on to it.
+type ErrorRecorder interface {
+	RecordError(err error)
+}
+
 func WrapResponse(response http.ResponseWriter) *responseWriter {
 	return &responseWriter{ResponseWriter: response}
 }
@@ -162,6 +111,18 @@ func (rw *responseWriter) Error() []byte {
 	return rw.error
 }
 
+// Err returns the error recorded by apierrors.Respond, if the response is one.
+func (rw *responseWriter) Err() error {
+	return rw.err
+}
+
+func (rw *responseWriter) RecordError(err error) {
+	rw.err = err
+	if recorder, ok := rw.ResponseWriter.(ErrorRecorder); ok {
+		recorder.RecordError(err)
+	}
+}
+
 func (rw *responseWriter) WriteHeader(statusCode int) {
 	if rw.headerWritten {
 		return
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"context"
+	"time"
+)
+
+// WithoutCancel returns a context with the values of ctx, which is not canceled with it. It is used
+// for the writes that must complete once the request made them, even if the client went away.
+// It mirrors context.WithoutCancel of Go 1.21.
+func WithoutCancel(ctx context.Context) context.Context {
+	return withoutCancel{ctx}
+}
+
+type withoutCancel struct {
+	parent context.Context
+}
+
+func (withoutCancel) Deadline() (deadline time.Time, ok bool) { return }
+func (withoutCancel) Done() <-chan struct{}                   { return nil }
+func (withoutCancel) Err() error                              { return nil }
+
+func (c withoutCancel) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
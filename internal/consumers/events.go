@@ -0,0 +1,21 @@
+package consumers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/consuming"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+)
+
+// Events dispatches the events consumed from their topics to the subscribers of the bus.
+// A body that is not an event is dead-lettered.
+func Events(bus *events.Bus) consuming.Handler {
+	return func(ctx context.Context, body []byte) error {
+		err := bus.Consume(ctx, body)
+		if errors.Is(err, events.ErrMalformed) {
+			return consuming.Permanent(err)
+		}
+		return err
+	}
+}
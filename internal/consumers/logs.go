@@ -0,0 +1,84 @@
+// Package consumers handles the messages of the topics published by the API.
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/consuming"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+)
+
+// Logs writes the log entries of api_requests and emails_requests as JSON lines, in the
+// structured format Cloud Logging reads from the output of a container.
+type Logs struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+func NewLogs(w io.Writer) *Logs {
+	return &Logs{w: w, now: time.Now}
+}
+
+type logLine struct {
+	Time       time.Time `json:"time"`
+	Severity   string    `json:"severity"`
+	Message    string    `json:"message"`
+	Component  string    `json:"component,omitempty"`
+	Topic      string    `json:"topic,omitempty"`
+	RequestID  string    `json:"requestId,omitempty"`
+	Route      string    `json:"route,omitempty"`
+	UID        string    `json:"uid,omitempty"`
+	BusinessID string    `json:"businessId,omitempty"`
+	TraceID    string    `json:"traceId,omitempty"`
+	SpanID     string    `json:"spanId,omitempty"`
+	InsertID   string    `json:"logging.googleapis.com/insertId,omitempty"` // Cloud Logging drops the duplicates
+}
+
+// Handle writes the model.LogEntry of the body. A body that is not an entry is dead-lettered.
+func (l *Logs) Handle(ctx context.Context, body []byte) error {
+	var entry model.LogEntry
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return consuming.Permanent(err)
+	}
+	severity := strings.ToUpper(entry.Severity)
+	if len(severity) == 0 {
+		severity = "INFO"
+	}
+	line, err := json.Marshal(&logLine{
+		Time:       l.now(),
+		Severity:   severity,
+		Message:    entry.Message,
+		Component:  entry.Component,
+		Topic:      entry.Topic,
+		RequestID:  entry.RequestID,
+		Route:      entry.Route,
+		UID:        entry.UID,
+		BusinessID: entry.BusinessID,
+		TraceID:    entry.TraceID,
+		SpanID:     entry.SpanID,
+		InsertID:   entry.InsertID,
+	})
+	if err != nil {
+		return consuming.Permanent(err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.w.Write(append(line, '\n'))
+	return err
+}
+
+// UnwrapLogEntry continues the trace stamped on the log entry by tracing.StampLogEntry.
+func UnwrapLogEntry(ctx context.Context, body []byte) (context.Context, []byte) {
+	var entry model.LogEntry
+	if err := json.Unmarshal(body, &entry); err != nil || len(entry.TraceContext) == 0 {
+		return ctx, body
+	}
+	return tracing.Extract(ctx, entry.TraceContext), body
+}
@@ -0,0 +1,29 @@
+package consumers
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/consuming"
+)
+
+func TestLogs(t *testing.T) {
+	var buf bytes.Buffer
+	logs := NewLogs(&buf)
+	logs.now = func() time.Time { return time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	body := `{"topic":"emails_requests","severity":"info","message":"method: SendInvite","component":"emails_service","traceId":"abc","insertId":"e1","requestId":"r1","route":"/api/v1/businesses/{business_id}","uid":"u1","businessId":"b1"}`
+	if err := logs.Handle(context.Background(), []byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"time":"2023-01-02T03:04:05Z","severity":"INFO","message":"method: SendInvite","component":"emails_service","topic":"emails_requests","requestId":"r1","route":"/api/v1/businesses/{business_id}","uid":"u1","businessId":"b1","traceId":"abc","logging.googleapis.com/insertId":"e1"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %s, want %s", buf.String(), want)
+	}
+
+	if err := logs.Handle(context.Background(), []byte("not json")); !consuming.IsPermanent(err) {
+		t.Errorf("got %v, want a permanent error", err)
+	}
+}
@@ -0,0 +1,67 @@
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/consuming"
+	"github.com/VinothKuppanna/pigeon-go/internal/crm"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
+)
+
+// A SignUp is the message of signup_event, a hubspot.SignUp. The messages published before the
+// outbox were the body of the sign-up request, whose password is not decoded, so it cannot end
+// up in a log.
+type SignUp struct {
+	ID           string `json:"id"`
+	FullName     string `json:"fullName"`
+	BusinessName string `json:"businessName"`
+	Email        string `json:"email"`
+	UID          string `json:"uid"` // of the existing user signing up a business
+
+	TraceContext map[string]string `json:"traceContext"`
+}
+
+// UnwrapSignUp continues the trace of the request that signed up the business.
+func UnwrapSignUp(ctx context.Context, body []byte) (context.Context, []byte) {
+	var signUp SignUp
+	if err := json.Unmarshal(body, &signUp); err != nil || len(signUp.TraceContext) == 0 {
+		return ctx, body
+	}
+	return tracing.Extract(ctx, signUp.TraceContext), body
+}
+
+// SignUps handles the sign-ups of signup_event, unwrapped with UnwrapSignUp.
+type SignUps struct {
+	logger *log.Logger
+	syncer *crm.Syncer // nil if the HubSpot sync is disabled
+}
+
+func NewSignUps(logger *log.Logger, syncer *crm.Syncer) *SignUps {
+	return &SignUps{logger, syncer}
+}
+
+// Handle logs the sign-up and syncs it to HubSpot. A body that is not a sign-up, or a sign-up
+// HubSpot rejects, is dead-lettered. The sync is idempotent, so a sign-up delivered twice is
+// synced again.
+func (s *SignUps) Handle(ctx context.Context, body []byte) error {
+	var signUp SignUp
+	if err := json.Unmarshal(body, &signUp); err != nil {
+		return consuming.Permanent(err)
+	}
+	if len(signUp.BusinessName) == 0 || (len(signUp.Email) == 0 && len(signUp.UID) == 0) {
+		return consuming.Permanent(errors.New("sign-up without a business name, an email or a uid"))
+	}
+	s.logger.Printf("signed up business %q, id=%s email=%q uid=%q\n", signUp.BusinessName, signUp.ID, signUp.Email, signUp.UID)
+	if s.syncer == nil {
+		return nil
+	}
+	err := s.syncer.SignUp(ctx, signUp.UID, signUp.Email)
+	var apiErr *crm.APIError
+	if errors.Is(err, crm.ErrOwnerNotFound) || (errors.As(err, &apiErr) && !apiErr.Temporary()) {
+		return consuming.Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,227 @@
+// Package consuming consumes the NSQ topics published by the API. The handlers of a topic run
+// with a limited concurrency, the failed messages are retried with an exponential backoff,
+// and those failing every attempt are moved to the dead-letter topic.
+package consuming
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
+	"github.com/nsqio/go-nsq"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// A Handler handles the body of a message. The message is retried if it returns an error,
+// unless the error is Permanent.
+type Handler func(ctx context.Context, body []byte) error
+
+// An Unwrapper returns the payload of the body and ctx with the trace context of the
+// publisher, e.g. consumers.UnwrapLogEntry.
+type Unwrapper func(ctx context.Context, body []byte) (context.Context, []byte)
+
+// Options of the handling of a topic. The zero values take the defaults of the consumer.
+type Options struct {
+	Channel     string
+	Concurrency int           // of the handlers
+	MaxAttempts int           // before the message is dead-lettered
+	Backoff     time.Duration // of the first retry, doubled on each retry
+	MaxBackoff  time.Duration
+	Unwrap      Unwrapper // none if nil
+}
+
+func (o Options) withDefaults(defaults Options) Options {
+	if len(o.Channel) == 0 {
+		o.Channel = defaults.Channel
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaults.Concurrency
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = defaults.MaxAttempts
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = defaults.Backoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = defaults.MaxBackoff
+	}
+	return o
+}
+
+// backoff is the delay of the retry after the attempt, starting at 1.
+func (o Options) backoff(attempt int) time.Duration {
+	delay := o.Backoff
+	for i := 1; i < attempt && delay < o.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > o.MaxBackoff {
+		delay = o.MaxBackoff
+	}
+	return delay
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth a retry, e.g. for a malformed body. The message is
+// dead-lettered at once.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// IsPermanent reports whether err was marked by Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// DeadLetterTopic is the topic of the messages of topic that failed every attempt. They are
+// published as they were received, so they can be replayed to topic.
+func DeadLetterTopic(topic string) string {
+	return topic + ".dead_letter"
+}
+
+type subscription struct {
+	topic    string
+	options  Options
+	handler  Handler
+	consumer *nsq.Consumer
+	c        *Consumer
+}
+
+// A Consumer consumes the topics of its handlers.
+type Consumer struct {
+	defaults      Options
+	producer      *publishing.Producer
+	logger        *log.Logger
+	errors        *log.Logger
+	subscriptions []*subscription
+}
+
+// New returns a consumer publishing the dead letters with producer.
+func New(defaults Options, producer *publishing.Producer, logger *log.Logger, errors *log.Logger) *Consumer {
+	return &Consumer{defaults: defaults, producer: producer, logger: logger, errors: errors}
+}
+
+// Handle registers the handler of the topic. It must be called before Connect.
+func (c *Consumer) Handle(topic string, options Options, handler Handler) {
+	c.subscriptions = append(c.subscriptions, &subscription{
+		topic:   topic,
+		options: options.withDefaults(c.defaults),
+		handler: handler,
+		c:       c,
+	})
+}
+
+// Connect starts consuming the topics from the nsqlookupd, or from the nsqd if there are none.
+func (c *Consumer) Connect(nsqdAddress string, lookupdAddresses []string) error {
+	for _, s := range c.subscriptions {
+		config := nsq.NewConfig()
+		config.MaxInFlight = s.options.Concurrency
+		config.MaxAttempts = 0 // the handler dead-letters the messages
+		config.MaxRequeueDelay = s.options.MaxBackoff
+		consumer, err := nsq.NewConsumer(s.topic, s.options.Channel, config)
+		if err != nil {
+			return fmt.Errorf("topic %s: %w", s.topic, err)
+		}
+		consumer.SetLogger(c.errors, nsq.LogLevelWarning)
+		consumer.AddConcurrentHandlers(s, s.options.Concurrency)
+		s.consumer = consumer
+		if len(lookupdAddresses) > 0 {
+			err = consumer.ConnectToNSQLookupds(lookupdAddresses)
+		} else {
+			err = consumer.ConnectToNSQD(nsqdAddress)
+		}
+		if err != nil {
+			return fmt.Errorf("topic %s: %w", s.topic, err)
+		}
+		c.logger.Printf("consuming %s on channel %s, concurrency %d\n", s.topic, s.options.Channel, s.options.Concurrency)
+	}
+	return nil
+}
+
+// Stop stops receiving messages and waits for the handlers to finish the messages in flight,
+// or for ctx to be done. It returns the error of ctx if messages were still in flight.
+func (c *Consumer) Stop(ctx context.Context) error {
+	for _, s := range c.subscriptions {
+		if s.consumer != nil {
+			s.consumer.Stop()
+		}
+	}
+	for _, s := range c.subscriptions {
+		if s.consumer == nil {
+			continue
+		}
+		select {
+		case <-s.consumer.StopChan:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// HandleMessage handles the message, and finishes, requeues or dead-letters it. It never
+// returns an error, as it responds to nsqd itself.
+func (s *subscription) HandleMessage(message *nsq.Message) error {
+	message.DisableAutoResponse()
+	attempt := int(message.Attempts)
+
+	ctx, body := context.Background(), message.Body
+	if s.options.Unwrap != nil {
+		ctx, body = s.options.Unwrap(ctx, body)
+	}
+	ctx, span := tracing.Start(ctx, "nsq.consume "+s.topic,
+		attribute.String("messaging.destination", s.topic),
+		attribute.String("messaging.nsq.channel", s.options.Channel),
+		attribute.Int("messaging.nsq.attempt", attempt))
+	err := s.call(ctx, body)
+	tracing.End(span, err)
+
+	switch {
+	case err == nil:
+		message.Finish()
+	case IsPermanent(err) || attempt >= s.options.MaxAttempts:
+		s.deadLetter(message, attempt, err)
+	default:
+		delay := s.options.backoff(attempt)
+		s.c.errors.Printf("failed to handle message, topic=%s, attempt=%d, retry in %v. error: %v\n", s.topic, attempt, delay, err)
+		message.RequeueWithoutBackoff(delay)
+	}
+	return nil
+}
+
+// call calls the handler, turning a panic into an error.
+func (s *subscription) call(ctx context.Context, body []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return s.handler(ctx, body)
+}
+
+// deadLetter moves the message to the dead-letter topic. The message is retried if it cannot
+// be published, so it is not lost.
+func (s *subscription) deadLetter(message *nsq.Message, attempt int, err error) {
+	topic := DeadLetterTopic(s.topic)
+	if publishErr := s.c.producer.Publish(topic, message.Body); publishErr != nil {
+		s.c.errors.Printf("failed to dead-letter message, topic=%s. error: %v\n", s.topic, publishErr)
+		message.RequeueWithoutBackoff(s.options.MaxBackoff)
+		return
+	}
+	s.c.errors.Printf("dead-lettered message, topic=%s, attempt=%d. error: %v\n", s.topic, attempt, err)
+	message.Finish()
+}
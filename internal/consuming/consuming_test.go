@@ -0,0 +1,88 @@
+package consuming
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"github.com/nsqio/go-nsq"
+)
+
+// delegate records the response to nsqd.
+type delegate struct {
+	finished bool
+	requeued time.Duration
+}
+
+func (d *delegate) OnFinish(*nsq.Message)                                 { d.finished = true }
+func (d *delegate) OnRequeue(_ *nsq.Message, delay time.Duration, _ bool) { d.requeued = delay }
+func (d *delegate) OnTouch(*nsq.Message)                                  {}
+
+func newSubscription(handler Handler, sink io.Writer) *subscription {
+	discard := log.New(io.Discard, "", 0)
+	consumer := New(Options{Channel: "test", Concurrency: 1, MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 3 * time.Second},
+		publishing.NewSink(sink), discard, discard)
+	consumer.Handle("api_requests", Options{}, handler)
+	return consumer.subscriptions[0]
+}
+
+func handle(s *subscription, attempt uint16) *delegate {
+	d := &delegate{requeued: -1}
+	message := nsq.NewMessage(nsq.MessageID{}, []byte(`{"message":"hi"}`))
+	message.Delegate = d
+	message.Attempts = attempt
+	_ = s.HandleMessage(message)
+	return d
+}
+
+func TestHandleMessage(t *testing.T) {
+	failure := errors.New("down")
+	tests := []struct {
+		name       string
+		err        error
+		panics     bool
+		attempt    uint16
+		finished   bool
+		requeued   time.Duration
+		deadLetter bool
+	}{
+		{name: "ok", attempt: 1, finished: true, requeued: -1},
+		{name: "retried", err: failure, attempt: 1, requeued: time.Second},
+		{name: "backoff doubled", err: failure, attempt: 2, requeued: 2 * time.Second},
+		{name: "panic retried", panics: true, attempt: 1, requeued: time.Second},
+		{name: "last attempt", err: failure, attempt: 3, finished: true, requeued: -1, deadLetter: true},
+		{name: "permanent", err: Permanent(failure), attempt: 1, finished: true, requeued: -1, deadLetter: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sink bytes.Buffer
+			d := handle(newSubscription(func(ctx context.Context, body []byte) error {
+				if test.panics {
+					panic("boom")
+				}
+				return test.err
+			}, &sink), test.attempt)
+			if d.finished != test.finished || d.requeued != test.requeued {
+				t.Errorf("finished %v, requeued %v; want %v, %v", d.finished, d.requeued, test.finished, test.requeued)
+			}
+			if deadLetter := strings.Contains(sink.String(), `"topic":"api_requests.dead_letter"`); deadLetter != test.deadLetter {
+				t.Errorf("dead-lettered %v, want %v: %s", deadLetter, test.deadLetter, sink.String())
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	options := Options{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 40: 5 * time.Second} {
+		if got := options.backoff(attempt); got != want {
+			t.Errorf("attempt %d: got %v, want %v", attempt, got, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Package crm syncs the business owners signing up to HubSpot, as a contact and a company,
+// and keeps the company up to date with the name and the category of the business.
+package crm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+)
+
+// Properties are the properties of a HubSpot object, by internal name.
+type Properties map[string]string
+
+// An APIError is a response of HubSpot with an error status.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("hubspot: %d %s: %s", e.Status, http.StatusText(e.Status), e.Message)
+}
+
+// Temporary reports whether the request may succeed if retried later.
+func (e *APIError) Temporary() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= http.StatusInternalServerError
+}
+
+// A Client calls the CRM API of HubSpot with the OAuth credentials of the config. The access
+// token is refreshed with the refresh token when it expires. The requests rate limited by
+// HubSpot, or failing on its side, are retried with a backoff.
+type Client struct {
+	config      configs.Hubspot
+	http        *http.Client
+	maxAttempts int
+	backoff     time.Duration // of the first retry, doubled on each retry
+	now         func() time.Time
+
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+func NewClient(config configs.Hubspot, httpClient *http.Client) *Client {
+	return &Client{
+		config:      config,
+		http:        httpClient,
+		maxAttempts: 4,
+		backoff:     time.Second,
+		now:         time.Now,
+	}
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"` // seconds
+}
+
+// token returns the access token, refreshed if it expires within a minute.
+func (c *Client) token(ctx context.Context, refresh bool) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !refresh && len(c.accessToken) > 0 && c.now().Add(time.Minute).Before(c.expiry) {
+		return c.accessToken, nil
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {c.config.ClientID},
+		"client_secret": {c.config.ClientSecret},
+		"refresh_token": {c.config.RefreshToken},
+	}
+	var response tokenResponse
+	err := c.send(ctx, http.MethodPost, "/oauth/v1/token", "application/x-www-form-urlencoded",
+		func() io.Reader { return strings.NewReader(form.Encode()) }, "", &response)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh the access token: %w", err)
+	}
+	c.accessToken = response.AccessToken
+	c.expiry = c.now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	return c.accessToken, nil
+}
+
+// do sends the JSON of in, if any, and decodes the response into out, if any. The token is
+// refreshed once if HubSpot rejects it.
+func (c *Client) do(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	newBody := func() io.Reader { return bytes.NewReader(body) }
+	for refresh := false; ; refresh = true {
+		token, err := c.token(ctx, refresh)
+		if err != nil {
+			return err
+		}
+		err = c.send(ctx, method, path, "application/json", newBody, token, out)
+		if apiErr, ok := err.(*APIError); ok && apiErr.Status == http.StatusUnauthorized && !refresh {
+			continue
+		}
+		return err
+	}
+}
+
+// send sends the request, retrying it while it fails with a temporary error. The delay of
+// the Retry-After header is used if HubSpot sends one.
+func (c *Client) send(ctx context.Context, method string, path string, contentType string,
+	newBody func() io.Reader, token string, out interface{}) error {
+	delay := c.backoff
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.config.ApiURL, "/")+path, newBody())
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", "application/json")
+		if len(token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode < 300 {
+			if out == nil || len(data) == 0 {
+				return nil
+			}
+			return json.Unmarshal(data, out)
+		}
+
+		apiErr := &APIError{Status: resp.StatusCode, Message: errorMessage(data)}
+		if !apiErr.Temporary() || attempt >= c.maxAttempts {
+			return apiErr
+		}
+		wait := delay
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			wait = time.Duration(seconds) * time.Second
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// errorMessage returns the message of the error body of HubSpot, or the body itself.
+func errorMessage(data []byte) string {
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil && len(body.Message) > 0 {
+		return body.Message
+	}
+	return strings.TrimSpace(string(data))
+}
+
+type object struct {
+	ID         string     `json:"id,omitempty"`
+	Properties Properties `json:"properties"`
+}
+
+type filter struct {
+	PropertyName string `json:"propertyName"`
+	Operator     string `json:"operator"`
+	Value        string `json:"value"`
+}
+
+type searchRequest struct {
+	FilterGroups []struct {
+		Filters []filter `json:"filters"`
+	} `json:"filterGroups"`
+	Limit int `json:"limit"`
+}
+
+// FindContact returns the ID of the contact with the email, or "" if there is none.
+func (c *Client) FindContact(ctx context.Context, email string) (string, error) {
+	request := searchRequest{Limit: 1}
+	request.FilterGroups = make([]struct {
+		Filters []filter `json:"filters"`
+	}, 1)
+	request.FilterGroups[0].Filters = []filter{{PropertyName: "email", Operator: "EQ", Value: email}}
+	var response struct {
+		Results []object `json:"results"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/crm/v3/objects/contacts/search", &request, &response); err != nil {
+		return "", err
+	}
+	if len(response.Results) == 0 {
+		return "", nil
+	}
+	return response.Results[0].ID, nil
+}
+
+// Create creates an object of the type, e.g. contacts or companies, and returns its ID.
+func (c *Client) Create(ctx context.Context, objectType string, properties Properties) (string, error) {
+	var created object
+	if err := c.do(ctx, http.MethodPost, "/crm/v3/objects/"+objectType, &object{Properties: properties}, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// Update updates the properties of the object of the type.
+func (c *Client) Update(ctx context.Context, objectType string, id string, properties Properties) error {
+	return c.do(ctx, http.MethodPatch, "/crm/v3/objects/"+objectType+"/"+url.PathEscape(id), &object{Properties: properties}, nil)
+}
+
+// Associate associates the contact with the company, with the default association.
+func (c *Client) Associate(ctx context.Context, contactID string, companyID string) error {
+	path := fmt.Sprintf("/crm/v4/objects/contact/%s/associations/default/company/%s", url.PathEscape(contactID), url.PathEscape(companyID))
+	return c.do(ctx, http.MethodPut, path, nil, nil)
+}
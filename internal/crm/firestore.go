@@ -0,0 +1,178 @@
+package crm
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreStore reads the owners from the users, and keeps the records in hubspotSync and the
+// lease of the watch in hubspotSyncLeases.
+type FirestoreStore struct {
+	db *db.Firestore
+}
+
+func NewFirestoreStore(db *db.Firestore) *FirestoreStore {
+	return &FirestoreStore{db}
+}
+
+type user struct {
+	FullName string `firestore:"fullName"`
+	Email    string `firestore:"email"`
+	Business struct {
+		ID   string `firestore:"id"`
+		Name string `firestore:"name"`
+	} `firestore:"business"`
+}
+
+type business struct {
+	Name     string `firestore:"name"`
+	Category *struct {
+		Name string `firestore:"name"`
+	} `firestore:"businessCategory"`
+}
+
+func (b *business) category() string {
+	if b.Category == nil {
+		return ""
+	}
+	return b.Category.Name
+}
+
+func (s *FirestoreStore) FindOwner(ctx context.Context, uid string, email string) (*Owner, error) {
+	var snapshot *firestore.DocumentSnapshot
+	var err error
+	if len(uid) > 0 {
+		snapshot, err = s.db.User(uid).Get(ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrOwnerNotFound
+		}
+	} else {
+		snapshot, err = s.db.Collection(db.Users).Where("email", "==", email).Limit(1).Documents(ctx).Next()
+		if err == iterator.Done {
+			return nil, ErrOwnerNotFound
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var u user
+	if err = snapshot.DataTo(&u); err != nil {
+		return nil, err
+	}
+	if len(u.Business.ID) == 0 {
+		return nil, ErrOwnerNotFound
+	}
+
+	owner := &Owner{UID: snapshot.Ref.ID, FullName: u.FullName, Email: u.Email,
+		Business: Business{ID: u.Business.ID, Name: u.Business.Name}}
+	snapshot, err = s.db.Business(u.Business.ID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrOwnerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var b business
+	if err = snapshot.DataTo(&b); err != nil {
+		return nil, err
+	}
+	owner.Business.Name, owner.Business.Category = b.Name, b.category()
+	return owner, nil
+}
+
+func (s *FirestoreStore) FindRecord(ctx context.Context, businessID string) (*Record, error) {
+	snapshot, err := s.db.HubspotSync(businessID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record Record
+	if err = snapshot.DataTo(&record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *FirestoreStore) SaveRecord(ctx context.Context, businessID string, record *Record) error {
+	_, err := s.db.HubspotSync(businessID).Set(ctx, record)
+	return err
+}
+
+func (s *FirestoreStore) WatchBusinesses(ctx context.Context, fn func(ctx context.Context, business Business) error) error {
+	snapshots := s.db.Businesses().Snapshots(ctx)
+	defer snapshots.Stop()
+	for {
+		snapshot, err := snapshots.Next()
+		if err != nil {
+			return err
+		}
+		for _, change := range snapshot.Changes {
+			if change.Kind == firestore.DocumentRemoved {
+				continue
+			}
+			var b business
+			if err = change.Doc.DataTo(&b); err != nil {
+				continue
+			}
+			if err = fn(ctx, Business{ID: change.Doc.Ref.ID, Name: b.Name, Category: b.category()}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+const watchLease = "watch"
+
+type lease struct {
+	Holder  string    `firestore:"holder"`
+	Expires time.Time `firestore:"expires"`
+}
+
+func (s *FirestoreStore) AcquireLease(ctx context.Context, holder string, now time.Time, ttl time.Duration) (bool, error) {
+	doc := s.db.HubspotSyncLease(watchLease)
+	var held bool
+	err := s.db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		held = false
+		snapshot, err := tx.Get(doc)
+		if err == nil {
+			var current lease
+			if err = snapshot.DataTo(&current); err != nil {
+				return err
+			}
+			if current.Holder != holder && now.Before(current.Expires) {
+				return nil
+			}
+		} else if status.Code(err) != codes.NotFound {
+			return err
+		}
+		held = true
+		return tx.Set(doc, &lease{Holder: holder, Expires: now.Add(ttl)})
+	})
+	return held, err
+}
+
+func (s *FirestoreStore) ReleaseLease(ctx context.Context, holder string) error {
+	doc := s.db.HubspotSyncLease(watchLease)
+	return s.db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snapshot, err := tx.Get(doc)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var current lease
+		if err = snapshot.DataTo(&current); err != nil || current.Holder != holder {
+			return err
+		}
+		return tx.Delete(doc)
+	})
+}
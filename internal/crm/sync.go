@@ -0,0 +1,273 @@
+package crm
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/outbox"
+)
+
+// An Owner is the user who signed up a business.
+type Owner struct {
+	UID      string
+	FullName string
+	Email    string
+	Business Business
+}
+
+// A Business is the part of a business synced to its company.
+type Business struct {
+	ID       string
+	Name     string
+	Category string // the name of the category
+}
+
+// A Record is the state of the sync of a business: the objects made for it in HubSpot, and
+// the values last synced to the company.
+type Record struct {
+	ContactID  string    `firestore:"contactId"`
+	CompanyID  string    `firestore:"companyId"`
+	Name       string    `firestore:"name"`
+	Category   string    `firestore:"category"`
+	SyncedDate time.Time `firestore:"syncedDate"`
+}
+
+// ErrOwnerNotFound is returned by Store.FindOwner if there is no such user, or no business.
+var ErrOwnerNotFound = errors.New("owner not found")
+
+// A Store reads the owners and the businesses, and keeps the records of the sync.
+type Store interface {
+	// FindOwner finds the user by uid, or by email if uid is empty.
+	FindOwner(ctx context.Context, uid string, email string) (*Owner, error)
+	// FindRecord returns nil if the business was not synced.
+	FindRecord(ctx context.Context, businessID string) (*Record, error)
+	SaveRecord(ctx context.Context, businessID string, record *Record) error
+	// WatchBusinesses calls fn with every business, and then with every change of a business,
+	// until ctx is done or the watch fails.
+	WatchBusinesses(ctx context.Context, fn func(ctx context.Context, business Business) error) error
+	// AcquireLease takes or renews the lease of the watch until now+ttl, unless another holder
+	// has it, and reports whether holder has it.
+	AcquireLease(ctx context.Context, holder string, now time.Time, ttl time.Duration) (bool, error)
+	// ReleaseLease gives the lease up if holder has it.
+	ReleaseLease(ctx context.Context, holder string) error
+}
+
+// A Syncer syncs the owners of the businesses and their businesses to HubSpot. The workers all
+// run its watch, and a lease in the store lets a single one watch at a time, so a change of a
+// business updates its company once.
+type Syncer struct {
+	client           *Client
+	store            Store
+	categoryProperty string // of the companies, none if empty
+	leaseTTL         time.Duration
+	holder           string // of the lease
+	retryDelay       time.Duration
+	logger           *log.Logger
+	errors           *log.Logger
+	now              func() time.Time
+
+	mu      sync.Mutex          // serializes the updates of the watch
+	pending map[string]Business // the failed updates, by business ID
+}
+
+func NewSyncer(client *Client, store Store, config configs.Hubspot, logger *log.Logger, errors *log.Logger) *Syncer {
+	return &Syncer{client: client, store: store, categoryProperty: config.CategoryProperty, leaseTTL: config.LeaseTTL,
+		holder: outbox.NewID(), retryDelay: 10 * time.Second, logger: logger, errors: errors, now: time.Now}
+}
+
+func (s *Syncer) companyProperties(business Business) Properties {
+	properties := Properties{"name": business.Name}
+	if len(s.categoryProperty) > 0 {
+		properties[s.categoryProperty] = business.Category
+	}
+	return properties
+}
+
+// SignUp upserts the contact of the owner and the company of the business, and associates
+// them. The record is saved after each step, so a retry does not create the company twice.
+func (s *Syncer) SignUp(ctx context.Context, uid string, email string) error {
+	owner, err := s.store.FindOwner(ctx, uid, email)
+	if err != nil {
+		return err
+	}
+	business := owner.Business
+	record, err := s.store.FindRecord(ctx, business.ID)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		record = &Record{}
+	}
+
+	firstName, lastName := splitName(owner.FullName)
+	contact := Properties{"email": owner.Email, "firstname": firstName, "lastname": lastName, "company": business.Name}
+	if len(record.ContactID) == 0 {
+		if record.ContactID, err = s.client.FindContact(ctx, owner.Email); err != nil {
+			return err
+		}
+	}
+	if len(record.ContactID) > 0 {
+		err = s.client.Update(ctx, "contacts", record.ContactID, contact)
+	} else {
+		record.ContactID, err = s.client.Create(ctx, "contacts", contact)
+	}
+	if err != nil {
+		return err
+	}
+	if err = s.store.SaveRecord(ctx, business.ID, record); err != nil {
+		return err
+	}
+
+	if len(record.CompanyID) > 0 {
+		err = s.client.Update(ctx, "companies", record.CompanyID, s.companyProperties(business))
+	} else {
+		record.CompanyID, err = s.client.Create(ctx, "companies", s.companyProperties(business))
+	}
+	if err != nil {
+		return err
+	}
+	record.Name, record.Category, record.SyncedDate = business.Name, business.Category, time.Now()
+	if err = s.store.SaveRecord(ctx, business.ID, record); err != nil {
+		return err
+	}
+
+	if err = s.client.Associate(ctx, record.ContactID, record.CompanyID); err != nil {
+		return err
+	}
+	s.logger.Printf("synced business %s to hubspot, contact=%s company=%s\n", business.ID, record.ContactID, record.CompanyID)
+	return nil
+}
+
+// BusinessChanged updates the company of the business if its name or category changed since
+// the last sync. The businesses that were not synced are skipped.
+func (s *Syncer) BusinessChanged(ctx context.Context, business Business) error {
+	record, err := s.store.FindRecord(ctx, business.ID)
+	if err != nil || record == nil || len(record.CompanyID) == 0 {
+		return err
+	}
+	if record.Name == business.Name && record.Category == business.Category {
+		return nil
+	}
+	if err = s.client.Update(ctx, "companies", record.CompanyID, s.companyProperties(business)); err != nil {
+		return err
+	}
+	record.Name, record.Category, record.SyncedDate = business.Name, business.Category, time.Now()
+	if err = s.store.SaveRecord(ctx, business.ID, record); err != nil {
+		return err
+	}
+	s.logger.Printf("updated company %s of business %s in hubspot\n", record.CompanyID, business.ID)
+	return nil
+}
+
+// Watch updates the companies on the changes of the businesses until ctx is done, while it
+// holds the lease. The changes made while no worker watched are synced when the watch starts.
+// A failed watch is restarted, and the failed updates are retried while the lease is renewed.
+func (s *Syncer) Watch(ctx context.Context) {
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(common.WithoutCancel(ctx), s.retryDelay)
+		defer cancel()
+		if err := s.store.ReleaseLease(releaseCtx, s.holder); err != nil {
+			s.errors.Printf("failed to release the lease of the watch of the businesses. error: %v\n", err)
+		}
+	}()
+	for {
+		held, err := s.store.AcquireLease(ctx, s.holder, s.now(), s.leaseTTL)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.errors.Printf("failed to acquire the lease of the watch of the businesses. error: %v\n", err)
+		} else if held {
+			err = s.watch(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			s.errors.Printf("the watch of the businesses stopped, restarting in %v. error: %v\n", s.retryDelay, err)
+		}
+		select {
+		case <-time.After(s.retryDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watch runs the watch of the businesses while the lease is renewed, every third of its TTL.
+// The failed updates are retried on each renewal. It returns when the watch fails or the lease
+// is lost, and the updates still failing are left to the next watch, which sees every business.
+func (s *Syncer) watch(ctx context.Context) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.mu.Lock()
+	s.pending = map[string]Business{}
+	s.mu.Unlock()
+
+	renewed := make(chan error, 1)
+	go func() {
+		ticker := time.NewTicker(s.leaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-watchCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			held, err := s.store.AcquireLease(watchCtx, s.holder, s.now(), s.leaseTTL)
+			if err == nil && !held {
+				err = errors.New("the lease was taken by another worker")
+			}
+			if err != nil {
+				renewed <- err
+				cancel()
+				return
+			}
+			s.retryPending(watchCtx)
+		}
+	}()
+
+	err := s.store.WatchBusinesses(watchCtx, func(ctx context.Context, business Business) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.pending, business.ID)
+		if err := s.BusinessChanged(ctx, business); err != nil && ctx.Err() == nil {
+			s.errors.Printf("failed to sync business %s to hubspot, retrying. error: %v\n", business.ID, err)
+			s.pending[business.ID] = business
+		}
+		return nil
+	})
+	select {
+	case err = <-renewed:
+	default:
+	}
+	return err
+}
+
+// retryPending retries the failed updates. A business changed again in the meantime is updated
+// by the watch instead, with its last values.
+func (s *Syncer) retryPending(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, business := range s.pending {
+		if err := s.BusinessChanged(ctx, business); err != nil {
+			if ctx.Err() == nil {
+				s.errors.Printf("failed to sync business %s to hubspot, retrying. error: %v\n", id, err)
+			}
+			continue
+		}
+		delete(s.pending, id)
+	}
+}
+
+func splitName(fullName string) (first string, last string) {
+	fields := strings.Fields(fullName)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	return fields[0], strings.Join(fields[1:], " ")
+}
@@ -0,0 +1,351 @@
+package crm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+)
+
+// stub is a HubSpot serving the calls of the client from memory.
+type stub struct {
+	mu           sync.Mutex
+	tokens       int
+	rateLimited  int // the next requests answered with 429
+	expireTokens bool
+	objects      map[string]Properties // by type/id
+	associations []string
+	calls        []string
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, r.Method+" "+r.URL.Path)
+	if r.URL.Path == "/oauth/v1/token" {
+		if r.FormValue("refresh_token") != "refresh" {
+			http.Error(w, `{"message":"bad refresh token"}`, http.StatusBadRequest)
+			return
+		}
+		s.tokens++
+		_ = json.NewEncoder(w).Encode(&tokenResponse{AccessToken: fmt.Sprint("token-", s.tokens), ExpiresIn: 1800})
+		return
+	}
+	if r.Header.Get("Authorization") != fmt.Sprint("Bearer token-", s.tokens) || s.expireTokens {
+		s.expireTokens = false
+		http.Error(w, `{"message":"expired"}`, http.StatusUnauthorized)
+		return
+	}
+	if s.rateLimited > 0 {
+		s.rateLimited--
+		http.Error(w, `{"message":"secondly limit"}`, http.StatusTooManyRequests)
+		return
+	}
+
+	var body object
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/crm/"), "/")
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 4 && parts[3] == "search":
+		var results []object
+		for key, properties := range s.objects {
+			if strings.HasPrefix(key, "contacts/") && properties["email"] == "owner@example.com" {
+				results = append(results, object{ID: strings.TrimPrefix(key, "contacts/")})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	case r.Method == http.MethodPost && len(parts) == 3:
+		id := fmt.Sprint(len(s.objects) + 1)
+		s.objects[parts[2]+"/"+id] = body.Properties
+		_ = json.NewEncoder(w).Encode(&object{ID: id, Properties: body.Properties})
+	case r.Method == http.MethodPatch && len(parts) == 4:
+		if _, ok := s.objects[parts[2]+"/"+parts[3]]; !ok {
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			return
+		}
+		s.objects[parts[2]+"/"+parts[3]] = body.Properties
+		_ = json.NewEncoder(w).Encode(&object{ID: parts[3]})
+	case r.Method == http.MethodPut && parts[0] == "v4":
+		s.associations = append(s.associations, parts[3]+"-"+parts[7])
+	default:
+		http.Error(w, `{"message":"unexpected call"}`, http.StatusBadRequest)
+	}
+}
+
+type memoryStore struct {
+	owner   *Owner
+	records map[string]*Record
+	changes chan Business // sent to the watch, not watched if nil
+
+	mu       sync.Mutex
+	lease    lease
+	watching int // the watches running
+	watched  int // the most watches running at a time
+}
+
+func (m *memoryStore) FindOwner(ctx context.Context, uid string, email string) (*Owner, error) {
+	if m.owner == nil || (uid != m.owner.UID && email != m.owner.Email) {
+		return nil, ErrOwnerNotFound
+	}
+	return m.owner, nil
+}
+
+func (m *memoryStore) FindRecord(ctx context.Context, businessID string) (*Record, error) {
+	if record, ok := m.records[businessID]; ok {
+		copied := *record
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) SaveRecord(ctx context.Context, businessID string, record *Record) error {
+	copied := *record
+	m.records[businessID] = &copied
+	return nil
+}
+
+func (m *memoryStore) WatchBusinesses(ctx context.Context, fn func(ctx context.Context, business Business) error) error {
+	if m.changes == nil {
+		return errors.New("not watched")
+	}
+	m.mu.Lock()
+	m.watching++
+	if m.watching > m.watched {
+		m.watched = m.watching
+	}
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.watching--
+		m.mu.Unlock()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case business := <-m.changes:
+			if err := fn(ctx, business); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (m *memoryStore) AcquireLease(ctx context.Context, holder string, now time.Time, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lease.Holder != holder && now.Before(m.lease.Expires) {
+		return false, nil
+	}
+	m.lease = lease{Holder: holder, Expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (m *memoryStore) ReleaseLease(ctx context.Context, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lease.Holder == holder {
+		m.lease = lease{}
+	}
+	return nil
+}
+
+func (m *memoryStore) holder() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lease.Holder
+}
+
+// eventually fails the test if condition is not true within a second.
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !condition(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func newSyncer(t *testing.T) (*Syncer, *stub, *memoryStore) {
+	hubspot := &stub{objects: map[string]Properties{}}
+	server := httptest.NewServer(hubspot)
+	t.Cleanup(server.Close)
+	client := NewClient(configs.Hubspot{ApiURL: server.URL, ClientID: "id", ClientSecret: "secret", RefreshToken: "refresh"}, server.Client())
+	client.backoff = time.Millisecond
+	store := &memoryStore{
+		owner: &Owner{UID: "u1", FullName: "Ada King Lovelace", Email: "owner@example.com",
+			Business: Business{ID: "b1", Name: "Pigeon Post", Category: "Delivery"}},
+		records: map[string]*Record{},
+	}
+	discard := log.New(io.Discard, "", 0)
+	return NewSyncer(client, store, configs.Hubspot{CategoryProperty: "business_category", LeaseTTL: 30 * time.Second}, discard, discard), hubspot, store
+}
+
+func TestSignUp(t *testing.T) {
+	syncer, hubspot, store := newSyncer(t)
+	hubspot.rateLimited = 2
+	ctx := context.Background()
+
+	if err := syncer.SignUp(ctx, "", "owner@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	record := store.records["b1"]
+	if record == nil || len(record.ContactID) == 0 || len(record.CompanyID) == 0 || record.Name != "Pigeon Post" {
+		t.Fatalf("record: %+v", record)
+	}
+	contact := hubspot.objects["contacts/"+record.ContactID]
+	if contact["firstname"] != "Ada" || contact["lastname"] != "King Lovelace" || contact["company"] != "Pigeon Post" {
+		t.Errorf("contact: %v", contact)
+	}
+	if company := hubspot.objects["companies/"+record.CompanyID]; company["name"] != "Pigeon Post" || company["business_category"] != "Delivery" {
+		t.Errorf("company: %v", company)
+	}
+	if want := []string{record.ContactID + "-" + record.CompanyID}; fmt.Sprint(hubspot.associations) != fmt.Sprint(want) {
+		t.Errorf("associations: %v, want %v", hubspot.associations, want)
+	}
+	if hubspot.tokens != 1 {
+		t.Errorf("refreshed the token %d times, want once", hubspot.tokens)
+	}
+
+	// a redelivered sign-up updates the objects instead of creating them again
+	hubspot.expireTokens = true
+	if err := syncer.SignUp(ctx, "u1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(hubspot.objects) != 2 {
+		t.Errorf("objects after the retry: %v", hubspot.objects)
+	}
+	if hubspot.tokens != 2 {
+		t.Errorf("expired token refreshed %d times, want once", hubspot.tokens-1)
+	}
+
+	if err := syncer.SignUp(ctx, "", "nobody@example.com"); !errors.Is(err, ErrOwnerNotFound) {
+		t.Errorf("unknown owner: got %v", err)
+	}
+}
+
+func TestBusinessChanged(t *testing.T) {
+	syncer, hubspot, store := newSyncer(t)
+	ctx := context.Background()
+	if err := syncer.BusinessChanged(ctx, Business{ID: "b2", Name: "Not synced"}); err != nil || len(hubspot.calls) > 0 {
+		t.Fatalf("business not synced: err %v, calls %v", err, hubspot.calls)
+	}
+	if err := syncer.SignUp(ctx, "u1", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := len(hubspot.calls)
+	if err := syncer.BusinessChanged(ctx, Business{ID: "b1", Name: "Pigeon Post", Category: "Delivery"}); err != nil || len(hubspot.calls) != calls {
+		t.Fatalf("unchanged business: err %v, calls %v", err, hubspot.calls[calls:])
+	}
+	if err := syncer.BusinessChanged(ctx, Business{ID: "b1", Name: "Pigeon Express", Category: "Logistics"}); err != nil {
+		t.Fatal(err)
+	}
+	record := store.records["b1"]
+	if company := hubspot.objects["companies/"+record.CompanyID]; company["name"] != "Pigeon Express" || company["business_category"] != "Logistics" {
+		t.Errorf("company: %v", company)
+	}
+	if record.Name != "Pigeon Express" || record.Category != "Logistics" {
+		t.Errorf("record: %+v", record)
+	}
+}
+
+func TestWatchLease(t *testing.T) {
+	first, _, store := newSyncer(t)
+	store.changes = make(chan Business)
+	second := NewSyncer(first.client, store, configs.Hubspot{LeaseTTL: 30 * time.Millisecond}, first.logger, first.errors)
+	first.leaseTTL, first.retryDelay, second.retryDelay = 30*time.Millisecond, 5*time.Millisecond, 5*time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		first.Watch(ctx)
+	}()
+	eventually(t, "the first watch", func() bool { return store.holder() == first.holder })
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+	secondDone := make(chan struct{})
+	go func() {
+		defer close(secondDone)
+		second.Watch(secondCtx)
+	}()
+	time.Sleep(100 * time.Millisecond) // a few renewals of the lease
+	if holder := store.holder(); holder != first.holder {
+		t.Errorf("holder: %q, want the first watch", holder)
+	}
+
+	cancel()
+	<-firstDone
+	eventually(t, "the second watch", func() bool { return store.holder() == second.holder })
+	cancelSecond()
+	<-secondDone
+	if store.holder() != "" {
+		t.Errorf("lease not released: %q", store.holder())
+	}
+	if store.watched != 1 {
+		t.Errorf("%d watches at a time, want 1", store.watched)
+	}
+}
+
+func TestWatchRetriesFailedUpdates(t *testing.T) {
+	syncer, hubspot, store := newSyncer(t)
+	syncer.leaseTTL = 30 * time.Millisecond
+	store.changes = make(chan Business)
+	store.records["b1"] = &Record{CompanyID: "7", Name: "Pigeon Post"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		syncer.Watch(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	// the company is missing, so the update fails until it is back
+	store.changes <- Business{ID: "b1", Name: "Pigeon Express"}
+	company := func() Properties {
+		hubspot.mu.Lock()
+		defer hubspot.mu.Unlock()
+		return hubspot.objects["companies/7"]
+	}
+	eventually(t, "the failed update", func() bool {
+		hubspot.mu.Lock()
+		defer hubspot.mu.Unlock()
+		return len(hubspot.calls) > 0 && hubspot.calls[len(hubspot.calls)-1] == "PATCH /crm/v3/objects/companies/7"
+	})
+	hubspot.mu.Lock()
+	hubspot.objects["companies/7"] = Properties{"name": "Pigeon Post"}
+	hubspot.mu.Unlock()
+	eventually(t, "the retried update", func() bool { return company()["name"] == "Pigeon Express" })
+}
+
+func TestClientErrors(t *testing.T) {
+	syncer, hubspot, _ := newSyncer(t)
+	hubspot.rateLimited = 10
+	_, err := syncer.client.Create(context.Background(), "companies", Properties{"name": "x"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusTooManyRequests || apiErr.Message != "secondly limit" {
+		t.Errorf("rate limited: got %v", err)
+	}
+	if hubspot.rateLimited != 10-syncer.client.maxAttempts {
+		t.Errorf("attempts: %d, want %d", 10-hubspot.rateLimited, syncer.client.maxAttempts)
+	}
+
+	hubspot.rateLimited = 0
+	err = syncer.client.Update(context.Background(), "companies", "404", Properties{"name": "x"})
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound || apiErr.Temporary() {
+		t.Errorf("not found: got %v", err)
+	}
+}
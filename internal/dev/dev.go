@@ -0,0 +1,181 @@
+// Package dev runs the API on a laptop without cloud credentials. It swaps each external
+// dependency for a local stand-in: Firestore is served from memory, the Firebase Auth, Vonage,
+// OpenTok, Places and Storage APIs are served in process by the transport of the environment,
+// ID tokens are signed with a local key, and the emails, push notifications, SMS and NSQ messages
+// are appended to files in the dev directory.
+package dev
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+)
+
+const (
+	ProjectID = "pigeon-dev"
+
+	serviceAccountFile = "service-account.json"
+	emailsFile         = "emails.jsonl"
+	pushFile           = "push.jsonl"
+	smsFile            = "sms.jsonl"
+	nsqFile            = "nsq.jsonl"
+	storageDir         = "storage"
+)
+
+// Environment holds the stand-ins of the dev profile.
+type Environment struct {
+	Dir            string
+	ServiceAccount []byte // JSON key of a generated service account, for signing
+	Transport      http.RoundTripper
+	Tokens         *Tokens
+	Producer       *publishing.Producer
+	Emails         definition.EmailsService
+	Push           definition.PushService
+	Links          definition.DynamicLinksService
+
+	files []io.Closer
+}
+
+// Source is the layer of the config values set by Configure.
+const Source configs.Source = "dev"
+
+// Configure fills in the config the dev profile needs, keeping the values already set. The
+// service account is the generated one, and the stores are in memory.
+func Configure(config *configs.Config, dir string) {
+	setDefault := func(value *string, defaultValue string) {
+		if len(*value) == 0 {
+			*value = defaultValue
+		}
+	}
+	config.ServiceAccountFile = filepath.Join(dir, serviceAccountFile)
+	setDefault(&config.StorageBucket, ProjectID+".appspot.com")
+	setDefault(&config.PlacesApiKey, "dev")
+	setDefault(&config.DynamicLinkDomain, "dev.page.link")
+	setDefault(&config.ArchiveTemplate, "./templates/archive.html")
+	setDefault(&config.Smtp.Host, "http://localhost:3030")
+	setDefault(&config.Opentok.ApiKey, "12345678")
+	setDefault(&config.Opentok.ApiSecret, "dev")
+	setDefault(&config.Vonage.AppID, "dev")
+	setDefault(&config.Vonage.ApiKey, "devkey00") // the SDK wants exactly 8 characters
+	setDefault(&config.Vonage.ApiSecret, "dev")
+	setDefault(&config.Vonage.FromNumber, "15550000000")
+	setDefault(&config.ActionCodeSettings.URL, "http://localhost:3030")
+	setDefault(&config.CustomerActionCodeSettings.URL, "http://localhost:3030")
+	config.SendGrid.Enabled = false
+	config.Hubspot.Enabled = false
+	config.RateLimit.Store = "memory"
+	config.Idempotency.Store = "memory"
+}
+
+// NewEnvironment creates the dev directory and the stand-ins writing to it. The service account
+// is generated on the first run and kept, so the signed URLs stay valid across runs.
+func NewEnvironment(dir string) (*Environment, error) {
+	if err := os.MkdirAll(filepath.Join(dir, storageDir), 0o755); err != nil {
+		return nil, err
+	}
+	serviceAccount, err := loadServiceAccount(filepath.Join(dir, serviceAccountFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the dev service account: %w", err)
+	}
+	tokens, err := NewTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	env := &Environment{Dir: dir, ServiceAccount: serviceAccount, Tokens: tokens}
+	open := func(name string) (io.Writer, error) {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		env.files = append(env.files, f)
+		return f, nil
+	}
+	var emails, push, sms, nsq io.Writer
+	for _, file := range []struct {
+		name string
+		w    *io.Writer
+	}{{emailsFile, &emails}, {pushFile, &push}, {smsFile, &sms}, {nsqFile, &nsq}} {
+		if *file.w, err = open(file.name); err != nil {
+			env.Close()
+			return nil, err
+		}
+	}
+
+	env.Producer = publishing.NewSink(nsq)
+	env.Emails = NewEmailsSink(emails)
+	env.Push = NewPushSink(push)
+	env.Links = NewLinks("https://dev.page.link")
+	env.Transport = NewTransport(map[string]http.Handler{
+		identityToolkitHost: NewIdentityToolkit(),
+		vonageHost:          NewVonage(sms),
+		openTokHost:         NewOpenTok(),
+		placesHost:          NewPlaces(),
+		storageHost:         NewStorage(filepath.Join(dir, storageDir)),
+	})
+	return env, nil
+}
+
+// HTTPClient returns a client that sends the requests to the stand-ins.
+func (e *Environment) HTTPClient() *http.Client {
+	return &http.Client{Transport: e.Transport}
+}
+
+// Close closes the files of the sinks.
+func (e *Environment) Close() error {
+	var err error
+	for _, f := range e.files {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func loadServiceAccount(file string) ([]byte, error) {
+	if serviceAccount, err := ioutil.ReadFile(file); err == nil {
+		return serviceAccount, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	keyID := make([]byte, 20)
+	if _, err := rand.Read(keyID); err != nil {
+		return nil, err
+	}
+	serviceAccount, err := json.MarshalIndent(map[string]string{
+		"type":           "service_account",
+		"project_id":     ProjectID,
+		"private_key_id": hex.EncodeToString(keyID),
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "pigeon-api@" + ProjectID + ".iam.gserviceaccount.com",
+		"client_id":      "100000000000000000000",
+		"auth_uri":       "https://accounts.google.com/o/oauth2/auth",
+		"token_uri":      "https://oauth2.googleapis.com/token",
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return serviceAccount, ioutil.WriteFile(file, serviceAccount, 0o600)
+}
@@ -0,0 +1,183 @@
+package dev
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	def "github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	opentok "github.com/VolodymyrPobochii/opentok-go/pkg"
+	"github.com/gorilla/mux"
+	"google.golang.org/api/option"
+)
+
+func newEnvironment(t *testing.T) *Environment {
+	env, err := NewEnvironment(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { env.Close() })
+	return env
+}
+
+func newAuthClient(t *testing.T, env *Environment) *auth.Client {
+	ctx := context.Background()
+	app, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: ProjectID},
+		option.WithCredentialsJSON(env.ServiceAccount),
+		option.WithHTTPClient(env.HTTPClient()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := app.Auth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestTokens(t *testing.T) {
+	tokens, err := NewTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idToken, err := tokens.Issue("u1", map[string]interface{}{"admin": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := tokens.VerifyIDToken(context.Background(), idToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.UID != "u1" || token.Claims["admin"] != true {
+		t.Errorf("token = %+v, want uid u1 with the admin claim", token)
+	}
+
+	other, _ := NewTokens()
+	for name, idToken := range map[string]string{
+		"tampered":   idToken[:len(idToken)-2] + "xx",
+		"other key":  mustIssue(t, other, "u1"),
+		"not a dev":  "eyJhbGciOiJSUzI1NiJ9.e30.sig",
+		"no payload": "dev..",
+	} {
+		if _, err := tokens.VerifyIDToken(context.Background(), idToken); err == nil {
+			t.Errorf("%s: VerifyIDToken succeeded, want an error", name)
+		}
+	}
+}
+
+func mustIssue(t *testing.T, tokens *Tokens, uid string) string {
+	idToken, err := tokens.Issue(uid, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idToken
+}
+
+func TestTokenEndpoint(t *testing.T) {
+	ctx := context.Background()
+	env := newEnvironment(t)
+	client := newAuthClient(t, env)
+
+	user, err := client.CreateUser(ctx, (&auth.UserToCreate{}).Email("jane@example.com").DisplayName("Jane"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetCustomUserClaims(ctx, user.UID, map[string]interface{}{"businessId": "b1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CreateUser(ctx, (&auth.UserToCreate{}).Email("jane@example.com")); !auth.IsEmailAlreadyExists(err) {
+		t.Errorf("CreateUser with a taken email = %v, want email already exists", err)
+	}
+
+	router := mux.NewRouter()
+	NewTokenEndpoint(env.Tokens, client).SetupRouts(router)
+	request := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, PathToken, strings.NewReader(body)))
+		return recorder
+	}
+
+	recorder := request(`{"email":"jane@example.com"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+	var response struct {
+		IDToken string `json:"idToken"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	token, err := env.Tokens.VerifyIDToken(ctx, response.IDToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.UID != user.UID || token.Claims["businessId"] != "b1" {
+		t.Errorf("token = %+v, want uid %s with the businessId claim", token, user.UID)
+	}
+
+	if recorder := request(`{"uid":"missing"}`); recorder.Code != http.StatusNotFound {
+		t.Errorf("status for a missing user = %d, want 404", recorder.Code)
+	}
+	if recorder := request(`{}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("status without uid and email = %d, want 400", recorder.Code)
+	}
+}
+
+func TestOpenTok(t *testing.T) {
+	env := newEnvironment(t)
+	// the SDK sends its requests with the default client
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = env.Transport
+	defer func() { http.DefaultTransport = defaultTransport }()
+
+	ot := opentok.NewOpenTok("12345678", "secret", nil)
+	session, err := ot.CreateSession(map[string]interface{}{"mediaMode": "routed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ot.GenerateToken(session.Id(), map[string]interface{}{"role": "publisher"}); err != nil {
+		t.Errorf("GenerateToken(%s) = %v", session.Id(), err)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	env := newEnvironment(t)
+	if _, err := env.HTTPClient().Get("https://api.sendgrid.com/v3/mail/send"); err == nil || !strings.Contains(err.Error(), "no stand-in") {
+		t.Errorf("request to an unknown host = %v, want no stand-in error", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+	resp, err := env.HTTPClient().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("status of the loopback request = %d, want 418", resp.StatusCode)
+	}
+}
+
+func TestEmailsSink(t *testing.T) {
+	var buf bytes.Buffer
+	emails := NewEmailsSink(&buf)
+	if response := emails.SendPasswordReset(context.Background(), def.SendPasswordResetRequest{
+		Email: "jane@example.com",
+	}); !response.OK() {
+		t.Fatal(response.Error)
+	}
+	var line struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil || line.Type != "passwordReset" {
+		t.Errorf("line = %s, want a passwordReset email", buf.String())
+	}
+}
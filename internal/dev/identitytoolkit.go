@@ -0,0 +1,315 @@
+package dev
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const identityToolkitHost = "identitytoolkit.googleapis.com"
+
+// user is an account of the Identity Toolkit API, as the Firebase Admin SDK reads it.
+type user struct {
+	LocalID          string `json:"localId"`
+	Email            string `json:"email,omitempty"`
+	DisplayName      string `json:"displayName,omitempty"`
+	PhoneNumber      string `json:"phoneNumber,omitempty"`
+	PhotoURL         string `json:"photoUrl,omitempty"`
+	CreatedAt        string `json:"createdAt,omitempty"`
+	CustomAttributes string `json:"customAttributes,omitempty"`
+	Disabled         bool   `json:"disabled,omitempty"`
+	EmailVerified    bool   `json:"emailVerified,omitempty"`
+	ValidSince       string `json:"validSince,omitempty"`
+}
+
+// userRequest has the fields of the create and update requests. The pointers tell the fields
+// that are not set apart.
+type userRequest struct {
+	LocalID          string   `json:"localId"`
+	Email            *string  `json:"email"`
+	DisplayName      *string  `json:"displayName"`
+	PhoneNumber      *string  `json:"phoneNumber"`
+	PhotoURL         *string  `json:"photoUrl"`
+	Password         *string  `json:"password"`
+	CustomAttributes *string  `json:"customAttributes"`
+	Disabled         *bool    `json:"disabled"`
+	DisableUser      *bool    `json:"disableUser"`
+	EmailVerified    *bool    `json:"emailVerified"`
+	ValidSince       *string  `json:"validSince"`
+	DeleteAttribute  []string `json:"deleteAttribute"`
+	DeleteProvider   []string `json:"deleteProvider"`
+}
+
+// IdentityToolkit serves the user management of Firebase Auth from memory.
+type IdentityToolkit struct {
+	mu    sync.Mutex
+	users map[string]*user
+}
+
+func NewIdentityToolkit() http.Handler {
+	toolkit := &IdentityToolkit{users: map[string]*user{}}
+	router := mux.NewRouter()
+	accounts := router.PathPrefix("/v1/projects/{project}").Subrouter()
+	accounts.HandleFunc("/accounts", toolkit.create).Methods(http.MethodPost)
+	accounts.HandleFunc("/accounts:lookup", toolkit.lookup).Methods(http.MethodPost)
+	accounts.HandleFunc("/accounts:update", toolkit.update).Methods(http.MethodPost)
+	accounts.HandleFunc("/accounts:delete", toolkit.delete).Methods(http.MethodPost)
+	accounts.HandleFunc("/accounts:batchGet", toolkit.batchGet).Methods(http.MethodGet)
+	accounts.HandleFunc("/accounts:sendOobCode", toolkit.sendOobCode).Methods(http.MethodPost)
+	return router
+}
+
+func (t *IdentityToolkit) create(resp http.ResponseWriter, req *http.Request) {
+	var request userRequest
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		identityError(resp, http.StatusBadRequest, "INVALID_JSON_PAYLOAD")
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(request.LocalID) == 0 {
+		request.LocalID = newID(14)
+	}
+	if _, ok := t.users[request.LocalID]; ok {
+		identityError(resp, http.StatusBadRequest, "DUPLICATE_LOCAL_ID")
+		return
+	}
+	u := &user{LocalID: request.LocalID, CreatedAt: strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)}
+	if code := t.apply(u, &request); len(code) > 0 {
+		identityError(resp, http.StatusBadRequest, code)
+		return
+	}
+	t.users[u.LocalID] = u
+	writeJSON(resp, map[string]string{"localId": u.LocalID})
+}
+
+func (t *IdentityToolkit) lookup(resp http.ResponseWriter, req *http.Request) {
+	var request struct {
+		LocalID     []string `json:"localId"`
+		Email       []string `json:"email"`
+		PhoneNumber []string `json:"phoneNumber"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		identityError(resp, http.StatusBadRequest, "INVALID_JSON_PAYLOAD")
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	users := []*user{}
+	for _, u := range t.sorted() {
+		if contains(request.LocalID, u.LocalID) || contains(request.Email, u.Email) || contains(request.PhoneNumber, u.PhoneNumber) {
+			users = append(users, u)
+		}
+	}
+	if len(users) == 0 {
+		// the API leaves the users out if none matches
+		writeJSON(resp, map[string]string{"kind": "identitytoolkit#GetAccountInfoResponse"})
+		return
+	}
+	writeJSON(resp, map[string][]*user{"users": users})
+}
+
+func (t *IdentityToolkit) update(resp http.ResponseWriter, req *http.Request) {
+	var request userRequest
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		identityError(resp, http.StatusBadRequest, "INVALID_JSON_PAYLOAD")
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, ok := t.users[request.LocalID]
+	if !ok {
+		identityError(resp, http.StatusBadRequest, "USER_NOT_FOUND")
+		return
+	}
+	updated := *u
+	if code := t.apply(&updated, &request); len(code) > 0 {
+		identityError(resp, http.StatusBadRequest, code)
+		return
+	}
+	*u = updated
+	writeJSON(resp, map[string]string{"localId": u.LocalID})
+}
+
+func (t *IdentityToolkit) delete(resp http.ResponseWriter, req *http.Request) {
+	var request struct {
+		LocalID string `json:"localId"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		identityError(resp, http.StatusBadRequest, "INVALID_JSON_PAYLOAD")
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.users[request.LocalID]; !ok {
+		identityError(resp, http.StatusBadRequest, "USER_NOT_FOUND")
+		return
+	}
+	delete(t.users, request.LocalID)
+	writeJSON(resp, map[string]string{"kind": "identitytoolkit#DeleteAccountResponse"})
+}
+
+// batchGet lists the users in pages ordered by uid. The page token is the last uid of the page.
+func (t *IdentityToolkit) batchGet(resp http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	maxResults, err := strconv.Atoi(query.Get("maxResults"))
+	if err != nil || maxResults <= 0 {
+		maxResults = 1000
+	}
+	after := query.Get("nextPageToken")
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	response := struct {
+		Users         []*user `json:"users,omitempty"`
+		NextPageToken string  `json:"nextPageToken,omitempty"`
+	}{}
+	for _, u := range t.sorted() {
+		if u.LocalID <= after {
+			continue
+		}
+		if len(response.Users) == maxResults {
+			response.NextPageToken = response.Users[maxResults-1].LocalID
+			break
+		}
+		response.Users = append(response.Users, u)
+	}
+	writeJSON(resp, response)
+}
+
+// sendOobCode returns the action link instead of sending an email.
+func (t *IdentityToolkit) sendOobCode(resp http.ResponseWriter, req *http.Request) {
+	var request struct {
+		RequestType string `json:"requestType"`
+		Email       string `json:"email"`
+		ContinueURL string `json:"continueUrl"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		identityError(resp, http.StatusBadRequest, "INVALID_JSON_PAYLOAD")
+		return
+	}
+	t.mu.Lock()
+	found := false
+	for _, u := range t.users {
+		found = found || u.Email == request.Email
+	}
+	t.mu.Unlock()
+	if !found && request.RequestType != "EMAIL_SIGNIN" {
+		identityError(resp, http.StatusBadRequest, "EMAIL_NOT_FOUND")
+		return
+	}
+	link := url.URL{Scheme: "https", Host: ProjectID + ".firebaseapp.com", Path: "/__/auth/action"}
+	link.RawQuery = url.Values{
+		"mode":        {modes[request.RequestType]},
+		"oobCode":     {newID(16)},
+		"continueUrl": {request.ContinueURL},
+		"apiKey":      {"dev"},
+	}.Encode()
+	writeJSON(resp, map[string]string{"email": request.Email, "oobLink": link.String()})
+}
+
+var modes = map[string]string{
+	"PASSWORD_RESET": "resetPassword",
+	"VERIFY_EMAIL":   "verifyEmail",
+	"EMAIL_SIGNIN":   "signIn",
+}
+
+// apply sets the fields of the request on the user, and returns the error code of the API if
+// the email or the phone number are taken.
+func (t *IdentityToolkit) apply(u *user, request *userRequest) string {
+	for _, other := range t.users {
+		if other.LocalID == u.LocalID {
+			continue
+		}
+		if request.Email != nil && len(*request.Email) > 0 && other.Email == *request.Email {
+			return "EMAIL_EXISTS"
+		}
+		if request.PhoneNumber != nil && len(*request.PhoneNumber) > 0 && other.PhoneNumber == *request.PhoneNumber {
+			return "PHONE_NUMBER_EXISTS"
+		}
+	}
+	setString := func(field *string, value *string) {
+		if value != nil {
+			*field = *value
+		}
+	}
+	setString(&u.Email, request.Email)
+	setString(&u.DisplayName, request.DisplayName)
+	setString(&u.PhoneNumber, request.PhoneNumber)
+	setString(&u.PhotoURL, request.PhotoURL)
+	setString(&u.CustomAttributes, request.CustomAttributes)
+	setString(&u.ValidSince, request.ValidSince)
+	for _, disabled := range []*bool{request.Disabled, request.DisableUser} {
+		if disabled != nil {
+			u.Disabled = *disabled
+		}
+	}
+	if request.EmailVerified != nil {
+		u.EmailVerified = *request.EmailVerified
+	}
+	for _, attribute := range request.DeleteAttribute {
+		switch attribute {
+		case "DISPLAY_NAME":
+			u.DisplayName = ""
+		case "PHOTO_URL":
+			u.PhotoURL = ""
+		}
+	}
+	for _, provider := range request.DeleteProvider {
+		if provider == "phone" {
+			u.PhoneNumber = ""
+		}
+	}
+	return ""
+}
+
+func (t *IdentityToolkit) sorted() []*user {
+	users := make([]*user, 0, len(t.users))
+	for _, u := range t.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].LocalID < users[j].LocalID })
+	return users
+}
+
+func identityError(resp http.ResponseWriter, status int, code string) {
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(status)
+	_ = json.NewEncoder(resp).Encode(map[string]interface{}{
+		"error": map[string]interface{}{"code": status, "message": code},
+	})
+}
+
+func writeJSON(resp http.ResponseWriter, v interface{}) {
+	resp.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(resp).Encode(v)
+}
+
+func contains(values []string, value string) bool {
+	if len(value) == 0 {
+		return false
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// newID returns a random hex ID of n bytes.
+func newID(n int) string {
+	id := make([]byte, n)
+	if _, err := rand.Read(id); err != nil {
+		panic(fmt.Sprintf("dev: failed to read random bytes: %v", err))
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,276 @@
+package dev
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	vonageHost  = "rest.nexmo.com"
+	openTokHost = "api.opentok.com"
+	placesHost  = "maps.googleapis.com"
+	storageHost = "storage.googleapis.com"
+)
+
+// NewVonage serves the SMS and numbers APIs of Vonage. The SMS are written to sms, one JSON
+// object per line, and reported as sent.
+func NewVonage(sms io.Writer) http.Handler {
+	var mu sync.Mutex
+	router := mux.NewRouter()
+	router.HandleFunc("/sms/json", func(resp http.ResponseWriter, req *http.Request) {
+		if err := req.ParseForm(); err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+		messageID := newID(8)
+		line, _ := json.Marshal(map[string]string{
+			"time":      time.Now().UTC().Format(time.RFC3339),
+			"messageId": messageID,
+			"from":      req.PostForm.Get("from"),
+			"to":        req.PostForm.Get("to"),
+			"text":      req.PostForm.Get("text"),
+		})
+		mu.Lock()
+		_, err := sms.Write(append(line, '\n'))
+		mu.Unlock()
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(resp, map[string]interface{}{
+			"message-count": "1",
+			"messages": []map[string]string{{
+				"to":                req.PostForm.Get("to"),
+				"message-id":        messageID,
+				"status":            "0",
+				"remaining-balance": "100.00",
+				"message-price":     "0.00",
+				"network":           "dev",
+			}},
+		})
+	}).Methods(http.MethodPost)
+	router.HandleFunc("/account/numbers", func(resp http.ResponseWriter, req *http.Request) {
+		writeJSON(resp, map[string]interface{}{"count": 0, "numbers": []interface{}{}})
+	}).Methods(http.MethodGet)
+	router.HandleFunc("/number/search", func(resp http.ResponseWriter, req *http.Request) {
+		size, err := strconv.Atoi(req.URL.Query().Get("size"))
+		if err != nil || size <= 0 {
+			size = 10
+		}
+		country := req.URL.Query().Get("country")
+		var numbers []map[string]interface{}
+		for i := 0; i < size; i++ {
+			numbers = append(numbers, map[string]interface{}{
+				"country":  country,
+				"msisdn":   fmt.Sprintf("1555%07d", i),
+				"type":     "mobile-lvn",
+				"cost":     "0.00",
+				"features": []string{"SMS", "VOICE"},
+			})
+		}
+		writeJSON(resp, map[string]interface{}{"count": len(numbers), "numbers": numbers})
+	}).Methods(http.MethodGet)
+	for _, path := range []string{"/number/buy", "/number/cancel", "/number/update"} {
+		router.HandleFunc(path, func(resp http.ResponseWriter, req *http.Request) {
+			writeJSON(resp, map[string]string{"error-code": "200", "error-code-label": "success"})
+		}).Methods(http.MethodPost)
+	}
+	return router
+}
+
+// NewOpenTok creates the video sessions of OpenTok. The tokens are generated by the SDK, which
+// only needs the session ID to name the API key.
+func NewOpenTok() http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc("/session/create", func(resp http.ResponseWriter, req *http.Request) {
+		// the JWT of the SDK names the API key in its issuer
+		apiKey := jwtIssuer(req.Header.Get("X-OPENTOK-AUTH"))
+		writeJSON(resp, []map[string]string{{
+			"session_id":       newSessionID(apiKey, time.Now()),
+			"project_id":       apiKey,
+			"create_dt":        time.Now().UTC().Format(time.RFC1123),
+			"media_server_url": "",
+		}})
+	}).Methods(http.MethodPost)
+	return router
+}
+
+// newSessionID encodes the API key and the creation time as OpenTok does. The SDK adds the
+// base64 padding back unless the length is a multiple of 4, so the fields are padded to avoid it.
+func newSessionID(apiKey string, created time.Time) string {
+	fields := fmt.Sprintf("1~%s~~%d~%s~", apiKey, created.UnixNano()/int64(time.Millisecond), newID(4))
+	for len(fields)%3 == 0 {
+		fields += "~"
+	}
+	return "1_" + base64.RawURLEncoding.EncodeToString([]byte(fields))
+}
+
+func jwtIssuer(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	_ = json.Unmarshal(payload, &claims)
+	return claims.Issuer
+}
+
+// NewPlaces serves the Places and Distance Matrix APIs of Google Maps. There are no places, and
+// the distances are those as the crow flies between coordinates, at 50 km/h.
+func NewPlaces() http.Handler {
+	router := mux.NewRouter()
+	for _, path := range []string{"textsearch", "findplacefromtext", "nearbysearch", "autocomplete"} {
+		router.HandleFunc("/maps/api/place/"+path+"/json", func(resp http.ResponseWriter, req *http.Request) {
+			writeJSON(resp, map[string]interface{}{"status": "ZERO_RESULTS", "results": []interface{}{}})
+		})
+	}
+	router.HandleFunc("/maps/api/place/details/json", func(resp http.ResponseWriter, req *http.Request) {
+		writeJSON(resp, map[string]interface{}{"status": "NOT_FOUND"})
+	})
+	router.HandleFunc("/maps/api/geocode/json", func(resp http.ResponseWriter, req *http.Request) {
+		writeJSON(resp, map[string]interface{}{"status": "ZERO_RESULTS", "results": []interface{}{}})
+	})
+	router.HandleFunc("/maps/api/distancematrix/json", func(resp http.ResponseWriter, req *http.Request) {
+		origins := strings.Split(req.URL.Query().Get("origins"), "|")
+		destinations := strings.Split(req.URL.Query().Get("destinations"), "|")
+		var rows []map[string]interface{}
+		for _, origin := range origins {
+			var elements []map[string]interface{}
+			for _, destination := range destinations {
+				elements = append(elements, distanceElement(origin, destination))
+			}
+			rows = append(rows, map[string]interface{}{"elements": elements})
+		}
+		writeJSON(resp, map[string]interface{}{
+			"status":                "OK",
+			"origin_addresses":      origins,
+			"destination_addresses": destinations,
+			"rows":                  rows,
+		})
+	})
+	return router
+}
+
+func distanceElement(origin, destination string) map[string]interface{} {
+	lat1, lng1, ok1 := parseLatLng(origin)
+	lat2, lng2, ok2 := parseLatLng(destination)
+	if !ok1 || !ok2 {
+		return map[string]interface{}{"status": "NOT_FOUND"}
+	}
+	meters := int64(haversine(lat1, lng1, lat2, lng2))
+	seconds := meters * 3600 / 50000
+	return map[string]interface{}{
+		"status":   "OK",
+		"distance": map[string]interface{}{"value": meters, "text": fmt.Sprintf("%.1f km", float64(meters)/1000)},
+		"duration": map[string]interface{}{"value": seconds, "text": fmt.Sprintf("%d mins", seconds/60)},
+	}
+}
+
+func parseLatLng(s string) (lat, lng float64, ok bool) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	lat, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	lng, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	return lat, lng, err1 == nil && err2 == nil
+}
+
+// haversine returns the distance in meters between two coordinates.
+func haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadius = 6371000
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat, dLng := rad(lat2-lat1), rad(lng2-lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// NewStorage stores the objects uploaded to Cloud Storage in dir, under the name of their bucket.
+// Only the multipart uploads are supported, which the client uses for objects below 16 MB.
+func NewStorage(dir string) http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc("/upload/storage/v1/b/{bucket}/o", func(resp http.ResponseWriter, req *http.Request) {
+		bucket := mux.Vars(req)["bucket"]
+		if uploadType := req.URL.Query().Get("uploadType"); uploadType != "multipart" {
+			http.Error(resp, "unsupported upload type "+uploadType, http.StatusNotImplemented)
+			return
+		}
+		mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+			http.Error(resp, "expected a multipart body", http.StatusBadRequest)
+			return
+		}
+		reader := multipart.NewReader(req.Body, params["boundary"])
+		metadataPart, err := reader.NextPart()
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var object map[string]interface{}
+		if err := json.NewDecoder(metadataPart).Decode(&object); err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+		name, _ := object["name"].(string)
+		if len(name) == 0 {
+			name = req.URL.Query().Get("name")
+		}
+		mediaPart, err := reader.NextPart()
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, err := ioutil.ReadAll(mediaPart)
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		file := filepath.Join(dir, bucket, filepath.FromSlash(name))
+		if !strings.HasPrefix(file, filepath.Join(dir, bucket)+string(filepath.Separator)) {
+			http.Error(resp, "invalid object name", http.StatusBadRequest)
+			return
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			http.Error(resp, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := ioutil.WriteFile(file, data, 0o644); err != nil {
+			http.Error(resp, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		now := time.Now().UTC().Format(time.RFC3339Nano)
+		object["kind"] = "storage#object"
+		object["id"] = bucket + "/" + name
+		object["name"] = name
+		object["bucket"] = bucket
+		object["size"] = strconv.Itoa(len(data))
+		object["generation"] = strconv.FormatInt(time.Now().UnixNano(), 10)
+		object["timeCreated"] = now
+		object["updated"] = now
+		object["mediaLink"] = "file://" + file
+		writeJSON(resp, object)
+	}).Methods(http.MethodPost)
+	return router
+}
@@ -0,0 +1,121 @@
+package dev
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	def "github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+)
+
+// sink appends the requests to w, one JSON object per line.
+type sink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *sink) write(kind string, request interface{}) def.SendResponse {
+	line, err := json.Marshal(map[string]interface{}{
+		"time":    time.Now().UTC().Format(time.RFC3339),
+		"type":    kind,
+		"request": request,
+	})
+	if err != nil {
+		return def.SendResponse{Error: err}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		return def.SendResponse{Error: err}
+	}
+	return def.SendResponse{}
+}
+
+type emailsSink struct {
+	sink
+}
+
+// NewEmailsSink writes the emails to w instead of sending them.
+func NewEmailsSink(w io.Writer) def.EmailsService {
+	return &emailsSink{sink{w: w}}
+}
+
+func (s *emailsSink) SendInvite(_ context.Context, req def.SendInviteRequest) def.SendResponse {
+	return s.write("invite", req)
+}
+
+func (s *emailsSink) SendPasswordReset(_ context.Context, req def.SendPasswordResetRequest) def.SendResponse {
+	return s.write("passwordReset", req)
+}
+
+func (s *emailsSink) SendNewRequestAlert(_ context.Context, req ...def.NewRequestAlertRequest) def.SendResponse {
+	return s.write("newRequestAlert", req)
+}
+
+func (s *emailsSink) SendIdleRequestAlert(_ context.Context, req def.IdleRequestAlertRequest) def.SendResponse {
+	return s.write("idleRequestAlert", req)
+}
+
+func (s *emailsSink) SendAcceptedRequestAlert(_ context.Context, req ...def.AcceptedRequestAlertRequest) def.SendResponse {
+	return s.write("acceptedRequestAlert", req)
+}
+
+func (s *emailsSink) SendIdleChatAlert(_ context.Context, req def.IdleChatAlertRequest) def.SendResponse {
+	return s.write("idleChatAlert", req)
+}
+
+func (s *emailsSink) SendUnreadChatAlert(_ context.Context, req def.UnreadChatAlertRequest) def.SendResponse {
+	return s.write("unreadChatAlert", req)
+}
+
+func (s *emailsSink) SendBusinessAccountCreated(_ context.Context, req def.SendBusinessAccountCreatedRequest) def.SendResponse {
+	return s.write("businessAccountCreated", req)
+}
+
+func (s *emailsSink) SendBusinessEmailVerification(_ context.Context, req def.SendBusinessEmailVerificationRequest) def.SendResponse {
+	return s.write("businessEmailVerification", req)
+}
+
+func (s *emailsSink) SendSLAEscalationAlert(_ context.Context, req def.SLAEscalationAlertRequest) def.SendResponse {
+	return s.write("slaEscalationAlert", req)
+}
+
+type pushSink struct {
+	sink
+}
+
+// NewPushSink writes the push notifications to w instead of sending them with FCM.
+func NewPushSink(w io.Writer) def.PushService {
+	return &pushSink{sink{w: w}}
+}
+
+func (s *pushSink) Send(_ context.Context, req def.PushRequest) def.SendResponse {
+	return s.write("push", req)
+}
+
+type links struct {
+	base string
+}
+
+// NewLinks builds the links under base instead of shortening them with Firebase Dynamic Links.
+func NewLinks(base string) def.DynamicLinksService {
+	return &links{base: base}
+}
+
+func (l *links) GenerateChatLink(_ context.Context, req def.ChatLinkRequest) def.LinkResponse {
+	return def.LinkResponse{ShortLink: l.base + "/" + req.ChatType + "/" + req.ChatSubtype + "/" + req.ChatID}
+}
+
+func (l *links) GenerateBusinessLink(_ context.Context, req def.BusinessLinkRequest) def.LinkResponse {
+	return def.LinkResponse{ShortLink: l.base + "/businesses/" + req.BusinessID}
+}
+
+func (l *links) CreateISILinkCustomer(_ context.Context, req def.CreateISILinkRequest) def.CreateISILinkResponse {
+	return def.CreateISILinkResponse{Link: req.RawLink}
+}
+
+func (l *links) CreateISILinkAssociate(_ context.Context, req def.CreateISILinkRequest) def.CreateISILinkResponse {
+	return def.CreateISILinkResponse{Link: req.RawLink}
+}
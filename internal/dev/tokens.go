@@ -0,0 +1,174 @@
+package dev
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
+	"github.com/gorilla/mux"
+)
+
+const (
+	PathToken = "/dev/token"
+
+	tokenPrefix = "dev"
+	tokenTTL    = time.Hour
+)
+
+var ErrInvalidToken = errors.New("dev: invalid ID token")
+
+// Tokens issues and verifies the dev ID tokens. They are signed with a key generated at start,
+// so the tokens of a previous run are rejected.
+type Tokens struct {
+	key []byte
+}
+
+type tokenPayload struct {
+	UID      string                 `json:"uid"`
+	Claims   map[string]interface{} `json:"claims,omitempty"`
+	IssuedAt int64                  `json:"iat"`
+	Expires  int64                  `json:"exp"`
+}
+
+func NewTokens() (*Tokens, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate the dev token key: %w", err)
+	}
+	return &Tokens{key: key}, nil
+}
+
+// Issue returns an ID token of the user with the claims, valid for an hour.
+func (t *Tokens) Issue(uid string, claims map[string]interface{}) (string, error) {
+	now := time.Now()
+	payload, err := json.Marshal(tokenPayload{
+		UID:      uid,
+		Claims:   claims,
+		IssuedAt: now.Unix(),
+		Expires:  now.Add(tokenTTL).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := tokenPrefix + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(t.sign(signed)), nil
+}
+
+// VerifyIDToken verifies the dev ID tokens in place of the Firebase ones.
+func (t *Tokens) VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 || parts[0] != tokenPrefix {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, t.sign(parts[0]+"."+parts[1])) {
+		return nil, ErrInvalidToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var payload tokenPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= payload.Expires {
+		return nil, fmt.Errorf("dev: ID token expired at %s", time.Unix(payload.Expires, 0).UTC().Format(time.RFC3339))
+	}
+	claims := map[string]interface{}{}
+	for name, value := range payload.Claims {
+		claims[name] = value
+	}
+	return &auth.Token{
+		AuthTime: payload.IssuedAt,
+		Issuer:   "https://securetoken.google.com/" + ProjectID,
+		Audience: ProjectID,
+		Expires:  payload.Expires,
+		IssuedAt: payload.IssuedAt,
+		Subject:  payload.UID,
+		UID:      payload.UID,
+		Claims:   claims,
+	}, nil
+}
+
+// VerifyIDTokenAndCheckRevoked verifies the dev ID tokens. They are short-lived and never revoked.
+func (t *Tokens) VerifyIDTokenAndCheckRevoked(ctx context.Context, idToken string) (*auth.Token, error) {
+	return t.VerifyIDToken(ctx, idToken)
+}
+
+func (t *Tokens) sign(s string) []byte {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(s))
+	return mac.Sum(nil)
+}
+
+// UserGetter looks up the users the tokens are issued to. *auth.Client is one.
+type UserGetter interface {
+	GetUser(ctx context.Context, uid string) (*auth.UserRecord, error)
+	GetUserByEmail(ctx context.Context, email string) (*auth.UserRecord, error)
+}
+
+// TokenEndpoint issues the ID tokens of the users, with their custom claims, in place of the
+// sign-in of the Firebase client SDKs.
+type TokenEndpoint struct {
+	tokens *Tokens
+	users  UserGetter
+}
+
+func NewTokenEndpoint(tokens *Tokens, users UserGetter) *TokenEndpoint {
+	return &TokenEndpoint{tokens: tokens, users: users}
+}
+
+func (e *TokenEndpoint) SetupRouts(router *mux.Router) {
+	routes.HandleFunc(router, PathToken, e.issueToken).Methods(http.MethodPost).Public()
+}
+
+func (e *TokenEndpoint) issueToken(resp http.ResponseWriter, req *http.Request) {
+	var request struct {
+		UID   string `json:"uid"`
+		Email string `json:"email" validate:"email"`
+	}
+	if err := validation.Decode(resp, req, &request); err != nil {
+		return
+	}
+	var user *auth.UserRecord
+	var err error
+	switch {
+	case len(request.UID) > 0:
+		user, err = e.users.GetUser(req.Context(), request.UID)
+	case len(request.Email) > 0:
+		user, err = e.users.GetUserByEmail(req.Context(), request.Email)
+	default:
+		apierrors.Respond(resp, apierrors.New(apierrors.CodeInvalidArgument, "uid or email is required"))
+		return
+	}
+	if auth.IsUserNotFound(err) {
+		apierrors.Respond(resp, apierrors.Wrap(apierrors.CodeNotFound, err))
+		return
+	}
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	idToken, err := e.tokens.Issue(user.UID, user.CustomClaims)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	resp.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(resp).Encode(map[string]interface{}{
+		"idToken":   idToken,
+		"localId":   user.UID,
+		"expiresIn": fmt.Sprint(int(tokenTTL.Seconds())),
+	})
+}
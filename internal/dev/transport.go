@@ -0,0 +1,43 @@
+package dev
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+)
+
+// Transport serves the requests to the hosts of the stand-ins in process. The requests to the
+// other remote hosts fail, so nothing leaves the laptop, while those to the loopback interface
+// go through.
+type Transport struct {
+	hosts    map[string]http.Handler
+	loopback http.RoundTripper
+}
+
+func NewTransport(hosts map[string]http.Handler) *Transport {
+	return &Transport{hosts: hosts, loopback: http.DefaultTransport}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	handler, ok := t.hosts[host]
+	if !ok {
+		if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+			return t.loopback.RoundTrip(req)
+		}
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("dev: no stand-in for %s", req.URL.Host)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	resp := recorder.Result()
+	resp.Request = req
+	return resp, nil
+}
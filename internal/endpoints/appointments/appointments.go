@@ -5,16 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"time"
 
-	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
 	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data"
-	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
 	"github.com/gorilla/mux"
 )
 
@@ -29,29 +30,46 @@ var (
 )
 
 type handler struct {
-	db                           *db.Firestore
-	appointsRepository           data.AppointmentsRepository
+	appointsRepository           definition.AppointmentsRepository
 	associateAssistantRepository data.AssociateAssistantRepository
+	businessesRepository         definition.BusinessesRepository
+	settingsRepository           definition.BusinessSettingsRepository
+	directoryRepository          definition.DirectoryRepository
+	customersRepository          definition.CustomersRepository
+	publisher                    events.Publisher
+	validator                    *validation.Validator
 }
 
-func NewHandler(firestoreClient *db.Firestore,
-	appointsRepository data.AppointmentsRepository,
-	associateAssistantRepository data.AssociateAssistantRepository) *handler {
+func NewHandler(appointsRepository definition.AppointmentsRepository,
+	associateAssistantRepository data.AssociateAssistantRepository,
+	businessesRepository definition.BusinessesRepository,
+	settingsRepository definition.BusinessSettingsRepository,
+	directoryRepository definition.DirectoryRepository,
+	customersRepository definition.CustomersRepository,
+	publisher events.Publisher) *handler {
 	return &handler{
-		firestoreClient,
 		appointsRepository,
-		associateAssistantRepository}
+		associateAssistantRepository,
+		businessesRepository,
+		settingsRepository,
+		directoryRepository,
+		customersRepository,
+		publisher,
+		validation.New().Register("customer", validation.Found(func(ctx context.Context, id string) error {
+			_, err := customersRepository.FindById(ctx, id)
+			return err
+		}))}
 }
 
 func (h *handler) Create() http.HandlerFunc {
 	type createAppointmentRequest struct {
 		AssistantId string      `json:"assistantId,omitempty"` //deprecated
-		CustomerId  string      `json:"customerId"`
-		AssociateId string      `json:"associateId,omitempty"`
-		Comment     string      `json:"comment,omitempty"`
-		StartDate   *time.Time  `json:"startDate"`
-		EndDate     *time.Time  `json:"endDate"`
-		Remind      int64       `json:"remind,omitempty"`
+		CustomerId  string      `json:"customerId" validate:"required,customer"`
+		AssociateId string      `json:"associateId,omitempty"` // the contact of the appointments settings by default
+		Comment     string      `json:"comment,omitempty" validate:"max=1000"`
+		StartDate   *time.Time  `json:"startDate" validate:"required"`
+		EndDate     *time.Time  `json:"endDate" validate:"required,gtfield=StartDate"`
+		Remind      int64       `json:"remind,omitempty" validate:"min=0"`
 		Cals        *model.Cals `json:"cals,omitempty"`
 		Canceled    bool        `json:"canceled"`
 	}
@@ -66,9 +84,8 @@ func (h *handler) Create() http.HandlerFunc {
 		uid := req.Context().Value("uid").(string)
 		businessId := mux.Vars(req)["business_id"]
 
-		body, err := ioutil.ReadAll(req.Body)
 		var request createAppointmentRequest
-		if err = json.Unmarshal(body, &request); common.RespondWithError(err, resp, http.StatusBadRequest) {
+		if err := h.validator.Decode(resp, req, &request); apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 
@@ -78,94 +95,61 @@ func (h *handler) Create() http.HandlerFunc {
 		startDate := request.StartDate
 		endDate := request.EndDate
 
-		if len(customerId) == 0 || len(associateContactId) == 0 || startDate == nil || endDate == nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(http.StatusText(http.StatusBadRequest))
-			return
-		}
-
-		settingsSnapshot, err := h.db.Collection("settings").Doc(businessId).Get(ctx)
-		if err != nil {
-			common.RespondWithError(ErrorAppointsNotAvailable, resp, http.StatusInternalServerError)
-			return
-		}
-		if !settingsSnapshot.Exists() {
-			common.RespondWithError(ErrorAppointsNotAvailable, resp, http.StatusInternalServerError)
+		settings, err := h.settingsRepository.FindById(ctx, businessId)
+		if err != nil || settings.Appoints == nil {
+			apierrors.RespondWithError(ErrorAppointsNotAvailable, resp, apierrors.CodeInternal)
 			return
 		}
-		appoints := settingsSnapshot.Data()["appoints"].(map[string]interface{})
-		isActive := appoints["active"].(bool)
-		if !isActive {
-			common.RespondWithError(errors.New("business restricted the appointments"), resp, http.StatusInternalServerError)
+		appoints := settings.Appoints
+		if !appoints.Active {
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeFailedPrecondition, "business restricted the appointments"))
 			return
 		}
-		dayString := startDate.Weekday().String()
-		days := appoints["appointDays"].([]interface{})
-		for _, day := range days {
-			dayObj := day.(map[string]interface{})
-			if dayObj["name"].(string) == dayString {
-				if !dayObj["active"].(bool) {
-					common.RespondWithError(errors.New("not working day for the appointments"), resp, http.StatusInternalServerError)
-					return
-				}
-				//todo: add checking for working time
-				break
-			}
-		}
-
-		if len(associateContactId) == 0 {
-			associateContactId = appoints["contact"].(map[string]interface{})["id"].(string)
-		}
-
-		var business *model.BusinessItem
-		businessRef := h.db.Collection("businesses").Doc(businessId)
-		bizSnapshot, err := businessRef.Get(ctx)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		//todo: add checking for working time
+		if day := appoints.AppointDay(startDate.Weekday()); day != nil && !day.Active {
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeFailedPrecondition, "not working day for the appointments"))
 			return
 		}
 
-		if err = bizSnapshot.DataTo(&business); common.RespondWithError(err, resp, http.StatusInternalServerError) {
-			return
+		if len(associateContactId) == 0 && appoints.Contact != nil {
+			associateContactId = appoints.Contact.Id
 		}
-		business.Id = bizSnapshot.Ref.ID
-
-		var customer *model.Customer
-		userSnapshot, err := businessRef.Collection("businessCustomers").Doc(customerId).Get(ctx)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		if len(associateContactId) == 0 {
+			apierrors.Respond(resp, apierrors.Validation(apierrors.FieldError{
+				Field:   "associateId",
+				Message: "is required when the business has no contact for the appointments",
+			}))
 			return
 		}
 
-		if err = userSnapshot.DataTo(&customer); common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		bizData, err := h.businessesRepository.FindById(ctx, businessId)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
+		business := &model.BusinessItem{Id: bizData.Id, Name: bizData.Name}
 
-		customer.Id = userSnapshot.Ref.ID
-
-		var associateContact *model.Contact
-		snapshot, err := businessRef.Collection("directory").Doc(associateContactId).Get(ctx)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		customer, err := h.customersRepository.FindBusinessCustomer(ctx, businessId, customerId)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 
-		if err = snapshot.DataTo(&associateContact); common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		associateContact, err := h.directoryRepository.FindById(ctx, businessId, associateContactId)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
-		associateContact.Id = snapshot.Ref.ID
 
 		// check contact is blocked
 		if h.checkBlocking(ctx, resp, uid, associateContact, customerId) {
 			return
 		}
 
-		documents, err := h.db.Collection("users").Doc(associateContact.Associate.Id).
-			Collection("bookedDates").Where("date", "==", startDate).
-			Select().Documents(ctx).GetAll()
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		booked, err := h.appointsRepository.IsBooked(ctx, associateContact.Associate.Id, *startDate)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 
-		if len(documents) > 0 {
-			common.RespondWithError(errors.New("time period is already booked"), resp, http.StatusInternalServerError)
+		if booked {
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeConflict, "time period is already booked"))
 			return
 		}
 
@@ -174,8 +158,8 @@ func (h *handler) Create() http.HandlerFunc {
 			createdBy = customer.FullName
 		} else if uid == associateContact.Associate.Id {
 			createdBy = associateContact.Name
-		} else if assistantSnapshot, err := h.db.Collection("users").Doc(uid).Get(ctx); err == nil {
-			createdBy = assistantSnapshot.Data()["fullName"].(string)
+		} else if assistant, err := h.customersRepository.FindById(ctx, uid); err == nil {
+			createdBy = assistant.FullName
 		}
 
 		createdEvent := fmt.Sprintf("Created by %s on %s", createdBy, time.Now().Format("Jan 2 at 3:04PM (MST)"))
@@ -196,22 +180,24 @@ func (h *handler) Create() http.HandlerFunc {
 
 		if uid != customer.Id && uid != associateContact.Associate.Id {
 			assistantIDs, err := h.associateAssistantRepository.FindAllIDs(associateContact.Associate.Id)
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 				return
 			}
 			appointment.AssistantIDs = append(appointment.AssistantIDs, assistantIDs...)
 		}
 
-		appointment, err = h.appointsRepository.Save(appointment)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-			return
-		}
-
-		bookedData := map[string]interface{}{"date": startDate}
-		if _, err = h.db.Batch().
-			Create(h.db.Collection("users").Doc(associateContact.Associate.Id).Collection("bookedDates").Doc(appointment.Id), bookedData).
-			Create(businessRef.Collection("associates").Doc(associateContact.Associate.Id).Collection("bookedDates").Doc(appointment.Id), bookedData).
-			Commit(ctx); common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		appointment, err = h.appointsRepository.Save(ctx, appointment, func(appointment *model.Appointment) []events.Event {
+			return []events.Event{&events.AppointmentCreated{
+				BusinessID:    businessId,
+				AppointmentID: appointment.Id,
+				CustomerID:    customer.Id,
+				ContactID:     associateContact.Id,
+				StartDate:     *startDate,
+				EndDate:       *endDate,
+				CreatedBy:     uid,
+			}}
+		})
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 
@@ -225,48 +211,36 @@ func (h *handler) Create() http.HandlerFunc {
 func (h *handler) checkBlocking(ctx context.Context, resp http.ResponseWriter, uid string, associateContact *model.Contact, customerId string) bool {
 	if uid == customerId {
 		blocked, err := h.isCustomerBlocked(ctx, associateContact, customerId)
-		if errorCode := common.GRPCToHttpErrorCode(err); errorCode != http.StatusNotFound && common.RespondWithError(err, resp, errorCode) {
+		if apierrors.CodeOf(err) != apierrors.CodeNotFound && apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return true
 		}
 		if blocked {
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusAccepted),
-				Message: "Your contact is blocked",
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, "Your contact is blocked"))
 			return true
 		}
 		blocked, err = h.isAssociateBlocked(ctx, customerId, associateContact)
-		if errorCode := common.GRPCToHttpErrorCode(err); errorCode != http.StatusNotFound && common.RespondWithError(err, resp, errorCode) {
+		if apierrors.CodeOf(err) != apierrors.CodeNotFound && apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return true
 		}
 		if blocked {
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusAccepted),
-				Message: "You blocked this contact",
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, "You blocked this contact"))
 			return true
 		}
 	} else {
 		blocked, err := h.isAssociateBlocked(ctx, customerId, associateContact)
-		if errorCode := common.GRPCToHttpErrorCode(err); errorCode != http.StatusNotFound && common.RespondWithError(err, resp, errorCode) {
+		if apierrors.CodeOf(err) != apierrors.CodeNotFound && apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return true
 		}
 		if blocked {
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusAccepted),
-				Message: "Your contact is blocked",
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, "Your contact is blocked"))
 			return true
 		}
 		blocked, err = h.isCustomerBlocked(ctx, associateContact, customerId)
-		if errorCode := common.GRPCToHttpErrorCode(err); errorCode != http.StatusNotFound && common.RespondWithError(err, resp, errorCode) {
+		if apierrors.CodeOf(err) != apierrors.CodeNotFound && apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return true
 		}
 		if blocked {
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusAccepted),
-				Message: "You blocked this contact",
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, "You blocked this contact"))
 			return true
 		}
 	}
@@ -278,10 +252,10 @@ func (h *handler) Update() http.HandlerFunc {
 		AssistantId string      `json:"assistantId,omitempty"` //deprecated
 		CustomerId  string      `json:"customerId"`
 		AssociateId string      `json:"associateId,omitempty"`
-		Comment     string      `json:"comment,omitempty"`
+		Comment     string      `json:"comment,omitempty" validate:"max=1000"`
 		StartDate   *time.Time  `json:"startDate"`
-		EndDate     *time.Time  `json:"endDate"`
-		Remind      int64       `json:"remind,omitempty"`
+		EndDate     *time.Time  `json:"endDate" validate:"gtfield=StartDate"`
+		Remind      int64       `json:"remind,omitempty" validate:"min=0"`
 		Cals        *model.Cals `json:"cals,omitempty"`
 	}
 	type createAppointmentResponse struct {
@@ -297,9 +271,8 @@ func (h *handler) Update() http.HandlerFunc {
 		appointId := vars["appoint_id"]
 		uid := req.Context().Value("uid").(string)
 
-		body, err := ioutil.ReadAll(req.Body)
 		var request createAppointmentRequest
-		if err = json.Unmarshal(body, &request); common.RespondWithError(err, resp, http.StatusBadRequest) {
+		if err := h.validator.Decode(resp, req, &request); apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 
@@ -309,79 +282,46 @@ func (h *handler) Update() http.HandlerFunc {
 		startDate := request.StartDate
 		endDate := request.EndDate
 
+		if cals == nil && remind <= 0 && len(comment) == 0 && startDate == nil && endDate == nil {
+			resp.WriteHeader(http.StatusNotModified)
+			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{Status: http.StatusText(http.StatusNotModified), Message: "No data to update"})
+			return
+		}
+
+		appointment, err := h.appointsRepository.FindById(ctx, businessId, appointId)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+			return
+		}
+
 		changeType := "Changed"
-		var updates []firestore.Update
 		if cals != nil {
-			updates = append(updates, firestore.Update{
-				Path:  "cals",
-				Value: cals,
-			})
+			appointment.Cals = cals
 		}
 		if remind > 0 {
-			updates = append(updates, firestore.Update{
-				Path:  "remind",
-				Value: remind,
-			})
+			appointment.Remind = remind
 		}
 		if len(comment) > 0 {
-			updates = append(updates, firestore.Update{
-				Path:  "comment",
-				Value: comment,
-			})
+			appointment.Comment = comment
 		}
 		if startDate != nil {
-			updates = append(updates, firestore.Update{
-				Path:  "startDate",
-				Value: startDate,
-			})
+			appointment.StartDate = startDate
 			changeType = "Rescheduled"
 		}
 		if endDate != nil {
-			updates = append(updates, firestore.Update{
-				Path:  "endDate",
-				Value: endDate,
-			})
+			appointment.EndDate = endDate
 			changeType = "Rescheduled"
 		}
 
-		if updates == nil {
-			resp.WriteHeader(http.StatusNotModified)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{Status: http.StatusText(http.StatusNotModified), Message: "No data to update"})
-			return
-		}
-
-		appointment, err := h.appointsRepository.FindById(businessId, appointId)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-			return
-		}
 		var updatedBy string
 		if uid == appointment.Customer.Id {
 			updatedBy = appointment.Customer.FullName
 		} else {
 			updatedBy = appointment.Associate.Name
 		}
-		updatedEvent := fmt.Sprintf("%s by %s on %s", changeType, updatedBy, time.Now().Format("Jan 2 at 3:04PM (MST)"))
+		appointment.Events = append(appointment.Events,
+			fmt.Sprintf("%s by %s on %s", changeType, updatedBy, time.Now().Format("Jan 2 at 3:04PM (MST)")))
 
-		updates = append(updates, firestore.Update{
-			Path:  "events",
-			Value: firestore.ArrayUnion(updatedEvent),
-		})
-
-		batch := h.db.Batch()
-		batch.Update(h.db.Collection("businesses").Doc(businessId).Collection("appointments").Doc(appointId), updates)
-
-		if changeType == "Rescheduled" {
-			bookedDateUpdate := []firestore.Update{{
-				Path:  "date",
-				Value: startDate,
-			}}
-			batch.Update(h.db.Collection("users").Doc(appointment.Associate.Associate.Id).
-				Collection("bookedDates").Doc(appointment.Id), bookedDateUpdate).
-				Update(h.db.Collection("businesses").Doc(businessId).Collection("associates").
-					Doc(appointment.Associate.Associate.Id).Collection("bookedDates").Doc(appointment.Id), bookedDateUpdate)
-		}
-
-		if _, err = batch.Commit(ctx); common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		if err = h.appointsRepository.Update(ctx, appointment); apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 
@@ -399,8 +339,8 @@ func (h *handler) Cancel(resp http.ResponseWriter, req *http.Request) {
 	appointId := vars["appoint_id"]
 	uid := req.Context().Value("uid").(string)
 
-	appointment, err := h.appointsRepository.FindById(businessId, appointId)
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	appointment, err := h.appointsRepository.FindById(ctx, businessId, appointId)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 	var updatedBy string
@@ -414,16 +354,7 @@ func (h *handler) Cancel(resp http.ResponseWriter, req *http.Request) {
 	appointment.Events = append(appointment.Events,
 		fmt.Sprintf("Canceled by %s on %s", updatedBy, time.Now().Format("Jan 2 at 3:04PM (MST)")))
 
-	if _, err = h.appointsRepository.Update(appointment); common.RespondWithError(err, resp, http.StatusInternalServerError) {
-		return
-	}
-
-	if _, err = h.db.Batch().
-		Delete(h.db.Collection("users").Doc(appointment.Associate.Associate.Id).
-			Collection("bookedDates").Doc(appointment.Id)).
-		Delete(h.db.Collection("businesses").Doc(businessId).Collection("associates").
-			Doc(appointment.Associate.Associate.Id).Collection("bookedDates").Doc(appointment.Id)).
-		Commit(ctx); common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	if err = h.appointsRepository.Cancel(ctx, appointment); apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
@@ -432,54 +363,34 @@ func (h *handler) Cancel(resp http.ResponseWriter, req *http.Request) {
 }
 
 func (h *handler) Delete(resp http.ResponseWriter, req *http.Request) {
-	_, cancel := context.WithCancel(req.Context())
+	ctx, cancel := context.WithCancel(req.Context())
 	defer cancel()
 
 	vars := mux.Vars(req)
 	businessId := vars["business_id"]
 	appointId := vars["appoint_id"]
-	userId := req.Context().Value("uid").(string)
 
-	id, err := h.appointsRepository.DeleteById(businessId, appointId)
-	if common.RespondWithError(err, resp, http.StatusNotFound) {
+	err := h.appointsRepository.DeleteById(ctx, businessId, appointId)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeNotFound) {
 		return
 	}
 
-	log.Printf("appointment: %s has been deleted by user: %s\n", id, userId)
+	logger.FromContext(ctx).Info(ctx, "appointment deleted", "appointmentId", appointId)
 
 	resp.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(resp).Encode(&model.DeleteAppointmentResponse{BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusOK)}})
 }
 
-// todo: extract business customers repository
-func (h *handler) isCustomerBlocked(context context.Context, associateContact *model.Contact, customerId string) (bool, error) {
-	snapshot, err := h.db.BusinessCustomer(associateContact.Business.Id, customerId).Get(context)
-	if snapshot == nil {
-		return false, nil
-	}
+func (h *handler) isCustomerBlocked(ctx context.Context, associateContact *model.Contact, customerId string) (bool, error) {
+	customer, err := h.customersRepository.FindBusinessCustomer(ctx, associateContact.Business.Id, customerId)
 	if err != nil {
 		return false, err
 	}
-	var customer *model.Customer
-	err = snapshot.DataTo(&customer)
-	if err != nil {
-		return false, err
-	}
-	blocked := common.ArraysInclude(customer.InBlocked, associateContact.AssociateIDs)
-	return blocked, nil
+	return common.ArraysInclude(customer.InBlocked, associateContact.AssociateIDs), nil
 }
 
-func (h *handler) isAssociateBlocked(context context.Context, customerID string, associateContact *model.Contact) (bool, error) {
-	for _, associateID := range associateContact.AssociateIDs {
-		snapshot, err := h.db.BlockedUser(customerID, associateID).Get(context)
-		if snapshot == nil && err != nil {
-			return false, err
-		}
-		if snapshot != nil && snapshot.Exists() {
-			return true, nil
-		}
-	}
-	return false, nil
+func (h *handler) isAssociateBlocked(ctx context.Context, customerID string, associateContact *model.Contact) (bool, error) {
+	return h.customersRepository.HasBlocked(ctx, customerID, associateContact.AssociateIDs)
 }
 
 func (h *handler) SetupRouts(router *mux.Router) {
@@ -7,23 +7,22 @@ import (
 	"fmt"
 	"html/template"
 	"io"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"os"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	cloudStorage "cloud.google.com/go/storage"
 	"firebase.google.com/go/v4/storage"
 	"github.com/SebastiaanKlippert/go-wkhtmltopdf"
 	"github.com/VinothKuppanna/pigeon-go/configs"
-	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"golang.org/x/oauth2/google"
-	"google.golang.org/api/iterator"
 )
 
 const PathArchiveExport string = "/businesses/{business_id}/archive/export"
@@ -31,17 +30,27 @@ const PathArchiveExport string = "/businesses/{business_id}/archive/export"
 var brokenPhotoUrls = map[string]bool{}
 
 type handler struct {
-	config          *configs.Config
-	firestoreClient *firestore.Client
-	storageClient   *storage.Client
-	storageBucket   string
-	templateFile    string
+	config             *configs.Config
+	casesRepository    definition.CasesRepository
+	messagesRepository definition.MessagesRepository
+	notesRepository    definition.NotesRepository
+	storageClient      *storage.Client
+	storageBucket      string
+	templateFile       string
 }
 
-func NewHandler(config *configs.Config, firestoreClient *firestore.Client, storageClient *storage.Client, storageBucket string, templateFile string) *handler {
+func NewHandler(config *configs.Config,
+	casesRepository definition.CasesRepository,
+	messagesRepository definition.MessagesRepository,
+	notesRepository definition.NotesRepository,
+	storageClient *storage.Client,
+	storageBucket string,
+	templateFile string) *handler {
 	return &handler{
 		config,
-		firestoreClient,
+		casesRepository,
+		messagesRepository,
+		notesRepository,
 		storageClient,
 		storageBucket,
 		templateFile,
@@ -49,47 +58,33 @@ func NewHandler(config *configs.Config, firestoreClient *firestore.Client, stora
 }
 
 func (h *handler) export(resp http.ResponseWriter, req *http.Request) {
-	body, err := ioutil.ReadAll(req.Body)
-	var request *model.ExportArchiveRequest
-	err = json.Unmarshal(body, &request)
-	if common.RespondWithError(err, resp, http.StatusBadRequest) {
+	ctx := req.Context()
+	var request model.ExportArchiveRequest
+	if err := validation.Decode(resp, req, &request); err != nil {
+		apierrors.Respond(resp, err)
 		return
 	}
 
 	businessId := mux.Vars(req)["business_id"]
 	ids := request.Ids
 
-	if len(ids) == 0 {
-		resp.WriteHeader(http.StatusBadRequest)
-		errorMessage := fmt.Sprintf("%s: 'ids' parameter is required", http.StatusText(http.StatusBadRequest))
-		_ = json.NewEncoder(resp).Encode(errorMessage)
-		return
-	}
-
-	archiveRef := h.firestoreClient.Collection("businesses").Doc(businessId).Collection("archive")
-	var refs []*firestore.DocumentRef
-	for _, id := range ids {
-		refs = append(refs, archiveRef.Doc(id))
-	}
-
-	snapshots, err := h.firestoreClient.GetAll(context.Background(), refs)
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	chats, err := h.casesRepository.FindArchived(context.Background(), businessId, ids)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
 	var cases []*Case
-	for _, doc := range snapshots {
-		var chat *model.TextSession
-		err := doc.DataTo(&chat)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	for _, chat := range chats {
+		ccase := chat.Case
+		messages, err := h.messagesRepository.FindBetween(context.Background(), ccase.TextSessionId, ccase.OpenedDate, ccase.ClosedDate)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
-		ccase := chat.Case
-		messages := h.firestoreClient.Collection("textSessions").Doc(ccase.TextSessionId).Collection("messages").
-			OrderBy("createdDate", firestore.Asc).
-			StartAfter(ccase.OpenedDate).
-			EndAt(ccase.ClosedDate).
-			Documents(context.Background())
+
+		var notes, customerNotes []*Note
+		if request.IncludeNotes {
+			notes, customerNotes = h.findNotes(ctx, businessId, ccase)
+		}
 
 		newCase := Case{
 			Number:     ccase.Number,
@@ -100,6 +95,10 @@ func (h *handler) export(resp http.ResponseWriter, req *http.Request) {
 			Customer:   ccase.Customer.Name,
 			CustomerId: ccase.Customer.Id,
 			Associate:  ccase.AssociateName(),
+
+			IncludeNotes:  request.IncludeNotes,
+			Notes:         notes,
+			CustomerNotes: customerNotes,
 		}
 
 		cases = append(cases, &newCase)
@@ -108,13 +107,13 @@ func (h *handler) export(resp http.ResponseWriter, req *http.Request) {
 	archive := Archive{Cases: cases}
 
 	tmpl, err := parseTemplate(h.templateFile, archive)
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
 	//Use newTestPDFGenerator and append to page1 and TOC
 	pdfg, err := wkhtmltopdf.NewPDFGenerator()
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
@@ -136,7 +135,7 @@ func (h *handler) export(resp http.ResponseWriter, req *http.Request) {
 
 	pdfg.AddPage(page)
 	err = pdfg.Create()
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
@@ -144,38 +143,38 @@ func (h *handler) export(resp http.ResponseWriter, req *http.Request) {
 	pdfFilePath := fmt.Sprintf("./templates/%s.pdf", fileName)
 
 	err = pdfg.WriteFile(pdfFilePath)
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
 	pdfSize := fmt.Sprintf("%vkB", len(pdfg.Bytes())/1024)
-	log.Println(fmt.Sprintf("PDF size %s", pdfSize))
+	logger.FromContext(ctx).Debug(ctx, "archive PDF generated", "size", pdfSize)
 
 	client := h.storageClient
 
 	pdfFileName := fmt.Sprintf("export/%s.pdf", fileName)
 	err = writeFile(client, h.storageBucket, pdfFilePath, pdfFileName)
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 	htmlFileName := fmt.Sprintf("export/%s.html", fileName)
 	err = writeBytes(client, h.storageBucket, htmlFileName, htmlfile)
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
 	signedURLPdf, err := h.generateV4GetObjectSignedURL(pdfFileName)
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
 	signedURLHtml, err := h.generateV4GetObjectSignedURL(htmlFileName)
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
 	if os.Remove(pdfFilePath) != nil {
-		log.Printf("failed to remove file: %s. error: %v\n", pdfFilePath, err)
+		logger.FromContext(ctx).Warn(ctx, "failed to remove the archive PDF", "path", pdfFilePath, "error", err)
 	}
 
 	resp.WriteHeader(http.StatusOK)
@@ -282,6 +281,18 @@ type Case struct {
 	Customer   string
 	CustomerId string
 	Associate  string
+
+	IncludeNotes  bool
+	Notes         []*Note
+	CustomerNotes []*Note
+}
+
+type Note struct {
+	Author      string
+	Text        string
+	CreatedDate string
+	Edited      bool
+	Attachments []string
 }
 
 type Message struct {
@@ -293,39 +304,23 @@ type Message struct {
 	TheSame     bool
 }
 
-func mapMessages(docIterator *firestore.DocumentIterator) []*Message {
-	defer docIterator.Stop()
+func mapMessages(messages []*model.Message) []*Message {
 	var mess []*Message
 	var lastUid string
-	for {
-		doc, err := docIterator.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			break
-		}
-		data := doc.Data()
-		sender, ok := data["sender"].(map[string]interface{})
-		if !ok {
-			continue
-		}
-		uid, ok := sender["uid"].(string)
-		if !ok {
-			continue
-		}
-		timestamp, ok := data["createdDate"].(time.Time)
-		if !ok {
+	for _, data := range messages {
+		if data.Sender == nil || data.CreatedDate == nil {
 			continue
 		}
+		uid := data.Sender.Uid
+		timestamp := *data.CreatedDate
 		message := &Message{
-			Sender:      sender["name"].(string),
-			Text:        data["text"].(string),
+			Sender:      data.Sender.Name,
+			Text:        data.Text,
 			CreatedDate: timestamp.Format("Mon Jan 2 15:04:05 MST 2006"),
 			Time:        timestamp.Format("03:04 AM"),
 			TheSame:     true,
 		}
-		if photoUrl, ok := data["photoUrl"].(string); ok {
+		if photoUrl := data.PhotoUrl; len(photoUrl) > 0 {
 			if _, ok := brokenPhotoUrls[photoUrl]; !ok {
 				response, err := http.Get(photoUrl)
 				if err != nil || response.StatusCode != http.StatusOK {
@@ -344,6 +339,44 @@ func mapMessages(docIterator *firestore.DocumentIterator) []*Message {
 	return mess
 }
 
+// findNotes returns the notes of the case and of its customer. A failure to read them is logged,
+// and leaves them out of the archive.
+func (h *handler) findNotes(ctx context.Context, businessId string, ccase *model.Case) (notes, customerNotes []*Note) {
+	if len(ccase.Id) > 0 {
+		caseNotes, err := h.notesRepository.FindCaseNotes(ctx, businessId, ccase.Id)
+		if err != nil {
+			logger.FromContext(ctx).Error(ctx, "failed to read the notes", "error", err)
+		}
+		notes = mapNotes(caseNotes)
+	}
+	bizCustomerNotes, err := h.notesRepository.FindCustomerNotes(ctx, businessId, ccase.Customer.Id)
+	if err != nil {
+		logger.FromContext(ctx).Error(ctx, "failed to read the notes", "error", err)
+	}
+	return notes, mapNotes(bizCustomerNotes)
+}
+
+func mapNotes(notes []*model.Note) []*Note {
+	var items []*Note
+	for _, note := range notes {
+		item := &Note{
+			Author: note.AuthorName(),
+			Text:   note.Text,
+			Edited: len(note.History) > 0,
+		}
+		if note.CreatedDate != nil {
+			item.CreatedDate = note.CreatedDate.Local().Format("Mon Jan 2 15:04:05 MST 2006")
+		}
+		for _, attachment := range note.Attachments {
+			if attachment != nil {
+				item.Attachments = append(item.Attachments, attachment.Name)
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
 func parseTemplate(fileName string, data interface{}) (string, error) {
 	t, err := template.ParseFiles(fileName)
 	if err != nil {
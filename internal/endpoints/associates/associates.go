@@ -4,55 +4,63 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 
 	"cloud.google.com/go/firestore"
 	"firebase.google.com/go/v4/auth"
 	"firebase.google.com/go/v4/storage"
 	"github.com/VinothKuppanna/pigeon-go/configs"
-	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const AssociatesPath = "/businesses/{business_id}/associates"
+const AssociatePath = "/businesses/{business_id}/associates/{associate_id}"
 
 type handler struct {
 	config          *configs.Config
 	authClient      *auth.Client
 	firestoreClient *firestore.Client
 	storageClient   *storage.Client
+	claimsService   definition.ClaimsService
 }
 
-func New(config *configs.Config, authClient *auth.Client, firestoreClient *firestore.Client, storageClient *storage.Client) *handler {
-	return &handler{config, authClient, firestoreClient, storageClient}
+func New(config *configs.Config, authClient *auth.Client, firestoreClient *firestore.Client, storageClient *storage.Client,
+	claimsService definition.ClaimsService) *handler {
+	return &handler{config, authClient, firestoreClient, storageClient, claimsService}
 }
 
 func (h *handler) Create(resp http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
 	vars := mux.Vars(req)
 	businessId := vars["business_id"]
 
-	body, err := ioutil.ReadAll(req.Body)
-	if common.RespondWithError(err, resp, http.StatusBadRequest) {
-		return
-	}
-
 	type inviteRequest struct {
-		FullName      string `json:"fullName"`
-		Password      string `json:"password"`
-		Position      string `json:"position"`
-		Phone         string `json:"phone"`
+		FullName      string `json:"fullName" validate:"required,max=200"`
+		Password      string `json:"password" validate:"required,min=6,max=4096"`
+		Position      string `json:"position" validate:"max=200"`
+		Phone         string `json:"phone" validate:"e164"`
 		Photo         string `json:"photo"`
-		Email         string `json:"email"`
+		Email         string `json:"email" validate:"required,email"`
 		Role          int64  `json:"role"`
 		Mode          string `json:"mode"`
 		SendInvite    bool   `json:"sendInvite"`
 		CreateContact bool   `json:"createContact"`
 	}
-	var request *inviteRequest
-	err = json.Unmarshal(body, &request)
-	if common.RespondWithError(err, resp, http.StatusBadRequest) {
+	var request inviteRequest
+	if err := validation.Decode(resp, req, &request); err != nil {
+		apierrors.Respond(resp, err)
+		return
+	}
+	associateRoles, err := grantable(ctx, request.Role)
+	if err != nil {
+		apierrors.Respond(resp, err)
 		return
 	}
 
@@ -64,7 +72,7 @@ func (h *handler) Create(resp http.ResponseWriter, req *http.Request) {
 	userToCreate.PhoneNumber(request.Phone)
 
 	userRecord, err := h.authClient.CreateUser(context.Background(), userToCreate)
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
@@ -79,11 +87,7 @@ func (h *handler) Create(resp http.ResponseWriter, req *http.Request) {
 		Business: &model.BusinessItem{
 			Id: businessId,
 		},
-		Roles: &model.Roles{
-			Associate:  request.Role >= 0b0001,
-			Admin:      request.Role >= 0b0011,
-			SuperAdmin: request.Role == 0b0111,
-		},
+		Roles: associateRoles,
 	}
 
 	uid := userRecord.UID
@@ -147,7 +151,112 @@ func (h *handler) Create(resp http.ResponseWriter, req *http.Request) {
 
 	_, err = batch.Commit(context.Background())
 
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+
+	if _, err = h.claimsService.Sync(context.Background(), uid); err != nil {
+		logger.FromContext(ctx).Error(ctx, "failed to sync the claims of the associate", "associateUid", uid, "error", err)
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&model.BaseResponse{Status: http.StatusText(http.StatusOK)})
+}
+
+// Update changes the role or the disabled state of an associate and syncs the custom claims.
+func (h *handler) Update(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	vars := mux.Vars(req)
+	businessId := vars["business_id"]
+	uid := vars["associate_id"]
+
+	type updateRequest struct {
+		Role     *int64 `json:"role"`
+		Disabled *bool  `json:"disabled"`
+	}
+	var request *updateRequest
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
+		return
+	}
+	if request == nil || (request.Role == nil && request.Disabled == nil) {
+		apierrors.Respond(resp, apierrors.New(apierrors.CodeInvalidArgument, "role or disabled required"))
+		return
+	}
+
+	associate, err := h.businessAssociate(ctx, businessId, uid)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	if err = manageable(ctx, associate); err != nil {
+		apierrors.Respond(resp, err)
+		return
+	}
+	var updates []firestore.Update
+	if request.Role != nil {
+		associateRoles, err := grantable(ctx, *request.Role)
+		if err != nil {
+			apierrors.Respond(resp, err)
+			return
+		}
+		updates = append(updates, firestore.Update{Path: "roles", Value: associateRoles})
+	}
+	if request.Disabled != nil {
+		updates = append(updates, firestore.Update{Path: "disabled", Value: *request.Disabled})
+	}
+	_, err = h.firestoreClient.Batch().
+		Update(h.firestoreClient.Collection("users").Doc(uid), updates).
+		Update(h.firestoreClient.Collection("businesses").Doc(businessId).Collection("associates").Doc(uid), updates).
+		Commit(ctx)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	if request.Disabled != nil && *request.Disabled != associate.Disabled {
+		_, err = h.authClient.UpdateUser(ctx, uid, (&auth.UserToUpdate{}).Disabled(*request.Disabled))
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+			return
+		}
+	}
+	_, err = h.claimsService.Sync(ctx, uid)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&model.BaseResponse{Status: http.StatusText(http.StatusOK)})
+}
+
+// Delete removes the associate from the business and clears the custom claims.
+func (h *handler) Delete(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	vars := mux.Vars(req)
+	businessId := vars["business_id"]
+	uid := vars["associate_id"]
+
+	associate, err := h.businessAssociate(ctx, businessId, uid)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	if err = manageable(ctx, associate); err != nil {
+		apierrors.Respond(resp, err)
+		return
+	}
+	_, err = h.firestoreClient.Batch().
+		Update(h.firestoreClient.Collection("users").Doc(uid), []firestore.Update{
+			{Path: "business", Value: firestore.Delete},
+			{Path: "roles", Value: firestore.Delete},
+		}).
+		Delete(h.firestoreClient.Collection("businesses").Doc(businessId).Collection("associates").Doc(uid)).
+		Commit(ctx)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	err = h.claimsService.Clear(ctx, uid)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
@@ -155,6 +264,83 @@ func (h *handler) Create(resp http.ResponseWriter, req *http.Request) {
 	_ = json.NewEncoder(resp).Encode(&model.BaseResponse{Status: http.StatusText(http.StatusOK)})
 }
 
+func (h *handler) businessAssociate(ctx context.Context, businessId string, uid string) (*model.Associate, error) {
+	snapshot, err := h.firestoreClient.Collection("users").Doc(uid).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var associate *model.Associate
+	if err = snapshot.DataTo(&associate); err != nil {
+		return nil, err
+	}
+	if associate.BusinessID() != businessId {
+		return nil, status.Error(codes.NotFound, "associate not found in business")
+	}
+	return associate, nil
+}
+
+const (
+	roleAssociate  int64 = 0b0001
+	roleAdmin      int64 = 0b0011
+	roleSuperAdmin int64 = 0b0111
+)
+
+var errRoleNotAllowed = apierrors.New(apierrors.CodePermissionDenied, "Role exceeds the role of the caller")
+
+// grantable returns the roles the caller may grant. Super admins are never granted by this
+// endpoint and a caller cannot grant a role above their own.
+func grantable(ctx context.Context, role int64) (*model.Roles, error) {
+	var roles *model.Roles
+	switch role {
+	case roleAssociate:
+		roles = &model.Roles{Associate: true}
+	case roleAdmin:
+		roles = &model.Roles{Associate: true, Admin: true}
+	case roleSuperAdmin:
+		return nil, errRoleNotAllowed
+	default:
+		return nil, apierrors.New(apierrors.CodeInvalidArgument, fmt.Sprintf("Unknown role %#b", role))
+	}
+	if rank(roles) > rank(callerRoles(ctx)) {
+		return nil, errRoleNotAllowed
+	}
+	return roles, nil
+}
+
+// manageable checks the caller may update or delete the associate, i.e. the role of the
+// associate does not exceed the role of the caller.
+func manageable(ctx context.Context, associate *model.Associate) error {
+	if rank(associate.Roles) > rank(callerRoles(ctx)) {
+		return errRoleNotAllowed
+	}
+	return nil
+}
+
+// callerRoles reads the roles of the caller from the claims put in the context by the authenticator.
+func callerRoles(ctx context.Context) *model.Roles {
+	claims, ok := ctx.Value("claims").(*model.UserClaims)
+	if !ok || !claims.Associate || claims.Disabled {
+		return nil
+	}
+	return &model.Roles{Associate: claims.Associate, Admin: claims.Admin, SuperAdmin: claims.SuperAdmin}
+}
+
+func rank(roles *model.Roles) int {
+	switch {
+	case roles == nil:
+		return 0
+	case roles.SuperAdmin:
+		return 3
+	case roles.Admin:
+		return 2
+	case roles.Associate:
+		return 1
+	}
+	return 0
+}
+
 func (h *handler) SetupRouts(router *mux.Router) {
 	router.HandleFunc(AssociatesPath, h.Create).Methods(http.MethodPost)
+	router.HandleFunc(AssociatePath, h.Update).Methods(http.MethodPatch)
+	router.HandleFunc(AssociatePath, h.Delete).Methods(http.MethodDelete)
 }
@@ -0,0 +1,77 @@
+package associates
+
+import (
+	"context"
+	"testing"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+)
+
+func TestGrantable(t *testing.T) {
+	admin := &model.UserClaims{BusinessID: "b1", Associate: true, Admin: true}
+	superAdmin := &model.UserClaims{BusinessID: "b1", Associate: true, Admin: true, SuperAdmin: true}
+	associate := &model.UserClaims{BusinessID: "b1", Associate: true}
+	tests := []struct {
+		name    string
+		caller  *model.UserClaims
+		role    int64
+		want    *model.Roles
+		wantErr bool
+	}{
+		{"admin grants associate", admin, roleAssociate, &model.Roles{Associate: true}, false},
+		{"admin grants admin", admin, roleAdmin, &model.Roles{Associate: true, Admin: true}, false},
+		{"admin grants super admin", admin, roleSuperAdmin, nil, true},
+		{"super admin grants super admin", superAdmin, roleSuperAdmin, nil, true},
+		{"associate grants admin", associate, roleAdmin, nil, true},
+		{"no claims", nil, roleAssociate, nil, true},
+		{"unknown role", admin, 0b0101, nil, true},
+		{"no role", admin, 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.caller != nil {
+				ctx = context.WithValue(ctx, "claims", tt.caller)
+			}
+			got, err := grantable(ctx, tt.role)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("grantable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil && *got != *tt.want {
+				t.Errorf("grantable() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManageable(t *testing.T) {
+	admin := &model.UserClaims{BusinessID: "b1", Associate: true, Admin: true}
+	superAdmin := &model.UserClaims{BusinessID: "b1", Associate: true, Admin: true, SuperAdmin: true}
+	associate := &model.UserClaims{BusinessID: "b1", Associate: true}
+	tests := []struct {
+		name    string
+		caller  *model.UserClaims
+		roles   *model.Roles
+		wantErr bool
+	}{
+		{"admin manages associate", admin, &model.Roles{Associate: true}, false},
+		{"admin manages admin", admin, &model.Roles{Associate: true, Admin: true}, false},
+		{"admin manages super admin", admin, &model.Roles{Associate: true, Admin: true, SuperAdmin: true}, true},
+		{"super admin manages super admin", superAdmin, &model.Roles{Associate: true, SuperAdmin: true}, false},
+		{"associate manages admin", associate, &model.Roles{Associate: true, Admin: true}, true},
+		{"no claims", nil, &model.Roles{Associate: true}, true},
+		{"no roles", admin, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.caller != nil {
+				ctx = context.WithValue(ctx, "claims", tt.caller)
+			}
+			err := manageable(ctx, &model.Associate{Roles: tt.roles})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("manageable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
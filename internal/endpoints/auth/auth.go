@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
 	def "github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 	"github.com/gorilla/mux"
 )
@@ -41,7 +43,7 @@ func (h *handler) inviteUser() http.HandlerFunc {
 		var request *inviteUserRequest
 		err := json.NewDecoder(req.Body).Decode(&request)
 		if err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 
@@ -54,13 +56,13 @@ func (h *handler) inviteUser() http.HandlerFunc {
 			UserID:       request.UserID,
 		})
 		if err = linkResponse.Error; err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 
 		isiLink := h.linkService.CreateISILinkAssociate(ctx, def.CreateISILinkRequest{RawLink: linkResponse.RawLink})
 		if err = isiLink.Error; err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 
@@ -70,7 +72,7 @@ func (h *handler) inviteUser() http.HandlerFunc {
 			AuthLink:     isiLink.Link})
 
 		if err = inviteResponse.Error; err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 
@@ -90,24 +92,24 @@ func (h *handler) resetPassword() http.HandlerFunc {
 		var resetRequest *resetPasswordRequest
 		err := json.NewDecoder(req.Body).Decode(&resetRequest)
 		if err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 		linkResponse := h.authService.ResetPasswordLink(ctx, def.ResetPasswordRequest{Email: resetRequest.Email, UserType: resetRequest.UserType})
 		if err = linkResponse.Error; err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 		isiLink := h.linkService.CreateISILinkAssociate(ctx, def.CreateISILinkRequest{RawLink: linkResponse.RawLink})
 		if err = isiLink.Error; err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 
 		sendResponse := h.emailService.SendPasswordReset(ctx, def.SendPasswordResetRequest{Email: resetRequest.Email, UserType: resetRequest.UserType, PasswordResetLink: isiLink.Link})
 
 		if err = sendResponse.Error; err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 
@@ -117,6 +119,6 @@ func (h *handler) resetPassword() http.HandlerFunc {
 
 func (h *handler) SetupRouts(router *mux.Router) {
 	router.HandleFunc(PathInviteLink, h.inviteUser()).Methods(http.MethodPost)
-	router.HandleFunc(PathResetPassword, h.resetPassword()).Methods(http.MethodPost)
-	router.HandleFunc(PathResetPassword2, h.resetPassword()).Methods(http.MethodPost)
+	routes.HandleFunc(router, PathResetPassword, h.resetPassword()).Methods(http.MethodPost).Public()
+	routes.HandleFunc(router, PathResetPassword2, h.resetPassword()).Methods(http.MethodPost).Public()
 }
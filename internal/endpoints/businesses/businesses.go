@@ -4,8 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"math"
 	"net/http"
 	"regexp"
@@ -16,8 +14,12 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
 	"github.com/VinothKuppanna/pigeon-go/internal/cache"
-	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/metrics"
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/gorilla/mux"
@@ -105,7 +107,7 @@ func (h *handler) findBusiness(ctx context.Context, resp http.ResponseWriter, qu
 	request := maps.TextSearchRequest{Query: query}
 
 	latLng, err := maps.ParseLatLng(location)
-	if common.RespondWithError(err, resp, http.StatusBadRequest) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 		return
 	}
 	request.Location = &latLng
@@ -117,18 +119,20 @@ func (h *handler) findBusiness(ctx context.Context, resp http.ResponseWriter, qu
 	request.Radius = uint(radiusM)
 
 	mapClient, err := maps.NewClient(maps.WithAPIKey(h.placesApiKey))
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
+	start := time.Now()
 	response, err := mapClient.TextSearch(ctx, &request)
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	metrics.ObserveProvider(metrics.ProviderPlaces, "TextSearch", start, err)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
 	err = cache.Cache.Add(requestKey, response, 5*time.Minute)
 	if err != nil {
-		log.Println("caching failed", err)
+		logger.FromContext(ctx).Warn(ctx, "failed to cache the response", "error", err)
 	}
 
 	resp.WriteHeader(http.StatusFound)
@@ -157,14 +161,14 @@ func (h *handler) findBusinesses(ctx context.Context, resp http.ResponseWriter,
 		}
 
 		if err != nil {
-			log.Println(fmt.Errorf("error processing document: %v", err))
+			logger.FromContext(ctx).Error(ctx, "failed to read the businesses", "error", err)
 			break
 		}
 
 		var business *model.Business
 		err = snapshot.DataTo(&business)
 		if err != nil {
-			log.Println(fmt.Errorf("error snapshot.DataTo: %v", err))
+			logger.FromContext(ctx).Error(ctx, "failed to read the business", "error", err)
 			break
 		}
 		business.Id = snapshot.Ref.ID
@@ -181,7 +185,7 @@ func (h *handler) findBusinesses(ctx context.Context, resp http.ResponseWriter,
 		searchResponse.Status = "ZERO_RESULTS"
 
 		mapClient, err := maps.NewClient(maps.WithAPIKey(h.placesApiKey))
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 
@@ -200,16 +204,16 @@ func (h *handler) findBusinesses(ctx context.Context, resp http.ResponseWriter,
 		if len(locationbias) > 0 {
 			split1 := strings.Split(locationbias, ":")
 			biasType, err := maps.ParseFindPlaceFromTextLocationBiasType(split1[0])
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 				return
 			}
 			split2 := strings.Split(split1[1], "@")
 			radius, err := strconv.ParseInt(split2[0], 0, 0)
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 				return
 			}
 			latLng, err := maps.ParseLatLng(split2[1])
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 				return
 			}
 
@@ -217,8 +221,10 @@ func (h *handler) findBusinesses(ctx context.Context, resp http.ResponseWriter,
 			request.LocationBiasCenter = &latLng
 			request.LocationBiasRadius = int(radius)
 
+			start := time.Now()
 			placeFromTextResponse, err := mapClient.FindPlaceFromText(ctx, &request)
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+			metrics.ObserveProvider(metrics.ProviderPlaces, "FindPlaceFromText", start, err)
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 				return
 			}
 
@@ -248,7 +254,7 @@ func (h *handler) findBusinesses(ctx context.Context, resp http.ResponseWriter,
 	// get users accessed businesses
 	refs, err := h.db.CustomerBusinessesAccess(uid).DocumentRefs(ctx).GetAll()
 	if err != nil {
-		http.Error(resp, errors.Wrap(err, http.StatusText(http.StatusBadRequest)).Error(), http.StatusBadRequest)
+		apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 		return
 	}
 	// check businesses for protected access
@@ -296,14 +302,14 @@ func (h *handler) findNearbyBusinesses(ctx context.Context, resp http.ResponseWr
 		}
 
 		if err != nil {
-			log.Println("error processing document:", err)
+			logger.FromContext(ctx).Error(ctx, "failed to read the businesses", "error", err)
 			break
 		}
 
 		var business *model.Business
 		err = snapshot.DataTo(&business)
 		if err != nil {
-			log.Println("error snapshot.DataTo", err)
+			logger.FromContext(ctx).Error(ctx, "failed to read the business", "error", err)
 			break
 		}
 		business.Id = snapshot.Ref.ID
@@ -320,7 +326,7 @@ func (h *handler) findNearbyBusinesses(ctx context.Context, resp http.ResponseWr
 		searchResponse.Status = "ZERO_RESULTS"
 
 		mapClient, err := maps.NewClient(maps.WithAPIKey(h.placesApiKey))
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 
@@ -336,14 +342,16 @@ func (h *handler) findNearbyBusinesses(ctx context.Context, resp http.ResponseWr
 				split1 := strings.Split(locationbias, ":")
 				split2 := strings.Split(split1[1], "@")
 				latLng, err := maps.ParseLatLng(split2[1])
-				if common.RespondWithError(err, resp, http.StatusBadRequest) {
+				if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 					return
 				}
 				request.Location = &latLng
 			}
 
+			start := time.Now()
 			placesSearchResponse, err := mapClient.NearbySearch(ctx, &request)
-			if common.RespondWithError(err, resp, http.StatusBadRequest) {
+			metrics.ObserveProvider(metrics.ProviderPlaces, "NearbySearch", start, err)
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 				return
 			}
 
@@ -373,7 +381,7 @@ func (h *handler) findNearbyBusinesses(ctx context.Context, resp http.ResponseWr
 	// get users accessed businesses
 	refs, err := h.db.CustomerBusinessesAccess(uid).DocumentRefs(ctx).GetAll()
 	if err != nil {
-		http.Error(resp, errors.Wrap(err, http.StatusText(http.StatusBadRequest)).Error(), http.StatusBadRequest)
+		apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 		return
 	}
 	// check businesses for protected access
@@ -415,7 +423,7 @@ func (h *handler) checkRequested(ctx context.Context, uid string, businesses []*
 		}
 
 		if err != nil {
-			log.Println("error processing document:", err)
+			logger.FromContext(ctx).Error(ctx, "failed to read the businesses", "error", err)
 			break
 		}
 
@@ -466,6 +474,7 @@ func (ps placesSearchResult) biz() *model.Business {
 }
 
 func (h *handler) nearbyBusinesses(resp http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
 	values := req.URL.Query()
 	centerStr := values.Get("center")
 	radiusStr := values.Get("radius")
@@ -473,29 +482,29 @@ func (h *handler) nearbyBusinesses(resp http.ResponseWriter, req *http.Request)
 	locationStr := strings.Split(centerStr, ",")
 
 	latitude, err := strconv.ParseFloat(locationStr[0], 64)
-	if common.RespondWithError(err, resp, http.StatusBadRequest) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 		return
 	}
 
 	longitude, err := strconv.ParseFloat(locationStr[1], 64)
-	if common.RespondWithError(err, resp, http.StatusBadRequest) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 		return
 	}
 	location := []float64{latitude, longitude}
 
 	radius, err := strconv.ParseFloat(radiusStr, 64)
-	if common.RespondWithError(err, resp, http.StatusBadRequest) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 		return
 	}
 
-	queries := h.queriesForDocumentsAround(location, radius)
+	queries := h.queriesForDocumentsAround(ctx, location, radius)
 
 	var resultCount, matchCount, matchDistanceKm, totalDistanceKm, maxDistanceKm float64
 	nearbyBusinesses := map[string]*model.BusinessSmall{}
 	for _, query := range queries {
 		snapshots, err := query.Documents(context.Background()).GetAll()
 		if err != nil {
-			log.Println("error retrieving document:", err)
+			logger.FromContext(ctx).Error(ctx, "failed to read the businesses", "error", err)
 			break
 		}
 		for _, snapshot := range snapshots {
@@ -503,7 +512,7 @@ func (h *handler) nearbyBusinesses(resp http.ResponseWriter, req *http.Request)
 			var business *model.BusinessSmall
 			err = snapshot.DataTo(&business)
 			if err != nil {
-				log.Println("error parse business:", err)
+				logger.FromContext(ctx).Error(ctx, "failed to read the business", "error", err)
 				continue
 			}
 			business.Id = snapshot.Ref.ID
@@ -523,7 +532,8 @@ func (h *handler) nearbyBusinesses(resp http.ResponseWriter, req *http.Request)
 		}
 	}
 
-	log.Printf("the total %.00f query results are a total of %.00fk  from the center. docs len=%.00f match count=%.00f match distance=%.00f\n", resultCount, totalDistanceKm, maxDistanceKm, matchCount, matchDistanceKm)
+	logger.FromContext(ctx).Debug(ctx, "nearby businesses found", "results", resultCount, "totalDistanceKm", totalDistanceKm,
+		"maxDistanceKm", maxDistanceKm, "matches", matchCount, "matchDistanceKm", matchDistanceKm)
 
 	resp.WriteHeader(http.StatusOK)
 	bizValues := getBusinesses(nearbyBusinesses)
@@ -540,9 +550,9 @@ func (h *handler) nearbyBusinesses(resp http.ResponseWriter, req *http.Request)
 	_ = json.NewEncoder(resp).Encode(&data)
 }
 
-func (h *handler) queriesForDocumentsAround(center []float64, radiusKm float64) []*firestore.Query {
+func (h *handler) queriesForDocumentsAround(ctx context.Context, center []float64, radiusKm float64) []*firestore.Query {
 	geohashQueries := geohashQueries(center, radiusKm)
-	log.Println("geohash queries:", geohashQueries)
+	logger.FromContext(ctx).Debug(ctx, "geohash queries", "queries", len(geohashQueries))
 	queries := make([]*firestore.Query, len(geohashQueries))
 	ref := h.db.Collection("businesses")
 	for index, location := range geohashQueries {
@@ -585,18 +595,20 @@ func (h *handler) businessInfoRest() http.HandlerFunc {
 		if businessType == ExternalBusiness {
 			detailsReq := maps.PlaceDetailsRequest{PlaceID: businessId}
 			client, err := maps.NewClient(maps.WithAPIKey(h.placesApiKey))
-			if common.RespondWithError(err, resp, http.StatusBadRequest) {
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 				return
 			}
 
+			start := time.Now()
 			result, err := client.PlaceDetails(context.Background(), &detailsReq)
-			if common.RespondWithError(err, resp, http.StatusBadRequest) {
+			metrics.ObserveProvider(metrics.ProviderPlaces, "PlaceDetails", start, err)
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 				return
 			}
 
 			err = cache.Cache.Add(businessId, result, 5*time.Minute)
 			if err != nil {
-				log.Printf("error in caching: %v", err)
+				logger.FromContext(ctx).Warn(ctx, "failed to cache the business", "error", err)
 			}
 
 			_ = json.NewEncoder(resp).Encode(&businessResponse{
@@ -607,20 +619,20 @@ func (h *handler) businessInfoRest() http.HandlerFunc {
 		}
 
 		snapshot, err := h.db.Business(businessId).Get(ctx)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 
 		var b *model.Business
 		err = snapshot.DataTo(&b)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 		b.Id = snapshot.Ref.ID
 
 		err = cache.Cache.Add(b.Id, b, 5*time.Minute)
 		if err != nil {
-			log.Printf("error in caching: %v", err)
+			logger.FromContext(ctx).Warn(ctx, "failed to cache the business", "error", err)
 		}
 
 		_ = json.NewEncoder(resp).Encode(&businessResponse{
@@ -645,7 +657,7 @@ func (h *handler) businessInfo() http.HandlerFunc {
 
 		var br *businessRequest
 		err := json.NewDecoder(req.Body).Decode(&br)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 
@@ -658,20 +670,20 @@ func (h *handler) businessInfo() http.HandlerFunc {
 		}
 
 		snapshot, err := h.db.Business(br.BusinessID).Get(ctx)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 
 		var b *model.Business
 		err = snapshot.DataTo(&b)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 		b.Id = snapshot.Ref.ID
 
 		err = cache.Cache.Add(b.Id, b, 5*time.Minute)
 		if err != nil {
-			log.Printf("error in caching: %v", err)
+			logger.FromContext(ctx).Warn(ctx, "failed to cache the business", "error", err)
 		}
 
 		_ = json.NewEncoder(resp).Encode(&businessResponse{
@@ -683,24 +695,22 @@ func (h *handler) businessInfo() http.HandlerFunc {
 
 func (h *handler) searchPlaceByAddress() http.HandlerFunc {
 	type placeByAddressRequest struct {
-		Address string `json:"address"`
+		Address string `json:"address" validate:"required"`
 	}
 	return func(resp http.ResponseWriter, req *http.Request) {
-		data, err := ioutil.ReadAll(req.Body)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
-			return
-		}
 		var placeByAddressRequest *placeByAddressRequest
-		err = json.Unmarshal(data, &placeByAddressRequest)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		err := validation.Decode(resp, req, &placeByAddressRequest)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 		mapClient, err := maps.NewClient(maps.WithAPIKey(h.placesApiKey))
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 		geocodingRequest := maps.GeocodingRequest{Address: placeByAddressRequest.Address, Region: "US"}
+		start := time.Now()
 		geocodingResults, err := mapClient.Geocode(context.Background(), &geocodingRequest)
+		metrics.ObserveProvider(metrics.ProviderPlaces, "Geocode", start, err)
 		//placeFromTextRequest := maps.FindPlaceFromTextRequest{
 		//	Input:        placeByAddressRequest.Address,
 		//	InputType:    maps.FindPlaceFromTextInputTypeTextQuery,
@@ -722,7 +732,7 @@ func (h *handler) searchPlaceByAddress() http.HandlerFunc {
 		//	Name:      "doggie academy",
 		//}
 		//nearbyResults, err := mapClient.NearbySearch(context.Background(), &nearbySearchRequest)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 		_ = json.NewEncoder(resp).Encode(geocodingResults)
@@ -731,20 +741,16 @@ func (h *handler) searchPlaceByAddress() http.HandlerFunc {
 
 func (h *handler) placeDetails() http.HandlerFunc {
 	type placeDetailsRequest struct {
-		PlaceID string `json:"placeId"`
+		PlaceID string `json:"placeId" validate:"required"`
 	}
 	return func(resp http.ResponseWriter, req *http.Request) {
-		data, err := ioutil.ReadAll(req.Body)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
-			return
-		}
 		var placeDetailsRequest *placeDetailsRequest
-		err = json.Unmarshal(data, &placeDetailsRequest)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		err := validation.Decode(resp, req, &placeDetailsRequest)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 		mapClient, err := maps.NewClient(maps.WithAPIKey(h.placesApiKey))
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 		detailsRequest := maps.PlaceDetailsRequest{PlaceID: placeDetailsRequest.PlaceID,
@@ -754,8 +760,10 @@ func (h *handler) placeDetails() http.HandlerFunc {
 				maps.PlaceDetailsFieldMaskName,
 				maps.PlaceDetailsFieldMaskFormattedAddress,
 				maps.PlaceDetailsFieldMaskReviews}}
+		start := time.Now()
 		detailsResult, err := mapClient.PlaceDetails(context.Background(), &detailsRequest)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		metrics.ObserveProvider(metrics.ProviderPlaces, "PlaceDetails", start, err)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 		_ = json.NewEncoder(resp).Encode(detailsResult)
@@ -775,14 +783,14 @@ func (h *handler) requestBusinessAccess() http.HandlerFunc {
 		var request *accessRequest
 		err := json.NewDecoder(req.Body).Decode(&request)
 		if err != nil {
-			if common.RespondWithError(err, resp, http.StatusBadRequest) {
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 				return
 			}
 		}
 
 		err = h.accessBusiness(ctx, uid, request.BusinessID, request.AccessCode)
 		if err != nil {
-			if common.RespondWithError(err, resp, http.StatusForbidden) {
+			if apierrors.RespondWithError(err, resp, apierrors.CodePermissionDenied) {
 				return
 			}
 		}
@@ -808,14 +816,14 @@ func (h *handler) requestBusinessAccessRest() http.HandlerFunc {
 		var request *accessRequest
 		err := json.NewDecoder(req.Body).Decode(&request)
 		if err != nil {
-			if common.RespondWithError(err, resp, http.StatusBadRequest) {
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 				return
 			}
 		}
 
 		err = h.accessBusiness(ctx, uid, businessID, request.AccessCode)
 		if err != nil {
-			if common.RespondWithError(err, resp, http.StatusForbidden) {
+			if apierrors.RespondWithError(err, resp, apierrors.CodePermissionDenied) {
 				return
 			}
 		}
@@ -852,20 +860,20 @@ func (h *handler) accessBusiness(ctx context.Context, uid string, businessID str
 }
 
 func (h *handler) SetupRouts(router *mux.Router) {
-	router.HandleFunc(PathBusinesses, h.searchBusinesses).Methods(http.MethodGet)
-	router.HandleFunc(PathBusiness, h.businessInfoRest()).Methods(http.MethodGet)
-	router.HandleFunc(FindBusiness, h.businessInfo()).Methods(http.MethodPost)
+	routes.HandleFunc(router, PathBusinesses, h.searchBusinesses).Methods(http.MethodGet).Public()
+	routes.HandleFunc(router, PathBusiness, h.businessInfoRest()).Methods(http.MethodGet).Public()
+	routes.HandleFunc(router, FindBusiness, h.businessInfo()).Methods(http.MethodPost).Public()
 	router.HandleFunc(PathBusinessesNearby, h.nearbyBusinesses).Methods(http.MethodGet)
 	router.HandleFunc(SearchPlaceByAddress, h.searchPlaceByAddress()).Methods(http.MethodPost)
 	router.HandleFunc(PlaceDetails, h.placeDetails()).Methods(http.MethodPost)
 	router.HandleFunc(RequestBusinessAccess, h.requestBusinessAccess()).Methods(http.MethodPost)
 	router.HandleFunc(PathRequestBusinessAccess, h.requestBusinessAccessRest()).Methods(http.MethodPost)
 
-	router.HandleFunc(PathBusinessCategories, h.listBusinessCategories()).Methods(http.MethodGet)
-	router.HandleFunc(ListBusinessCategories, h.listBusinessCategories()).Methods(http.MethodPost)
+	routes.HandleFunc(router, PathBusinessCategories, h.listBusinessCategories()).Methods(http.MethodGet).Public()
+	routes.HandleFunc(router, ListBusinessCategories, h.listBusinessCategories()).Methods(http.MethodPost).Public()
 
-	router.HandleFunc(PathSearchBusinesses, h.searchBusinessPublicRest()).Methods(http.MethodGet)
-	router.HandleFunc(SearchBusinesses, h.searchBusinessPublic()).Methods(http.MethodPost)
+	routes.HandleFunc(router, PathSearchBusinesses, h.searchBusinessPublicRest()).Methods(http.MethodGet).Public()
+	routes.HandleFunc(router, SearchBusinesses, h.searchBusinessPublic()).Methods(http.MethodPost).Public()
 }
 
 func (h *handler) listBusinessCategories() func(http.ResponseWriter, *http.Request) {
@@ -882,19 +890,12 @@ func (h *handler) listBusinessCategories() func(http.ResponseWriter, *http.Reque
 		documents := h.db.BusinessCategories().OrderBy("index", firestore.Asc).Documents(ctx)
 		snapshots, err := documents.GetAll()
 		if err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(&categoriesResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: err.Error(),
-			})
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 		if len(snapshots) == 0 {
-			resp.WriteHeader(http.StatusNotFound)
-			_ = json.NewEncoder(resp).Encode(&categoriesResponse{
-				Status:  http.StatusText(http.StatusNotFound),
-				Message: errorCategoriesNotFound,
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeNotFound, errorCategoriesNotFound))
+			return
 		}
 		var cs []*model.BusinessCategory
 		for _, s := range snapshots {
@@ -933,39 +934,23 @@ func (h *handler) searchBusinessPublic() func(http.ResponseWriter, *http.Request
 		var br *businessesRequest
 		err := json.NewDecoder(req.Body).Decode(&br)
 		if err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(&businessesResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: err.Error(),
-			})
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 
 		if !validate(br) {
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(&businessesResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: errorEmptyRequest,
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeInvalidArgument, errorEmptyRequest))
 			return
 		}
 
 		bs, err := h.findBusinessesLocal(ctx, br.Query, br.CategoryID)
 		if err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(&businessesResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: err.Error(),
-			})
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 
 		if len(bs) == 0 {
-			resp.WriteHeader(http.StatusNotFound)
-			_ = json.NewEncoder(resp).Encode(&businessesResponse{
-				Status:  http.StatusText(http.StatusNotFound),
-				Message: errorBusinessesNotFound,
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeNotFound, errorBusinessesNotFound))
 			return
 		}
 
@@ -993,30 +978,18 @@ func (h *handler) searchBusinessPublicRest() func(http.ResponseWriter, *http.Req
 		categoryID := q.Get("categoryId")
 
 		if query == "" && categoryID == "" {
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(&businessesResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: errorEmptyRequest,
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeInvalidArgument, errorEmptyRequest))
 			return
 		}
 
 		bs, err := h.findBusinessesLocal(ctx, query, categoryID)
 		if err != nil {
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(&businessesResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: err.Error(),
-			})
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 
 		if len(bs) == 0 {
-			resp.WriteHeader(http.StatusNotFound)
-			_ = json.NewEncoder(resp).Encode(&businessesResponse{
-				Status:  http.StatusText(http.StatusNotFound),
-				Message: errorBusinessesNotFound,
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeNotFound, errorBusinessesNotFound))
 			return
 		}
 
@@ -4,16 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/gorilla/mux"
-	"github.com/pkg/errors"
 )
 
 const (
@@ -56,33 +55,15 @@ type baseResponse struct {
 }
 
 func (h *handler) createChannel() http.HandlerFunc {
-	// todo: add fields validation
 	type CreateChannelRequest struct {
-		BusinessID         string `json:"businessId"`
-		BusinessName       string `json:"businessName"`
-		ChannelName        string `json:"channelName"`
-		ChannelDescription string `json:"channelDescription"`
+		BusinessID         string `json:"businessId" validate:"required"`
+		BusinessName       string `json:"businessName" validate:"required"`
+		ChannelName        string `json:"channelName" validate:"required,max=100"`
+		ChannelDescription string `json:"channelDescription" validate:"max=1000"`
 		ImageUrl           string `json:"imageUrl"`
 		ChannelType        uint   `json:"channelType"`
 	}
 
-	validate := func(req *CreateChannelRequest) error {
-		var messages []string
-		if req.BusinessID == "" {
-			messages = append(messages, "businessId")
-		}
-		if req.BusinessName == "" {
-			messages = append(messages, "businessName")
-		}
-		if req.ChannelName == "" {
-			messages = append(messages, "channelName")
-		}
-		if len(messages) > 0 {
-			return errors.New(fmt.Sprintf("%s: must not be empty", strings.Join(messages, ",")))
-		}
-		return nil
-	}
-
 	type CreateChannelResponse struct {
 		Status  string         `json:"status"`
 		Message string         `json:"message,omitempty"`
@@ -92,27 +73,14 @@ func (h *handler) createChannel() http.HandlerFunc {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		// todo: START - potentially common logic
-		bytes, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			respondBadRequest(resp, err)
-			return
-		}
-		var createReq *CreateChannelRequest
-		err = json.Unmarshal(bytes, &createReq)
-		if err != nil {
-			respondBadRequest(resp, err)
-			return
-		}
-
-		err = validate(createReq)
+		var createReq CreateChannelRequest
+		err := validation.Decode(resp, req, &createReq)
 		if err != nil {
 			respondBadRequest(resp, err)
 			return
 		}
 
 		uid := ctx.Value("uid").(string)
-		// todo: END
 
 		userSnapshot, err := h.db.User(uid).Get(ctx)
 		if err != nil {
@@ -158,44 +126,23 @@ func (h *handler) createChannel() http.HandlerFunc {
 
 func (h *handler) deleteChannel() http.HandlerFunc {
 	type DeleteChannelRequest struct {
-		ChannelID  string `json:"channelId"`
-		BusinessID string `json:"BusinessId"`
+		ChannelID  string `json:"channelId" validate:"required"`
+		BusinessID string `json:"BusinessId" validate:"required"`
 	}
 	type DeleteChannelResponse struct {
 		Status  string `json:"status"`
 		Message string `json:"message"`
 	}
-	validateRequest := func(request *DeleteChannelRequest) error {
-		if request.BusinessID == "" {
-			return errors.New("Business ID is missed")
-		}
-		if request.ChannelID == "" {
-			return errors.New("Channel ID is missed")
-		}
-		return nil
-	}
 	return func(resp http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		// todo: START - potentially common logic
-		bytes, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			respondBadRequest(resp, err)
-			return
-		}
-		var deleteReq *DeleteChannelRequest
-		err = json.Unmarshal(bytes, &deleteReq)
+		var deleteReq DeleteChannelRequest
+		err := validation.Decode(resp, req, &deleteReq)
 		if err != nil {
 			respondBadRequest(resp, err)
 			return
 		}
-		// todo: END
-
-		if err = validateRequest(deleteReq); err != nil {
-			respondBadRequest(resp, err)
-			return
-		}
 
 		_, err = h.db.BusinessChannel(deleteReq.BusinessID, deleteReq.ChannelID).Delete(ctx, firestore.Exists)
 		if err != nil {
@@ -212,37 +159,16 @@ func (h *handler) deleteChannel() http.HandlerFunc {
 
 func (h *handler) subscribeChannel() http.HandlerFunc {
 	type subscribeRequest struct {
-		ChannelID  string `json:"channelId"`
-		BusinessID string `json:"businessId"`
-	}
-	validateRequest := func(request *subscribeRequest) error {
-		if request.BusinessID == "" {
-			return errors.New("Business ID is missed")
-		}
-		if request.ChannelID == "" {
-			return errors.New("Channel ID is missed")
-		}
-		return nil
+		ChannelID  string `json:"channelId" validate:"required"`
+		BusinessID string `json:"businessId" validate:"required"`
 	}
 	return func(resp http.ResponseWriter, req *http.Request) {
-		// todo: START - potentially common logic
-		bytes, err := ioutil.ReadAll(req.Body)
+		var subReq subscribeRequest
+		err := validation.Decode(resp, req, &subReq)
 		if err != nil {
 			respondBadRequest(resp, err)
 			return
 		}
-		var subReq *subscribeRequest
-		err = json.Unmarshal(bytes, &subReq)
-		if err != nil {
-			respondBadRequest(resp, err)
-			return
-		}
-		// todo: END
-
-		if err = validateRequest(subReq); err != nil {
-			respondBadRequest(resp, err)
-			return
-		}
 
 		err = h.subscribeToChannel(req.Context(), subReq.BusinessID, subReq.ChannelID)
 
@@ -279,37 +205,16 @@ func (h *handler) subscribeChannelRest() http.HandlerFunc {
 }
 func (h *handler) unsubscribeChannel() http.HandlerFunc {
 	type unsubscribeRequest struct {
-		ChannelID  string `json:"channelId"`
-		BusinessID string `json:"businessId"`
-	}
-	validateRequest := func(request *unsubscribeRequest) error {
-		if request.BusinessID == "" {
-			return errors.New("Business ID is missed")
-		}
-		if request.ChannelID == "" {
-			return errors.New("Channel ID is missed")
-		}
-		return nil
+		ChannelID  string `json:"channelId" validate:"required"`
+		BusinessID string `json:"businessId" validate:"required"`
 	}
 	return func(resp http.ResponseWriter, req *http.Request) {
-		// todo: START - potentially common logic
-		bytes, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			respondBadRequest(resp, err)
-			return
-		}
-		var unsubReq *unsubscribeRequest
-		err = json.Unmarshal(bytes, &unsubReq)
+		var unsubReq unsubscribeRequest
+		err := validation.Decode(resp, req, &unsubReq)
 		if err != nil {
 			respondBadRequest(resp, err)
 			return
 		}
-		// todo: END
-
-		if err = validateRequest(unsubReq); err != nil {
-			respondBadRequest(resp, err)
-			return
-		}
 
 		err = h.unsubscribeFromChannel(req.Context(), unsubReq.BusinessID, unsubReq.ChannelID)
 
@@ -347,37 +252,16 @@ func (h *handler) unsubscribeChannelRest() http.HandlerFunc {
 
 func (h *handler) readChannel() http.HandlerFunc {
 	type readChannelRequest struct {
-		ChannelID  string `json:"channelId"`
-		BusinessID string `json:"businessId"`
-	}
-	validateRequest := func(request *readChannelRequest) error {
-		if request.BusinessID == "" {
-			return errors.New("Business ID is missed")
-		}
-		if request.ChannelID == "" {
-			return errors.New("Channel ID is missed")
-		}
-		return nil
+		ChannelID  string `json:"channelId" validate:"required"`
+		BusinessID string `json:"businessId" validate:"required"`
 	}
 	return func(resp http.ResponseWriter, req *http.Request) {
-		// todo: START - potentially common logic
-		bytes, err := ioutil.ReadAll(req.Body)
+		var readReq readChannelRequest
+		err := validation.Decode(resp, req, &readReq)
 		if err != nil {
 			respondBadRequest(resp, err)
 			return
 		}
-		var readReq *readChannelRequest
-		err = json.Unmarshal(bytes, &readReq)
-		if err != nil {
-			respondBadRequest(resp, err)
-			return
-		}
-		// todo: END
-
-		if err = validateRequest(readReq); err != nil {
-			respondBadRequest(resp, err)
-			return
-		}
 
 		err = h.readChannelLocal(req.Context(), readReq.BusinessID, readReq.ChannelID)
 
@@ -492,6 +376,5 @@ func (h *handler) readChannelLocal(ctx context.Context, businessID string, chann
 }
 
 func respondBadRequest(resp http.ResponseWriter, err error) {
-	err = errors.Wrap(err, http.StatusText(http.StatusBadRequest))
-	http.Error(resp, err.Error(), http.StatusBadRequest)
+	apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 }
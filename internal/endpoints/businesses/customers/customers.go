@@ -3,16 +3,12 @@ package customers
 import (
 	"context"
 	"encoding/json"
-	"log"
 	"net/http"
-	"time"
 
-	"cloud.google.com/go/firestore"
-	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
-	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/identity"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 	"github.com/gorilla/mux"
-	"google.golang.org/api/iterator"
 )
 
 const PathBlockBusinessCustomer = "/businesses/{business_id}/businessCustomers/{customer_id}/block"
@@ -21,12 +17,13 @@ const PathBlockCustomer = "/BusinessService.blockCustomer"
 const PathUnblockCustomer = "/BusinessService.unblockCustomer"
 
 type handler struct {
-	firestore        *firestore.Client
-	customersService definition.CustomersService
+	customersRepository definition.CustomersRepository
+	customersService    definition.CustomersService
+	identity            *identity.Resolver
 }
 
-func NewHandler(firestore *firestore.Client) *handler {
-	return &handler{firestore, nil}
+func NewHandler(customersRepository definition.CustomersRepository, identityResolver *identity.Resolver) *handler {
+	return &handler{customersRepository, nil, identityResolver}
 }
 
 func (h *handler) blockCustomerRest() http.HandlerFunc {
@@ -59,15 +56,19 @@ func (h *handler) blockCustomer() http.HandlerFunc {
 		err := json.NewDecoder(request.Body).Decode(&r)
 		if err != nil {
 			// error
-			log.Println("decoding", err)
-			http.Error(response, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 			return
 		}
 
+		associateID, err := h.identity.ActingSubject(request, r.AssociateID)
+		if err != nil {
+			apierrors.RespondWithError(err, response, apierrors.CodePermissionDenied)
+			return
+		}
 		customerRequest := definition.BlockCustomerRequest{
 			BusinessId:  r.BusinessID,
 			CustomerId:  r.CustomerID,
-			AssociateId: ctx.Value("uid").(string),
+			AssociateId: associateID,
 		}
 		//_ = h.customersService.BlockCustomer(customerRequest)
 
@@ -76,74 +77,17 @@ func (h *handler) blockCustomer() http.HandlerFunc {
 }
 
 func (h *handler) blockUserInternal(ctx context.Context, customerRequest definition.BlockCustomerRequest, response http.ResponseWriter) {
-	customerRef := h.firestore.Collection(db.Businesses).Doc(customerRequest.BusinessId).Collection(db.BusinessCustomers).Doc(customerRequest.CustomerId)
-	customerSnapshot, err := customerRef.Get(ctx)
+	businessCustomer, err := h.customersRepository.FindBusinessCustomer(ctx, customerRequest.BusinessId, customerRequest.CustomerId)
 	if err != nil {
 		// error
-		log.Println("business customer get", err)
-		http.Error(response, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	if customerSnapshot == nil || !customerSnapshot.Exists() {
-		// error
-		log.Println("customer not found in business", err)
-		http.Error(response, "customer not found in business", http.StatusBadRequest)
+		apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 		return
 	}
 
-	var businessCustomer *model.Customer
-	err = customerSnapshot.DataTo(&businessCustomer)
+	err = h.customersRepository.Block(ctx, customerRequest.BusinessId, businessCustomer, customerRequest.AssociateId)
 	if err != nil {
 		// error
-		log.Println("customer data to struct", err)
-		http.Error(response, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	batch := h.firestore.Batch()
-	// block customer in business
-	batch.Update(customerRef, []firestore.Update{{Path: "blocked", Value: firestore.ArrayUnion(customerRequest.AssociateId)}})
-
-	documents := h.firestore.Collection(db.TextSessions).
-		Where("customer.id", "==", customerRequest.CustomerId).
-		Where("memberIDs", "array-contains", customerRequest.AssociateId).
-		Documents(ctx)
-	defer documents.Stop()
-
-	for {
-		snapshot, err := documents.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			continue
-		}
-		// block customer in chats
-		batch.Update(snapshot.Ref, []firestore.Update{{FieldPath: []string{"blockList", customerRequest.AssociateId}, Value: customerRequest.CustomerId}})
-	}
-
-	// block customer in user's block list todo: legacy
-	type blockedCustomer struct {
-		Id          string          `firestore:"id" json:"id"`
-		Name        string          `firestore:"name" json:"name"`
-		Description string          `firestore:"description" json:"description"`
-		PhotoUrl    *model.PhotoUrl `firestore:"photoUrl" json:"photoUrl"`
-		CreatedDate time.Time       `firestore:"createdDate" json:"createdDate"`
-	}
-	batch.Create(h.firestore.Collection(db.Users).Doc(customerRequest.AssociateId).Collection(db.BlockList).Doc(customerRequest.CustomerId), blockedCustomer{
-		Id:          customerRequest.CustomerId,
-		Name:        businessCustomer.FullName,
-		Description: "Customer",
-		PhotoUrl:    businessCustomer.PhotoUrl,
-		CreatedDate: time.Now(),
-	})
-
-	_, err = batch.Commit(ctx)
-	if err != nil {
-		// error
-		log.Println("commit batch", err)
-		http.Error(response, err.Error(), http.StatusBadRequest)
+		apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 		return
 	}
 	response.WriteHeader(http.StatusOK)
@@ -179,14 +123,19 @@ func (h *handler) unblockCustomer() http.HandlerFunc {
 		err := json.NewDecoder(request.Body).Decode(&r)
 		if err != nil {
 			// error
-			http.Error(response, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 			return
 		}
 
+		associateID, err := h.identity.ActingSubject(request, r.AssociateID)
+		if err != nil {
+			apierrors.RespondWithError(err, response, apierrors.CodePermissionDenied)
+			return
+		}
 		unblockRequest := definition.BlockCustomerRequest{
 			BusinessId:  r.BusinessID,
 			CustomerId:  r.CustomerID,
-			AssociateId: ctx.Value("uid").(string),
+			AssociateId: associateID,
 		}
 		//_ = h.customersService.BlockCustomer(customerRequest)
 
@@ -195,49 +144,17 @@ func (h *handler) unblockCustomer() http.HandlerFunc {
 }
 
 func (h *handler) unblockCustomerInternal(ctx context.Context, unblockRequest definition.BlockCustomerRequest, response http.ResponseWriter) {
-	customerRef := h.firestore.Collection(db.Businesses).Doc(unblockRequest.BusinessId).Collection(db.BusinessCustomers).Doc(unblockRequest.CustomerId)
-	customerSnapshot, err := customerRef.Get(ctx)
+	_, err := h.customersRepository.FindBusinessCustomer(ctx, unblockRequest.BusinessId, unblockRequest.CustomerId)
 	if err != nil {
 		// error
-		http.Error(response, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	if customerSnapshot == nil || !customerSnapshot.Exists() {
-		// error
-		http.Error(response, "customer not found in business", http.StatusBadRequest)
+		apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 		return
 	}
 
-	batch := h.firestore.Batch()
-	// block customer in business
-	batch.Update(customerRef, []firestore.Update{{Path: "blocked", Value: firestore.ArrayRemove(unblockRequest.AssociateId)}})
-
-	documents := h.firestore.Collection(db.TextSessions).
-		Where("customer.id", "==", unblockRequest.CustomerId).
-		Where("memberIDs", "array-contains", unblockRequest.AssociateId).
-		Documents(ctx)
-	defer documents.Stop()
-
-	for {
-		snapshot, err := documents.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			continue
-		}
-		// block customer in chats
-		batch.Update(snapshot.Ref, []firestore.Update{{FieldPath: []string{"blockList", unblockRequest.AssociateId}, Value: firestore.Delete}})
-	}
-
-	// block customer in user's block list todo: legacy
-	batch.Delete(h.firestore.Collection(db.Users).Doc(unblockRequest.AssociateId).Collection(db.BlockList).Doc(unblockRequest.CustomerId))
-
-	_, err = batch.Commit(ctx)
+	err = h.customersRepository.Unblock(ctx, unblockRequest.BusinessId, unblockRequest.CustomerId, unblockRequest.AssociateId)
 	if err != nil {
 		// error
-		http.Error(response, err.Error(), http.StatusBadRequest)
+		apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 		return
 	}
 	response.WriteHeader(http.StatusOK)
@@ -3,19 +3,17 @@ package directory
 import (
 	"context"
 
-	"cloud.google.com/go/firestore"
-	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 	"github.com/go-kit/kit/endpoint"
-	"google.golang.org/api/iterator"
 )
 
 type endpoints struct {
 	getBusinessDirectory endpoint.Endpoint
 }
 
-func makeEndpoints(db *db.Firestore) *endpoints {
-	return &endpoints{getBusinessDirectory: makeGetBusinessDirectoryEndpoint(db)}
+func makeEndpoints(directoryRepository definition.DirectoryRepository) *endpoints {
+	return &endpoints{getBusinessDirectory: makeGetBusinessDirectoryEndpoint(directoryRepository)}
 }
 
 type getBusinessDirectoryRequest struct {
@@ -28,28 +26,15 @@ type getBusinessDirectoryResponse struct {
 }
 
 // todo: extract service/use case layer
-func makeGetBusinessDirectoryEndpoint(db *db.Firestore) endpoint.Endpoint {
+func makeGetBusinessDirectoryEndpoint(directoryRepository definition.DirectoryRepository) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		req := request.(*getBusinessDirectoryRequest)
-		documents := db.BusinessDirectory(req.businessID).
-			Where("rules.VISIBILITY.visible", "==", true).
-			OrderBy("flatIndex", firestore.Asc).
-			Documents(ctx)
-		defer documents.Stop()
+		directory, err := directoryRepository.FindVisible(ctx, req.businessID)
+		if err != nil {
+			return &getBusinessDirectoryResponse{error: err}, err
+		}
 		var contacts []*contact
-		for {
-			snapshot, err := documents.Next()
-			if err == iterator.Done {
-				break
-			}
-			if err != nil {
-				return &getBusinessDirectoryResponse{error: err}, err
-			}
-			var dc *model.DirectoryContact
-			if err = snapshot.DataTo(&dc); err != nil {
-				return nil, err
-			}
-			dc.Id = snapshot.Ref.ID
+		for _, dc := range directory {
 			contacts = append(contacts, mapToContact(dc))
 		}
 		return &getBusinessDirectoryResponse{directory: contacts}, nil
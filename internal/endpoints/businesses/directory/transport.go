@@ -3,11 +3,12 @@ package directory
 import (
 	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
 	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses"
-	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
 )
@@ -44,29 +45,30 @@ type handler struct {
 	endpoints *endpoints
 }
 
-func NewHandler(db *db.Firestore) *handler {
-	return &handler{makeEndpoints(db)}
+func NewHandler(directoryRepository definition.DirectoryRepository) *handler {
+	return &handler{makeEndpoints(directoryRepository)}
 }
 
 func (h *handler) SetupRouts(router *mux.Router) {
 	getBusinessDirectoryRest := kithttp.NewServer(
 		h.endpoints.getBusinessDirectory,
 		decodeGetBusinessDirectoryRestRequest,
-		encodeGetBusinessDirectoryResponse)
+		encodeGetBusinessDirectoryResponse,
+		kithttp.ServerErrorEncoder(encodeError))
 
 	getBusinessDirectory := kithttp.NewServer(
 		h.endpoints.getBusinessDirectory,
 		decodeGetBusinessDirectoryRequest,
-		encodeGetBusinessDirectoryResponse)
+		encodeGetBusinessDirectoryResponse,
+		kithttp.ServerErrorEncoder(encodeError))
 
-	router.Handle(PathBusinessDirectory, getBusinessDirectoryRest).Methods(http.MethodGet)
-	router.Handle(LoadBusinessDirectory, getBusinessDirectory).Methods(http.MethodPost)
+	routes.Handle(router, PathBusinessDirectory, getBusinessDirectoryRest).Methods(http.MethodGet).Public()
+	routes.Handle(router, LoadBusinessDirectory, getBusinessDirectory).Methods(http.MethodPost).Public()
 }
 
 func decodeGetBusinessDirectoryRestRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
 	businessID := mux.Vars(req)["business_id"]
 	request = &getBusinessDirectoryRequest{businessID}
-	log.Println("decodeGetBusinessDirectoryRestRequest:", request)
 	return
 }
 
@@ -76,20 +78,21 @@ func decodeGetBusinessDirectoryRequest(_ context.Context, req *http.Request) (re
 		return
 	}
 	request = &getBusinessDirectoryRequest{httpr.BusinessID}
-	log.Println("decodeGetBusinessDirectoryRequest:", request)
 	return
 }
 
 func encodeGetBusinessDirectoryResponse(_ context.Context, resp http.ResponseWriter, response interface{}) error {
 	r := response.(*getBusinessDirectoryResponse)
-	httpr := &getBusinessDirectoryHttpResponse{
-		Status:    http.StatusText(http.StatusOK),
-		Directory: r.directory,
-	}
 	if r.error != nil {
-		httpr.Message = r.error.Error()
-		httpr.Status = http.StatusText(http.StatusBadRequest)
+		apierrors.RespondWithError(r.error, resp, apierrors.CodeInvalidArgument)
+		return nil
 	}
-	log.Println("encodeGetBusinessDirectoryResponse:", *r)
-	return json.NewEncoder(resp).Encode(httpr)
+	return json.NewEncoder(resp).Encode(&getBusinessDirectoryHttpResponse{
+		Status:    http.StatusText(http.StatusOK),
+		Directory: r.directory,
+	})
+}
+
+func encodeError(_ context.Context, err error, resp http.ResponseWriter) {
+	apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 }
@@ -3,11 +3,11 @@ package callbacks
 import (
 	"encoding/json"
 	"io/ioutil"
-	"log"
 	"net/http"
-	"os"
 	"time"
 
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 	ot "github.com/VolodymyrPobochii/opentok-go/pkg"
@@ -21,61 +21,61 @@ const (
 type handler struct {
 	chatsRepository          definition.TextSessionsRepository
 	chatVideoCallsRepository data.VideoCallsRepository
-	logger                   *log.Logger
 }
 
 func NewHandler(chatsRepository definition.TextSessionsRepository, chatVideoCallsRepository data.VideoCallsRepository) *handler {
-	logger := log.New(os.Stdout, "TOKBOX::", log.LstdFlags|log.Lshortfile)
-	return &handler{chatsRepository, chatVideoCallsRepository, logger}
+	return &handler{chatsRepository, chatVideoCallsRepository}
 }
 
 func (h *handler) videoSessionEvent() http.HandlerFunc {
 	return func(resp http.ResponseWriter, req *http.Request) {
 		defer resp.WriteHeader(http.StatusOK)
+		ctx := req.Context()
+		l := logger.FromContext(ctx).With("callback", "tokbox")
 
 		body, err := ioutil.ReadAll(req.Body)
 		if err != nil {
-			h.logger.Println("error", err)
+			l.Error(ctx, "failed to read the callback", "error", err)
 		}
 		var sessionEvent *ot.SessionCallback
 		err = json.Unmarshal(body, &sessionEvent)
 		if err != nil {
-			h.logger.Println("json.Unmarshal:", err)
+			l.Error(ctx, "failed to decode the callback", "error", err)
 		}
 		uid, chatId, videoCallId, err := sessionEvent.ParseData()
 		if err != nil {
-			h.logger.Println("sessionEvent.ParseData:", err)
+			l.Error(ctx, "failed to parse the session data", "error", err)
 		}
 		if len(uid) == 0 && len(chatId) == 0 && len(videoCallId) == 0 {
-			h.logger.Println("data string params are empty [uid, chatId, videoCallId]")
+			l.Warn(ctx, "session data is empty")
 			return
 		}
-		h.logger.Printf("uid=%s, chatId=%s, videoCallId=%s\n", uid, chatId, videoCallId)
+		l = l.With("sessionUid", uid, "chatId", chatId, "videoCallId", videoCallId)
 		videoCall, err := h.chatVideoCallsRepository.Find(chatId, videoCallId)
 		if err != nil {
-			h.logger.Println("chatVideoCallsRepository.Find:", err)
+			l.Error(ctx, "failed to find the video call", "error", err)
 			return
 		}
 		if !videoCall.EndedDate.IsZero() {
-			h.logger.Println("video call ended. no update")
+			l.Info(ctx, "video call ended, no update")
 			return
 		}
 		textSession, err := h.chatsRepository.Find(chatId) //todo: possible data inconsistency?
 		if err != nil {
-			h.logger.Println("chatsRepository.Find:", err)
+			l.Error(ctx, "failed to find the text session", "error", err)
 		}
 		switch sessionEvent.Callback.Event {
 		case ot.EventConnectionCreated:
-			h.logger.Printf("uid=%s connected to chatId=%s\n", uid, chatId)
+			l.Info(ctx, "connected")
 			videoCall.Connected[uid] = true
 		case ot.EventConnectionDestroyed:
-			h.logger.Printf("uid=%s disconnected to chatId=%s\n", uid, chatId)
+			l.Info(ctx, "disconnected")
 			videoCall.Connected[uid] = false
 		case ot.EventStreamCreated:
-			h.logger.Printf("uid=%s published to chatId=%s\n", uid, chatId)
+			l.Info(ctx, "published")
 			videoCall.Published[uid] = true
 		case ot.EventStreamDestroyed:
-			h.logger.Printf("uid=%s unpublished to chatId=%s\n", uid, chatId)
+			l.Info(ctx, "unpublished")
 			videoCall.Published[uid] = false
 		}
 		textSession.VideoCall = videoCall
@@ -86,11 +86,11 @@ func (h *handler) videoSessionEvent() http.HandlerFunc {
 		}
 		err = h.chatVideoCallsRepository.Update(chatId, videoCall)
 		if err != nil {
-			h.logger.Println("chatVideoCallsRepository.Update:", err)
+			l.Error(ctx, "failed to update the video call", "error", err)
 		}
 		err = h.chatsRepository.Update(textSession)
 		if err != nil {
-			h.logger.Println("chatsRepository.Update:", err)
+			l.Error(ctx, "failed to update the text session", "error", err)
 		}
 	}
 }
@@ -105,5 +105,5 @@ func allClientsDisconnected(connected map[string]bool) bool {
 }
 
 func (h *handler) SetupRouts(router *mux.Router) {
-	router.HandleFunc(PathCallbackVideoSession, h.videoSessionEvent()).Methods(http.MethodPost)
+	routes.HandleFunc(router, PathCallbackVideoSession, h.videoSessionEvent()).Methods(http.MethodPost).Public()
 }
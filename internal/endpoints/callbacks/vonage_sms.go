@@ -2,6 +2,7 @@
 package callbacks
 
 import (
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
 	"github.com/gorilla/mux"
 	"io/ioutil"
 	"log"
@@ -76,6 +77,6 @@ func (h *smsHandler) inboundMessage() http.HandlerFunc {
 }
 
 func (h *smsHandler) SetupRouts(router *mux.Router) {
-	router.Handle(PathInboundSMS, h.inboundMessage()).Methods(http.MethodPost)
-	router.Handle(PathBusinessInboundSMS, h.inboundMessage()).Methods(http.MethodPost)
+	routes.Handle(router, PathInboundSMS, h.inboundMessage()).Methods(http.MethodPost).Public()
+	routes.Handle(router, PathBusinessInboundSMS, h.inboundMessage()).Methods(http.MethodPost).Public()
 }
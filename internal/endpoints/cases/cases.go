@@ -3,19 +3,17 @@ package cases
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"time"
 
-	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
 	"github.com/VinothKuppanna/pigeon-go/internal/common"
-	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
 	"github.com/gorilla/mux"
-	"google.golang.org/api/iterator"
 )
 
 const (
@@ -23,27 +21,70 @@ const (
 	PathCaseAccept   = "/businesses/{business_id}/cases/{case_id}/accept"
 	PathCaseReject   = "/businesses/{business_id}/cases/{case_id}/reject"
 	PathCaseUnAccept = "/businesses/{business_id}/cases/{case_id}/unaccept"
+	PathCaseHandoff  = "/businesses/{business_id}/cases/{case_id}/handoff"
+
+	PathCaseHandoffAccept  = PathCaseHandoff + "/accept"
+	PathCaseHandoffDecline = PathCaseHandoff + "/decline"
 )
 
-var forwardExcludeMessageTypes = []int64{
-	int64(model.MessageTypeCaseClosed),
-	int64(model.MessageTypeForwardCase),
-	int64(model.MessageTypeAwayChoice),
+var forwardExcludeMessageTypes = []model.MessageType{
+	model.MessageTypeCaseClosed,
+	model.MessageTypeForwardCase,
+	model.MessageTypeAwayChoice,
+	model.MessageTypeForwarding,
 }
 
 type handler struct {
-	db              *db.Firestore
-	chatsRepository definition.TextSessionsRepository
+	casesRepository     definition.CasesRepository
+	chatsRepository     definition.TextSessionsRepository
+	messagesRepository  definition.MessagesRepository
+	directoryRepository definition.DirectoryRepository
+	customersRepository definition.CustomersRepository
+	pushService         definition.PushService
+	publisher           events.Publisher
 }
 
-func NewHandler(firestoreClient *db.Firestore, chatsRepository definition.TextSessionsRepository) *handler {
-	return &handler{firestoreClient, chatsRepository}
+func NewHandler(casesRepository definition.CasesRepository,
+	chatsRepository definition.TextSessionsRepository,
+	messagesRepository definition.MessagesRepository,
+	directoryRepository definition.DirectoryRepository,
+	customersRepository definition.CustomersRepository,
+	pushService definition.PushService,
+	publisher events.Publisher) *handler {
+	return &handler{
+		casesRepository,
+		chatsRepository,
+		messagesRepository,
+		directoryRepository,
+		customersRepository,
+		pushService,
+		publisher,
+	}
+}
+
+func caseForwarded(businessId string, srcCase *model.Case, toContactId string, textSessionId string, uid string) *events.CaseForwarded {
+	event := &events.CaseForwarded{
+		BusinessID:    businessId,
+		CaseID:        srcCase.Id,
+		ToContactID:   toContactId,
+		TextSessionID: textSessionId,
+		ForwardedBy:   uid,
+	}
+	if srcCase.Customer != nil {
+		event.CustomerID = srcCase.Customer.Id
+	}
+	if srcCase.Associate != nil {
+		event.FromContactID = srcCase.Associate.Id
+	}
+	return event
 }
 
 type forwardCaseRequest struct {
-	ToContactId string `json:"toContactId"`
+	ToContactId string `json:"toContactId" validate:"required"`
 	BusinessId  string `json:"businessId"`
 	CaseId      string `json:"caseId"`
+	Acceptance  bool   `json:"acceptance"`
+	Timeout     int64  `json:"timeout" validate:"min=0"` // seconds to accept the handoff
 }
 
 func (h *handler) forward(resp http.ResponseWriter, req *http.Request) {
@@ -52,26 +93,9 @@ func (h *handler) forward(resp http.ResponseWriter, req *http.Request) {
 	uid := ctx.Value("uid").(string)
 	vars := mux.Vars(req)
 
-	bytes, err := ioutil.ReadAll(req.Body)
-	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
-		return
-	}
-
 	var forwardRequest *forwardCaseRequest
-	err = json.Unmarshal(bytes, &forwardRequest)
-	if err != nil {
-		log.Printf("json.Unmarshal -> error: %v\n", err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
+	err := validation.Decode(resp, req, &forwardRequest)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 		return
 	}
 
@@ -82,188 +106,128 @@ func (h *handler) forward(resp http.ResponseWriter, req *http.Request) {
 	businessId := forwardRequest.BusinessId
 	caseId := forwardRequest.CaseId
 
-	if len(toContactId) == 0 {
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: "Missing request data",
-		})
+	srcCase, err := h.casesRepository.FindById(ctx, businessId, caseId)
+	if err != nil {
+		apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 		return
 	}
 
-	caseRef := h.db.Collection("businesses").Doc(businessId).Collection("cases").Doc(caseId)
-	caseSnapshot, err := caseRef.Get(ctx)
-	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
+	if srcCase.Handoff.IsPending(time.Now()) {
+		apierrors.Respond(resp, errCaseForwarding)
 		return
 	}
 
-	var srcCase *model.Case
-	err = caseSnapshot.DataTo(&srcCase)
+	toContact, err := h.forwardContact(ctx, businessId, toContactId, srcCase.Customer.Id)
 	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
+		apierrors.RespondWithError(err, resp, apierrors.CodeInternal)
 		return
 	}
 
-	if srcCase.Forwarding {
-		resp.WriteHeader(http.StatusPreconditionFailed)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusPreconditionFailed),
-			Message: "Case is being forwarded",
-		})
-		return
+	now := time.Now()
+	expiresDate := now.Add(immediateHandoffTimeout)
+	handoff := &model.CaseHandoff{
+		Mode:          model.HandoffModeImmediate,
+		Status:        model.HandoffPending,
+		RequestedBy:   uid,
+		From:          srcCase.Associate,
+		To:            &model.AssociateItem{Id: toContact.Id, Name: toContact.Name},
+		ToUIDs:        contactUIDs(toContact),
+		RequestedDate: &now,
+		ExpiresDate:   &expiresDate,
+	}
+	if forwardRequest.Acceptance {
+		handoff.Mode = model.HandoffModeAcceptance
+		expiresDate = now.Add(handoffTimeout(forwardRequest.Timeout))
 	}
 
 	// lock case for forwarding
-	_, err = caseSnapshot.Ref.Update(ctx, []firestore.Update{{Path: "forwarding", Value: true}}) // lock case
-	defer func() {
-		_, _ = caseSnapshot.Ref.Update(ctx, []firestore.Update{{Path: "forwarding", Value: firestore.Delete}})
-	}() // unlock case
+	locked, err := h.casesRepository.LockHandoff(ctx, businessId, caseId, handoff)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	if !locked {
+		apierrors.Respond(resp, errCaseForwarding)
+		return
+	}
 
-	if err != nil {
-		log.Println(err.Error())
-		errCode := common.GRPCToHttpErrorCode(err)
-		resp.WriteHeader(errCode)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(errCode),
-			Message: err.Error(),
+	if forwardRequest.Acceptance {
+		h.requestHandoff(ctx, srcCase, handoff)
+		resp.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(resp).Encode(handoffResponse{
+			BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusAccepted), Message: "Case handoff has been requested"},
+			Handoff:      handoff,
 		})
 		return
 	}
 
-	chatId := srcCase.TextSessionId
-	customerId := srcCase.Customer.Id
-
-	toContactSnapshot, err := h.db.Collection("businesses").Doc(businessId).
-		Collection("directory").Doc(toContactId).
-		Get(ctx)
-	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
+	_, err = h.transfer(ctx, uid, businessId, srcCase, toContact, resolved(handoff, model.HandoffAccepted, uid))
+	if err == definition.ErrHandoffResolved {
+		apierrors.Respond(resp, errHandoffResolved)
 		return
 	}
-	var toContact *model.Contact
-	if err := toContactSnapshot.DataTo(&toContact); err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
+	if err != nil {
+		// the case is unlocked by the expiry of the handoff if this fails
+		_ = h.resolveHandoff(ctx, businessId, caseId, handoff, model.HandoffFailed, uid)
+		apierrors.RespondWithError(err, resp, apierrors.CodeInternal)
 		return
 	}
-	toContact.Id = toContactSnapshot.Ref.ID
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(model.BaseResponse{Status: http.StatusText(http.StatusOK), Message: "Case has been forwarded"})
+}
+
+// forwardContact loads the target directory contact and checks the customer has no blocks against it.
+func (h *handler) forwardContact(ctx context.Context, businessId string, toContactId string, customerId string) (*model.Contact, error) {
+	toContact, err := h.directoryRepository.FindById(ctx, businessId, toContactId)
+	if err != nil {
+		return nil, apierrors.From(err, apierrors.CodeInvalidArgument)
+	}
 
 	blocked, err := h.isAssociateBlocked(ctx, customerId, toContact.AssociateIDs)
-	if errorCode := common.GRPCToHttpErrorCode(err); err != nil && errorCode != http.StatusNotFound {
-		log.Println(err.Error())
-		resp.WriteHeader(errorCode)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(errorCode),
-			Message: err.Error(),
-		})
-		return
+	if err != nil && apierrors.CodeOf(err) != apierrors.CodeNotFound {
+		return nil, err
 	}
 	if blocked {
-		resp.WriteHeader(http.StatusForbidden)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(http.StatusForbidden),
-			Message: "The contact is blocked by customer",
-		})
-		return
+		return nil, apierrors.New(apierrors.CodePermissionDenied, "The contact is blocked by customer")
 	}
 	blocked, err = h.isCustomerBlocked(ctx, customerId, toContact.AssociateIDs, businessId)
-	if errorCode := common.GRPCToHttpErrorCode(err); err != nil && errorCode != http.StatusNotFound {
-		log.Println(err.Error())
-		resp.WriteHeader(errorCode)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(errorCode),
-			Message: err.Error(),
-		})
-		return
+	if err != nil && apierrors.CodeOf(err) != apierrors.CodeNotFound {
+		return nil, err
 	}
 	if blocked {
-		resp.WriteHeader(http.StatusForbidden)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(http.StatusForbidden),
-			Message: "Associate blocked this customer",
-		})
-		return
+		return nil, apierrors.New(apierrors.CodePermissionDenied, "Associate blocked this customer")
 	}
+	return toContact, nil
+}
 
-	chatsRef := h.db.Collection("textSessions")
+// transfer moves the case with its messages to the chat of the customer with the target contact,
+// and reports it with CaseForwarded. The uid is the chat member on whose behalf the case is
+// forwarded.
+func (h *handler) transfer(ctx context.Context, uid string, businessId string, srcCase *model.Case, toContact *model.Contact,
+	handoff *model.CaseHandoff) (string, error) {
+	chatId := srcCase.TextSessionId
+	customerId := srcCase.Customer.Id
 
-	srcChatRef := chatsRef.Doc(chatId)
-	srcChatSnapshot, err := srcChatRef.Get(ctx)
+	srcChat, err := h.chatsRepository.Find(chatId)
 	if err != nil {
-		log.Println(err.Error())
-		statusCode := common.GRPCToHttpErrorCode(err)
-		resp.WriteHeader(statusCode)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(statusCode),
-			Message: err.Error(),
-		})
-		return
+		return "", err
 	}
-	var srcChat *model.TextSession
-	err = srcChatSnapshot.DataTo(&srcChat)
-	if err != nil {
-		log.Println(err.Error())
-		statusCode := common.GRPCToHttpErrorCode(err)
-		resp.WriteHeader(statusCode)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(statusCode),
-			Message: err.Error(),
-		})
-		return
+	var currentMember *model.Member
+	if srcChat.Members != nil {
+		currentMember = srcChat.Members.ByID(uid)
 	}
-	currentMember := srcChat.Members.ByID(uid)
 	if currentMember == nil {
-		resp.WriteHeader(http.StatusPreconditionFailed)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusPreconditionFailed),
-			Message: "Not a chat member",
-		})
-		return
+		return "", apierrors.New(apierrors.CodeFailedPrecondition, "Not a chat member")
 	}
 
-	srcChatCaseRef := srcChatRef.Collection("cases").Doc(caseId)
-	srcChatMessagesRef := srcChatRef.Collection("messages")
-
-	textSession, err := h.chatsRepository.FindActiveTextSession(customerId, toContactId)
+	textSession, err := h.chatsRepository.FindActiveTextSession(customerId, toContact.Id)
 	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
-		return
+		return "", apierrors.From(err, apierrors.CodeInvalidArgument)
 	}
 	now := time.Now()
-	batch := h.db.Batch()
 	if textSession != nil {
 		if textSession.HasOngoingCase() {
-			resp.WriteHeader(http.StatusPreconditionFailed)
-			_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-				Status:  http.StatusText(http.StatusPreconditionFailed),
-				Message: fmt.Sprintf("The case can't be forwarded. %s is already assisting the customer", textSession.Associate.Name),
-			})
-			return
+			return "", apierrors.Newf(apierrors.CodeFailedPrecondition,
+				"The case can't be forwarded. %s is already assisting the customer", textSession.Associate.Name)
 		}
 
 		caseAssociate := &model.AssociateItem{
@@ -284,232 +248,82 @@ func (h *handler) forward(resp http.ResponseWriter, req *http.Request) {
 			ForwardedDate: &now,
 			TextSessionId: textSession.Id,
 		}
-
-		batch.
-			Set(chatsRef.Doc(textSession.Id), textSession).
-			Set(chatsRef.Doc(textSession.Id).Collection("cases").Doc(caseId), textSession.Case).
-			Update(caseRef, []firestore.Update{
-				{Path: "textSessionId", Value: textSession.Case.TextSessionId},
-				{Path: "associate", Value: textSession.Case.Associate},
-				{Path: "forwardedDate", Value: textSession.Case.ForwardedDate},
-			}).
-			Update(srcChatRef, []firestore.Update{
-				{Path: "case", Value: firestore.Delete},
-				{Path: "lastMessage", Value: firestore.Delete}}).
-			Delete(srcChatCaseRef)
-
-		_, err = batch.Commit(ctx)
+	} else {
+		customer, err := h.customersRepository.FindById(ctx, customerId)
 		if err != nil {
-			log.Println(err.Error())
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: err.Error(),
-			})
-			return
+			return "", apierrors.From(err, apierrors.CodeInvalidArgument)
 		}
+		// the chat is created by the forward
+		textSession = model.NewActiveTextSession(customer, toContact, model.UserTypeCustomer)
 
-		destMessagesRef := chatsRef.Doc(textSession.Id).Collection("messages")
-		documentsIterator := srcChatMessagesRef.
-			Where("createdDate", ">=", srcCase.OpenedDate).
-			OrderBy("createdDate", firestore.Asc).
-			Documents(ctx)
-
-		err = safeBatch(ctx, h.db.Client, documentsIterator, destMessagesRef, processMessage(textSession.Id, textSession.MemberIDs))
-
-		if err != nil {
-			log.Println(err.Error())
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: err.Error(),
-			})
-			return
-		}
-
-		messageData := map[string]interface{}{
-			"sender": map[string]interface{}{
-				"uid":       currentMember.Uid,
-				"contactId": currentMember.Id,
-				"name":      currentMember.Name,
-				"type":      model.MessageSenderTypeSystem},
-			"recipient": map[string]string{
-				"uid":       textSession.Associate.Uid,
-				"contactId": textSession.Associate.Id,
-				"name":      textSession.Associate.Name,
-			},
-			"text":             "Case has been forwarded",
-			"action":           "forwarded case to",
-			"createdDate":      time.Now(),
-			"type":             model.MessageTypeForwardCase,
-			"textSessionId":    chatId,
-			"newTextSessionId": textSession.Id,
-			"memberIDs":        []string{uid, customerId},
-		}
-		_, err = srcChatMessagesRef.NewDoc().Create(ctx, messageData)
-		if err != nil {
-			log.Println(err.Error())
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: err.Error(),
-			})
-			return
+		caseAssociate := &model.AssociateItem{
+			Id:   toContact.Id,
+			Name: toContact.Name,
 		}
-
-		messageData["memberIDs"] = []string{textSession.Associate.Uid, customerId}
-		_, err = destMessagesRef.NewDoc().Create(ctx, messageData)
-		if err != nil {
-			log.Println(err.Error())
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: err.Error(),
-			})
-			return
+		businessCase := &model.Case{
+			Id:            srcCase.Id,
+			Name:          srcCase.Name,
+			Business:      srcCase.Business,
+			Customer:      srcCase.Customer,
+			Associate:     caseAssociate,
+			Closed:        srcCase.Closed,
+			Number:        srcCase.Number,
+			Priority:      srcCase.Priority,
+			Status:        srcCase.Status,
+			OpenedDate:    srcCase.OpenedDate,
+			ForwardedDate: &now,
 		}
-		resp.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{Status: http.StatusText(http.StatusOK)})
-		return
+		textSession.Case = businessCase
 	}
 
-	customerSnapshot, err := h.db.Collection("users").Doc(customerId).Get(ctx)
-	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
-		return
+	forwarded := func(chat *model.TextSession) []events.Event {
+		return []events.Event{caseForwarded(businessId, srcCase, toContact.Id, chat.Id, uid)}
 	}
-	var customer *model.Customer
-	if err := customerSnapshot.DataTo(&customer); err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
-		return
+	err = h.casesRepository.Forward(ctx, businessId, srcCase, textSession, handoff, forwarded)
+	if err == definition.ErrHandoffResolved {
+		return "", err
 	}
-	customer.Id = customerSnapshot.Ref.ID
-
-	caseAssociate := &model.AssociateItem{
-		Id:   toContact.Id,
-		Name: toContact.Name,
-	}
-	businessCase := &model.Case{
-		Id:            srcCase.Id,
-		Name:          srcCase.Name,
-		Business:      srcCase.Business,
-		Customer:      srcCase.Customer,
-		Associate:     caseAssociate,
-		Closed:        srcCase.Closed,
-		Number:        srcCase.Number,
-		Priority:      srcCase.Priority,
-		Status:        srcCase.Status,
-		OpenedDate:    srcCase.OpenedDate,
-		ForwardedDate: &now,
-	}
-	// create chat
-	textSession, err = h.chatsRepository.CreateActiveChatWithCase(customer, toContact, model.UserTypeCustomer, businessCase)
 	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
-		return
+		return "", apierrors.From(err, apierrors.CodeInvalidArgument)
 	}
-	batch.
-		Set(chatsRef.Doc(textSession.Id), textSession).
-		Set(chatsRef.Doc(textSession.Id).Collection("cases").Doc(caseId), textSession.Case).
-		Update(caseRef, []firestore.Update{
-			{Path: "textSessionId", Value: textSession.Case.TextSessionId},
-			{Path: "associate", Value: textSession.Case.Associate},
-			{Path: "forwardedDate", Value: textSession.Case.ForwardedDate},
-		}).
-		Update(srcChatRef, []firestore.Update{
-			{Path: "case", Value: firestore.Delete},
-			{Path: "lastMessage", Value: firestore.Delete}}).
-		Delete(srcChatCaseRef)
-
-	_, err = batch.Commit(ctx)
-	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
-		return
-	}
-
-	destMessagesRef := chatsRef.Doc(textSession.Id).Collection("messages")
-	documentsIterator := srcChatMessagesRef.
-		Where("createdDate", ">=", srcCase.OpenedDate).
-		OrderBy("createdDate", firestore.Asc).Documents(ctx)
-
-	err = safeBatch(ctx, h.db.Client, documentsIterator, destMessagesRef, processMessage(textSession.Id, textSession.MemberIDs))
-
+	err = h.messagesRepository.Move(ctx, chatId, textSession.Id, srcCase.OpenedDate, textSession.MemberIDs, forwardExcludeMessageTypes)
 	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
-		return
+		return "", apierrors.From(err, apierrors.CodeInvalidArgument)
 	}
 
-	messageData := map[string]interface{}{
-		"sender": map[string]interface{}{
-			"uid":       currentMember.Uid,
-			"contactId": currentMember.Id,
-			"name":      currentMember.Name,
-			"type":      model.MessageSenderTypeSystem},
-		"recipient": map[string]string{
-			"uid":       textSession.Associate.Uid,
-			"contactId": textSession.Associate.Id,
-			"name":      textSession.Associate.Name,
+	createdDate := time.Now()
+	message := &model.Message{
+		Sender: &model.MessageSender{
+			Uid:       currentMember.Uid,
+			ContactId: currentMember.Id,
+			Name:      currentMember.Name,
+			Type:      model.MessageSenderTypeSystem,
 		},
-		"text":        "Case has been forwarded",
-		"action":      "forwarded case to",
-		"createdDate": time.Now(),
-		"type":        model.MessageTypeForwardCase,
-	}
-	messageData["memberIDs"] = []string{uid, customerId}
-	messageData["textSessionId"] = chatId
-	messageData["newTextSessionId"] = textSession.Id
-
-	_, err = srcChatMessagesRef.NewDoc().Create(ctx, messageData)
-	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
-		return
-	}
-	messageData["memberIDs"] = []string{textSession.Associate.Uid, customerId}
-	messageData["textSessionId"] = textSession.Id
-	delete(messageData, "newTextSessionId")
-
-	_, err = destMessagesRef.NewDoc().Create(ctx, messageData)
-	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
-		return
-	}
-	resp.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(resp).Encode(model.BaseResponse{Status: http.StatusText(http.StatusOK), Message: "Case has been forwarded"})
+		Recipient: &model.MessageRecipient{
+			Uid:       textSession.Associate.Uid,
+			ContactId: textSession.Associate.Id,
+			Name:      textSession.Associate.Name,
+		},
+		Text:             "Case has been forwarded",
+		Action:           "forwarded case to",
+		CreatedDate:      &createdDate,
+		Type:             model.MessageTypeForwardCase,
+		TextSessionId:    chatId,
+		NewTextSessionId: textSession.Id,
+		MemberIDs:        []string{uid, customerId},
+	}
+	if _, err = h.messagesRepository.Save(ctx, chatId, message, nil); err != nil {
+		return "", apierrors.From(err, apierrors.CodeInvalidArgument)
+	}
+
+	forwardedMessage := *message
+	forwardedMessage.MemberIDs = []string{textSession.Associate.Uid, customerId}
+	forwardedMessage.TextSessionId = textSession.Id
+	forwardedMessage.NewTextSessionId = ""
+	if _, err = h.messagesRepository.Save(ctx, textSession.Id, &forwardedMessage, nil); err != nil {
+		return "", apierrors.From(err, apierrors.CodeInvalidArgument)
+	}
+	return textSession.Id, nil
 }
 
 func (h *handler) accept(resp http.ResponseWriter, req *http.Request) {
@@ -523,88 +337,45 @@ func (h *handler) accept(resp http.ResponseWriter, req *http.Request) {
 		TextSession   *model.TextSession `json:"textSession"`
 	}
 
+	uid, _ := ctx.Value("uid").(string)
 	businessId := mux.Vars(req)["business_id"]
 	caseId := mux.Vars(req)["case_id"]
 
-	caseRef := h.db.Collection("businesses").Doc(businessId).Collection("cases").Doc(caseId)
-	snapshot, err := caseRef.Get(ctx)
+	caseData, err := h.casesRepository.FindById(ctx, businessId, caseId)
 	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
+		apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 		return
 	}
 
-	var caseData *model.Case
-	err = snapshot.DataTo(&caseData)
-	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
-		return
-	}
-
-	chatRef := h.db.Collection("textSessions").Doc(caseData.TextSessionId)
-	chatCaseRef := chatRef.Collection("cases").Doc(caseId)
-
 	// accept case
 	now := time.Now()
 	caseData.Status = model.CaseAccepted
 	caseData.AcceptedDate = &now
-	updateData := caseData.Map()
-	updateData["id"] = caseId
-
-	_, err = h.db.Batch().
-		Set(caseRef, caseData).
-		Set(chatCaseRef, caseData).
-		Update(chatRef, []firestore.Update{
-			{
-				Path:  "case",
-				Value: updateData,
-			},
-		}).
-		Commit(ctx)
 
-	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
-		return
+	accepted := &events.CaseAccepted{
+		BusinessID:    businessId,
+		CaseID:        caseId,
+		TextSessionID: caseData.TextSessionId,
+		AcceptedBy:    uid,
 	}
-
-	// todo: add conversation rules check
-	snapshot, err = chatRef.Get(ctx)
+	if caseData.Customer != nil {
+		accepted.CustomerID = caseData.Customer.Id
+	}
+	if caseData.Associate != nil {
+		accepted.AssociateID = caseData.Associate.Id
+	}
+	err = h.casesRepository.Save(ctx, businessId, caseData, accepted)
 	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
+		apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 		return
 	}
 
-	var chat *model.TextSession
-	err = snapshot.DataTo(&chat)
+	// todo: add conversation rules check
+	chat, err := h.chatsRepository.Find(caseData.TextSessionId)
 	if err != nil {
-		log.Println(err.Error())
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
+		apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 		return
 	}
-	chat.Id = snapshot.Ref.ID
 	response := acceptCaseResponse{
 		BaseResponse:  model.BaseResponse{Status: http.StatusText(http.StatusOK)},
 		TextSessionId: chat.Id,
@@ -613,7 +384,7 @@ func (h *handler) accept(resp http.ResponseWriter, req *http.Request) {
 	resp.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(resp).Encode(&response)
 	if err != nil {
-		log.Println(err.Error())
+		logger.FromContext(ctx).Error(ctx, "failed to encode the response", "error", err)
 	}
 }
 
@@ -624,62 +395,27 @@ func (h *handler) reject(resp http.ResponseWriter, req *http.Request) {
 	businessId := mux.Vars(req)["business_id"]
 	caseId := mux.Vars(req)["case_id"]
 
-	caseRef := h.db.Collection("businesses").Doc(businessId).Collection("cases").Doc(caseId)
-	snapshot, err := caseRef.Get(ctx)
+	caseData, err := h.casesRepository.FindById(ctx, businessId, caseId)
 	if err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
+		apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 		return
 	}
 
-	var caseData *model.Case
-	err = snapshot.DataTo(&caseData)
-	if err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
-		return
-	}
-
-	chatRef := h.db.Collection("textSessions").Doc(caseData.TextSessionId)
-	chatCaseRef := chatRef.Collection("cases").Doc(caseId)
-
 	// reject case
 	now := time.Now()
 	caseData.Status = model.CaseRejected
 	caseData.RejectedDate = &now
-	updateData := caseData.Map()
-	updateData["id"] = caseId
-
-	_, err = h.db.Batch().
-		Set(caseRef, caseData).
-		Set(chatCaseRef, caseData).
-		Update(chatRef, []firestore.Update{
-			{
-				Path:  "case",
-				Value: updateData,
-			},
-		}).
-		Commit(ctx)
 
+	err = h.casesRepository.Save(ctx, businessId, caseData)
 	if err != nil {
-		resp.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusInternalServerError),
-			Message: err.Error(),
-		})
+		apierrors.RespondWithError(err, resp, apierrors.CodeInternal)
 		return
 	}
 
 	resp.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(resp).Encode(&model.BaseResponse{Status: http.StatusText(http.StatusOK)})
 	if err != nil {
-		log.Println(err.Error())
+		logger.FromContext(ctx).Error(ctx, "failed to encode the response", "error", err)
 	}
 }
 
@@ -690,94 +426,44 @@ func (h *handler) unaccept(resp http.ResponseWriter, req *http.Request) {
 	businessId := mux.Vars(req)["business_id"]
 	caseId := mux.Vars(req)["case_id"]
 
-	caseRef := h.db.Collection("businesses").Doc(businessId).Collection("cases").Doc(caseId)
-	snapshot, err := caseRef.Get(ctx)
-	if err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
-		return
-	}
-
-	var caseData *model.Case
-	err = snapshot.DataTo(&caseData)
+	caseData, err := h.casesRepository.FindById(ctx, businessId, caseId)
 	if err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
+		apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 		return
 	}
 
-	chatRef := h.db.Collection("textSessions").Doc(caseData.TextSessionId)
-	chatCaseRef := chatRef.Collection("cases").Doc(caseId)
-
 	// request case
 	caseData.Status = model.CaseRequested
 	caseData.AcceptedDate = nil
 	caseData.RejectedDate = nil
-	updateData := caseData.Map()
-	updateData["id"] = caseId
-
-	_, err = h.db.Batch().
-		Set(caseRef, caseData).
-		Set(chatCaseRef, caseData).
-		Update(chatRef, []firestore.Update{
-			{
-				Path:  "case",
-				Value: updateData,
-			},
-		}).
-		Commit(ctx)
 
+	requested := &events.CaseRequested{BusinessID: businessId, CaseID: caseId, TextSessionID: caseData.TextSessionId}
+	if caseData.Customer != nil {
+		requested.CustomerID = caseData.Customer.Id
+	}
+	err = h.casesRepository.Save(ctx, businessId, caseData, requested)
 	if err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
+		apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 		return
 	}
 
 	resp.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(resp).Encode(&model.BaseResponse{Status: http.StatusText(http.StatusOK)})
 	if err != nil {
-		log.Println(err.Error())
+		logger.FromContext(ctx).Error(ctx, "failed to encode the response", "error", err)
 	}
 }
 
-// todo: extract business customers repository
-func (h *handler) isCustomerBlocked(context context.Context, customerId string, associateIDs []string, businessID string) (bool, error) {
-	snapshot, err := h.db.BusinessCustomer(businessID, customerId).Get(context)
-	if snapshot == nil {
-		return false, nil
-	}
+func (h *handler) isCustomerBlocked(ctx context.Context, customerId string, associateIDs []string, businessID string) (bool, error) {
+	customer, err := h.customersRepository.FindBusinessCustomer(ctx, businessID, customerId)
 	if err != nil {
 		return false, err
 	}
-	var customer *model.Customer
-	err = snapshot.DataTo(&customer)
-	if err != nil {
-		return false, err
-	}
-	blocked := common.ArraysInclude(customer.InBlocked, associateIDs)
-	return blocked, nil
+	return common.ArraysInclude(customer.InBlocked, associateIDs), nil
 }
 
-func (h *handler) isAssociateBlocked(context context.Context, customerID string, associateIDs []string) (bool, error) {
-	for _, associateID := range associateIDs {
-		snapshot, err := h.db.BlockedUser(customerID, associateID).Get(context)
-		if err != nil {
-			return false, err
-		}
-		if snapshot != nil && snapshot.Exists() {
-			return true, nil
-		}
-	}
-	return false, nil
+func (h *handler) isAssociateBlocked(ctx context.Context, customerID string, associateIDs []string) (bool, error) {
+	return h.customersRepository.HasBlocked(ctx, customerID, associateIDs)
 }
 
 func (h handler) SetupRouts(router *mux.Router) {
@@ -785,58 +471,7 @@ func (h handler) SetupRouts(router *mux.Router) {
 	router.HandleFunc(PathCaseAccept, h.accept).Methods(http.MethodPatch)
 	router.HandleFunc(PathCaseReject, h.reject).Methods(http.MethodPatch)
 	router.HandleFunc(PathCaseUnAccept, h.unaccept).Methods(http.MethodPatch)
-}
-
-func safeBatch(ctx context.Context, firestoreClient *firestore.Client,
-	documentsIterator *firestore.DocumentIterator,
-	messagesRef *firestore.CollectionRef, fn batchFunc) error {
-	defer documentsIterator.Stop()
-	batch := firestoreClient.Batch()
-	batchOps := 0
-	for {
-		doc, err := documentsIterator.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return err
-		}
-		fn(doc, batch, messagesRef)
-		batchOps++
-		// copy only 100 last documents
-		if batchOps == 100 {
-			_, err = batch.Commit(ctx)
-			if err != nil {
-				return err
-			}
-			batchOps = 0
-			break
-		}
-	}
-	if batchOps > 0 {
-		_, err := batch.Commit(ctx)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-type batchFunc = func(doc *firestore.DocumentSnapshot, batch *firestore.WriteBatch, messagesRef *firestore.CollectionRef)
-
-func processMessage(textSessionId string, memberIDs []string) batchFunc {
-	return func(doc *firestore.DocumentSnapshot, batch *firestore.WriteBatch, messagesRef *firestore.CollectionRef) {
-		docData := doc.Data()
-		messageType, ok := docData["type"].(int64)
-		if !ok {
-			return
-		}
-		if !common.IntArrayIncludes(forwardExcludeMessageTypes, messageType) {
-			docData["textSessionId"] = textSessionId
-			docData["memberIDs"] = memberIDs
-			docData["createdDate"] = time.Now()
-			batch.Create(messagesRef.NewDoc(), docData)
-			batch.Delete(doc.Ref)
-		}
-	}
+	router.HandleFunc(PathCaseHandoff, h.getHandoff).Methods(http.MethodGet)
+	router.HandleFunc(PathCaseHandoffAccept, h.acceptHandoff).Methods(http.MethodPatch)
+	router.HandleFunc(PathCaseHandoffDecline, h.declineHandoff).Methods(http.MethodPatch)
 }
@@ -0,0 +1,209 @@
+package cases
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/memory"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+	"github.com/gorilla/mux"
+)
+
+// recorder records the events published.
+type recorder struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (r *recorder) Publish(_ context.Context, published ...events.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, published...)
+	return nil
+}
+
+// pushes records the push notifications sent.
+type pushes struct {
+	mu       sync.Mutex
+	requests []definition.PushRequest
+}
+
+func (p *pushes) Send(_ context.Context, req definition.PushRequest) definition.SendResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+	return definition.SendResponse{}
+}
+
+type fixture struct {
+	store     *memory.Store
+	cases     definition.CasesRepository
+	chats     definition.TextSessionsRepository
+	messages  definition.MessagesRepository
+	published *recorder
+	pushed    *pushes
+	handler   *handler
+	router    *mux.Router
+}
+
+// newFixture returns the handler backed by the in-memory store, with the case case1 of the
+// customer u1 open in the chat chat1 of the associate a1, and the personal contact c2 of the
+// associate a2 to forward it to.
+func newFixture(t *testing.T) *fixture {
+	store := memory.NewStore()
+	published := &recorder{}
+	f := &fixture{
+		store:     store,
+		cases:     memory.NewCasesRepository(store, published),
+		chats:     memory.NewTextSessionsRepository(store, published),
+		messages:  memory.NewMessagesRepository(store, published),
+		published: published,
+		pushed:    &pushes{},
+		router:    mux.NewRouter(),
+	}
+	f.handler = NewHandler(f.cases, f.chats, f.messages, memory.NewDirectoryRepository(store), memory.NewCustomersRepository(store),
+		f.pushed, published)
+	f.handler.SetupRouts(f.router)
+
+	store.Put("users/u1", &model.Customer{User: model.User{FullName: "Jane"}})
+	store.Put("businesses/b1/directory/c2", &model.Contact{
+		Name:         "John",
+		Type:         model.ContactTypePersonal,
+		Business:     &model.BusinessItem{Id: "b1"},
+		Associate:    &model.Associate{User: model.User{Id: "a2", Name: "John"}},
+		AssociateIDs: []string{"a2"},
+	})
+	store.Put("textSessions/chat1", &model.TextSession{
+		Members:   &model.Members{"a1": {Uid: "a1", Id: "c1", Name: "Ann"}, "u1": {Uid: "u1", Id: "u1", Name: "Jane"}},
+		MemberIDs: []string{"a1", "u1"},
+	})
+	opened := time.Now().Add(-time.Hour)
+	err := f.cases.Save(context.Background(), "b1", &model.Case{
+		Id:            "case1",
+		Business:      &model.BusinessItem{Id: "b1"},
+		Customer:      &model.CustomerItem{Id: "u1", Name: "Jane"},
+		Associate:     &model.AssociateItem{Id: "c1", Name: "Ann"},
+		Status:        model.CaseAccepted,
+		TextSessionId: "chat1",
+		OpenedDate:    &opened,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range []*model.Message{
+		{Text: "Hi", Type: model.MessageTypeStandard},
+		{Text: "Closed", Type: model.MessageTypeCaseClosed},
+	} {
+		if _, err = f.messages.Save(context.Background(), "chat1", message, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+func (f *fixture) forward(uid string, body string) *httptest.ResponseRecorder {
+	return f.request(http.MethodPatch, "/businesses/b1/cases/case1/forward", uid, body)
+}
+
+func (f *fixture) request(method string, path string, uid string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), "uid", uid))
+	resp := httptest.NewRecorder()
+	f.router.ServeHTTP(resp, req)
+	return resp
+}
+
+// Events returns a copy of the events published.
+func (r *recorder) Events() []events.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]events.Event(nil), r.events...)
+}
+
+func TestForward(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	resp := f.forward("a1", `{"toContactId": "c2"}`)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("forward = %d %s, want 200", resp.Code, resp.Body)
+	}
+
+	forwarded, err := f.cases.FindById(ctx, "b1", "case1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forwarded.TextSessionId == "chat1" || forwarded.Associate.Id != "c2" || forwarded.ForwardedDate == nil || forwarded.Handoff != nil {
+		t.Errorf("case = %+v, want it forwarded to c2 and unlocked", forwarded)
+	}
+	chat, err := f.chats.Find(forwarded.TextSessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chat.Case == nil || chat.Case.Id != "case1" {
+		t.Errorf("chat = %+v, want the chat with the forwarded case", chat)
+	}
+	if from, err := f.chats.Find("chat1"); err != nil || from.Case != nil {
+		t.Errorf("forwarded chat = %+v, %v, want it without the case", from, err)
+	}
+
+	moved, err := f.messages.FindBetween(ctx, chat.Id, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 2 || moved[0].Text != "Hi" || moved[1].Type != model.MessageTypeForwardCase {
+		t.Errorf("messages of the chat = %d, want the message moved and the forward", len(moved))
+	}
+	left, err := f.messages.FindBetween(ctx, "chat1", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 2 || left[0].Text != "Closed" || left[1].NewTextSessionId != chat.Id {
+		t.Errorf("messages of the forwarded chat = %d, want the excluded message and the forward", len(left))
+	}
+
+	if len(f.published.events) != 1 {
+		t.Fatalf("published = %v, want CaseForwarded", f.published.events)
+	}
+	if event, ok := f.published.events[0].(*events.CaseForwarded); !ok || event.TextSessionID != chat.Id || event.ForwardedBy != "a1" {
+		t.Errorf("published = %+v, want the case forwarded to the chat by a1", f.published.events[0])
+	}
+}
+
+func TestForwardPendingHandoff(t *testing.T) {
+	f := newFixture(t)
+	expires := time.Now().Add(time.Hour)
+	locked, err := f.cases.LockHandoff(context.Background(), "b1", "case1", &model.CaseHandoff{Status: model.HandoffPending, ExpiresDate: &expires})
+	if err != nil || !locked {
+		t.Fatalf("LockHandoff = %v, %v", locked, err)
+	}
+
+	if resp := f.forward("a1", `{"toContactId": "c2"}`); resp.Code != http.StatusPreconditionFailed {
+		t.Errorf("forward = %d %s, want 412", resp.Code, resp.Body)
+	}
+	if found, err := f.cases.FindById(context.Background(), "b1", "case1"); err != nil || found.TextSessionId != "chat1" {
+		t.Errorf("case = %+v, %v, want it left in chat1", found, err)
+	}
+}
+
+func TestForwardNotMember(t *testing.T) {
+	f := newFixture(t)
+
+	if resp := f.forward("a3", `{"toContactId": "c2"}`); resp.Code != http.StatusPreconditionFailed {
+		t.Errorf("forward = %d %s, want 412", resp.Code, resp.Body)
+	}
+	found, err := f.cases.FindById(context.Background(), "b1", "case1")
+	if err != nil || found.TextSessionId != "chat1" || found.Handoff != nil {
+		t.Errorf("case = %+v, %v, want it left in chat1 and unlocked", found, err)
+	}
+	if len(f.published.events) != 0 {
+		t.Errorf("published = %v, want nothing", f.published.events)
+	}
+}
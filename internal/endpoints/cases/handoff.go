@@ -0,0 +1,287 @@
+package cases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/scheduler"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultHandoffTimeout = 5 * time.Minute
+	minHandoffTimeout     = 30 * time.Second
+	maxHandoffTimeout     = time.Hour
+	// an immediate handoff locks the case while it is transferred. The lock expires in case the
+	// handoff is never resolved, e.g. the process died during the transfer.
+	immediateHandoffTimeout = time.Minute
+
+	handoffRequestCategory = "CASE_HANDOFF_CATEGORY"
+
+	DefaultHandoffExpiryInterval = 15 * time.Second
+	handoffExpiryJobID           = "handoff-expiry"
+)
+
+var (
+	errCaseForwarding  = apierrors.New(apierrors.CodeFailedPrecondition, "Case is being forwarded")
+	errHandoffExpired  = apierrors.New(apierrors.CodeGone, "handoff has expired")
+	errHandoffResolved = apierrors.New(apierrors.CodeConflict, "Case handoff has already been resolved")
+)
+
+type handoffResponse struct {
+	model.BaseResponse
+	Handoff       *model.CaseHandoff `json:"handoff,omitempty"`
+	TextSessionId string             `json:"textSessionId,omitempty"`
+}
+
+func handoffTimeout(seconds int64) time.Duration {
+	timeout := time.Duration(seconds) * time.Second
+	if timeout <= 0 {
+		return defaultHandoffTimeout
+	}
+	if timeout < minHandoffTimeout {
+		return minHandoffTimeout
+	}
+	if timeout > maxHandoffTimeout {
+		return maxHandoffTimeout
+	}
+	return timeout
+}
+
+func contactUIDs(contact *model.Contact) []string {
+	if len(contact.AssociateIDs) > 0 {
+		return contact.AssociateIDs
+	}
+	if contact.Associate != nil && len(contact.Associate.Id) > 0 {
+		return []string{contact.Associate.Id}
+	}
+	return []string{}
+}
+
+// resolved marks the handoff resolved with the status by the user, for the write resolving it.
+func resolved(handoff *model.CaseHandoff, status model.HandoffStatus, resolvedBy string) *model.CaseHandoff {
+	now := time.Now()
+	handoff.Status = status
+	handoff.ResolvedDate = &now
+	handoff.ResolvedBy = resolvedBy
+	return handoff
+}
+
+// resolveHandoff closes the handoff record and unlocks the case, unless the handoff was resolved
+// meanwhile. It completes even if the client went away, so the case is not left locked.
+func (h *handler) resolveHandoff(ctx context.Context, businessId string, caseId string, handoff *model.CaseHandoff,
+	status model.HandoffStatus, resolvedBy string) error {
+	ctx = common.WithoutCancel(ctx)
+	err := h.casesRepository.ResolveHandoff(ctx, businessId, caseId, resolved(handoff, status, resolvedBy))
+	if err != nil && err != definition.ErrHandoffResolved {
+		logger.FromContext(ctx).Error(ctx, "failed to resolve the handoff", "handoffId", handoff.Id, "status", status, "error", err)
+	}
+	return err
+}
+
+// requestHandoff notifies the chat and the receiving contact. The handoff expires unanswered by
+// the sweep of Schedule.
+func (h *handler) requestHandoff(ctx context.Context, srcCase *model.Case, handoff *model.CaseHandoff) {
+	businessId := srcCase.Business.Id
+	h.postHandoffMessage(ctx, srcCase.TextSessionId, handoff,
+		fmt.Sprintf("Case has been offered to %s. Waiting for the answer", handoff.ToName()))
+
+	pushResponse := h.pushService.Send(ctx, definition.PushRequest{
+		UIDs:     handoff.ToUIDs,
+		Title:    fmt.Sprintf("Case #%d handoff", srcCase.Number),
+		Body:     fmt.Sprintf("%s wants to forward a case to you", handoff.FromName()),
+		Category: handoffRequestCategory,
+		Data: map[string]string{
+			"businessId":    businessId,
+			"caseId":        srcCase.Id,
+			"handoffId":     handoff.Id,
+			"textSessionId": srcCase.TextSessionId,
+			"expiresDate":   handoff.ExpiresDate.Format(time.RFC3339),
+		},
+	})
+	if !pushResponse.OK() {
+		logger.FromContext(ctx).Error(ctx, "failed to notify the handoff recipients", "handoffId", handoff.Id, "error", pushResponse.Error)
+	}
+}
+
+// Schedule registers the periodic expiry of the handoffs left unanswered. The handoffs are kept
+// in Firestore, so those of an instance which stopped are expired by another one.
+func (h *handler) Schedule(ctx context.Context, jobScheduler scheduler.Scheduler, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultHandoffExpiryInterval
+	}
+	job := jobScheduler.NewJob(handoffExpiryJobID, h.expireHandoffs)
+	jobScheduler.AddPeriodic(ctx, job, interval)
+}
+
+func (h *handler) expireHandoffs(ctx context.Context) {
+	expired, err := h.casesRepository.ExpireHandoffs(ctx, time.Now())
+	if err != nil {
+		logger.FromContext(ctx).Error(ctx, "failed to expire the handoffs", "error", err)
+	}
+	for _, bizCase := range expired {
+		h.expiredHandoff(ctx, bizCase, bizCase.Handoff)
+	}
+}
+
+// expiredHandoff tells the chat the handoff the case was offered with has expired.
+func (h *handler) expiredHandoff(ctx context.Context, bizCase *model.Case, handoff *model.CaseHandoff) {
+	if handoff.Mode != model.HandoffModeAcceptance {
+		return
+	}
+	h.postHandoffMessage(ctx, bizCase.TextSessionId, handoff,
+		fmt.Sprintf("%s did not answer in time. The case stays with %s", handoff.ToName(), handoff.FromName()))
+}
+
+func (h *handler) postHandoffMessage(ctx context.Context, chatId string, handoff *model.CaseHandoff, text string) {
+	chat, err := h.chatsRepository.Find(chatId)
+	if err != nil {
+		logger.FromContext(ctx).Error(ctx, "failed to post the handoff message", "chatId", chatId, "error", err)
+		return
+	}
+	now := time.Now()
+	message := &model.Message{
+		Sender: &model.MessageSender{
+			Uid:  handoff.RequestedBy,
+			Name: handoff.FromName(),
+			Type: model.MessageSenderTypeSystem,
+		},
+		Recipient: &model.MessageRecipient{
+			ContactId: handoff.To.Id,
+			Name:      handoff.ToName(),
+		},
+		Type:          model.MessageTypeForwarding,
+		Text:          text,
+		TextSessionId: chatId,
+		MemberIDs:     chat.MemberIDs,
+		CreatedDate:   &now,
+	}
+	if _, err = h.messagesRepository.Save(ctx, chatId, message, nil); err != nil {
+		logger.FromContext(ctx).Error(ctx, "failed to post the handoff message", "chatId", chatId, "error", err)
+	}
+}
+
+// pendingHandoff loads the case and its pending handoff. An expired handoff is closed on the way.
+func (h *handler) pendingHandoff(ctx context.Context, businessId string, caseId string) (*model.Case, *model.CaseHandoff, error) {
+	bizCase, err := h.casesRepository.FindById(ctx, businessId, caseId)
+	if err != nil {
+		return nil, nil, err
+	}
+	handoff := bizCase.Handoff
+	if handoff == nil || handoff.Status != model.HandoffPending {
+		return nil, nil, apierrors.New(apierrors.CodeNotFound, "no pending handoff")
+	}
+	if !handoff.IsPending(time.Now()) {
+		if h.resolveHandoff(ctx, businessId, caseId, handoff, model.HandoffExpired, "") == nil {
+			h.expiredHandoff(ctx, bizCase, handoff)
+		}
+		return nil, nil, errHandoffExpired
+	}
+	return bizCase, handoff, nil
+}
+
+func (h *handler) getHandoff(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	vars := mux.Vars(req)
+	_, handoff, err := h.pendingHandoff(ctx, vars["business_id"], vars["case_id"])
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(handoffResponse{
+		BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusOK)},
+		Handoff:      handoff,
+	})
+}
+
+func (h *handler) acceptHandoff(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	uid := ctx.Value("uid").(string)
+	vars := mux.Vars(req)
+	businessId := vars["business_id"]
+
+	bizCase, handoff, err := h.pendingHandoff(ctx, businessId, vars["case_id"])
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	if !handoff.IsRecipient(uid) {
+		apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, "not a handoff recipient"))
+		return
+	}
+
+	toContact, err := h.forwardContact(ctx, businessId, handoff.To.Id, bizCase.Customer.Id)
+	if err == nil {
+		var textSessionId string
+		textSessionId, err = h.transfer(ctx, handoff.RequestedBy, businessId, bizCase, toContact,
+			resolved(handoff, model.HandoffAccepted, uid))
+		if err == nil {
+			resp.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(resp).Encode(handoffResponse{
+				BaseResponse:  model.BaseResponse{Status: http.StatusText(http.StatusOK), Message: "Case has been forwarded"},
+				Handoff:       handoff,
+				TextSessionId: textSessionId,
+			})
+			return
+		}
+	}
+	if err == definition.ErrHandoffResolved {
+		apierrors.Respond(resp, errHandoffResolved)
+		return
+	}
+	logger.FromContext(ctx).Error(ctx, "failed to accept the handoff", "error", err)
+	if h.resolveHandoff(ctx, businessId, bizCase.Id, handoff, model.HandoffFailed, uid) == nil {
+		h.postHandoffMessage(ctx, bizCase.TextSessionId, handoff,
+			fmt.Sprintf("The case could not be forwarded to %s. The case stays with %s", handoff.ToName(), handoff.FromName()))
+	}
+	apierrors.RespondWithError(err, resp, apierrors.CodeInternal)
+}
+
+func (h *handler) declineHandoff(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	uid := ctx.Value("uid").(string)
+	vars := mux.Vars(req)
+	businessId := vars["business_id"]
+
+	bizCase, handoff, err := h.pendingHandoff(ctx, businessId, vars["case_id"])
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	if !handoff.IsRecipient(uid) && handoff.RequestedBy != uid {
+		apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, "not a handoff participant"))
+		return
+	}
+
+	err = h.resolveHandoff(ctx, businessId, bizCase.Id, handoff, model.HandoffDeclined, uid)
+	if err == definition.ErrHandoffResolved {
+		apierrors.Respond(resp, errHandoffResolved)
+		return
+	}
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	text := fmt.Sprintf("%s declined the case. The case stays with %s", handoff.ToName(), handoff.FromName())
+	if uid == handoff.RequestedBy {
+		text = fmt.Sprintf("The case handoff to %s has been canceled", handoff.ToName())
+	}
+	h.postHandoffMessage(ctx, bizCase.TextSessionId, handoff, text)
+
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(handoffResponse{
+		BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusOK)},
+		Handoff:      handoff,
+	})
+}
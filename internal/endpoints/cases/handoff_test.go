@@ -0,0 +1,192 @@
+package cases
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+)
+
+const (
+	pathHandoff        = "/businesses/b1/cases/case1/handoff"
+	pathHandoffAccept  = pathHandoff + "/accept"
+	pathHandoffDecline = pathHandoff + "/decline"
+)
+
+// requestHandoff offers case1 to the contact c2 of a2 on behalf of a1, and returns the handoff.
+func (f *fixture) requestHandoff(t *testing.T) *model.CaseHandoff {
+	if resp := f.forward("a1", `{"toContactId": "c2", "acceptance": true, "timeout": 60}`); resp.Code != http.StatusAccepted {
+		t.Fatalf("forward with acceptance = %d %s, want 202", resp.Code, resp.Body)
+	}
+	found, err := f.cases.FindById(context.Background(), "b1", "case1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found.Handoff.IsPending(time.Now()) || found.Handoff.Mode != model.HandoffModeAcceptance {
+		t.Fatalf("handoff = %+v, want a pending handoff awaiting acceptance", found.Handoff)
+	}
+	return found.Handoff
+}
+
+// handoff returns the record of the handoff.
+func (f *fixture) handoff(t *testing.T, handoffId string) *model.CaseHandoff {
+	var handoff model.CaseHandoff
+	if err := f.store.Get("businesses/b1/cases/case1/handoffs/"+handoffId, &handoff); err != nil {
+		t.Fatal(err)
+	}
+	return &handoff
+}
+
+func TestHandoffAccept(t *testing.T) {
+	f := newFixture(t)
+	handoff := f.requestHandoff(t)
+	if len(f.pushed.requests) != 1 || len(f.pushed.requests[0].UIDs) != 1 || f.pushed.requests[0].UIDs[0] != "a2" {
+		t.Errorf("pushes = %+v, want the request sent to a2", f.pushed.requests)
+	}
+	if resp := f.forward("a1", `{"toContactId": "c2"}`); resp.Code != http.StatusPreconditionFailed {
+		t.Errorf("forward of a case offered = %d %s, want 412", resp.Code, resp.Body)
+	}
+	if resp := f.request(http.MethodGet, pathHandoff, "a2", ""); resp.Code != http.StatusOK {
+		t.Errorf("get handoff = %d %s, want 200", resp.Code, resp.Body)
+	}
+	if resp := f.request(http.MethodPatch, pathHandoffAccept, "a3", ""); resp.Code != http.StatusForbidden {
+		t.Errorf("accept by a3 = %d %s, want 403", resp.Code, resp.Body)
+	}
+
+	if resp := f.request(http.MethodPatch, pathHandoffAccept, "a2", ""); resp.Code != http.StatusOK {
+		t.Fatalf("accept = %d %s, want 200", resp.Code, resp.Body)
+	}
+	found, err := f.cases.FindById(context.Background(), "b1", "case1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.TextSessionId == "chat1" || found.Associate.Id != "c2" || found.Handoff != nil {
+		t.Errorf("case = %+v, want it forwarded to c2 and unlocked", found)
+	}
+	if record := f.handoff(t, handoff.Id); record.Status != model.HandoffAccepted || record.ResolvedBy != "a2" {
+		t.Errorf("handoff = %+v, want it accepted by a2", record)
+	}
+	published := f.published.Events()
+	if len(published) != 1 {
+		t.Fatalf("published = %v, want CaseForwarded", published)
+	}
+	if event, ok := published[0].(*events.CaseForwarded); !ok || event.TextSessionID != found.TextSessionId || event.ForwardedBy != "a1" {
+		t.Errorf("published = %+v, want the case forwarded to its chat on behalf of a1", published[0])
+	}
+	if resp := f.request(http.MethodPatch, pathHandoffAccept, "a2", ""); resp.Code != http.StatusNotFound {
+		t.Errorf("accept again = %d %s, want 404", resp.Code, resp.Body)
+	}
+}
+
+// TestHandoffConcurrentAccepts accepts a handoff several times at once, and checks the case is
+// forwarded once.
+func TestHandoffConcurrentAccepts(t *testing.T) {
+	f := newFixture(t)
+	f.requestHandoff(t)
+
+	codes := make(chan int, 5)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(codes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- f.request(http.MethodPatch, pathHandoffAccept, "a2", "").Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	accepted := 0
+	for code := range codes {
+		switch code {
+		case http.StatusOK:
+			accepted++
+		case http.StatusNotFound, http.StatusConflict:
+		default:
+			t.Errorf("accept = %d, want 200, 404 or 409", code)
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("accepted = %d, want 1", accepted)
+	}
+	if published := f.published.Events(); len(published) != 1 {
+		t.Errorf("published = %v, want CaseForwarded once", published)
+	}
+	found, err := f.cases.FindById(context.Background(), "b1", "case1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved, err := f.messages.FindBetween(context.Background(), found.TextSessionId, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 2 {
+		t.Errorf("messages of the chat = %d, want the message moved and the forward once", len(moved))
+	}
+}
+
+func TestHandoffDecline(t *testing.T) {
+	f := newFixture(t)
+	handoff := f.requestHandoff(t)
+
+	if resp := f.request(http.MethodPatch, pathHandoffDecline, "a3", ""); resp.Code != http.StatusForbidden {
+		t.Errorf("decline by a3 = %d %s, want 403", resp.Code, resp.Body)
+	}
+	if resp := f.request(http.MethodPatch, pathHandoffDecline, "a2", ""); resp.Code != http.StatusOK {
+		t.Fatalf("decline = %d %s, want 200", resp.Code, resp.Body)
+	}
+	found, err := f.cases.FindById(context.Background(), "b1", "case1")
+	if err != nil || found.TextSessionId != "chat1" || found.Handoff != nil {
+		t.Errorf("case = %+v, %v, want it left in chat1 and unlocked", found, err)
+	}
+	if record := f.handoff(t, handoff.Id); record.Status != model.HandoffDeclined {
+		t.Errorf("handoff = %+v, want it declined", record)
+	}
+	if resp := f.request(http.MethodPatch, pathHandoffAccept, "a2", ""); resp.Code != http.StatusNotFound {
+		t.Errorf("accept after the decline = %d %s, want 404", resp.Code, resp.Body)
+	}
+	if published := f.published.Events(); len(published) != 0 {
+		t.Errorf("published = %v, want nothing", published)
+	}
+}
+
+func TestHandoffExpiry(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	requested, expires := time.Now().Add(-time.Hour), time.Now().Add(-time.Minute)
+	handoff := &model.CaseHandoff{
+		Mode:          model.HandoffModeAcceptance,
+		Status:        model.HandoffPending,
+		RequestedBy:   "a1",
+		From:          &model.AssociateItem{Id: "c1", Name: "Ann"},
+		To:            &model.AssociateItem{Id: "c2", Name: "John"},
+		ToUIDs:        []string{"a2"},
+		RequestedDate: &requested,
+		ExpiresDate:   &expires,
+	}
+	if locked, err := f.cases.LockHandoff(ctx, "b1", "case1", handoff); err != nil || !locked {
+		t.Fatalf("LockHandoff = %v, %v", locked, err)
+	}
+
+	f.handler.expireHandoffs(ctx)
+	found, err := f.cases.FindById(ctx, "b1", "case1")
+	if err != nil || found.TextSessionId != "chat1" || found.Handoff != nil {
+		t.Errorf("case = %+v, %v, want it left in chat1 and unlocked", found, err)
+	}
+	if record := f.handoff(t, handoff.Id); record.Status != model.HandoffExpired {
+		t.Errorf("handoff = %+v, want it expired", record)
+	}
+	messages, err := f.messages.FindBetween(ctx, "chat1", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := messages[len(messages)-1]; last.Type != model.MessageTypeForwarding {
+		t.Errorf("last message = %+v, want the expiry posted to the chat", last)
+	}
+	if resp := f.request(http.MethodPatch, pathHandoffAccept, "a2", ""); resp.Code != http.StatusNotFound {
+		t.Errorf("accept after the expiry = %d %s, want 404", resp.Code, resp.Body)
+	}
+}
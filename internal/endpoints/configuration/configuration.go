@@ -0,0 +1,46 @@
+// Package configuration serves the effective config of the server to the super admins.
+package configuration
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/gorilla/mux"
+)
+
+const PathConfig = "/config"
+
+type handler struct {
+	config *configs.Config
+}
+
+func NewHandler(config *configs.Config) *handler {
+	return &handler{config}
+}
+
+type configResponse struct {
+	model.BaseResponse
+	Config []configs.Entry `json:"config"`
+}
+
+// effective lists the values of the config with the layer each comes from. The secrets are redacted.
+func (h *handler) effective(resp http.ResponseWriter, req *http.Request) {
+	claims, _ := req.Context().Value("claims").(*model.UserClaims)
+	if claims == nil || !claims.SuperAdmin {
+		apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, "Insufficient permissions"))
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&configResponse{
+		BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusOK)},
+		Config:       h.config.Effective(),
+	})
+}
+
+func (h *handler) SetupRouts(router *mux.Router) {
+	routes.HandleFunc(router, PathConfig, h.effective).Methods(http.MethodGet)
+}
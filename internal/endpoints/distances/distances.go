@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
 	"github.com/VinothKuppanna/pigeon-go/internal/cache"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 	"github.com/gorilla/mux"
@@ -40,12 +41,6 @@ func (h *handler) findDistance() http.HandlerFunc {
 		Result  *distancePoint `json:"result"`
 		Message string         `json:"message,omitempty"`
 	}
-	responseWithError := func(resp http.ResponseWriter, response *findDistanceResponse, err error, statusCode int) {
-		response.Status = http.StatusText(statusCode)
-		response.Message = err.Error()
-		resp.WriteHeader(statusCode)
-		_ = json.NewEncoder(resp).Encode(response)
-	}
 	responseOk := func(resp http.ResponseWriter, response *findDistanceResponse) {
 		response.Status = http.StatusText(http.StatusOK)
 		resp.WriteHeader(http.StatusOK)
@@ -71,13 +66,13 @@ func (h *handler) findDistance() http.HandlerFunc {
 
 		bytes, err := ioutil.ReadAll(req.Body)
 		if err != nil {
-			responseWithError(resp, response, err, http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 
 		var request *findDistanceRequest
 		if err = json.Unmarshal(bytes, &request); err != nil {
-			responseWithError(resp, response, err, http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 
@@ -98,7 +93,7 @@ func (h *handler) findDistance() http.HandlerFunc {
 
 		distanceResponse := h.service.FindDistance(ctx, &distanceRequest)
 		if err = distanceResponse.Error; err != nil {
-			responseWithError(resp, response, err, http.StatusNotFound)
+			apierrors.RespondWithError(err, resp, apierrors.CodeNotFound)
 			return
 		}
 		if distance := distanceResponse.Result; distance != nil {
@@ -4,11 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
 
 	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/gorilla/mux"
 )
@@ -24,33 +25,18 @@ func NewHandler(firestoreClient *firestore.Client) *handler {
 }
 
 func (h handler) AddFeedback(resp http.ResponseWriter, req *http.Request) {
-	log.Println("AddFeedback")
-
-	body, err := ioutil.ReadAll(req.Body)
-
-	if err != nil {
-		_ = fmt.Errorf("error reading request %v\n", err)
-		_ = json.NewEncoder(resp).Encode(err)
-		return
-	}
-
 	var data *model.Feedback
-	err = json.Unmarshal(body, &data)
-
-	if err != nil {
-		_ = fmt.Errorf("error unmarshaling request %v\n", err)
-		_ = json.NewEncoder(resp).Encode(err)
+	err := validation.Decode(resp, req, &data)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 		return
 	}
 
 	mapData := *data.Map()
-	fmt.Printf("data: %v\n", mapData)
+	logger.FromContext(req.Context()).Debug(req.Context(), "adding feedback", "feedback", mapData)
 
 	documentRef, _, err := h.firestoreClient.Collection("feedback").Add(context.Background(), &data)
 
-	if err != nil {
-		_ = fmt.Errorf("error creating document %v\n", err)
-		_ = json.NewEncoder(resp).Encode(err)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
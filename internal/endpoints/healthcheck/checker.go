@@ -0,0 +1,158 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTimeout = 3 * time.Second  // of the checks without a timeout
+	DefaultTTL     = 10 * time.Second // of the results
+)
+
+// ErrDisabled is returned by the checks of the features turned off in the config.
+var ErrDisabled = errors.New("disabled")
+
+// The statuses of a check and of the report.
+const (
+	StatusOK          = "ok"
+	StatusFailing     = "failing"
+	StatusDisabled    = "disabled"
+	StatusDegraded    = "degraded"    // a non-critical check is failing
+	StatusUnavailable = "unavailable" // a critical check is failing
+)
+
+// A Check probes a dependency of the server. The server is not ready while a critical
+// check fails. A check run once keeps its result for good once it passes, e.g. for the checks
+// of boot. Until then it is run again like the others.
+type Check struct {
+	Name     string
+	Run      func(ctx context.Context) error
+	Timeout  time.Duration // DefaultTimeout if zero
+	Critical bool
+	Once     bool
+}
+
+// A Result is the last run of a check.
+type Result struct {
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	Critical   bool      `json:"critical"`
+	DurationMs int64     `json:"durationMs"`
+	CheckedAt  time.Time `json:"checkedAt"`
+}
+
+// A Report is the result of all the checks.
+type Report struct {
+	Status string             `json:"status"`
+	Checks map[string]*Result `json:"checks"`
+}
+
+// Ready reports whether all the critical checks pass.
+func (r *Report) Ready() bool {
+	return r.Status != StatusUnavailable
+}
+
+// A Checker runs the checks, and keeps their results for the ttl so the probes of the load
+// balancer do not hit the dependencies on every request.
+type Checker struct {
+	checks []Check
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	results map[string]*Result
+}
+
+func NewChecker(ttl time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, ttl: ttl, now: time.Now, results: map[string]*Result{}}
+}
+
+// Run runs the checks whose result is stale, in parallel, and reports all the results.
+func (c *Checker) Run(ctx context.Context) *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	var stale []Check
+	for _, check := range c.checks {
+		if result, ok := c.results[check.Name]; ok && c.fresh(check, result, now) {
+			continue
+		}
+		stale = append(stale, check)
+	}
+	var wg sync.WaitGroup
+	var resultsMu sync.Mutex
+	results := make(map[string]*Result, len(stale))
+	for _, check := range stale {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := c.run(ctx, check)
+			resultsMu.Lock()
+			results[check.Name] = result
+			resultsMu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+	for name, result := range results {
+		c.results[name] = result
+	}
+
+	report := &Report{Status: StatusOK, Checks: map[string]*Result{}}
+	for _, check := range c.checks {
+		result := *c.results[check.Name]
+		report.Checks[check.Name] = &result
+		if result.Status != StatusFailing {
+			continue
+		}
+		if check.Critical {
+			report.Status = StatusUnavailable
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (c *Checker) fresh(check Check, result *Result, now time.Time) bool {
+	if check.Once && result.Status != StatusFailing {
+		return true
+	}
+	return now.Sub(result.CheckedAt) < c.ttl
+}
+
+// run runs the check within its timeout. A check ignoring ctx is left behind when it times out.
+func (c *Checker) run(ctx context.Context, check Check) *Result {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := c.now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Run(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %v", timeout)
+	}
+
+	result := &Result{Status: StatusOK, Critical: check.Critical, CheckedAt: start, DurationMs: c.now().Sub(start).Milliseconds()}
+	switch {
+	case errors.Is(err, ErrDisabled):
+		result.Status = StatusDisabled
+	case err != nil:
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,122 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func counting(name string, critical bool, err error, calls *int32) Check {
+	return Check{Name: name, Critical: critical, Run: func(ctx context.Context) error {
+		atomic.AddInt32(calls, 1)
+		return err
+	}}
+}
+
+func TestCheckerCaches(t *testing.T) {
+	var calls, onceCalls int32
+	once := counting("once", true, nil, &onceCalls)
+	once.Once = true
+	checker := NewChecker(time.Minute, counting("db", true, nil, &calls), once)
+	now := time.Now()
+	checker.now = func() time.Time { return now }
+
+	checker.Run(context.Background())
+	checker.Run(context.Background())
+	if calls != 1 || onceCalls != 1 {
+		t.Fatalf("within the ttl: %d runs, %d runs of the once check", calls, onceCalls)
+	}
+	now = now.Add(2 * time.Minute)
+	checker.Run(context.Background())
+	if calls != 2 || onceCalls != 1 {
+		t.Errorf("after the ttl: %d runs, %d runs of the once check", calls, onceCalls)
+	}
+}
+
+func TestCheckerRetriesFailingOnce(t *testing.T) {
+	var calls int32
+	failure := errors.New("down")
+	check := Check{Name: "once", Critical: true, Once: true, Run: func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return failure
+		}
+		return nil
+	}}
+	checker := NewChecker(time.Minute, check)
+	now := time.Now()
+	checker.now = func() time.Time { return now }
+
+	if report := checker.Run(context.Background()); report.Ready() {
+		t.Fatal("ready after the failing run")
+	}
+	now = now.Add(2 * time.Minute)
+	if report := checker.Run(context.Background()); !report.Ready() {
+		t.Fatal("not ready after the check passed")
+	}
+	now = now.Add(2 * time.Minute)
+	checker.Run(context.Background())
+	if calls != 2 {
+		t.Errorf("%d runs, want 2", calls)
+	}
+}
+
+func TestCheckerStatus(t *testing.T) {
+	var calls int32
+	failure := errors.New("down")
+	tests := []struct {
+		name   string
+		checks []Check
+		want   string
+	}{
+		{"ok", []Check{counting("a", true, nil, &calls)}, StatusOK},
+		{"disabled", []Check{counting("a", false, ErrDisabled, &calls)}, StatusOK},
+		{"degraded", []Check{counting("a", true, nil, &calls), counting("b", false, failure, &calls)}, StatusDegraded},
+		{"unavailable", []Check{counting("a", true, failure, &calls), counting("b", false, failure, &calls)}, StatusUnavailable},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := NewChecker(time.Minute, test.checks...).Run(context.Background())
+			if report.Status != test.want {
+				t.Errorf("got %q, want %q", report.Status, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	checker := NewChecker(time.Minute, Check{Name: "slow", Critical: true, Timeout: 10 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			<-block // ignores ctx
+			return nil
+		}})
+	report := checker.Run(context.Background())
+	if result := report.Checks["slow"]; result.Status != StatusFailing || result.Error == "" {
+		t.Errorf("got %+v, want a timeout", result)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	var calls int32
+	router := mux.NewRouter()
+	NewHandler(NewChecker(time.Minute, counting("db", true, errors.New("down"), &calls))).SetupRouts(router)
+
+	for path, want := range map[string]int{
+		PathLive:        http.StatusOK,
+		PathHealthCheck: http.StatusOK,
+		PathReady:       http.StatusServiceUnavailable,
+	} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != want {
+			t.Errorf("%s: got %d, want %d", path, recorder.Code, want)
+		}
+	}
+}
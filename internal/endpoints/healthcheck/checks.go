@@ -0,0 +1,106 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Firestore reads a document. A missing document still means Firestore answered.
+func Firestore(client *firestore.Client) Check {
+	return Check{Name: "firestore", Critical: true, Run: func(ctx context.Context) error {
+		_, err := client.Collection("health").Doc("ping").Get(ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		return err
+	}}
+}
+
+// NSQ pings nsqd. The producer does not take a context, so a hung ping is left to the timeout.
+func NSQ(producer *publishing.Producer) Check {
+	return Check{Name: "nsq", Critical: true, Run: func(ctx context.Context) error {
+		return producer.Ping()
+	}}
+}
+
+// SecretManager reads the service account once, as the server does at boot.
+func SecretManager(config *configs.Config) Check {
+	return Check{Name: "secret_manager", Critical: true, Once: true, Run: func(ctx context.Context) error {
+		data, err := config.ReadServiceAccount()
+		if err == nil && len(data) == 0 {
+			err = errors.New("empty service account")
+		}
+		return err
+	}}
+}
+
+// Templates parses the email templates and the archive template.
+func Templates(config *configs.Config) Check {
+	return Check{Name: "templates", Critical: true, Run: func(ctx context.Context) error {
+		if err := (&configs.EmailTemplates{}).Parse(); err != nil {
+			return err
+		}
+		if len(config.ArchiveTemplate) > 0 {
+			if _, err := template.ParseFiles(config.ArchiveTemplate); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+}
+
+// SendGrid checks the shape of the credentials without calling the API.
+func SendGrid(config configs.SendGrid) Check {
+	return Check{Name: "sendgrid", Run: func(ctx context.Context) error {
+		if !config.Enabled {
+			return ErrDisabled
+		}
+		if !strings.HasPrefix(config.APIKey, "SG.") {
+			return errors.New("the API key is not a SendGrid key")
+		}
+		if u, err := url.Parse(config.Host); err != nil || len(u.Host) == 0 {
+			return fmt.Errorf("the host %q is not a URL", config.Host)
+		}
+		return nil
+	}}
+}
+
+// Vonage checks the shape of the credentials without calling the API.
+func Vonage(config configs.Vonage) Check {
+	return Check{Name: "vonage", Run: func(ctx context.Context) error {
+		if len(config.ApiKey) != 8 {
+			return errors.New("the API key is not 8 characters")
+		}
+		if len(config.ApiSecret) == 0 {
+			return errors.New("no API secret")
+		}
+		if _, err := strconv.ParseUint(config.FromNumber, 10, 64); err != nil {
+			return fmt.Errorf("the from number %q is not digits", config.FromNumber)
+		}
+		return nil
+	}}
+}
+
+// OpenTok checks the shape of the credentials without calling the API.
+func OpenTok(config configs.Opentok) Check {
+	return Check{Name: "opentok", Run: func(ctx context.Context) error {
+		if _, err := strconv.ParseUint(config.ApiKey, 10, 64); err != nil {
+			return errors.New("the API key is not numeric")
+		}
+		if len(config.ApiSecret) == 0 {
+			return errors.New("no API secret")
+		}
+		return nil
+	}}
+}
@@ -1,17 +1,56 @@
 package healthcheck
 
 import (
-	"github.com/gorilla/mux"
+	"context"
+	"encoding/json"
 	"net/http"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
+	"github.com/gorilla/mux"
 )
 
-const PathHealthCheck = "/health-check"
+const (
+	PathHealthCheck = "/health-check" // legacy liveness
+	PathLive        = "/livez"
+	PathReady       = "/readyz"
+)
 
-func SetupRouts(router *mux.Router) {
-	router.HandleFunc(PathHealthCheck, healthCheck).Methods(http.MethodGet)
+type handler struct {
+	checker *Checker
+}
+
+func NewHandler(checker *Checker) *handler {
+	return &handler{checker}
+}
+
+func (h *handler) SetupRouts(router *mux.Router) {
+	routes.HandleFunc(router, PathHealthCheck, healthCheck).Methods(http.MethodGet).Public()
+	routes.HandleFunc(router, PathLive, live).Methods(http.MethodGet).Public()
+	routes.HandleFunc(router, PathReady, h.ready).Methods(http.MethodGet).Public()
 }
 
 func healthCheck(writer http.ResponseWriter, request *http.Request) {
 	writer.WriteHeader(http.StatusOK)
 	_, _ = writer.Write([]byte(http.StatusText(http.StatusOK)))
 }
+
+// live answers as long as the server serves requests. It checks no dependency, so a broken
+// dependency does not get the server restarted.
+func live(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(writer).Encode(map[string]string{"status": StatusOK})
+}
+
+// ready reports the checks. It fails with 503 while a critical check fails.
+func (h *handler) ready(writer http.ResponseWriter, request *http.Request) {
+	// not the context of the request: the results are shared, a probe giving up must not fail them
+	report := h.checker.Run(context.Background())
+	code := http.StatusOK
+	if !report.Ready() {
+		code = http.StatusServiceUnavailable
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(code)
+	_ = json.NewEncoder(writer).Encode(report)
+}
@@ -4,54 +4,64 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 
 	"cloud.google.com/go/firestore"
 	"firebase.google.com/go/v4/auth"
 	"github.com/VinothKuppanna/pigeon-go/configs"
-	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
 	"github.com/gorilla/mux"
 )
 
-const invitesPath = "/businesses/{business_id}/invites"
-const invitePath = "/businesses/{business_id}/invites/{invite_id}"
+const InvitesPath = "/businesses/{business_id}/invites"
+const InvitePath = "/businesses/{business_id}/invites/{invite_id}"
+
+// maxInvites keeps the invites of a request within a batch, with their events.
+const maxInvites = 200
 
 type handler struct {
 	config          *configs.Config
 	authClient      *auth.Client
 	firestoreClient *firestore.Client
 	inviteService   definition.InvitesService
+	publisher       events.Publisher
 }
 
-func New(config *configs.Config, authClient *auth.Client, firestoreClient *firestore.Client, inviteService definition.InvitesService) *handler {
-	return &handler{config, authClient, firestoreClient, inviteService}
+func New(config *configs.Config,
+	authClient *auth.Client,
+	firestoreClient *firestore.Client,
+	inviteService definition.InvitesService,
+	publisher events.Publisher) *handler {
+	return &handler{config, authClient, firestoreClient, inviteService, publisher}
 }
 
 func (h *handler) Create(resp http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	businessId := vars["business_id"]
 
-	body, err := ioutil.ReadAll(req.Body)
-	if common.RespondWithError(err, resp, http.StatusBadRequest) {
-		return
-	}
-
 	type inviteRequest struct {
-		Email string `json:"email"`
+		Email string `json:"email" validate:"required,email"`
 		Role  int64  `json:"role"`
 		Mode  string `json:"mode"`
 	}
 	var requests []*inviteRequest
-	err = json.Unmarshal(body, &requests)
-	if common.RespondWithError(err, resp, http.StatusBadRequest) {
+	if err := validation.Decode(resp, req, &requests); err != nil {
+		apierrors.Respond(resp, err)
+		return
+	}
+	if len(requests) > maxInvites {
+		apierrors.Respond(resp, apierrors.Newf(apierrors.CodeInvalidArgument, "at most %d invites can be sent at once", maxInvites))
 		return
 	}
 
 	batch := h.firestoreClient.Batch()
 	invitesRef := h.firestoreClient.Collection("businesses").Doc(businessId).Collection("invites")
+	var invited []events.Event
 
 	for _, request := range requests {
 		newDoc := invitesRef.NewDoc()
@@ -71,13 +81,28 @@ func (h *handler) Create(resp http.ResponseWriter, req *http.Request) {
 		}
 
 		batch.Set(newDoc, &invite)
+		invited = append(invited, &events.AssociateInvited{
+			BusinessID: businessId,
+			InviteID:   newDoc.ID,
+			Email:      request.Email,
+			Role:       request.Role,
+			Mode:       request.Mode,
+		})
+	}
+	pending, err := events.InBatch(req.Context(), h.publisher, batch, invited...)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
 	}
 
 	_, err = batch.Commit(context.Background())
 
-	if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
+	ctx := req.Context()
+	if err = h.publisher.Publish(ctx, pending...); err != nil {
+		logger.FromContext(ctx).Error(ctx, "failed to publish the events", "error", err)
+	}
 
 	resp.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(resp).Encode(&model.BaseResponse{Status: http.StatusText(http.StatusOK)})
@@ -99,6 +124,6 @@ func (h *handler) Delete() func(resp http.ResponseWriter, req *http.Request) {
 }
 
 func (h *handler) SetupRouts(router *mux.Router) {
-	router.HandleFunc(invitesPath, h.Create).Methods(http.MethodPost)
-	router.HandleFunc(invitePath, h.Delete()).Methods(http.MethodDelete)
+	router.HandleFunc(InvitesPath, h.Create).Methods(http.MethodPost)
+	router.HandleFunc(InvitePath, h.Delete()).Methods(http.MethodDelete)
 }
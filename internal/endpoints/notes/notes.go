@@ -0,0 +1,363 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/gorilla/mux"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	PathCaseNotes = "/businesses/{business_id}/cases/{case_id}/notes"
+	PathCaseNote  = PathCaseNotes + "/{note_id}"
+
+	PathCustomerNotes = "/businesses/{business_id}/businessCustomers/{customer_id}/notes"
+	PathCustomerNote  = PathCustomerNotes + "/{note_id}"
+
+	noteMentionCategory = "NOTE_MENTION_CATEGORY"
+)
+
+// notesRef resolves the notes collection of the request.
+type notesRef func(vars map[string]string) *firestore.CollectionRef
+
+type handler struct {
+	db          *db.Firestore
+	pushService definition.PushService
+}
+
+func NewHandler(db *db.Firestore, pushService definition.PushService) *handler {
+	return &handler{db, pushService}
+}
+
+type noteRequest struct {
+	Text        string                  `json:"text" validate:"required_without=Attachments,max=4000"`
+	MentionIDs  []string                `json:"mentionIDs"`
+	Attachments []*model.NoteAttachment `json:"attachments" validate:"dive,required"`
+}
+
+type noteResponse struct {
+	model.BaseResponse
+	Note *model.Note `json:"note,omitempty"`
+}
+
+type notesResponse struct {
+	model.BaseResponse
+	Notes []*model.Note `json:"notes"`
+}
+
+func (h *handler) caseNotes(vars map[string]string) *firestore.CollectionRef {
+	return h.db.CaseNotes(vars["business_id"], vars["case_id"])
+}
+
+func (h *handler) customerNotes(vars map[string]string) *firestore.CollectionRef {
+	return h.db.BusinessCustomerNotes(vars["business_id"], vars["customer_id"])
+}
+
+func (h *handler) list(ref notesRef) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		notes, err := listNotes(ctx, ref(mux.Vars(req)))
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+			return
+		}
+		resp.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(resp).Encode(&notesResponse{
+			BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusOK)},
+			Notes:        notes,
+		})
+	}
+}
+
+func (h *handler) create(ref notesRef, subject string) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		uid := ctx.Value("uid").(string)
+		vars := mux.Vars(req)
+		businessId := vars["business_id"]
+
+		request, err := decodeNoteRequest(resp, req)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
+			return
+		}
+		author, err := h.associate(ctx, businessId, uid)
+		if apierrors.RespondWithError(err, resp, apierrors.CodePermissionDenied) {
+			return
+		}
+		mentions, err := h.mentions(ctx, businessId, request.MentionIDs)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
+			return
+		}
+
+		now := time.Now()
+		noteRef := ref(vars).NewDoc()
+		note := &model.Note{
+			Id:          noteRef.ID,
+			Text:        request.Text,
+			Author:      author,
+			Mentions:    mentions,
+			MentionIDs:  mentionIDs(mentions),
+			Attachments: request.Attachments,
+			CreatedDate: &now,
+		}
+		_, err = noteRef.Create(ctx, note)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+			return
+		}
+		h.notifyMentions(ctx, vars, note, note.MentionIDs, subject)
+
+		resp.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(resp).Encode(&noteResponse{
+			BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusCreated)},
+			Note:         note,
+		})
+	}
+}
+
+// update replaces the note text, mentions and attachments. The previous version goes to the note history.
+func (h *handler) update(ref notesRef, subject string) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		uid := ctx.Value("uid").(string)
+		vars := mux.Vars(req)
+
+		request, err := decodeNoteRequest(resp, req)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
+			return
+		}
+		mentions, err := h.mentions(ctx, vars["business_id"], request.MentionIDs)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
+			return
+		}
+
+		var note *model.Note
+		var previousMentionIDs []string
+		noteRef := ref(vars).Doc(vars["note_id"])
+		err = h.db.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+			snapshot, err := t.Get(noteRef)
+			if err != nil {
+				return err
+			}
+			if err = snapshot.DataTo(&note); err != nil {
+				return err
+			}
+			if note.AuthorId() != uid {
+				return errNotAuthor
+			}
+			now := time.Now()
+			previousMentionIDs = note.MentionIDs
+			note.History = append(note.History, &model.NoteRevision{
+				Text:        note.Text,
+				Attachments: note.Attachments,
+				EditedBy:    note.Author,
+				EditedDate:  &now,
+			})
+			note.Text = request.Text
+			note.Mentions = mentions
+			note.MentionIDs = mentionIDs(mentions)
+			note.Attachments = request.Attachments
+			note.UpdatedDate = &now
+			return t.Set(noteRef, note)
+		}, firestore.MaxAttempts(db.TransactionRetries))
+		if err == errNotAuthor {
+			apierrors.RespondWithError(err, resp, apierrors.CodePermissionDenied)
+			return
+		}
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+			return
+		}
+		h.notifyMentions(ctx, vars, note, note.NewMentionIDs(previousMentionIDs), subject)
+
+		resp.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(resp).Encode(&noteResponse{
+			BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusOK)},
+			Note:         note,
+		})
+	}
+}
+
+func (h *handler) delete(ref notesRef) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		uid := ctx.Value("uid").(string)
+		vars := mux.Vars(req)
+		noteRef := ref(vars).Doc(vars["note_id"])
+
+		err := h.db.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+			snapshot, err := t.Get(noteRef)
+			if err != nil {
+				return err
+			}
+			var note *model.Note
+			if err = snapshot.DataTo(&note); err != nil {
+				return err
+			}
+			if note.AuthorId() != uid {
+				return errNotAuthor
+			}
+			return t.Delete(noteRef)
+		}, firestore.MaxAttempts(db.TransactionRetries))
+		if err == errNotAuthor {
+			apierrors.RespondWithError(err, resp, apierrors.CodePermissionDenied)
+			return
+		}
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+			return
+		}
+		resp.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{Status: http.StatusText(http.StatusOK)})
+	}
+}
+
+var errNotAuthor = errors.New("only the author can change the note")
+
+func decodeNoteRequest(resp http.ResponseWriter, req *http.Request) (*noteRequest, error) {
+	var request noteRequest
+	if err := validation.Decode(resp, req, &request); err != nil {
+		return nil, err
+	}
+	request.Text = strings.TrimSpace(request.Text)
+	return &request, nil
+}
+
+// associate loads the associate of the business with the given uid.
+func (h *handler) associate(ctx context.Context, businessId string, uid string) (*model.AssociateItem, error) {
+	snapshot, err := h.db.User(uid).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var associate *model.Associate
+	if err = snapshot.DataTo(&associate); err != nil {
+		return nil, err
+	}
+	if associate.BusinessID() != businessId {
+		return nil, errors.New("not an associate of the business")
+	}
+	return &model.AssociateItem{Id: uid, Name: associate.GetName()}, nil
+}
+
+// mentions resolves the mentioned uids to associates of the business.
+func (h *handler) mentions(ctx context.Context, businessId string, uids []string) ([]*model.AssociateItem, error) {
+	uids = common.DeDuplicateStrings(uids)
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	var refs []*firestore.DocumentRef
+	for _, uid := range uids {
+		refs = append(refs, h.db.User(uid))
+	}
+	snapshots, err := h.db.GetAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	var mentions []*model.AssociateItem
+	for _, snapshot := range snapshots {
+		if !snapshot.Exists() {
+			return nil, fmt.Errorf("mentioned user %s not found", snapshot.Ref.ID)
+		}
+		var associate *model.Associate
+		if err = snapshot.DataTo(&associate); err != nil {
+			return nil, err
+		}
+		if associate.BusinessID() != businessId {
+			return nil, fmt.Errorf("mentioned user %s is not an associate of the business", snapshot.Ref.ID)
+		}
+		mentions = append(mentions, &model.AssociateItem{Id: snapshot.Ref.ID, Name: associate.GetName()})
+	}
+	return mentions, nil
+}
+
+func mentionIDs(mentions []*model.AssociateItem) (uids []string) {
+	for _, mention := range mentions {
+		uids = append(uids, mention.Id)
+	}
+	return
+}
+
+func (h *handler) notifyMentions(ctx context.Context, vars map[string]string, note *model.Note, uids []string, subject string) {
+	var recipients []string
+	for _, uid := range uids {
+		if uid != note.AuthorId() {
+			recipients = append(recipients, uid)
+		}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+	data := map[string]string{
+		"businessId": vars["business_id"],
+		"noteId":     note.Id,
+	}
+	if caseId, ok := vars["case_id"]; ok {
+		data["caseId"] = caseId
+	}
+	if customerId, ok := vars["customer_id"]; ok {
+		data["customerId"] = customerId
+	}
+	response := h.pushService.Send(ctx, definition.PushRequest{
+		UIDs:     recipients,
+		Title:    fmt.Sprintf("%s mentioned you in %s", note.AuthorName(), subject),
+		Body:     note.Text,
+		Category: noteMentionCategory,
+		Data:     data,
+	})
+	if !response.OK() {
+		logger.FromContext(ctx).Error(ctx, "failed to notify the note mentions", "noteId", note.Id, "error", response.Error)
+	}
+}
+
+// listNotes returns the notes of the collection, oldest first.
+func listNotes(ctx context.Context, ref *firestore.CollectionRef) ([]*model.Note, error) {
+	documents := ref.OrderBy("createdDate", firestore.Asc).Documents(ctx)
+	defer documents.Stop()
+	notes := make([]*model.Note, 0)
+	for {
+		snapshot, err := documents.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var note *model.Note
+		if err = snapshot.DataTo(&note); err != nil {
+			return nil, err
+		}
+		note.Id = snapshot.Ref.ID
+		notes = append(notes, note)
+	}
+	return notes, nil
+}
+
+func (h *handler) SetupRouts(router *mux.Router) {
+	router.HandleFunc(PathCaseNotes, h.list(h.caseNotes)).Methods(http.MethodGet)
+	router.HandleFunc(PathCaseNotes, h.create(h.caseNotes, "a case note")).Methods(http.MethodPost)
+	router.HandleFunc(PathCaseNote, h.update(h.caseNotes, "a case note")).Methods(http.MethodPatch)
+	router.HandleFunc(PathCaseNote, h.delete(h.caseNotes)).Methods(http.MethodDelete)
+
+	router.HandleFunc(PathCustomerNotes, h.list(h.customerNotes)).Methods(http.MethodGet)
+	router.HandleFunc(PathCustomerNotes, h.create(h.customerNotes, "a customer note")).Methods(http.MethodPost)
+	router.HandleFunc(PathCustomerNote, h.update(h.customerNotes, "a customer note")).Methods(http.MethodPatch)
+	router.HandleFunc(PathCustomerNote, h.delete(h.customerNotes)).Methods(http.MethodDelete)
+}
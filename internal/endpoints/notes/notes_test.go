@@ -0,0 +1,144 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/memfirestore"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/gorilla/mux"
+)
+
+// pushes records the push notifications sent.
+type pushes struct {
+	mu       sync.Mutex
+	requests []definition.PushRequest
+}
+
+func (p *pushes) Send(_ context.Context, req definition.PushRequest) definition.SendResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+	return definition.SendResponse{}
+}
+
+type fixture struct {
+	db     *db.Firestore
+	pushed *pushes
+	router *mux.Router
+}
+
+// newFixture returns the handler backed by Firestore in memory, with the associates a1 and a2 of
+// the business b1, and the associate a3 of another business.
+func newFixture(t *testing.T) *fixture {
+	client, cleanup, err := memfirestore.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cleanup)
+	f := &fixture{db: &db.Firestore{Client: client}, pushed: &pushes{}, router: mux.NewRouter()}
+	NewHandler(f.db, f.pushed).SetupRouts(f.router)
+
+	for uid, businessId := range map[string]string{"a1": "b1", "a2": "b1", "a3": "b2"} {
+		associate := &model.Associate{User: model.User{Name: uid}, Business: &model.BusinessItem{Id: businessId}}
+		if _, err = f.db.User(uid).Set(context.Background(), associate); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+func (f *fixture) request(method string, path string, uid string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), "uid", uid))
+	resp := httptest.NewRecorder()
+	f.router.ServeHTTP(resp, req)
+	return resp
+}
+
+func decodeNote(t *testing.T, resp *httptest.ResponseRecorder) *model.Note {
+	var response noteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil || response.Note == nil {
+		t.Fatalf("note response = %+v, %v", response, err)
+	}
+	return response.Note
+}
+
+func TestNotes(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		subject string
+	}{
+		{"case", "/businesses/b1/cases/case1/notes", "a case note"},
+		{"customer", "/businesses/b1/businessCustomers/u1/notes", "a customer note"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+
+			resp := f.request(http.MethodPost, tt.path, "a1", `{"text": " Call back ", "mentionIDs": ["a1", "a2", "a2"]}`)
+			if resp.Code != http.StatusCreated {
+				t.Fatalf("create = %d %s, want 201", resp.Code, resp.Body)
+			}
+			note := decodeNote(t, resp)
+			if note.Text != "Call back" || note.AuthorId() != "a1" || len(note.MentionIDs) != 2 {
+				t.Errorf("note = %+v, want the trimmed text of a1 mentioning a1 and a2", note)
+			}
+			if len(f.pushed.requests) != 1 || len(f.pushed.requests[0].UIDs) != 1 || f.pushed.requests[0].UIDs[0] != "a2" ||
+				f.pushed.requests[0].Title != "a1 mentioned you in "+tt.subject {
+				t.Errorf("pushes = %+v, want a2 notified of the mention", f.pushed.requests)
+			}
+			if resp := f.request(http.MethodPost, tt.path, "a1", `{"text": "Hi", "mentionIDs": ["a3"]}`); resp.Code != http.StatusBadRequest {
+				t.Errorf("create mentioning another business = %d %s, want 400", resp.Code, resp.Body)
+			}
+			if resp := f.request(http.MethodPost, tt.path, "a3", `{"text": "Hi"}`); resp.Code != http.StatusForbidden {
+				t.Errorf("create by another business = %d %s, want 403", resp.Code, resp.Body)
+			}
+			if resp := f.request(http.MethodPost, tt.path, "a1", `{"text": ""}`); resp.Code != http.StatusBadRequest {
+				t.Errorf("create without text nor attachments = %d %s, want 400", resp.Code, resp.Body)
+			}
+
+			notePath := tt.path + "/" + note.Id
+			if resp := f.request(http.MethodPatch, notePath, "a2", `{"text": "Mine"}`); resp.Code != http.StatusForbidden {
+				t.Errorf("update by a2 = %d %s, want 403", resp.Code, resp.Body)
+			}
+			resp = f.request(http.MethodPatch, notePath, "a1", `{"text": "Called back", "mentionIDs": ["a2"]}`)
+			if resp.Code != http.StatusOK {
+				t.Fatalf("update = %d %s, want 200", resp.Code, resp.Body)
+			}
+			if updated := decodeNote(t, resp); updated.Text != "Called back" || len(updated.History) != 1 || updated.History[0].Text != "Call back" {
+				t.Errorf("note = %+v, want the new text and the previous one in the history", updated)
+			}
+			if len(f.pushed.requests) != 1 {
+				t.Errorf("pushes = %d, want a2 not notified again", len(f.pushed.requests))
+			}
+
+			resp = f.request(http.MethodGet, tt.path, "a2", "")
+			var list notesResponse
+			if err := json.NewDecoder(resp.Body).Decode(&list); err != nil || resp.Code != http.StatusOK {
+				t.Fatalf("list = %d, %v", resp.Code, err)
+			}
+			if len(list.Notes) != 1 || list.Notes[0].Id != note.Id || list.Notes[0].Text != "Called back" {
+				t.Errorf("notes = %+v, want the updated note", list.Notes)
+			}
+
+			if resp := f.request(http.MethodDelete, notePath, "a2", ""); resp.Code != http.StatusForbidden {
+				t.Errorf("delete by a2 = %d %s, want 403", resp.Code, resp.Body)
+			}
+			if resp := f.request(http.MethodDelete, notePath, "a1", ""); resp.Code != http.StatusOK {
+				t.Errorf("delete = %d %s, want 200", resp.Code, resp.Body)
+			}
+			if resp := f.request(http.MethodDelete, notePath, "a1", ""); resp.Code != http.StatusNotFound {
+				t.Errorf("delete again = %d %s, want 404", resp.Code, resp.Body)
+			}
+		})
+	}
+}
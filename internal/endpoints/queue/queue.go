@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/gorilla/mux"
+)
+
+const (
+	PathBusinessQueue = "/businesses/{business_id}/queue"
+	PathCaseQueue     = "/businesses/{business_id}/cases/{case_id}/queue"
+)
+
+type handler struct {
+	queueService definition.QueueService
+}
+
+func NewHandler(queueService definition.QueueService) *handler {
+	return &handler{queueService}
+}
+
+type queueResponse struct {
+	model.BaseResponse
+	Queue            []*model.QueueItem `json:"queue"`
+	AvgAcceptTime    int64              `json:"avgAcceptTime"` // seconds
+	OnlineAssociates int                `json:"onlineAssociates"`
+}
+
+type positionResponse struct {
+	model.BaseResponse
+	Position      int   `json:"position"`
+	QueueLength   int   `json:"queueLength"`
+	EstimatedWait int64 `json:"estimatedWait"` // seconds
+}
+
+// queue lists the pending requests of the business. Optional contactId narrows the queue to a group or an associate.
+func (h *handler) queue(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	response := h.queueService.Queue(ctx, definition.QueueRequest{
+		BusinessID: mux.Vars(req)["business_id"],
+		ContactID:  req.URL.Query().Get("contactId"),
+	})
+	if err := response.Error; apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&queueResponse{
+		BaseResponse:     model.BaseResponse{Status: http.StatusText(http.StatusOK)},
+		Queue:            response.Items,
+		AvgAcceptTime:    int64(response.AvgAcceptTime / time.Second),
+		OnlineAssociates: response.OnlineAssociates,
+	})
+}
+
+// position returns the queue position of the customer request and the estimated wait.
+func (h *handler) position(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	vars := mux.Vars(req)
+	response := h.queueService.Position(ctx, definition.QueuePositionRequest{
+		BusinessID: vars["business_id"],
+		CaseID:     vars["case_id"],
+		CustomerID: ctx.Value("uid").(string),
+	})
+	switch err := response.Error; err {
+	case nil:
+	case domain.ErrNotCaseOwner:
+		apierrors.RespondWithError(err, resp, apierrors.CodePermissionDenied)
+		return
+	case domain.ErrCaseNotQueued:
+		apierrors.RespondWithError(err, resp, apierrors.CodeNotFound)
+		return
+	default:
+		apierrors.RespondWithError(err, resp, apierrors.CodeInternal)
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&positionResponse{
+		BaseResponse:  model.BaseResponse{Status: http.StatusText(http.StatusOK)},
+		Position:      response.Position,
+		QueueLength:   response.QueueLength,
+		EstimatedWait: int64(response.EstimatedWait / time.Second),
+	})
+}
+
+func (h *handler) SetupRouts(router *mux.Router) {
+	router.HandleFunc(PathBusinessQueue, h.queue).Methods(http.MethodGet)
+	router.HandleFunc(PathCaseQueue, h.position).Methods(http.MethodGet)
+}
@@ -0,0 +1,451 @@
+package replies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/gorilla/mux"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	PathReplies     = "/businesses/{business_id}/replies"
+	PathReply       = PathReplies + "/{reply_id}"
+	PathRenderReply = PathReply + "/render"
+
+	nextOpeningTimeFormat = "Mon, Jan 2 at 3:04PM"
+)
+
+var (
+	errNotAllowed       = apierrors.New(apierrors.CodePermissionDenied, "not allowed to change the reply")
+	errShortcutConflict = apierrors.New(apierrors.CodeConflict, "shortcut is already in use")
+)
+
+type handler struct {
+	db *db.Firestore
+}
+
+func NewHandler(db *db.Firestore) *handler {
+	return &handler{db}
+}
+
+type replyRequest struct {
+	Scope    model.ReplyScope `json:"scope" validate:"oneof=business personal"`
+	Folder   string           `json:"folder" validate:"max=100"`
+	Shortcut string           `json:"shortcut" validate:"max=50"`
+	Title    string           `json:"title" validate:"max=200"`
+	Text     string           `json:"text" validate:"required,max=4000"`
+}
+
+type renderRequest struct {
+	TextSessionId string `json:"textSessionId"`
+	CustomerName  string `json:"customerName"`
+}
+
+type replyResponse struct {
+	model.BaseResponse
+	Reply *model.Reply `json:"reply,omitempty"`
+}
+
+type repliesResponse struct {
+	model.BaseResponse
+	Replies []*model.Reply `json:"replies"`
+	Folders []string       `json:"folders"`
+}
+
+type renderResponse struct {
+	model.BaseResponse
+	Text string `json:"text"`
+}
+
+// repliesRef resolves the replies collection of the scope.
+func (h *handler) repliesRef(scope model.ReplyScope, businessId string, uid string) *firestore.CollectionRef {
+	if scope == model.ReplyScopePersonal {
+		return h.db.UserReplies(uid)
+	}
+	return h.db.BusinessReplies(businessId)
+}
+
+func requestScope(req *http.Request) model.ReplyScope {
+	if model.ReplyScope(req.URL.Query().Get("scope")) == model.ReplyScopePersonal {
+		return model.ReplyScopePersonal
+	}
+	return model.ReplyScopeBusiness
+}
+
+// list returns the business and the personal replies of the associate.
+// Optional filters: scope, folder, shortcut and staleDays - replies not used for the number of days.
+func (h *handler) list(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	uid := ctx.Value("uid").(string)
+	businessId := mux.Vars(req)["business_id"]
+	query := req.URL.Query()
+
+	scopes := []model.ReplyScope{model.ReplyScopeBusiness, model.ReplyScopePersonal}
+	if scope := model.ReplyScope(query.Get("scope")); len(scope) > 0 {
+		scopes = []model.ReplyScope{scope}
+	}
+	var staleSince *time.Time
+	if staleDays, err := strconv.Atoi(query.Get("staleDays")); err == nil && staleDays > 0 {
+		since := time.Now().AddDate(0, 0, -staleDays)
+		staleSince = &since
+	}
+
+	replies := make([]*model.Reply, 0)
+	folders := make([]string, 0)
+	seenFolders := make(map[string]bool)
+	for _, scope := range scopes {
+		q := h.repliesRef(scope, businessId, uid).Query
+		if scope == model.ReplyScopePersonal {
+			q = q.Where("businessId", "==", businessId)
+		}
+		if folder := query.Get("folder"); len(folder) > 0 {
+			q = q.Where("folder", "==", folder)
+		}
+		if shortcut := query.Get("shortcut"); len(shortcut) > 0 {
+			q = q.Where("shortcut", "==", shortcut)
+		}
+		scopeReplies, err := readReplies(q.Documents(ctx))
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+			return
+		}
+		for _, reply := range scopeReplies {
+			if staleSince != nil && !reply.IsStale(*staleSince) {
+				continue
+			}
+			if len(reply.Folder) > 0 && !seenFolders[reply.Folder] {
+				seenFolders[reply.Folder] = true
+				folders = append(folders, reply.Folder)
+			}
+			replies = append(replies, reply)
+		}
+	}
+	sort.SliceStable(replies, func(i, j int) bool {
+		return replies[i].UsageCount > replies[j].UsageCount
+	})
+	sort.Strings(folders)
+
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&repliesResponse{
+		BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusOK)},
+		Replies:      replies,
+		Folders:      folders,
+	})
+}
+
+func (h *handler) create(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	uid := ctx.Value("uid").(string)
+	businessId := mux.Vars(req)["business_id"]
+
+	request, err := decodeReplyRequest(resp, req)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
+		return
+	}
+	if len(request.Scope) == 0 {
+		request.Scope = model.ReplyScopePersonal
+	}
+	associate, err := h.associate(ctx, businessId, uid)
+	if apierrors.RespondWithError(err, resp, apierrors.CodePermissionDenied) {
+		return
+	}
+	if request.Scope == model.ReplyScopeBusiness && !isAdmin(associate) {
+		apierrors.RespondWithError(errNotAllowed, resp, apierrors.CodePermissionDenied)
+		return
+	}
+
+	repliesRef := h.repliesRef(request.Scope, businessId, uid)
+	if err = h.checkShortcut(ctx, repliesRef, businessId, request.Shortcut, ""); err != nil {
+		apierrors.RespondWithError(err, resp, apierrors.CodeConflict)
+		return
+	}
+
+	now := time.Now()
+	replyRef := repliesRef.NewDoc()
+	reply := &model.Reply{
+		Id:          replyRef.ID,
+		Scope:       request.Scope,
+		BusinessId:  businessId,
+		Folder:      request.Folder,
+		Shortcut:    request.Shortcut,
+		Title:       request.Title,
+		Text:        request.Text,
+		CreatedBy:   &model.AssociateItem{Id: uid, Name: associate.GetName()},
+		CreatedDate: &now,
+	}
+	if request.Scope == model.ReplyScopePersonal {
+		reply.OwnerId = uid
+	}
+	_, err = replyRef.Create(ctx, reply)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	resp.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(resp).Encode(&replyResponse{
+		BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusCreated)},
+		Reply:        reply,
+	})
+}
+
+func (h *handler) update(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	uid := ctx.Value("uid").(string)
+	vars := mux.Vars(req)
+	businessId := vars["business_id"]
+	scope := requestScope(req)
+
+	request, err := decodeReplyRequest(resp, req)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
+		return
+	}
+	err = h.checkAccess(ctx, scope, businessId, uid)
+	if apierrors.RespondWithError(err, resp, apierrors.CodePermissionDenied) {
+		return
+	}
+	repliesRef := h.repliesRef(scope, businessId, uid)
+	if err = h.checkShortcut(ctx, repliesRef, businessId, request.Shortcut, vars["reply_id"]); err != nil {
+		apierrors.RespondWithError(err, resp, apierrors.CodeConflict)
+		return
+	}
+
+	replyRef := repliesRef.Doc(vars["reply_id"])
+	_, err = replyRef.Update(ctx, []firestore.Update{
+		{Path: "folder", Value: request.Folder},
+		{Path: "shortcut", Value: request.Shortcut},
+		{Path: "title", Value: request.Title},
+		{Path: "text", Value: request.Text},
+		{Path: "updatedDate", Value: firestore.ServerTimestamp},
+	})
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	snapshot, err := replyRef.Get(ctx)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	var reply *model.Reply
+	if err = snapshot.DataTo(&reply); apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&replyResponse{
+		BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusOK)},
+		Reply:        reply,
+	})
+}
+
+func (h *handler) delete(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	uid := ctx.Value("uid").(string)
+	vars := mux.Vars(req)
+	businessId := vars["business_id"]
+	scope := requestScope(req)
+
+	err := h.checkAccess(ctx, scope, businessId, uid)
+	if apierrors.RespondWithError(err, resp, apierrors.CodePermissionDenied) {
+		return
+	}
+	_, err = h.repliesRef(scope, businessId, uid).Doc(vars["reply_id"]).Delete(ctx)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&model.BaseResponse{Status: http.StatusText(http.StatusOK)})
+}
+
+// render returns the reply text with the template variables substituted and counts the usage of the reply.
+func (h *handler) render(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	uid := ctx.Value("uid").(string)
+	vars := mux.Vars(req)
+	businessId := vars["business_id"]
+	scope := requestScope(req)
+
+	var request *renderRequest
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil || request == nil {
+		request = &renderRequest{}
+	}
+	associate, err := h.associate(ctx, businessId, uid)
+	if apierrors.RespondWithError(err, resp, apierrors.CodePermissionDenied) {
+		return
+	}
+
+	replyRef := h.repliesRef(scope, businessId, uid).Doc(vars["reply_id"])
+	snapshot, err := replyRef.Get(ctx)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	var reply *model.Reply
+	if err = snapshot.DataTo(&reply); apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+
+	variables := model.ReplyVariables{
+		CustomerName:  request.CustomerName,
+		BusinessName:  associate.BusinessName(),
+		AssociateName: associate.GetName(),
+	}
+	if len(request.TextSessionId) > 0 {
+		snapshot, err := h.db.Chat(request.TextSessionId).Get(ctx)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+			return
+		}
+		var chat *model.TextSession
+		if err = snapshot.DataTo(&chat); apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+			return
+		}
+		if chat.BusinessID() != businessId {
+			apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, "chat belongs to another business"))
+			return
+		}
+		if name := chat.CustomerName(); len(name) > 0 {
+			variables.CustomerName = name
+		}
+		if name := chat.BusinessName(); len(name) > 0 {
+			variables.BusinessName = name
+		}
+	}
+	if strings.Contains(reply.Text, model.ReplyVarNextOpeningTime) {
+		variables.NextOpeningTime = h.nextOpeningTime(ctx, businessId)
+	}
+
+	_, err = replyRef.Update(ctx, []firestore.Update{
+		{Path: "usageCount", Value: firestore.Increment(1)},
+		{Path: "lastUsedDate", Value: firestore.ServerTimestamp},
+	})
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&renderResponse{
+		BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusOK)},
+		Text:         reply.Render(variables),
+	})
+}
+
+func (h *handler) nextOpeningTime(ctx context.Context, businessId string) string {
+	snapshot, err := h.db.BusinessSettings(businessId).Get(ctx)
+	if err != nil {
+		return ""
+	}
+	var settings *model.Settings
+	if err = snapshot.DataTo(&settings); err != nil || len(settings.WorkingDays) == 0 {
+		return ""
+	}
+	if nearest := settings.NearestWorkingTime(); nearest != nil {
+		return nearest.Format(nextOpeningTimeFormat)
+	}
+	return ""
+}
+
+func (h *handler) associate(ctx context.Context, businessId string, uid string) (*model.Associate, error) {
+	snapshot, err := h.db.User(uid).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var associate *model.Associate
+	if err = snapshot.DataTo(&associate); err != nil {
+		return nil, err
+	}
+	if associate.BusinessID() != businessId {
+		return nil, errors.New("not an associate of the business")
+	}
+	return associate, nil
+}
+
+// checkAccess allows admins to change business replies. Personal replies are always owned by the caller.
+func (h *handler) checkAccess(ctx context.Context, scope model.ReplyScope, businessId string, uid string) error {
+	associate, err := h.associate(ctx, businessId, uid)
+	if err != nil {
+		return apierrors.Wrap(apierrors.CodePermissionDenied, err)
+	}
+	if scope == model.ReplyScopeBusiness && !isAdmin(associate) {
+		return errNotAllowed
+	}
+	return nil
+}
+
+// checkShortcut makes sure the shortcut is unique in the scope.
+func (h *handler) checkShortcut(ctx context.Context, repliesRef *firestore.CollectionRef, businessId string, shortcut string, replyId string) error {
+	if len(shortcut) == 0 {
+		return nil
+	}
+	replies, err := readReplies(repliesRef.Where("shortcut", "==", shortcut).Documents(ctx))
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if reply.Id != replyId && reply.BusinessId == businessId {
+			return errShortcutConflict
+		}
+	}
+	return nil
+}
+
+func isAdmin(associate *model.Associate) bool {
+	return associate.Roles != nil && (associate.Roles.Admin || associate.Roles.SuperAdmin)
+}
+
+func decodeReplyRequest(resp http.ResponseWriter, req *http.Request) (*replyRequest, error) {
+	var request replyRequest
+	if err := validation.Decode(resp, req, &request); err != nil {
+		return nil, err
+	}
+	request.Text = strings.TrimSpace(request.Text)
+	request.Shortcut = strings.TrimSpace(request.Shortcut)
+	request.Folder = strings.TrimSpace(request.Folder)
+	if len(request.Title) == 0 {
+		request.Title = request.Shortcut
+	}
+	return &request, nil
+}
+
+func readReplies(documents *firestore.DocumentIterator) ([]*model.Reply, error) {
+	defer documents.Stop()
+	var replies []*model.Reply
+	for {
+		snapshot, err := documents.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var reply *model.Reply
+		if err = snapshot.DataTo(&reply); err != nil {
+			return nil, err
+		}
+		reply.Id = snapshot.Ref.ID
+		replies = append(replies, reply)
+	}
+	return replies, nil
+}
+
+func (h *handler) SetupRouts(router *mux.Router) {
+	router.HandleFunc(PathReplies, h.list).Methods(http.MethodGet)
+	router.HandleFunc(PathReplies, h.create).Methods(http.MethodPost)
+	router.HandleFunc(PathReply, h.update).Methods(http.MethodPatch)
+	router.HandleFunc(PathReply, h.delete).Methods(http.MethodDelete)
+	router.HandleFunc(PathRenderReply, h.render).Methods(http.MethodPost)
+}
@@ -9,6 +9,8 @@ import (
 	"sync"
 
 	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/gorilla/mux"
 	"google.golang.org/api/iterator"
@@ -31,29 +33,17 @@ func (h *handler) DbReset(resp http.ResponseWriter, req *http.Request) {
 	body, err := ioutil.ReadAll(req.Body)
 
 	if err != nil {
-		fmt.Println(fmt.Errorf("DbReset error: %v", err))
-		_ = json.NewEncoder(resp).Encode(err)
+		logger.FromContext(req.Context()).Warn(req.Context(), "failed to read the reset request", "error", err)
+		apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 		return
 	}
 
-	//err := req.ParseForm()
-
-	//if err != nil {
-	//	fmt.Println(fmt.Errorf("DbReset parse from error: %v", err))
-	//	_ = json.NewEncoder(resp).Encode(err)
-	//	return
-	//}
-
-	//values := req.PostForm
-	//_ = json.NewEncoder(resp).Encode(string(bytes))
-	//return
-
 	var resetRequest *model.ResetRequest
 	err = json.Unmarshal(body, &resetRequest)
 
 	if err != nil {
-		fmt.Println(fmt.Errorf("DbReset error: %v", err))
-		_ = json.NewEncoder(resp).Encode(err)
+		logger.FromContext(req.Context()).Warn(req.Context(), "failed to read the reset request", "error", err)
+		apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 		return
 	}
 
@@ -82,7 +72,7 @@ func (h *handler) DbReset(resp http.ResponseWriter, req *http.Request) {
 
 	for _, collectionID := range collections {
 		wg.Add(1)
-		go processCollection(h.firestoreClient, collectionID)
+		go processCollection(logger.FromContext(req.Context()), h.firestoreClient, collectionID)
 	}
 
 	wg.Wait()
@@ -94,7 +84,7 @@ func (h *handler) SetupRouts(router *mux.Router) {
 	router.HandleFunc(PathDbReset, h.DbReset).Methods(http.MethodPost)
 }
 
-func processCollection(firestoreClient *firestore.Client, collectionID string) {
+func processCollection(l *logger.Logger, firestoreClient *firestore.Client, collectionID string) {
 	defer wg.Done()
 
 	var docs = firestoreClient.CollectionGroup(collectionID).Limit(499).Documents(context.Background())
@@ -109,7 +99,7 @@ func processCollection(firestoreClient *firestore.Client, collectionID string) {
 		}
 
 		if err != nil {
-			fmt.Println(fmt.Errorf("error processing document: %v", err))
+			l.Error(context.Background(), "failed to read the documents to reset", "collection", collectionID, "error", err)
 			break
 		}
 
@@ -119,15 +109,14 @@ func processCollection(firestoreClient *firestore.Client, collectionID string) {
 	results, err := batch.Commit(context.Background())
 
 	if err != nil {
-		err := fmt.Errorf("error: %v", err)
-		fmt.Println(err)
-		clearedCollections[collectionID] = err.Error()
+		l.Error(context.Background(), "failed to reset the collection", "collection", collectionID, "error", err)
+		clearedCollections[collectionID] = fmt.Sprintf("error: %v", err)
 		return
 	}
 
 	if len(results) == 499 {
 		wg.Add(1)
-		go processCollection(firestoreClient, collectionID)
+		go processCollection(l, firestoreClient, collectionID)
 	} else {
 		clearedCollections[collectionID] = "cleared"
 	}
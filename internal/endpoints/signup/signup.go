@@ -3,17 +3,17 @@ package signup
 import (
 	"context"
 	"encoding/json"
-	"errors"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	"firebase.google.com/go/v4/auth"
 	"github.com/VinothKuppanna/pigeon-go/configs"
-	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
 	"github.com/VinothKuppanna/pigeon-go/internal/middleware/hubspot"
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 	"github.com/gorilla/mux"
@@ -45,20 +45,19 @@ func NewHandler(config *configs.Config,
 
 func (h *handler) SignUp() http.HandlerFunc {
 	type signUpRequest struct {
-		FullName     string `json:"fullName"`
-		BusinessName string `json:"businessName"`
-		Password     string `json:"password"`
-		Email        string `json:"email"`
+		FullName     string `json:"fullName" validate:"required_without=UID,max=200"`
+		BusinessName string `json:"businessName" validate:"required,max=200"`
+		Password     string `json:"password" validate:"required_without=UID,max=4096"`
+		Email        string `json:"email" validate:"required_without=UID,email"`
 		UID          string `json:"uid"`
 	}
 	return func(resp http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		var request *signUpRequest
-		body, err := ioutil.ReadAll(req.Body)
-		err = json.Unmarshal(body, &request)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		var request signUpRequest
+		if err := validation.Decode(resp, req, &request); err != nil {
+			apierrors.Respond(resp, err)
 			return
 		}
 
@@ -67,10 +66,10 @@ func (h *handler) SignUp() http.HandlerFunc {
 
 		if len(uid) > 0 {
 			userRecord, err := h.authClient.GetUser(ctx, uid)
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 				return
 			}
-			if err := signUpAndCreateBusiness(ctx, h.authClient, h.firestoreClient, userRecord, businessName); common.RespondWithError(err, resp, http.StatusInternalServerError) {
+			if err := h.signUpAndCreateBusiness(ctx, userRecord, businessName); apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 				return
 			}
 			resp.WriteHeader(http.StatusCreated)
@@ -84,11 +83,6 @@ func (h *handler) SignUp() http.HandlerFunc {
 		email := request.Email
 		password := request.Password
 
-		if len(fullName) == 0 || len(businessName) == 0 || len(email) == 0 || len(password) == 0 {
-			common.RespondWithError(errors.New("bad request arguments"), resp, http.StatusBadRequest)
-			return
-		}
-
 		newUser := &auth.UserToCreate{}
 		newUser.DisplayName(fullName)
 		newUser.Email(email)
@@ -97,11 +91,11 @@ func (h *handler) SignUp() http.HandlerFunc {
 
 		userRecord, err := h.authClient.CreateUser(ctx, newUser)
 
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 
-		if err := signUpAndCreateBusiness(ctx, h.authClient, h.firestoreClient, userRecord, businessName); common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		if err := h.signUpAndCreateBusiness(ctx, userRecord, businessName); apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 
@@ -112,7 +106,7 @@ func (h *handler) SignUp() http.HandlerFunc {
 		})
 
 		if !sendResponse.OK() {
-			log.Println("failed send email.", sendResponse.Error)
+			logger.FromContext(ctx).Error(ctx, "failed to send the sign-up email", "error", sendResponse.Error)
 		}
 
 		resp.WriteHeader(http.StatusCreated)
@@ -122,7 +116,8 @@ func (h *handler) SignUp() http.HandlerFunc {
 	}
 }
 
-func signUpAndCreateBusiness(ctx context.Context, authClient *auth.Client, firestoreClient *firestore.Client, userRecord *auth.UserRecord, businessName string) error {
+func (h *handler) signUpAndCreateBusiness(ctx context.Context, userRecord *auth.UserRecord, businessName string) error {
+	authClient, firestoreClient := h.authClient, h.firestoreClient
 	transFunc := func(ctx context.Context, transaction *firestore.Transaction) error {
 		newDoc := firestoreClient.Collection("businesses").NewDoc()
 		businessId := newDoc.ID
@@ -167,6 +162,17 @@ func signUpAndCreateBusiness(ctx context.Context, authClient *auth.Client, fires
 		if err != nil {
 			return err
 		}
+		if h.config.Hubspot.Enabled {
+			err = h.mw.SignedUp(ctx, transaction, hubspot.SignUp{
+				FullName:     userRecord.DisplayName,
+				BusinessName: businessName,
+				Email:        userRecord.Email,
+				UID:          userRecord.UID,
+			})
+			if err != nil {
+				return err
+			}
+		}
 
 		err = authClient.SetCustomUserClaims(ctx, userRecord.UID, map[string]interface{}{
 			"businessId": businessId,
@@ -186,9 +192,5 @@ func signUpAndCreateBusiness(ctx context.Context, authClient *auth.Client, fires
 }
 
 func (h *handler) SetupRouts(router *mux.Router) {
-	hf := h.SignUp()
-	if h.config.Hubspot.Enabled {
-		hf = h.mw.SignUp(hf)
-	}
-	router.HandleFunc(PathBusinessSignUp, hf).Methods(http.MethodPost)
+	routes.HandleFunc(router, PathBusinessSignUp, h.SignUp()).Methods(http.MethodPost).Public()
 }
@@ -0,0 +1,132 @@
+package sla
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/scheduler"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/gorilla/mux"
+)
+
+const (
+	PathBusinessSLA = "/businesses/{business_id}/sla"
+	PathCaseSLA     = "/businesses/{business_id}/cases/{case_id}/sla"
+
+	DefaultEvaluationInterval = time.Minute
+	evaluationJobID           = "sla-evaluation"
+)
+
+type handler struct {
+	db         *db.Firestore
+	slaService definition.SLAService
+}
+
+func NewHandler(db *db.Firestore, slaService definition.SLAService) *handler {
+	return &handler{db, slaService}
+}
+
+type policyResponse struct {
+	model.BaseResponse
+	Policy *model.SLAPolicy `json:"policy"`
+}
+
+type caseSLAResponse struct {
+	model.BaseResponse
+	CaseId   string                    `json:"caseId"`
+	Priority int64                     `json:"priority"`
+	SLA      map[string]*model.CaseSLA `json:"sla"`
+}
+
+func (h *handler) getPolicy(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	businessId := mux.Vars(req)["business_id"]
+	snapshot, err := h.db.BusinessSettings(businessId).Get(ctx)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	var settings *model.Settings
+	if err = snapshot.DataTo(&settings); apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	policy := settings.SLA
+	if policy == nil {
+		policy = &model.SLAPolicy{}
+	}
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&policyResponse{
+		BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusOK)},
+		Policy:       policy,
+	})
+}
+
+func (h *handler) updatePolicy(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	businessId := mux.Vars(req)["business_id"]
+	var policy *model.SLAPolicy
+	if err := validation.Decode(resp, req, &policy); err != nil {
+		apierrors.Respond(resp, err)
+		return
+	}
+	_, err := h.db.BusinessSettings(businessId).Update(ctx, []firestore.Update{{Path: "sla", Value: policy}})
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&policyResponse{
+		BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusOK)},
+		Policy:       policy,
+	})
+}
+
+func (h *handler) caseSLA(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	vars := mux.Vars(req)
+	response := h.slaService.CaseSLA(ctx, definition.CaseSLARequest{
+		BusinessID: vars["business_id"],
+		CaseID:     vars["case_id"],
+	})
+	if err := response.Error; apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&caseSLAResponse{
+		BaseResponse: model.BaseResponse{Status: http.StatusText(http.StatusOK)},
+		CaseId:       response.Case.Id,
+		Priority:     response.Case.Priority,
+		SLA:          response.SLA,
+	})
+}
+
+// Schedule registers the periodic evaluation of the SLA policies of all the businesses.
+func (h *handler) Schedule(ctx context.Context, jobScheduler scheduler.Scheduler, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultEvaluationInterval
+	}
+	job := jobScheduler.NewJob(evaluationJobID, func(ctx context.Context) {
+		if err := h.slaService.EvaluateAll(ctx); err != nil {
+			logger.FromContext(ctx).Error(ctx, "SLA evaluation failed", "error", err)
+		}
+	})
+	jobScheduler.AddPeriodic(ctx, job, interval)
+}
+
+func (h *handler) SetupRouts(router *mux.Router) {
+	router.HandleFunc(PathBusinessSLA, h.getPolicy).Methods(http.MethodGet)
+	router.HandleFunc(PathBusinessSLA, h.updatePolicy).Methods(http.MethodPut)
+	router.HandleFunc(PathCaseSLA, h.caseSLA).Methods(http.MethodGet)
+}
@@ -10,6 +10,9 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/metrics"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
@@ -40,23 +43,14 @@ func NewHandler(config *configs.Config,
 
 func (h *handler) sendSMS() http.HandlerFunc {
 	type sendSMSRequest struct {
-		To   []string `json:"to"`
-		Text string   `json:"text"`
+		To   []string `json:"to" validate:"required,max=100,dive,e164"`
+		Text string   `json:"text" validate:"required,max=1600"`
 	}
 	type sendSMSResponse struct {
 		Status  string   `json:"status"`
 		Message string   `json:"message"`
 		Errors  []string `json:"errors"`
 	}
-	validateRequest := func(request *sendSMSRequest) error {
-		if len(request.To) == 0 {
-			return errors.New("missed request parameter - 'to'")
-		}
-		if request.Text == "" {
-			return errors.New("missed request parameter - 'textß'")
-		}
-		return nil
-	}
 	formatPhoneNumber := func(phone string) string {
 		return strings.TrimSpace(strings.ReplaceAll(phone, "+", ""))
 	}
@@ -68,54 +62,50 @@ func (h *handler) sendSMS() http.HandlerFunc {
 		}()
 		uid := ctx.Value("uid").(string)
 		var sendSMSRequest *sendSMSRequest
-		err := json.NewDecoder(req.Body).Decode(&sendSMSRequest)
+		err := validation.Decode(resp, req, &sendSMSRequest)
 		if err != nil {
-			http.Error(resp, errors.Wrap(err, "failed to decode request").Error(), http.StatusBadRequest)
-			return
-		}
-		if err = validateRequest(sendSMSRequest); err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
+			apierrors.Respond(resp, err)
 			return
 		}
 		var associate *model.Associate
 		snapshot, err := h.db.User(uid).Get(ctx)
 		if err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 		err = snapshot.DataTo(&associate)
 		if err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 		businessID := associate.BusinessID()
 		if businessID == "" {
-			http.Error(resp, ErrorNoBusiness, http.StatusBadRequest)
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeInvalidArgument, ErrorNoBusiness))
 			return
 		}
 		var business *model.Business
 		businessRef := h.db.Business(businessID)
 		snapshot, err = businessRef.Get(ctx)
 		if err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 		err = snapshot.DataTo(&business)
 		if err != nil {
-			http.Error(resp, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 			return
 		}
 		dynamicLink := business.DynamicLink
 		if dynamicLink == "" {
 			linkResponse := h.dlService.GenerateBusinessLink(ctx, definition.BusinessLinkRequest{BusinessID: businessID})
 			if err = linkResponse.Error; err != nil {
-				http.Error(resp, err.Error(), http.StatusBadRequest)
+				apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 				return
 			}
 			dynamicLink = linkResponse.ShortLink
 			_, err = businessRef.Update(ctx, []firestore.Update{{Path: "dynamicLink", Value: dynamicLink}})
 			if err != nil {
-				http.Error(resp, err.Error(), http.StatusBadRequest)
+				apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 				return
 			}
 		}
@@ -129,11 +119,13 @@ func (h *handler) sendSMS() http.HandlerFunc {
 		}
 		for _, to := range sendSMSRequest.To {
 			toNumber := formatPhoneNumber(to)
+			start := time.Now()
 			response, errResponse, err := h.smsClient.Send(
 				fromNumber,
 				toNumber,
 				smsText,
 				vonage.SMSOpts{})
+			metrics.ObserveProviderResult(metrics.ProviderVonage, "SendSMS", start, err == nil && response.Messages[0].Status == "0")
 			if err != nil {
 				sendSMSResponse.Errors = append(
 					sendSMSResponse.Errors,
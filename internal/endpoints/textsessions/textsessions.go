@@ -4,18 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"time"
 
-	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
 	"github.com/VinothKuppanna/pigeon-go/internal/common"
-	"github.com/VinothKuppanna/pigeon-go/pkg/data"
-	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
 	"github.com/gorilla/mux"
-	"google.golang.org/api/iterator"
 )
 
 const (
@@ -27,26 +25,59 @@ const (
 	PathTextSessionMembers       string = "/textsessions/{text_session_id}/members"
 )
 
+// dummyCustomerEmail is the email of the customer who chats with the associates in the tour.
+const dummyCustomerEmail = "dummy.customer@getpigeon.com"
+
+var errNoBusinessContact = apierrors.New(apierrors.CodePermissionDenied, "Associate has no business contact assigned")
+
 type handler struct {
-	db                    *db.Firestore
 	textSessionRepository definition.TextSessionsRepository
-	messagesRepository    data.MessagesRepository
+	messagesRepository    definition.MessagesRepository
+	directoryRepository   definition.DirectoryRepository
 	businessesRepository  definition.BusinessesRepository
 	bizSettingsRepository definition.BusinessSettingsRepository
+	customersRepository   definition.CustomersRepository
+	publisher             events.Publisher
 }
 
-func NewHandler(firestoreClient *db.Firestore,
-	textSessionRepository definition.TextSessionsRepository,
-	messagesRepository data.MessagesRepository,
+func NewHandler(textSessionRepository definition.TextSessionsRepository,
+	messagesRepository definition.MessagesRepository,
+	directoryRepository definition.DirectoryRepository,
 	businessesRepository definition.BusinessesRepository,
-	bizSettingsRepository definition.BusinessSettingsRepository) *handler {
+	bizSettingsRepository definition.BusinessSettingsRepository,
+	customersRepository definition.CustomersRepository,
+	publisher events.Publisher) *handler {
 	return &handler{
-		firestoreClient,
 		textSessionRepository,
 		messagesRepository,
+		directoryRepository,
 		businessesRepository,
 		bizSettingsRepository,
+		customersRepository,
+		publisher,
+	}
+}
+
+// messagesPosted returns a MessagePosted for each of the messages posted to the chat.
+func messagesPosted(messages ...*model.Message) []events.Event {
+	posted := make([]events.Event, 0, len(messages))
+	for _, message := range messages {
+		event := &events.MessagePosted{TextSessionID: message.TextSessionId, MessageID: message.Id, Type: int(message.Type)}
+		if message.Sender != nil {
+			event.SenderUID = message.Sender.Uid
+		}
+		posted = append(posted, event)
 	}
+	return posted
+}
+
+// findAssociateContact returns the directory contact the customer chats with.
+func (h *handler) findAssociateContact(ctx context.Context, businessId string, contactId string) (*model.Contact, error) {
+	contact, err := h.directoryRepository.FindById(ctx, businessId, contactId)
+	if apierrors.CodeOf(err) == apierrors.CodeNotFound {
+		return nil, errNoBusinessContact
+	}
+	return contact, err
 }
 
 type textSessionsResponse struct {
@@ -57,90 +88,44 @@ type textSessionsResponse struct {
 
 func (h *handler) CreateTourActiveChat() func(resp http.ResponseWriter, req *http.Request) {
 	type createActiveTextSessionRequest struct {
-		BusinessId  string `json:"businessId"`
-		AssociateId string `json:"associateId"`
+		BusinessId  string `json:"businessId" validate:"required"`
+		AssociateId string `json:"associateId" validate:"required"`
 	}
 	return func(resp http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
 		//uid := ctx.Value("uid").(string)
-		body, err := ioutil.ReadAll(req.Body)
 		var request *createActiveTextSessionRequest
-		err = json.Unmarshal(body, &request)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		err := validation.Decode(resp, req, &request)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 
 		businessID := request.BusinessId
 		associateContactID := request.AssociateId
 
-		if len(businessID) == 0 || len(associateContactID) == 0 {
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: "bad request data",
-			})
-			return
-		}
-
-		usersRef := h.db.Collection("users")
-		snapshots, err := usersRef.Where("email", "==", "dummy.customer@getpigeon.com").Documents(ctx).GetAll()
-		if common.RespondWithError(err, resp, http.StatusPreconditionFailed) {
-			return
-		}
-		dummyCustomerSnapshot := snapshots[0]
-		customerID := dummyCustomerSnapshot.Ref.ID
-
-		businessRef := h.db.Collection("businesses").Doc(businessID)
-		businessCustomerRef := businessRef.Collection("businessCustomers").Doc(customerID)
-		directoryRef := businessRef.Collection("directory")
-
-		customerContactSnapshot, _ := businessCustomerRef.Get(ctx)
-		if customerContactSnapshot == nil || !customerContactSnapshot.Exists() {
-			customerContactSnapshot = dummyCustomerSnapshot
-		}
-		if customerContactSnapshot == nil || !customerContactSnapshot.Exists() {
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusAccepted),
-				Message: "Customer record not found",
-			})
+		dummyCustomer, err := h.customersRepository.FindByEmail(ctx, dummyCustomerEmail)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeFailedPrecondition) {
 			return
 		}
+		customerID := dummyCustomer.Id
 
-		var customerContact *model.Customer
-		err = customerContactSnapshot.DataTo(&customerContact)
-		if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
-			return
+		customerContact, err := h.customersRepository.FindBusinessCustomer(ctx, businessID, customerID)
+		if err != nil {
+			customerContact = dummyCustomer
 		}
-		customerContact.Id = customerContactSnapshot.Ref.ID
 		customerID = customerContact.Id // reassign customer ID from retrieved contact
 
 		//todo: extract use case (used in appointments)
-		associateContactSnapshot, err := directoryRef.Doc(associateContactID).Get(ctx)
-		if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
-			return
-		}
-
-		if associateContactSnapshot == nil || !associateContactSnapshot.Exists() {
-			resp.WriteHeader(http.StatusForbidden)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusForbidden),
-				Message: "Associate has no business contact assigned",
-			})
+		associateContact, err := h.findAssociateContact(ctx, businessID, associateContactID)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
-
-		var associateContact *model.Contact
-		err = associateContactSnapshot.DataTo(&associateContact)
-		if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
-			return
-		}
-		associateContact.Id = associateContactSnapshot.Ref.ID
 		associateContactID = associateContact.Id // reassign associate contact ID from retrieved contact
 
 		textSession, err := h.textSessionRepository.FindActiveTextSession(customerID, associateContactID)
-		if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 
@@ -157,7 +142,7 @@ func (h *handler) CreateTourActiveChat() func(resp http.ResponseWriter, req *htt
 
 		// create text session
 		textSession, err = h.textSessionRepository.CreateActiveTextSession(customerContact, associateContact, model.UserTypeCustomer)
-		if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 		now := time.Now()
@@ -174,8 +159,8 @@ func (h *handler) CreateTourActiveChat() func(resp http.ResponseWriter, req *htt
 			TextSessionId: textSession.Id,
 			MemberIDs:     textSession.MemberIDs,
 			CreatedDate:   &now,
-		})
-		if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
+		}, messagesPosted)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 		response := textSessionsResponse{
@@ -190,20 +175,19 @@ func (h *handler) CreateTourActiveChat() func(resp http.ResponseWriter, req *htt
 
 func (h *handler) CreateActive() func(resp http.ResponseWriter, req *http.Request) {
 	type createActiveTextSessionRequest struct {
-		BusinessId  string `json:"businessId"`
-		CustomerId  string `json:"customerId"`
-		AssociateId string `json:"associateId"`
-		Creator     int    `json:"creator"` //todo: possible unused
+		BusinessId  string `json:"businessId" validate:"required"`
+		CustomerId  string `json:"customerId" validate:"required"`
+		AssociateId string `json:"associateId" validate:"required"`
+		Creator     int    `json:"creator" validate:"required"` //todo: possible unused
 	}
 	return func(resp http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
 		uid := ctx.Value("uid").(string)
-		body, err := ioutil.ReadAll(req.Body)
 		var request *createActiveTextSessionRequest
-		err = json.Unmarshal(body, &request)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		err := validation.Decode(resp, req, &request)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 
@@ -212,73 +196,30 @@ func (h *handler) CreateActive() func(resp http.ResponseWriter, req *http.Reques
 		associateContactId := request.AssociateId
 		creator := request.Creator
 
-		if len(businessId) == 0 || len(customerId) == 0 || len(associateContactId) == 0 || creator == 0 {
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: "bad request data",
-			})
-			return
-		}
-
 		//todo: extract use case (used in appointments)
-		businessRef := h.db.Collection("businesses").Doc(businessId)
-		businessCustomerRef := businessRef.Collection("businessCustomers").Doc(customerId)
-		directoryRef := businessRef.Collection("directory")
-
-		customerContactSnapshot, _ := businessCustomerRef.Get(context.Background())
-		if customerContactSnapshot == nil || !customerContactSnapshot.Exists() {
-			customerContactSnapshot, err = h.db.Collection("users").Doc(customerId).Get(context.Background())
-			if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
-				return
-			}
+		customerContact, err := h.customersRepository.FindBusinessCustomer(ctx, businessId, customerId)
+		if apierrors.CodeOf(err) == apierrors.CodeNotFound {
+			customerContact, err = h.customersRepository.FindById(ctx, customerId)
 		}
-		if customerContactSnapshot == nil || !customerContactSnapshot.Exists() {
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusAccepted),
-				Message: "Customer record not found",
-			})
+		if apierrors.CodeOf(err) == apierrors.CodeNotFound {
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeNotFound, "Customer record not found"))
 			return
 		}
-
-		var customerContact *model.Customer
-		err = customerContactSnapshot.DataTo(&customerContact)
-		if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
-		customerContact.Id = customerContactSnapshot.Ref.ID
 		customerId = customerContact.Id // reassign customer ID from retrieved contact
 
 		if uid != customerId && !customerContact.Permissions.Contact {
-			resp.WriteHeader(http.StatusForbidden)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusForbidden),
-				Message: "Permission to contact is not granted",
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, "Permission to contact is not granted"))
 			return
 		}
 
 		//todo: extract use case (used in appointments)
-		associateContactSnapshot, err := directoryRef.Doc(associateContactId).Get(context.Background())
-		if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
-			return
-		}
-
-		if associateContactSnapshot == nil || !associateContactSnapshot.Exists() {
-			resp.WriteHeader(http.StatusForbidden)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusForbidden),
-				Message: "Associate has no business contact assigned",
-			})
+		associateContact, err := h.findAssociateContact(ctx, businessId, associateContactId)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
-
-		var associateContact *model.Contact
-		err = associateContactSnapshot.DataTo(&associateContact)
-		if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
-			return
-		}
-		associateContact.Id = associateContactSnapshot.Ref.ID
 		associateContactId = associateContact.Id // reassign associate contact ID from retrieved contact
 
 		// check contact is blocked
@@ -287,7 +228,7 @@ func (h *handler) CreateActive() func(resp http.ResponseWriter, req *http.Reques
 		}
 
 		textSession, err := h.textSessionRepository.FindActiveTextSession(customerId, associateContactId)
-		if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 
@@ -295,7 +236,7 @@ func (h *handler) CreateActive() func(resp http.ResponseWriter, req *http.Reques
 			if !common.StringArrayIncludes(textSession.MemberIDs, uid) {
 				textSession.MemberIDs = append(textSession.MemberIDs, uid)
 				err = h.textSessionRepository.Update(textSession)
-				if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
+				if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 					return
 				}
 			}
@@ -311,7 +252,7 @@ func (h *handler) CreateActive() func(resp http.ResponseWriter, req *http.Reques
 
 		// create text session
 		textSession, err = h.textSessionRepository.CreateActiveTextSession(customerContact, associateContact, creator)
-		if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 
@@ -328,52 +269,36 @@ func (h *handler) CreateActive() func(resp http.ResponseWriter, req *http.Reques
 func (h *handler) checkBlocking(ctx context.Context, resp http.ResponseWriter, uid string, associateContact *model.Contact, customerId string) bool {
 	if uid == customerId {
 		blocked, err := h.isCustomerBlocked(ctx, associateContact, customerId)
-		if errorCode := common.GRPCToHttpErrorCode(err); errorCode != http.StatusNotFound && common.RespondWithError(err, resp, errorCode) {
+		if apierrors.CodeOf(err) != apierrors.CodeNotFound && apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return true
 		}
 		if blocked {
-			resp.WriteHeader(http.StatusForbidden)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusForbidden),
-				Message: "Your contact is blocked",
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, "Your contact is blocked"))
 			return true
 		}
 		blocked, err = h.isAssociateBlocked(ctx, customerId, associateContact)
-		if errorCode := common.GRPCToHttpErrorCode(err); errorCode != http.StatusNotFound && common.RespondWithError(err, resp, errorCode) {
+		if apierrors.CodeOf(err) != apierrors.CodeNotFound && apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return true
 		}
 		if blocked {
-			resp.WriteHeader(http.StatusForbidden)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusForbidden),
-				Message: "You blocked this contact",
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, "You blocked this contact"))
 			return true
 		}
 	} else {
 		blocked, err := h.isAssociateBlocked(ctx, customerId, associateContact)
-		if errorCode := common.GRPCToHttpErrorCode(err); errorCode != http.StatusNotFound && common.RespondWithError(err, resp, errorCode) {
+		if apierrors.CodeOf(err) != apierrors.CodeNotFound && apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return true
 		}
 		if blocked {
-			resp.WriteHeader(http.StatusForbidden)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusForbidden),
-				Message: "Your contact is blocked",
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, "Your contact is blocked"))
 			return true
 		}
 		blocked, err = h.isCustomerBlocked(ctx, associateContact, customerId)
-		if errorCode := common.GRPCToHttpErrorCode(err); errorCode != http.StatusNotFound && common.RespondWithError(err, resp, errorCode) {
+		if apierrors.CodeOf(err) != apierrors.CodeNotFound && apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return true
 		}
 		if blocked {
-			resp.WriteHeader(http.StatusForbidden)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusForbidden),
-				Message: "You blocked this contact",
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, "You blocked this contact"))
 			return true
 		}
 	}
@@ -382,20 +307,19 @@ func (h *handler) checkBlocking(ctx context.Context, resp http.ResponseWriter, u
 
 func (h *handler) CreateInner() func(resp http.ResponseWriter, req *http.Request) {
 	type createInnerTextSessionRequest struct {
-		BusinessId string   `json:"businessId"`
-		FromId     string   `json:"fromId"`
-		ToId       string   `json:"toId,omitempty"`
+		BusinessId string   `json:"businessId" validate:"required"`
+		FromId     string   `json:"fromId" validate:"required"`
+		ToId       string   `json:"toId,omitempty" validate:"required_without=ToIds"`
 		ToIds      []string `json:"toIds,omitempty"`
-		Title      string   `json:"title,omitempty"`
+		Title      string   `json:"title,omitempty" validate:"max=100"`
 	}
 	return func(resp http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 		uid := ctx.Value("uid").(string)
-		body, err := ioutil.ReadAll(req.Body)
 		var request *createInnerTextSessionRequest
-		err = json.Unmarshal(body, &request)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		err := validation.Decode(resp, req, &request)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 
@@ -405,59 +329,27 @@ func (h *handler) CreateInner() func(resp http.ResponseWriter, req *http.Request
 		toContactIds := request.ToIds
 		chatTitle := request.Title
 
-		if len(businessId) == 0 || len(fromUserId) == 0 || (len(toContactId) == 0 && len(toContactIds) == 0) {
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: err.Error(),
-			})
-			return
-		}
-
 		createGroup := len(toContactIds) > 0
 
-		directoryRef := h.db.Collection("businesses").Doc(businessId).Collection("directory")
-
-		fromContactSnapshot := directoryRef.Where("associate.id", "==", fromUserId).Limit(1).Documents(ctx)
-		fromContacts, err := fromContactSnapshot.GetAll()
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		fromContact, err := h.directoryRepository.FindByAssociate(ctx, businessId, fromUserId)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
-		if len(fromContacts) == 0 {
-			resp.WriteHeader(http.StatusPreconditionFailed)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusPreconditionFailed),
-				Message: fmt.Sprintf("User must have business contact to be able to chat"),
-			})
+		if fromContact == nil {
+			apierrors.Respond(resp, apierrors.Newf(apierrors.CodeFailedPrecondition, "User must have business contact to be able to chat"))
 			return
 		}
-		fromContactRef := fromContacts[0]
-		var fromContact *model.Contact
-		err = fromContactRef.DataTo(&fromContact)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-			return
-		}
-		fromContact.Id = fromContactRef.Ref.ID
 		if !createGroup {
-			compoundId := model.CompoundID{fromContact.Id: toContactId, toContactId: fromContact.Id}
-			docIterator := h.db.Collection("textSessions").Where("compoundId", "==", compoundId).Limit(1).Documents(ctx)
-			snapshots, err := docIterator.GetAll()
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+			chatData, err := h.textSessionRepository.FindInnerTextSession(fromContact.Id, toContactId)
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 				return
 			}
 
-			if len(snapshots) == 1 && snapshots[0].Exists() {
-				snapshot := snapshots[0]
-				var chatData *model.TextSession
-				err := snapshot.DataTo(&chatData)
-				if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-					return
-				}
-				chatData.Id = snapshot.Ref.ID
+			if chatData != nil {
 				if !common.StringArrayIncludes(chatData.MemberIDs, uid) {
 					chatData.MemberIDs = append(chatData.MemberIDs, uid)
 					err = h.textSessionRepository.Update(chatData)
-					if common.RespondWithError(err, resp, common.GRPCToHttpErrorCode(err)) {
+					if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 						return
 					}
 				}
@@ -473,28 +365,11 @@ func (h *handler) CreateInner() func(resp http.ResponseWriter, req *http.Request
 				return
 			}
 			// crate new inner text session
-			fromContactSnapshot := directoryRef.Where("associate.id", "==", fromUserId).Limit(1).Documents(ctx)
-			fromContacts, err := fromContactSnapshot.GetAll()
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-				return
-			}
-			toContactRef, err := directoryRef.Doc(toContactId).Get(ctx)
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-				return
-			}
-			fromContactRef := fromContacts[0]
-			var fromContact *model.Contact
-			err = fromContactRef.DataTo(&fromContact)
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-				return
-			}
-			fromContact.Id = fromContactRef.Ref.ID
-			var toContact *model.Contact
-			err = toContactRef.DataTo(&toContact)
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+			compoundId := model.CompoundID{fromContact.Id: toContactId, toContactId: fromContact.Id}
+			toContact, err := h.directoryRepository.FindById(ctx, businessId, toContactId)
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 				return
 			}
-			toContact.Id = toContactRef.Ref.ID
 			toContactType := toContact.Type
 			isToPersonalContact := toContactType == model.ContactTypePersonal
 			fromAssociate := fromContact.Associate
@@ -544,22 +419,10 @@ func (h *handler) CreateInner() func(resp http.ResponseWriter, req *http.Request
 			}
 
 			sessionData.MemberIDs = sessionData.Members.UIDs()
-			textSessionRef := h.db.Collection("textSessions").NewDoc()
-			_, err = textSessionRef.Set(ctx, &sessionData)
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+			sessionData, err = h.textSessionRepository.Create(ctx, sessionData)
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 				return
 			}
-			snapshot, err := textSessionRef.Get(ctx)
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-				return
-			}
-			sessionData = &model.TextSession{}
-			err = snapshot.DataTo(sessionData)
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-				return
-			}
-			// @ts-ignore
-			sessionData.Id = textSessionRef.ID
 			response := textSessionsResponse{
 				BaseResponse: model.BaseResponse{
 					Status:  http.StatusText(http.StatusOK),
@@ -572,31 +435,15 @@ func (h *handler) CreateInner() func(resp http.ResponseWriter, req *http.Request
 			return
 		}
 		// group text session
-		var toContacts = make([]*model.Contact, 0)
-		var errs = make([]error, 0)
-
+		var toContacts = make([]*model.Contact, 0, len(toContactIds))
 		for _, contactId := range toContactIds {
-			toContactRef, err := directoryRef.Doc(contactId).Get(ctx)
-			if common.CheckError(err) {
-				errs = append(errs, err)
-				return
-			}
-			var toContact *model.Contact
-			err = toContactRef.DataTo(&toContact)
-			if common.CheckError(err) {
-				errs = append(errs, err)
+			toContact, err := h.directoryRepository.FindById(ctx, businessId, contactId)
+			if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 				return
 			}
-			toContact.Id = toContactRef.Ref.ID
 			toContacts = append(toContacts, toContact)
 		}
 
-		if len(errs) > 0 {
-			if common.RespondWithError(errs[0], resp, http.StatusInternalServerError) {
-				return
-			}
-		}
-
 		fromAssociate := fromContact.Associate
 		toContact := toContacts[0]
 		sessionData := &model.TextSession{
@@ -646,22 +493,10 @@ func (h *handler) CreateInner() func(resp http.ResponseWriter, req *http.Request
 		}
 
 		sessionData.MemberIDs = sessionData.Members.UIDs()
-		textSessionRef := h.db.Collection("textSessions").NewDoc()
-		_, err = textSessionRef.Set(ctx, &sessionData)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-			return
-		}
-		snapshot, err := textSessionRef.Get(ctx)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-			return
-		}
-		sessionData = &model.TextSession{}
-		err = snapshot.DataTo(sessionData)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		sessionData, err = h.textSessionRepository.Create(ctx, sessionData)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
-		// @ts-ignore
-		sessionData.Id = textSessionRef.ID
 		response := textSessionsResponse{
 			BaseResponse: model.BaseResponse{
 				Status:  http.StatusText(http.StatusOK),
@@ -674,35 +509,16 @@ func (h *handler) CreateInner() func(resp http.ResponseWriter, req *http.Request
 	}
 }
 
-// todo: extract business customers repository
-func (h *handler) isCustomerBlocked(context context.Context, associateContact *model.Contact, customerId string) (bool, error) {
-	snapshot, err := h.db.BusinessCustomer(associateContact.Business.Id, customerId).Get(context)
-	if snapshot == nil {
-		return false, nil
-	}
-	if err != nil {
-		return false, err
-	}
-	var customer *model.Customer
-	err = snapshot.DataTo(&customer)
+func (h *handler) isCustomerBlocked(ctx context.Context, associateContact *model.Contact, customerId string) (bool, error) {
+	customer, err := h.customersRepository.FindBusinessCustomer(ctx, associateContact.Business.Id, customerId)
 	if err != nil {
 		return false, err
 	}
-	blocked := common.ArraysInclude(customer.InBlocked, associateContact.AssociateIDs)
-	return blocked, nil
+	return common.ArraysInclude(customer.InBlocked, associateContact.AssociateIDs), nil
 }
 
-func (h *handler) isAssociateBlocked(context context.Context, customerID string, associateContact *model.Contact) (bool, error) {
-	for _, associateID := range associateContact.AssociateIDs {
-		snapshot, err := h.db.BlockedUser(customerID, associateID).Get(context)
-		if snapshot == nil && err != nil {
-			return false, err
-		}
-		if snapshot != nil && snapshot.Exists() {
-			return true, nil
-		}
-	}
-	return false, nil
+func (h *handler) isAssociateBlocked(ctx context.Context, customerID string, associateContact *model.Contact) (bool, error) {
+	return h.customersRepository.HasBlocked(ctx, customerID, associateContact.AssociateIDs)
 }
 
 func (h *handler) LeaveTextSession() func(resp http.ResponseWriter, req *http.Request) {
@@ -710,53 +526,33 @@ func (h *handler) LeaveTextSession() func(resp http.ResponseWriter, req *http.Re
 		uid := req.Context().Value("uid").(string)
 		textSessionId := mux.Vars(req)["text_session_id"]
 
-		documentRef := h.db.Collection("textSessions").Doc(textSessionId)
-		messagesRef := documentRef.Collection("messages")
-
-		snapshot, err := documentRef.Get(context.Background())
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		chat, err := h.textSessionRepository.Find(textSessionId)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
+			return
+		}
+		var leftMember *model.Member
+		if chat.Members != nil {
+			leftMember = chat.Members.ByID(uid)
+		}
+		if leftMember == nil {
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeFailedPrecondition, "Not a chat member"))
 			return
 		}
 
-		docData := snapshot.Data()
-		members := docData["members"].(map[string]interface{})
-		leftMember := members[uid].(map[string]interface{})
-		leftMemberName := leftMember["name"].(string)
-
-		batch := h.db.Batch()
-
-		batch.Update(documentRef, []firestore.Update{
-			{
-				FieldPath: []string{"members", uid, "leaved"},
-				Value:     true,
-			},
-			{
-				FieldPath: []string{"members", uid, "left"},
-				Value:     true,
-			},
-			{
-				Path:  "memberIDs",
-				Value: firestore.ArrayRemove(uid),
-			},
-		})
-
-		batch.Create(messagesRef.NewDoc(), &model.MessageLeaveChat{
-			Message: model.Message{
-				Sender: &model.MessageSender{
-					Uid:       uid,
-					ContactId: uid,
-					Name:      leftMemberName,
-					Type:      model.MessageSenderTypeSystem,
-				},
-				Type:          model.MessageTypeLeaveChat,
-				Text:          fmt.Sprintf("%s has left the chat", leftMemberName),
-				TextSessionId: textSessionId,
-				MemberIDs:     mapKeys(members),
+		leaveMessage := &model.Message{
+			Sender: &model.MessageSender{
+				Uid:       uid,
+				ContactId: uid,
+				Name:      leftMember.Name,
+				Type:      model.MessageSenderTypeSystem,
 			},
-		})
-
-		_, err = batch.Commit(context.Background())
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+			Type:          model.MessageTypeLeaveChat,
+			Text:          fmt.Sprintf("%s has left the chat", leftMember.Name),
+			TextSessionId: textSessionId,
+			MemberIDs:     chat.Members.UIDs(),
+		}
+		err = h.textSessionRepository.Leave(common.WithoutCancel(req.Context()), textSessionId, uid, leaveMessage, messagesPosted)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 
@@ -769,13 +565,13 @@ func (h *handler) LeaveTextSession() func(resp http.ResponseWriter, req *http.Re
 
 func (h *handler) addMembers() func(resp http.ResponseWriter, req *http.Request) {
 	type addMembersRequest struct {
-		Title  string `json:"title"`
+		Title  string `json:"title" validate:"max=100"`
 		Sender struct {
-			ContactId   string `json:"contactId"`
-			ContactName string `json:"contactName"`
+			ContactId   string `json:"contactId" validate:"required"`
+			ContactName string `json:"contactName" validate:"required"`
 		} `json:"sender"`
-		BusinessId string   `json:"businessId"`
-		ContactIDs []string `json:"contactIds"`
+		BusinessId string   `json:"businessId" validate:"required"`
+		ContactIDs []string `json:"contactIds" validate:"required,min=1"`
 	}
 
 	type addMembersResponse struct {
@@ -786,9 +582,8 @@ func (h *handler) addMembers() func(resp http.ResponseWriter, req *http.Request)
 		defer cancel()
 		uid := ctx.Value("uid").(string)
 		var request *addMembersRequest
-		body, err := ioutil.ReadAll(req.Body)
-		err = json.Unmarshal(body, &request)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
+		err := validation.Decode(resp, req, &request)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 
@@ -798,113 +593,30 @@ func (h *handler) addMembers() func(resp http.ResponseWriter, req *http.Request)
 		title := request.Title
 		sender := request.Sender
 
-		if len(businessId) == 0 || len(sender.ContactId) == 0 || len(sender.ContactName) == 0 || len(contactIDs) == 0 {
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: fmt.Sprintf("businessId:%s,sender:[%s,%s],contactIDs:%d", businessId, sender.ContactId, sender.ContactName, len(contactIDs)),
-			})
-			return
-		}
-
-		directoryRef := h.db.Collection("businesses").Doc(businessId).Collection("directory")
-		docRefs := make([]*firestore.DocumentRef, len(contactIDs), len(contactIDs))
-		for index, id := range contactIDs {
-			docRefs[index] = directoryRef.Doc(id)
-		}
-		snapshots, err := h.db.GetAll(ctx, docRefs)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		contacts, err := h.directoryRepository.FindByIds(ctx, businessId, contactIDs)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
-		chatMembers := make(map[string]interface{}, len(contactIDs))
-		var memberIds []interface{}
-		for _, snapshot := range snapshots {
-			var contact *model.DirectoryContact
-			err := snapshot.DataTo(&contact)
-			if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-				return
-			}
-			contact.Id = snapshot.Ref.ID
+		chatMembers := make(model.Members, len(contactIDs))
+		for _, contact := range contacts {
 			if contact.Type == model.ContactTypePersonal {
 				member := contact.ToChatMemberLegacy()
 				chatMembers[member.Uid] = member
-				memberIds = append(memberIds, member.Uid)
 				continue
 			}
 			if contact.Contacts != nil {
 				for _, contact := range excludeUid(contact.Contacts, uid) {
 					member := contact.ToChatMemberLegacy()
 					chatMembers[member.Uid] = member
-					memberIds = append(memberIds, member.Uid)
 				}
 			}
 		}
 
-		chatRef := h.db.Collection("textSessions").Doc(chatId)
-		chatUpdates := []firestore.Update{
-			{
-				Path:  "memberIDs",
-				Value: firestore.ArrayUnion(memberIds...),
-			},
-			{
-				Path:  "compoundId",
-				Value: firestore.Delete,
-			},
-			{
-				Path:  "subtype",
-				Value: model.SessionSubtypeGroupExtended,
-			},
-		}
-
-		if len(title) > 0 {
-			chatUpdates = append(chatUpdates, firestore.Update{
-				Path:  "title",
-				Value: title,
-			})
-		}
-		_, err = h.db.Batch().
-			Set(chatRef, map[string]interface{}{"members": chatMembers}, firestore.MergeAll).
-			Update(chatRef, chatUpdates).
-			Commit(ctx)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		chat, err := h.textSessionRepository.AddMembers(ctx, chatId, chatMembers, title)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 
-		snapshot, err := chatRef.Get(ctx)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-			return
-		}
-
-		var chat *model.TextSession
-		err = snapshot.DataTo(&chat)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
-			return
-		}
-
-		documentIterator := chatRef.Collection("messages").Select().Documents(ctx)
-		defer documentIterator.Stop()
-
-		updates := []firestore.Update{{Path: "memberIDs", Value: chat.MemberIDs}}
-		batch := h.db.Batch()
-		iter := 0
-		for {
-			doc, err := documentIterator.Next()
-			if err == iterator.Done {
-				break
-			}
-			if err != nil {
-				continue
-			}
-			batch.Update(doc.Ref, updates)
-			iter++
-			if iter == 499 {
-				_, _ = batch.Commit(ctx)
-				batch = h.db.Batch()
-				iter = 0
-			}
-		}
-		_, _ = batch.Commit(ctx)
-
 		var messages []*model.Message
 		messageSender := model.MessageSender{
 			Uid:       uid,
@@ -912,8 +624,7 @@ func (h *handler) addMembers() func(resp http.ResponseWriter, req *http.Request)
 			Name:      sender.ContactName,
 			Type:      model.MessageSenderTypeSystem,
 		}
-		for _, contact := range chatMembers {
-			member := contact.(*model.Member)
+		for _, member := range chatMembers {
 			message := &model.Message{
 				Recipient: &model.MessageRecipient{
 					Uid:       member.Uid,
@@ -929,8 +640,8 @@ func (h *handler) addMembers() func(resp http.ResponseWriter, req *http.Request)
 			messages = append(messages, message)
 		}
 
-		messages, err = h.messagesRepository.SaveAll(ctx, chatId, messages)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		_, err = h.messagesRepository.SaveAll(ctx, chatId, messages, messagesPosted)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 
@@ -950,16 +661,6 @@ func (h *handler) SetupRouts(router *mux.Router) {
 	router.HandleFunc(PathTextSessionMembers, h.addMembers()).Methods(http.MethodPost)
 }
 
-func mapKeys(srcMap map[string]interface{}) []string {
-	keys := make([]string, len(srcMap))
-	i := 0
-	for k := range srcMap {
-		keys[i] = k
-		i++
-	}
-	return keys
-}
-
 // deprecated
 func excludeUidLegacy(contacts []*model.Contact, uid string) (target []*model.Contact) {
 	for _, contact := range contacts {
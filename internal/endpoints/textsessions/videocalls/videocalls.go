@@ -2,11 +2,11 @@ package videocalls
 
 import (
 	"encoding/json"
-	"io/ioutil"
-	"log"
 	"net/http"
 
-	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain"
 	"github.com/gorilla/mux"
 )
@@ -38,7 +38,7 @@ func (h *handler) initVideoCall() http.HandlerFunc {
 		textSessionId := mux.Vars(req)["text_session_id"]
 
 		apiKey, sessionId, token, err := h.videoCallService.InitVideoCall(uid, textSessionId)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 		response := &initVideoCallResponse{
@@ -60,17 +60,13 @@ func (h *handler) joinVideoCall() http.HandlerFunc {
 		textSessionId := mux.Vars(req)["text_session_id"]
 		videoCallId := mux.Vars(req)["video_call_id"]
 		var joinRequest *joinVideoCallRequest
-		bytes, err := ioutil.ReadAll(req.Body)
-		if common.RespondWithError(err, resp, http.StatusBadRequest) {
-			return
-		}
-		err = json.Unmarshal(bytes, &joinRequest)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		err := validation.Decode(resp, req, &joinRequest)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 		sessionId := joinRequest.SessionID
 		apiKey, sessionId, token, err := h.videoCallService.JoinVideoCall(uid, textSessionId, videoCallId, sessionId)
-		if common.RespondWithError(err, resp, http.StatusInternalServerError) {
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 		response := &initVideoCallResponse{
@@ -85,13 +81,13 @@ func (h *handler) joinVideoCall() http.HandlerFunc {
 
 func (h *handler) endVideoCall() http.HandlerFunc {
 	return func(resp http.ResponseWriter, req *http.Request) {
-		uid := req.Context().Value("uid").(string)
+		ctx := req.Context()
 		chatId := mux.Vars(req)["text_session_id"]
 		videoCallId := mux.Vars(req)["video_call_id"]
 
-		log.Println("end video call:", uid, chatId, videoCallId)
+		logger.FromContext(ctx).Info(ctx, "ending the video call", "chatId", chatId, "videoCallId", videoCallId)
 
-		http.Error(resp, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)
+		apierrors.Respond(resp, apierrors.New(apierrors.CodeNotImplemented, http.StatusText(http.StatusNotImplemented)))
 	}
 }
 
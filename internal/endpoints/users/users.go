@@ -3,14 +3,14 @@ package users
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	"firebase.google.com/go/v4/auth"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/identity"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/gorilla/mux"
@@ -30,6 +30,7 @@ const (
 type handler struct {
 	authClient      *auth.Client
 	firestoreClient *firestore.Client
+	identity        *identity.Resolver
 }
 
 type blockUnblockRequest struct {
@@ -37,80 +38,61 @@ type blockUnblockRequest struct {
 	AssociateID string `json:"associateId"`
 }
 
-func NewHandler(authClient *auth.Client, firestoreClient *firestore.Client) *handler {
-	return &handler{authClient, firestoreClient}
+func NewHandler(authClient *auth.Client, firestoreClient *firestore.Client, identityResolver *identity.Resolver) *handler {
+	return &handler{authClient, firestoreClient, identityResolver}
 }
 
 func (h *handler) listAllUsers(resp http.ResponseWriter, req *http.Request) {
-	started := time.Now()
-	fmt.Printf("Processing %s - started at %v\n", req.RequestURI, started)
-	userIterator := h.authClient.Users(context.Background(), "")
+	userIterator := h.authClient.Users(req.Context(), "")
 	var users []*auth.ExportedUserRecord
 	for {
 		userRecord, err := userIterator.Next()
 		if err == iterator.Done {
 			break
 		}
-		if err != nil {
-			_ = fmt.Errorf("error listing users %v\n", err)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 			return
 		}
 		users = append(users, userRecord)
 	}
-
-	bytes, err := json.Marshal(&users)
-
-	if err != nil {
-		_ = json.NewEncoder(resp).Encode(err)
-		return
-	}
-
-	_, err = resp.Write(bytes)
-	if err != nil {
-		_ = fmt.Errorf("error writing response %v\n", err)
-	}
-	finished := time.Now()
-	fmt.Printf("Finished at %v (time: %v)\n", finished, finished.Sub(started))
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&users)
 }
 
 func (h *handler) user(resp http.ResponseWriter, req *http.Request) {
-	uid := mux.Vars(req)["uid"]
-	userRecord, err := h.authClient.GetUser(context.Background(), uid)
-
-	if err != nil {
-		fmt.Println(fmt.Errorf("error get user[%s]: %v\n", uid, err))
+	ctx := req.Context()
+	uid := mux.Vars(req)["user_id"]
+	userRecord, err := h.authClient.GetUser(ctx, uid)
+	if auth.IsUserNotFound(err) {
+		apierrors.Respond(resp, apierrors.New(apierrors.CodeNotFound, "User not found"))
 		return
 	}
-
-	bytes, err := json.Marshal(&userRecord)
-
-	if err != nil {
-		_ = json.NewEncoder(resp).Encode(err)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
-
-	_, err = resp.Write(bytes)
-	if err != nil {
-		_ = fmt.Errorf("error writing response %v\n", err)
-	}
+	resp.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(resp).Encode(&userRecord)
 }
 
 // Add validation for uid and business ID
 func (h *handler) userPermissions(resp http.ResponseWriter, req *http.Request) {
 	err := req.ParseForm()
-	if respondWithError(resp, http.StatusBadRequest, err) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 		return
 	}
 	vars := mux.Vars(req)
-	uid := vars["user_id"]
+	uid, err := h.identity.ActingSubject(req, vars["user_id"])
+	if apierrors.RespondWithError(err, resp, apierrors.CodePermissionDenied) {
+		return
+	}
 	businessId := vars["business_id"]
 	contactRegardingCase, err := strconv.ParseBool(req.PostForm.Get("contact"))
-	if respondWithError(resp, http.StatusInternalServerError, err) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
 	contactRegardingPromo, err := strconv.ParseBool(req.PostForm.Get("promote"))
-	if respondWithError(resp, http.StatusInternalServerError, err) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
@@ -123,19 +105,19 @@ func (h *handler) userPermissions(resp http.ResponseWriter, req *http.Request) {
 	data["permissions"] = permissions
 
 	snapshot, err := h.firestoreClient.Collection("users").Doc(uid).Get(context.Background())
-	if respondWithError(resp, http.StatusInternalServerError, err) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
 	var user *model.Customer
 	err = snapshot.DataTo(&user)
-	if respondWithError(resp, http.StatusInternalServerError, err) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
 	_, err = h.firestoreClient.Collection("users").Doc(uid).Collection("businesses").Doc(businessId).Set(context.Background(), data, firestore.MergeAll)
 
-	if respondWithError(resp, http.StatusInternalServerError, err) {
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInternal) {
 		return
 	}
 
@@ -155,12 +137,16 @@ func (h *handler) blockAssociateRest() http.HandlerFunc {
 		var r *blockAssociateRequest
 		err := json.NewDecoder(request.Body).Decode(&r)
 		if err != nil {
-			log.Println("decoding", err)
-			http.Error(response, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
+			return
+		}
+		customerID, err := h.identity.ActingSubject(request, vars["user_id"])
+		if err != nil {
+			apierrors.RespondWithError(err, response, apierrors.CodePermissionDenied)
 			return
 		}
 		blockRequest := blockUnblockRequest{
-			CustomerID:  vars["user_id"],
+			CustomerID:  customerID,
 			AssociateID: r.AssociateID,
 		}
 		h.blockAssociateInternal(ctx, blockRequest, response)
@@ -180,13 +166,17 @@ func (h *handler) blockAssociate() http.HandlerFunc {
 		err := json.NewDecoder(request.Body).Decode(&r)
 		if err != nil {
 			// error
-			log.Println("decoding", err)
-			http.Error(response, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 			return
 		}
 
+		customerID, err := h.identity.ActingSubject(request, r.CustomerID)
+		if err != nil {
+			apierrors.RespondWithError(err, response, apierrors.CodePermissionDenied)
+			return
+		}
 		blockRequest := blockUnblockRequest{
-			CustomerID:  r.CustomerID,
+			CustomerID:  customerID,
 			AssociateID: r.AssociateID,
 		}
 
@@ -197,13 +187,13 @@ func (h *handler) blockAssociate() http.HandlerFunc {
 func (h *handler) blockAssociateInternal(ctx context.Context, associateRequest blockUnblockRequest, response http.ResponseWriter) {
 	snapshot, err := h.firestoreClient.Collection(db.Users).Doc(associateRequest.AssociateID).Get(ctx)
 	if err != nil {
-		http.Error(response, err.Error(), http.StatusBadRequest)
+		apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 		return
 	}
 	var associate *model.Associate
 	err = snapshot.DataTo(&associate)
 	if err != nil {
-		http.Error(response, err.Error(), http.StatusBadRequest)
+		apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 		return
 	}
 	batch := h.firestoreClient.Batch()
@@ -252,8 +242,7 @@ func (h *handler) blockAssociateInternal(ctx context.Context, associateRequest b
 		}
 		if err != nil {
 			// error
-			log.Println("update contacts", err)
-			http.Error(response, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 			return
 		}
 		batch.Update(contact.Ref, []firestore.Update{{Path: "blocked", Value: true}})
@@ -273,8 +262,7 @@ func (h *handler) blockAssociateInternal(ctx context.Context, associateRequest b
 		}
 		if err != nil {
 			// error
-			log.Println("update chats", err)
-			http.Error(response, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 			return
 		}
 		// block customer in chats
@@ -284,8 +272,7 @@ func (h *handler) blockAssociateInternal(ctx context.Context, associateRequest b
 	_, err = batch.Commit(ctx)
 	if err != nil {
 		// error
-		log.Println("commit batch", err)
-		http.Error(response, err.Error(), http.StatusBadRequest)
+		apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 		return
 	}
 	response.WriteHeader(http.StatusOK)
@@ -296,8 +283,13 @@ func (h *handler) unblockAssociateRest() http.HandlerFunc {
 		ctx, cancel := context.WithCancel(request.Context())
 		defer cancel()
 		vars := mux.Vars(request)
+		customerID, err := h.identity.ActingSubject(request, vars["user_id"])
+		if err != nil {
+			apierrors.RespondWithError(err, response, apierrors.CodePermissionDenied)
+			return
+		}
 		unblockRequest := blockUnblockRequest{
-			CustomerID:  vars["user_id"],
+			CustomerID:  customerID,
 			AssociateID: vars["blocked_user_id"],
 		}
 		h.unblockAssociateInternal(ctx, unblockRequest, response)
@@ -317,12 +309,17 @@ func (h *handler) unblockAssociate() http.HandlerFunc {
 		err := json.NewDecoder(request.Body).Decode(&r)
 		if err != nil {
 			// error
-			http.Error(response, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 			return
 		}
 
+		customerID, err := h.identity.ActingSubject(request, r.CustomerID)
+		if err != nil {
+			apierrors.RespondWithError(err, response, apierrors.CodePermissionDenied)
+			return
+		}
 		unblockRequest := blockUnblockRequest{
-			CustomerID:  r.CustomerID,
+			CustomerID:  customerID,
 			AssociateID: r.AssociateID,
 		}
 
@@ -349,8 +346,7 @@ func (h *handler) unblockAssociateInternal(ctx context.Context, unblockRequest b
 		}
 		if err != nil {
 			// error
-			log.Println("update contacts", err)
-			http.Error(response, err.Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 			return
 		}
 		batch.Update(contact.Ref, []firestore.Update{{Path: "blocked", Value: firestore.Delete}})
@@ -377,8 +373,7 @@ func (h *handler) unblockAssociateInternal(ctx context.Context, unblockRequest b
 	_, err := batch.Commit(ctx)
 	if err != nil {
 		// error
-		log.Println("commit batch", err)
-		http.Error(response, err.Error(), http.StatusBadRequest)
+		apierrors.RespondWithError(err, response, apierrors.CodeInvalidArgument)
 		return
 	}
 	response.WriteHeader(http.StatusOK)
@@ -392,18 +387,3 @@ func (h *handler) SetupRouts(router *mux.Router) {
 	router.HandleFunc(PathBlockUser, h.blockAssociate()).Methods(http.MethodPost)
 	router.HandleFunc(PathUnblockUser, h.unblockAssociate()).Methods(http.MethodPost)
 }
-
-func respondWithError(resp http.ResponseWriter, code int, err error) bool {
-	if err != nil {
-		fmt.Println(fmt.Errorf("error: %v", err))
-		resp.WriteHeader(code)
-		response := model.BaseResponse{
-			Status:  http.StatusText(code),
-			Message: err.Error(),
-		}
-		_ = json.NewEncoder(resp).Encode(&response)
-		return true
-	} else {
-		return false
-	}
-}
@@ -7,16 +7,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"firebase.google.com/go/v4/auth"
 	"github.com/VinothKuppanna/pigeon-go/configs"
-	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
 	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 	"github.com/gorilla/mux"
@@ -30,18 +30,18 @@ const (
 )
 
 type handler struct {
-	config          *configs.Config
-	emailService    definition.EmailsService
-	authClient      *auth.Client
-	firestoreClient *firestore.Client
+	config                  *configs.Config
+	emailService            definition.EmailsService
+	authClient              *auth.Client
+	verificationsRepository definition.VerificationsRepository
 }
 
 func NewHandler(
 	config *configs.Config,
 	emailService definition.EmailsService,
 	authClient *auth.Client,
-	firestoreClient *firestore.Client) *handler {
-	return &handler{config, emailService, authClient, firestoreClient}
+	verificationsRepository definition.VerificationsRepository) *handler {
+	return &handler{config, emailService, authClient, verificationsRepository}
 }
 
 func (h *handler) verifyEmail() http.HandlerFunc {
@@ -54,85 +54,48 @@ func (h *handler) verifyEmail() http.HandlerFunc {
 		defer cancel()
 
 		request := struct {
-			Email string `json:"email"`
+			Email string `json:"email" validate:"required,email"`
 		}{}
 
-		body, err := ioutil.ReadAll(req.Body)
-		err = json.Unmarshal(body, &request)
-		if common.CheckError(err) {
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: err.Error(),
-			})
+		err := validation.Decode(resp, req, &request)
+		if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 			return
 		}
 
 		email := request.Email
 
-		if len(email) == 0 {
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: "empty parameter: email",
-			})
-			return
-		}
-
 		userRecord, _ := h.authClient.GetUserByEmail(ctx, email)
 
 		if userRecord != nil && len(userRecord.UID) > 0 {
-			resp.WriteHeader(http.StatusPreconditionFailed)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusPreconditionFailed),
-				Message: "user already exists",
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeFailedPrecondition, "user already exists"))
 			return
 		}
 
-		documentIterator := h.firestoreClient.Collection("verifications").Where("email", "==", email).Limit(1).Documents(ctx)
-		snapshots, _ := documentIterator.GetAll()
-
 		var skey string
 		var docId string
 
-		if len(snapshots) == 1 {
-			snapshot := snapshots[0]
-			data := snapshot.Data()
-			verified := data["verified"]
-			if verified != nil && verified.(bool) {
-				resp.WriteHeader(http.StatusPreconditionFailed)
-				_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-					Status:  http.StatusText(http.StatusPreconditionFailed),
-					Message: "Email is already verified",
-				})
+		if verification, err := h.verificationsRepository.FindByEmail(ctx, email); err == nil {
+			if verification.Verified {
+				apierrors.Respond(resp, apierrors.New(apierrors.CodeFailedPrecondition, "Email is already verified"))
 				return
 			}
-			docId = snapshot.Ref.ID
-			skey = data["skey"].(string)
+			docId = verification.Id
+			skey = verification.Skey
 		} else {
-			created := time.Now()
-
 			skey = generateKey(16)
 
-			ref, _, err := h.firestoreClient.Collection("verifications").Add(ctx, map[string]interface{}{
-				"email":    email,
-				"verified": false,
-				"created":  created,
-				"skey":     skey,
+			verification, err = h.verificationsRepository.Create(ctx, &model.Verification{
+				Email:   email,
+				Created: time.Now(),
+				Skey:    skey,
 			})
 
 			if err != nil {
-				log.Printf("error VerifyEmail: %v", err)
-				resp.WriteHeader(http.StatusBadRequest)
-				_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-					Status:  http.StatusText(http.StatusBadRequest),
-					Message: err.Error(),
-				})
+				apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument)
 				return
 			}
 
-			docId = ref.ID
+			docId = verification.Id
 		}
 
 		response := h.emailService.SendBusinessEmailVerification(ctx, definition.SendBusinessEmailVerificationRequest{
@@ -141,12 +104,7 @@ func (h *handler) verifyEmail() http.HandlerFunc {
 		})
 
 		if !response.OK() {
-			log.Println(response.Error)
-			resp.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusBadRequest),
-				Message: response.Error.Error(),
-			})
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeInvalidArgument, response.Error.Error()))
 			return
 		}
 
@@ -163,20 +121,15 @@ func (h *handler) verifyEmail() http.HandlerFunc {
 
 // VerifyCompany deprecated
 func (h *handler) VerifyCompany(resp http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
 	request := struct {
-		CompanyId    string                 `json:"companyId"`
-		CompanyEmail string                 `json:"companyEmail"`
+		CompanyId    string                 `json:"companyId" validate:"required"`
+		CompanyEmail string                 `json:"companyEmail" validate:"required,email"`
 		Company      map[string]interface{} `json:"company"`
 	}{}
 
-	body, err := ioutil.ReadAll(req.Body)
-	err = json.Unmarshal(body, &request)
-	if common.CheckError(err) {
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-		})
+	err := validation.Decode(resp, req, &request)
+	if apierrors.RespondWithError(err, resp, apierrors.CodeInvalidArgument) {
 		return
 	}
 
@@ -184,25 +137,16 @@ func (h *handler) VerifyCompany(resp http.ResponseWriter, req *http.Request) {
 	companyEmail := request.CompanyEmail
 	company := request.Company
 
-	documentIterator := h.firestoreClient.Collection("verifications").Where("companyId", "==", companyId).Limit(1).Documents(context.Background())
-	snapshots, _ := documentIterator.GetAll()
-
 	var skey string
 	var docId string
 
-	if len(snapshots) == 1 {
-		snapshot := snapshots[0]
-		data := snapshot.Data()
-		if verified, ok := data["verified"].(bool); ok && verified {
-			resp.WriteHeader(http.StatusPreconditionFailed)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusPreconditionFailed),
-				Message: "Business is already verified",
-			})
+	if verification, err := h.verificationsRepository.FindByCompanyId(context.Background(), companyId); err == nil {
+		if verification.Verified {
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeFailedPrecondition, "Business is already verified"))
 			return
 		}
-		docId = snapshot.Ref.ID
-		skey = data["skey"].(string)
+		docId = verification.Id
+		skey = verification.Skey
 	} else {
 		created := time.Now()
 
@@ -236,37 +180,27 @@ func (h *handler) VerifyCompany(resp http.ResponseWriter, req *http.Request) {
 			}
 		}
 
-		ref, _, err := h.firestoreClient.Collection("verifications").Add(context.Background(), map[string]interface{}{
-			"companyId":    companyId,
-			"companyEmail": companyEmail,
-			"company":      business,
-			"created":      created,
-			"skey":         skey,
+		verification, err := h.verificationsRepository.Create(context.Background(), &model.Verification{
+			CompanyId:    companyId,
+			CompanyEmail: companyEmail,
+			Company:      business,
+			Created:      created,
+			Skey:         skey,
 		})
 
 		if err != nil {
-			log.Printf("error VerifyCompany: %v", err)
-			resp.WriteHeader(http.StatusInternalServerError)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusInternalServerError),
-				Message: err.Error(),
-			})
+			apierrors.RespondWithError(err, resp, apierrors.CodeInternal)
 			return
 		}
 
-		docId = ref.ID
+		docId = verification.Id
 	}
 
 	link := fmt.Sprintf("%s/auth/action?mode=verifyCompany&docId=%s&skey=%s", h.config.Smtp.Host, docId, skey)
-	log.Printf("link generated: %s", link)
+	logger.FromContext(ctx).Debug(ctx, "verification link generated", "link", link)
 	htmlTemplate, err := parseTemplate("./templates/business_verify.html", map[string]string{"Link": link})
 	if err != nil {
-		log.Println(err)
-		resp.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(http.StatusInternalServerError),
-			Message: err.Error(),
-		})
+		apierrors.RespondWithError(err, resp, apierrors.CodeInternal)
 		return
 	}
 
@@ -280,10 +214,10 @@ func (h *handler) VerifyCompany(resp http.ResponseWriter, req *http.Request) {
 	diler := gomail.NewDialer(h.config.Smtp.Server, h.config.Smtp.Port, h.config.Smtp.Email, h.config.Smtp.Password)
 
 	if err := diler.DialAndSend(&message); err != nil {
-		log.Println(err)
+		logger.FromContext(ctx).Error(ctx, "failed to send the business verification email", "error", err)
 		return
 	}
-	log.Println("Email has been sent to verify business")
+	logger.FromContext(ctx).Info(ctx, "business verification email sent")
 	resp.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
 		Status: http.StatusText(http.StatusOK),
@@ -295,66 +229,32 @@ func (h *handler) CompleteCompanyVerification(resp http.ResponseWriter, req *htt
 	verificationId := mux.Vars(req)["id"]
 	skey := req.URL.Query().Get("skey")
 
-	snapshot, err := h.firestoreClient.Collection("verifications").Doc(verificationId).Get(context.Background())
+	verification, err := h.verificationsRepository.FindById(context.Background(), verificationId)
 
-	if err != nil {
-		log.Printf("error CompleteCompanyVerification: %v", err)
-		resp.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(http.StatusInternalServerError),
-			Message: err.Error(),
-		})
+	if apierrors.CodeOf(err) == apierrors.CodeNotFound {
+		apierrors.Respond(resp, apierrors.New(apierrors.CodeNotFound, "Verification link is not valid"))
 		return
 	}
 
-	if !snapshot.Exists() {
-		resp.WriteHeader(http.StatusNotFound)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(http.StatusNotFound),
-			Message: "Verification link is not valid",
-		})
+	if err != nil {
+		apierrors.RespondWithError(err, resp, apierrors.CodeInternal)
 		return
 	}
 
-	data := snapshot.Data()
-
-	verified := data["verified"]
-	if verified != nil && verified.(bool) {
-		resp.WriteHeader(http.StatusPreconditionFailed)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(http.StatusPreconditionFailed),
-			Message: "Already verified",
-		})
+	if verification.Verified {
+		apierrors.Respond(resp, apierrors.New(apierrors.CodeFailedPrecondition, "Already verified"))
 		return
 	}
 
-	srcSkey := data["skey"]
-	if srcSkey != nil && srcSkey != skey {
-		resp.WriteHeader(http.StatusPreconditionFailed)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(http.StatusPreconditionFailed),
-			Message: "Invalid verification token",
-		})
+	if len(verification.Skey) > 0 && verification.Skey != skey {
+		apierrors.Respond(resp, apierrors.New(apierrors.CodeFailedPrecondition, "Invalid verification token"))
 		return
 	}
 
-	update := []firestore.Update{{
-		Path:  "verified",
-		Value: true,
-	}, {
-		Path:  "skey",
-		Value: firestore.Delete,
-	}}
-
-	_, err = h.firestoreClient.Collection("verifications").Doc(verificationId).Update(context.Background(), update)
+	err = h.verificationsRepository.Verify(context.Background(), verificationId, true)
 
 	if err != nil {
-		log.Printf("error CompleteCompanyVerification: %v", err)
-		resp.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(http.StatusInternalServerError),
-			Message: err.Error(),
-		})
+		apierrors.RespondWithError(err, resp, apierrors.CodeInternal)
 		return
 	}
 
@@ -381,56 +281,26 @@ func (h *handler) checkEmailInternal(ctx context.Context, resp http.ResponseWrit
 	verificationId := mux.Vars(req)["id"]
 	skey := req.URL.Query().Get("skey")
 
-	verificationRef := h.firestoreClient.Collection("verifications").Doc(verificationId)
-	snapshot, err := verificationRef.Get(ctx)
+	verification, err := h.verificationsRepository.FindById(ctx, verificationId)
 
 	if err != nil {
-		log.Printf("error CompleteEmailVerification: %v", err)
-		resp.WriteHeader(http.StatusNotFound)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(http.StatusNotFound),
-			Message: err.Error(),
-		})
-		return "", true
-	}
-
-	if !snapshot.Exists() {
-		resp.WriteHeader(http.StatusNotFound)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(http.StatusNotFound),
-			Message: "Verification link is not valid",
-		})
+		apierrors.RespondWithError(err, resp, apierrors.CodeNotFound)
 		return "", true
 	}
 
-	data := snapshot.Data()
-
-	if srcSkey, ok := data["skey"]; ok && srcSkey != skey {
-		resp.WriteHeader(http.StatusPreconditionFailed)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(http.StatusPreconditionFailed),
-			Message: "Invalid verification token",
-		})
+	if len(verification.Skey) > 0 && verification.Skey != skey {
+		apierrors.Respond(resp, apierrors.New(apierrors.CodeFailedPrecondition, "Invalid verification token"))
 		return "", true
 	}
 
-	update := []firestore.Update{{
-		Path:  "verified",
-		Value: true,
-	}}
-
-	_, err = verificationRef.Update(ctx, update)
+	err = h.verificationsRepository.Verify(ctx, verificationId, false)
 
 	if err != nil {
-		resp.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-			Status:  http.StatusText(http.StatusBadRequest),
-			Message: "Failed to verify email",
-		})
+		apierrors.Respond(resp, apierrors.New(apierrors.CodeInvalidArgument, "Failed to verify email"))
 		return "", true
 	}
 
-	return data["email"].(string), false
+	return verification.Email, false
 }
 
 func (h *handler) completeEmailVerification(resp http.ResponseWriter, req *http.Request) {
@@ -439,15 +309,10 @@ func (h *handler) completeEmailVerification(resp http.ResponseWriter, req *http.
 	emil, hasError := h.checkEmailInternal(ctx, resp, req)
 	if !hasError {
 		verificationId := mux.Vars(req)["id"]
-		_, err := h.firestoreClient.Collection("verifications").Doc(verificationId).Delete(ctx)
+		err := h.verificationsRepository.Delete(ctx, verificationId)
 
 		if err != nil {
-			log.Printf("error CompleteEmailVerification: %v", err)
-			resp.WriteHeader(http.StatusNotFound)
-			_ = json.NewEncoder(resp).Encode(&model.BaseResponse{
-				Status:  http.StatusText(http.StatusNotFound),
-				Message: err.Error(),
-			})
+			apierrors.RespondWithError(err, resp, apierrors.CodeNotFound)
 			return
 		}
 
@@ -460,9 +325,9 @@ func (h *handler) completeEmailVerification(resp http.ResponseWriter, req *http.
 }
 
 func (h *handler) SetupRouts(router *mux.Router) {
-	router.HandleFunc(PathVerifications, h.verifyEmail()).Methods(http.MethodPost)
-	router.HandleFunc(PathVerification, h.checkEmailVerification).Methods(http.MethodGet)
-	router.HandleFunc(PathVerification, h.completeEmailVerification).Methods(http.MethodDelete)
+	routes.HandleFunc(router, PathVerifications, h.verifyEmail()).Methods(http.MethodPost).Public()
+	routes.HandleFunc(router, PathVerification, h.checkEmailVerification).Methods(http.MethodGet).Public()
+	routes.HandleFunc(router, PathVerification, h.completeEmailVerification).Methods(http.MethodDelete).Public()
 }
 
 func parseTemplate(fileName string, data interface{}) (string, error) {
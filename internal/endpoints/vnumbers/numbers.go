@@ -3,11 +3,13 @@ package vnumbers
 import (
 	"cloud.google.com/go/firestore"
 	"encoding/json"
-	"fmt"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/metrics"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"github.com/vonage/vonage-go-sdk"
 	"net/http"
+	"time"
 )
 
 const (
@@ -31,13 +33,15 @@ func NewHandler(appID string, host string,
 
 func (h *handler) listOwnNumbers() http.HandlerFunc {
 	return func(resp http.ResponseWriter, req *http.Request) {
+		start := time.Now()
 		numbers, response, err := h.numbersClient.List(vonage.NumbersOpts{ApplicationID: h.appID})
+		metrics.ObserveProviderResult(metrics.ProviderVonage, "ListNumbers", start, err == nil && response.ErrorCode == "")
 		if err != nil {
-			http.Error(resp, errors.Wrap(err, "failed to get list of numbers").Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(errors.Wrap(err, "failed to get list of numbers"), resp, apierrors.CodeUpstreamFailed)
 			return
 		}
 		if response.ErrorCode != "" {
-			http.Error(resp, fmt.Sprintf("error %s %s", response.ErrorCode, response.ErrorCodeLabel), http.StatusBadRequest)
+			apierrors.Respond(resp, apierrors.Newf(apierrors.CodeUpstreamFailed, "error %s %s", response.ErrorCode, response.ErrorCodeLabel))
 			return
 		}
 		resp.WriteHeader(http.StatusOK)
@@ -47,13 +51,15 @@ func (h *handler) listOwnNumbers() http.HandlerFunc {
 
 func (h *handler) searchNumbersToBuy() http.HandlerFunc {
 	return func(resp http.ResponseWriter, req *http.Request) {
+		start := time.Now()
 		numbers, response, err := h.numbersClient.Search("US", vonage.NumberSearchOpts{Size: 15})
+		metrics.ObserveProviderResult(metrics.ProviderVonage, "SearchNumbers", start, err == nil && response.ErrorCode == "")
 		if err != nil {
-			http.Error(resp, errors.Wrap(err, "failed to search for numbers").Error(), http.StatusBadRequest)
+			apierrors.RespondWithError(errors.Wrap(err, "failed to search for numbers"), resp, apierrors.CodeUpstreamFailed)
 			return
 		}
 		if response.ErrorCode != "" {
-			http.Error(resp, fmt.Sprintf("error %s %s", response.ErrorCode, response.ErrorCodeLabel), http.StatusBadRequest)
+			apierrors.Respond(resp, apierrors.Newf(apierrors.CodeUpstreamFailed, "error %s %s", response.ErrorCode, response.ErrorCodeLabel))
 			return
 		}
 		resp.WriteHeader(http.StatusOK)
@@ -74,7 +80,7 @@ func (h *handler) buyNumber() http.HandlerFunc {
 		//	AppID:     h.appID,
 		//	MoHTTPURL: fmt.Sprintf("%s/callbacks/sms/%s", h.host, businessID),
 		//})
-		http.Error(resp, "not implemented", http.StatusNotImplemented)
+		apierrors.Respond(resp, apierrors.New(apierrors.CodeNotImplemented, "not implemented"))
 	}
 }
 
@@ -90,7 +96,7 @@ func (h *handler) releaseNumber() http.HandlerFunc {
 		//response, errorResponse, err := h.numbersClient.Update("US", "", vonage.NumberUpdateOpts{
 		//	MoHTTPURL: "",
 		//})
-		http.Error(resp, "not implemented", http.StatusNotImplemented)
+		apierrors.Respond(resp, apierrors.New(apierrors.CodeNotImplemented, "not implemented"))
 	}
 }
 
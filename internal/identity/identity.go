@@ -0,0 +1,86 @@
+// Package identity resolves the subject a caller acts as.
+package identity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrImpersonation = errors.New("not allowed to act on behalf of another user")
+
+// An Auditor records the rejected impersonation attempts.
+type Auditor interface {
+	Audit(ctx context.Context, entry *model.AuditEntry)
+}
+
+type Resolver struct {
+	assistants data.AssociateAssistantRepository
+	auditor    Auditor
+}
+
+func NewResolver(assistants data.AssociateAssistantRepository, auditor Auditor) *Resolver {
+	return &Resolver{assistants, auditor}
+}
+
+// ActingSubject returns the uid the caller acts as. A caller may act as themselves,
+// or as an associate they are an assistant of. An empty principal means the caller.
+func (r *Resolver) ActingSubject(req *http.Request, principalUID string) (string, error) {
+	ctx := req.Context()
+	uid, _ := ctx.Value("uid").(string)
+	if len(uid) == 0 {
+		return "", ErrImpersonation
+	}
+	if len(principalUID) == 0 || principalUID == uid {
+		return uid, nil
+	}
+	if r.isDelegated(ctx, principalUID, uid) {
+		return principalUID, nil
+	}
+	r.auditor.Audit(ctx, &model.AuditEntry{
+		Type:         model.AuditImpersonationAttempt,
+		UID:          uid,
+		PrincipalUID: principalUID,
+		Method:       req.Method,
+		Path:         req.URL.Path,
+		RemoteAddr:   req.RemoteAddr,
+		CreatedDate:  time.Now(),
+	})
+	return "", ErrImpersonation
+}
+
+func (r *Resolver) isDelegated(ctx context.Context, principalUID string, uid string) bool {
+	assistant, err := r.assistants.Find(principalUID, uid)
+	if err != nil {
+		if status.Code(err) != codes.NotFound {
+			logger.FromContext(ctx).Error(ctx, "failed to check the assistant", "assistantUid", uid, "associateUid", principalUID, "error", err)
+		}
+		return false
+	}
+	return assistant != nil
+}
+
+type firestoreAuditor struct {
+	db *db.Firestore
+}
+
+func NewFirestoreAuditor(db *db.Firestore) Auditor {
+	return &firestoreAuditor{db}
+}
+
+func (a *firestoreAuditor) Audit(ctx context.Context, entry *model.AuditEntry) {
+	logger.FromContext(ctx).Info(ctx, "audit", "type", entry.Type, "auditUid", entry.UID, "principalUid", entry.PrincipalUID, "method", entry.Method, "path", entry.Path)
+	// the request context may be canceled by the time the entry is written
+	_, _, err := a.db.Collection(db.AuditLog).Add(context.Background(), entry)
+	if err != nil {
+		logger.FromContext(ctx).Error(ctx, "failed to write the audit entry", "error", err)
+	}
+}
@@ -0,0 +1,85 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type assistants map[string]bool // associateId/assistantId
+
+func (a assistants) Find(associateId string, assistantId string) (*model.Assistant, error) {
+	if associateId == "broken" {
+		return nil, errors.New("lookup failed")
+	}
+	if a[associateId+"/"+assistantId] {
+		return &model.Assistant{}, nil
+	}
+	return nil, status.Error(codes.NotFound, "not found")
+}
+
+func (a assistants) FindAll(string) ([]*model.Assistant, error) { return nil, nil }
+func (a assistants) FindAllIDs(string) ([]string, error)        { return nil, nil }
+
+type auditLog []*model.AuditEntry
+
+func (l *auditLog) Audit(_ context.Context, entry *model.AuditEntry) {
+	*l = append(*l, entry)
+}
+
+func TestActingSubject(t *testing.T) {
+	tests := []struct {
+		name      string
+		uid       string
+		principal string
+		want      string
+		wantErr   bool
+		audited   bool
+	}{
+		{"self", "a1", "a1", "a1", false, false},
+		{"no principal", "a1", "", "a1", false, false},
+		{"assistant of the principal", "helper", "a1", "a1", false, false},
+		{"not an assistant", "a2", "a1", "", true, true},
+		{"assistant of another associate", "helper", "a2", "", true, true},
+		{"lookup failure", "helper", "broken", "", true, true},
+		{"unauthenticated", "", "a1", "", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var audit auditLog
+			resolver := NewResolver(assistants{"a1/helper": true}, &audit)
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/"+tt.principal, nil)
+			if len(tt.uid) > 0 {
+				req = req.WithContext(context.WithValue(req.Context(), "uid", tt.uid))
+			}
+
+			got, err := resolver.ActingSubject(req, tt.principal)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Fatalf("ActingSubject = %q, %v, want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrImpersonation) {
+				t.Errorf("error = %v, want ErrImpersonation", err)
+			}
+			if !tt.audited {
+				if len(audit) > 0 {
+					t.Errorf("audited %+v, want no entry", audit[0])
+				}
+				return
+			}
+			if len(audit) != 1 {
+				t.Fatalf("audited %d entries, want 1", len(audit))
+			}
+			entry := audit[0]
+			if entry.Type != model.AuditImpersonationAttempt || entry.UID != tt.uid || entry.PrincipalUID != tt.principal ||
+				entry.Method != http.MethodPatch || entry.Path != req.URL.Path {
+				t.Errorf("audit entry = %+v", entry)
+			}
+		})
+	}
+}
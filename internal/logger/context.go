@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+)
+
+// A Request correlates the log lines of a request. The request-ID middleware sets it up, and the
+// authenticator adds the user once it knows it.
+type Request struct {
+	ID    string
+	Route string // template
+
+	mu         sync.Mutex
+	uid        string
+	businessID string
+}
+
+// SetUser sets the uid, and the business ID unless the route named one.
+func (r *Request) SetUser(uid string, businessID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.uid = uid
+	if len(r.businessID) == 0 {
+		r.businessID = businessID
+	}
+}
+
+// SetBusinessID sets the business ID of the route.
+func (r *Request) SetBusinessID(businessID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.businessID = businessID
+}
+
+func (r *Request) User() (uid string, businessID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.uid, r.businessID
+}
+
+type contextKey int
+
+const (
+	requestKey contextKey = iota
+	loggerKey
+)
+
+func WithRequest(ctx context.Context, request *Request) context.Context {
+	return context.WithValue(ctx, requestKey, request)
+}
+
+// RequestFromContext returns the request of ctx, or nil outside of a request.
+func RequestFromContext(ctx context.Context) *Request {
+	request, _ := ctx.Value(requestKey).(*Request)
+	return request
+}
+
+// NewContext returns ctx with the logger, for the code without one of its own.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the logger of ctx, or the default one.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(loggerKey).(*Logger); ok {
+		return l
+	}
+	return Default()
+}
+
+// StampLogEntry sets the request of ctx on the entry, so the entries published to NSQ are
+// correlated with the log lines of the request.
+func StampLogEntry(ctx context.Context, entry *model.LogEntry) {
+	request := RequestFromContext(ctx)
+	if request == nil {
+		return
+	}
+	entry.RequestID, entry.Route = request.ID, request.Route
+	entry.UID, entry.BusinessID = request.User()
+}
@@ -0,0 +1,204 @@
+// Package logger writes the logs as JSON lines, in the structured format Cloud Logging reads from
+// the output of a container. Each line has a severity, and the lines logged within a request
+// carry its ID, route template, uid and business ID, and the trace.
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// the severities of Cloud Logging
+var severities = [...]string{"DEBUG", "INFO", "WARNING", "ERROR"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+	return severities[l]
+}
+
+// ParseLevel parses debug, info, warn or error.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// output is shared by a logger and those returned by its With.
+type output struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+// A Logger writes the lines of its level and above. The key-value pairs of a line follow the
+// fields of the logger; the values are written as JSON, and the errors as their message.
+type Logger struct {
+	out    *output
+	level  Level
+	fields []interface{}
+}
+
+func New(w io.Writer, level Level) *Logger {
+	return &Logger{out: &output{w: w, now: time.Now}, level: level}
+}
+
+// With returns a logger adding the key-value pairs to each line.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := append(append([]interface{}(nil), l.fields...), keyvals...)
+	return &Logger{out: l.out, level: l.level, fields: fields}
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+func (l *Logger) Debug(ctx context.Context, message string, keyvals ...interface{}) {
+	l.Log(ctx, LevelDebug, message, keyvals...)
+}
+
+func (l *Logger) Info(ctx context.Context, message string, keyvals ...interface{}) {
+	l.Log(ctx, LevelInfo, message, keyvals...)
+}
+
+func (l *Logger) Warn(ctx context.Context, message string, keyvals ...interface{}) {
+	l.Log(ctx, LevelWarn, message, keyvals...)
+}
+
+func (l *Logger) Error(ctx context.Context, message string, keyvals ...interface{}) {
+	l.Log(ctx, LevelError, message, keyvals...)
+}
+
+// Log writes a line of the level, with the request and the trace of ctx.
+func (l *Logger) Log(ctx context.Context, level Level, message string, keyvals ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	writeField(&buf, "time", l.out.now().UTC().Format(time.RFC3339Nano))
+	writeField(&buf, "severity", level.String())
+	writeField(&buf, "message", message)
+	if request := RequestFromContext(ctx); request != nil {
+		uid, businessID := request.User()
+		for _, field := range [...][2]string{
+			{"requestId", request.ID}, {"route", request.Route}, {"uid", uid}, {"businessId", businessID},
+		} {
+			if len(field[1]) > 0 {
+				writeField(&buf, field[0], field[1])
+			}
+		}
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		writeField(&buf, "traceId", spanContext.TraceID().String())
+		writeField(&buf, "spanId", spanContext.SpanID().String())
+	}
+	writeKeyvals(&buf, l.fields)
+	writeKeyvals(&buf, keyvals)
+	buf.WriteString("}\n")
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	_, _ = l.out.w.Write(buf.Bytes())
+}
+
+func writeKeyvals(buf *bytes.Buffer, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		var value interface{} = "(missing)"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		writeField(buf, key, value)
+	}
+}
+
+func writeField(buf *bytes.Buffer, key string, value interface{}) {
+	if buf.Len() > 1 {
+		buf.WriteByte(',')
+	}
+	encoded, _ := json.Marshal(key)
+	buf.Write(encoded)
+	buf.WriteByte(':')
+	switch v := value.(type) {
+	case time.Time:
+		// RFC 3339
+	case error:
+		value = v.Error()
+	case fmt.Stringer:
+		value = v.String()
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		encoded, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(encoded)
+}
+
+// Std returns a *log.Logger writing each of its lines as a message of the level, for the code
+// logging with the log package. Its lines carry no request.
+func (l *Logger) Std(level Level) *log.Logger {
+	return log.New(&stdWriter{l, level}, "", 0)
+}
+
+type stdWriter struct {
+	logger *Logger
+	level  Level
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	w.logger.Log(context.Background(), w.level, strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger = New(os.Stdout, LevelInfo)
+)
+
+// Default returns the logger of the process, which writes to stdout at the info level until
+// SetDefault replaces it.
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault sets the logger of the process, and writes the lines of the log package with it,
+// at the info level.
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defaultLogger = l
+	defaultMu.Unlock()
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(&stdWriter{l, LevelInfo})
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLogger(level Level) (*Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	l := New(&buf, level)
+	l.out.now = func() time.Time { return time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return l, &buf
+}
+
+func TestLogWritesJSONLines(t *testing.T) {
+	l, buf := newTestLogger(LevelInfo)
+	request := &Request{ID: "r1", Route: "/api/v1/businesses/{business_id}/cases"}
+	request.SetBusinessID("b1")
+	request.SetUser("u1", "b2")
+	ctx := WithRequest(context.Background(), request)
+
+	l.With("component", "api").Error(ctx, "failed", "status", 500, "error", errors.New("boom"))
+
+	want := `{"time":"2023-01-02T03:04:05Z","severity":"ERROR","message":"failed",` +
+		`"requestId":"r1","route":"/api/v1/businesses/{business_id}/cases","uid":"u1","businessId":"b1",` +
+		`"component":"api","status":500,"error":"boom"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %s, want %s", buf.String(), want)
+	}
+}
+
+func TestLogFiltersLevels(t *testing.T) {
+	l, buf := newTestLogger(LevelWarn)
+	ctx := context.Background()
+	l.Debug(ctx, "debug")
+	l.Info(ctx, "info")
+	l.Warn(ctx, "warn")
+	l.Error(ctx, "error")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"severity":"WARNING"`) || !strings.Contains(lines[1], `"severity":"ERROR"`) {
+		t.Errorf("got %q, want the warning and the error", lines)
+	}
+}
+
+func TestStd(t *testing.T) {
+	l, buf := newTestLogger(LevelInfo)
+	l.Std(LevelError).Printf("failed to connect. error: %v\n", "refused")
+
+	want := `{"time":"2023-01-02T03:04:05Z","severity":"ERROR","message":"failed to connect. error: refused"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %s, want %s", buf.String(), want)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, want := range map[string]Level{"debug": LevelDebug, "INFO": LevelInfo, "warn": LevelWarn, "error": LevelError} {
+		if got, err := ParseLevel(s); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(verbose) succeeded, want an error")
+	}
+}
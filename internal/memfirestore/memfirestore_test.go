@@ -0,0 +1,206 @@
+package memfirestore
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newClient(t *testing.T) *firestore.Client {
+	client, cleanup, err := NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cleanup)
+	return client
+}
+
+func TestWrites(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+	doc := client.Doc("businesses/b1")
+
+	if _, err := doc.Create(ctx, map[string]interface{}{
+		"name":      "Pigeon",
+		"count":     1,
+		"tags":      []string{"a"},
+		"createdAt": firestore.ServerTimestamp,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.Create(ctx, map[string]interface{}{}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("second Create = %v, want AlreadyExists", err)
+	}
+	if _, err := doc.Update(ctx, []firestore.Update{
+		{Path: "count", Value: firestore.Increment(2)},
+		{Path: "tags", Value: firestore.ArrayUnion("a", "b")},
+		{Path: "address.city", Value: "Chennai"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Doc("businesses/missing").Update(ctx, []firestore.Update{{Path: "name", Value: "x"}}); status.Code(err) != codes.NotFound {
+		t.Errorf("Update of a missing document = %v, want NotFound", err)
+	}
+
+	snapshot, err := doc.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := snapshot.Data()
+	if data["name"] != "Pigeon" || data["count"] != int64(3) {
+		t.Errorf("data = %v, want name Pigeon and count 3", data)
+	}
+	if tags := data["tags"].([]interface{}); len(tags) != 2 {
+		t.Errorf("tags = %v, want [a b]", tags)
+	}
+	if city, _ := snapshot.DataAt("address.city"); city != "Chennai" {
+		t.Errorf("address.city = %v, want Chennai", city)
+	}
+	if _, ok := data["createdAt"]; !ok {
+		t.Error("createdAt is not set")
+	}
+
+	if _, err := doc.Set(ctx, map[string]interface{}{"count": firestore.Delete}, firestore.MergeAll); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot, err = doc.Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := snapshot.Data()["count"]; ok {
+		t.Error("count was not deleted")
+	}
+
+	if _, err := doc.Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.Get(ctx); status.Code(err) != codes.NotFound {
+		t.Errorf("Get after Delete = %v, want NotFound", err)
+	}
+}
+
+func TestQueries(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+	batch := client.Batch()
+	for id, priority := range map[string]int{"c1": 3, "c2": 1, "c3": 2, "c4": 5} {
+		batch.Set(client.Doc("businesses/b1/cases/"+id), map[string]interface{}{"priority": priority, "open": priority != 5})
+	}
+	batch.Set(client.Doc("businesses/b2/cases/c5"), map[string]interface{}{"priority": 4, "open": true})
+	batch.Set(client.Doc("businesses/b1/notes/n1"), map[string]interface{}{"priority": 1})
+	if _, err := batch.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(q firestore.Query) []string {
+		t.Helper()
+		var ids []string
+		it := q.Documents(ctx)
+		defer it.Stop()
+		for {
+			snapshot, err := it.Next()
+			if err == iterator.Done {
+				return ids
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, snapshot.Ref.ID)
+		}
+	}
+	cases := client.Collection("businesses/b1/cases")
+	tests := []struct {
+		name  string
+		query firestore.Query
+		want  []string
+	}{
+		{"order", cases.OrderBy("priority", firestore.Asc), []string{"c2", "c3", "c1", "c4"}},
+		{"filter", cases.Where("open", "==", true).OrderBy("priority", firestore.Desc), []string{"c1", "c3", "c2"}},
+		{"inequality", cases.Where("priority", ">", 1), []string{"c3", "c1", "c4"}},
+		{"in", cases.Where("priority", "in", []int{1, 5}), []string{"c2", "c4"}},
+		{"cursor", cases.OrderBy("priority", firestore.Asc).StartAfter(2).Limit(1), []string{"c1"}},
+		{"end", cases.OrderBy("priority", firestore.Asc).EndAt(2), []string{"c2", "c3"}},
+		{"offset", cases.OrderBy("priority", firestore.Asc).Offset(3), []string{"c4"}},
+		{"collection group", client.CollectionGroup("cases").Where("priority", ">=", 4), []string{"c5", "c4"}},
+		{"empty", cases.Where("priority", ">", 10), nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ids(test.query)
+			if len(got) != len(test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Fatalf("got %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+	doc := client.Doc("counters/c")
+	for i := 0; i < 3; i++ {
+		err := client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			snapshot, err := tx.Get(doc)
+			var n int64
+			if err == nil {
+				n, _ = snapshot.Data()["n"].(int64)
+			} else if status.Code(err) != codes.NotFound {
+				return err
+			}
+			return tx.Set(doc, map[string]interface{}{"n": n + 1})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshot, err := doc.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := snapshot.Data()["n"]; n != int64(3) {
+		t.Errorf("n = %v, want 3", n)
+	}
+}
+
+func TestTransactionConflict(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+	doc := client.Doc("counters/c")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+				snapshot, err := tx.Get(doc)
+				var n int64
+				if err == nil {
+					n, _ = snapshot.Data()["n"].(int64)
+				} else if status.Code(err) != codes.NotFound {
+					return err
+				}
+				return tx.Set(doc, map[string]interface{}{"n": n + 1})
+			}, firestore.MaxAttempts(100))
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	snapshot, err := doc.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := snapshot.Data()["n"]; n != int64(10) {
+		t.Errorf("n = %v, want every increment", n)
+	}
+}
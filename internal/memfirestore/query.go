@@ -0,0 +1,343 @@
+package memfirestore
+
+import (
+	"sort"
+	"strings"
+
+	pb "google.golang.org/genproto/googleapis/firestore/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const fieldName = "__name__"
+
+// An order is a parsed order of the query.
+type order struct {
+	path       []string
+	name       bool // orders by the document name
+	descending bool
+}
+
+func (o order) value(doc *pb.Document) (*pb.Value, bool) {
+	if o.name {
+		return &pb.Value{ValueType: &pb.Value_ReferenceValue{ReferenceValue: doc.GetName()}}, true
+	}
+	return getField(doc.GetFields(), o.path)
+}
+
+// runQuery returns the documents of the query under the parent, which is either the root of
+// the documents or a document.
+func runQuery(docs map[string]*pb.Document, parent string, query *pb.StructuredQuery) ([]*pb.Document, error) {
+	if len(query.GetFrom()) != 1 {
+		return nil, status.Error(codes.Unimplemented, "queries must select one collection")
+	}
+	from := query.GetFrom()[0]
+	filter, err := newFilter(query.GetWhere())
+	if err != nil {
+		return nil, err
+	}
+	orders, err := queryOrders(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*pb.Document
+	for name, doc := range docs {
+		if !inCollection(name, parent, from) || !filter(doc) || !hasOrderFields(doc, orders) {
+			continue
+		}
+		result = append(result, doc)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return compareDocs(result[i], result[j], orders) < 0
+	})
+
+	if cursor := query.GetStartAt(); cursor != nil {
+		for len(result) > 0 {
+			c := compareCursor(result[0], orders, cursor.GetValues())
+			if c > 0 || c == 0 && cursor.GetBefore() {
+				break
+			}
+			result = result[1:]
+		}
+	}
+	if cursor := query.GetEndAt(); cursor != nil {
+		for len(result) > 0 {
+			c := compareCursor(result[len(result)-1], orders, cursor.GetValues())
+			if c < 0 || c == 0 && !cursor.GetBefore() {
+				break
+			}
+			result = result[:len(result)-1]
+		}
+	}
+	if offset := int(query.GetOffset()); offset > 0 {
+		if offset > len(result) {
+			offset = len(result)
+		}
+		result = result[offset:]
+	}
+	if limit := query.GetLimit(); limit != nil && int(limit.GetValue()) < len(result) {
+		result = result[:limit.GetValue()]
+	}
+	if projection := query.GetSelect(); projection != nil {
+		for i, doc := range result {
+			if result[i], err = project(doc, projection.GetFields()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// inCollection reports whether the named document is in the collection of the selector, directly
+// under the parent or, for collection groups, anywhere below it.
+func inCollection(name, parent string, from *pb.StructuredQuery_CollectionSelector) bool {
+	if !strings.HasPrefix(name, parent+"/") {
+		return false
+	}
+	segments := strings.Split(strings.TrimPrefix(name, parent+"/"), "/")
+	if from.GetAllDescendants() {
+		return segments[len(segments)-2] == from.GetCollectionId()
+	}
+	return len(segments) == 2 && segments[0] == from.GetCollectionId()
+}
+
+// queryOrders returns the explicit orders of the query, preceded by the field of the inequality
+// filter if there is no explicit order, and followed by the document name.
+func queryOrders(query *pb.StructuredQuery) ([]order, error) {
+	var orders []order
+	for _, o := range query.GetOrderBy() {
+		parsed, err := newOrder(o.GetField().GetFieldPath(), o.GetDirection() == pb.StructuredQuery_DESCENDING)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, parsed)
+	}
+	if len(orders) == 0 {
+		if path := inequalityField(query.GetWhere()); len(path) > 0 {
+			parsed, err := newOrder(path, false)
+			if err != nil {
+				return nil, err
+			}
+			orders = append(orders, parsed)
+		}
+	}
+	for _, o := range orders {
+		if o.name {
+			return orders, nil
+		}
+	}
+	descending := len(orders) > 0 && orders[len(orders)-1].descending
+	return append(orders, order{name: true, descending: descending}), nil
+}
+
+func newOrder(path string, descending bool) (order, error) {
+	if path == fieldName {
+		return order{name: true, descending: descending}, nil
+	}
+	segments, err := parseFieldPath(path)
+	if err != nil {
+		return order{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return order{path: segments, descending: descending}, nil
+}
+
+func inequalityField(filter *pb.StructuredQuery_Filter) string {
+	switch f := filter.GetFilterType().(type) {
+	case *pb.StructuredQuery_Filter_FieldFilter:
+		switch f.FieldFilter.GetOp() {
+		case pb.StructuredQuery_FieldFilter_LESS_THAN,
+			pb.StructuredQuery_FieldFilter_LESS_THAN_OR_EQUAL,
+			pb.StructuredQuery_FieldFilter_GREATER_THAN,
+			pb.StructuredQuery_FieldFilter_GREATER_THAN_OR_EQUAL,
+			pb.StructuredQuery_FieldFilter_NOT_EQUAL,
+			pb.StructuredQuery_FieldFilter_NOT_IN:
+			return f.FieldFilter.GetField().GetFieldPath()
+		}
+	case *pb.StructuredQuery_Filter_CompositeFilter:
+		for _, sub := range f.CompositeFilter.GetFilters() {
+			if path := inequalityField(sub); len(path) > 0 {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+func hasOrderFields(doc *pb.Document, orders []order) bool {
+	for _, o := range orders {
+		if _, ok := o.value(doc); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func compareDocs(a, b *pb.Document, orders []order) int {
+	for _, o := range orders {
+		va, _ := o.value(a)
+		vb, _ := o.value(b)
+		c := compare(va, vb)
+		if o.descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareCursor compares the document with the values of a cursor, one per order.
+func compareCursor(doc *pb.Document, orders []order, values []*pb.Value) int {
+	for i, value := range values {
+		if i >= len(orders) {
+			break
+		}
+		docValue, _ := orders[i].value(doc)
+		c := compare(docValue, value)
+		if orders[i].descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// newFilter returns the predicate of the filter. A nil filter matches all the documents.
+func newFilter(filter *pb.StructuredQuery_Filter) (func(doc *pb.Document) bool, error) {
+	switch f := filter.GetFilterType().(type) {
+	case nil:
+		return func(*pb.Document) bool { return true }, nil
+	case *pb.StructuredQuery_Filter_CompositeFilter:
+		var filters []func(doc *pb.Document) bool
+		for _, sub := range f.CompositeFilter.GetFilters() {
+			parsed, err := newFilter(sub)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, parsed)
+		}
+		return func(doc *pb.Document) bool {
+			for _, filter := range filters {
+				if !filter(doc) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case *pb.StructuredQuery_Filter_FieldFilter:
+		return newFieldFilter(f.FieldFilter)
+	case *pb.StructuredQuery_Filter_UnaryFilter:
+		return newUnaryFilter(f.UnaryFilter)
+	}
+	return nil, status.Error(codes.Unimplemented, "unsupported filter")
+}
+
+func newFieldFilter(filter *pb.StructuredQuery_FieldFilter) (func(doc *pb.Document) bool, error) {
+	o, err := newOrder(filter.GetField().GetFieldPath(), false)
+	if err != nil {
+		return nil, err
+	}
+	operand := filter.GetValue()
+	var match func(value *pb.Value) bool
+	switch filter.GetOp() {
+	case pb.StructuredQuery_FieldFilter_EQUAL:
+		match = func(value *pb.Value) bool { return equal(value, operand) }
+	case pb.StructuredQuery_FieldFilter_NOT_EQUAL:
+		match = func(value *pb.Value) bool { return !isNull(value) && !equal(value, operand) }
+	case pb.StructuredQuery_FieldFilter_LESS_THAN:
+		match = func(value *pb.Value) bool { return comparable(value, operand) && compare(value, operand) < 0 }
+	case pb.StructuredQuery_FieldFilter_LESS_THAN_OR_EQUAL:
+		match = func(value *pb.Value) bool { return comparable(value, operand) && compare(value, operand) <= 0 }
+	case pb.StructuredQuery_FieldFilter_GREATER_THAN:
+		match = func(value *pb.Value) bool { return comparable(value, operand) && compare(value, operand) > 0 }
+	case pb.StructuredQuery_FieldFilter_GREATER_THAN_OR_EQUAL:
+		match = func(value *pb.Value) bool { return comparable(value, operand) && compare(value, operand) >= 0 }
+	case pb.StructuredQuery_FieldFilter_ARRAY_CONTAINS:
+		match = func(value *pb.Value) bool { return containsAny(value.GetArrayValue().GetValues(), operand) }
+	case pb.StructuredQuery_FieldFilter_IN:
+		match = func(value *pb.Value) bool { return containsAny(operand.GetArrayValue().GetValues(), value) }
+	case pb.StructuredQuery_FieldFilter_ARRAY_CONTAINS_ANY:
+		match = func(value *pb.Value) bool {
+			return containsAny(value.GetArrayValue().GetValues(), operand.GetArrayValue().GetValues()...)
+		}
+	case pb.StructuredQuery_FieldFilter_NOT_IN:
+		match = func(value *pb.Value) bool {
+			return !isNull(value) && !containsAny(operand.GetArrayValue().GetValues(), value)
+		}
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "unsupported operator %v", filter.GetOp())
+	}
+	return func(doc *pb.Document) bool {
+		value, ok := o.value(doc)
+		return ok && match(value)
+	}, nil
+}
+
+func newUnaryFilter(filter *pb.StructuredQuery_UnaryFilter) (func(doc *pb.Document) bool, error) {
+	o, err := newOrder(filter.GetField().GetFieldPath(), false)
+	if err != nil {
+		return nil, err
+	}
+	var match func(value *pb.Value) bool
+	switch filter.GetOp() {
+	case pb.StructuredQuery_UnaryFilter_IS_NULL:
+		match = isNull
+	case pb.StructuredQuery_UnaryFilter_IS_NAN:
+		match = isNaN
+	case pb.StructuredQuery_UnaryFilter_IS_NOT_NULL:
+		match = func(value *pb.Value) bool { return !isNull(value) }
+	case pb.StructuredQuery_UnaryFilter_IS_NOT_NAN:
+		match = func(value *pb.Value) bool {
+			return typeOrder(value) == typeOrder(&pb.Value{ValueType: &pb.Value_DoubleValue{}}) && !isNaN(value)
+		}
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "unsupported operator %v", filter.GetOp())
+	}
+	return func(doc *pb.Document) bool {
+		value, ok := o.value(doc)
+		return ok && match(value)
+	}, nil
+}
+
+// comparable reports whether the range filters compare the values, which must be of the same type.
+func comparable(a, b *pb.Value) bool {
+	return typeOrder(a) == typeOrder(b) && !isNaN(a) && !isNaN(b)
+}
+
+func containsAny(values []*pb.Value, wanted ...*pb.Value) bool {
+	for _, value := range values {
+		for _, w := range wanted {
+			if equal(value, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// project returns a copy of the document with only the fields of the mask.
+func project(doc *pb.Document, fields []*pb.StructuredQuery_FieldReference) (*pb.Document, error) {
+	projected := &pb.Document{
+		Name:       doc.GetName(),
+		Fields:     map[string]*pb.Value{},
+		CreateTime: doc.GetCreateTime(),
+		UpdateTime: doc.GetUpdateTime(),
+	}
+	for _, field := range fields {
+		if field.GetFieldPath() == fieldName {
+			continue
+		}
+		path, err := parseFieldPath(field.GetFieldPath())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if value, ok := getField(doc.GetFields(), path); ok {
+			setField(projected.Fields, path, value)
+		}
+	}
+	return projected, nil
+}
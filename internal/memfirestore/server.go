@@ -0,0 +1,442 @@
+// Package memfirestore serves the Firestore API from memory, so the Firestore client can run
+// without a project. It keeps the documents in a map and implements the RPCs the client uses for
+// reads, writes, queries and transactions. Transactions are optimistic: their reads see the latest
+// documents, and the commit aborts if a document they read changed since, for the client to retry.
+package memfirestore
+
+import (
+	"context"
+	"crypto/rand"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/api/option"
+	pb "google.golang.org/genproto/googleapis/firestore/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Server struct {
+	pb.UnimplementedFirestoreServer
+
+	mu   sync.RWMutex
+	docs map[string]*pb.Document
+	txs  map[string]reads // of the open transactions, by ID
+	now  func() time.Time
+	last time.Time // of the last commit
+}
+
+// reads are the update times of the documents a transaction read, nil for the missing ones.
+type reads map[string]*timestamppb.Timestamp
+
+func NewServer() *Server {
+	return &Server{docs: map[string]*pb.Document{}, txs: map[string]reads{}, now: time.Now}
+}
+
+// NewClient returns a Firestore client of the project connected to a new in-memory server.
+// The cleanup closes the client and stops the server.
+func NewClient(ctx context.Context, projectID string) (*firestore.Client, func(), error) {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pb.RegisterFirestoreServer(server, NewServer())
+	go server.Serve(listener)
+
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithInsecure())
+	if err != nil {
+		server.Stop()
+		return nil, nil, err
+	}
+	client, err := firestore.NewClient(ctx, projectID, option.WithGRPCConn(conn))
+	if err != nil {
+		conn.Close()
+		server.Stop()
+		return nil, nil, err
+	}
+	return client, func() {
+		client.Close()
+		server.Stop()
+	}, nil
+}
+
+func (s *Server) GetDocument(_ context.Context, req *pb.GetDocumentRequest) (*pb.Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc, ok := s.docs[req.GetName()]
+	s.read(req.GetTransaction(), req.GetName())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "document %s not found", req.GetName())
+	}
+	return applyMask(doc, req.GetMask()), nil
+}
+
+func (s *Server) BatchGetDocuments(req *pb.BatchGetDocumentsRequest, stream pb.Firestore_BatchGetDocumentsServer) error {
+	s.mu.Lock()
+	readTime := s.timestamp()
+	var responses []*pb.BatchGetDocumentsResponse
+	for _, name := range req.GetDocuments() {
+		s.read(req.GetTransaction(), name)
+		response := &pb.BatchGetDocumentsResponse{ReadTime: readTime}
+		if doc, ok := s.docs[name]; ok {
+			response.Result = &pb.BatchGetDocumentsResponse_Found{Found: applyMask(doc, req.GetMask())}
+		} else {
+			response.Result = &pb.BatchGetDocumentsResponse_Missing{Missing: name}
+		}
+		responses = append(responses, response)
+	}
+	s.mu.Unlock()
+
+	for _, response := range responses {
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) RunQuery(req *pb.RunQueryRequest, stream pb.Firestore_RunQueryServer) error {
+	s.mu.Lock()
+	readTime := s.timestamp()
+	docs, err := runQuery(s.docs, req.GetParent(), req.GetStructuredQuery())
+	for _, doc := range docs {
+		s.read(req.GetTransaction(), doc.GetName())
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if len(docs) == 0 {
+		return stream.Send(&pb.RunQueryResponse{ReadTime: readTime})
+	}
+	for _, doc := range docs {
+		if err := stream.Send(&pb.RunQueryResponse{Document: doc, ReadTime: readTime}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListDocuments lists the documents of the collection, including the missing ones that only
+// have subcollections if asked to.
+func (s *Server) ListDocuments(_ context.Context, req *pb.ListDocumentsRequest) (*pb.ListDocumentsResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	prefix := req.GetParent() + "/" + req.GetCollectionId() + "/"
+	names := map[string]bool{}
+	for name := range s.docs {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		id := strings.SplitN(strings.TrimPrefix(name, prefix), "/", 2)[0]
+		if _, ok := s.docs[prefix+id]; ok || req.GetShowMissing() {
+			names[prefix+id] = true
+		}
+	}
+	response := &pb.ListDocumentsResponse{}
+	for name := range names {
+		if doc, ok := s.docs[name]; ok {
+			response.Documents = append(response.Documents, applyMask(doc, req.GetMask()))
+		} else {
+			response.Documents = append(response.Documents, &pb.Document{Name: name})
+		}
+	}
+	sort.Slice(response.Documents, func(i, j int) bool {
+		return response.Documents[i].GetName() < response.Documents[j].GetName()
+	})
+	return response, nil
+}
+
+func (s *Server) ListCollectionIds(_ context.Context, req *pb.ListCollectionIdsRequest) (*pb.ListCollectionIdsResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	prefix := req.GetParent() + "/"
+	ids := map[string]bool{}
+	for name := range s.docs {
+		if strings.HasPrefix(name, prefix) {
+			ids[strings.SplitN(strings.TrimPrefix(name, prefix), "/", 2)[0]] = true
+		}
+	}
+	response := &pb.ListCollectionIdsResponse{}
+	for id := range ids {
+		response.CollectionIds = append(response.CollectionIds, id)
+	}
+	sort.Strings(response.CollectionIds)
+	return response, nil
+}
+
+func (s *Server) BeginTransaction(context.Context, *pb.BeginTransactionRequest) (*pb.BeginTransactionResponse, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	s.mu.Lock()
+	s.txs[string(id)] = reads{}
+	s.mu.Unlock()
+	return &pb.BeginTransactionResponse{Transaction: id}, nil
+}
+
+func (s *Server) Rollback(_ context.Context, req *pb.RollbackRequest) (*emptypb.Empty, error) {
+	s.mu.Lock()
+	delete(s.txs, string(req.GetTransaction()))
+	s.mu.Unlock()
+	return &emptypb.Empty{}, nil
+}
+
+// Commit applies the writes in order, and all of them or none. The commit of a transaction
+// aborts if a document it read was written since.
+func (s *Server) Commit(_ context.Context, req *pb.CommitRequest) (*pb.CommitResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tx := req.GetTransaction(); len(tx) > 0 {
+		read, ok := s.txs[string(tx)]
+		delete(s.txs, string(tx))
+		if !ok {
+			return nil, status.Error(codes.InvalidArgument, "transaction not found")
+		}
+		for name, updateTime := range read {
+			if !proto.Equal(s.docs[name].GetUpdateTime(), updateTime) {
+				return nil, status.Errorf(codes.Aborted, "document %s changed during the transaction", name)
+			}
+		}
+	}
+	commitTime := s.commitTime()
+	staged := map[string]*pb.Document{}
+	lookup := func(name string) (*pb.Document, bool) {
+		if doc, ok := staged[name]; ok {
+			return doc, doc != nil
+		}
+		doc, ok := s.docs[name]
+		return doc, ok
+	}
+
+	response := &pb.CommitResponse{CommitTime: commitTime}
+	for _, write := range req.GetWrites() {
+		name := writeName(write)
+		current, exists := lookup(name)
+		if err := checkPrecondition(name, current, exists, write.GetCurrentDocument()); err != nil {
+			return nil, err
+		}
+		result := &pb.WriteResult{UpdateTime: commitTime}
+		switch op := write.GetOperation().(type) {
+		case *pb.Write_Delete:
+			staged[name] = nil
+			result.UpdateTime = nil
+		case *pb.Write_Update:
+			doc, err := update(current, exists, op.Update, write.GetUpdateMask(), commitTime)
+			if err != nil {
+				return nil, err
+			}
+			if result.TransformResults, err = transform(doc, write.GetUpdateTransforms(), commitTime); err != nil {
+				return nil, err
+			}
+			staged[name] = doc
+		case *pb.Write_Transform:
+			doc := newDocument(name, current, exists, commitTime)
+			var err error
+			if result.TransformResults, err = transform(doc, op.Transform.GetFieldTransforms(), commitTime); err != nil {
+				return nil, err
+			}
+			staged[name] = doc
+		default:
+			return nil, status.Error(codes.InvalidArgument, "unsupported write")
+		}
+		response.WriteResults = append(response.WriteResults, result)
+	}
+
+	for name, doc := range staged {
+		if doc == nil {
+			delete(s.docs, name)
+		} else {
+			s.docs[name] = doc
+		}
+	}
+	return response, nil
+}
+
+// read records the update time of the document the transaction, if any, read. It keeps the first
+// read of the document, and must be called with the write lock held.
+func (s *Server) read(tx []byte, name string) {
+	read, ok := s.txs[string(tx)]
+	if len(tx) == 0 || !ok {
+		return
+	}
+	if _, ok = read[name]; !ok {
+		read[name] = s.docs[name].GetUpdateTime()
+	}
+}
+
+func (s *Server) timestamp() *timestamppb.Timestamp {
+	ts, _ := ptypes.TimestampProto(s.now())
+	return ts
+}
+
+// commitTime returns the current time, strictly after the previous commit, so that the update
+// times of successive commits differ. It must be called with the write lock held.
+func (s *Server) commitTime() *timestamppb.Timestamp {
+	now := s.now()
+	if !now.After(s.last) {
+		now = s.last.Add(time.Microsecond)
+	}
+	s.last = now
+	ts, _ := ptypes.TimestampProto(now)
+	return ts
+}
+
+func writeName(write *pb.Write) string {
+	switch op := write.GetOperation().(type) {
+	case *pb.Write_Delete:
+		return op.Delete
+	case *pb.Write_Update:
+		return op.Update.GetName()
+	case *pb.Write_Transform:
+		return op.Transform.GetDocument()
+	}
+	return ""
+}
+
+func checkPrecondition(name string, current *pb.Document, exists bool, precondition *pb.Precondition) error {
+	switch condition := precondition.GetConditionType().(type) {
+	case *pb.Precondition_Exists:
+		if condition.Exists && !exists {
+			return status.Errorf(codes.NotFound, "no document to update: %s", name)
+		}
+		if !condition.Exists && exists {
+			return status.Errorf(codes.AlreadyExists, "document already exists: %s", name)
+		}
+	case *pb.Precondition_UpdateTime:
+		if !exists || !proto.Equal(current.GetUpdateTime(), condition.UpdateTime) {
+			return status.Errorf(codes.FailedPrecondition, "the update time of %s does not match", name)
+		}
+	}
+	return nil
+}
+
+// newDocument returns a copy of the current document to write to, or an empty one.
+func newDocument(name string, current *pb.Document, exists bool, now *timestamppb.Timestamp) *pb.Document {
+	if exists {
+		doc := proto.Clone(current).(*pb.Document)
+		doc.UpdateTime = now
+		if doc.Fields == nil {
+			doc.Fields = map[string]*pb.Value{}
+		}
+		return doc
+	}
+	return &pb.Document{Name: name, Fields: map[string]*pb.Value{}, CreateTime: now, UpdateTime: now}
+}
+
+// update returns the document written by the update. Without a mask, the update replaces all the
+// fields. With one, it only sets the fields of the mask, and deletes those it has no value for.
+func update(current *pb.Document, exists bool, write *pb.Document, mask *pb.DocumentMask, now *timestamppb.Timestamp) (*pb.Document, error) {
+	doc := newDocument(write.GetName(), current, exists, now)
+	written := proto.Clone(write).(*pb.Document)
+	if mask == nil {
+		doc.Fields = written.GetFields()
+		if doc.Fields == nil {
+			doc.Fields = map[string]*pb.Value{}
+		}
+		return doc, nil
+	}
+	for _, fieldPath := range mask.GetFieldPaths() {
+		path, err := parseFieldPath(fieldPath)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if value, ok := getField(written.GetFields(), path); ok {
+			setField(doc.Fields, path, value)
+		} else {
+			deleteField(doc.Fields, path)
+		}
+	}
+	return doc, nil
+}
+
+// transform applies the field transforms to the document and returns their results.
+func transform(doc *pb.Document, transforms []*pb.DocumentTransform_FieldTransform, now *timestamppb.Timestamp) ([]*pb.Value, error) {
+	var results []*pb.Value
+	for _, t := range transforms {
+		path, err := parseFieldPath(t.GetFieldPath())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		current, _ := getField(doc.Fields, path)
+		var value *pb.Value
+		switch transformType := t.GetTransformType().(type) {
+		case *pb.DocumentTransform_FieldTransform_SetToServerValue:
+			value = &pb.Value{ValueType: &pb.Value_TimestampValue{TimestampValue: now}}
+		case *pb.DocumentTransform_FieldTransform_Increment:
+			value = increment(current, transformType.Increment)
+		case *pb.DocumentTransform_FieldTransform_AppendMissingElements:
+			values := current.GetArrayValue().GetValues()
+			for _, element := range transformType.AppendMissingElements.GetValues() {
+				if !containsAny(values, element) {
+					values = append(values, element)
+				}
+			}
+			value = &pb.Value{ValueType: &pb.Value_ArrayValue{ArrayValue: &pb.ArrayValue{Values: values}}}
+		case *pb.DocumentTransform_FieldTransform_RemoveAllFromArray:
+			var values []*pb.Value
+			for _, element := range current.GetArrayValue().GetValues() {
+				if !containsAny(transformType.RemoveAllFromArray.GetValues(), element) {
+					values = append(values, element)
+				}
+			}
+			value = &pb.Value{ValueType: &pb.Value_ArrayValue{ArrayValue: &pb.ArrayValue{Values: values}}}
+		default:
+			return nil, status.Error(codes.Unimplemented, "unsupported field transform")
+		}
+		setField(doc.Fields, path, value)
+		results = append(results, value)
+	}
+	return results, nil
+}
+
+// increment adds the operand to the current value, which counts as 0 if it is not a number.
+// The sum is an integer only if both are integers.
+func increment(current, operand *pb.Value) *pb.Value {
+	if typeOrder(current) != typeOrder(operand) {
+		return operand
+	}
+	a, aInt := current.GetValueType().(*pb.Value_IntegerValue)
+	b, bInt := operand.GetValueType().(*pb.Value_IntegerValue)
+	if aInt && bInt {
+		return &pb.Value{ValueType: &pb.Value_IntegerValue{IntegerValue: a.IntegerValue + b.IntegerValue}}
+	}
+	return &pb.Value{ValueType: &pb.Value_DoubleValue{DoubleValue: toFloat(current) + toFloat(operand)}}
+}
+
+// applyMask returns a copy of the document, with only the fields of the mask if there is one.
+func applyMask(doc *pb.Document, mask *pb.DocumentMask) *pb.Document {
+	if mask == nil {
+		return proto.Clone(doc).(*pb.Document)
+	}
+	masked := &pb.Document{
+		Name:       doc.GetName(),
+		Fields:     map[string]*pb.Value{},
+		CreateTime: doc.GetCreateTime(),
+		UpdateTime: doc.GetUpdateTime(),
+	}
+	for _, fieldPath := range mask.GetFieldPaths() {
+		path, err := parseFieldPath(fieldPath)
+		if err != nil {
+			continue
+		}
+		if value, ok := getField(doc.GetFields(), path); ok {
+			setField(masked.Fields, path, proto.Clone(value).(*pb.Value))
+		}
+	}
+	return masked
+}
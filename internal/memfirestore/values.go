@@ -0,0 +1,263 @@
+package memfirestore
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	pb "google.golang.org/genproto/googleapis/firestore/v1"
+)
+
+// typeOrder is the rank of the type of v in the Firestore value ordering.
+func typeOrder(v *pb.Value) int {
+	switch v.GetValueType().(type) {
+	case *pb.Value_NullValue:
+		return 0
+	case *pb.Value_BooleanValue:
+		return 1
+	case *pb.Value_IntegerValue, *pb.Value_DoubleValue:
+		return 2
+	case *pb.Value_TimestampValue:
+		return 3
+	case *pb.Value_StringValue:
+		return 4
+	case *pb.Value_BytesValue:
+		return 5
+	case *pb.Value_ReferenceValue:
+		return 6
+	case *pb.Value_GeoPointValue:
+		return 7
+	case *pb.Value_ArrayValue:
+		return 8
+	case *pb.Value_MapValue:
+		return 9
+	}
+	return 0
+}
+
+// compare orders the values as Firestore does: by type first, then by value. Integers and doubles
+// compare as numbers, and NaN is less than any other number.
+func compare(a, b *pb.Value) int {
+	if orderA, orderB := typeOrder(a), typeOrder(b); orderA != orderB {
+		return compareInts(int64(orderA), int64(orderB))
+	}
+	switch a.GetValueType().(type) {
+	case *pb.Value_BooleanValue:
+		return compareBools(a.GetBooleanValue(), b.GetBooleanValue())
+	case *pb.Value_IntegerValue, *pb.Value_DoubleValue:
+		return compareNumbers(a, b)
+	case *pb.Value_TimestampValue:
+		ta, tb := a.GetTimestampValue(), b.GetTimestampValue()
+		if c := compareInts(ta.GetSeconds(), tb.GetSeconds()); c != 0 {
+			return c
+		}
+		return compareInts(int64(ta.GetNanos()), int64(tb.GetNanos()))
+	case *pb.Value_StringValue:
+		return strings.Compare(a.GetStringValue(), b.GetStringValue())
+	case *pb.Value_BytesValue:
+		return bytes.Compare(a.GetBytesValue(), b.GetBytesValue())
+	case *pb.Value_ReferenceValue:
+		return compareReferences(a.GetReferenceValue(), b.GetReferenceValue())
+	case *pb.Value_GeoPointValue:
+		ga, gb := a.GetGeoPointValue(), b.GetGeoPointValue()
+		if c := compareFloats(ga.GetLatitude(), gb.GetLatitude()); c != 0 {
+			return c
+		}
+		return compareFloats(ga.GetLongitude(), gb.GetLongitude())
+	case *pb.Value_ArrayValue:
+		va, vb := a.GetArrayValue().GetValues(), b.GetArrayValue().GetValues()
+		for i := 0; i < len(va) && i < len(vb); i++ {
+			if c := compare(va[i], vb[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInts(int64(len(va)), int64(len(vb)))
+	case *pb.Value_MapValue:
+		return compareMaps(a.GetMapValue().GetFields(), b.GetMapValue().GetFields())
+	}
+	return 0
+}
+
+// equal reports whether the values are equal for the equality filters, so NaN equals nothing.
+func equal(a, b *pb.Value) bool {
+	if isNaN(a) || isNaN(b) {
+		return false
+	}
+	return compare(a, b) == 0
+}
+
+func isNaN(v *pb.Value) bool {
+	d, ok := v.GetValueType().(*pb.Value_DoubleValue)
+	return ok && math.IsNaN(d.DoubleValue)
+}
+
+func isNull(v *pb.Value) bool {
+	_, ok := v.GetValueType().(*pb.Value_NullValue)
+	return ok
+}
+
+func compareNumbers(a, b *pb.Value) int {
+	ia, aInt := a.GetValueType().(*pb.Value_IntegerValue)
+	ib, bInt := b.GetValueType().(*pb.Value_IntegerValue)
+	if aInt && bInt {
+		return compareInts(ia.IntegerValue, ib.IntegerValue)
+	}
+	return compareFloats(toFloat(a), toFloat(b))
+}
+
+func toFloat(v *pb.Value) float64 {
+	if i, ok := v.GetValueType().(*pb.Value_IntegerValue); ok {
+		return float64(i.IntegerValue)
+	}
+	return v.GetDoubleValue()
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case math.IsNaN(a) && math.IsNaN(b):
+		return 0
+	case math.IsNaN(a):
+		return -1
+	case math.IsNaN(b):
+		return 1
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	}
+	return 1
+}
+
+// compareReferences compares the document names segment by segment, so a parent sorts
+// before its children.
+func compareReferences(a, b string) int {
+	sa, sb := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(sa) && i < len(sb); i++ {
+		if c := strings.Compare(sa[i], sb[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(int64(len(sa)), int64(len(sb)))
+}
+
+func compareMaps(a, b map[string]*pb.Value) int {
+	ka, kb := sortedKeys(a), sortedKeys(b)
+	for i := 0; i < len(ka) && i < len(kb); i++ {
+		if c := strings.Compare(ka[i], kb[i]); c != 0 {
+			return c
+		}
+		if c := compare(a[ka[i]], b[kb[i]]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(int64(len(ka)), int64(len(kb)))
+}
+
+func sortedKeys(m map[string]*pb.Value) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseFieldPath splits a field path of the API, e.g. a.`b.c`.d, into its segments.
+func parseFieldPath(path string) ([]string, error) {
+	var segments []string
+	var segment strings.Builder
+	quoted, escaped := false, false
+	for _, r := range path {
+		switch {
+		case escaped:
+			segment.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '`':
+			quoted = !quoted
+		case !quoted && r == '.':
+			segments = append(segments, segment.String())
+			segment.Reset()
+		default:
+			segment.WriteRune(r)
+		}
+	}
+	if quoted || escaped {
+		return nil, fmt.Errorf("invalid field path %q", path)
+	}
+	segments = append(segments, segment.String())
+	for _, s := range segments {
+		if len(s) == 0 {
+			return nil, fmt.Errorf("invalid field path %q", path)
+		}
+	}
+	return segments, nil
+}
+
+func getField(fields map[string]*pb.Value, path []string) (*pb.Value, bool) {
+	for i, segment := range path {
+		value, ok := fields[segment]
+		if !ok {
+			return nil, false
+		}
+		if i == len(path)-1 {
+			return value, true
+		}
+		m := value.GetMapValue()
+		if m == nil {
+			return nil, false
+		}
+		fields = m.GetFields()
+	}
+	return nil, false
+}
+
+// setField sets the value at the path, replacing the values on the way that are not maps.
+func setField(fields map[string]*pb.Value, path []string, value *pb.Value) {
+	for _, segment := range path[:len(path)-1] {
+		m := fields[segment].GetMapValue()
+		if m == nil {
+			m = &pb.MapValue{}
+			fields[segment] = &pb.Value{ValueType: &pb.Value_MapValue{MapValue: m}}
+		}
+		if m.Fields == nil {
+			m.Fields = map[string]*pb.Value{}
+		}
+		fields = m.Fields
+	}
+	fields[path[len(path)-1]] = value
+}
+
+func deleteField(fields map[string]*pb.Value, path []string) {
+	for _, segment := range path[:len(path)-1] {
+		m := fields[segment].GetMapValue()
+		if m == nil {
+			return
+		}
+		fields = m.GetFields()
+	}
+	delete(fields, path[len(path)-1])
+}
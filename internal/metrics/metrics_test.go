@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	ObserveProvider(ProviderVonage, "SendSMS", time.Now(), nil)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, PathMetrics, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+	body := recorder.Body.String()
+	for _, want := range []string{
+		`provider_requests_total{operation="SendSMS",provider="vonage",result="ok"} 1`,
+		"go_goroutines ",
+		"process_start_time_seconds ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	server := NewServer(":0")
+	for path, want := range map[string]int{PathMetrics: http.StatusOK, "/users": http.StatusNotFound} {
+		recorder := httptest.NewRecorder()
+		server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != want {
+			t.Errorf("GET %s: status = %d, want %d", path, recorder.Code, want)
+		}
+	}
+}
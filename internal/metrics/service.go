@@ -0,0 +1,128 @@
+// Package metrics keeps the counters and histograms of the service and exposes them,
+// with the Go runtime and process metrics, to Prometheus.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// PathMetrics is scraped by Prometheus.
+const PathMetrics = "/metrics"
+
+// Default is the registry of the service metrics.
+var Default = prometheus.NewRegistry()
+
+func init() {
+	Default.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+var factory = promauto.With(Default)
+
+var (
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of the HTTP requests by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	FirestoreDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "firestore_operation_duration_seconds",
+		Help:    "Latency of the Firestore operations by repository method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"repository", "method"})
+	FirestoreErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "firestore_operation_errors_total",
+		Help: "Failed Firestore operations by repository method.",
+	}, []string{"repository", "method"})
+
+	ProviderRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "provider_requests_total",
+		Help: "Calls to the external providers by operation and result, ok or error.",
+	}, []string{"provider", "operation", "result"})
+	ProviderDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "provider_request_duration_seconds",
+		Help:    "Latency of the calls to the external providers by operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"provider", "operation"})
+
+	JobRuns = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_job_runs_total",
+		Help: "Scheduler job runs by kind, periodic or one_shot.",
+	}, []string{"kind"})
+	JobDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "scheduler_job_duration_seconds",
+		Help:    "Duration of the scheduler job runs by kind.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"kind"})
+
+	NSQPublishFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "nsq_publish_failures_total",
+		Help: "Messages that failed to be published to NSQ by topic.",
+	}, []string{"topic"})
+	OutboxRelayed = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "outbox_relayed_total",
+		Help: "Outbox entries published to NSQ by the relay by topic.",
+	}, []string{"topic"})
+	OutboxDeadLettered = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "outbox_dead_lettered_total",
+		Help: "Outbox entries moved to the dead letters by the relay by topic.",
+	}, []string{"topic"})
+)
+
+// The external providers.
+const (
+	ProviderSendGrid     = "sendgrid"
+	ProviderVonage       = "vonage"
+	ProviderOpenTok      = "opentok"
+	ProviderPlaces       = "places"
+	ProviderDynamicLinks = "dynamic_links"
+)
+
+// ObserveFirestore records a repository method called at start that returned err.
+func ObserveFirestore(repository, method string, start time.Time, err error) {
+	FirestoreDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		FirestoreErrors.WithLabelValues(repository, method).Inc()
+	}
+}
+
+// ObserveProvider records a call to the provider made at start that returned err.
+func ObserveProvider(provider, operation string, start time.Time, err error) {
+	ObserveProviderResult(provider, operation, start, err == nil)
+}
+
+// ObserveProviderResult records a call to the provider made at start, for the providers
+// reporting failures in their responses rather than as errors.
+func ObserveProviderResult(provider, operation string, start time.Time, ok bool) {
+	ProviderDuration.WithLabelValues(provider, operation).Observe(time.Since(start).Seconds())
+	result := "ok"
+	if !ok {
+		result = "error"
+	}
+	ProviderRequests.WithLabelValues(provider, operation, result).Inc()
+}
+
+// Handler serves the service metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Default, promhttp.HandlerOpts{})
+}
+
+// NewServer serves the metrics on addr, apart from the API, so they are not exposed with it.
+func NewServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(PathMetrics, Handler())
+	return &http.Server{Addr: addr, Handler: mux}
+}
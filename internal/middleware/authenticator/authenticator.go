@@ -2,102 +2,102 @@ package authenticator
 
 import (
 	"context"
-	"encoding/json"
-	"log"
 	"net/http"
 	"strings"
 
 	"firebase.google.com/go/v4/auth"
-	auz "github.com/VinothKuppanna/pigeon-go/internal/endpoints/auth"
-	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses"
-	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses/directory"
-	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/callbacks"
-	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/signup"
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-var excludedPaths = []*exclude{
-	{"/health-check", http.MethodGet},
-	{"/businesses", http.MethodGet},
-	{"/verifications", http.MethodPost},
-	{"/verifications", http.MethodGet},
-	{"/verifications", http.MethodDelete},
-	{"/invites", http.MethodPost},
-	{signup.PathBusinessSignUp, http.MethodPost},
-	{callbacks.PathCallbackVideoSession, http.MethodPost},
-	{"/callbacks/sms", http.MethodPost},
-	{auz.PathResetPassword, http.MethodPost},
-	{auz.PathResetPassword2, http.MethodPost},
-	{businesses.ListBusinessCategories, http.MethodPost},
-	{businesses.PathBusinessCategories, http.MethodGet},
-	{businesses.PathSearchBusinesses, http.MethodGet},
-	{businesses.SearchBusinesses, http.MethodPost},
-	{businesses.FindBusiness, http.MethodPost},
-	{businesses.PathBusiness, http.MethodGet},
-	{directory.PathBusinessDirectory, http.MethodGet},
-	{directory.LoadBusinessDirectory, http.MethodPost},
-}
-
-type exclude struct {
-	Path   string
-	Method string
+// A TokenVerifier verifies the ID token of a request. *auth.Client verifies Firebase ID tokens.
+// The tokens of disabled, removed or downgraded associates are revoked, so the check is required.
+type TokenVerifier interface {
+	VerifyIDTokenAndCheckRevoked(ctx context.Context, idToken string) (*auth.Token, error)
 }
 
 type handler struct {
-	authClient *auth.Client
-	logger     *log.Logger
-	errors     *log.Logger
+	verifier      TokenVerifier
+	claimsService definition.ClaimsService
+	logger        *logger.Logger
 }
 
-func New(authClient *auth.Client, logger *log.Logger, errors *log.Logger) *handler {
-	return &handler{authClient, logger, errors}
+func New(verifier TokenVerifier, claimsService definition.ClaimsService, logger *logger.Logger) *handler {
+	return &handler{verifier, claimsService, logger}
 }
 
-func (h *handler) authenticator(excluded []*exclude) func(next http.Handler) http.Handler {
+// authenticator verifies the ID token. Routes registered as public are served without a token.
+func (h *handler) authenticator() func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 			ctx, cancel := context.WithCancel(req.Context())
 			defer cancel()
 			resp.Header().Set("Content-Type", "application/json")
-			response := &model.BaseResponse{}
 			headers := req.Header
 			idToken := strings.TrimSpace(strings.TrimPrefix(headers.Get("Authorization"), "Bearer"))
 			if len(idToken) == 0 {
-				if h.contains(excluded, req.Method, req.URL.Path) {
+				if routes.IsPublic(mux.CurrentRoute(req)) {
 					ctx = context.WithValue(context.WithValue(ctx, "idToken", ""), "uid", "")
 					next.ServeHTTP(resp, req.WithContext(ctx))
 					return
 				}
-				resp.WriteHeader(401)
-				response.Status = "BAD_AUTH_HEADERS"
-				response.Message = "Empty ID Token or UID"
-				_ = json.NewEncoder(resp).Encode(&response)
+				apierrors.Respond(resp, apierrors.New(apierrors.CodeUnauthenticated, "Empty ID Token or UID"))
+				return
+			}
+			token, err := h.verifier.VerifyIDTokenAndCheckRevoked(ctx, idToken)
+			if auth.IsIDTokenRevoked(err) {
+				apierrors.Respond(resp, apierrors.New(apierrors.CodeUnauthenticated, "ID token has been revoked"))
+				return
+			}
+			if err != nil {
+				h.logger.Warn(ctx, "failed to verify the ID token", "error", err)
+				apierrors.Respond(resp, apierrors.Wrap(apierrors.CodeUnauthenticated, err))
 				return
 			}
-			token, err := h.authClient.VerifyIDToken(ctx, idToken)
+			claims, err := h.claims(ctx, token)
 			if err != nil {
-				h.errors.Printf("error verifying ID token: %v\n", err)
-				resp.WriteHeader(401)
-				response.Status = "INVALID_ID_TOKEN"
-				response.Message = err.Error()
-				_ = json.NewEncoder(resp).Encode(&response)
+				h.logger.Error(ctx, "failed to resolve the claims", "error", err)
+				apierrors.Respond(resp, apierrors.Wrap(apierrors.CodeUnauthenticated, err))
 				return
 			}
-			next.ServeHTTP(resp, req.WithContext(context.WithValue(ctx, "uid", token.UID)))
+			if claims.Disabled {
+				apierrors.Respond(resp, apierrors.New(apierrors.CodeAccountDisabled, "User account is disabled"))
+				return
+			}
+			if request := logger.RequestFromContext(ctx); request != nil {
+				request.SetUser(token.UID, claims.BusinessID)
+			}
+			ctx = context.WithValue(context.WithValue(ctx, "uid", token.UID), "claims", claims)
+			next.ServeHTTP(resp, req.WithContext(ctx))
 		})
 	}
 }
 
-func (h *handler) contains(excluded []*exclude, method, urlPath string) bool {
-	for _, excludeItem := range excluded {
-		if method == excludeItem.Method && strings.Contains(urlPath, excludeItem.Path) {
-			return true
-		}
+// claims reads the custom claims of the token. Firestore is read only when the claims version does not match.
+func (h *handler) claims(ctx context.Context, token *auth.Token) (*model.UserClaims, error) {
+	claims := model.ClaimsFromToken(token.Claims)
+	if claims.IsCurrent() {
+		return claims, nil
 	}
-	return false
+	claims, err := h.claimsService.Claims(ctx, token.UID)
+	if status.Code(err) == codes.NotFound {
+		// customers and new users have no user document yet
+		return &model.UserClaims{Version: model.ClaimsVersion}, nil
+	}
+	return claims, err
 }
 
 func (h *handler) Setup(router *mux.Router) {
-	router.Use(h.authenticator(excludedPaths))
+	router.Use(h.authenticator())
+}
+
+// ReportPublicRoutes logs the routes served without an ID token.
+func (h *handler) ReportPublicRoutes(router *mux.Router) {
+	routes.Report(router, h.logger.Std(logger.LevelInfo))
 }
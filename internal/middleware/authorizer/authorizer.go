@@ -0,0 +1,162 @@
+package authorizer
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/gorilla/mux"
+)
+
+// A Resolver resolves the caller's associate or customer record for the business.
+// Business finds the business of the associate, see OwnBusiness.
+type Resolver interface {
+	Resolve(ctx context.Context, uid string, businessID string) (*Subject, error)
+	Business(ctx context.Context, uid string) (string, error)
+}
+
+type handler struct {
+	resolver Resolver
+	policies Policies
+	logger   *logger.Logger
+}
+
+func New(resolver Resolver, logger *logger.Logger) *handler {
+	return &handler{resolver, routePolicies, logger}
+}
+
+// authorizer checks the policy of the matched route. It runs after the authenticator.
+// The resolved subject is put in the context under the "subject" key.
+// Non-public routes without a policy are denied, so a new route cannot be left open by mistake.
+func (h *handler) authorizer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		currentRoute := mux.CurrentRoute(req)
+		if currentRoute == nil {
+			next.ServeHTTP(resp, req)
+			return
+		}
+		pathTemplate, err := currentRoute.GetPathTemplate()
+		if err != nil {
+			next.ServeHTTP(resp, req)
+			return
+		}
+		ctx := req.Context()
+		policy := h.policies.Find(req.Method, pathTemplate)
+		if policy == nil {
+			if routes.IsPublic(currentRoute) {
+				next.ServeHTTP(resp, req)
+				return
+			}
+			h.logger.Error(ctx, "no policy for the route", "method", req.Method, "path", pathTemplate)
+			forbidden(resp, "Access denied")
+			return
+		}
+		uid, _ := ctx.Value("uid").(string)
+		if len(uid) == 0 {
+			forbidden(resp, "Access denied")
+			return
+		}
+		if policy.Authenticated {
+			next.ServeHTTP(resp, req)
+			return
+		}
+		businessID := mux.Vars(req)["business_id"]
+		if policy.Business != nil {
+			if businessID, err = policy.Business(req, h.resolver); err != nil {
+				h.logger.Warn(ctx, "failed to find the business of the request", "error", err)
+			}
+		}
+		if len(businessID) == 0 {
+			forbidden(resp, "Access denied")
+			return
+		}
+		subject, err := h.resolver.Resolve(ctx, uid, businessID)
+		if err != nil {
+			h.logger.Error(ctx, "failed to resolve the subject", "error", err)
+			forbidden(resp, "Access denied")
+			return
+		}
+		if !policy.Allows(subject) {
+			forbidden(resp, "Insufficient permissions")
+			return
+		}
+		next.ServeHTTP(resp, req.WithContext(context.WithValue(ctx, "subject", subject)))
+	})
+}
+
+func forbidden(resp http.ResponseWriter, message string) {
+	apierrors.Respond(resp, apierrors.New(apierrors.CodePermissionDenied, message))
+}
+
+func (h *handler) Setup(router *mux.Router) {
+	router.Use(h.authorizer)
+}
+
+type firestoreResolver struct {
+	db *db.Firestore
+}
+
+func NewFirestoreResolver(db *db.Firestore) Resolver {
+	return &firestoreResolver{db}
+}
+
+// Resolve uses the custom claims of the token for associates and reads Firestore otherwise.
+func (r *firestoreResolver) Resolve(ctx context.Context, uid string, businessID string) (*Subject, error) {
+	subject := &Subject{UID: uid, BusinessID: businessID}
+	if claims, ok := ctx.Value("claims").(*model.UserClaims); ok && claims.IsCurrent() && claims.Associate {
+		if claims.BusinessID == businessID && !claims.Disabled {
+			subject.Associate = true
+			subject.Roles = model.Roles{
+				Associate:  claims.Associate,
+				Admin:      claims.Admin,
+				SuperAdmin: claims.SuperAdmin,
+			}
+		}
+		return subject, nil
+	}
+	snapshot, err := r.db.User(uid).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var user *model.Associate
+	if err = snapshot.DataTo(&user); err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return subject, nil
+	}
+	if user.Type == model.UserTypeAssociate {
+		if user.BusinessID() == businessID {
+			subject.Associate = true
+			if user.Roles != nil {
+				subject.Roles = *user.Roles
+			}
+		}
+		return subject, nil
+	}
+	customerSnapshot, err := r.db.BusinessCustomer(businessID, uid).Get(ctx)
+	if err == nil && customerSnapshot.Exists() {
+		subject.Customer = true
+	}
+	return subject, nil
+}
+
+// Business uses the custom claims of the token and reads Firestore otherwise.
+func (r *firestoreResolver) Business(ctx context.Context, uid string) (string, error) {
+	if claims, ok := ctx.Value("claims").(*model.UserClaims); ok && claims.IsCurrent() && claims.Associate {
+		return claims.BusinessID, nil
+	}
+	snapshot, err := r.db.User(uid).Get(ctx)
+	if err != nil {
+		return "", err
+	}
+	var user *model.Associate
+	if err = snapshot.DataTo(&user); err != nil {
+		return "", err
+	}
+	return user.BusinessID(), nil
+}
@@ -0,0 +1,221 @@
+package authorizer
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/appointments"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/archive"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/auth"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses/channels"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses/customers"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/cases"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/queue"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/sms"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/vnumbers"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/routes"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/gorilla/mux"
+)
+
+func TestPolicyAllows(t *testing.T) {
+	associate := &Subject{Associate: true}
+	admin := &Subject{Associate: true, Roles: model.Roles{Admin: true}}
+	superAdmin := &Subject{Associate: true, Roles: model.Roles{SuperAdmin: true}}
+	customer := &Subject{Customer: true}
+	stranger := &Subject{}
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		subject *Subject
+		want    bool
+	}{
+		{"associate accepts case", http.MethodPatch, cases.PathCaseAccept, associate, true},
+		{"customer accepts case", http.MethodPatch, cases.PathCaseAccept, customer, false},
+		{"stranger accepts case", http.MethodPatch, cases.PathCaseAccept, stranger, false},
+		{"associate exports archive", http.MethodPost, archive.PathArchiveExport, associate, false},
+		{"admin exports archive", http.MethodPost, archive.PathArchiveExport, admin, true},
+		{"super admin exports archive", http.MethodPost, archive.PathArchiveExport, superAdmin, true},
+		{"customer gets queue position", http.MethodGet, queue.PathCaseQueue, customer, true},
+		{"associate gets queue position", http.MethodGet, queue.PathCaseQueue, associate, false},
+		{"nil subject", http.MethodPatch, cases.PathCaseAccept, nil, false},
+		{"customer cancels appointment", http.MethodPatch, appointments.PathAppointmentCancel, customer, true},
+		{"customer creates appointment", http.MethodPost, appointments.PathAppointments, customer, false},
+		{"stranger requests access", http.MethodPost, businesses.PathRequestBusinessAccess, &Subject{UID: "uid"}, true},
+		{"anonymous requests access", http.MethodPost, businesses.PathRequestBusinessAccess, &Subject{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := routePolicies.Find(tt.method, tt.path)
+			if policy == nil {
+				t.Fatalf("no policy for %s %s", tt.method, tt.path)
+			}
+			if got := policy.Allows(tt.subject); got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type resolverFunc func(ctx context.Context, uid string, businessID string) (*Subject, error)
+
+func (f resolverFunc) Resolve(ctx context.Context, uid string, businessID string) (*Subject, error) {
+	return f(ctx, uid, businessID)
+}
+
+// Business is biz1 for the associates and admins of the tests.
+func (f resolverFunc) Business(_ context.Context, uid string) (string, error) {
+	if uid == "admin" || uid == "associate" {
+		return "biz1", nil
+	}
+	return "", nil
+}
+
+func TestAuthorizer(t *testing.T) {
+	resolver := resolverFunc(func(ctx context.Context, uid string, businessID string) (*Subject, error) {
+		switch {
+		case uid == "failing":
+			return nil, errors.New("lookup failed")
+		case uid == "admin" && businessID == "biz1":
+			return &Subject{UID: uid, BusinessID: businessID, Associate: true, Roles: model.Roles{Admin: true}}, nil
+		case uid == "associate" && businessID == "biz1":
+			return &Subject{UID: uid, BusinessID: businessID, Associate: true}, nil
+		}
+		return &Subject{UID: uid, BusinessID: businessID}, nil
+	})
+	h := &handler{
+		resolver: resolver,
+		policies: Policies{}.
+			Add(http.MethodPost, "/businesses/{business_id}/admin", admin).
+			Add(http.MethodPost, "/businesses/{business_id}/access", authenticated),
+		logger: logger.New(ioutil.Discard, logger.LevelError),
+	}
+	router := mux.NewRouter()
+	ok := func(resp http.ResponseWriter, req *http.Request) {
+		if _, found := req.Context().Value("subject").(*Subject); !found && req.URL.Path == "/businesses/biz1/admin" {
+			t.Error("subject is missing in the context")
+		}
+		resp.WriteHeader(http.StatusOK)
+	}
+	router.HandleFunc("/businesses/{business_id}/admin", ok).Methods(http.MethodPost)
+	router.HandleFunc("/businesses/{business_id}/unannotated", ok).Methods(http.MethodPost)
+	router.HandleFunc("/businesses/{business_id}/access", ok).Methods(http.MethodPost)
+	routes.HandleFunc(router, "/businesses/{business_id}/open", ok).Methods(http.MethodPost).Public()
+	router.HandleFunc("/users/{user_id}", ok).Methods(http.MethodPost)
+	h.Setup(router)
+
+	tests := []struct {
+		name string
+		uid  string
+		path string
+		want int
+	}{
+		{"admin of the business", "admin", "/businesses/biz1/admin", http.StatusOK},
+		{"admin of another business", "admin", "/businesses/biz2/admin", http.StatusForbidden},
+		{"associate", "associate", "/businesses/biz1/admin", http.StatusForbidden},
+		{"unauthenticated", "", "/businesses/biz1/admin", http.StatusForbidden},
+		{"resolver error", "failing", "/businesses/biz1/admin", http.StatusForbidden},
+		{"business route without policy", "admin", "/businesses/biz1/unannotated", http.StatusForbidden},
+		{"public business route", "", "/businesses/biz1/open", http.StatusOK},
+		{"route without policy", "associate", "/users/associate", http.StatusForbidden},
+		{"authenticated route", "stranger", "/businesses/biz1/access", http.StatusOK},
+		{"authenticated route, anonymous", "", "/businesses/biz1/access", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req = req.WithContext(context.WithValue(req.Context(), "uid", tt.uid))
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d", resp.Code, tt.want)
+			}
+		})
+	}
+}
+
+// TestAuthorizerRPC checks the RPC routes, which take the business from the body, are denied
+// to the non-members of the business.
+func TestAuthorizerRPC(t *testing.T) {
+	resolver := resolverFunc(func(ctx context.Context, uid string, businessID string) (*Subject, error) {
+		subject := &Subject{UID: uid, BusinessID: businessID}
+		switch {
+		case uid == "admin" && businessID == "biz1":
+			subject.Associate, subject.Roles = true, model.Roles{Admin: true}
+		case uid == "associate" && businessID == "biz1":
+			subject.Associate = true
+		case uid == "customer" && businessID == "biz1":
+			subject.Customer = true
+		}
+		return subject, nil
+	})
+	h := New(resolver, logger.New(ioutil.Discard, logger.LevelError))
+	router := mux.NewRouter()
+	ok := func(resp http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		if req.Method == http.MethodPost && len(body) == 0 {
+			t.Errorf("%s: the body is not put back for the handler", req.URL.Path)
+		}
+		resp.WriteHeader(http.StatusOK)
+	}
+	for r := range routePolicies {
+		if !strings.Contains(r.Path, "{") {
+			router.HandleFunc(r.Path, ok).Methods(r.Method)
+		}
+	}
+	h.Setup(router)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		member string
+	}{
+		{http.MethodPost, channels.CreateChannel, `{"businessId":"biz1"}`, "associate"},
+		{http.MethodPost, channels.DeleteChannel, `{"BusinessId":"biz1"}`, "associate"},
+		{http.MethodPost, channels.SubscribeChannel, `{"businessId":"biz1"}`, "customer"},
+		{http.MethodPost, customers.PathBlockCustomer, `{"businessId":"biz1"}`, "associate"},
+		{http.MethodPost, customers.PathUnblockCustomer, `{"businessId":"biz1"}`, "associate"},
+		{http.MethodPost, auth.PathInviteLink, `{"bid":"biz1"}`, "admin"},
+		{http.MethodPost, vnumbers.PathBuyNumber, `{"businessId":"biz1"}`, "admin"},
+		{http.MethodPost, vnumbers.PathReleaseNumber, `{"businessId":"biz1"}`, "admin"},
+		{http.MethodGet, vnumbers.PathListNumbers, ``, "admin"},
+		{http.MethodGet, vnumbers.PathSearchNumbers, ``, "admin"},
+		{http.MethodPost, sms.SendSms, `{"text":"hi"}`, "associate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			for uid, want := range map[string]int{
+				tt.member:  http.StatusOK,
+				"stranger": http.StatusForbidden,
+			} {
+				req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+				req = req.WithContext(context.WithValue(req.Context(), "uid", uid))
+				resp := httptest.NewRecorder()
+				router.ServeHTTP(resp, req)
+				if resp.Code != want {
+					t.Errorf("%s: status = %d, want %d", uid, resp.Code, want)
+				}
+			}
+			if len(tt.body) == 0 {
+				return
+			}
+			other := strings.Replace(tt.body, "biz1", "biz2", 1)
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(other))
+			req = req.WithContext(context.WithValue(req.Context(), "uid", tt.member))
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			if want := http.StatusForbidden; other != tt.body && resp.Code != want {
+				t.Errorf("%s in another business: status = %d, want %d", tt.member, resp.Code, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package authorizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/appointments"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/archive"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/associates"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/auth"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses/channels"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses/customers"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/cases"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/configuration"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/distances"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/feedback"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/invites"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/notes"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/queue"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/replies"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/reset"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/sla"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/sms"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/textsessions"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/textsessions/videocalls"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/users"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/vnumbers"
+	"github.com/VinothKuppanna/pigeon-go/internal/validation"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+)
+
+type Role string
+
+const (
+	RoleAssociate  Role = "associate"
+	RoleAdmin      Role = "admin"
+	RoleSuperAdmin Role = "superAdmin"
+	RoleCustomer   Role = "customer" // customer of the business
+)
+
+// A Subject is the caller resolved for the business of the route.
+type Subject struct {
+	UID        string
+	BusinessID string
+	Associate  bool
+	Customer   bool
+	Roles      model.Roles
+}
+
+func (s *Subject) Has(role Role) bool {
+	if s == nil {
+		return false
+	}
+	switch role {
+	case RoleAssociate:
+		return s.Associate
+	case RoleAdmin:
+		return s.Associate && (s.Roles.Admin || s.Roles.SuperAdmin)
+	case RoleSuperAdmin:
+		return s.Associate && s.Roles.SuperAdmin
+	case RoleCustomer:
+		return s.Customer
+	}
+	return false
+}
+
+// A Policy allows the subjects having any of the roles.
+// An authenticated policy allows any signed-in caller without resolving the subject.
+// The business is the {business_id} of the path, unless the policy finds it otherwise.
+type Policy struct {
+	Roles         []Role
+	Authenticated bool
+	Business      BusinessFunc
+}
+
+// A BusinessFunc finds the business of a route without {business_id}, e.g. of an RPC route.
+type BusinessFunc func(req *http.Request, resolver Resolver) (string, error)
+
+var errNoBusiness = errors.New("the request has no business")
+
+// BodyField finds the business in the field of the JSON body. The name is matched
+// case-insensitively, as encoding/json does. The body is put back for the handler.
+func BodyField(name string) BusinessFunc {
+	return func(req *http.Request, _ Resolver) (string, error) {
+		if req.Body == nil {
+			return "", errNoBusiness
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(nil, req.Body, validation.MaxBodySize))
+		_ = req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		var fields map[string]json.RawMessage
+		if err = json.Unmarshal(body, &fields); err != nil {
+			return "", err
+		}
+		for key, value := range fields {
+			var businessID string
+			if strings.EqualFold(key, name) && json.Unmarshal(value, &businessID) == nil && len(businessID) > 0 {
+				return businessID, nil
+			}
+		}
+		return "", errNoBusiness
+	}
+}
+
+// OwnBusiness finds the business of the calling associate, for the routes acting on it implicitly.
+func OwnBusiness(req *http.Request, resolver Resolver) (string, error) {
+	uid, _ := req.Context().Value("uid").(string)
+	return resolver.Business(req.Context(), uid)
+}
+
+// In returns a copy of the policy finding the business with business.
+func (p *Policy) In(business BusinessFunc) *Policy {
+	policy := *p
+	policy.Business = business
+	return &policy
+}
+
+func Require(roles ...Role) *Policy {
+	return &Policy{Roles: roles}
+}
+
+// Authenticated is the policy of the business routes any signed-in user may call,
+// e.g. to request access to the business. The handler checks the caller itself.
+func Authenticated() *Policy {
+	return &Policy{Authenticated: true}
+}
+
+func (p *Policy) Allows(subject *Subject) bool {
+	if p.Authenticated {
+		return subject != nil && len(subject.UID) > 0
+	}
+	for _, role := range p.Roles {
+		if subject.Has(role) {
+			return true
+		}
+	}
+	return false
+}
+
+type route struct {
+	Path   string
+	Method string
+}
+
+// Policies annotates the routes with the required roles.
+// A non-public route without a policy is denied.
+type Policies map[route]*Policy
+
+func (p Policies) Find(method, pathTemplate string) *Policy {
+	return p[route{pathTemplate, method}]
+}
+
+func (p Policies) Add(method, pathTemplate string, policy *Policy) Policies {
+	p[route{pathTemplate, method}] = policy
+	return p
+}
+
+var associate = Require(RoleAssociate)
+var admin = Require(RoleAdmin)
+var customer = Require(RoleCustomer)
+var authenticated = Authenticated()
+
+var routePolicies = Policies{
+	{cases.PathCaseForward, http.MethodPatch}:        associate,
+	{cases.PathCaseAccept, http.MethodPatch}:         associate,
+	{cases.PathCaseReject, http.MethodPatch}:         associate,
+	{cases.PathCaseUnAccept, http.MethodPatch}:       associate,
+	{cases.PathCaseHandoff, http.MethodGet}:          associate,
+	{cases.PathCaseHandoffAccept, http.MethodPatch}:  associate,
+	{cases.PathCaseHandoffDecline, http.MethodPatch}: associate,
+
+	{archive.PathArchiveExport, http.MethodPost}:  admin,
+	{associates.AssociatesPath, http.MethodPost}:  admin,
+	{associates.AssociatePath, http.MethodPatch}:  admin,
+	{associates.AssociatePath, http.MethodDelete}: admin,
+	{invites.InvitesPath, http.MethodPost}:        admin,
+	{invites.InvitePath, http.MethodDelete}:       admin,
+
+	{customers.PathBlockBusinessCustomer, http.MethodPost}:   associate,
+	{customers.PathUnblockBusinessCustomer, http.MethodPost}: associate,
+
+	{sla.PathBusinessSLA, http.MethodGet}: associate,
+	{sla.PathBusinessSLA, http.MethodPut}: admin,
+	{sla.PathCaseSLA, http.MethodGet}:     associate,
+
+	{notes.PathCaseNotes, http.MethodGet}:       associate,
+	{notes.PathCaseNotes, http.MethodPost}:      associate,
+	{notes.PathCaseNote, http.MethodPatch}:      associate,
+	{notes.PathCaseNote, http.MethodDelete}:     associate,
+	{notes.PathCustomerNotes, http.MethodGet}:   associate,
+	{notes.PathCustomerNotes, http.MethodPost}:  associate,
+	{notes.PathCustomerNote, http.MethodPatch}:  associate,
+	{notes.PathCustomerNote, http.MethodDelete}: associate,
+	{replies.PathReplies, http.MethodGet}:       associate,
+	{replies.PathReplies, http.MethodPost}:      associate,
+	{replies.PathReply, http.MethodPatch}:       associate,
+	{replies.PathReply, http.MethodDelete}:      associate,
+	{replies.PathRenderReply, http.MethodPost}:  associate,
+	{queue.PathBusinessQueue, http.MethodGet}:   associate,
+	{queue.PathCaseQueue, http.MethodGet}:       customer,
+
+	{appointments.PathAppointments, http.MethodPost}:       associate,
+	{appointments.PathAppointment, http.MethodPatch}:       associate,
+	{appointments.PathAppointment, http.MethodDelete}:      associate,
+	{appointments.PathAppointmentCancel, http.MethodPatch}: Require(RoleAssociate, RoleCustomer),
+
+	{channels.PathSubscribeChannel, http.MethodPut}:   customer,
+	{channels.PathUnsubscribeChannel, http.MethodPut}: customer,
+	{channels.PathReadChannel, http.MethodPut}:        customer,
+
+	{businesses.PathRequestBusinessAccess, http.MethodPost}: authenticated,
+	{users.PathUserPermissions, http.MethodPost}:            authenticated,
+
+	// RPC routes, the business is in the body.
+	{channels.CreateChannel, http.MethodPost}:        associate.In(BodyField("businessId")),
+	{channels.DeleteChannel, http.MethodPost}:        associate.In(BodyField("businessId")),
+	{channels.SubscribeChannel, http.MethodPost}:     customer.In(BodyField("businessId")),
+	{channels.UnsubscribeChannel, http.MethodPost}:   customer.In(BodyField("businessId")),
+	{channels.ReadChannel, http.MethodPost}:          customer.In(BodyField("businessId")),
+	{customers.PathBlockCustomer, http.MethodPost}:   associate.In(BodyField("businessId")),
+	{customers.PathUnblockCustomer, http.MethodPost}: associate.In(BodyField("businessId")),
+	{auth.PathInviteLink, http.MethodPost}:           admin.In(BodyField("bid")),
+	{vnumbers.PathBuyNumber, http.MethodPost}:        admin.In(BodyField("businessId")),
+	{vnumbers.PathReleaseNumber, http.MethodPost}:    admin.In(BodyField("businessId")),
+	{vnumbers.PathListNumbers, http.MethodGet}:       admin.In(OwnBusiness),
+	{vnumbers.PathSearchNumbers, http.MethodGet}:     admin.In(OwnBusiness),
+	{sms.SendSms, http.MethodPost}:                   associate.In(OwnBusiness),
+
+	// Routes of the caller, the handlers check the caller against the resources.
+	{businesses.RequestBusinessAccess, http.MethodPost}:          authenticated,
+	{businesses.PathBusinessesNearby, http.MethodGet}:            authenticated,
+	{businesses.SearchPlaceByAddress, http.MethodPost}:           authenticated,
+	{businesses.PlaceDetails, http.MethodPost}:                   authenticated,
+	{users.PathUser, http.MethodGet}:                             authenticated,
+	{users.PathUserBlockList, http.MethodPost}:                   authenticated,
+	{users.PathUserBlockListUser, http.MethodDelete}:             authenticated,
+	{users.PathBlockUser, http.MethodPost}:                       authenticated,
+	{users.PathUnblockUser, http.MethodPost}:                     authenticated,
+	{textsessions.PathTourServiceCreateActive, http.MethodPost}:  authenticated,
+	{textsessions.PathChatsServiceCreateActive, http.MethodPost}: authenticated,
+	{textsessions.PathActiveTextSessions, http.MethodPost}:       authenticated,
+	{textsessions.PathInnerTextSessions, http.MethodPost}:        authenticated,
+	{textsessions.PathTextSession, http.MethodPatch}:             authenticated,
+	{textsessions.PathTextSessionMembers, http.MethodPost}:       authenticated,
+	{videocalls.PathChatVideoCalls, http.MethodPost}:             authenticated,
+	{videocalls.PathChatVideoCall, http.MethodPatch}:             authenticated,
+	{videocalls.PathChatVideoCall, http.MethodDelete}:            authenticated,
+	{distances.PathDistance, http.MethodPost}:                    authenticated,
+	{feedback.PathFeedback, http.MethodPost}:                     authenticated,
+	{configuration.PathConfig, http.MethodGet}:                   authenticated,
+
+	// Mounted by the dev profile only.
+	{reset.PathDbReset, http.MethodPost}: Require(RoleSuperAdmin),
+}
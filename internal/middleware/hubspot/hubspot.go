@@ -1,44 +1,46 @@
 package hubspot
 
 import (
-	"log"
-	"net/http"
+	"context"
+	"encoding/json"
 
-	"github.com/VinothKuppanna/pigeon-go/internal/common"
-	"github.com/nsqio/go-nsq"
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/outbox"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
 )
 
+// TopicSignUpEvent messages are SignUps.
 const TopicSignUpEvent = "signup_event"
 
+// A SignUp is the message of a business signed up, synced to HubSpot by pigeon-worker. The
+// password of the request is left out.
+type SignUp struct {
+	ID           string `json:"id"` // to drop the messages delivered twice
+	FullName     string `json:"fullName"`
+	BusinessName string `json:"businessName"`
+	Email        string `json:"email"`
+	UID          string `json:"uid"` // of the owner
+
+	TraceContext map[string]string `json:"traceContext,omitempty"` // of the request, see tracing.Inject
+}
+
+// Handler writes the sign-ups to the outbox.
 type Handler struct {
-	logger    *log.Logger
-	publisher *nsq.Producer
+	outbox *outbox.Outbox
 }
 
-func New(logger *log.Logger, publisher *nsq.Producer) *Handler {
-	return &Handler{logger, publisher}
+func New(outbox *outbox.Outbox) *Handler {
+	return &Handler{outbox}
 }
 
-func (h *Handler) SignUp(next http.HandlerFunc) http.HandlerFunc {
-	return func(resp http.ResponseWriter, req *http.Request) {
-		httpReq := common.HttpRequest(*req)
-		bytes, err := httpReq.BodyWithCopy()
-		if err != nil {
-			h.logger.Println("failed to copy req body.", err)
-			http.Error(resp, err.Error(), http.StatusBadRequest)
-			return
-		}
-		request := http.Request(httpReq)
-		wrapped := common.WrapResponse(resp)
-		next(wrapped, &request)
-		statusCode := wrapped.Status()
-		if statusCode != http.StatusCreated {
-			h.logger.Println("bad status-code:", statusCode)
-			return
-		}
-		err = h.publisher.PublishAsync(TopicSignUpEvent, bytes, nil)
-		if err != nil {
-			h.logger.Println("failed to publish message.", err)
-		}
+// SignedUp writes the message of the sign-up in the transaction creating the business, so it is
+// published if and only if the business is created.
+func (h *Handler) SignedUp(ctx context.Context, tx *firestore.Transaction, signUp SignUp) error {
+	signUp.ID = outbox.NewID()
+	signUp.TraceContext = tracing.Inject(ctx)
+	body, err := json.Marshal(&signUp)
+	if err != nil {
+		return err
 	}
+	return h.outbox.AddToTransaction(tx, signUp.ID, TopicSignUpEvent, body)
 }
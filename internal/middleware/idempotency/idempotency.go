@@ -0,0 +1,207 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/appointments"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/associates"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/cases"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/invites"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/sms"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/gorilla/mux"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	HeaderReplayed       = "Idempotent-Replayed"
+
+	DefaultTTL     = 24 * time.Hour
+	maxKeyLength   = 255
+	StoreFirestore = "firestore"
+
+	// inProgressLease bounds the record of a running request, so the key is not locked
+	// until the ttl when the replica serving the request dies.
+	inProgressLease = time.Minute
+)
+
+type route struct {
+	Path   string
+	Method string
+}
+
+// enabledRoutes accept the Idempotency-Key header.
+var enabledRoutes = map[route]bool{
+	{appointments.PathAppointments, http.MethodPost}: true,
+	{sms.SendSms, http.MethodPost}:                   true,
+	{cases.PathCaseForward, http.MethodPatch}:        true,
+	{cases.PathCaseAccept, http.MethodPatch}:         true,
+	{cases.PathCaseReject, http.MethodPatch}:         true,
+	{cases.PathCaseUnAccept, http.MethodPatch}:       true,
+	{cases.PathCaseHandoffAccept, http.MethodPatch}:  true,
+	{cases.PathCaseHandoffDecline, http.MethodPatch}: true,
+	{invites.InvitesPath, http.MethodPost}:           true,
+	{associates.AssociatesPath, http.MethodPost}:     true,
+}
+
+// NewStore returns the shared Firestore store for the "firestore" kind and the in-memory store otherwise.
+func NewStore(kind string, db *db.Firestore) Store {
+	if kind == StoreFirestore {
+		return NewFirestoreStore(db)
+	}
+	return NewMemoryStore()
+}
+
+type handler struct {
+	store  Store
+	ttl    time.Duration
+	logger *logger.Logger
+}
+
+func New(store Store, ttl time.Duration, logger *logger.Logger) *handler {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &handler{store, ttl, logger}
+}
+
+// idempotent stores the first response per key and caller, and replays it on retries.
+// A key reused with another request body is rejected with 422, a retry of a running request with 409.
+// Server errors and panics are not stored, so the request can be retried with the same key.
+func (h *handler) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		idempotencyKey := req.Header.Get(HeaderIdempotencyKey)
+		if len(idempotencyKey) == 0 || !h.enabled(req) {
+			next.ServeHTTP(resp, req)
+			return
+		}
+		if len(idempotencyKey) > maxKeyLength {
+			apierrors.Respond(resp, apierrors.New(apierrors.CodeInvalidArgument, "Idempotency-Key is too long"))
+			return
+		}
+		body, err := (*common.HttpRequest)(req).BodyWithCopy()
+		if err != nil {
+			apierrors.Respond(resp, apierrors.Wrap(apierrors.CodeInvalidArgument, err))
+			return
+		}
+		ctx := req.Context()
+		uid, _ := ctx.Value("uid").(string)
+		key := hash(uid, req.Method, req.URL.Path, idempotencyKey)
+		record := &Record{
+			RequestHash: hash(string(body)),
+			Expires:     time.Now().Add(inProgressLease),
+		}
+		existing, err := h.store.Begin(ctx, key, record)
+		if err != nil {
+			// fail open, the request is served without the idempotency guarantee
+			h.logger.Error(ctx, "idempotency store error", "error", err)
+			next.ServeHTTP(resp, req)
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.RequestHash != record.RequestHash:
+				apierrors.Respond(resp, apierrors.New(apierrors.CodeUnprocessable, "Idempotency-Key was used with another request"))
+			case !existing.Completed:
+				apierrors.Respond(resp, apierrors.New(apierrors.CodeConflict, "Request with this Idempotency-Key is in progress"))
+			default:
+				if len(existing.ContentType) > 0 {
+					resp.Header().Set("Content-Type", existing.ContentType)
+				}
+				resp.Header().Set(HeaderReplayed, "true")
+				resp.WriteHeader(existing.Status)
+				_, _ = resp.Write(existing.Body)
+			}
+			return
+		}
+
+		// the record is stored even when the client goes away before the response
+		storeCtx := common.WithoutCancel(ctx)
+		defer func() {
+			if p := recover(); p != nil {
+				h.release(storeCtx, key)
+				panic(p)
+			}
+		}()
+		recorder := &responseRecorder{ResponseWriter: resp, status: http.StatusOK}
+		next.ServeHTTP(recorder, req)
+
+		if recorder.status >= http.StatusInternalServerError {
+			h.release(storeCtx, key)
+			return
+		}
+		record.Completed = true
+		record.Status = recorder.status
+		record.ContentType = resp.Header().Get("Content-Type")
+		record.Body = recorder.body.Bytes()
+		record.Expires = time.Now().Add(h.ttl)
+		if err = h.store.Complete(storeCtx, key, record); err != nil {
+			h.logger.Error(ctx, "idempotency store error", "error", err)
+		}
+	})
+}
+
+// release deletes the record, so the request can be retried with the key.
+func (h *handler) release(ctx context.Context, key string) {
+	if err := h.store.Delete(ctx, key); err != nil {
+		h.logger.Error(ctx, "idempotency store error", "error", err)
+	}
+}
+
+func (h *handler) enabled(req *http.Request) bool {
+	currentRoute := mux.CurrentRoute(req)
+	if currentRoute == nil {
+		return false
+	}
+	pathTemplate, err := currentRoute.GetPathTemplate()
+	return err == nil && enabledRoutes[route{pathTemplate, req.Method}]
+}
+
+func hash(values ...string) string {
+	sum := sha256.New()
+	for _, value := range values {
+		sum.Write([]byte(value))
+		sum.Write([]byte{0})
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.status = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) RecordError(err error) {
+	if recorder, ok := r.ResponseWriter.(common.ErrorRecorder); ok {
+		recorder.RecordError(err)
+	}
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
+
+func (h *handler) Setup(router *mux.Router) {
+	router.Use(h.idempotent)
+}
@@ -0,0 +1,97 @@
+package idempotency
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/associates"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/gorilla/mux"
+)
+
+// contextStore fails like the Firestore store does when the context is done.
+type contextStore struct {
+	Store
+}
+
+func (s contextStore) Complete(ctx context.Context, key string, record *Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Store.Complete(ctx, key, record)
+}
+
+func (s contextStore) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Store.Delete(ctx, key)
+}
+
+func TestIdempotent(t *testing.T) {
+	store := contextStore{NewMemoryStore()}
+	h := New(store, time.Hour, logger.New(ioutil.Discard, logger.LevelError))
+	var calls int
+	var handle func(resp http.ResponseWriter, req *http.Request)
+	var disconnect context.CancelFunc
+	router := mux.NewRouter()
+	router.HandleFunc(associates.AssociatesPath, func(resp http.ResponseWriter, req *http.Request) {
+		calls++
+		if disconnect != nil {
+			// the client goes away while the request is served
+			disconnect()
+		}
+		handle(resp, req)
+	}).Methods(http.MethodPost)
+	h.Setup(router)
+	send := func(key string, cancel bool) (code int, panicked bool) {
+		defer func() {
+			panicked = recover() != nil
+		}()
+		req := httptest.NewRequest(http.MethodPost, "/businesses/b1/associates", strings.NewReader(`{}`))
+		req.Header.Set(HeaderIdempotencyKey, key)
+		ctx, done := context.WithCancel(context.WithValue(req.Context(), "uid", "u1"))
+		defer done()
+		disconnect = nil
+		if cancel {
+			disconnect = done
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req.WithContext(ctx))
+		return resp.Code, false
+	}
+
+	handle = func(resp http.ResponseWriter, req *http.Request) { panic("boom") }
+	if _, panicked := send("k1", false); !panicked {
+		t.Fatal("the panic was swallowed")
+	}
+	handle = func(resp http.ResponseWriter, req *http.Request) { resp.WriteHeader(http.StatusCreated) }
+	if code, _ := send("k1", false); code != http.StatusCreated || calls != 2 {
+		t.Fatalf("retry after a panic: status = %d after %d calls, want 201 after 2", code, calls)
+	}
+
+	if code, _ := send("k2", true); code != http.StatusCreated {
+		t.Fatalf("canceled request: status = %d, want 201", code)
+	}
+	if code, _ := send("k2", false); code != http.StatusCreated || calls != 3 {
+		t.Errorf("retry of a canceled request: status = %d after %d calls, want the replay after 3", code, calls)
+	}
+}
+
+func TestInProgressLease(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	running := &Record{RequestHash: "a", Expires: time.Now().Add(50 * time.Millisecond)}
+	if _, err := store.Begin(ctx, "key", running); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if existing, err := store.Begin(ctx, "key", running); err != nil || existing != nil {
+		t.Errorf("Begin() after the lease = %+v, %v, want nil, nil", existing, err)
+	}
+}
@@ -0,0 +1,111 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/patrickmn/go-cache"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const idempotencyKeysCollection = "idempotencyKeys"
+
+// A Record is the first request made with an idempotency key and, once completed, its response.
+type Record struct {
+	RequestHash string    `firestore:"requestHash"`
+	Completed   bool      `firestore:"completed"`
+	Status      int       `firestore:"status"`
+	ContentType string    `firestore:"contentType"`
+	Body        []byte    `firestore:"body"`
+	Expires     time.Time `firestore:"expires"`
+}
+
+var errRecordExists = errors.New("idempotency record exists")
+
+// A Store keeps the idempotency records until they expire.
+type Store interface {
+	// Begin creates the record of the key. It returns the existing record if the key has been used.
+	Begin(ctx context.Context, key string, record *Record) (existing *Record, err error)
+	Complete(ctx context.Context, key string, record *Record) error
+	Delete(ctx context.Context, key string) error
+}
+
+type memoryStore struct {
+	cache *cache.Cache
+}
+
+// NewMemoryStore keeps the records in the process.
+func NewMemoryStore() Store {
+	return &memoryStore{cache.New(DefaultTTL, 10*time.Minute)}
+}
+
+func (s *memoryStore) Begin(_ context.Context, key string, record *Record) (*Record, error) {
+	if err := s.cache.Add(key, record, time.Until(record.Expires)); err != nil {
+		if existing, ok := s.cache.Get(key); ok {
+			return existing.(*Record), nil
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) Complete(_ context.Context, key string, record *Record) error {
+	s.cache.Set(key, record, time.Until(record.Expires))
+	return nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, key string) error {
+	s.cache.Delete(key)
+	return nil
+}
+
+type firestoreStore struct {
+	db *db.Firestore
+}
+
+// NewFirestoreStore keeps the records in Firestore, so retries can land on any replica.
+// The expires field can be used for a TTL policy of the collection.
+func NewFirestoreStore(db *db.Firestore) Store {
+	return &firestoreStore{db}
+}
+
+func (s *firestoreStore) Begin(ctx context.Context, key string, record *Record) (*Record, error) {
+	ref := s.db.Collection(idempotencyKeysCollection).Doc(key)
+	var existing *Record
+	err := s.db.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		existing = nil
+		snapshot, err := t.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			var stored Record
+			if err = snapshot.DataTo(&stored); err != nil {
+				return err
+			}
+			if time.Now().Before(stored.Expires) {
+				existing = &stored
+				return errRecordExists
+			}
+		}
+		return t.Set(ref, record)
+	})
+	if err == errRecordExists {
+		return existing, nil
+	}
+	return nil, err
+}
+
+func (s *firestoreStore) Complete(ctx context.Context, key string, record *Record) error {
+	_, err := s.db.Collection(idempotencyKeysCollection).Doc(key).Set(ctx, record)
+	return err
+}
+
+func (s *firestoreStore) Delete(ctx context.Context, key string) error {
+	_, err := s.db.Collection(idempotencyKeysCollection).Doc(key).Delete(ctx)
+	return err
+}
@@ -0,0 +1,33 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreBegin(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	first := &Record{RequestHash: "a", Expires: time.Now().Add(time.Minute)}
+	if existing, err := store.Begin(ctx, "key", first); err != nil || existing != nil {
+		t.Fatalf("Begin() = %v, %v, want nil, nil", existing, err)
+	}
+	existing, err := store.Begin(ctx, "key", &Record{RequestHash: "b", Expires: time.Now().Add(time.Minute)})
+	if err != nil || existing == nil || existing.RequestHash != "a" || existing.Completed {
+		t.Fatalf("Begin() = %+v, %v, want the running record", existing, err)
+	}
+	completed := &Record{RequestHash: "a", Completed: true, Status: 201, Body: []byte("{}"), Expires: first.Expires}
+	if err = store.Complete(ctx, "key", completed); err != nil {
+		t.Fatal(err)
+	}
+	if existing, _ = store.Begin(ctx, "key", first); existing == nil || !existing.Completed || existing.Status != 201 {
+		t.Errorf("Begin() = %+v, want the completed record", existing)
+	}
+	if err = store.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if existing, _ = store.Begin(ctx, "key", first); existing != nil {
+		t.Errorf("Begin() after Delete() = %+v, want nil", existing)
+	}
+}
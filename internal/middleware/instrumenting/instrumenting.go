@@ -0,0 +1,54 @@
+package instrumenting
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/metrics"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type handler struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func New() *handler {
+	return &handler{metrics.HTTPRequests, metrics.HTTPRequestDuration}
+}
+
+// instrumenting traces the requests and labels their metrics by the route template rather than
+// the path, so /users/{id} is a single series whatever the ID.
+func (h *handler) instrumenting(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		route := "unknown"
+		if currentRoute := mux.CurrentRoute(req); currentRoute != nil {
+			if pathTemplate, err := currentRoute.GetPathTemplate(); err == nil {
+				route = pathTemplate
+			}
+		}
+		req, span := tracing.StartRequest(req, route)
+		wrapped := common.WrapResponse(resp)
+		next.ServeHTTP(wrapped, req)
+		status := wrapped.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		tracing.EndRequest(span, status)
+		statusCode := strconv.Itoa(status)
+		h.requests.WithLabelValues(req.Method, route, statusCode).Inc()
+		h.duration.WithLabelValues(req.Method, route, statusCode).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Setup instruments the routes of the router. The metrics are served on their own listener,
+// see metrics.NewServer. It should be the first middleware, so the others log and publish
+// within the trace of the request.
+func (h *handler) Setup(router *mux.Router) {
+	router.Use(h.instrumenting)
+}
@@ -0,0 +1,56 @@
+package instrumenting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrumentingLabelsRouteTemplate(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	h := &handler{
+		prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests_total", Help: "Requests."},
+			[]string{"method", "route", "status"}),
+		prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration_seconds", Help: "Latency."},
+			[]string{"method", "route", "status"}),
+	}
+	registry.MustRegister(h.requests, h.duration)
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id}", func(resp http.ResponseWriter, req *http.Request) {
+		if mux.Vars(req)["id"] == "missing" {
+			resp.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = resp.Write([]byte("{}"))
+	})
+	router.Use(h.instrumenting)
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(h.requests.WithLabelValues(http.MethodGet, "/users/{id}", "200")); got != 2 {
+		t.Errorf("200 requests = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(h.requests.WithLabelValues(http.MethodGet, "/users/{id}", "404")); got != 1 {
+		t.Errorf("404 requests = %v, want 1", got)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if strings.HasPrefix(label.GetValue(), "/users/") && label.GetValue() != "/users/{id}" {
+					t.Errorf("%s is labelled by the raw path %s", family.GetName(), label.GetValue())
+				}
+			}
+		}
+	}
+}
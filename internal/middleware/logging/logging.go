@@ -1,67 +1,97 @@
 package logging
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"time"
 
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
 	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/outbox"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/gorilla/mux"
-	"github.com/nsqio/go-nsq"
 )
 
 const NSQApiRequestTopic = "api_requests"
 
 type handler struct {
-	publisher *nsq.Producer
-	logger    *log.Logger
-	errors    *log.Logger
+	outbox *outbox.Outbox
+	logger *logger.Logger
 }
 
-func New(publisher *nsq.Producer, logger *log.Logger, errors *log.Logger) *handler {
-	return &handler{publisher, logger, errors}
+func New(outbox *outbox.Outbox, logger *logger.Logger) *handler {
+	return &handler{outbox, logger}
 }
 
+// logging writes an access line per request, and a line with the cause for the error responses.
+// Both are archived to NSQApiRequestTopic too.
 func (h *handler) logging(next http.Handler) http.Handler {
 	fn := func(resp http.ResponseWriter, req *http.Request) {
 		start := time.Now()
 		wrapped := common.WrapResponse(resp)
 		next.ServeHTTP(wrapped, req)
+		ctx := req.Context()
+		duration := time.Since(start)
+		h.logger.Info(ctx, "request served", "method", req.Method, "path", req.URL.Path,
+			"status", wrapped.Status(), "duration", duration)
 		message := fmt.Sprintf("status: %s, method: %s, path: %s, duration: %v",
-			http.StatusText(wrapped.Status()), req.Method, req.RequestURI, time.Since(start))
-		h.logger.Println(message)
-		go h.archiveLog(model.LogEntry{
+			http.StatusText(wrapped.Status()), req.Method, req.RequestURI, duration)
+		h.archiveLog(ctx, model.LogEntry{
 			Topic:     NSQApiRequestTopic,
 			Severity:  "INFO",
 			Message:   message,
 			Component: "api-service",
 		})
-		if len(wrapped.Error()) > 0 {
-			errorMsg := string(wrapped.Error())
-			h.errors.Println(errorMsg)
-			go h.archiveLog(model.LogEntry{
-				Topic:     NSQApiRequestTopic,
-				Severity:  "ERROR",
-				Message:   errorMsg,
-				Component: "api-service",
-			})
+		if len(wrapped.Error()) > 0 || wrapped.Err() != nil {
+			h.logError(ctx, wrapped.Status(), wrapped.Error(), wrapped.Err())
 		}
 	}
 	return http.HandlerFunc(fn)
 }
 
-func (h *handler) archiveLog(entry model.LogEntry) {
-	// publish log message
+// logError logs the server errors as errors and the client errors as warnings.
+func (h *handler) logError(ctx context.Context, status int, body []byte, err error) {
+	level, severity := logger.LevelWarn, "WARNING"
+	if status >= http.StatusInternalServerError {
+		level, severity = logger.LevelError, "ERROR"
+	}
+	keyvals := []interface{}{"status", status}
+	errorMsg := string(body)
+	var apiErr *apierrors.Error
+	if errors.As(err, &apiErr) {
+		errorMsg = apiErr.Message
+		keyvals = append(keyvals, "code", apiErr.Code)
+		if cause := apiErr.Unwrap(); cause != nil {
+			keyvals = append(keyvals, "error", cause)
+		}
+	}
+	h.logger.Log(ctx, level, errorMsg, keyvals...)
+	h.archiveLog(ctx, model.LogEntry{
+		Topic:     NSQApiRequestTopic,
+		Severity:  severity,
+		Message:   errorMsg,
+		Component: "api-service",
+	})
+}
+
+// archiveLog writes the entry to the outbox, to be published to NSQApiRequestTopic. It is written
+// even if the client went away, so the request is always logged.
+func (h *handler) archiveLog(ctx context.Context, entry model.LogEntry) {
+	tracing.StampLogEntry(ctx, &entry)
+	logger.StampLogEntry(ctx, &entry)
+	entry.InsertID = outbox.NewID()
 	bytes, err := json.Marshal(entry)
 	if err != nil {
-		h.errors.Printf("failed to marshal log message. error: %v\n", err)
+		h.logger.Error(ctx, "failed to marshal the log entry", "error", err)
 		return
 	}
-	if err = h.publisher.Publish(NSQApiRequestTopic, bytes); err != nil {
-		h.errors.Printf("failed to publish message, topic=%s. error: %v\n", NSQApiRequestTopic, err)
+	if err = h.outbox.Add(context.Background(), entry.InsertID, NSQApiRequestTopic, bytes); err != nil {
+		h.logger.Error(ctx, "failed to write the log entry to the outbox", "topic", NSQApiRequestTopic, "error", err)
 	}
 }
 
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+type Scope string
+
+const (
+	ScopeIP       Scope = "ip"
+	ScopeUID      Scope = "uid"
+	ScopeBusiness Scope = "business"
+)
+
+// A Limit allows Requests per Period with bursts up to Burst requests.
+type Limit struct {
+	Scope    Scope
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// rate is the number of tokens added per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// A bucket is the token bucket state of a key.
+type bucket struct {
+	Tokens  float64   `firestore:"tokens"`
+	Updated time.Time `firestore:"updated"`
+	Expires time.Time `firestore:"expires"` // the bucket is full again, can be dropped
+}
+
+// take refills the bucket and takes a token. When the bucket is empty it returns the time until the next token.
+func (b bucket) take(limit Limit, now time.Time) (bucket, bool, time.Duration) {
+	if b.Updated.IsZero() {
+		b.Tokens = limit.capacity()
+	} else if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(limit.capacity(), b.Tokens+elapsed*limit.rate())
+	}
+	b.Updated = now
+	b.Expires = now.Add(limit.idle())
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return b, true, 0
+	}
+	wait := (1 - b.Tokens) / limit.rate()
+	return b, false, time.Duration(math.Ceil(wait)) * time.Second
+}
+
+// idle is the time after which an unused bucket is full again.
+func (l Limit) idle() time.Duration {
+	return time.Duration(math.Ceil(l.capacity()/l.rate())) * time.Second
+}
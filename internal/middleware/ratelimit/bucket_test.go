@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketTake(t *testing.T) {
+	limit := Limit{Scope: ScopeIP, Requests: 2, Period: time.Minute}
+	now := time.Date(2021, 1, 6, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		at             time.Duration
+		wantAllowed    bool
+		wantRetryAfter time.Duration
+	}{
+		{"first request", 0, true, 0},
+		{"burst", 0, true, 0},
+		{"empty bucket", time.Second, false, 29 * time.Second},
+		{"refilled token", 31 * time.Second, true, 0},
+		{"empty again", 32 * time.Second, false, 28 * time.Second},
+	}
+	var b bucket
+	for _, tt := range tests {
+		var allowed bool
+		var retryAfter time.Duration
+		b, allowed, retryAfter = b.take(limit, now.Add(tt.at))
+		if allowed != tt.wantAllowed || retryAfter != tt.wantRetryAfter {
+			t.Errorf("%s: take() = %v, %v, want %v, %v", tt.name, allowed, retryAfter, tt.wantAllowed, tt.wantRetryAfter)
+		}
+	}
+}
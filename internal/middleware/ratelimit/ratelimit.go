@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/auth"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/businesses"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/signup"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/sms"
+	"github.com/VinothKuppanna/pigeon-go/internal/endpoints/verification"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type route struct {
+	Path   string
+	Method string
+}
+
+// Limits configures the limits of the routes. A request must pass every limit of its route.
+type Limits map[route][]Limit
+
+func (l Limits) Add(method, pathTemplate string, limits ...Limit) Limits {
+	l[route{pathTemplate, method}] = append(l[route{pathTemplate, method}], limits...)
+	return l
+}
+
+var perIPEmails = []Limit{{Scope: ScopeIP, Requests: 5, Period: time.Hour}}
+var perIPSearch = []Limit{{Scope: ScopeIP, Requests: 60, Period: time.Minute, Burst: 20}}
+
+var routeLimits = Limits{
+	{verification.PathVerifications, http.MethodPost}: perIPEmails,
+	{signup.PathBusinessSignUp, http.MethodPost}:      perIPEmails,
+	{auth.PathResetPassword, http.MethodPost}:         perIPEmails,
+	{auth.PathResetPassword2, http.MethodPost}:        perIPEmails,
+	{sms.SendSms, http.MethodPost}: {
+		{Scope: ScopeIP, Requests: 30, Period: time.Hour},
+		{Scope: ScopeUID, Requests: 20, Period: time.Hour},
+		{Scope: ScopeBusiness, Requests: 200, Period: time.Hour},
+	},
+	{businesses.PathBusinesses, http.MethodGet}:       perIPSearch,
+	{businesses.PathSearchBusinesses, http.MethodGet}: perIPSearch,
+	{businesses.SearchBusinesses, http.MethodPost}:    perIPSearch,
+	{businesses.FindBusiness, http.MethodPost}:        perIPSearch,
+}
+
+type handler struct {
+	store          Store
+	limits         Limits
+	trustedProxies int
+	logger         *logger.Logger
+}
+
+// New limits the requests. trustedProxies is the number of proxies in front of the server
+// which append to X-Forwarded-For, see ClientIP.
+func New(store Store, trustedProxies int, logger *logger.Logger) *handler {
+	return &handler{store, routeLimits, trustedProxies, logger}
+}
+
+// limiter runs after the authenticator, so the uid is known for the authenticated routes.
+func (h *handler) limiter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		currentRoute := mux.CurrentRoute(req)
+		if currentRoute == nil {
+			next.ServeHTTP(resp, req)
+			return
+		}
+		pathTemplate, err := currentRoute.GetPathTemplate()
+		if err != nil {
+			next.ServeHTTP(resp, req)
+			return
+		}
+		var buckets []Bucket
+		for _, limit := range h.limits[route{pathTemplate, req.Method}] {
+			subject := h.subjectOf(req, limit.Scope)
+			if len(subject) == 0 {
+				continue
+			}
+			key := fmt.Sprintf("%s|%s|%s|%s", req.Method, pathTemplate, limit.Scope, subject)
+			buckets = append(buckets, Bucket{key, limit})
+		}
+		if len(buckets) == 0 {
+			next.ServeHTTP(resp, req)
+			return
+		}
+		allowed, retryAfter, err := h.store.Take(req.Context(), buckets, time.Now())
+		if status.Code(err) == codes.Aborted {
+			// the transactions of a burst conflict on its buckets, so the burst is rejected
+			h.logger.Warn(req.Context(), "rate limit store contention", "route", pathTemplate, "error", err)
+			tooManyRequests(resp, time.Second)
+			return
+		}
+		if err != nil {
+			// fail open on an outage of the store, the limiter must not take the API down
+			h.logger.Error(req.Context(), "rate limit store error", "route", pathTemplate, "error", err)
+			next.ServeHTTP(resp, req)
+			return
+		}
+		if !allowed {
+			tooManyRequests(resp, retryAfter)
+			return
+		}
+		next.ServeHTTP(resp, req)
+	})
+}
+
+func (h *handler) subjectOf(req *http.Request, scope Scope) string {
+	switch scope {
+	case ScopeIP:
+		return ClientIP(req, h.trustedProxies)
+	case ScopeUID:
+		uid, _ := req.Context().Value("uid").(string)
+		return uid
+	case ScopeBusiness:
+		if businessID := mux.Vars(req)["business_id"]; len(businessID) > 0 {
+			return businessID
+		}
+		if claims, ok := req.Context().Value("claims").(*model.UserClaims); ok {
+			return claims.BusinessID
+		}
+	}
+	return ""
+}
+
+// ClientIP returns the address the last of the trusted proxies received the request from.
+// Each proxy appends the address of its peer to X-Forwarded-For, so the entries left of those
+// are set by the client and cannot be trusted. Without trusted proxies it is the remote address.
+func ClientIP(req *http.Request, trustedProxies int) string {
+	if forwarded := req.Header.Values("X-Forwarded-For"); trustedProxies > 0 && len(forwarded) > 0 {
+		addresses := strings.Split(strings.Join(forwarded, ","), ",")
+		i := len(addresses) - trustedProxies
+		if i < 0 {
+			i = 0
+		}
+		if address := strings.TrimSpace(addresses[i]); len(address) > 0 {
+			return address
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func tooManyRequests(resp http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(retryAfter / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	resp.Header().Set("Retry-After", strconv.Itoa(seconds))
+	apierrors.Respond(resp, apierrors.Newf(apierrors.CodeRateLimited, "Too many requests. Retry in %d seconds", seconds))
+}
+
+func (h *handler) Setup(router *mux.Router) {
+	router.Use(h.limiter)
+}
@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		forwarded      []string
+		trustedProxies int
+		want           string
+	}{
+		{"no header", nil, 1, "10.0.0.1"},
+		{"no trusted proxies", []string{"1.1.1.1"}, 0, "10.0.0.1"},
+		{"one proxy", []string{"1.1.1.1"}, 1, "1.1.1.1"},
+		{"spoofed by the client", []string{"6.6.6.6, 1.1.1.1"}, 1, "1.1.1.1"},
+		{"load balancer", []string{"6.6.6.6, 1.1.1.1, 35.0.0.1"}, 2, "1.1.1.1"},
+		{"several headers", []string{"6.6.6.6", "1.1.1.1"}, 1, "1.1.1.1"},
+		{"fewer entries than proxies", []string{"1.1.1.1"}, 2, "1.1.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := ClientIP(req, tt.trustedProxies); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterTakesAllOrNothing(t *testing.T) {
+	store := NewMemoryStore()
+	h := &handler{
+		store: store,
+		limits: Limits{}.Add(http.MethodPost, "/businesses/{business_id}/sms",
+			Limit{Scope: ScopeIP, Requests: 2, Period: time.Hour},
+			Limit{Scope: ScopeBusiness, Requests: 1, Period: time.Hour}),
+		trustedProxies: 1,
+		logger:         logger.New(ioutil.Discard, logger.LevelError),
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/businesses/{business_id}/sms", func(resp http.ResponseWriter, req *http.Request) {}).
+		Methods(http.MethodPost)
+	h.Setup(router)
+	send := func(businessID string) int {
+		req := httptest.NewRequest(http.MethodPost, "/businesses/"+businessID+"/sms", nil)
+		req.Header.Set("X-Forwarded-For", "1.1.1.1")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	if code := send("b1"); code != http.StatusOK {
+		t.Fatalf("first request: status = %d, want 200", code)
+	}
+	// the business limit rejects the request, so the IP keeps its second token
+	for i := 0; i < 3; i++ {
+		if code := send("b1"); code != http.StatusTooManyRequests {
+			t.Fatalf("business limit: status = %d, want 429", code)
+		}
+	}
+	if code := send("b2"); code != http.StatusOK {
+		t.Errorf("another business: status = %d, want 200", code)
+	}
+}
+
+type storeFunc func(ctx context.Context, buckets []Bucket, now time.Time) (bool, time.Duration, error)
+
+func (f storeFunc) Take(ctx context.Context, buckets []Bucket, now time.Time) (bool, time.Duration, error) {
+	return f(ctx, buckets, now)
+}
+
+func TestLimiterStoreErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"contention", status.Error(codes.Aborted, "too much contention"), http.StatusTooManyRequests},
+		{"outage", status.Error(codes.Unavailable, "unavailable"), http.StatusOK},
+		{"other error", errors.New("failed"), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &handler{
+				store: storeFunc(func(context.Context, []Bucket, time.Time) (bool, time.Duration, error) {
+					return false, 0, tt.err
+				}),
+				limits:         Limits{}.Add(http.MethodPost, "/sms", Limit{Scope: ScopeIP, Requests: 1, Period: time.Hour}),
+				trustedProxies: 0,
+				logger:         logger.New(ioutil.Discard, logger.LevelError),
+			}
+			router := mux.NewRouter()
+			router.HandleFunc("/sms", func(resp http.ResponseWriter, req *http.Request) {}).Methods(http.MethodPost)
+			h.Setup(router)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/sms", nil))
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d", resp.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayeredStore(t *testing.T) {
+	var shared int
+	store := NewLayeredStore(NewMemoryStore(), storeFunc(func(context.Context, []Bucket, time.Time) (bool, time.Duration, error) {
+		shared++
+		return true, 0, nil
+	}))
+	buckets := []Bucket{{"key", Limit{Scope: ScopeIP, Requests: 2, Period: time.Hour}}}
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		allowed, _, err := store.Take(context.Background(), buckets, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := i < 2; allowed != want {
+			t.Errorf("request %d: allowed = %v, want %v", i, allowed, want)
+		}
+	}
+	if shared != 2 {
+		t.Errorf("shared store takes = %d, want only the requests allowed locally", shared)
+	}
+}
@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const rateLimitsCollection = "rateLimits"
+
+// A Bucket is the token bucket of a limit for one subject.
+type Bucket struct {
+	Key   string
+	Limit Limit
+}
+
+// A Store keeps the token buckets. Take takes a token from every bucket, or from none of them
+// when one is empty, so a rejected request does not use up the other limits of the route.
+type Store interface {
+	Take(ctx context.Context, buckets []Bucket, now time.Time) (allowed bool, retryAfter time.Duration, err error)
+}
+
+const StoreFirestore = "firestore"
+
+// NewStore returns the in-memory store, backed by the shared Firestore store for the "firestore" kind.
+func NewStore(kind string, db *db.Firestore) Store {
+	if kind == StoreFirestore {
+		return NewLayeredStore(NewMemoryStore(), NewFirestoreStore(db))
+	}
+	return NewMemoryStore()
+}
+
+type layeredStore struct {
+	local  Store
+	shared Store
+}
+
+// NewLayeredStore takes the tokens of the local store first, so a burst over the limit is
+// rejected by the replica and does not contend on the shared store. The shared store holds
+// the limits across the replicas and sees only the requests the replicas allowed.
+func NewLayeredStore(local, shared Store) Store {
+	return &layeredStore{local, shared}
+}
+
+func (s *layeredStore) Take(ctx context.Context, buckets []Bucket, now time.Time) (bool, time.Duration, error) {
+	allowed, retryAfter, err := s.local.Take(ctx, buckets, now)
+	if err != nil || !allowed {
+		return allowed, retryAfter, err
+	}
+	return s.shared.Take(ctx, buckets, now)
+}
+
+type memoryStore struct {
+	mutex   sync.Mutex
+	buckets map[string]bucket
+	sweep   time.Time
+}
+
+// NewMemoryStore keeps the buckets in the process. Limits are per replica.
+func NewMemoryStore() Store {
+	return &memoryStore{buckets: make(map[string]bucket)}
+}
+
+func (s *memoryStore) Take(_ context.Context, buckets []Bucket, now time.Time) (bool, time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cleanup(now)
+	next := make([]bucket, len(buckets))
+	for i, b := range buckets {
+		var allowed bool
+		var retryAfter time.Duration
+		next[i], allowed, retryAfter = s.buckets[b.Key].take(b.Limit, now)
+		if !allowed {
+			return false, retryAfter, nil
+		}
+	}
+	for i, b := range buckets {
+		s.buckets[b.Key] = next[i]
+	}
+	return true, 0, nil
+}
+
+// cleanup drops the buckets which are full again, at most once a minute.
+func (s *memoryStore) cleanup(now time.Time) {
+	if now.Sub(s.sweep) < time.Minute {
+		return
+	}
+	s.sweep = now
+	for key, b := range s.buckets {
+		if now.After(b.Expires) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+type firestoreStore struct {
+	db *db.Firestore
+}
+
+// NewFirestoreStore keeps the buckets in Firestore, so the limits hold across replicas.
+// The expires field of the buckets can be used for a TTL policy of the collection.
+func NewFirestoreStore(db *db.Firestore) Store {
+	return &firestoreStore{db}
+}
+
+func (s *firestoreStore) Take(ctx context.Context, buckets []Bucket, now time.Time) (allowed bool, retryAfter time.Duration, err error) {
+	refs := make([]*firestore.DocumentRef, len(buckets))
+	for i, b := range buckets {
+		sum := sha1.Sum([]byte(b.Key))
+		refs[i] = s.db.Collection(rateLimitsCollection).Doc(hex.EncodeToString(sum[:]))
+	}
+	err = s.db.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		allowed, retryAfter = false, 0
+		next := make([]bucket, len(buckets))
+		// all the reads of a transaction go before its writes
+		for i, ref := range refs {
+			var current bucket
+			snapshot, err := t.Get(ref)
+			if err != nil && status.Code(err) != codes.NotFound {
+				return err
+			}
+			if err == nil {
+				if err = snapshot.DataTo(&current); err != nil {
+					return err
+				}
+			}
+			var ok bool
+			next[i], ok, retryAfter = current.take(buckets[i].Limit, now)
+			if !ok {
+				return nil
+			}
+		}
+		for i, ref := range refs {
+			if err := t.Set(ref, next[i]); err != nil {
+				return err
+			}
+		}
+		allowed = true
+		return nil
+	})
+	return
+}
@@ -0,0 +1,71 @@
+// Package requestid correlates the log lines of a request. It takes the request ID from the
+// X-Request-ID header of the request, or generates one, and sends it back in the response.
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/gorilla/mux"
+)
+
+const (
+	Header = "X-Request-ID"
+
+	maxLength = 128
+)
+
+type handler struct {
+	logger *logger.Logger
+}
+
+func New(logger *logger.Logger) *handler {
+	return &handler{logger}
+}
+
+// requestID puts the logger.Request of the request in its context, with the ID, the route
+// template and the business of the route, and the logger for the handlers without one.
+func (h *handler) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(Header)
+		if !valid(id) {
+			id = newID()
+		}
+		resp.Header().Set(Header, id)
+		request := &logger.Request{ID: id}
+		if route := mux.CurrentRoute(req); route != nil {
+			request.Route, _ = route.GetPathTemplate()
+		}
+		if businessID := mux.Vars(req)["business_id"]; len(businessID) > 0 {
+			request.SetBusinessID(businessID)
+		}
+		ctx := logger.NewContext(logger.WithRequest(req.Context(), request), h.logger)
+		next.ServeHTTP(resp, req.WithContext(ctx))
+	})
+}
+
+// valid reports whether the ID of the client is short and printable, so it can be logged as is.
+func valid(id string) bool {
+	if len(id) == 0 || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Setup should come first, so the other middlewares log within the request.
+func (h *handler) Setup(router *mux.Router) {
+	router.Use(h.requestID)
+}
@@ -0,0 +1,45 @@
+package requestid
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/gorilla/mux"
+)
+
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	h := New(logger.New(&buf, logger.LevelInfo))
+	router := mux.NewRouter()
+	router.HandleFunc("/businesses/{business_id}/cases", func(resp http.ResponseWriter, req *http.Request) {
+		logger.FromContext(req.Context()).Info(req.Context(), "handled")
+	})
+	router.Use(h.requestID)
+
+	req := httptest.NewRequest(http.MethodGet, "/businesses/b1/cases", nil)
+	req.Header.Set(Header, "client-id-1")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	if got := resp.Header().Get(Header); got != "client-id-1" {
+		t.Errorf("%s = %q, want the ID of the client", Header, got)
+	}
+	for _, field := range []string{`"requestId":"client-id-1"`, `"route":"/businesses/{business_id}/cases"`, `"businessId":"b1"`} {
+		if !strings.Contains(buf.String(), field) {
+			t.Errorf("got %s, want %s", buf.String(), field)
+		}
+	}
+
+	for _, id := range []string{"", "has spaces", strings.Repeat("a", maxLength+1)} {
+		req = httptest.NewRequest(http.MethodGet, "/businesses/b1/cases", nil)
+		req.Header.Set(Header, id)
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		if got := resp.Header().Get(Header); len(got) != 32 {
+			t.Errorf("%s = %q for %q, want a generated ID", Header, got, id)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package outbox publishes the messages to NSQ reliably. A message is written to the outbox in
+// Firestore, in the same batch or transaction as the state change it reports, so it is kept if
+// and only if the change is. The Relay then publishes the entries of the outbox and deletes them
+// once nsqd acknowledged them. A crash in between publishes an entry again, so the consumers drop
+// the messages they already handled by their ID. The entries are not published in the order they
+// were committed, so the consumers must not depend on it.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/metrics"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+)
+
+// An Entry is a message waiting in the outbox.
+type Entry struct {
+	ID          string    `firestore:"-"` // of the document, and the deduplication ID of the message
+	Topic       string    `firestore:"topic"`
+	Body        []byte    `firestore:"body"`
+	Sequence    int64     `firestore:"sequence"` // the order of publishing
+	CreatedDate time.Time `firestore:"createdDate"`
+	Attempts    int       `firestore:"attempts"` // the failed publishing while nsqd was reachable
+}
+
+// NewID returns a new ID for an entry. The callers put it in the body, for the consumers to
+// drop the messages delivered twice.
+func NewID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+type Outbox struct {
+	db *db.Firestore
+
+	mu   sync.Mutex
+	last int64 // the last sequence
+}
+
+func New(db *db.Firestore) *Outbox {
+	return &Outbox{db: db}
+}
+
+// newEntry returns the entry with the next sequence. The sequences are the creation times in
+// nanoseconds, strictly increasing within the process, so the relay takes the oldest entries
+// first. They are taken before the commit and from the clock of each instance, so they do not
+// order the entries committed by different instances, nor those committed concurrently.
+func (o *Outbox) newEntry(id string, topic string, body []byte) (*firestore.DocumentRef, *Entry) {
+	now := time.Now()
+	o.mu.Lock()
+	sequence := now.UnixNano()
+	if sequence <= o.last {
+		sequence = o.last + 1
+	}
+	o.last = sequence
+	o.mu.Unlock()
+	return o.db.Outbox().Doc(id), &Entry{ID: id, Topic: topic, Body: body, Sequence: sequence, CreatedDate: now}
+}
+
+// AddToBatch writes the message to the outbox when the batch is committed.
+func (o *Outbox) AddToBatch(batch *firestore.WriteBatch, id string, topic string, body []byte) {
+	doc, entry := o.newEntry(id, topic, body)
+	batch.Create(doc, entry)
+}
+
+// AddToTransaction writes the message to the outbox when the transaction is committed.
+func (o *Outbox) AddToTransaction(tx *firestore.Transaction, id string, topic string, body []byte) error {
+	doc, entry := o.newEntry(id, topic, body)
+	return tx.Create(doc, entry)
+}
+
+// Add writes the message to the outbox on its own, for the messages not reporting a change of
+// Firestore.
+func (o *Outbox) Add(ctx context.Context, id string, topic string, body []byte) error {
+	start := time.Now()
+	doc, entry := o.newEntry(id, topic, body)
+	_, err := doc.Create(ctx, entry)
+	metrics.ObserveFirestore("outbox", "Add", start, err)
+	return err
+}
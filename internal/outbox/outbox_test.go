@@ -0,0 +1,181 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/memfirestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+)
+
+func newDB(t *testing.T) *db.Firestore {
+	client, cleanup, err := memfirestore.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cleanup)
+	return &db.Firestore{Client: client}
+}
+
+func newRelay(db *db.Firestore, w io.Writer) *Relay {
+	discard := log.New(io.Discard, "", 0)
+	config := configs.Outbox{Relay: true, Interval: time.Second, BatchSize: 2, LeaseTTL: 30 * time.Second, MaxAttempts: 2}
+	return NewRelay(db, publishing.NewSink(w), config, discard, discard)
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	store := newDB(t)
+	outbox := New(store)
+
+	if err := outbox.Add(ctx, "e1", "topic_a", []byte(`1`)); err != nil {
+		t.Fatal(err)
+	}
+	batch := store.Batch()
+	batch.Set(store.Collection("businesses").Doc("b1"), map[string]interface{}{"name": "Pigeon Post"})
+	outbox.AddToBatch(batch, "e2", "topic_b", []byte(`2`))
+	outbox.AddToBatch(batch, "e3", "topic_a", []byte(`3`))
+	if _, err := batch.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	err := store.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		return outbox.AddToTransaction(tx, "e4", "topic_b", []byte(`4`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// not committed, so never published
+	outbox.AddToBatch(store.Batch(), "e5", "topic_a", []byte(`5`))
+
+	var buf bytes.Buffer
+	relay := newRelay(store, &buf)
+	n, err := relay.Relay(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("relayed %d entries, want 4", n)
+	}
+	want := strings.Join([]string{
+		`{"topic":"topic_a","body":1}`,
+		`{"topic":"topic_b","body":2}`,
+		`{"topic":"topic_a","body":3}`,
+		`{"topic":"topic_b","body":4}`,
+	}, "\n") + "\n"
+	if buf.String() != want {
+		t.Errorf("published:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	pending, err := store.Outbox().Documents(ctx).GetAll()
+	if err != nil || len(pending) > 0 {
+		t.Errorf("pending after the relay: %d, err %v", len(pending), err)
+	}
+}
+
+func TestLease(t *testing.T) {
+	ctx := context.Background()
+	store := newDB(t)
+	outbox := New(store)
+	var first, second bytes.Buffer
+	relay, other := newRelay(store, &first), newRelay(store, &second)
+
+	if _, err := relay.Relay(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.Add(ctx, "e1", "topic_a", []byte(`1`)); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := other.Relay(ctx); err != nil || n > 0 {
+		t.Fatalf("relayed %d entries without the lease, err %v", n, err)
+	}
+
+	// the lease expired
+	other.now = func() time.Time { return time.Now().Add(time.Minute) }
+	if n, err := other.Relay(ctx); err != nil || n != 1 {
+		t.Fatalf("relayed %d entries after the expiry, err %v", n, err)
+	}
+
+	if err := outbox.Add(ctx, "e2", "topic_a", []byte(`2`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.Add(ctx, "e3", "topic_a", []byte(`3`)); err != nil {
+		t.Fatal(err)
+	}
+	// released on drain, so taken over at once
+	if n, err := relay.Relay(ctx); err != nil || n != 1 {
+		t.Fatalf("relayed %d entries after the release, err %v", n, err)
+	}
+	if first.Len() == 0 || second.String() != `{"topic":"topic_a","body":1}`+"\n"+`{"topic":"topic_a","body":2}`+"\n" {
+		t.Errorf("published by the first relay %q, by the second %q", first.String(), second.String())
+	}
+}
+
+// poisonWriter fails the messages of the poison topic.
+type poisonWriter struct {
+	bytes.Buffer
+}
+
+func (w *poisonWriter) Write(p []byte) (int, error) {
+	if bytes.Contains(p, []byte(`"poison"`)) {
+		return 0, errors.New("message rejected")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	store := newDB(t)
+	outbox := New(store)
+	if err := outbox.Add(ctx, "e1", "poison", []byte(`1`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Outbox().Doc("e2").Create(ctx, map[string]interface{}{"topic": 2, "sequence": 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.Add(ctx, "e3", "topic_a", []byte(`3`)); err != nil {
+		t.Fatal(err)
+	}
+
+	var w poisonWriter
+	relay := newRelay(store, &w)
+	for i := 0; i < 2; i++ {
+		if n, err := relay.Relay(ctx); err != nil || n != 1-i {
+			t.Fatalf("relay %d: relayed %d entries, err %v", i, n, err)
+		}
+	}
+	if w.String() != `{"topic":"topic_a","body":3}`+"\n" {
+		t.Errorf("published %q", w.String())
+	}
+	pending, err := store.Outbox().Documents(ctx).GetAll()
+	if err != nil || len(pending) > 0 {
+		t.Errorf("pending after the relay: %d, err %v", len(pending), err)
+	}
+	for _, id := range []string{"e1", "e2"} {
+		snapshot, err := store.OutboxDeadLetters().Doc(id).Get(ctx)
+		if err != nil {
+			t.Fatalf("dead letter %s: %v", id, err)
+		}
+		if cause, _ := snapshot.Data()["error"].(string); len(cause) == 0 {
+			t.Errorf("dead letter %s = %v, want the error", id, snapshot.Data())
+		}
+	}
+	snapshot, err := store.OutboxDeadLetters().Doc("e1").Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry Entry
+	if err = snapshot.DataTo(&entry); err != nil || entry.Topic != "poison" || entry.Attempts != 1 {
+		t.Errorf("dead letter = %+v, err %v, want the entry after its first counted attempt", entry, err)
+	}
+}
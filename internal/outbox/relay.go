@@ -0,0 +1,200 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/metrics"
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const leaseName = "relay"
+
+type lease struct {
+	Holder  string    `firestore:"holder"`
+	Expires time.Time `firestore:"expires"`
+}
+
+// A Relay publishes the entries of the outbox to NSQ. The instances of the API all run one, and
+// a lease in Firestore lets a single one relay at a time, so an entry is not published by two at
+// once. An entry failing to be published while nsqd is unreachable stops the relay until the next
+// poll. One failing while nsqd is reachable, or that cannot be read, is skipped, and moved to the
+// dead letters once it failed config.MaxAttempts times, or at once if it cannot be read.
+type Relay struct {
+	db       *db.Firestore
+	producer *publishing.Producer
+	config   configs.Outbox
+	holder   string // of the lease
+	logger   *log.Logger
+	errors   *log.Logger
+	now      func() time.Time
+}
+
+func NewRelay(db *db.Firestore, producer *publishing.Producer, config configs.Outbox, logger *log.Logger, errors *log.Logger) *Relay {
+	return &Relay{db: db, producer: producer, config: config, holder: NewID(), logger: logger, errors: errors, now: time.Now}
+}
+
+// Run relays the outbox every interval until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Relay(ctx); err != nil && ctx.Err() == nil {
+			r.errors.Printf("failed to relay the outbox. error: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay publishes the pending entries if it holds the lease, or can take it, and returns how
+// many it published.
+func (r *Relay) Relay(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		held, err := r.acquire(ctx)
+		if err != nil || !held {
+			return published, err
+		}
+		n, full, err := r.relayPage(ctx)
+		published += n
+		if err != nil || !full {
+			return published, err
+		}
+	}
+}
+
+// Drain publishes the pending entries, then releases the lease for another instance to take it
+// over without waiting for it to expire. It is called on shutdown, once Run returned.
+func (r *Relay) Drain(ctx context.Context) error {
+	n, err := r.Relay(ctx)
+	if n > 0 {
+		r.logger.Printf("relayed %d outbox entries\n", n)
+	}
+	if releaseErr := r.release(ctx); err == nil {
+		err = releaseErr
+	}
+	return err
+}
+
+// relayPage publishes a page of the oldest entries, and deletes those nsqd acknowledged. full
+// reports whether the page was full, so there may be more entries.
+func (r *Relay) relayPage(ctx context.Context) (published int, full bool, err error) {
+	snapshots, err := r.db.Outbox().OrderBy("sequence", firestore.Asc).Limit(r.config.BatchSize).Documents(ctx).GetAll()
+	if err != nil || len(snapshots) == 0 {
+		return 0, false, err
+	}
+	batch := r.db.Batch()
+	for _, snapshot := range snapshots {
+		var entry Entry
+		if decodeErr := snapshot.DataTo(&entry); decodeErr != nil {
+			if err = r.deadLetter(ctx, snapshot, decodeErr); err != nil {
+				break
+			}
+			continue
+		}
+		if publishErr := r.producer.Publish(entry.Topic, entry.Body); publishErr != nil {
+			metrics.NSQPublishFailures.WithLabelValues(entry.Topic).Inc()
+			if r.producer.Ping() != nil {
+				// nsqd is down, the entry is not at fault
+				err = publishErr
+				break
+			}
+			if err = r.failed(ctx, snapshot, entry, publishErr); err != nil {
+				break
+			}
+			continue
+		}
+		metrics.OutboxRelayed.WithLabelValues(entry.Topic).Inc()
+		batch.Delete(snapshot.Ref)
+		published++
+	}
+	if published > 0 {
+		// the entries are published again if this fails
+		if _, deleteErr := batch.Commit(ctx); err == nil {
+			err = deleteErr
+		}
+	}
+	return published, err == nil && len(snapshots) == r.config.BatchSize, err
+}
+
+// failed counts a failed attempt to publish the entry, and moves it to the dead letters once it
+// failed config.MaxAttempts times.
+func (r *Relay) failed(ctx context.Context, snapshot *firestore.DocumentSnapshot, entry Entry, cause error) error {
+	if entry.Attempts+1 >= r.config.MaxAttempts {
+		return r.deadLetter(ctx, snapshot, cause)
+	}
+	r.errors.Printf("failed to publish the outbox entry %s to %s, attempt %d. error: %v\n", snapshot.Ref.ID, entry.Topic, entry.Attempts+1, cause)
+	_, err := snapshot.Ref.Update(ctx, []firestore.Update{{Path: "attempts", Value: firestore.Increment(1)}})
+	return err
+}
+
+// deadLetter moves the entry to the dead letters, with the error of its last attempt, for it to
+// be inspected and written back to the outbox by hand.
+func (r *Relay) deadLetter(ctx context.Context, snapshot *firestore.DocumentSnapshot, cause error) error {
+	data := snapshot.Data()
+	data["error"] = cause.Error()
+	data["deadLetteredDate"] = r.now()
+	batch := r.db.Batch()
+	batch.Set(r.db.OutboxDeadLetters().Doc(snapshot.Ref.ID), data)
+	batch.Delete(snapshot.Ref)
+	if _, err := batch.Commit(ctx); err != nil {
+		return err
+	}
+	topic, _ := data["topic"].(string)
+	metrics.OutboxDeadLettered.WithLabelValues(topic).Inc()
+	r.errors.Printf("moved the outbox entry %s to the dead letters. error: %v\n", snapshot.Ref.ID, cause)
+	return nil
+}
+
+// acquire takes or renews the lease, unless another relay holds it.
+func (r *Relay) acquire(ctx context.Context) (bool, error) {
+	doc := r.db.OutboxLease(leaseName)
+	var held bool
+	err := r.db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		held = false
+		now := r.now()
+		snapshot, err := tx.Get(doc)
+		if err == nil {
+			var current lease
+			if err = snapshot.DataTo(&current); err != nil {
+				return err
+			}
+			if current.Holder != r.holder && now.Before(current.Expires) {
+				return nil
+			}
+		} else if status.Code(err) != codes.NotFound {
+			return err
+		}
+		held = true
+		return tx.Set(doc, &lease{Holder: r.holder, Expires: now.Add(r.config.LeaseTTL)})
+	})
+	return held, err
+}
+
+func (r *Relay) release(ctx context.Context) error {
+	doc := r.db.OutboxLease(leaseName)
+	return r.db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snapshot, err := tx.Get(doc)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var current lease
+		if err = snapshot.DataTo(&current); err != nil || current.Holder != r.holder {
+			return err
+		}
+		return tx.Delete(doc)
+	})
+}
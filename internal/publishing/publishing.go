@@ -0,0 +1,112 @@
+// Package publishing publishes messages to NSQ and keeps count of those in flight, so the
+// producer can be flushed before it stops. nsq.Producer.Stop fails the pending ones instead.
+package publishing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/nsqio/go-nsq"
+)
+
+type Producer struct {
+	producer *nsq.Producer
+	pending  sync.WaitGroup
+
+	sinkMu sync.Mutex
+	sink   io.Writer
+}
+
+func New(address string, config *nsq.Config) (*Producer, error) {
+	producer, err := nsq.NewProducer(address, config)
+	if err != nil {
+		return nil, err
+	}
+	return &Producer{producer: producer}, nil
+}
+
+// NewSink returns a producer that writes the messages to w instead of nsqd, one JSON object
+// with the topic and the body per line.
+func NewSink(w io.Writer) *Producer {
+	return &Producer{sink: w}
+}
+
+type sinkMessage struct {
+	Topic string          `json:"topic"`
+	Body  json.RawMessage `json:"body"`
+}
+
+func (p *Producer) write(topic string, body []byte) error {
+	message := sinkMessage{Topic: topic, Body: body}
+	if !json.Valid(body) {
+		quoted, _ := json.Marshal(string(body))
+		message.Body = quoted
+	}
+	line, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	p.sinkMu.Lock()
+	defer p.sinkMu.Unlock()
+	_, err = p.sink.Write(append(line, '\n'))
+	return err
+}
+
+// Publish publishes the body and waits for nsqd to acknowledge it.
+func (p *Producer) Publish(topic string, body []byte) error {
+	if p.sink != nil {
+		return p.write(topic, body)
+	}
+	p.pending.Add(1)
+	defer p.pending.Done()
+	return p.producer.Publish(topic, body)
+}
+
+// PublishAsync publishes the body without waiting. The message is in flight until nsqd
+// acknowledges it.
+func (p *Producer) PublishAsync(topic string, body []byte) error {
+	if p.sink != nil {
+		return p.write(topic, body)
+	}
+	done := make(chan *nsq.ProducerTransaction, 1)
+	p.pending.Add(1)
+	if err := p.producer.PublishAsync(topic, body, done); err != nil {
+		p.pending.Done()
+		return err
+	}
+	go func() {
+		<-done
+		p.pending.Done()
+	}()
+	return nil
+}
+
+// Ping checks nsqd is reachable. The sink is always reachable.
+func (p *Producer) Ping() error {
+	if p.sink != nil {
+		return nil
+	}
+	return p.producer.Ping()
+}
+
+// Stop waits for the messages in flight, or for ctx to be done, and then stops the producer.
+// It returns the error of ctx if messages were still in flight.
+func (p *Producer) Stop(ctx context.Context) error {
+	flushed := make(chan struct{})
+	go func() {
+		p.pending.Wait()
+		close(flushed)
+	}()
+	var err error
+	select {
+	case <-flushed:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if p.producer != nil {
+		p.producer.Stop()
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package publishing
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/nsqio/go-nsq"
+)
+
+func newProducer(t *testing.T) *Producer {
+	// an address nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	producer, err := New(address, nsq.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	producer.producer.SetLogger(nil, nsq.LogLevelError)
+	return producer
+}
+
+func TestPublishFailureIsNotInFlight(t *testing.T) {
+	producer := newProducer(t)
+	if err := producer.Publish("api_requests", []byte(`{}`)); err == nil {
+		t.Fatal("Publish without nsqd succeeded")
+	}
+	if err := producer.PublishAsync("emails_requests", []byte(`{}`)); err == nil {
+		t.Fatal("PublishAsync without nsqd succeeded")
+	}
+	if err := producer.Stop(context.Background()); err != nil {
+		t.Errorf("Stop = %v, want no messages in flight", err)
+	}
+}
+
+func TestStopWaitsForMessagesInFlight(t *testing.T) {
+	producer := newProducer(t)
+	producer.pending.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := producer.Stop(ctx); err != context.Canceled {
+		t.Errorf("Stop = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestSink(t *testing.T) {
+	var buf bytes.Buffer
+	producer := NewSink(&buf)
+	if err := producer.Publish("api_requests", []byte(`{"path":"/v1/users"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := producer.PublishAsync("emails_requests", []byte("not json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := producer.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"topic":"api_requests","body":{"path":"/v1/users"}}` + "\n" +
+		`{"topic":"emails_requests","body":"not json"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("sink = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+// Package routes declares the auth requirement of the routes when they are registered.
+package routes
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+var public sync.Map // *mux.Route -> bool
+
+// A Builder registers a route. Routes are authenticated unless marked public.
+type Builder struct {
+	route *mux.Route
+}
+
+func Handle(router *mux.Router, path string, handler http.Handler) *Builder {
+	return &Builder{router.Handle(path, handler)}
+}
+
+func HandleFunc(router *mux.Router, path string, f func(http.ResponseWriter, *http.Request)) *Builder {
+	return &Builder{router.HandleFunc(path, f)}
+}
+
+func (b *Builder) Methods(methods ...string) *Builder {
+	b.route.Methods(methods...)
+	return b
+}
+
+// Public lets the route be called without an ID token.
+func (b *Builder) Public() *Builder {
+	public.Store(b.route, true)
+	return b
+}
+
+func (b *Builder) Route() *mux.Route {
+	return b.route
+}
+
+// IsPublic reports whether the matched route was registered as public.
+func IsPublic(route *mux.Route) bool {
+	if route == nil {
+		return false
+	}
+	_, ok := public.Load(route)
+	return ok
+}
+
+// PublicRoutes lists the public routes of the router as "METHOD path".
+func PublicRoutes(router *mux.Router) []string {
+	var result []string
+	_ = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if !IsPublic(route) {
+			return nil
+		}
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{"ANY"}
+		}
+		result = append(result, fmt.Sprintf("%s %s", strings.Join(methods, ","), path))
+		return nil
+	})
+	sort.Strings(result)
+	return result
+}
+
+// Report logs the public routes. It should be called once all the routes are registered.
+func Report(router *mux.Router, logger *log.Logger) {
+	publicRoutes := PublicRoutes(router)
+	logger.Printf("public routes (%d):\n", len(publicRoutes))
+	for _, route := range publicRoutes {
+		logger.Printf("  %s\n", route)
+	}
+}
@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestPublic(t *testing.T) {
+	router := mux.NewRouter()
+	matched := map[string]bool{}
+	record := func(resp http.ResponseWriter, req *http.Request) {
+		matched[req.URL.Path] = IsPublic(mux.CurrentRoute(req))
+	}
+	HandleFunc(router, "/health", record).Methods(http.MethodGet).Public()
+	Handle(router, "/callbacks/sms", http.HandlerFunc(record)).Methods(http.MethodPost).Public()
+	HandleFunc(router, "/users/{user_id}", record).Methods(http.MethodGet)
+	router.HandleFunc("/feedback", record).Methods(http.MethodPost)
+
+	for _, tt := range []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{http.MethodGet, "/health", true},
+		{http.MethodPost, "/callbacks/sms", true},
+		{http.MethodGet, "/users/u1", false},
+		{http.MethodPost, "/feedback", false},
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+		got, ok := matched[tt.path]
+		if !ok {
+			t.Errorf("%s %s: no route matched", tt.method, tt.path)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s %s: IsPublic() = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+	if IsPublic(nil) {
+		t.Error("IsPublic(nil) = true, want false")
+	}
+
+	want := []string{"GET /health", "POST /callbacks/sms"}
+	if got := PublicRoutes(router); !reflect.DeepEqual(got, want) {
+		t.Errorf("PublicRoutes() = %v, want %v", got, want)
+	}
+
+	var buf bytes.Buffer
+	Report(router, log.New(&buf, "", 0))
+	for _, line := range append([]string{"public routes (2)"}, want...) {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Report() = %q, want it to contain %q", buf.String(), line)
+		}
+	}
+}
@@ -2,10 +2,15 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
 	"log"
-	"os"
 	"sync"
 	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/metrics"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type jobScheduler struct {
@@ -86,7 +91,7 @@ func (js *jobScheduler) processPeriodic(ctx context.Context, job *job, interval
 	for {
 		select {
 		case <-ticker.C:
-			job.task(ctx)
+			js.run(ctx, job, "periodic")
 		case <-ctx.Done():
 			ticker.Stop()
 			js.wg.Done()
@@ -103,7 +108,7 @@ func (js *jobScheduler) processOneShot(ctx context.Context, job *job, duration t
 	for {
 		select {
 		case <-timer.C:
-			job.task(ctx)
+			js.run(ctx, job, "one_shot")
 			js.logger.Println("job done. ID:", job.id)
 			return
 		case <-ctx.Done():
@@ -116,17 +121,26 @@ func (js *jobScheduler) processOneShot(ctx context.Context, job *job, duration t
 	}
 }
 
+func (js *jobScheduler) run(ctx context.Context, job *job, kind string) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "scheduler.job", attribute.String("job.id", fmt.Sprint(job.id)), attribute.String("job.kind", kind))
+	job.task(ctx)
+	span.End()
+	metrics.JobRuns.WithLabelValues(kind).Inc()
+	metrics.JobDuration.WithLabelValues(kind).Observe(time.Since(start).Seconds())
+}
+
 func (js *jobScheduler) NewJob(id interface{}, task func(ctx context.Context)) *job {
 	js.logger.Println("created job:", id)
 	return &job{id, task}
 }
 
-func New(logger *log.Logger) Scheduler {
-	if logger == nil {
-		logger = log.New(os.Stdout, "SCHED", log.LstdFlags)
+func New(l *log.Logger) Scheduler {
+	if l == nil {
+		l = logger.Default().Std(logger.LevelInfo)
 	}
 	return &jobScheduler{
-		logger:                  logger,
+		logger:                  l,
 		wg:                      new(sync.WaitGroup),
 		mappedCancellationsSync: new(sync.Map),
 		cancellations:           make([]context.CancelFunc, 0),
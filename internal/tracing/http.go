@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// A Transport traces the outbound requests and passes the trace context on in their headers.
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(instrumentationName).Start(req.Context(), fmt.Sprintf("HTTP %s %s", req.Method, req.URL.Host),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPMethod(req.Method),
+			semconv.HTTPURL(redactedURL(req)),
+			semconv.NetPeerName(req.URL.Hostname()),
+		))
+	defer span.End()
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+	span.SetAttributes(semconv.HTTPStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
+
+// redactedURL leaves out the query, which carries the API keys of Places and Dynamic Links.
+func redactedURL(req *http.Request) string {
+	u := *req.URL
+	u.RawQuery = ""
+	u.User = nil
+	return u.String()
+}
+
+// StartRequest starts the server span of the request, continuing the trace of the caller if any.
+// The span is named by the route template rather than the path.
+func StartRequest(req *http.Request, route string) (*http.Request, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, fmt.Sprintf("%s %s", req.Method, route),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethod(req.Method),
+			semconv.HTTPRoute(route),
+			semconv.HTTPTarget(req.URL.Path),
+		))
+	return req.WithContext(ctx), span
+}
+
+// EndRequest ends the server span with the status of the response. Server errors fail the span.
+func EndRequest(span trace.Span, status int) {
+	span.SetAttributes(semconv.HTTPStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	span.End()
+}
@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Inject returns the trace context of ctx as the W3C headers, e.g. traceparent.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract returns ctx with the trace context of the headers made by Inject.
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+}
+
+// StampLogEntry writes the trace of ctx into the entry, so the consumer continues the trace
+// and the log line can be found by the trace ID.
+func StampLogEntry(ctx context.Context, entry *model.LogEntry) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return
+	}
+	entry.TraceID = spanContext.TraceID().String()
+	entry.SpanID = spanContext.SpanID().String()
+	entry.TraceContext = Inject(ctx)
+}
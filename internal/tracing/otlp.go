@@ -0,0 +1,36 @@
+//go:build otlp
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// NewOTLPExporter exports to the OTLP/HTTP collector at the endpoint, e.g. the OpenTelemetry
+// Collector, Jaeger or Tempo at http://otel-collector:4318. The exporter uses its own transport,
+// so its requests are not traced.
+func NewOTLPExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	if len(endpoint) == 0 {
+		return nil, fmt.Errorf("otlp exporter: endpoint required")
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || len(u.Host) == 0 {
+		return nil, fmt.Errorf("otlp exporter: invalid endpoint %q", endpoint)
+	}
+	options := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(strings.TrimSuffix(u.Path, "/") + "/v1/traces"),
+		otlptracehttp.WithTimeout(10 * time.Second),
+	}
+	if u.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(context.Background(), options...)
+}
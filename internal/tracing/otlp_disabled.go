@@ -0,0 +1,16 @@
+//go:build !otlp
+
+package tracing
+
+import (
+	"fmt"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// NewOTLPExporter fails: the OTLP exporter of otlp.go is built with the otlp tag, once
+// go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp is required in go.mod. It
+// needs grpc 1.32 or later, which the replace of grpc in go.mod still holds back.
+func NewOTLPExporter(string) (sdktrace.SpanExporter, error) {
+	return nil, fmt.Errorf("otlp exporter: not built in, build with -tags otlp")
+}
@@ -0,0 +1,37 @@
+//go:build otlp
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	exported := make(chan string, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		exported <- req.URL.Path + " " + req.Header.Get("Content-Type")
+	}))
+	defer collector.Close()
+	exporter, err := NewExporter(configs.Tracing{Exporter: ExporterOTLP, Endpoint: collector.URL + "/"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	_, span := provider.Tracer("test").Start(context.Background(), "request")
+	span.End()
+	if err = provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-exported; got != "/v1/traces application/x-protobuf" {
+		t.Errorf("export = %s", got)
+	}
+	if _, err = NewExporter(configs.Tracing{Exporter: ExporterOTLP}, nil); err == nil {
+		t.Error("exporter without an endpoint")
+	}
+}
@@ -0,0 +1,87 @@
+// Package tracing sets up OpenTelemetry and carries the trace context across HTTP and NSQ,
+// so an API request and the async work it causes share one trace.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/VinothKuppanna/pigeon-go"
+
+// The exporters of configs.Tracing.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp" // OTLP/HTTP to configs.Tracing.Endpoint, built with the otlp tag
+)
+
+// Setup installs the tracer provider of the service with the configured exporter and the W3C
+// trace context propagator, and instruments http.DefaultTransport, which the SendGrid, Vonage,
+// Places and Dynamic Links clients use. Shutdown flushes the pending spans.
+// With the none exporter the spans are not recorded, but the trace context is still propagated.
+func Setup(config configs.Tracing, service string) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	http.DefaultTransport = NewTransport(http.DefaultTransport)
+
+	exporter, err := NewExporter(config, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(service)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewExporter returns the exporter of the config, or nil for none. The stdout exporter writes
+// to w.
+func NewExporter(config configs.Tracing, w io.Writer) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(w), stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		return NewOTLPExporter(config.Endpoint)
+	}
+	return nil, fmt.Errorf("unknown tracing exporter %q", config.Exporter)
+}
+
+// Start starts a span as a child of the span of ctx, if any.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End records err, if any, on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
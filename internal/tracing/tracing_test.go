@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+func TestNewExporter(t *testing.T) {
+	if exporter, err := NewExporter(configs.Tracing{Exporter: ExporterNone}, nil); exporter != nil || err != nil {
+		t.Errorf("none exporter = %v, %v", exporter, err)
+	}
+	if _, err := NewExporter(configs.Tracing{Exporter: "zipkin"}, nil); err == nil {
+		t.Error("unknown exporter")
+	}
+	var buf bytes.Buffer
+	exporter, err := NewExporter(configs.Tracing{Exporter: ExporterStdout}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	_, span := provider.Tracer("test").Start(context.Background(), "request")
+	span.End()
+	if err = provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"Name": "request"`) {
+		t.Errorf("stdout = %s, want the span", buf.String())
+	}
+}
+
+func TestStampLogEntry(t *testing.T) {
+	setupRecorder(t)
+	var entry model.LogEntry
+	StampLogEntry(context.Background(), &entry)
+	if len(entry.TraceID) > 0 || entry.TraceContext != nil {
+		t.Errorf("entry without a span = %+v", entry)
+	}
+	ctx, span := Start(context.Background(), "request")
+	defer span.End()
+	StampLogEntry(ctx, &entry)
+	if entry.TraceID != span.SpanContext().TraceID().String() || len(entry.TraceContext["traceparent"]) == 0 {
+		t.Errorf("entry = %+v", entry)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	recorder := setupRecorder(t)
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		traceparent = req.Header.Get("traceparent")
+		resp.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	ctx, span := Start(context.Background(), "request")
+	client := &http.Client{Transport: NewTransport(nil)}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/v1/shortLinks?key=secret", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	span.End()
+
+	if len(traceparent) == 0 {
+		t.Error("traceparent header was not sent")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended spans = %d, want 2", len(spans))
+	}
+	clientSpan := spans[0]
+	if clientSpan.Parent().SpanID() != span.SpanContext().SpanID() {
+		t.Error("client span is not a child of the request span")
+	}
+	for _, attribute := range clientSpan.Attributes() {
+		if attribute.Key == "http.url" && attribute.Value.AsString() != server.URL+"/v1/shortLinks" {
+			t.Errorf("http.url = %s, want it without the query", attribute.Value.AsString())
+		}
+	}
+	if clientSpan.Status().Code.String() != "Error" {
+		t.Errorf("status = %v, want Error", clientSpan.Status())
+	}
+}
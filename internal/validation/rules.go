@@ -0,0 +1,227 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var e164 = regexp.MustCompile(`^\+[1-9]\d{1,14}$`)
+
+var builtinRules = map[string]Rule{
+	"required":         required,
+	"required_without": requiredWithout,
+	"email":            email,
+	"e164":             phone,
+	"url":              absoluteURL,
+	"min":              atLeast,
+	"max":              atMost,
+	"oneof":            oneOf,
+	"gtfield":          greaterThanField,
+}
+
+// builtinParams check the parameters of the built-in rules, for the struct type and the type of the field.
+var builtinParams = map[string]func(parent reflect.Type, fieldType reflect.Type, param string) error{
+	"required_without": fieldParam,
+	"min":              numberParam,
+	"max":              numberParam,
+	"gtfield":          comparableFieldParam,
+}
+
+func fieldParam(parent reflect.Type, _ reflect.Type, param string) error {
+	if _, ok := parent.FieldByName(param); !ok {
+		return fmt.Errorf("unknown field %s", param)
+	}
+	return nil
+}
+
+// comparableFieldParam requires the other field to be a time if the field is, or a number if the field is.
+func comparableFieldParam(parent reflect.Type, fieldType reflect.Type, param string) error {
+	other, ok := parent.FieldByName(param)
+	if !ok {
+		return fmt.Errorf("unknown field %s", param)
+	}
+	otherType := indirectType(other.Type)
+	switch {
+	case fieldType == timeType || otherType == timeType:
+		if fieldType != otherType {
+			return fmt.Errorf("%s is not comparable with field %s of %s", fieldType, param, otherType)
+		}
+	case !isNumber(fieldType.Kind()) || !isNumber(otherType.Kind()):
+		return fmt.Errorf("%s is not comparable with field %s of %s", fieldType, param, otherType)
+	}
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func numberParam(_ reflect.Type, _ reflect.Type, param string) error {
+	_, err := strconv.ParseFloat(param, 64)
+	return err
+}
+
+func required(_ context.Context, field Field, _ string) string {
+	if isZero(field.Value) || (field.Value.Kind() == reflect.String && len(strings.TrimSpace(field.Value.String())) == 0) {
+		return "is required"
+	}
+	return ""
+}
+
+// requiredWithout requires the field when the other field of the struct, named as in Go, is not set.
+func requiredWithout(ctx context.Context, field Field, param string) string {
+	if !isZero(indirect(field.Parent.FieldByName(param))) {
+		return ""
+	}
+	if message := required(ctx, field, ""); len(message) > 0 {
+		return fmt.Sprintf("is required when %s is not set", jsonNameOf(field.Parent.Type(), param))
+	}
+	return ""
+}
+
+func email(_ context.Context, field Field, _ string) string {
+	address, err := mail.ParseAddress(field.Value.String())
+	if err != nil || address.Address != field.Value.String() {
+		return "must be an email address"
+	}
+	return ""
+}
+
+func phone(_ context.Context, field Field, _ string) string {
+	if !e164.MatchString(field.Value.String()) {
+		return "must be a phone number in E.164 format"
+	}
+	return ""
+}
+
+func absoluteURL(_ context.Context, field Field, _ string) string {
+	u, err := url.Parse(field.Value.String())
+	if err != nil || !u.IsAbs() || len(u.Host) == 0 {
+		return "must be an absolute URL"
+	}
+	return ""
+}
+
+// atLeast is the minimal length of strings and slices, and the minimal value of numbers.
+func atLeast(_ context.Context, field Field, param string) string {
+	limit, _ := strconv.ParseFloat(param, 64)
+	if size, unit := sizeOf(field.Value); size < limit {
+		return strings.TrimSpace(fmt.Sprintf("must be at least %s %s", param, unit))
+	}
+	return ""
+}
+
+// atMost is the maximal length of strings and slices, and the maximal value of numbers.
+func atMost(_ context.Context, field Field, param string) string {
+	limit, _ := strconv.ParseFloat(param, 64)
+	if size, unit := sizeOf(field.Value); size > limit {
+		return strings.TrimSpace(fmt.Sprintf("must be at most %s %s", param, unit))
+	}
+	return ""
+}
+
+// oneOf takes the space separated allowed values.
+func oneOf(_ context.Context, field Field, param string) string {
+	value := fmt.Sprint(field.Value.Interface())
+	for _, allowed := range strings.Fields(param) {
+		if value == allowed {
+			return ""
+		}
+	}
+	return fmt.Sprintf("must be one of %s", strings.Join(strings.Fields(param), ", "))
+}
+
+// greaterThanField compares times and numbers with another field of the struct, named as in Go.
+// An unset other field is left to its own rules.
+func greaterThanField(_ context.Context, field Field, param string) string {
+	other := indirect(field.Parent.FieldByName(param))
+	if isZero(other) {
+		return ""
+	}
+	otherName := jsonNameOf(field.Parent.Type(), param)
+	if t, ok := field.Value.Interface().(time.Time); ok {
+		if otherTime, ok := other.Interface().(time.Time); !ok || !t.After(otherTime) {
+			return fmt.Sprintf("must be after %s", otherName)
+		}
+		return ""
+	}
+	value, _ := sizeOf(field.Value)
+	otherValue, _ := sizeOf(other)
+	if value <= otherValue {
+		return fmt.Sprintf("must be greater than %s", otherName)
+	}
+	return ""
+}
+
+// Exists returns the rule checking that the document with the ID of the field exists.
+// The parameter of the rule is the collection, e.g. `validate:"exists=users"`.
+func Exists(db *db.Firestore) Rule {
+	return func(ctx context.Context, field Field, collection string) string {
+		_, err := db.Collection(collection).Doc(field.Value.String()).Get(ctx)
+		return existence(ctx, field, err)
+	}
+}
+
+// Found returns the rule checking that find finds the ID of the field, for the documents read
+// through a repository.
+func Found(find func(ctx context.Context, id string) error) Rule {
+	return func(ctx context.Context, field Field, _ string) string {
+		return existence(ctx, field, find(ctx, field.Value.String()))
+	}
+}
+
+// existence logs the errors other than NotFound, their messages are not for the clients.
+func existence(ctx context.Context, field Field, err error) string {
+	switch status.Code(err) {
+	case codes.OK:
+		return ""
+	case codes.NotFound:
+		return "does not exist"
+	}
+	logger.FromContext(ctx).Error(ctx, "failed to check the existence", "field", field.Name, "error", err)
+	return "could not be checked"
+}
+
+// sizeOf returns the length of strings, slices and maps with its unit, or the value of numbers.
+func sizeOf(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(len([]rune(value.String()))), "characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), "items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	}
+	return 0, ""
+}
+
+func jsonNameOf(structType reflect.Type, fieldName string) string {
+	if field, ok := structType.FieldByName(fieldName); ok {
+		return jsonName(field)
+	}
+	return fieldName
+}
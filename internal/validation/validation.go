@@ -0,0 +1,351 @@
+// Package validation decodes the request payloads and validates them by the `validate` struct tags.
+//
+// The tag is a comma separated list of rules, e.g. `validate:"required,e164"`. A rule takes its
+// parameter after '=': `validate:"max=160"`, `validate:"gtfield=StartDate"`, `validate:"exists=users"`.
+// The rules after dive apply to the items of a slice: `validate:"required,dive,e164"`.
+// The rules except the required ones skip zero values, so optional fields are validated only when set.
+// Nested structs and slices of structs are validated too.
+// A bad tag fails the validation of its type with an internal error, see Validator.Check.
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+)
+
+// MaxBodySize caps the request payloads.
+const MaxBodySize = 1 << 20
+
+// A Field is the value a rule validates.
+type Field struct {
+	Name   string        // JSON name of the field
+	Value  reflect.Value // value of the field, pointers are dereferenced
+	Parent reflect.Value // struct of the field, for the cross-field rules
+}
+
+// A Rule returns the message of the violation, or an empty string if the value is valid.
+type Rule func(ctx context.Context, field Field, param string) string
+
+// A Validator validates structs by their tags with the built-in and the registered rules.
+// The tags of a type are checked once, before its first validation.
+type Validator struct {
+	rules   map[string]Rule
+	checked sync.Map // of reflect.Type to checkResult
+}
+
+type checkResult struct {
+	err error
+}
+
+func New() *Validator {
+	v := &Validator{rules: map[string]Rule{}}
+	for name, rule := range builtinRules {
+		v.rules[name] = rule
+	}
+	return v
+}
+
+// Register adds the rule under the name. It replaces a rule of the same name.
+// Rules are registered before the first validation.
+func (v *Validator) Register(name string, rule Rule) *Validator {
+	v.rules[name] = rule
+	return v
+}
+
+// Check checks the tags of the struct s points to: the rules must be known, their parameters
+// valid and dive used on slices only. Struct checks the tags too, so Check only reports a bad
+// tag earlier, e.g. in a test.
+func (v *Validator) Check(s interface{}) error {
+	t := reflect.TypeOf(s)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("validation: %v is not a struct", t)
+	}
+	return v.check(t)
+}
+
+func (v *Validator) check(t reflect.Type) error {
+	if result, ok := v.checked.Load(t); ok {
+		return result.(checkResult).err
+	}
+	err := v.checkStruct(t, "", map[reflect.Type]bool{})
+	v.checked.Store(t, checkResult{err})
+	return err
+}
+
+// Decode reads the JSON body of the request into dst and validates it.
+// dst must be a pointer to a struct, or to a slice of structs for a JSON array.
+// The errors are *apierrors.Error.
+func (v *Validator) Decode(resp http.ResponseWriter, req *http.Request, dst interface{}) error {
+	body := http.MaxBytesReader(resp, req.Body, MaxBodySize)
+	defer body.Close()
+	if err := json.NewDecoder(body).Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	return v.Struct(req.Context(), dst)
+}
+
+// Struct validates the struct s points to, through any number of pointers.
+// The structs of a slice are validated each, their fields are named after the index, e.g. "[0].email".
+func (v *Validator) Struct(ctx context.Context, s interface{}) error {
+	value := indirect(reflect.ValueOf(s))
+	structType := reflect.Type(nil)
+	switch value.Kind() {
+	case reflect.Struct:
+		structType = value.Type()
+	case reflect.Slice:
+		if itemType := indirectType(value.Type().Elem()); itemType.Kind() == reflect.Struct {
+			structType = itemType
+		}
+	}
+	if structType == nil {
+		return apierrors.New(apierrors.CodeInvalidArgument, "request body must be a JSON object")
+	}
+	if err := v.check(structType); err != nil {
+		return apierrors.Wrap(apierrors.CodeInternal, err)
+	}
+	var details []apierrors.FieldError
+	if value.Kind() == reflect.Slice {
+		for i := 0; i < value.Len(); i++ {
+			item := indirect(value.Index(i))
+			if !item.IsValid() {
+				details = append(details, apierrors.FieldError{Field: fmt.Sprintf("[%d]", i), Message: "is required"})
+				continue
+			}
+			v.validateStruct(ctx, item, fmt.Sprintf("[%d].", i), &details)
+		}
+	} else {
+		v.validateStruct(ctx, value, "", &details)
+	}
+	if len(details) > 0 {
+		return apierrors.Validation(details...)
+	}
+	return nil
+}
+
+func (v *Validator) validateStruct(ctx context.Context, value reflect.Value, prefix string, details *[]apierrors.FieldError) {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		structField := valueType.Field(i)
+		if len(structField.PkgPath) > 0 && !structField.Anonymous {
+			continue // unexported
+		}
+		name := jsonName(structField)
+		if name == "-" {
+			continue
+		}
+		field := Field{Name: prefix + name, Value: value.Field(i), Parent: value}
+		if structField.Anonymous {
+			field.Name = strings.TrimSuffix(prefix, ".")
+		}
+		if tag, ok := structField.Tag.Lookup("validate"); ok {
+			if fieldErrors := v.validateField(ctx, field, tag); len(fieldErrors) > 0 {
+				*details = append(*details, fieldErrors...)
+				continue
+			}
+		}
+		v.validateNested(ctx, field, details)
+	}
+}
+
+func (v *Validator) validateNested(ctx context.Context, field Field, details *[]apierrors.FieldError) {
+	value := indirect(field.Value)
+	prefix := field.Name
+	if len(prefix) > 0 {
+		prefix += "."
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		v.validateStruct(ctx, value, prefix, details)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			item := indirect(value.Index(i))
+			if item.Kind() == reflect.Struct {
+				v.validateStruct(ctx, item, fmt.Sprintf("%s[%d].", field.Name, i), details)
+			}
+		}
+	}
+}
+
+// validateField runs the rules of the tag until the first violation.
+// The rules after dive are run for each item of a slice.
+func (v *Validator) validateField(ctx context.Context, field Field, tag string) []apierrors.FieldError {
+	field.Value = indirect(field.Value)
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		name, param := rule, ""
+		if j := strings.Index(rule, "="); j >= 0 {
+			name, param = rule[:j], rule[j+1:]
+		}
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(name, "required") && isZero(field.Value) {
+			return nil
+		}
+		if name == "dive" {
+			return v.validateItems(ctx, field, strings.Join(rules[i+1:], ","))
+		}
+		check, ok := v.rules[name]
+		if !ok {
+			continue // the tags of the type are checked before
+		}
+		if message := check(ctx, field, param); len(message) > 0 {
+			return []apierrors.FieldError{{Field: field.Name, Message: message}}
+		}
+	}
+	return nil
+}
+
+func (v *Validator) validateItems(ctx context.Context, field Field, tag string) []apierrors.FieldError {
+	if kind := field.Value.Kind(); kind != reflect.Slice && kind != reflect.Array {
+		return nil
+	}
+	var fieldErrors []apierrors.FieldError
+	for i := 0; i < field.Value.Len(); i++ {
+		item := Field{Name: fmt.Sprintf("%s[%d]", field.Name, i), Value: field.Value.Index(i), Parent: field.Parent}
+		fieldErrors = append(fieldErrors, v.validateField(ctx, item, tag)...)
+	}
+	return fieldErrors
+}
+
+func (v *Validator) checkStruct(t reflect.Type, prefix string, seen map[reflect.Type]bool) error {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if len(structField.PkgPath) > 0 && !structField.Anonymous {
+			continue // unexported
+		}
+		name := prefix + jsonName(structField)
+		if tag, ok := structField.Tag.Lookup("validate"); ok {
+			if err := v.checkTag(t, structField.Type, name, tag); err != nil {
+				return err
+			}
+		}
+		fieldType := indirectType(structField.Type)
+		if kind := fieldType.Kind(); kind == reflect.Slice || kind == reflect.Array {
+			fieldType = indirectType(fieldType.Elem())
+		}
+		if fieldType.Kind() == reflect.Struct {
+			if err := v.checkStruct(fieldType, name+".", seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *Validator) checkTag(parent reflect.Type, fieldType reflect.Type, name string, tag string) error {
+	fieldType = indirectType(fieldType)
+	for _, rule := range strings.Split(tag, ",") {
+		ruleName, param := rule, ""
+		if j := strings.Index(rule, "="); j >= 0 {
+			ruleName, param = rule[:j], rule[j+1:]
+		}
+		ruleName = strings.TrimSpace(ruleName)
+		switch {
+		case len(ruleName) == 0:
+			continue
+		case ruleName == "dive":
+			if kind := fieldType.Kind(); kind != reflect.Slice && kind != reflect.Array {
+				return fmt.Errorf("validation: dive into %s field %s of %s", kind, name, parent)
+			}
+			fieldType = indirectType(fieldType.Elem())
+			continue
+		}
+		if _, ok := v.rules[ruleName]; !ok {
+			return fmt.Errorf("validation: unknown rule %q of field %s of %s", ruleName, name, parent)
+		}
+		if checkParam, ok := builtinParams[ruleName]; ok {
+			if err := checkParam(parent, fieldType, param); err != nil {
+				return fmt.Errorf("validation: bad %s of field %s of %s: %v", ruleName, name, parent, err)
+			}
+		}
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return apierrors.New(apierrors.CodeInvalidArgument, "request body must not be empty")
+	case err.Error() == "http: request body too large":
+		return apierrors.Newf(apierrors.CodePayloadTooLarge, "request body must not be larger than %d bytes", MaxBodySize)
+	case errors.As(err, &syntaxErr):
+		return apierrors.Newf(apierrors.CodeInvalidArgument, "malformed JSON at position %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		return apierrors.Validation(apierrors.FieldError{Field: typeErr.Field, Message: "must be " + typeErr.Type.String()})
+	}
+	return apierrors.Wrap(apierrors.CodeInvalidArgument, err)
+}
+
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if len(name) == 0 {
+		return field.Name
+	}
+	return name
+}
+
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isZero treats empty slices and maps as unset, like nil ones.
+func isZero(value reflect.Value) bool {
+	if !value.IsValid() {
+		return true
+	}
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+var defaultValidator = New()
+
+// Register adds the rule to the default validator. Rules are registered on init.
+func Register(name string, rule Rule) {
+	defaultValidator.Register(name, rule)
+}
+
+// Decode decodes and validates with the default validator.
+func Decode(resp http.ResponseWriter, req *http.Request, dst interface{}) error {
+	return defaultValidator.Decode(resp, req, dst)
+}
+
+// Struct validates with the default validator.
+func Struct(ctx context.Context, s interface{}) error {
+	return defaultValidator.Struct(ctx, s)
+}
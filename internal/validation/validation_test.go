@@ -0,0 +1,205 @@
+package validation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/apierrors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type appointmentRequest struct {
+	CustomerId string     `json:"customerId" validate:"required"`
+	Email      string     `json:"email" validate:"email"`
+	Phones     []string   `json:"phones" validate:"dive,e164"`
+	StartDate  *time.Time `json:"startDate" validate:"required"`
+	EndDate    *time.Time `json:"endDate" validate:"required,gtfield=StartDate"`
+	Reminder   *struct {
+		Minutes int `json:"minutes" validate:"min=5,max=60"`
+	} `json:"reminder"`
+}
+
+func TestStruct(t *testing.T) {
+	start := time.Date(2021, 1, 6, 10, 0, 0, 0, time.UTC)
+	before := start.Add(-time.Hour)
+	tests := []struct {
+		name    string
+		request appointmentRequest
+		want    []apierrors.FieldError
+	}{
+		{
+			name:    "valid",
+			request: appointmentRequest{CustomerId: "c", Email: "a@b.co", Phones: []string{"+15005550006"}, StartDate: &start, EndDate: &[]time.Time{start.Add(time.Hour)}[0]},
+		},
+		{
+			name:    "missing fields",
+			request: appointmentRequest{CustomerId: "  "},
+			want: []apierrors.FieldError{
+				{Field: "customerId", Message: "is required"},
+				{Field: "startDate", Message: "is required"},
+				{Field: "endDate", Message: "is required"},
+			},
+		},
+		{
+			name: "bad values",
+			request: appointmentRequest{CustomerId: "c", Email: "Bob <bob@b.co>", Phones: []string{"+15005550006", "5005550006"}, StartDate: &start, EndDate: &before,
+				Reminder: &struct {
+					Minutes int `json:"minutes" validate:"min=5,max=60"`
+				}{Minutes: 90}},
+			want: []apierrors.FieldError{
+				{Field: "email", Message: "must be an email address"},
+				{Field: "phones[1]", Message: "must be a phone number in E.164 format"},
+				{Field: "endDate", Message: "must be after startDate"},
+				{Field: "reminder.minutes", Message: "must be at most 60"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		err := Struct(context.Background(), &tt.request)
+		var got []apierrors.FieldError
+		if err != nil {
+			apiErr := err.(*apierrors.Error)
+			if apiErr.Code != apierrors.CodeValidationFailed {
+				t.Errorf("%s: code = %s, want %s", tt.name, apiErr.Code, apierrors.CodeValidationFailed)
+			}
+			got = apiErr.Details
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: details = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want apierrors.Code
+	}{
+		{"empty body", "", apierrors.CodeInvalidArgument},
+		{"malformed", `{"customerId":`, apierrors.CodeInvalidArgument},
+		{"wrong type", `{"customerId":1}`, apierrors.CodeValidationFailed},
+		{"null", `null`, apierrors.CodeValidationFailed},
+		{"too large", `{"customerId":"` + strings.Repeat("a", MaxBodySize) + `"}`, apierrors.CodePayloadTooLarge},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+		var request appointmentRequest
+		err := Decode(httptest.NewRecorder(), req, &request)
+		if got := apierrors.CodeOf(err); got != tt.want {
+			t.Errorf("%s: Decode() code = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRequiredWithout(t *testing.T) {
+	type inner struct {
+		ToId  string   `json:"toId" validate:"required_without=ToIds"`
+		ToIds []string `json:"toIds"`
+	}
+	if err := Struct(context.Background(), &inner{ToIds: []string{"a"}}); err != nil {
+		t.Errorf("Struct() = %v, want nil", err)
+	}
+	err := Struct(context.Background(), &inner{ToIds: []string{}})
+	want := []apierrors.FieldError{{Field: "toId", Message: "is required when toIds is not set"}}
+	if err == nil || !reflect.DeepEqual(err.(*apierrors.Error).Details, want) {
+		t.Errorf("Struct() = %v, want %+v", err, want)
+	}
+}
+
+func TestFound(t *testing.T) {
+	type request struct {
+		CustomerId string `json:"customerId" validate:"required,customer"`
+	}
+	validator := New().Register("customer", Found(func(_ context.Context, id string) error {
+		if id != "u1" {
+			return status.Errorf(codes.NotFound, "users/%s not found", id)
+		}
+		return nil
+	}))
+	if err := validator.Struct(context.Background(), &request{CustomerId: "u1"}); err != nil {
+		t.Errorf("Struct() = %v, want nil", err)
+	}
+	err := validator.Struct(context.Background(), &request{CustomerId: "u2"})
+	want := []apierrors.FieldError{{Field: "customerId", Message: "does not exist"}}
+	if err == nil || !reflect.DeepEqual(err.(*apierrors.Error).Details, want) {
+		t.Errorf("Struct() = %v, want %+v", err, want)
+	}
+	// the cause of a failed check is logged, not sent to the client
+	validator = New().Register("customer", Found(func(_ context.Context, id string) error {
+		return status.Error(codes.Unavailable, "dial tcp 10.0.0.7:443: connection refused")
+	}))
+	err = validator.Struct(context.Background(), &request{CustomerId: "u1"})
+	want = []apierrors.FieldError{{Field: "customerId", Message: "could not be checked"}}
+	if err == nil || !reflect.DeepEqual(err.(*apierrors.Error).Details, want) {
+		t.Errorf("Struct() = %v, want %+v", err, want)
+	}
+}
+
+func TestBadTags(t *testing.T) {
+	type unknownRule struct {
+		Name string `json:"name" validate:"required,shiny"`
+	}
+	type badMax struct {
+		Name string `json:"name" validate:"max=ten"`
+	}
+	type unknownField struct {
+		EndDate *time.Time `json:"endDate" validate:"gtfield=Start"`
+	}
+	type timeAfterString struct {
+		Start   string     `json:"start"`
+		EndDate *time.Time `json:"endDate" validate:"gtfield=Start"`
+	}
+	type numberAfterTime struct {
+		Start *time.Time `json:"start"`
+		Count int        `json:"count" validate:"gtfield=Start"`
+	}
+	type diveIntoString struct {
+		Name string `json:"name" validate:"dive,e164"`
+	}
+	type nested struct {
+		Items []*unknownRule `json:"items"`
+	}
+	for name, s := range map[string]interface{}{
+		"unknown rule":      &unknownRule{Name: "a"},
+		"bad max":           &badMax{Name: "a"},
+		"unknown field":     &unknownField{},
+		"time after string": &timeAfterString{Start: "now"},
+		"number after time": &numberAfterTime{},
+		"dive into string":  &diveIntoString{Name: "a"},
+		"nested":            &nested{},
+	} {
+		if err := New().Check(s); err == nil {
+			t.Errorf("%s: Check() = nil, want an error", name)
+		}
+		if err := Struct(context.Background(), s); apierrors.CodeOf(err) != apierrors.CodeInternal {
+			t.Errorf("%s: Struct() = %v, want an internal error", name, err)
+		}
+	}
+	if err := New().Check(&appointmentRequest{}); err != nil {
+		t.Errorf("Check() = %v, want nil", err)
+	}
+}
+
+func TestSlice(t *testing.T) {
+	type invite struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	err := Struct(context.Background(), &[]*invite{{Email: "a@example.com"}, {Email: "b"}, nil})
+	want := []apierrors.FieldError{
+		{Field: "[1].email", Message: "must be an email address"},
+		{Field: "[2]", Message: "is required"},
+	}
+	if err == nil || !reflect.DeepEqual(err.(*apierrors.Error).Details, want) {
+		t.Errorf("Struct() = %v, want %+v", err, want)
+	}
+	if err = Struct(context.Background(), &[]string{"a"}); apierrors.CodeOf(err) != apierrors.CodeInvalidArgument {
+		t.Errorf("Struct() = %v, want an invalid argument error", err)
+	}
+}
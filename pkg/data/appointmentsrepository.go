@@ -2,39 +2,71 @@ package data
 
 import (
 	"context"
+	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
 )
 
-// todo: add Context parameter to repo methods
-type AppointmentsRepository interface {
-	Delete(data *model.Appointment) (string, error)
-	Update(data *model.Appointment) (string, error)
-	Save(data *model.Appointment) (*model.Appointment, error)
-	FindById(businessId, appointId string) (*model.Appointment, error)
-	DeleteById(businessId, appointId string) (string, error)
-}
+const bookedDates = "bookedDates"
 
 type appointmentsRepository struct {
-	db *firestore.Client
+	db        *firestore.Client
+	publisher events.Publisher
 }
 
-func NewAppointmentsRepo(client *firestore.Client) AppointmentsRepository {
-	return appointmentsRepository{db: client}
+func NewAppointmentsRepo(client *firestore.Client, publisher events.Publisher) definition.AppointmentsRepository {
+	return &appointmentsRepository{db: client, publisher: publisher}
 }
 
-func (r appointmentsRepository) Save(data *model.Appointment) (*model.Appointment, error) {
-	doc, _, err := r.db.Collection("businesses").Doc(data.Business.Id).Collection("appointments").Add(context.Background(), data)
-	if err != nil {
+func (r *appointmentsRepository) appointments(businessId string) *firestore.CollectionRef {
+	return r.db.Collection(db.Businesses).Doc(businessId).Collection(db.Appoints)
+}
+
+// bookedDates returns the booked dates of the appointment, in the calendars of the associate and
+// of the associate in the business.
+func (r *appointmentsRepository) bookedDates(appointment *model.Appointment) []*firestore.DocumentRef {
+	associateId := appointment.Associate.Associate.Id
+	return []*firestore.DocumentRef{
+		r.db.Collection(db.Users).Doc(associateId).Collection(bookedDates).Doc(appointment.Id),
+		r.db.Collection(db.Businesses).Doc(appointment.Business.Id).Collection(db.Associates).Doc(associateId).
+			Collection(bookedDates).Doc(appointment.Id),
+	}
+}
+
+// set writes the appointment to the batch. The client drops the serverTimestamp fields which are
+// set, so the creation date is written again.
+func (r *appointmentsRepository) set(batch *firestore.WriteBatch, appointment *model.Appointment) *firestore.WriteBatch {
+	doc := r.appointments(appointment.Business.Id).Doc(appointment.Id)
+	batch.Set(doc, appointment)
+	if !appointment.CreatedDate.IsZero() {
+		batch.Update(doc, []firestore.Update{{Path: "createdDate", Value: appointment.CreatedDate}})
+	}
+	return batch
+}
+
+func (r *appointmentsRepository) Save(ctx context.Context, appointment *model.Appointment, booked definition.Booked) (_ *model.Appointment, err error) {
+	ctx, done := instrument(ctx, "appointments", "Save")
+	defer done(&err)
+	doc := r.appointments(appointment.Business.Id).NewDoc()
+	appointment.Id = doc.ID
+	batch := r.db.Batch().Create(doc, appointment)
+	for _, ref := range r.bookedDates(appointment) {
+		batch.Create(ref, map[string]interface{}{"date": appointment.StartDate})
+	}
+	if err = commit(ctx, r.publisher, batch, booked.Events(appointment)...); err != nil {
 		return nil, err
 	}
-	data.Id = doc.ID
-	return data, nil
+	return appointment, nil
 }
 
-func (r appointmentsRepository) FindById(businessId, appointId string) (*model.Appointment, error) {
-	snapshot, err := r.db.Collection("businesses").Doc(businessId).Collection("appointments").Doc(appointId).Get(context.Background())
+func (r *appointmentsRepository) FindById(ctx context.Context, businessId string, appointId string) (_ *model.Appointment, err error) {
+	ctx, done := instrument(ctx, "appointments", "FindById")
+	defer done(&err)
+	snapshot, err := r.appointments(businessId).Doc(appointId).Get(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -47,21 +79,44 @@ func (r appointmentsRepository) FindById(businessId, appointId string) (*model.A
 	return appoint, nil
 }
 
-func (r appointmentsRepository) Delete(data *model.Appointment) (string, error) {
-	return r.DeleteById(data.Business.Id, data.Id)
+func (r *appointmentsRepository) DeleteById(ctx context.Context, businessId string, appointId string) (err error) {
+	ctx, done := instrument(ctx, "appointments", "DeleteById")
+	defer done(&err)
+	_, err = r.appointments(businessId).Doc(appointId).Delete(ctx)
+	return err
 }
 
-func (r appointmentsRepository) DeleteById(businessId, appointId string) (string, error) {
-	_, err := r.db.Collection("businesses").Doc(businessId).Collection("appointments").Doc(appointId).Delete(context.Background())
-	if err != nil {
-		return appointId, err
+func (r *appointmentsRepository) Update(ctx context.Context, appointment *model.Appointment) (err error) {
+	ctx, done := instrument(ctx, "appointments", "Update")
+	defer done(&err)
+	batch := r.set(r.db.Batch(), appointment)
+	if !appointment.Canceled {
+		for _, ref := range r.bookedDates(appointment) {
+			batch.Set(ref, map[string]interface{}{"date": appointment.StartDate})
+		}
+	}
+	_, err = batch.Commit(ctx)
+	return err
+}
+
+func (r *appointmentsRepository) Cancel(ctx context.Context, appointment *model.Appointment) (err error) {
+	ctx, done := instrument(ctx, "appointments", "Cancel")
+	defer done(&err)
+	batch := r.set(r.db.Batch(), appointment)
+	for _, ref := range r.bookedDates(appointment) {
+		batch.Delete(ref)
 	}
-	return appointId, nil
+	_, err = batch.Commit(ctx)
+	return err
 }
 
-func (r appointmentsRepository) Update(data *model.Appointment) (string, error) {
-	businessId := data.Business.Id
-	id := data.Id
-	_, err := r.db.Collection("businesses").Doc(businessId).Collection("appointments").Doc(id).Set(context.Background(), &data)
-	return id, err
+func (r *appointmentsRepository) IsBooked(ctx context.Context, associateId string, date time.Time) (_ bool, err error) {
+	ctx, done := instrument(ctx, "appointments", "IsBooked")
+	defer done(&err)
+	documents, err := r.db.Collection(db.Users).Doc(associateId).Collection(bookedDates).
+		Where("date", "==", date).Limit(1).Select().Documents(ctx).GetAll()
+	if err != nil {
+		return false, err
+	}
+	return len(documents) > 0, nil
 }
@@ -17,9 +17,11 @@ type associateAssistantRepository struct {
 	dataSource *firestore.Client
 }
 
-func (r *associateAssistantRepository) Find(associateId string, assistantId string) (*model.Assistant, error) {
+func (r *associateAssistantRepository) Find(associateId string, assistantId string) (_ *model.Assistant, err error) {
+	ctx, done := instrument(context.Background(), "associate_assistants", "Find")
+	defer done(&err)
 	//todo: refactor with collection('users').doc(currentUser.uid).collection('settings').doc('appoints').update('assistants', FieldValue.arrayUnion(assistantData))
-	snapshot, err := r.dataSource.Collection("users").Doc(associateId).Collection("assistants").Doc(assistantId).Get(context.Background())
+	snapshot, err := r.dataSource.Collection("users").Doc(associateId).Collection("assistants").Doc(assistantId).Get(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -31,9 +33,11 @@ func (r *associateAssistantRepository) Find(associateId string, assistantId stri
 	return assistant, nil
 }
 
-func (r *associateAssistantRepository) FindAll(associateId string) ([]*model.Assistant, error) {
+func (r *associateAssistantRepository) FindAll(associateId string) (_ []*model.Assistant, err error) {
+	ctx, done := instrument(context.Background(), "associate_assistants", "FindAll")
+	defer done(&err)
 	//todo: refactor with collection('users').doc(currentUser.uid).collection('settings').doc('appoints').update('assistants', FieldValue.arrayUnion(assistantData))
-	documents := r.dataSource.Collection("users").Doc(associateId).Collection("assistants").Documents(context.Background())
+	documents := r.dataSource.Collection("users").Doc(associateId).Collection("assistants").Documents(ctx)
 	all, err := documents.GetAll()
 	if err != nil {
 		return nil, err
@@ -50,9 +54,11 @@ func (r *associateAssistantRepository) FindAll(associateId string) ([]*model.Ass
 	return assistants, nil
 }
 
-func (r *associateAssistantRepository) FindAllIDs(associateId string) ([]string, error) {
+func (r *associateAssistantRepository) FindAllIDs(associateId string) (_ []string, err error) {
+	ctx, done := instrument(context.Background(), "associate_assistants", "FindAllIDs")
+	defer done(&err)
 	//todo: refactor with collection('users').doc(currentUser.uid).collection('settings').doc('appoints').update('assistants', FieldValue.arrayUnion(assistantData))
-	documents := r.dataSource.Collection("users").Doc(associateId).Collection("assistants").Select().Documents(context.Background())
+	documents := r.dataSource.Collection("users").Doc(associateId).Collection("assistants").Select().Documents(ctx)
 	all, err := documents.GetAll()
 	if err != nil {
 		return nil, err
@@ -13,7 +13,9 @@ type associatesRepository struct {
 	db *db.Firestore
 }
 
-func (ir *associatesRepository) Delete(ctx context.Context, associateID string) (*model.Associate, error) {
+func (ir *associatesRepository) Delete(ctx context.Context, associateID string) (_ *model.Associate, err error) {
+	ctx, done := instrument(ctx, "associates", "Delete")
+	defer done(&err)
 	snapshot, err := ir.db.User(associateID).Get(ctx)
 	if err != nil {
 		return nil, err
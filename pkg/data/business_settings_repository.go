@@ -18,7 +18,9 @@ func NewBusinessSettingsRepository(firestoreClient *firestore.Client) definition
 }
 
 func (r *businessSettingsRepository) FindById(context context.Context, businessId string) (settings *model.Settings, err error) {
-	snapshot, err := r.firestoreClient.Collection("settings").Doc(businessId).Get(context)
+	ctx, done := instrument(context, "business_settings", "FindById")
+	defer done(&err)
+	snapshot, err := r.firestoreClient.Collection("settings").Doc(businessId).Get(ctx)
 	if err != nil {
 		return
 	}
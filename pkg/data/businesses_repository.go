@@ -20,7 +20,9 @@ func NewBusinessesRepository(firestoreClient *firestore.Client) definition.Busin
 }
 
 func (r *businessesRepository) FindById(context context.Context, businessId string) (business *model.Business, err error) {
-	snapshot, err := r.firestoreClient.Collection(CollectionBusinesses).Doc(businessId).Get(context)
+	ctx, done := instrument(context, "businesses", "FindById")
+	defer done(&err)
+	snapshot, err := r.firestoreClient.Collection(CollectionBusinesses).Doc(businessId).Get(ctx)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,240 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const archive = "archive"
+
+type casesRepository struct {
+	db        *db.Firestore
+	publisher events.Publisher
+}
+
+func NewCasesRepository(db *db.Firestore, publisher events.Publisher) definition.CasesRepository {
+	return &casesRepository{db, publisher}
+}
+
+func (r *casesRepository) FindById(ctx context.Context, businessId string, caseId string) (_ *model.Case, err error) {
+	ctx, done := instrument(ctx, "cases", "FindById")
+	defer done(&err)
+	snapshot, err := r.db.BusinessCase(businessId, caseId).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var businessCase *model.Case
+	if err = snapshot.DataTo(&businessCase); err != nil {
+		return nil, err
+	}
+	businessCase.Id = snapshot.Ref.ID
+	return businessCase, nil
+}
+
+func (r *casesRepository) Save(ctx context.Context, businessId string, businessCase *model.Case, reported ...events.Event) (err error) {
+	ctx, done := instrument(ctx, "cases", "Save")
+	defer done(&err)
+	batch := r.db.Batch().Set(r.db.BusinessCase(businessId, businessCase.Id), businessCase)
+	if len(businessCase.TextSessionId) > 0 {
+		chatRef := r.db.Chat(businessCase.TextSessionId)
+		batch.
+			Set(chatRef.Collection(db.Cases).Doc(businessCase.Id), businessCase).
+			Update(chatRef, []firestore.Update{{Path: "case", Value: businessCase.Map()}})
+	}
+	return commit(ctx, r.publisher, batch, reported...)
+}
+
+func (r *casesRepository) FindArchived(ctx context.Context, businessId string, ids []string) (chats []*model.TextSession, err error) {
+	ctx, done := instrument(ctx, "cases", "FindArchived")
+	defer done(&err)
+	archiveRef := r.db.Business(businessId).Collection(archive)
+	var refs []*firestore.DocumentRef
+	for _, id := range ids {
+		refs = append(refs, archiveRef.Doc(id))
+	}
+	snapshots, err := r.db.GetAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		if !snapshot.Exists() {
+			return nil, status.Errorf(codes.NotFound, "archived chat %s not found", snapshot.Ref.ID)
+		}
+		var chat *model.TextSession
+		if err = snapshot.DataTo(&chat); err != nil {
+			return nil, err
+		}
+		chat.Id = snapshot.Ref.ID
+		chats = append(chats, chat)
+	}
+	return chats, nil
+}
+
+func (r *casesRepository) LockHandoff(ctx context.Context, businessId string, caseId string, handoff *model.CaseHandoff) (locked bool, err error) {
+	ctx, done := instrument(ctx, "cases", "LockHandoff")
+	defer done(&err)
+	caseRef := r.db.BusinessCase(businessId, caseId)
+	handoffsRef := r.db.CaseHandoffs(businessId, caseId)
+	err = r.db.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		locked = false
+		snapshot, err := t.Get(caseRef)
+		if err != nil {
+			return err
+		}
+		var current *model.Case
+		if err = snapshot.DataTo(&current); err != nil {
+			return err
+		}
+		now := time.Now()
+		if current.Handoff.IsPending(now) {
+			return nil
+		}
+		if current.Handoff != nil && current.Handoff.Status == model.HandoffPending && len(current.Handoff.Id) > 0 {
+			err = t.Update(handoffsRef.Doc(current.Handoff.Id), []firestore.Update{
+				{Path: "status", Value: model.HandoffExpired},
+				{Path: "resolvedDate", Value: now},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		ref := handoffsRef.NewDoc()
+		handoff.Id = ref.ID
+		if err = t.Create(ref, handoff); err != nil {
+			return err
+		}
+		locked = true
+		return t.Update(caseRef, []firestore.Update{{Path: "handoff", Value: handoff}})
+	}, firestore.MaxAttempts(db.TransactionRetries))
+	return locked, err
+}
+
+func (r *casesRepository) ResolveHandoff(ctx context.Context, businessId string, caseId string, handoff *model.CaseHandoff) (err error) {
+	ctx, done := instrument(ctx, "cases", "ResolveHandoff")
+	defer done(&err)
+	return r.resolveHandoff(ctx, businessId, caseId, handoff)
+}
+
+func (r *casesRepository) resolveHandoff(ctx context.Context, businessId string, caseId string, handoff *model.CaseHandoff) error {
+	caseRef := r.db.BusinessCase(businessId, caseId)
+	return r.db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := pendingHandoff(tx, caseRef, handoff.Id); err != nil {
+			return err
+		}
+		if err := tx.Set(r.db.CaseHandoffs(businessId, caseId).Doc(handoff.Id), handoff); err != nil {
+			return err
+		}
+		return tx.Update(caseRef, []firestore.Update{{Path: "handoff", Value: firestore.Delete}})
+	}, firestore.MaxAttempts(db.TransactionRetries))
+}
+
+func (r *casesRepository) ExpireHandoffs(ctx context.Context, now time.Time) (expired []*model.Case, err error) {
+	ctx, done := instrument(ctx, "cases", "ExpireHandoffs")
+	defer done(&err)
+	snapshots, err := r.db.CollectionGroup(db.Handoffs).
+		Where("status", "==", model.HandoffPending).
+		Where("expiresDate", "<", now).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		var handoff *model.CaseHandoff
+		if err = snapshot.DataTo(&handoff); err != nil {
+			return expired, err
+		}
+		handoff.Id = snapshot.Ref.ID
+		handoff.Status, handoff.ResolvedDate = model.HandoffExpired, &now
+		caseRef := snapshot.Ref.Parent.Parent
+		businessId := caseRef.Parent.Parent.ID
+		err = r.resolveHandoff(ctx, businessId, caseRef.ID, handoff)
+		if err == definition.ErrHandoffResolved {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		businessCase, err := r.FindById(ctx, businessId, caseRef.ID)
+		if err != nil {
+			return expired, err
+		}
+		businessCase.Handoff = handoff
+		expired = append(expired, businessCase)
+	}
+	return expired, nil
+}
+
+// pendingHandoff reads the case in the transaction, and returns it if the handoff is its pending
+// handoff, or ErrHandoffResolved.
+func pendingHandoff(tx *firestore.Transaction, caseRef *firestore.DocumentRef, handoffId string) (*model.Case, error) {
+	snapshot, err := tx.Get(caseRef)
+	if err != nil {
+		return nil, err
+	}
+	var current *model.Case
+	if err = snapshot.DataTo(&current); err != nil {
+		return nil, err
+	}
+	if current.Handoff == nil || current.Handoff.Id != handoffId || current.Handoff.Status != model.HandoffPending {
+		return nil, definition.ErrHandoffResolved
+	}
+	return current, nil
+}
+
+func (r *casesRepository) Forward(ctx context.Context, businessId string, businessCase *model.Case, chat *model.TextSession,
+	handoff *model.CaseHandoff, forwarded definition.Forwarded) (err error) {
+	ctx, done := instrument(ctx, "cases", "Forward")
+	defer done(&err)
+	caseRef := r.db.BusinessCase(businessId, businessCase.Id)
+	chatRef := r.db.Chats().NewDoc()
+	if len(chat.Id) > 0 {
+		chatRef = r.db.Chat(chat.Id)
+	} else {
+		chat.Id = chatRef.ID
+		chat.Case.TextSessionId = chat.Id
+	}
+	fromChatRef := r.db.Chat(businessCase.TextSessionId)
+	return transact(ctx, r.db, r.publisher, func(ctx context.Context, tx *firestore.Transaction) error {
+		current, err := pendingHandoff(tx, caseRef, handoff.Id)
+		if err != nil {
+			return err
+		}
+		if current.TextSessionId != businessCase.TextSessionId {
+			return definition.ErrHandoffResolved
+		}
+		if err = tx.Set(chatRef, chat); err != nil {
+			return err
+		}
+		if err = tx.Set(chatRef.Collection(db.Cases).Doc(businessCase.Id), chat.Case); err != nil {
+			return err
+		}
+		if err = tx.Set(r.db.CaseHandoffs(businessId, businessCase.Id).Doc(handoff.Id), handoff); err != nil {
+			return err
+		}
+		err = tx.Update(caseRef, []firestore.Update{
+			{Path: "textSessionId", Value: chat.Case.TextSessionId},
+			{Path: "associate", Value: chat.Case.Associate},
+			{Path: "forwardedDate", Value: chat.Case.ForwardedDate},
+			{Path: "handoff", Value: firestore.Delete},
+		})
+		if err != nil {
+			return err
+		}
+		err = tx.Update(fromChatRef, []firestore.Update{
+			{Path: "case", Value: firestore.Delete},
+			{Path: "lastMessage", Value: firestore.Delete},
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Delete(fromChatRef.Collection(db.Cases).Doc(businessCase.Id))
+	}, forwarded.Events(chat)...)
+}
@@ -24,9 +24,11 @@ func NewVideoCallsRepo(firestoreClient *firestore.Client) VideoCallsRepository {
 	return &videoCallsRepository{firestoreClient}
 }
 
-func (r *videoCallsRepository) Find(chatId string, videoCallId string) (*model.VideoCall, error) {
+func (r *videoCallsRepository) Find(chatId string, videoCallId string) (_ *model.VideoCall, err error) {
+	ctx, done := instrument(context.Background(), "video_calls", "Find")
+	defer done(&err)
 	snapshot, err := r.firestoreClient.Collection("textSessions").Doc(chatId).
-		Collection("videoCalls").Doc(videoCallId).Get(context.Background())
+		Collection("videoCalls").Doc(videoCallId).Get(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -41,19 +43,23 @@ func (r *videoCallsRepository) Find(chatId string, videoCallId string) (*model.V
 	return videoCall, nil
 }
 
-func (r *videoCallsRepository) Update(chatId string, videoCall *model.VideoCall) error {
-	_, err := r.firestoreClient.Collection("textSessions").Doc(chatId).
-		Collection("videoCalls").Doc(videoCall.Id).Set(context.Background(), videoCall)
+func (r *videoCallsRepository) Update(chatId string, videoCall *model.VideoCall) (err error) {
+	ctx, done := instrument(context.Background(), "video_calls", "Update")
+	defer done(&err)
+	_, err = r.firestoreClient.Collection("textSessions").Doc(chatId).
+		Collection("videoCalls").Doc(videoCall.Id).Set(ctx, videoCall)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *videoCallsRepository) Save(chatId string, videoCall *model.VideoCall) (*model.VideoCall, error) {
+func (r *videoCallsRepository) Save(chatId string, videoCall *model.VideoCall) (_ *model.VideoCall, err error) {
+	ctx, done := instrument(context.Background(), "video_calls", "Save")
+	defer done(&err)
 	videoCall.StartedDate = time.Now()
 	ref, _, err := r.firestoreClient.Collection("textSessions").Doc(chatId).
-		Collection("videoCalls").Add(context.Background(), &videoCall)
+		Collection("videoCalls").Add(ctx, &videoCall)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,147 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BlockedCustomer is the entry of a customer in the block list of an associate.
+type BlockedCustomer struct {
+	Id          string          `firestore:"id" json:"id"`
+	Name        string          `firestore:"name" json:"name"`
+	Description string          `firestore:"description" json:"description"`
+	PhotoUrl    *model.PhotoUrl `firestore:"photoUrl" json:"photoUrl"`
+	CreatedDate time.Time       `firestore:"createdDate" json:"createdDate"`
+}
+
+type customersRepository struct {
+	db *db.Firestore
+}
+
+func NewCustomersRepository(db *db.Firestore) definition.CustomersRepository {
+	return &customersRepository{db}
+}
+
+func (r *customersRepository) FindById(ctx context.Context, customerId string) (_ *model.Customer, err error) {
+	ctx, done := instrument(ctx, "customers", "FindById")
+	defer done(&err)
+	return getCustomer(ctx, r.db.User(customerId))
+}
+
+func (r *customersRepository) FindBusinessCustomer(ctx context.Context, businessId string, customerId string) (_ *model.Customer, err error) {
+	ctx, done := instrument(ctx, "customers", "FindBusinessCustomer")
+	defer done(&err)
+	return getCustomer(ctx, r.db.BusinessCustomer(businessId, customerId))
+}
+
+func (r *customersRepository) FindByEmail(ctx context.Context, email string) (_ *model.Customer, err error) {
+	ctx, done := instrument(ctx, "customers", "FindByEmail")
+	defer done(&err)
+	snapshots, err := r.db.Collection(db.Users).Where("email", "==", email).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no user with the email %s", email)
+	}
+	var customer *model.Customer
+	if err = snapshots[0].DataTo(&customer); err != nil {
+		return nil, err
+	}
+	customer.Id = snapshots[0].Ref.ID
+	return customer, nil
+}
+
+func getCustomer(ctx context.Context, ref *firestore.DocumentRef) (*model.Customer, error) {
+	snapshot, err := ref.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var customer *model.Customer
+	if err = snapshot.DataTo(&customer); err != nil {
+		return nil, err
+	}
+	customer.Id = snapshot.Ref.ID
+	return customer, nil
+}
+
+func (r *customersRepository) HasBlocked(ctx context.Context, userId string, userIDs []string) (_ bool, err error) {
+	ctx, done := instrument(ctx, "customers", "HasBlocked")
+	defer done(&err)
+	for _, blockedId := range userIDs {
+		_, err = r.db.BlockedUser(userId, blockedId).Get(ctx)
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *customersRepository) Block(ctx context.Context, businessId string, customer *model.Customer, associateId string) (err error) {
+	ctx, done := instrument(ctx, "customers", "Block")
+	defer done(&err)
+	chats, err := r.chats(ctx, customer.Id, associateId)
+	if err != nil {
+		return err
+	}
+	batch := r.db.Batch()
+	batch.Update(r.db.BusinessCustomer(businessId, customer.Id), []firestore.Update{{Path: "blocked", Value: firestore.ArrayUnion(associateId)}})
+	for _, chat := range chats {
+		batch.Update(chat, []firestore.Update{{FieldPath: []string{"blockList", associateId}, Value: customer.Id}})
+	}
+	// todo: legacy
+	batch.Create(r.db.BlockedUser(associateId, customer.Id), BlockedCustomer{
+		Id:          customer.Id,
+		Name:        customer.FullName,
+		Description: "Customer",
+		PhotoUrl:    customer.PhotoUrl,
+		CreatedDate: time.Now(),
+	})
+	_, err = batch.Commit(ctx)
+	return err
+}
+
+func (r *customersRepository) Unblock(ctx context.Context, businessId string, customerId string, associateId string) (err error) {
+	ctx, done := instrument(ctx, "customers", "Unblock")
+	defer done(&err)
+	chats, err := r.chats(ctx, customerId, associateId)
+	if err != nil {
+		return err
+	}
+	batch := r.db.Batch()
+	batch.Update(r.db.BusinessCustomer(businessId, customerId), []firestore.Update{{Path: "blocked", Value: firestore.ArrayRemove(associateId)}})
+	for _, chat := range chats {
+		batch.Update(chat, []firestore.Update{{FieldPath: []string{"blockList", associateId}, Value: firestore.Delete}})
+	}
+	// todo: legacy
+	batch.Delete(r.db.BlockedUser(associateId, customerId))
+	_, err = batch.Commit(ctx)
+	return err
+}
+
+// chats returns the chats of the customer with the associate.
+func (r *customersRepository) chats(ctx context.Context, customerId string, associateId string) ([]*firestore.DocumentRef, error) {
+	snapshots, err := r.db.Chats().
+		Where("customer.id", "==", customerId).
+		Where("memberIDs", "array-contains", associateId).
+		Select().Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	var refs []*firestore.DocumentRef
+	for _, snapshot := range snapshots {
+		refs = append(refs, snapshot.Ref)
+	}
+	return refs, nil
+}
@@ -25,4 +25,15 @@ const (
 	InvitedCustomers   = "invitedCustomers"
 	BusinessesAccess   = "businessesAccess"
 	BusinessCategories = "businessCategories"
+	FCMTokens          = "fcmTokens"
+	Handoffs           = "handoffs"
+	Notes              = "notes"
+	CustomerNotes      = "customerNotes"
+	Replies            = "replies"
+	AuditLog           = "auditLog"
+	HubspotSync        = "hubspotSync"
+	HubspotSyncLeases  = "hubspotSyncLeases"
+	Outbox             = "outbox"
+	OutboxLeases       = "outboxLeases"
+	OutboxDeadLetters  = "outboxDeadLetters"
 )
@@ -23,6 +23,36 @@ func (f *Firestore) BusinessCategories() (col *firestore.CollectionRef) {
 	return
 }
 
+// HubspotSync - Reference to the document of the HubSpot sync of the business /**
+func (f *Firestore) HubspotSync(businessID string) (doc *firestore.DocumentRef) {
+	doc = f.Collection(HubspotSync).Doc(businessID)
+	return
+}
+
+// HubspotSyncLease - Reference to the document of the lease of a watch of the HubSpot sync /**
+func (f *Firestore) HubspotSyncLease(name string) (doc *firestore.DocumentRef) {
+	doc = f.Collection(HubspotSyncLeases).Doc(name)
+	return
+}
+
+// Outbox - Reference to the outbox collection, the messages waiting to be published to NSQ /**
+func (f *Firestore) Outbox() (col *firestore.CollectionRef) {
+	col = f.Collection(Outbox)
+	return
+}
+
+// OutboxDeadLetters - Reference to the outbox entries the relay gave up publishing /**
+func (f *Firestore) OutboxDeadLetters() (col *firestore.CollectionRef) {
+	col = f.Collection(OutboxDeadLetters)
+	return
+}
+
+// OutboxLease - Reference to the document of the lease of the outbox relay /**
+func (f *Firestore) OutboxLease(name string) (doc *firestore.DocumentRef) {
+	doc = f.Collection(OutboxLeases).Doc(name)
+	return
+}
+
 // User - Reference to the user document /**
 func (f *Firestore) User(userID string) (doc *firestore.DocumentRef) {
 	doc = f.Collection(Users).Doc(userID)
@@ -113,6 +143,54 @@ func (f *Firestore) BusinessChannelMember(businessID string, channelID string, m
 	return
 }
 
+// BusinessCases - Reference to business cases collection /**
+func (f *Firestore) BusinessCases(businessID string) (collection *firestore.CollectionRef) {
+	collection = f.Business(businessID).Collection(Cases)
+	return
+}
+
+// BusinessCase - Reference to business case document /**
+func (f *Firestore) BusinessCase(businessID string, caseID string) (doc *firestore.DocumentRef) {
+	doc = f.BusinessCases(businessID).Doc(caseID)
+	return
+}
+
+// CaseHandoffs - Reference to case handoffs collection /**
+func (f *Firestore) CaseHandoffs(businessID string, caseID string) (collection *firestore.CollectionRef) {
+	collection = f.BusinessCase(businessID, caseID).Collection(Handoffs)
+	return
+}
+
+// CaseNotes - Reference to case notes collection /**
+func (f *Firestore) CaseNotes(businessID string, caseID string) (collection *firestore.CollectionRef) {
+	collection = f.BusinessCase(businessID, caseID).Collection(Notes)
+	return
+}
+
+// BusinessCustomerNotes - Reference to business customer notes collection /**
+func (f *Firestore) BusinessCustomerNotes(businessID string, customerID string) (collection *firestore.CollectionRef) {
+	collection = f.BusinessCustomer(businessID, customerID).Collection(CustomerNotes)
+	return
+}
+
+// BusinessReplies - Reference to business canned replies collection /**
+func (f *Firestore) BusinessReplies(businessID string) (collection *firestore.CollectionRef) {
+	collection = f.Business(businessID).Collection(Replies)
+	return
+}
+
+// UserReplies - Reference to personal canned replies collection /**
+func (f *Firestore) UserReplies(userID string) (collection *firestore.CollectionRef) {
+	collection = f.User(userID).Collection(Replies)
+	return
+}
+
+// FCMTokens - Reference to FCM tokens collection /**
+func (f *Firestore) FCMTokens() (collection *firestore.CollectionRef) {
+	collection = f.Collection(FCMTokens)
+	return
+}
+
 // Chats - Chats collection
 func (f *Firestore) Chats() (collection *firestore.CollectionRef) {
 	collection = f.Collection(TextSessions)
@@ -0,0 +1,91 @@
+package data
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+)
+
+type directoryRepository struct {
+	db *db.Firestore
+}
+
+func NewDirectoryRepository(db *db.Firestore) definition.DirectoryRepository {
+	return &directoryRepository{db}
+}
+
+func (r *directoryRepository) FindById(ctx context.Context, businessId string, contactId string) (_ *model.Contact, err error) {
+	ctx, done := instrument(ctx, "directory", "FindById")
+	defer done(&err)
+	snapshot, err := r.db.BusinessDirectoryContact(businessId, contactId).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var contact *model.Contact
+	if err = snapshot.DataTo(&contact); err != nil {
+		return nil, err
+	}
+	contact.Id = snapshot.Ref.ID
+	return contact, nil
+}
+
+func (r *directoryRepository) FindVisible(ctx context.Context, businessId string) (contacts []*model.DirectoryContact, err error) {
+	ctx, done := instrument(ctx, "directory", "FindVisible")
+	defer done(&err)
+	snapshots, err := r.db.BusinessDirectory(businessId).
+		Where("rules.VISIBILITY.visible", "==", true).
+		OrderBy("flatIndex", firestore.Asc).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		var contact *model.DirectoryContact
+		if err = snapshot.DataTo(&contact); err != nil {
+			return nil, err
+		}
+		contact.Id = snapshot.Ref.ID
+		contacts = append(contacts, contact)
+	}
+	return contacts, nil
+}
+
+func (r *directoryRepository) FindByAssociate(ctx context.Context, businessId string, uid string) (_ *model.Contact, err error) {
+	ctx, done := instrument(ctx, "directory", "FindByAssociate")
+	defer done(&err)
+	snapshots, err := r.db.BusinessDirectory(businessId).Where("associate.id", "==", uid).Limit(1).Documents(ctx).GetAll()
+	if err != nil || len(snapshots) == 0 {
+		return nil, err
+	}
+	var contact *model.Contact
+	if err = snapshots[0].DataTo(&contact); err != nil {
+		return nil, err
+	}
+	contact.Id = snapshots[0].Ref.ID
+	return contact, nil
+}
+
+func (r *directoryRepository) FindByIds(ctx context.Context, businessId string, ids []string) (contacts []*model.DirectoryContact, err error) {
+	ctx, done := instrument(ctx, "directory", "FindByIds")
+	defer done(&err)
+	refs := make([]*firestore.DocumentRef, len(ids))
+	for i, id := range ids {
+		refs[i] = r.db.BusinessDirectoryContact(businessId, id)
+	}
+	snapshots, err := r.db.GetAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		var contact *model.DirectoryContact
+		if err = snapshot.DataTo(&contact); err != nil {
+			return nil, err
+		}
+		contact.Id = snapshot.Ref.ID
+		contacts = append(contacts, contact)
+	}
+	return contacts, nil
+}
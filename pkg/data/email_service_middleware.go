@@ -5,84 +5,102 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/metrics"
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	def "github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
-	"github.com/nsqio/go-nsq"
 )
 
+// TopicEmailsRequests messages are model.LogEntry of the calls to the emails service.
+const TopicEmailsRequests = "emails_requests"
+
 type LoggingMiddleware func(service def.EmailsService) def.EmailsService
 
 type emailServiceMW struct {
 	def.EmailsService
-	producer *nsq.Producer
+	producer *publishing.Producer
 }
 
 func (s *emailServiceMW) SendInvite(ctx context.Context, request def.SendInviteRequest) def.SendResponse {
 	response := s.EmailsService.SendInvite(ctx, request)
-	_ = s.publishLogEntry("SendInvite", response)
+	_ = s.publishLogEntry(ctx, "SendInvite", response)
 	return response
 }
 
 func (s *emailServiceMW) SendPasswordReset(ctx context.Context, request def.SendPasswordResetRequest) def.SendResponse {
 	response := s.EmailsService.SendPasswordReset(ctx, request)
-	_ = s.publishLogEntry("SendPasswordReset", response)
+	_ = s.publishLogEntry(ctx, "SendPasswordReset", response)
 	return response
 }
 
 func (s *emailServiceMW) SendNewRequestAlert(ctx context.Context, request ...def.NewRequestAlertRequest) def.SendResponse {
 	response := s.EmailsService.SendNewRequestAlert(ctx, request...)
-	_ = s.publishLogEntry("SendNewRequestAlert", response)
+	_ = s.publishLogEntry(ctx, "SendNewRequestAlert", response)
 	return response
 }
 
 func (s *emailServiceMW) SendIdleRequestAlert(ctx context.Context, request def.IdleRequestAlertRequest) def.SendResponse {
 	response := s.EmailsService.SendIdleRequestAlert(ctx, request)
-	_ = s.publishLogEntry("SendIdleRequestAlert", response)
+	_ = s.publishLogEntry(ctx, "SendIdleRequestAlert", response)
 	return response
 }
 
 func (s *emailServiceMW) SendAcceptedRequestAlert(ctx context.Context, request ...def.AcceptedRequestAlertRequest) def.SendResponse {
 	response := s.EmailsService.SendAcceptedRequestAlert(ctx, request...)
-	_ = s.publishLogEntry("SendAcceptedRequestAlert", response)
+	_ = s.publishLogEntry(ctx, "SendAcceptedRequestAlert", response)
 	return response
 }
 
 func (s *emailServiceMW) SendIdleChatAlert(ctx context.Context, request def.IdleChatAlertRequest) def.SendResponse {
 	response := s.EmailsService.SendIdleChatAlert(ctx, request)
-	_ = s.publishLogEntry("SendIdleChatAlert", response)
+	_ = s.publishLogEntry(ctx, "SendIdleChatAlert", response)
 	return response
 }
 
 func (s *emailServiceMW) SendUnreadChatAlert(ctx context.Context, request def.UnreadChatAlertRequest) def.SendResponse {
 	response := s.EmailsService.SendUnreadChatAlert(ctx, request)
-	_ = s.publishLogEntry("SendUnreadChatAlert", response)
+	_ = s.publishLogEntry(ctx, "SendUnreadChatAlert", response)
 	return response
 }
 
 func (s *emailServiceMW) SendBusinessAccountCreated(ctx context.Context, request def.SendBusinessAccountCreatedRequest) def.SendResponse {
 	response := s.EmailsService.SendBusinessAccountCreated(ctx, request)
-	_ = s.publishLogEntry("SendBusinessAccountCreated", response)
+	_ = s.publishLogEntry(ctx, "SendBusinessAccountCreated", response)
 	return response
 }
 
 func (s *emailServiceMW) SendBusinessEmailVerification(ctx context.Context, request def.SendBusinessEmailVerificationRequest) def.SendResponse {
 	response := s.EmailsService.SendBusinessEmailVerification(ctx, request)
-	_ = s.publishLogEntry("SendBusinessEmailVerification", response)
+	_ = s.publishLogEntry(ctx, "SendBusinessEmailVerification", response)
 	return response
 }
 
-func (s *emailServiceMW) publishLogEntry(method string, resp def.SendResponse) error {
+func (s *emailServiceMW) SendSLAEscalationAlert(ctx context.Context, request def.SLAEscalationAlertRequest) def.SendResponse {
+	response := s.EmailsService.SendSLAEscalationAlert(ctx, request)
+	_ = s.publishLogEntry(ctx, "SendSLAEscalationAlert", response)
+	return response
+}
+
+func (s *emailServiceMW) publishLogEntry(ctx context.Context, method string, resp def.SendResponse) error {
 	logEntry := model.LogEntry{
-		Topic:     "emails_requests",
+		Topic:     TopicEmailsRequests,
 		Severity:  "info",
 		Message:   fmt.Sprintf("method: %s, success: %v, error: %v", method, resp.OK(), resp.Error),
 		Component: "emails_service",
 	}
+	tracing.StampLogEntry(ctx, &logEntry)
+	logger.StampLogEntry(ctx, &logEntry)
 	bytes, _ := json.Marshal(&logEntry)
-	return s.producer.PublishAsync(logEntry.Topic, bytes, nil)
+	err := s.producer.PublishAsync(logEntry.Topic, bytes)
+	if err != nil {
+		metrics.NSQPublishFailures.WithLabelValues(logEntry.Topic).Inc()
+	}
+	return err
 }
 
-func NewLoggingMiddleware(producer *nsq.Producer) LoggingMiddleware {
+func NewLoggingMiddleware(producer *publishing.Producer) LoggingMiddleware {
 	return func(service def.EmailsService) def.EmailsService {
 		return &emailServiceMW{service, producer}
 	}
@@ -0,0 +1,53 @@
+package data
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+)
+
+// commit commits the batch with the events, so they are published if and only if the batch is
+// committed. The events the publisher can't write in a batch are published once it is, and a
+// failure to publish them does not fail the write.
+func commit(ctx context.Context, publisher events.Publisher, batch *firestore.WriteBatch, posted ...events.Event) error {
+	pending, err := events.InBatch(ctx, publisher, batch, posted...)
+	if err != nil {
+		return err
+	}
+	if _, err = batch.Commit(ctx); err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		if err = publisher.Publish(ctx, pending...); err != nil {
+			logger.FromContext(ctx).Error(ctx, "failed to publish the events", "error", err)
+		}
+	}
+	return nil
+}
+
+// transact runs the transaction with the events, so they are published if and only if it is
+// committed. The events the publisher can't write in a transaction are published once it is, as
+// commit does.
+func transact(ctx context.Context, firestoreDb *db.Firestore, publisher events.Publisher,
+	f func(ctx context.Context, tx *firestore.Transaction) error, posted ...events.Event) error {
+	var pending []events.Event
+	err := firestoreDb.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) (err error) {
+		if err = f(ctx, tx); err != nil {
+			return err
+		}
+		pending, err = events.InTransaction(ctx, publisher, tx, posted...)
+		return err
+	}, firestore.MaxAttempts(db.TransactionRetries))
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		if err = publisher.Publish(ctx, pending...); err != nil {
+			logger.FromContext(ctx).Error(ctx, "failed to publish the events", "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"context"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	def "github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+)
+
+const fcmBatchSize = 500
+
+type fcmPushService struct {
+	db        *db.Firestore
+	messaging *messaging.Client
+}
+
+func NewFCMPushService(db *db.Firestore, messaging *messaging.Client) def.PushService {
+	return &fcmPushService{db, messaging}
+}
+
+func (s *fcmPushService) Send(ctx context.Context, req def.PushRequest) (resp def.SendResponse) {
+	data := map[string]string{
+		"title":    req.Title,
+		"message":  req.Body,
+		"category": req.Category,
+	}
+	for key, value := range req.Data {
+		data[key] = value
+	}
+	notification := &messaging.Notification{
+		Title: req.Title,
+		Body:  req.Body,
+	}
+	var tokens []string
+	var messages []*messaging.Message
+	for _, uid := range common.DeDuplicateStrings(req.UIDs) {
+		userSnapshot, err := s.db.User(uid).Get(ctx)
+		if err != nil {
+			logger.FromContext(ctx).Error(ctx, "failed to read the user", "pushUid", uid, "error", err)
+			continue
+		}
+		var user *model.User
+		if err = userSnapshot.DataTo(&user); err != nil {
+			logger.FromContext(ctx).Error(ctx, "failed to read the user", "pushUid", uid, "error", err)
+			continue
+		}
+		if user.IsMuted() {
+			continue
+		}
+		tokensSnapshot, err := s.db.FCMTokens().Where("uid", "==", uid).Documents(ctx).GetAll()
+		if err != nil {
+			logger.FromContext(ctx).Error(ctx, "failed to get the FCM tokens", "pushUid", uid, "error", err)
+			continue
+		}
+		for _, snapshot := range tokensSnapshot {
+			var token *model.FCMToken
+			if err = snapshot.DataTo(&token); err != nil {
+				logger.FromContext(ctx).Error(ctx, "failed to read the FCM token", "pushUid", uid, "error", err)
+				continue
+			}
+			token.ID = snapshot.Ref.ID
+			message := &messaging.Message{
+				Data:       data,
+				Token:      token.ID,
+				FCMOptions: &messaging.FCMOptions{AnalyticsLabel: req.Category},
+			}
+			switch token.Platform {
+			case "web":
+				continue
+			case "ios":
+				message.Notification = notification
+				message.APNS = &messaging.APNSConfig{
+					Payload: &messaging.APNSPayload{Aps: &messaging.Aps{Category: req.Category}},
+				}
+			default:
+				message.Android = &messaging.AndroidConfig{Priority: "high"}
+			}
+			messages = append(messages, message)
+			tokens = append(tokens, token.ID)
+		}
+	}
+	for offset := 0; offset < len(messages); offset += fcmBatchSize {
+		end := offset + fcmBatchSize
+		if end > len(messages) {
+			end = len(messages)
+		}
+		response, err := s.messaging.SendAll(ctx, messages[offset:end])
+		if err != nil {
+			resp.Error = err
+			return
+		}
+		batch := s.db.Batch()
+		invalid := 0
+		for index, result := range response.Responses {
+			if !result.Success && messaging.IsUnregistered(result.Error) {
+				batch.Delete(s.db.FCMTokens().Doc(tokens[offset+index]))
+				invalid++
+			}
+		}
+		if invalid > 0 {
+			if _, err = batch.Commit(ctx); err != nil {
+				logger.FromContext(ctx).Error(ctx, "failed to clear the invalid FCM tokens", "error", err)
+			}
+		}
+	}
+	return
+}
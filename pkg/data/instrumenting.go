@@ -0,0 +1,20 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/metrics"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
+)
+
+// instrument starts the span of a repository method. The returned func ends the span and records
+// the metrics; it is deferred with the pointer to the named error result.
+func instrument(ctx context.Context, repository, method string) (context.Context, func(err *error)) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, repository+"."+method)
+	return ctx, func(err *error) {
+		tracing.End(span, *err)
+		metrics.ObserveFirestore(repository, method, start, *err)
+	}
+}
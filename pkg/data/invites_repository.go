@@ -13,7 +13,9 @@ type invitesRepository struct {
 	firestoreClient *firestore.Client
 }
 
-func (ir *invitesRepository) Delete(ctx context.Context, businessId string, inviteId string) (*model.Invite, error) {
+func (ir *invitesRepository) Delete(ctx context.Context, businessId string, inviteId string) (_ *model.Invite, err error) {
+	ctx, done := instrument(ctx, "invites", "Delete")
+	defer done(&err)
 	snapshot, err := ir.firestoreClient.Collection("businesses").Doc(businessId).Collection("invites").Doc(inviteId).Get(ctx)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+)
+
+const bookedDates = "bookedDates"
+
+type appointmentsRepository struct {
+	store     *Store
+	publisher events.Publisher
+}
+
+func NewAppointmentsRepository(store *Store, publisher events.Publisher) definition.AppointmentsRepository {
+	return &appointmentsRepository{store, publisher}
+}
+
+func appointmentPath(businessId string, appointId string) string {
+	return join(db.Businesses, businessId, db.Appoints, appointId)
+}
+
+func bookedDatePaths(appointment *model.Appointment) []string {
+	associateId := appointment.Associate.Associate.Id
+	return []string{
+		join(db.Users, associateId, bookedDates, appointment.Id),
+		join(db.Businesses, appointment.Business.Id, db.Associates, associateId, bookedDates, appointment.Id),
+	}
+}
+
+func (r *appointmentsRepository) FindById(_ context.Context, businessId string, appointId string) (*model.Appointment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var appointment model.Appointment
+	if err := r.store.get(appointmentPath(businessId, appointId), &appointment); err != nil {
+		return nil, err
+	}
+	appointment.Id = appointId
+	return &appointment, nil
+}
+
+func (r *appointmentsRepository) Save(ctx context.Context, appointment *model.Appointment, booked definition.Booked) (*model.Appointment, error) {
+	if err := r.save(appointment); err != nil {
+		return nil, err
+	}
+	publish(ctx, r.publisher, booked.Events(appointment)...)
+	return appointment, nil
+}
+
+func (r *appointmentsRepository) save(appointment *model.Appointment) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	appointment.Id = newID()
+	paths := bookedDatePaths(appointment)
+	for _, path := range paths {
+		if r.store.exists(path) {
+			return alreadyExists(path)
+		}
+	}
+	stored := *appointment
+	stored.CreatedDate = serverTime()
+	r.store.put(appointmentPath(appointment.Business.Id, appointment.Id), &stored)
+	for _, path := range paths {
+		r.store.put(path, map[string]interface{}{"date": appointment.StartDate})
+	}
+	return nil
+}
+
+func (r *appointmentsRepository) Update(_ context.Context, appointment *model.Appointment) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.put(appointmentPath(appointment.Business.Id, appointment.Id), appointment)
+	if !appointment.Canceled {
+		for _, path := range bookedDatePaths(appointment) {
+			r.store.put(path, map[string]interface{}{"date": appointment.StartDate})
+		}
+	}
+	return nil
+}
+
+func (r *appointmentsRepository) Cancel(_ context.Context, appointment *model.Appointment) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.put(appointmentPath(appointment.Business.Id, appointment.Id), appointment)
+	for _, path := range bookedDatePaths(appointment) {
+		r.store.delete(path)
+	}
+	return nil
+}
+
+func (r *appointmentsRepository) DeleteById(_ context.Context, businessId string, appointId string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.delete(appointmentPath(businessId, appointId))
+	return nil
+}
+
+func (r *appointmentsRepository) IsBooked(_ context.Context, associateId string, date time.Time) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	collection := join(db.Users, associateId, bookedDates)
+	for _, id := range r.store.ids(collection) {
+		booked, _ := r.store.docs[join(collection, id)].(map[string]interface{})
+		switch bookedDate := booked["date"].(type) {
+		case *time.Time:
+			if bookedDate != nil && bookedDate.Equal(date) {
+				return true, nil
+			}
+		case time.Time:
+			if bookedDate.Equal(date) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,231 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+)
+
+type casesRepository struct {
+	store     *Store
+	publisher events.Publisher
+}
+
+func NewCasesRepository(store *Store, publisher events.Publisher) definition.CasesRepository {
+	return &casesRepository{store, publisher}
+}
+
+func casePath(businessId string, caseId string) string {
+	return join(db.Businesses, businessId, db.Cases, caseId)
+}
+
+func (r *casesRepository) FindById(_ context.Context, businessId string, caseId string) (*model.Case, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var businessCase model.Case
+	if err := r.store.get(casePath(businessId, caseId), &businessCase); err != nil {
+		return nil, err
+	}
+	businessCase.Id = caseId
+	return &businessCase, nil
+}
+
+func (r *casesRepository) Save(ctx context.Context, businessId string, businessCase *model.Case, reported ...events.Event) error {
+	if err := r.save(businessId, businessCase); err != nil {
+		return err
+	}
+	publish(ctx, r.publisher, reported...)
+	return nil
+}
+
+func (r *casesRepository) save(businessId string, businessCase *model.Case) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if len(businessCase.TextSessionId) == 0 {
+		r.store.put(casePath(businessId, businessCase.Id), businessCase)
+		return nil
+	}
+	var chat model.TextSession
+	if err := r.store.get(chatPath(businessCase.TextSessionId), &chat); err != nil {
+		return err
+	}
+	chat.Case = chatCase(businessCase)
+	r.store.put(casePath(businessId, businessCase.Id), businessCase)
+	r.store.put(join(chatPath(businessCase.TextSessionId), db.Cases, businessCase.Id), businessCase)
+	r.store.put(chatPath(businessCase.TextSessionId), &chat)
+	return nil
+}
+
+// chatCase returns the summary of the case kept in its chat, as Case.Map does.
+func chatCase(c *model.Case) *model.Case {
+	return &model.Case{
+		Id:            c.Id,
+		Name:          c.Name,
+		Code:          c.Code,
+		Business:      &model.BusinessItem{Id: c.Business.Id, Name: c.Business.Name},
+		Number:        c.Number,
+		Priority:      c.Priority,
+		Status:        c.Status,
+		TextSessionId: c.TextSessionId,
+		Customer:      &model.CustomerItem{Id: c.Customer.Id, Name: c.Customer.Name},
+		Associate:     &model.AssociateItem{Id: c.Associate.Id, Name: c.Associate.Name},
+		OpenedDate:    c.OpenedDate,
+		ClosedDate:    c.ClosedDate,
+		AcceptedDate:  c.AcceptedDate,
+		RejectedDate:  c.RejectedDate,
+		ForwardedDate: c.ForwardedDate,
+	}
+}
+
+func (r *casesRepository) FindArchived(_ context.Context, businessId string, ids []string) ([]*model.TextSession, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var chats []*model.TextSession
+	for _, id := range ids {
+		var chat model.TextSession
+		if err := r.store.get(join(db.Businesses, businessId, "archive", id), &chat); err != nil {
+			return nil, err
+		}
+		chat.Id = id
+		chats = append(chats, &chat)
+	}
+	return chats, nil
+}
+
+func handoffPath(businessId string, caseId string, handoffId string) string {
+	return join(casePath(businessId, caseId), db.Handoffs, handoffId)
+}
+
+func (r *casesRepository) LockHandoff(_ context.Context, businessId string, caseId string, handoff *model.CaseHandoff) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var current model.Case
+	if err := r.store.get(casePath(businessId, caseId), &current); err != nil {
+		return false, err
+	}
+	now := time.Now()
+	if current.Handoff.IsPending(now) {
+		return false, nil
+	}
+	if current.Handoff != nil && current.Handoff.Status == model.HandoffPending && len(current.Handoff.Id) > 0 {
+		var expired model.CaseHandoff
+		if err := r.store.get(handoffPath(businessId, caseId, current.Handoff.Id), &expired); err != nil {
+			return false, err
+		}
+		expired.Status, expired.ResolvedDate = model.HandoffExpired, &now
+		r.store.put(handoffPath(businessId, caseId, current.Handoff.Id), &expired)
+	}
+	handoff.Id = newID()
+	current.Handoff = handoff
+	r.store.put(handoffPath(businessId, caseId, handoff.Id), handoff)
+	r.store.put(casePath(businessId, caseId), &current)
+	return true, nil
+}
+
+func (r *casesRepository) ResolveHandoff(_ context.Context, businessId string, caseId string, handoff *model.CaseHandoff) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	_, err := r.resolveHandoff(businessId, caseId, handoff)
+	return err
+}
+
+// resolveHandoff resolves the handoff, and returns the case it was detached from. It must be
+// called with the write lock held.
+func (r *casesRepository) resolveHandoff(businessId string, caseId string, handoff *model.CaseHandoff) (*model.Case, error) {
+	current, err := r.pendingHandoff(businessId, caseId, handoff.Id)
+	if err != nil {
+		return nil, err
+	}
+	current.Handoff = nil
+	r.store.put(handoffPath(businessId, caseId, handoff.Id), handoff)
+	r.store.put(casePath(businessId, caseId), current)
+	return current, nil
+}
+
+// pendingHandoff returns the case if the handoff is its pending handoff, or ErrHandoffResolved.
+func (r *casesRepository) pendingHandoff(businessId string, caseId string, handoffId string) (*model.Case, error) {
+	var current model.Case
+	if err := r.store.get(casePath(businessId, caseId), &current); err != nil {
+		return nil, err
+	}
+	if current.Handoff == nil || current.Handoff.Id != handoffId || current.Handoff.Status != model.HandoffPending {
+		return nil, definition.ErrHandoffResolved
+	}
+	current.Id = caseId
+	return &current, nil
+}
+
+func (r *casesRepository) ExpireHandoffs(_ context.Context, now time.Time) ([]*model.Case, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var expired []*model.Case
+	for path := range r.store.docs {
+		segments := strings.Split(path, "/")
+		if len(segments) != 6 || segments[0] != db.Businesses || segments[2] != db.Cases || segments[4] != db.Handoffs {
+			continue
+		}
+		var handoff model.CaseHandoff
+		if err := r.store.get(path, &handoff); err != nil {
+			return nil, err
+		}
+		if handoff.Status != model.HandoffPending || handoff.ExpiresDate == nil || !handoff.ExpiresDate.Before(now) {
+			continue
+		}
+		handoff.Id = segments[5]
+		handoff.Status, handoff.ResolvedDate = model.HandoffExpired, &now
+		businessCase, err := r.resolveHandoff(segments[1], segments[3], &handoff)
+		if err == definition.ErrHandoffResolved {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		businessCase.Handoff = &handoff
+		expired = append(expired, businessCase)
+	}
+	return expired, nil
+}
+
+func (r *casesRepository) Forward(ctx context.Context, businessId string, businessCase *model.Case, chat *model.TextSession,
+	handoff *model.CaseHandoff, forwarded definition.Forwarded) error {
+	if err := r.forward(businessId, businessCase, chat, handoff); err != nil {
+		return err
+	}
+	publish(ctx, r.publisher, forwarded.Events(chat)...)
+	return nil
+}
+
+func (r *casesRepository) forward(businessId string, businessCase *model.Case, chat *model.TextSession, handoff *model.CaseHandoff) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	current, err := r.pendingHandoff(businessId, businessCase.Id, handoff.Id)
+	if err != nil {
+		return err
+	}
+	if current.TextSessionId != businessCase.TextSessionId {
+		return definition.ErrHandoffResolved
+	}
+	var fromChat model.TextSession
+	if err = r.store.get(chatPath(businessCase.TextSessionId), &fromChat); err != nil {
+		return err
+	}
+	if len(chat.Id) == 0 {
+		chat.Id = newID()
+		chat.Case.TextSessionId = chat.Id
+	}
+	current.TextSessionId, current.Associate, current.ForwardedDate = chat.Case.TextSessionId, chat.Case.Associate, chat.Case.ForwardedDate
+	current.Handoff = nil
+	fromChat.Case, fromChat.LastMessage = nil, nil
+	r.store.put(chatPath(chat.Id), chat)
+	r.store.put(join(chatPath(chat.Id), db.Cases, businessCase.Id), chat.Case)
+	r.store.put(handoffPath(businessId, businessCase.Id, handoff.Id), handoff)
+	r.store.put(casePath(businessId, businessCase.Id), current)
+	r.store.put(chatPath(businessCase.TextSessionId), &fromChat)
+	r.store.delete(join(chatPath(businessCase.TextSessionId), db.Cases, businessCase.Id))
+	return nil
+}
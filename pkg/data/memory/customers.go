@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type customersRepository struct {
+	store *Store
+}
+
+func NewCustomersRepository(store *Store) definition.CustomersRepository {
+	return &customersRepository{store}
+}
+
+func businessCustomerPath(businessId string, customerId string) string {
+	return join(db.Businesses, businessId, db.BusinessCustomers, customerId)
+}
+
+func blockedUserPath(userId string, blockedId string) string {
+	return join(db.Users, userId, db.BlockList, blockedId)
+}
+
+func (r *customersRepository) FindById(_ context.Context, customerId string) (*model.Customer, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	return r.find(join(db.Users, customerId), customerId)
+}
+
+func (r *customersRepository) FindBusinessCustomer(_ context.Context, businessId string, customerId string) (*model.Customer, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	return r.find(businessCustomerPath(businessId, customerId), customerId)
+}
+
+func (r *customersRepository) FindByEmail(_ context.Context, email string) (*model.Customer, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, id := range r.store.ids(db.Users) {
+		customer, err := r.find(join(db.Users, id), id)
+		if err != nil {
+			return nil, err
+		}
+		if customer.Email == email {
+			return customer, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "no user with the email %s", email)
+}
+
+func (r *customersRepository) find(path string, customerId string) (*model.Customer, error) {
+	var customer model.Customer
+	if err := r.store.get(path, &customer); err != nil {
+		return nil, err
+	}
+	customer.Id = customerId
+	return &customer, nil
+}
+
+func (r *customersRepository) HasBlocked(_ context.Context, userId string, userIDs []string) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, blockedId := range userIDs {
+		if r.store.exists(blockedUserPath(userId, blockedId)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *customersRepository) Block(_ context.Context, businessId string, customer *model.Customer, associateId string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	businessCustomer, err := r.find(businessCustomerPath(businessId, customer.Id), customer.Id)
+	if err != nil {
+		return err
+	}
+	if r.store.exists(blockedUserPath(associateId, customer.Id)) {
+		return alreadyExists(blockedUserPath(associateId, customer.Id))
+	}
+	chats, err := r.chats(customer.Id, associateId)
+	if err != nil {
+		return err
+	}
+	if !common.StringArrayIncludes(businessCustomer.InBlocked, associateId) {
+		businessCustomer.InBlocked = append(businessCustomer.InBlocked, associateId)
+	}
+	r.store.put(businessCustomerPath(businessId, customer.Id), businessCustomer)
+	for _, chat := range chats {
+		if chat.BlockList == nil {
+			chat.BlockList = map[string]string{}
+		}
+		chat.BlockList[associateId] = customer.Id
+		r.store.put(chatPath(chat.Id), chat)
+	}
+	r.store.put(blockedUserPath(associateId, customer.Id), &data.BlockedCustomer{
+		Id:          customer.Id,
+		Name:        customer.FullName,
+		Description: "Customer",
+		PhotoUrl:    customer.PhotoUrl,
+		CreatedDate: time.Now(),
+	})
+	return nil
+}
+
+func (r *customersRepository) Unblock(_ context.Context, businessId string, customerId string, associateId string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	businessCustomer, err := r.find(businessCustomerPath(businessId, customerId), customerId)
+	if err != nil {
+		return err
+	}
+	chats, err := r.chats(customerId, associateId)
+	if err != nil {
+		return err
+	}
+	var blocked []string
+	for _, id := range businessCustomer.InBlocked {
+		if id != associateId {
+			blocked = append(blocked, id)
+		}
+	}
+	businessCustomer.InBlocked = blocked
+	r.store.put(businessCustomerPath(businessId, customerId), businessCustomer)
+	for _, chat := range chats {
+		delete(chat.BlockList, associateId)
+		r.store.put(chatPath(chat.Id), chat)
+	}
+	r.store.delete(blockedUserPath(associateId, customerId))
+	return nil
+}
+
+// chats returns the chats of the customer with the associate.
+func (r *customersRepository) chats(customerId string, associateId string) ([]*model.TextSession, error) {
+	var chats []*model.TextSession
+	for _, id := range r.store.ids(db.TextSessions) {
+		var chat model.TextSession
+		if err := r.store.get(chatPath(id), &chat); err != nil {
+			return nil, err
+		}
+		if chat.Customer != nil && chat.Customer.Id == customerId && common.StringArrayIncludes(chat.MemberIDs, associateId) {
+			chat.Id = id
+			chats = append(chats, &chat)
+		}
+	}
+	return chats, nil
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+)
+
+type directoryRepository struct {
+	store *Store
+}
+
+func NewDirectoryRepository(store *Store) definition.DirectoryRepository {
+	return &directoryRepository{store}
+}
+
+func directoryPath(businessId string) string {
+	return join(db.Businesses, businessId, db.Directory)
+}
+
+func (r *directoryRepository) FindById(_ context.Context, businessId string, contactId string) (*model.Contact, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var contact model.Contact
+	if err := r.store.get(join(directoryPath(businessId), contactId), &contact); err != nil {
+		return nil, err
+	}
+	contact.Id = contactId
+	return &contact, nil
+}
+
+func (r *directoryRepository) FindVisible(_ context.Context, businessId string) ([]*model.DirectoryContact, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var contacts []*model.DirectoryContact
+	for _, id := range r.store.ids(directoryPath(businessId)) {
+		var contact model.DirectoryContact
+		if err := r.store.get(join(directoryPath(businessId), id), &contact); err != nil {
+			return nil, err
+		}
+		if contact.Rules == nil || contact.Rules.Visibility == nil || !contact.Rules.Visibility.Visible {
+			continue
+		}
+		contact.Id = id
+		contacts = append(contacts, &contact)
+	}
+	sort.SliceStable(contacts, func(i, j int) bool {
+		return contacts[i].FlatIndex < contacts[j].FlatIndex
+	})
+	return contacts, nil
+}
+
+func (r *directoryRepository) FindByAssociate(_ context.Context, businessId string, uid string) (*model.Contact, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, id := range r.store.ids(directoryPath(businessId)) {
+		var contact model.Contact
+		if err := r.store.get(join(directoryPath(businessId), id), &contact); err != nil {
+			return nil, err
+		}
+		if contact.Associate != nil && contact.Associate.Id == uid {
+			contact.Id = id
+			return &contact, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *directoryRepository) FindByIds(_ context.Context, businessId string, ids []string) ([]*model.DirectoryContact, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var contacts []*model.DirectoryContact
+	for _, id := range ids {
+		var contact model.DirectoryContact
+		if err := r.store.get(join(directoryPath(businessId), id), &contact); err != nil {
+			return nil, err
+		}
+		contact.Id = id
+		contacts = append(contacts, &contact)
+	}
+	return contacts, nil
+}
@@ -0,0 +1,20 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+)
+
+// publish publishes the events of a write once it is done. The writes of the store can't fail
+// once they started, so the events are published if and only if the write is done, as they are
+// with Firestore. A failure to publish them does not fail the write.
+func publish(ctx context.Context, publisher events.Publisher, posted ...events.Event) {
+	if len(posted) == 0 {
+		return
+	}
+	if err := publisher.Publish(ctx, posted...); err != nil {
+		logger.FromContext(ctx).Error(ctx, "failed to publish the events", "error", err)
+	}
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+)
+
+type messagesRepository struct {
+	store     *Store
+	publisher events.Publisher
+}
+
+func NewMessagesRepository(store *Store, publisher events.Publisher) definition.MessagesRepository {
+	return &messagesRepository{store, publisher}
+}
+
+func messagesPath(chatId string) string {
+	return join(chatPath(chatId), db.Messages)
+}
+
+func (r *messagesRepository) Save(ctx context.Context, chatId string, message *model.Message, posted definition.Posted) (*model.Message, error) {
+	r.saveAll(chatId, message)
+	publish(ctx, r.publisher, posted.Events(message)...)
+	return message, nil
+}
+
+func (r *messagesRepository) SaveAll(ctx context.Context, chatId string, messages []*model.Message, posted definition.Posted) ([]*model.Message, error) {
+	r.saveAll(chatId, messages...)
+	publish(ctx, r.publisher, posted.Events(messages...)...)
+	return messages, nil
+}
+
+func (r *messagesRepository) saveAll(chatId string, messages ...*model.Message) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, message := range messages {
+		r.save(chatId, message)
+	}
+}
+
+func (r *messagesRepository) save(chatId string, message *model.Message) {
+	message.Id = newID()
+	stored := *message
+	now := serverTime()
+	stored.CreatedDate = &now
+	r.store.put(join(messagesPath(chatId), message.Id), &stored)
+}
+
+func (r *messagesRepository) Delete(_ context.Context, chatId string, messageId string) (string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.delete(join(messagesPath(chatId), messageId))
+	return messageId, nil
+}
+
+func (r *messagesRepository) FindBetween(_ context.Context, chatId string, after *time.Time, until *time.Time) ([]*model.Message, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var messages []*model.Message
+	for _, id := range r.store.ids(messagesPath(chatId)) {
+		var message model.Message
+		if err := r.store.get(join(messagesPath(chatId), id), &message); err != nil {
+			return nil, err
+		}
+		created := message.CreatedDate
+		if created == nil || after != nil && !created.After(*after) || until != nil && created.After(*until) {
+			continue
+		}
+		message.Id = id
+		messages = append(messages, &message)
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedDate.Before(*messages[j].CreatedDate)
+	})
+	return messages, nil
+}
+
+// moveLimit is the most messages Move moves.
+const moveLimit = 100
+
+func (r *messagesRepository) Move(_ context.Context, fromChatId string, toChatId string, since *time.Time,
+	memberIDs []string, exclude []model.MessageType) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var messages []*model.Message
+	for _, id := range r.store.ids(messagesPath(fromChatId)) {
+		var message model.Message
+		if err := r.store.get(join(messagesPath(fromChatId), id), &message); err != nil {
+			return err
+		}
+		if message.CreatedDate == nil || since != nil && message.CreatedDate.Before(*since) {
+			continue
+		}
+		message.Id = id
+		messages = append(messages, &message)
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedDate.Before(*messages[j].CreatedDate)
+	})
+	if len(messages) > moveLimit {
+		messages = messages[:moveLimit]
+	}
+	for _, message := range messages {
+		if excludes(exclude, message.Type) {
+			continue
+		}
+		r.store.delete(join(messagesPath(fromChatId), message.Id))
+		now := time.Now()
+		message.Id, message.TextSessionId, message.MemberIDs, message.CreatedDate = newID(), toChatId, memberIDs, &now
+		r.store.put(join(messagesPath(toChatId), message.Id), message)
+	}
+	return nil
+}
+
+func excludes(types []model.MessageType, messageType model.MessageType) bool {
+	for _, t := range types {
+		if t == messageType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+)
+
+type notesRepository struct {
+	store *Store
+}
+
+func NewNotesRepository(store *Store) definition.NotesRepository {
+	return &notesRepository{store}
+}
+
+func (r *notesRepository) FindCaseNotes(_ context.Context, businessId string, caseId string) ([]*model.Note, error) {
+	return r.find(join(casePath(businessId, caseId), db.Notes))
+}
+
+func (r *notesRepository) FindCustomerNotes(_ context.Context, businessId string, customerId string) ([]*model.Note, error) {
+	return r.find(join(businessCustomerPath(businessId, customerId), db.CustomerNotes))
+}
+
+func (r *notesRepository) find(collection string) ([]*model.Note, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var notes []*model.Note
+	for _, id := range r.store.ids(collection) {
+		var note model.Note
+		if err := r.store.get(join(collection, id), &note); err != nil || note.CreatedDate == nil {
+			continue // as Firestore skips the notes without the field of the order
+		}
+		notes = append(notes, &note)
+	}
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].CreatedDate.Before(*notes[j].CreatedDate)
+	})
+	return notes, nil
+}
@@ -0,0 +1,850 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/memfirestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/outbox"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/memory"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// backend is a data store with its repositories. The tests run against both the in-memory store
+// and Firestore, to check they behave the same.
+type backend struct {
+	put           func(path string, doc interface{})
+	get           func(path string, dst interface{}) error
+	cases         definition.CasesRepository
+	appointments  definition.AppointmentsRepository
+	messages      definition.MessagesRepository
+	directory     definition.DirectoryRepository
+	settings      definition.BusinessSettingsRepository
+	verifications definition.VerificationsRepository
+	customers     definition.CustomersRepository
+	chats         definition.TextSessionsRepository
+	notes         definition.NotesRepository
+	published     *recorder
+}
+
+// recorder records the events published.
+type recorder struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (r *recorder) Publish(_ context.Context, published ...events.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, published...)
+	return nil
+}
+
+func (r *recorder) Events() []events.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]events.Event(nil), r.events...)
+}
+
+func backends(t *testing.T) map[string]*backend {
+	store := memory.NewStore()
+	client, cleanup, err := memfirestore.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cleanup)
+	firestoreDb := &db.Firestore{Client: client}
+	memoryPublished, firestorePublished := &recorder{}, &recorder{}
+	return map[string]*backend{
+		"memory": {
+			put:           store.Put,
+			get:           store.Get,
+			cases:         memory.NewCasesRepository(store, memoryPublished),
+			appointments:  memory.NewAppointmentsRepository(store, memoryPublished),
+			messages:      memory.NewMessagesRepository(store, memoryPublished),
+			directory:     memory.NewDirectoryRepository(store),
+			settings:      memory.NewBusinessSettingsRepository(store),
+			verifications: memory.NewVerificationsRepository(store),
+			customers:     memory.NewCustomersRepository(store),
+			chats:         memory.NewTextSessionsRepository(store, memoryPublished),
+			notes:         memory.NewNotesRepository(store),
+			published:     memoryPublished,
+		},
+		"firestore": {
+			put: func(path string, doc interface{}) {
+				if _, err := client.Doc(path).Set(context.Background(), doc); err != nil {
+					t.Fatal(err)
+				}
+			},
+			get: func(path string, dst interface{}) error {
+				snapshot, err := client.Doc(path).Get(context.Background())
+				if err != nil {
+					return err
+				}
+				return snapshot.DataTo(dst)
+			},
+			cases:         data.NewCasesRepository(firestoreDb, firestorePublished),
+			appointments:  data.NewAppointmentsRepo(client, firestorePublished),
+			messages:      data.NewMessagesRepo(firestoreDb, firestorePublished),
+			directory:     data.NewDirectoryRepository(firestoreDb),
+			settings:      data.NewBusinessSettingsRepository(client),
+			verifications: data.NewVerificationsRepository(client),
+			customers:     data.NewCustomersRepository(firestoreDb),
+			chats:         data.NewTextSessionsRepo(firestoreDb, firestorePublished),
+			notes:         data.NewNotesRepository(firestoreDb),
+			published:     firestorePublished,
+		},
+	}
+}
+
+// date returns a time Firestore stores without loss.
+func date(hour int) time.Time {
+	return time.Date(2026, time.March, 2, hour, 0, 0, 0, time.UTC)
+}
+
+func TestCases(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			b.put("textSessions/chat1", &model.TextSession{Title: "chat"})
+			opened := date(9)
+			businessCase := &model.Case{
+				Id:            "case1",
+				Business:      &model.BusinessItem{Id: "b1", Name: "Pigeon"},
+				Customer:      &model.CustomerItem{Id: "u1", Name: "Jane"},
+				Associate:     &model.AssociateItem{Id: "c1", Name: "Support"},
+				Status:        model.CaseAccepted,
+				TextSessionId: "chat1",
+				OpenedDate:    &opened,
+				Priority:      2,
+			}
+			if err := b.cases.Save(ctx, "b1", businessCase, &events.CaseAccepted{CaseID: "case1"}); err != nil {
+				t.Fatal(err)
+			}
+			found, err := b.cases.FindById(ctx, "b1", "case1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found.Id != "case1" || found.Status != model.CaseAccepted || !found.OpenedDate.Equal(opened) {
+				t.Errorf("FindById = %+v, want the saved case", found)
+			}
+			chat, err := b.chats.Find("chat1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if chat.Title != "chat" || chat.Case == nil || chat.Case.Status != model.CaseAccepted || chat.Case.Priority != 2 {
+				t.Errorf("chat = %+v, want the chat with the case", chat)
+			}
+			var chatCase model.Case
+			if err := b.get("textSessions/chat1/cases/case1", &chatCase); err != nil || chatCase.Status != model.CaseAccepted {
+				t.Errorf("case of the chat = %+v, %v, want the saved case", chatCase, err)
+			}
+
+			businessCase.Id, businessCase.TextSessionId = "case2", "missing"
+			if err := b.cases.Save(ctx, "b1", businessCase, &events.CaseAccepted{CaseID: "case2"}); status.Code(err) != codes.NotFound {
+				t.Errorf("Save with a missing chat = %v, want NotFound", err)
+			}
+			if published := b.published.Events(); len(published) != 1 || published[0].(*events.CaseAccepted).CaseID != "case1" {
+				t.Errorf("published = %v, want only the case saved", published)
+			}
+			if _, err := b.cases.FindById(ctx, "b1", "case2"); status.Code(err) != codes.NotFound {
+				t.Errorf("FindById of a case not saved = %v, want NotFound", err)
+			}
+
+			b.put("businesses/b1/archive/a1", &model.TextSession{Case: &model.Case{Number: 7}})
+			archived, err := b.cases.FindArchived(ctx, "b1", []string{"a1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(archived) != 1 || archived[0].Id != "a1" || archived[0].Case.Number != 7 {
+				t.Errorf("FindArchived = %+v, want the archived chat a1", archived)
+			}
+			if _, err := b.cases.FindArchived(ctx, "b1", []string{"a1", "missing"}); status.Code(err) != codes.NotFound {
+				t.Errorf("FindArchived with a missing chat = %v, want NotFound", err)
+			}
+		})
+	}
+}
+
+func TestAppointments(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			start, end := date(10), date(11)
+			appointment, err := b.appointments.Save(ctx, &model.Appointment{
+				Business:  &model.BusinessItem{Id: "b1"},
+				Associate: &model.Contact{Id: "c1", Associate: &model.Associate{User: model.User{Id: "a1"}}},
+				StartDate: &start,
+				EndDate:   &end,
+			}, func(appointment *model.Appointment) []events.Event {
+				return []events.Event{&events.AppointmentCreated{AppointmentID: appointment.Id}}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			published := b.published.Events()
+			if len(published) != 1 || published[0].(*events.AppointmentCreated).AppointmentID != appointment.Id {
+				t.Errorf("published = %v, want the appointment created", published)
+			}
+			if booked, err := b.appointments.IsBooked(ctx, "a1", start); err != nil || !booked {
+				t.Errorf("IsBooked(%v) = %v, %v, want true", start, booked, err)
+			}
+
+			found, err := b.appointments.FindById(ctx, "b1", appointment.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found.Id != appointment.Id || !found.StartDate.Equal(start) || found.CreatedDate.IsZero() {
+				t.Errorf("FindById = %+v, want the saved appointment with its creation date", found)
+			}
+
+			moved := date(14)
+			found.StartDate = &moved
+			if err := b.appointments.Update(ctx, found); err != nil {
+				t.Fatal(err)
+			}
+			if booked, _ := b.appointments.IsBooked(ctx, "a1", start); booked {
+				t.Errorf("the former start date is still booked")
+			}
+			if booked, _ := b.appointments.IsBooked(ctx, "a1", moved); !booked {
+				t.Errorf("the new start date is not booked")
+			}
+
+			found.Canceled = true
+			if err := b.appointments.Cancel(ctx, found); err != nil {
+				t.Fatal(err)
+			}
+			if booked, _ := b.appointments.IsBooked(ctx, "a1", moved); booked {
+				t.Errorf("the start date of the canceled appointment is still booked")
+			}
+			if canceled, err := b.appointments.FindById(ctx, "b1", appointment.Id); err != nil || !canceled.Canceled || !canceled.CreatedDate.Equal(found.CreatedDate) {
+				t.Errorf("FindById = %+v, %v, want the canceled appointment with its creation date", canceled, err)
+			}
+
+			if err := b.appointments.DeleteById(ctx, "b1", appointment.Id); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := b.appointments.FindById(ctx, "b1", appointment.Id); status.Code(err) != codes.NotFound {
+				t.Errorf("FindById of a deleted appointment = %v, want NotFound", err)
+			}
+		})
+	}
+}
+
+func TestMessages(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			// the repositories set the creation dates, so the older messages are written as the apps do
+			for i, hour := range []int{12, 9, 10, 11} {
+				b.put(fmt.Sprintf("textSessions/chat1/messages/m%d", i), map[string]interface{}{
+					"text":        date(hour).Format("15"),
+					"createdDate": date(hour),
+				})
+			}
+			if _, err := b.messages.Delete(ctx, "chat1", "m0"); err != nil {
+				t.Fatal(err)
+			}
+			saved, err := b.messages.SaveAll(ctx, "chat1", []*model.Message{{Text: "now"}}, func(messages ...*model.Message) []events.Event {
+				return []events.Event{&events.MessagePosted{TextSessionID: "chat1", MessageID: messages[0].Id}}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			published := b.published.Events()
+			if len(published) != 1 || published[0].(*events.MessagePosted).MessageID != saved[0].Id {
+				t.Errorf("published = %v, want the message posted", published)
+			}
+
+			after, until := date(9), date(11)
+			found, err := b.messages.FindBetween(ctx, "chat1", &after, &until)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var texts []string
+			for _, message := range found {
+				texts = append(texts, message.Text)
+			}
+			if len(texts) != 2 || texts[0] != "10" || texts[1] != "11" {
+				t.Errorf("FindBetween = %v, want [10 11]", texts)
+			}
+
+			all, err := b.messages.FindBetween(ctx, "chat1", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != 4 || all[3].Id != saved[0].Id || all[3].CreatedDate == nil {
+				t.Errorf("FindBetween without dates = %d messages, want 4 ending with the one created now", len(all))
+			}
+		})
+	}
+}
+
+func TestDirectory(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			visible := &model.Rules{Visibility: &model.VisibilityRule{Visible: true}}
+			b.put("businesses/b1/directory/c1", &model.DirectoryContact{Name: "Sales", Rules: visible, FlatIndex: 2})
+			b.put("businesses/b1/directory/c2", &model.DirectoryContact{Name: "Hidden", FlatIndex: 1})
+			b.put("businesses/b1/directory/c3", &model.DirectoryContact{Name: "Support", Rules: visible, FlatIndex: 1, AssociateIDs: []string{"a1"}})
+
+			contacts, err := b.directory.FindVisible(ctx, "b1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(contacts) != 2 || contacts[0].Id != "c3" || contacts[1].Id != "c1" {
+				t.Errorf("FindVisible = %+v, want c3 and c1", contacts)
+			}
+
+			contact, err := b.directory.FindById(ctx, "b1", "c3")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if contact.Id != "c3" || contact.Name != "Support" || len(contact.AssociateIDs) != 1 {
+				t.Errorf("FindById = %+v, want the contact c3", contact)
+			}
+			if _, err := b.directory.FindById(ctx, "b1", "missing"); status.Code(err) != codes.NotFound {
+				t.Errorf("FindById of a missing contact = %v, want NotFound", err)
+			}
+		})
+	}
+}
+
+func TestSettings(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			b.put("settings/b1", &model.Settings{
+				TimeZone: "UTC+02:00",
+				Appoints: &model.Appoints{
+					Active:      true,
+					AppointDays: []*model.WorkingDay{{Name: "Monday", Active: true}},
+					Contact:     &model.AssociateItem{Id: "c1"},
+				},
+			})
+			settings, err := b.settings.FindById(ctx, "b1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if settings.Id != "b1" || settings.Appoints == nil || settings.Appoints.AppointDay(time.Monday) == nil {
+				t.Errorf("FindById = %+v, want the settings with the appointments on Monday", settings)
+			}
+			if _, err := b.settings.FindById(ctx, "missing"); status.Code(err) != codes.NotFound {
+				t.Errorf("FindById of missing settings = %v, want NotFound", err)
+			}
+		})
+	}
+}
+
+func TestVerifications(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			verification, err := b.verifications.Create(ctx, &model.Verification{Email: "jane@example.com", Skey: "key", Created: date(9)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			found, err := b.verifications.FindByEmail(ctx, "jane@example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found.Id != verification.Id || found.Skey != "key" || found.Verified {
+				t.Errorf("FindByEmail = %+v, want the pending verification", found)
+			}
+			if _, err := b.verifications.FindByEmail(ctx, "john@example.com"); status.Code(err) != codes.NotFound {
+				t.Errorf("FindByEmail of an unknown email = %v, want NotFound", err)
+			}
+
+			if err := b.verifications.Verify(ctx, verification.Id, true); err != nil {
+				t.Fatal(err)
+			}
+			if verified, err := b.verifications.FindById(ctx, verification.Id); err != nil || !verified.Verified || verified.Skey != "" {
+				t.Errorf("FindById = %+v, %v, want the verified verification without its key", verified, err)
+			}
+			if err := b.verifications.Verify(ctx, "missing", false); status.Code(err) != codes.NotFound {
+				t.Errorf("Verify of a missing verification = %v, want NotFound", err)
+			}
+
+			if err := b.verifications.Delete(ctx, verification.Id); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := b.verifications.FindById(ctx, verification.Id); status.Code(err) != codes.NotFound {
+				t.Errorf("FindById of a deleted verification = %v, want NotFound", err)
+			}
+		})
+	}
+}
+
+func TestCustomers(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			jane := &model.Customer{User: model.User{FullName: "Jane"}}
+			b.put("users/u1", jane)
+			b.put("businesses/b1/businessCustomers/u1", jane)
+			b.put("textSessions/chat1", &model.TextSession{Customer: &model.CustomerContact{Id: "u1"}, MemberIDs: []string{"u1", "a1"}})
+			b.put("textSessions/chat2", &model.TextSession{Customer: &model.CustomerContact{Id: "u1"}, MemberIDs: []string{"u1", "a2"}})
+
+			customer, err := b.customers.FindBusinessCustomer(ctx, "b1", "u1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if customer.Id != "u1" || customer.FullName != "Jane" {
+				t.Errorf("FindBusinessCustomer = %+v, want Jane", customer)
+			}
+			if _, err := b.customers.FindById(ctx, "missing"); status.Code(err) != codes.NotFound {
+				t.Errorf("FindById of a missing customer = %v, want NotFound", err)
+			}
+
+			if err := b.customers.Block(ctx, "b1", customer, "a1"); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.customers.Block(ctx, "b1", customer, "a1"); status.Code(err) != codes.AlreadyExists {
+				t.Errorf("second Block = %v, want AlreadyExists", err)
+			}
+			if blocked, err := b.customers.HasBlocked(ctx, "a1", []string{"a0", "u1"}); err != nil || !blocked {
+				t.Errorf("HasBlocked = %v, %v, want true", blocked, err)
+			}
+			if blocked, err := b.customers.HasBlocked(ctx, "u1", []string{"a1"}); err != nil || blocked {
+				t.Errorf("HasBlocked by the customer = %v, %v, want false", blocked, err)
+			}
+			if customer, _ := b.customers.FindBusinessCustomer(ctx, "b1", "u1"); len(customer.InBlocked) != 1 || customer.InBlocked[0] != "a1" {
+				t.Errorf("blocked = %v, want [a1]", customer.InBlocked)
+			}
+			if chat, _ := b.chats.Find("chat1"); chat.BlockList["a1"] != "u1" {
+				t.Errorf("block list of the chat with a1 = %v, want u1 blocked by a1", chat.BlockList)
+			}
+			if chat, _ := b.chats.Find("chat2"); len(chat.BlockList) != 0 {
+				t.Errorf("block list of the chat with a2 = %v, want none", chat.BlockList)
+			}
+
+			if err := b.customers.Unblock(ctx, "b1", "u1", "a1"); err != nil {
+				t.Fatal(err)
+			}
+			if blocked, _ := b.customers.HasBlocked(ctx, "a1", []string{"u1"}); blocked {
+				t.Errorf("the customer is still in the block list of a1")
+			}
+			if customer, _ := b.customers.FindBusinessCustomer(ctx, "b1", "u1"); len(customer.InBlocked) != 0 {
+				t.Errorf("blocked = %v, want none", customer.InBlocked)
+			}
+			if chat, _ := b.chats.Find("chat1"); len(chat.BlockList) != 0 {
+				t.Errorf("block list of the chat with a1 = %v, want none", chat.BlockList)
+			}
+			if err := b.customers.Unblock(ctx, "missing", "u1", "a1"); status.Code(err) != codes.NotFound {
+				t.Errorf("Unblock of a missing business customer = %v, want NotFound", err)
+			}
+		})
+	}
+}
+
+func TestTextSessions(t *testing.T) {
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			customer := &model.Customer{User: model.User{Id: "u1", FullName: "Jane"}}
+			contact := &model.Contact{
+				Id:        "c1",
+				Name:      "Support",
+				Type:      model.ContactTypePersonal,
+				Business:  &model.BusinessItem{Id: "b1"},
+				Associate: &model.Associate{User: model.User{Id: "a1"}},
+			}
+			chat, err := b.chats.CreateActiveTextSession(customer, contact, model.UserTypeCustomer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found, err := b.chats.FindActiveTextSession("u1", "c1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found == nil || found.Id != chat.Id || len(found.MemberIDs) != 2 {
+				t.Errorf("FindActiveTextSession = %+v, want the chat %s of u1 and a1", found, chat.Id)
+			}
+			if found, err := b.chats.FindActiveTextSession("u1", "c2"); err != nil || found != nil {
+				t.Errorf("FindActiveTextSession with another contact = %+v, %v, want none", found, err)
+			}
+			found.Title = "renamed"
+			if err := b.chats.Update(found); err != nil {
+				t.Fatal(err)
+			}
+			if found, err := b.chats.Find(chat.Id); err != nil || found.Title != "renamed" || found.UpdatedDate == nil {
+				t.Errorf("Find = %+v, %v, want the updated chat", found, err)
+			}
+		})
+	}
+}
+
+func TestCaseHandoffs(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			b.put("textSessions/chat1", &model.TextSession{})
+			if err := b.cases.Save(ctx, "b1", &model.Case{
+				Id:            "case1",
+				Business:      &model.BusinessItem{Id: "b1"},
+				Customer:      &model.CustomerItem{Id: "u1"},
+				Associate:     &model.AssociateItem{Id: "c1"},
+				TextSessionId: "chat1",
+			}); err != nil {
+				t.Fatal(err)
+			}
+			requested, expired := date(9), date(10)
+			stale := &model.CaseHandoff{Status: model.HandoffPending, RequestedDate: &requested, ExpiresDate: &expired}
+			if locked, err := b.cases.LockHandoff(ctx, "b1", "case1", stale); err != nil || !locked || stale.Id == "" {
+				t.Fatalf("LockHandoff = %v, %v, want the case locked", locked, err)
+			}
+			expires := time.Now().Add(time.Hour)
+			handoff := &model.CaseHandoff{Status: model.HandoffPending, RequestedDate: &requested, ExpiresDate: &expires}
+			if locked, err := b.cases.LockHandoff(ctx, "b1", "case1", handoff); err != nil || !locked {
+				t.Fatalf("LockHandoff after an expired handoff = %v, %v, want the case locked", locked, err)
+			}
+			var closed model.CaseHandoff
+			if err := b.get("businesses/b1/cases/case1/handoffs/"+stale.Id, &closed); err != nil || closed.Status != model.HandoffExpired {
+				t.Errorf("expired handoff = %+v, %v, want it closed", closed, err)
+			}
+			other := &model.CaseHandoff{Status: model.HandoffPending, RequestedDate: &requested, ExpiresDate: &expires}
+			if locked, err := b.cases.LockHandoff(ctx, "b1", "case1", other); err != nil || locked {
+				t.Errorf("LockHandoff of a locked case = %v, %v, want it refused", locked, err)
+			}
+
+			resolved := time.Now()
+			handoff.Status, handoff.ResolvedDate = model.HandoffAccepted, &resolved
+			if err := b.cases.ResolveHandoff(ctx, "b1", "case1", handoff); err != nil {
+				t.Fatal(err)
+			}
+			found, err := b.cases.FindById(ctx, "b1", "case1")
+			if err != nil || found.Handoff != nil {
+				t.Errorf("FindById after ResolveHandoff = %+v, %v, want the case without a handoff", found, err)
+			}
+			var accepted model.CaseHandoff
+			if err := b.get("businesses/b1/cases/case1/handoffs/"+handoff.Id, &accepted); err != nil || accepted.Status != model.HandoffAccepted {
+				t.Errorf("resolved handoff = %+v, %v, want it accepted", accepted, err)
+			}
+			handoff.Status = model.HandoffDeclined
+			if err := b.cases.ResolveHandoff(ctx, "b1", "case1", handoff); err != definition.ErrHandoffResolved {
+				t.Errorf("ResolveHandoff of a resolved handoff = %v, want ErrHandoffResolved", err)
+			}
+			if err := b.get("businesses/b1/cases/case1/handoffs/"+handoff.Id, &accepted); err != nil || accepted.Status != model.HandoffAccepted {
+				t.Errorf("resolved handoff = %+v, %v, want it left accepted", accepted, err)
+			}
+
+			unanswered := &model.CaseHandoff{Status: model.HandoffPending, RequestedDate: &requested, ExpiresDate: &expired}
+			if locked, err := b.cases.LockHandoff(ctx, "b1", "case1", unanswered); err != nil || !locked {
+				t.Fatalf("LockHandoff = %v, %v, want the case locked", locked, err)
+			}
+			cases, err := b.cases.ExpireHandoffs(ctx, time.Now())
+			if err != nil || len(cases) != 1 || cases[0].Id != "case1" || cases[0].Handoff == nil || cases[0].Handoff.Id != unanswered.Id {
+				t.Fatalf("ExpireHandoffs = %+v, %v, want the case with the unanswered handoff", cases, err)
+			}
+			if cases[0].Handoff.Status != model.HandoffExpired || cases[0].TextSessionId != "chat1" {
+				t.Errorf("expired case = %+v, want its handoff expired", cases[0])
+			}
+			if found, err := b.cases.FindById(ctx, "b1", "case1"); err != nil || found.Handoff != nil {
+				t.Errorf("FindById after ExpireHandoffs = %+v, %v, want the case unlocked", found, err)
+			}
+			if cases, err := b.cases.ExpireHandoffs(ctx, time.Now()); err != nil || len(cases) != 0 {
+				t.Errorf("ExpireHandoffs again = %+v, %v, want none", cases, err)
+			}
+		})
+	}
+}
+
+func TestCaseForward(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			b.put("textSessions/chat1", &model.TextSession{Title: "from"})
+			businessCase := &model.Case{
+				Id:            "case1",
+				Business:      &model.BusinessItem{Id: "b1"},
+				Customer:      &model.CustomerItem{Id: "u1"},
+				Associate:     &model.AssociateItem{Id: "c1"},
+				TextSessionId: "chat1",
+			}
+			if err := b.cases.Save(ctx, "b1", businessCase); err != nil {
+				t.Fatal(err)
+			}
+			expires := time.Now().Add(time.Hour)
+			handoff := &model.CaseHandoff{Mode: model.HandoffModeImmediate, Status: model.HandoffPending, ExpiresDate: &expires}
+			if locked, err := b.cases.LockHandoff(ctx, "b1", "case1", handoff); err != nil || !locked {
+				t.Fatalf("LockHandoff = %v, %v, want the case locked", locked, err)
+			}
+			forwarded := date(12)
+			chat := &model.TextSession{
+				Id:    "chat2",
+				Title: "to",
+				Case: &model.Case{
+					Id:            "case1",
+					TextSessionId: "chat2",
+					Associate:     &model.AssociateItem{Id: "c2"},
+					ForwardedDate: &forwarded,
+				},
+			}
+			reported := func(chat *model.TextSession) []events.Event {
+				return []events.Event{&events.CaseForwarded{CaseID: "case1", TextSessionID: chat.Id}}
+			}
+			handoff.Status = model.HandoffAccepted
+			if err := b.cases.Forward(ctx, "b1", businessCase, chat, handoff, reported); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.cases.Forward(ctx, "b1", businessCase, chat, handoff, reported); err != definition.ErrHandoffResolved {
+				t.Errorf("Forward with a resolved handoff = %v, want ErrHandoffResolved", err)
+			}
+			if published := b.published.Events(); len(published) != 1 {
+				t.Errorf("published = %v, want CaseForwarded once", published)
+			}
+			var accepted model.CaseHandoff
+			if err := b.get("businesses/b1/cases/case1/handoffs/"+handoff.Id, &accepted); err != nil || accepted.Status != model.HandoffAccepted {
+				t.Errorf("handoff = %+v, %v, want it accepted", accepted, err)
+			}
+			found, err := b.cases.FindById(ctx, "b1", "case1")
+			if err != nil || found.TextSessionId != "chat2" || found.Associate.Id != "c2" || !found.ForwardedDate.Equal(forwarded) || found.Handoff != nil {
+				t.Errorf("FindById = %+v, %v, want the case forwarded to chat2", found, err)
+			}
+			if from, err := b.chats.Find("chat1"); err != nil || from.Case != nil {
+				t.Errorf("forwarded chat = %+v, %v, want it without the case", from, err)
+			}
+			if to, err := b.chats.Find("chat2"); err != nil || to.Title != "to" || to.Case == nil || to.Case.Id != "case1" {
+				t.Errorf("chat = %+v, %v, want it with the case", to, err)
+			}
+			var moved model.Case
+			if err := b.get("textSessions/chat1/cases/case1", &moved); status.Code(err) != codes.NotFound {
+				t.Errorf("case of the forwarded chat = %v, want NotFound", err)
+			}
+			if err := b.get("textSessions/chat2/cases/case1", &moved); err != nil || moved.TextSessionId != "chat2" {
+				t.Errorf("case of the chat = %+v, %v, want the forwarded case", moved, err)
+			}
+		})
+	}
+}
+
+func TestMessagesMove(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, hour := range []int{8, 9, 10, 11} {
+				messageType := model.MessageTypeStandard
+				if hour == 10 {
+					messageType = model.MessageTypeCaseClosed
+				}
+				b.put(fmt.Sprintf("textSessions/chat1/messages/m%d", i), map[string]interface{}{
+					"text":        date(hour).Format("15"),
+					"type":        messageType,
+					"createdDate": date(hour),
+				})
+			}
+			since := date(9)
+			err := b.messages.Move(ctx, "chat1", "chat2", &since, []string{"u1", "a2"}, []model.MessageType{model.MessageTypeCaseClosed})
+			if err != nil {
+				t.Fatal(err)
+			}
+			moved, err := b.messages.FindBetween(ctx, "chat2", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(moved) != 2 || moved[0].Text != "09" || moved[1].Text != "11" ||
+				moved[0].TextSessionId != "chat2" || len(moved[0].MemberIDs) != 2 {
+				t.Errorf("moved = %d messages, want 09 and 11 in chat2", len(moved))
+			}
+			left, err := b.messages.FindBetween(ctx, "chat1", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(left) != 2 || left[0].Text != "08" || left[1].Text != "10" {
+				t.Errorf("left = %d messages, want 08 and 10 in chat1", len(left))
+			}
+		})
+	}
+}
+
+func TestNotes(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, hour := range []int{11, 9} {
+				created := date(hour)
+				b.put(fmt.Sprintf("businesses/b1/cases/case1/notes/n%d", i), &model.Note{Text: created.Format("15"), CreatedDate: &created})
+			}
+			created := date(10)
+			b.put("businesses/b1/businessCustomers/u1/customerNotes/n1", &model.Note{Text: "customer", CreatedDate: &created})
+
+			notes, err := b.notes.FindCaseNotes(ctx, "b1", "case1")
+			if err != nil || len(notes) != 2 || notes[0].Text != "09" || notes[1].Text != "11" {
+				t.Errorf("FindCaseNotes = %d notes, %v, want 09 and 11", len(notes), err)
+			}
+			notes, err = b.notes.FindCustomerNotes(ctx, "b1", "u1")
+			if err != nil || len(notes) != 1 || notes[0].Text != "customer" {
+				t.Errorf("FindCustomerNotes = %d notes, %v, want the note of the customer", len(notes), err)
+			}
+		})
+	}
+}
+
+func TestDirectoryAssociates(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			b.put("businesses/b1/directory/c1", &model.Contact{Name: "Jane", Associate: &model.Associate{User: model.User{Id: "a1"}}})
+			b.put("businesses/b1/directory/c2", &model.DirectoryContact{Name: "Support"})
+
+			contact, err := b.directory.FindByAssociate(ctx, "b1", "a1")
+			if err != nil || contact == nil || contact.Id != "c1" {
+				t.Errorf("FindByAssociate = %+v, %v, want c1", contact, err)
+			}
+			if contact, err := b.directory.FindByAssociate(ctx, "b1", "a2"); err != nil || contact != nil {
+				t.Errorf("FindByAssociate of an associate without a contact = %+v, %v, want none", contact, err)
+			}
+			contacts, err := b.directory.FindByIds(ctx, "b1", []string{"c2", "c1"})
+			if err != nil || len(contacts) != 2 || contacts[0].Id != "c2" || contacts[1].Name != "Jane" {
+				t.Errorf("FindByIds = %d contacts, %v, want c2 and c1", len(contacts), err)
+			}
+		})
+	}
+}
+
+func TestCustomersFindByEmail(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			b.put("users/u1", &model.Customer{User: model.User{FullName: "Jane", Email: "jane@example.com"}})
+
+			customer, err := b.customers.FindByEmail(ctx, "jane@example.com")
+			if err != nil || customer.Id != "u1" || customer.FullName != "Jane" {
+				t.Errorf("FindByEmail = %+v, %v, want u1", customer, err)
+			}
+			if _, err := b.customers.FindByEmail(ctx, "john@example.com"); status.Code(err) != codes.NotFound {
+				t.Errorf("FindByEmail of an unknown email = %v, want NotFound", err)
+			}
+		})
+	}
+}
+
+func TestTextSessionsMembers(t *testing.T) {
+	ctx := context.Background()
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			members := model.Members{
+				"a1": {Uid: "a1", Id: "c1", Name: "Jane"},
+				"a2": {Uid: "a2", Id: "c2", Name: "John"},
+			}
+			chat, err := b.chats.Create(ctx, &model.TextSession{
+				Title:      "inner",
+				Type:       model.SessionTypeInner,
+				Subtype:    model.SessionSubtypeDirect,
+				CompoundId: &model.CompoundID{"c1": "c2", "c2": "c1"},
+				Members:    &members,
+				MemberIDs:  members.UIDs(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if chat.Id == "" || chat.Title != "inner" || len(chat.MemberIDs) != 2 {
+				t.Fatalf("Create = %+v, want the chat with its ID", chat)
+			}
+			if _, err := b.messages.Save(ctx, chat.Id, &model.Message{Text: "hi", MemberIDs: chat.MemberIDs}, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			added, err := b.chats.AddMembers(ctx, chat.Id, model.Members{"a3": {Uid: "a3", Id: "c3", Name: "Ann"}}, "group")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if added.Title != "group" || added.Subtype != model.SessionSubtypeGroupExtended || added.CompoundId != nil ||
+				len(added.MemberIDs) != 3 || added.Members.ByID("a1") == nil || added.Members.ByID("a3") == nil {
+				t.Errorf("AddMembers = %+v, want the extended group of a1, a2 and a3", added)
+			}
+			messages, err := b.messages.FindBetween(ctx, chat.Id, nil, nil)
+			if err != nil || len(messages) != 1 || len(messages[0].MemberIDs) != 3 {
+				t.Errorf("messages = %+v, %v, want the message given to the new member", messages, err)
+			}
+
+			message := &model.Message{Text: "Jane has left the chat", Type: model.MessageTypeLeaveChat, TextSessionId: chat.Id}
+			posted := func(messages ...*model.Message) []events.Event {
+				return []events.Event{&events.MessagePosted{TextSessionID: chat.Id, MessageID: messages[0].Id}}
+			}
+			if err := b.chats.Leave(ctx, chat.Id, "a1", message, posted); err != nil {
+				t.Fatal(err)
+			}
+			left, err := b.chats.Find(chat.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if member := left.Members.ByID("a1"); member == nil || !member.Left || !member.Leaved || len(left.MemberIDs) != 2 {
+				t.Errorf("Find after Leave = %+v, want a1 marked as left and removed from the members", left)
+			}
+			published := b.published.Events()
+			if len(published) != 1 || published[0].(*events.MessagePosted).MessageID != message.Id || message.Id == "" {
+				t.Errorf("published = %v, want the message posted", published)
+			}
+			messages, err = b.messages.FindBetween(ctx, chat.Id, nil, nil)
+			if err != nil || len(messages) != 2 || messages[1].Type != model.MessageTypeLeaveChat {
+				t.Errorf("messages after Leave = %d, %v, want the message about it", len(messages), err)
+			}
+		})
+	}
+}
+
+// TestOutboxEvents checks the Firestore repositories write the events to the outbox in the batch
+// of the change, so an event is kept if and only if the change is.
+func TestOutboxEvents(t *testing.T) {
+	ctx := context.Background()
+	client, cleanup, err := memfirestore.NewClient(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	firestoreDb := &db.Firestore{Client: client}
+	publisher := events.NewOutboxPublisher(outbox.New(firestoreDb))
+	cases := data.NewCasesRepository(firestoreDb, publisher)
+	messages := data.NewMessagesRepo(firestoreDb, publisher)
+	entries := func() int {
+		snapshots, err := firestoreDb.Outbox().Documents(ctx).GetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(snapshots)
+	}
+
+	if _, err = client.Doc("textSessions/chat1").Set(ctx, &model.TextSession{}); err != nil {
+		t.Fatal(err)
+	}
+	businessCase := &model.Case{
+		Id:            "case1",
+		Business:      &model.BusinessItem{Id: "b1"},
+		Customer:      &model.CustomerItem{Id: "u1"},
+		Associate:     &model.AssociateItem{Id: "c1"},
+		TextSessionId: "chat1",
+	}
+	if err = cases.Save(ctx, "b1", businessCase, &events.CaseAccepted{CaseID: "case1"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := entries(); n != 1 {
+		t.Errorf("outbox = %d entries after Save, want the event", n)
+	}
+	businessCase.TextSessionId = "missing"
+	if err = cases.Save(ctx, "b1", businessCase, &events.CaseAccepted{CaseID: "case1"}); err == nil {
+		t.Fatal("Save with a missing chat = nil, want an error")
+	}
+	if n := entries(); n != 1 {
+		t.Errorf("outbox = %d entries after a failed Save, want no event", n)
+	}
+
+	posted := func(messages ...*model.Message) []events.Event {
+		return []events.Event{&events.MessagePosted{TextSessionID: "chat1", MessageID: messages[0].Id}}
+	}
+	if _, err = messages.Save(ctx, "chat1", &model.Message{Text: "hi"}, posted); err != nil {
+		t.Fatal(err)
+	}
+	if n := entries(); n != 2 {
+		t.Errorf("outbox = %d entries after the message, want its event", n)
+	}
+}
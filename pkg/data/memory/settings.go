@@ -0,0 +1,28 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+)
+
+type businessSettingsRepository struct {
+	store *Store
+}
+
+func NewBusinessSettingsRepository(store *Store) definition.BusinessSettingsRepository {
+	return &businessSettingsRepository{store}
+}
+
+func (r *businessSettingsRepository) FindById(_ context.Context, businessId string) (*model.Settings, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var settings model.Settings
+	if err := r.store.get(join(db.Settings, businessId), &settings); err != nil {
+		return nil, err
+	}
+	settings.Id = businessId
+	return &settings, nil
+}
@@ -0,0 +1,178 @@
+// Package memory implements the repositories of definition in memory, with the semantics of the
+// Firestore ones. The documents are kept at the paths they have in Firestore, so the writes which
+// span several documents are atomic, as the batches of Firestore are.
+package memory
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Store holds the documents of the repositories. It is safe for concurrent use.
+type Store struct {
+	mu   sync.RWMutex
+	docs map[string]interface{}
+}
+
+func NewStore() *Store {
+	return &Store{docs: map[string]interface{}{}}
+}
+
+// Put writes a copy of the document at the path, e.g. "businesses/b1/directory/c1", as the apps
+// and the functions do for the data the repositories only read. The document is a pointer to a
+// model struct, or a map.
+func (s *Store) Put(path string, doc interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(path, doc)
+}
+
+// Get reads the document at the path into dst, a pointer to a model struct. The document is
+// converted if it was written with another struct, as DataTo does for any struct.
+func (s *Store) Get(path string, dst interface{}) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.get(path, dst)
+}
+
+func (s *Store) put(path string, doc interface{}) {
+	s.docs[path] = clone(reflect.ValueOf(doc)).Interface()
+}
+
+func (s *Store) get(path string, dst interface{}) error {
+	doc, ok := s.docs[path]
+	if !ok {
+		return notFound(path)
+	}
+	if reflect.TypeOf(doc) == reflect.TypeOf(dst) {
+		reflect.ValueOf(dst).Elem().Set(clone(reflect.ValueOf(doc)).Elem())
+		return nil
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+func (s *Store) exists(path string) bool {
+	_, ok := s.docs[path]
+	return ok
+}
+
+func (s *Store) create(path string, doc interface{}) error {
+	if s.exists(path) {
+		return alreadyExists(path)
+	}
+	s.put(path, doc)
+	return nil
+}
+
+func (s *Store) delete(path string) {
+	delete(s.docs, path)
+}
+
+// ids returns the IDs of the documents of the collection, in order, as Firestore lists them.
+func (s *Store) ids(collection string) []string {
+	prefix := collection + "/"
+	var ids []string
+	for path := range s.docs {
+		if id := strings.TrimPrefix(path, prefix); len(id) < len(path) && !strings.Contains(id, "/") {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func notFound(path string) error {
+	return status.Errorf(codes.NotFound, "%s not found", path)
+}
+
+func alreadyExists(path string) error {
+	return status.Errorf(codes.AlreadyExists, "%s already exists", path)
+}
+
+func join(segments ...string) string {
+	return strings.Join(segments, "/")
+}
+
+const idChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// newID returns a random document ID, as NewDoc does.
+func newID() string {
+	id := make([]byte, 20)
+	for i := range id {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(idChars))))
+		if err != nil {
+			panic(fmt.Sprintf("memory: failed to generate an ID: %v", err))
+		}
+		id[i] = idChars[n.Int64()]
+	}
+	return string(id)
+}
+
+// serverTime is the time the serverTimestamp fields are set to when the documents are created.
+func serverTime() time.Time {
+	return time.Now().UTC()
+}
+
+// clone returns a deep copy of v, so the documents don't share memory with the callers.
+func clone(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(clone(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if field := c.Field(i); field.CanSet() {
+				field.Set(clone(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(clone(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), clone(iter.Value()))
+		}
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(clone(v.Elem()))
+		return c
+	default:
+		return v
+	}
+}
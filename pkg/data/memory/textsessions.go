@@ -0,0 +1,175 @@
+package memory
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+)
+
+type textSessionsRepository struct {
+	store     *Store
+	publisher events.Publisher
+}
+
+func NewTextSessionsRepository(store *Store, publisher events.Publisher) definition.TextSessionsRepository {
+	return &textSessionsRepository{store, publisher}
+}
+
+func chatPath(chatId string) string {
+	return join(db.TextSessions, chatId)
+}
+
+func (r *textSessionsRepository) Find(chatId string) (*model.TextSession, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var chat model.TextSession
+	if err := r.store.get(chatPath(chatId), &chat); err != nil {
+		return nil, err
+	}
+	chat.Id = chatId
+	return &chat, nil
+}
+
+func (r *textSessionsRepository) Update(textSession *model.TextSession) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	now := time.Now()
+	textSession.UpdatedDate = &now
+	r.store.put(chatPath(textSession.Id), textSession)
+	return nil
+}
+
+func (r *textSessionsRepository) CreateActiveChatWithCase(customerContact *model.Customer, associateContact *model.Contact,
+	creator int, businessCase *model.Case) (*model.TextSession, error) {
+	sessionData := model.NewActiveTextSession(customerContact, associateContact, creator)
+	sessionData.Id = newID()
+	if businessCase != nil {
+		businessCase.TextSessionId = sessionData.Id
+		sessionData.Case = businessCase
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.put(chatPath(sessionData.Id), sessionData)
+	return sessionData, nil
+}
+
+func (r *textSessionsRepository) CreateActiveTextSession(customerContact *model.Customer, associateContact *model.Contact, creator int) (*model.TextSession, error) {
+	return r.CreateActiveChatWithCase(customerContact, associateContact, creator, nil)
+}
+
+func (r *textSessionsRepository) FindActiveTextSession(customerId string, associateContactId string) (*model.TextSession, error) {
+	return r.findByCompoundId(model.CompoundID{customerId: associateContactId, associateContactId: customerId})
+}
+
+func (r *textSessionsRepository) FindInnerTextSession(contactID string, secondContactID string) (*model.TextSession, error) {
+	return r.findByCompoundId(model.CompoundID{contactID: secondContactID, secondContactID: contactID})
+}
+
+func (r *textSessionsRepository) findByCompoundId(compoundId model.CompoundID) (*model.TextSession, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, id := range r.store.ids(db.TextSessions) {
+		var chat model.TextSession
+		if err := r.store.get(chatPath(id), &chat); err != nil {
+			return nil, err
+		}
+		if chat.CompoundId != nil && reflect.DeepEqual(*chat.CompoundId, compoundId) {
+			chat.Id = id
+			return &chat, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *textSessionsRepository) Create(_ context.Context, chat *model.TextSession) (*model.TextSession, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	id := newID()
+	r.store.put(chatPath(id), chat)
+	var created model.TextSession
+	if err := r.store.get(chatPath(id), &created); err != nil {
+		return nil, err
+	}
+	created.Id = id
+	return &created, nil
+}
+
+func (r *textSessionsRepository) Leave(ctx context.Context, chatId string, uid string, message *model.Message, posted definition.Posted) error {
+	if err := r.leave(chatId, uid, message); err != nil {
+		return err
+	}
+	publish(ctx, r.publisher, posted.Events(message)...)
+	return nil
+}
+
+func (r *textSessionsRepository) leave(chatId string, uid string, message *model.Message) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var chat model.TextSession
+	if err := r.store.get(chatPath(chatId), &chat); err != nil {
+		return err
+	}
+	if chat.Members == nil {
+		chat.Members = &model.Members{}
+	}
+	member := chat.Members.ByID(uid)
+	if member == nil {
+		member = &model.Member{}
+		(*chat.Members)[uid] = member
+	}
+	member.Left, member.Leaved = true, true
+	var memberIDs []string
+	for _, id := range chat.MemberIDs {
+		if id != uid {
+			memberIDs = append(memberIDs, id)
+		}
+	}
+	chat.MemberIDs = memberIDs
+	message.Id = newID()
+	stored := *message
+	now := serverTime()
+	stored.CreatedDate = &now
+	r.store.put(chatPath(chatId), &chat)
+	r.store.put(join(messagesPath(chatId), message.Id), &stored)
+	return nil
+}
+
+func (r *textSessionsRepository) AddMembers(_ context.Context, chatId string, members model.Members, title string) (*model.TextSession, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var chat model.TextSession
+	if err := r.store.get(chatPath(chatId), &chat); err != nil {
+		return nil, err
+	}
+	if chat.Members == nil {
+		chat.Members = &model.Members{}
+	}
+	for uid, member := range members {
+		(*chat.Members)[uid] = member
+		if !common.StringArrayIncludes(chat.MemberIDs, uid) {
+			chat.MemberIDs = append(chat.MemberIDs, uid)
+		}
+	}
+	chat.CompoundId = nil
+	chat.Subtype = model.SessionSubtypeGroupExtended
+	if len(title) > 0 {
+		chat.Title = title
+	}
+	r.store.put(chatPath(chatId), &chat)
+	for _, id := range r.store.ids(messagesPath(chatId)) {
+		var message model.Message
+		if err := r.store.get(join(messagesPath(chatId), id), &message); err != nil {
+			return nil, err
+		}
+		message.MemberIDs = chat.MemberIDs
+		r.store.put(join(messagesPath(chatId), id), &message)
+	}
+	chat.Id = chatId
+	return &chat, nil
+}
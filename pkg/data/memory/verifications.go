@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type verificationsRepository struct {
+	store *Store
+}
+
+func NewVerificationsRepository(store *Store) definition.VerificationsRepository {
+	return &verificationsRepository{store}
+}
+
+func verificationPath(id string) string {
+	return join(data.CollectionVerifications, id)
+}
+
+func (r *verificationsRepository) FindById(_ context.Context, id string) (*model.Verification, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	return r.find(id)
+}
+
+func (r *verificationsRepository) find(id string) (*model.Verification, error) {
+	var verification model.Verification
+	if err := r.store.get(verificationPath(id), &verification); err != nil {
+		return nil, err
+	}
+	verification.Id = id
+	return &verification, nil
+}
+
+func (r *verificationsRepository) FindByEmail(_ context.Context, email string) (*model.Verification, error) {
+	return r.findFirst("email", func(v *model.Verification) bool { return v.Email == email }, email)
+}
+
+func (r *verificationsRepository) FindByCompanyId(_ context.Context, companyId string) (*model.Verification, error) {
+	return r.findFirst("companyId", func(v *model.Verification) bool { return v.CompanyId == companyId }, companyId)
+}
+
+func (r *verificationsRepository) findFirst(path string, match func(*model.Verification) bool, value string) (*model.Verification, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, id := range r.store.ids(data.CollectionVerifications) {
+		verification, err := r.find(id)
+		if err != nil {
+			return nil, err
+		}
+		if match(verification) {
+			return verification, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "no verification with %s %s", path, value)
+}
+
+func (r *verificationsRepository) Create(_ context.Context, verification *model.Verification) (*model.Verification, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	verification.Id = newID()
+	r.store.put(verificationPath(verification.Id), verification)
+	return verification, nil
+}
+
+func (r *verificationsRepository) Verify(_ context.Context, id string, revokeKey bool) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	verification, err := r.find(id)
+	if err != nil {
+		return err
+	}
+	verification.Verified = true
+	if revokeKey {
+		verification.Skey = ""
+	}
+	r.store.put(verificationPath(id), verification)
+	return nil
+}
+
+func (r *verificationsRepository) Delete(_ context.Context, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.delete(verificationPath(id))
+	return nil
+}
@@ -2,26 +2,28 @@ package data
 
 import (
 	"context"
+	"time"
 
+	"cloud.google.com/go/firestore"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+	"google.golang.org/api/iterator"
 )
 
-type MessagesRepository interface {
-	Save(context context.Context, chatId string, data *model.Message) (*model.Message, error)
-	SaveAll(context context.Context, chatId string, data []*model.Message) ([]*model.Message, error)
-	Delete(context context.Context, chatId string, messageId string) (string, error)
-}
-
 type messagesRepository struct {
-	db *db.Firestore
+	db        *db.Firestore
+	publisher events.Publisher
 }
 
-func NewMessagesRepo(db *db.Firestore) MessagesRepository {
-	return &messagesRepository{db}
+func NewMessagesRepo(db *db.Firestore, publisher events.Publisher) definition.MessagesRepository {
+	return &messagesRepository{db, publisher}
 }
 
-func (r *messagesRepository) SaveAll(ctx context.Context, chatId string, data []*model.Message) ([]*model.Message, error) {
+func (r *messagesRepository) SaveAll(ctx context.Context, chatId string, data []*model.Message, posted definition.Posted) (_ []*model.Message, err error) {
+	ctx, done := instrument(ctx, "messages", "SaveAll")
+	defer done(&err)
 	messagesRef := r.db.ChatMessages(chatId)
 	batch := r.db.Batch()
 	for _, message := range data {
@@ -29,22 +31,106 @@ func (r *messagesRepository) SaveAll(ctx context.Context, chatId string, data []
 		message.Id = doc.ID
 		batch.Create(doc, message)
 	}
-	_, err := batch.Commit(ctx)
-	if err != nil {
+	if err = commit(ctx, r.publisher, batch, posted.Events(data...)...); err != nil {
 		return nil, err
 	}
 	return data, nil
 }
 
-func (r *messagesRepository) Save(ctx context.Context, chatId string, message *model.Message) (*model.Message, error) {
-	ref, _, err := r.db.ChatMessages(chatId).Add(ctx, message)
-	if err != nil {
+func (r *messagesRepository) Save(ctx context.Context, chatId string, message *model.Message, posted definition.Posted) (_ *model.Message, err error) {
+	ctx, done := instrument(ctx, "messages", "Save")
+	defer done(&err)
+	ref := r.db.ChatMessages(chatId).NewDoc()
+	message.Id = ref.ID
+	if err = commit(ctx, r.publisher, r.db.Batch().Create(ref, message), posted.Events(message)...); err != nil {
 		return nil, err
 	}
-	message.Id = ref.ID
 	return message, nil
 }
 
-func (r *messagesRepository) Delete(ctx context.Context, chatId string, messageId string) (string, error) {
-	panic("implement me")
+func (r *messagesRepository) Delete(ctx context.Context, chatId string, messageId string) (_ string, err error) {
+	ctx, done := instrument(ctx, "messages", "Delete")
+	defer done(&err)
+	_, err = r.db.ChatMessages(chatId).Doc(messageId).Delete(ctx)
+	if err != nil {
+		return "", err
+	}
+	return messageId, nil
+}
+
+func (r *messagesRepository) FindBetween(ctx context.Context, chatId string, after *time.Time, until *time.Time) (messages []*model.Message, err error) {
+	ctx, done := instrument(ctx, "messages", "FindBetween")
+	defer done(&err)
+	query := r.db.ChatMessages(chatId).OrderBy("createdDate", firestore.Asc)
+	if after != nil {
+		query = query.StartAfter(*after)
+	}
+	if until != nil {
+		query = query.EndAt(*until)
+	}
+	snapshots, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		var message *model.Message
+		if snapshot.DataTo(&message) != nil {
+			continue // legacy message
+		}
+		message.Id = snapshot.Ref.ID
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// moveLimit is the most messages Move moves.
+const moveLimit = 100
+
+func (r *messagesRepository) Move(ctx context.Context, fromChatId string, toChatId string, since *time.Time,
+	memberIDs []string, exclude []model.MessageType) (err error) {
+	ctx, done := instrument(ctx, "messages", "Move")
+	defer done(&err)
+	query := r.db.ChatMessages(fromChatId).OrderBy("createdDate", firestore.Asc)
+	if since != nil {
+		query = query.Where("createdDate", ">=", *since)
+	}
+	documents := query.Limit(moveLimit).Documents(ctx)
+	defer documents.Stop()
+	toMessagesRef := r.db.ChatMessages(toChatId)
+	batch := r.db.Batch()
+	moved := 0
+	for {
+		snapshot, err := documents.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		// the messages are copied as maps, to keep the fields of the legacy ones
+		message := snapshot.Data()
+		messageType, ok := message["type"].(int64)
+		if !ok || excludes(exclude, model.MessageType(messageType)) {
+			continue
+		}
+		message["textSessionId"] = toChatId
+		message["memberIDs"] = memberIDs
+		message["createdDate"] = time.Now()
+		batch.Create(toMessagesRef.NewDoc(), message).Delete(snapshot.Ref)
+		moved++
+	}
+	if moved == 0 {
+		return nil
+	}
+	_, err = batch.Commit(ctx)
+	return err
+}
+
+func excludes(types []model.MessageType, messageType model.MessageType) bool {
+	for _, t := range types {
+		if t == messageType {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+const AuditImpersonationAttempt = "impersonationAttempt"
+
+// An AuditEntry records a security relevant event.
+type AuditEntry struct {
+	Type         string    `firestore:"type" json:"type"`
+	UID          string    `firestore:"uid" json:"uid"`
+	PrincipalUID string    `firestore:"principalUid,omitempty" json:"principalUid,omitempty"`
+	Method       string    `firestore:"method,omitempty" json:"method,omitempty"`
+	Path         string    `firestore:"path,omitempty" json:"path,omitempty"`
+	RemoteAddr   string    `firestore:"remoteAddr,omitempty" json:"remoteAddr,omitempty"`
+	CreatedDate  time.Time `firestore:"createdDate" json:"createdDate"`
+}
@@ -0,0 +1,76 @@
+package model
+
+// ClaimsVersion is the version of the custom claims layout. Tokens with another version are
+// resolved from Firestore until the claims are synced and the token is refreshed.
+const ClaimsVersion int64 = 1
+
+// UserClaims are the Firebase custom claims of an associate.
+type UserClaims struct {
+	Version    int64
+	BusinessID string
+	Associate  bool
+	Admin      bool
+	SuperAdmin bool
+	Disabled   bool
+}
+
+func NewUserClaims(associate *Associate) *UserClaims {
+	claims := &UserClaims{
+		Version:    ClaimsVersion,
+		BusinessID: associate.BusinessID(),
+		Disabled:   associate.Disabled,
+	}
+	if roles := associate.Roles; roles != nil && len(claims.BusinessID) > 0 {
+		claims.Associate = roles.Associate || roles.Admin || roles.SuperAdmin
+		claims.Admin = roles.Admin || roles.SuperAdmin
+		claims.SuperAdmin = roles.SuperAdmin
+	}
+	return claims
+}
+
+func (c *UserClaims) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"claimsVersion": c.Version,
+		"businessId":    c.BusinessID,
+		"associate":     c.Associate,
+		"admin":         c.Admin,
+		"superAdmin":    c.SuperAdmin,
+		"disabled":      c.Disabled,
+	}
+}
+
+// ClaimsFromToken reads the custom claims of a verified ID token.
+func ClaimsFromToken(claims map[string]interface{}) *UserClaims {
+	result := &UserClaims{}
+	switch version := claims["claimsVersion"].(type) {
+	case float64:
+		result.Version = int64(version)
+	case int64:
+		result.Version = version
+	}
+	result.BusinessID, _ = claims["businessId"].(string)
+	result.Associate, _ = claims["associate"].(bool)
+	result.Admin, _ = claims["admin"].(bool)
+	result.SuperAdmin, _ = claims["superAdmin"].(bool)
+	result.Disabled, _ = claims["disabled"].(bool)
+	return result
+}
+
+func (c *UserClaims) IsCurrent() bool {
+	return c != nil && c.Version == ClaimsVersion
+}
+
+// Revokes reports whether the change from the previous claims takes away a privilege,
+// so the tokens carrying the previous claims must not be used anymore.
+func (c *UserClaims) Revokes(previous *UserClaims) bool {
+	if c.Disabled {
+		return true
+	}
+	if previous == nil || !previous.Associate {
+		return false
+	}
+	return c.BusinessID != previous.BusinessID ||
+		(previous.Associate && !c.Associate) ||
+		(previous.Admin && !c.Admin) ||
+		(previous.SuperAdmin && !c.SuperAdmin)
+}
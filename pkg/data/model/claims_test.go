@@ -0,0 +1,30 @@
+package model
+
+import "testing"
+
+func TestUserClaimsRevokes(t *testing.T) {
+	admin := &UserClaims{Version: ClaimsVersion, BusinessID: "b1", Associate: true, Admin: true}
+	tests := []struct {
+		name     string
+		claims   *UserClaims
+		previous *UserClaims
+		want     bool
+	}{
+		{"unchanged", admin, admin, false},
+		{"promoted", admin, &UserClaims{BusinessID: "b1", Associate: true}, false},
+		{"new associate", admin, &UserClaims{}, false},
+		{"no previous claims", admin, nil, false},
+		{"demoted", &UserClaims{BusinessID: "b1", Associate: true}, admin, true},
+		{"removed", &UserClaims{}, admin, true},
+		{"moved to another business", &UserClaims{BusinessID: "b2", Associate: true, Admin: true}, admin, true},
+		{"disabled", &UserClaims{BusinessID: "b1", Associate: true, Admin: true, Disabled: true}, admin, true},
+		{"super admin demoted", admin, &UserClaims{BusinessID: "b1", Associate: true, Admin: true, SuperAdmin: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.claims.Revokes(tt.previous); got != tt.want {
+				t.Errorf("Revokes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+type HandoffMode string
+
+const (
+	HandoffModeImmediate  HandoffMode = "immediate"
+	HandoffModeAcceptance HandoffMode = "acceptance"
+)
+
+type HandoffStatus string
+
+const (
+	HandoffPending  HandoffStatus = "pending"
+	HandoffAccepted HandoffStatus = "accepted"
+	HandoffDeclined HandoffStatus = "declined"
+	HandoffExpired  HandoffStatus = "expired"
+	HandoffFailed   HandoffStatus = "failed"
+)
+
+// A CaseHandoff is a request to move a case to another directory contact.
+// The pending handoff is also kept on the case and locks it for forwarding.
+type CaseHandoff struct {
+	Id            string         `firestore:"id" json:"id"`
+	Mode          HandoffMode    `firestore:"mode" json:"mode"`
+	Status        HandoffStatus  `firestore:"status" json:"status"`
+	RequestedBy   string         `firestore:"requestedBy" json:"requestedBy"`
+	From          *AssociateItem `firestore:"from,omitempty" json:"from,omitempty"`
+	To            *AssociateItem `firestore:"to" json:"to"`
+	ToUIDs        []string       `firestore:"toUIDs" json:"toUIDs"`
+	RequestedDate *time.Time     `firestore:"requestedDate" json:"requestedDate"`
+	ExpiresDate   *time.Time     `firestore:"expiresDate,omitempty" json:"expiresDate,omitempty"`
+	ResolvedDate  *time.Time     `firestore:"resolvedDate,omitempty" json:"resolvedDate,omitempty"`
+	ResolvedBy    string         `firestore:"resolvedBy,omitempty" json:"resolvedBy,omitempty"`
+}
+
+func (h *CaseHandoff) IsPending(now time.Time) bool {
+	if h == nil || h.Status != HandoffPending {
+		return false
+	}
+	return h.ExpiresDate == nil || now.Before(*h.ExpiresDate)
+}
+
+func (h *CaseHandoff) IsRecipient(uid string) bool {
+	for _, id := range h.ToUIDs {
+		if id == uid {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *CaseHandoff) ToName() string {
+	if h.To != nil {
+		return h.To.Name
+	}
+	return ""
+}
+
+func (h *CaseHandoff) FromName() string {
+	if h.From != nil {
+		return h.From.Name
+	}
+	return ""
+}
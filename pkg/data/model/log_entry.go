@@ -2,7 +2,6 @@ package model
 
 import (
 	"encoding/json"
-	"log"
 )
 
 type LogEntry struct {
@@ -10,16 +9,23 @@ type LogEntry struct {
 	Severity  string `json:"severity"`
 	Message   string `json:"message"`
 	Component string `json:"component,omitempty"`
+	InsertID  string `json:"insertId,omitempty"` // to drop the entries delivered twice
+	// the request that logged the entry
+	RequestID  string `json:"requestId,omitempty"`
+	Route      string `json:"route,omitempty"` // template
+	UID        string `json:"uid,omitempty"`
+	BusinessID string `json:"businessId,omitempty"`
+	// the trace of the request that logged the entry
+	TraceID      string            `json:"traceId,omitempty"`
+	SpanID       string            `json:"spanId,omitempty"`
+	TraceContext map[string]string `json:"traceContext,omitempty"`
 }
 
 func (e *LogEntry) String() (out string) {
 	if len(e.Severity) == 0 {
 		e.Severity = "INFO"
 	}
-	bytes, err := json.Marshal(e)
-	if err != nil {
-		log.Printf("json.Marshal: %v\n", err)
-	}
+	bytes, _ := json.Marshal(e) // of strings only, so it does not fail
 	out = string(bytes)
 	return
 }
@@ -28,8 +28,8 @@ type OS struct {
 
 type Feedback struct {
 	RequestedBy *UserItem  `firestore:"requestedBy"`
-	Subject     string     `firestore:"subject"`
-	Message     string     `firestore:"message"`
+	Subject     string     `firestore:"subject" validate:"required,max=200"`
+	Message     string     `firestore:"message" validate:"required,max=5000"`
 	Priority    int        `firestore:"priority"`
 	Status      int        `firestore:"status"`
 	Device      *Device    `firestore:"device"`
@@ -205,6 +205,9 @@ type Message struct {
 	MemberIDs     []string          `firestore:"memberIDs" json:"memberIDs"`
 	CreatedDate   *time.Time        `firestore:"createdDate,serverTimestamp" json:"createdDate,omitempty"`
 	Recipient     *MessageRecipient `firestore:"recipient,omitempty" json:"recipient,omitempty"`
+	// of the case forwarding messages
+	Action           string `firestore:"action,omitempty" json:"action,omitempty"`
+	NewTextSessionId string `firestore:"newTextSessionId,omitempty" json:"newTextSessionId,omitempty"`
 }
 
 // v2.0
@@ -255,6 +258,96 @@ type TextSession struct {
 	Creator        int               `firestore:"creator" json:"creator"`                                   // version 2.0
 	Presence       *Presence         `firestore:"presence,omitempty" json:"presence,omitempty" `
 	Unread         map[string]int    `firestore:"unread,omitempty" json:"unread,omitempty"`
+	BlockList      map[string]string `firestore:"blockList,omitempty" json:"blockList,omitempty"` // customer IDs by the IDs of the associates who blocked them
+}
+
+// NewActiveTextSession returns the active chat of the customer with the directory contact, not yet
+// saved.
+func NewActiveTextSession(customerContact *Customer, associateContact *Contact, creator int) *TextSession {
+	isToPersonalContact := associateContact.Type == ContactTypePersonal
+	compoundId := CompoundID{customerContact.Id: associateContact.Id, associateContact.Id: customerContact.Id}
+
+	now := time.Now()
+	sessionData := &TextSession{
+		CompoundId: &compoundId,
+		Contact:    associateContact,
+		Business:   associateContact.Business,
+		Type:       SessionTypeActive,
+		From: &Person{
+			Id:          customerContact.Id,
+			Name:        customerContact.FullName,
+			Uid:         customerContact.Id,
+			Description: "Customer",
+			Position:    "Customer",
+			Type:        UserTypeCustomer,
+		},
+		To: &Person{
+			Id:          associateContact.Id,
+			Name:        associateContact.Name,
+			Description: associateContact.Position,
+			Position:    associateContact.Position,
+			Type:        UserTypeAssociate,
+		},
+		Members: &Members{
+			customerContact.Id: &Member{
+				Id:          customerContact.Id,
+				Name:        customerContact.FullName,
+				Uid:         customerContact.Id,
+				Type:        UserTypeCustomer,
+				Description: "Customer",
+				PhotoUrl:    customerContact.PhotoUrl,
+				Status:      customerContact.Status,
+			},
+		},
+		Customer: &CustomerContact{
+			Id:          customerContact.Id,
+			Uid:         customerContact.Id,
+			Name:        customerContact.FullName,
+			FullName:    customerContact.FullName,
+			Email:       customerContact.Email,
+			PhoneNumber: customerContact.PhoneNumber,
+			PhotoUrl:    customerContact.PhotoUrl,
+			Status:      customerContact.Status,
+			Permissions: &customerContact.Permissions,
+		},
+		Associate: &AssociateContact{
+			CustomerContact: CustomerContact{
+				Id:          associateContact.Id,
+				Name:        associateContact.Name,
+				FullName:    associateContact.FullName,
+				Email:       associateContact.Email,
+				PhoneNumber: associateContact.PhoneNumber,
+				PhotoUrl:    associateContact.PhotoUrl,
+				Status:      associateContact.Status(),
+			},
+			Position: associateContact.Position,
+		},
+		Creator:     creator, // todo: revise usage!
+		CreatedDate: &now,
+	}
+
+	if isToPersonalContact {
+		sessionData.Subtype = SessionSubtypeDirect
+		associate := associateContact.Associate
+		sessionData.To.Uid = associate.Id
+		sessionData.Associate.Uid = associate.Id
+		sessionData.Associate.FullName = associate.FullName
+	} else {
+		sessionData.Subtype = SessionSubtypeGroup
+	}
+
+	//todo: refactor with contact.associate
+	members := *sessionData.Members
+	if isToPersonalContact {
+		members[associateContact.Associate.Id] = associateContact.ToChatMemberLegacy()
+	} else if len(associateContact.Contacts) > 0 { //todo: refactor with contact.contacts[].associate
+		for _, contact := range associateContact.Contacts {
+			members[contact.Associate.Id] = contact.ToChatMemberLegacy()
+		}
+	}
+
+	sessionData.MemberIDs = sessionData.Members.UIDs()
+	return sessionData
 }
 
 func (ts *TextSession) HasUnread() bool {
@@ -445,24 +538,25 @@ type AssociateItem struct {
 }
 
 type Case struct {
-	Id            string         `firestore:"id,omitempty" json:"id,omitempty"`
-	Name          string         `firestore:"name,omitempty" json:"name,omitempty"`
-	Business      *BusinessItem  `firestore:"business" json:"business"`
-	Closed        bool           `firestore:"closed" json:"closed"`
-	Number        int64          `firestore:"number" json:"number"`
-	Priority      int64          `firestore:"priority" json:"priority"`
-	Status        CaseStatus     `firestore:"status" json:"status"`
-	TextSessionId string         `firestore:"textSessionId" json:"textSessionId"`
-	OpenedDate    *time.Time     `firestore:"openedDate" json:"openedDate"`
-	ClosedDate    *time.Time     `firestore:"closedDate,omitempty" json:"closedDate"`
-	AcceptedDate  *time.Time     `firestore:"acceptedDate" json:"acceptedDate"`
-	RejectedDate  *time.Time     `firestore:"rejectedDate" json:"rejectedDate"`
-	ForwardedDate *time.Time     `firestore:"forwardedDate,omitempty" json:"forwardedDate"`
-	Customer      *CustomerItem  `firestore:"customer" json:"customer"`
-	Associate     *AssociateItem `firestore:"associate,omitempty" json:"associate,omitempty"`
-	Code          string         `firestore:"code,omitempty" json:"code,omitempty"`
-	Forwarding    bool           `firestore:"forwarding,omitempty" json:"forwarding,omitempty"`
-	ClosedBy      AssociateItem  `firestore:"closedBy" json:"closedBy"`
+	Id            string              `firestore:"id,omitempty" json:"id,omitempty"`
+	Name          string              `firestore:"name,omitempty" json:"name,omitempty"`
+	Business      *BusinessItem       `firestore:"business" json:"business"`
+	Closed        bool                `firestore:"closed" json:"closed"`
+	Number        int64               `firestore:"number" json:"number"`
+	Priority      int64               `firestore:"priority" json:"priority"`
+	Status        CaseStatus          `firestore:"status" json:"status"`
+	TextSessionId string              `firestore:"textSessionId" json:"textSessionId"`
+	OpenedDate    *time.Time          `firestore:"openedDate" json:"openedDate"`
+	ClosedDate    *time.Time          `firestore:"closedDate,omitempty" json:"closedDate"`
+	AcceptedDate  *time.Time          `firestore:"acceptedDate" json:"acceptedDate"`
+	RejectedDate  *time.Time          `firestore:"rejectedDate" json:"rejectedDate"`
+	ForwardedDate *time.Time          `firestore:"forwardedDate,omitempty" json:"forwardedDate"`
+	Customer      *CustomerItem       `firestore:"customer" json:"customer"`
+	Associate     *AssociateItem      `firestore:"associate,omitempty" json:"associate,omitempty"`
+	Code          string              `firestore:"code,omitempty" json:"code,omitempty"`
+	Handoff       *CaseHandoff        `firestore:"handoff,omitempty" json:"handoff,omitempty"`
+	ClosedBy      AssociateItem       `firestore:"closedBy" json:"closedBy"`
+	SLA           map[string]*CaseSLA `firestore:"sla,omitempty" json:"sla,omitempty"`
 }
 
 func (c *Case) AssociateName() string {
@@ -545,6 +639,9 @@ type Member struct {
 	Description string `firestore:"description,omitempty" json:"description"`
 	//deprecated
 	ChatAdmin bool `firestore:"chatAdmin,omitempty" json:"chatAdmin,omitempty"`
+	Left      bool `firestore:"left,omitempty" json:"left,omitempty"`
+	//deprecated
+	Leaved bool `firestore:"leaved,omitempty" json:"leaved,omitempty"`
 }
 
 func (m *Members) UIDs() []string {
@@ -1026,6 +1123,18 @@ type Appointment struct {
 	AssistantIDs []string      `firestore:"assistantIDs,omitempty" json:"assistantIDs,omitempty"`
 }
 
+// A Verification is a pending verification of an email, or of a company (deprecated).
+type Verification struct {
+	Id           string                 `firestore:"-" json:"id"`
+	Email        string                 `firestore:"email,omitempty" json:"email,omitempty"`
+	CompanyId    string                 `firestore:"companyId,omitempty" json:"companyId,omitempty"`
+	CompanyEmail string                 `firestore:"companyEmail,omitempty" json:"companyEmail,omitempty"`
+	Company      map[string]interface{} `firestore:"company,omitempty" json:"company,omitempty"`
+	Verified     bool                   `firestore:"verified" json:"verified"`
+	Created      time.Time              `firestore:"created" json:"created"`
+	Skey         string                 `firestore:"skey,omitempty" json:"-"`
+}
+
 type Cals struct {
 	Google  bool `firestore:"google" json:"google"`
 	Ical    bool `firestore:"ical" json:"ical"`
@@ -1033,7 +1142,8 @@ type Cals struct {
 }
 
 type ExportArchiveRequest struct {
-	Ids []string `json:"ids"`
+	Ids          []string `json:"ids" validate:"required,max=100,dive,required"`
+	IncludeNotes bool     `json:"includeNotes"`
 }
 
 func (c *Case) Map() map[string]interface{} {
@@ -1127,6 +1237,25 @@ type Settings struct {
 	WorkingDays      []*WorkingDay    `firestore:"workingDays"`
 	Away             *Away            `firestore:"away"`
 	AccessProtection AccessProtection `firestore:"accessProtection"`
+	SLA              *SLAPolicy       `firestore:"sla,omitempty"`
+	Appoints         *Appoints        `firestore:"appoints,omitempty"`
+}
+
+// Appoints are the appointment settings of a business.
+type Appoints struct {
+	Active      bool           `firestore:"active"`
+	AppointDays []*WorkingDay  `firestore:"appointDays"`
+	Contact     *AssociateItem `firestore:"contact,omitempty"` // the directory contact booked by default
+}
+
+// AppointDay returns the appointment settings of the weekday, or nil.
+func (a *Appoints) AppointDay(weekday time.Weekday) *WorkingDay {
+	for _, day := range a.AppointDays {
+		if day != nil && day.Name == weekday.String() {
+			return day
+		}
+	}
+	return nil
 }
 
 func (s *Settings) ClosedMessage() (string, bool) {
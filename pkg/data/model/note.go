@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+type NoteAttachment struct {
+	Name        string `firestore:"name" json:"name"`
+	Url         string `firestore:"url" json:"url" validate:"required"`
+	ContentType string `firestore:"contentType,omitempty" json:"contentType,omitempty"`
+	Size        int64  `firestore:"size,omitempty" json:"size,omitempty"`
+}
+
+// A NoteRevision is a previous version of an edited note.
+type NoteRevision struct {
+	Text        string            `firestore:"text" json:"text"`
+	Attachments []*NoteAttachment `firestore:"attachments,omitempty" json:"attachments,omitempty"`
+	EditedBy    *AssociateItem    `firestore:"editedBy" json:"editedBy"`
+	EditedDate  *time.Time        `firestore:"editedDate" json:"editedDate"`
+}
+
+// A Note is an internal associate note on a case or on a business customer.
+// Notes are never shown to the customer.
+type Note struct {
+	Id          string            `firestore:"id" json:"id"`
+	Text        string            `firestore:"text" json:"text"`
+	Author      *AssociateItem    `firestore:"author" json:"author"`
+	Mentions    []*AssociateItem  `firestore:"mentions,omitempty" json:"mentions,omitempty"`
+	MentionIDs  []string          `firestore:"mentionIDs,omitempty" json:"mentionIDs,omitempty"`
+	Attachments []*NoteAttachment `firestore:"attachments,omitempty" json:"attachments,omitempty"`
+	History     []*NoteRevision   `firestore:"history,omitempty" json:"history,omitempty"`
+	CreatedDate *time.Time        `firestore:"createdDate" json:"createdDate"`
+	UpdatedDate *time.Time        `firestore:"updatedDate,omitempty" json:"updatedDate,omitempty"`
+}
+
+func (n *Note) AuthorId() string {
+	if n.Author != nil {
+		return n.Author.Id
+	}
+	return ""
+}
+
+func (n *Note) AuthorName() string {
+	if n.Author != nil {
+		return n.Author.Name
+	}
+	return ""
+}
+
+// NewMentionIDs returns the mentioned uids that are not in the previous list.
+func (n *Note) NewMentionIDs(previous []string) (uids []string) {
+	known := make(map[string]bool, len(previous))
+	for _, uid := range previous {
+		known[uid] = true
+	}
+	for _, uid := range n.MentionIDs {
+		if !known[uid] {
+			known[uid] = true
+			uids = append(uids, uid)
+		}
+	}
+	return
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+// A QueueItem is a pending request in the business queue.
+type QueueItem struct {
+	Position      int            `json:"position"`
+	CaseId        string         `json:"caseId"`
+	CaseNumber    int64          `json:"caseNumber"`
+	Priority      int64          `json:"priority"`
+	TextSessionId string         `json:"textSessionId"`
+	Customer      *CustomerItem  `json:"customer"`
+	Contact       *AssociateItem `json:"contact,omitempty"`
+	OpenedDate    *time.Time     `json:"openedDate"`
+	WaitingTime   int64          `json:"waitingTime"`   // seconds
+	EstimatedWait int64          `json:"estimatedWait"` // seconds
+}
+
+// SortQueue orders the requested cases by priority, the oldest first within the same priority.
+func SortQueue(cases []*Case) {
+	sort.SliceStable(cases, func(i, j int) bool {
+		if cases[i].Priority != cases[j].Priority {
+			return cases[i].Priority > cases[j].Priority
+		}
+		if cases[i].OpenedDate == nil || cases[j].OpenedDate == nil {
+			return cases[j].OpenedDate == nil && cases[i].OpenedDate != nil
+		}
+		return cases[i].OpenedDate.Before(*cases[j].OpenedDate)
+	})
+}
+
+// EstimateWait estimates the wait of the request at the 1-based queue position.
+// The online associates take the requests in parallel, each one in the average accept time.
+func EstimateWait(position int, avgAcceptTime time.Duration, onlineAssociates int) time.Duration {
+	if position <= 0 || avgAcceptTime <= 0 {
+		return 0
+	}
+	if onlineAssociates <= 0 {
+		onlineAssociates = 1
+	}
+	rounds := (position + onlineAssociates - 1) / onlineAssociates
+	return time.Duration(rounds) * avgAcceptTime
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateWait(t *testing.T) {
+	tests := []struct {
+		position, online int
+		avg, want        time.Duration
+	}{
+		{1, 1, 2 * time.Minute, 2 * time.Minute},
+		{3, 1, 2 * time.Minute, 6 * time.Minute},
+		{3, 2, 2 * time.Minute, 4 * time.Minute},
+		{2, 2, 2 * time.Minute, 2 * time.Minute},
+		{3, 0, time.Minute, 3 * time.Minute}, // no one online counts as one
+		{0, 1, time.Minute, 0},
+		{1, 1, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := EstimateWait(tt.position, tt.avg, tt.online); got != tt.want {
+			t.Errorf("EstimateWait(%d, %v, %d) = %v, want %v", tt.position, tt.avg, tt.online, got, tt.want)
+		}
+	}
+}
+
+func TestSortQueue(t *testing.T) {
+	opened := time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		date := opened.Add(time.Duration(minutes) * time.Minute)
+		return &date
+	}
+	cases := []*Case{
+		{Id: "low-old", Priority: 0, OpenedDate: at(0)},
+		{Id: "high-new", Priority: 2, OpenedDate: at(5)},
+		{Id: "undated", Priority: 2},
+		{Id: "high-old", Priority: 2, OpenedDate: at(1)},
+		{Id: "low-new", Priority: 0, OpenedDate: at(3)},
+	}
+	SortQueue(cases)
+
+	want := []string{"high-old", "high-new", "undated", "low-old", "low-new"}
+	for i, id := range want {
+		if cases[i].Id != id {
+			t.Fatalf("position %d = %s, want %s (got %v)", i, cases[i].Id, id, ids(cases))
+		}
+	}
+}
+
+func ids(cases []*Case) (ids []string) {
+	for _, c := range cases {
+		ids = append(ids, c.Id)
+	}
+	return
+}
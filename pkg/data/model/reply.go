@@ -0,0 +1,64 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+type ReplyScope string
+
+const (
+	ReplyScopeBusiness ReplyScope = "business"
+	ReplyScopePersonal ReplyScope = "personal"
+)
+
+// Template variables of a reply text.
+const (
+	ReplyVarCustomerName    = "{{customerName}}"
+	ReplyVarBusinessName    = "{{businessName}}"
+	ReplyVarAssociateName   = "{{associateName}}"
+	ReplyVarNextOpeningTime = "{{nextOpeningTime}}"
+)
+
+// A Reply is a canned reply. Business replies are shared by all the associates of the business,
+// personal replies are kept by the associate.
+type Reply struct {
+	Id           string         `firestore:"id" json:"id"`
+	Scope        ReplyScope     `firestore:"scope" json:"scope"`
+	BusinessId   string         `firestore:"businessId" json:"businessId"`
+	OwnerId      string         `firestore:"ownerId,omitempty" json:"ownerId,omitempty"`
+	Folder       string         `firestore:"folder,omitempty" json:"folder,omitempty"`
+	Shortcut     string         `firestore:"shortcut,omitempty" json:"shortcut,omitempty"`
+	Title        string         `firestore:"title" json:"title"`
+	Text         string         `firestore:"text" json:"text"`
+	UsageCount   int64          `firestore:"usageCount" json:"usageCount"`
+	LastUsedDate *time.Time     `firestore:"lastUsedDate,omitempty" json:"lastUsedDate,omitempty"`
+	CreatedBy    *AssociateItem `firestore:"createdBy" json:"createdBy"`
+	CreatedDate  *time.Time     `firestore:"createdDate" json:"createdDate"`
+	UpdatedDate  *time.Time     `firestore:"updatedDate,omitempty" json:"updatedDate,omitempty"`
+}
+
+// IsStale reports whether the reply has not been used since the given date.
+func (r *Reply) IsStale(since time.Time) bool {
+	if r.LastUsedDate != nil {
+		return r.LastUsedDate.Before(since)
+	}
+	return r.CreatedDate == nil || r.CreatedDate.Before(since)
+}
+
+type ReplyVariables struct {
+	CustomerName    string
+	BusinessName    string
+	AssociateName   string
+	NextOpeningTime string
+}
+
+// Render substitutes the template variables of the reply text.
+func (r *Reply) Render(vars ReplyVariables) string {
+	return strings.NewReplacer(
+		ReplyVarCustomerName, vars.CustomerName,
+		ReplyVarBusinessName, vars.BusinessName,
+		ReplyVarAssociateName, vars.AssociateName,
+		ReplyVarNextOpeningTime, vars.NextOpeningTime,
+	).Replace(r.Text)
+}
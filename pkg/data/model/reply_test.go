@@ -0,0 +1,47 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplyRender(t *testing.T) {
+	vars := ReplyVariables{CustomerName: "Ann", BusinessName: "Acme", AssociateName: "Bob", NextOpeningTime: "9:00"}
+	tests := []struct {
+		text, want string
+	}{
+		{"Hi {{customerName}}, this is {{associateName}} of {{businessName}}.", "Hi Ann, this is Bob of Acme."},
+		{"We open at {{nextOpeningTime}}. {{customerName}}, {{customerName}}!", "We open at 9:00. Ann, Ann!"},
+		{"No variables", "No variables"},
+		{"Unknown {{caseNumber}}", "Unknown {{caseNumber}}"},
+	}
+	for _, tt := range tests {
+		reply := &Reply{Text: tt.text}
+		if got := reply.Render(vars); got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestReplyIsStale(t *testing.T) {
+	since := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	before, after := since.Add(-time.Hour), since.Add(time.Hour)
+	tests := []struct {
+		name     string
+		created  *time.Time
+		lastUsed *time.Time
+		want     bool
+	}{
+		{"used since", &before, &after, false},
+		{"used before", &before, &before, true},
+		{"created since, never used", &after, nil, false},
+		{"created before, never used", &before, nil, true},
+		{"no dates", nil, nil, true},
+	}
+	for _, tt := range tests {
+		reply := &Reply{CreatedDate: tt.created, LastUsedDate: tt.lastUsed}
+		if got := reply.IsStale(since); got != tt.want {
+			t.Errorf("%s: IsStale = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package model
+
+import "time"
+
+type SLAMetric string
+
+const (
+	SLAMetricAccept        SLAMetric = "accept"
+	SLAMetricFirstResponse SLAMetric = "firstResponse"
+	SLAMetricResolve       SLAMetric = "resolve"
+)
+
+var SLAMetrics = []SLAMetric{SLAMetricAccept, SLAMetricFirstResponse, SLAMetricResolve}
+
+type SLAStatus string
+
+const (
+	SLAStatusOK       SLAStatus = "ok"
+	SLAStatusWarning  SLAStatus = "warning"
+	SLAStatusBreached SLAStatus = "breached"
+	SLAStatusMet      SLAStatus = "met"
+	SLAStatusMissed   SLAStatus = "missed" // met after the due date
+)
+
+const defaultSLAWarningPercent = 80
+
+// An SLATarget holds time limits in minutes. Zero means the metric is not tracked.
+type SLATarget struct {
+	Priority          int64 `firestore:"priority" json:"priority" validate:"min=0"`
+	AcceptTime        int64 `firestore:"acceptTime" json:"acceptTime" validate:"min=0"`
+	FirstResponseTime int64 `firestore:"firstResponseTime" json:"firstResponseTime" validate:"min=0"`
+	ResolveTime       int64 `firestore:"resolveTime" json:"resolveTime" validate:"min=0"`
+}
+
+func (t *SLATarget) Limit(metric SLAMetric) time.Duration {
+	if t == nil {
+		return 0
+	}
+	switch metric {
+	case SLAMetricAccept:
+		return time.Duration(t.AcceptTime) * time.Minute
+	case SLAMetricFirstResponse:
+		return time.Duration(t.FirstResponseTime) * time.Minute
+	case SLAMetricResolve:
+		return time.Duration(t.ResolveTime) * time.Minute
+	}
+	return 0
+}
+
+// An SLAPolicy is stored in the business settings and scoped by case priority.
+type SLAPolicy struct {
+	Active          bool            `firestore:"active" json:"active"`
+	Default         *SLATarget      `firestore:"default,omitempty" json:"default,omitempty"`
+	Priorities      []*SLATarget    `firestore:"priorities,omitempty" json:"priorities,omitempty" validate:"max=20"`
+	WarningPercent  int64           `firestore:"warningPercent" json:"warningPercent" validate:"min=0,max=100"`
+	Supervisors     []*AlertContact `firestore:"supervisors,omitempty" json:"supervisors,omitempty" validate:"max=50"`
+	Emails          []string        `firestore:"emails,omitempty" json:"emails,omitempty" validate:"max=50,dive,email"`
+	EscalateByEmail bool            `firestore:"escalateByEmail" json:"escalateByEmail"`
+	EscalateByPush  bool            `firestore:"escalateByPush" json:"escalateByPush"`
+}
+
+func (p *SLAPolicy) Target(priority int64) *SLATarget {
+	for _, target := range p.Priorities {
+		if target != nil && target.Priority == priority {
+			return target
+		}
+	}
+	return p.Default
+}
+
+func (p *SLAPolicy) warningPercent() int64 {
+	if p.WarningPercent <= 0 || p.WarningPercent >= 100 {
+		return defaultSLAWarningPercent
+	}
+	return p.WarningPercent
+}
+
+func (p *SLAPolicy) SupervisorEmails() (emails []string) {
+	emails = append(emails, p.Emails...)
+	for _, contact := range p.Supervisors {
+		if contact != nil && len(contact.Email) > 0 {
+			emails = append(emails, contact.Email)
+		}
+	}
+	return
+}
+
+func (p *SLAPolicy) SupervisorUIDs() (uids []string) {
+	for _, contact := range p.Supervisors {
+		if contact != nil && len(contact.UID) > 0 {
+			uids = append(uids, contact.UID)
+		}
+	}
+	return
+}
+
+// A CaseSLA is the state of a single SLA metric of a case.
+type CaseSLA struct {
+	Status          SLAStatus  `firestore:"status" json:"status"`
+	DueDate         *time.Time `firestore:"dueDate" json:"dueDate"`
+	MetDate         *time.Time `firestore:"metDate,omitempty" json:"metDate,omitempty"`
+	EscalatedStatus SLAStatus  `firestore:"escalatedStatus,omitempty" json:"escalatedStatus,omitempty"`
+	EscalatedDate   *time.Time `firestore:"escalatedDate,omitempty" json:"escalatedDate,omitempty"`
+}
+
+// NeedsEscalation reports whether the state has reached a level that has not been escalated yet.
+func (s *CaseSLA) NeedsEscalation() bool {
+	switch s.Status {
+	case SLAStatusWarning:
+		return len(s.EscalatedStatus) == 0
+	case SLAStatusBreached:
+		return s.EscalatedStatus != SLAStatusBreached
+	}
+	return false
+}
+
+// Evaluate calculates the state of every tracked metric of the case.
+// The firstResponse is the date of the first associate message, if any.
+// The previous state is used to keep escalation marks.
+func (p *SLAPolicy) Evaluate(c *Case, firstResponse *time.Time, previous map[string]*CaseSLA, now time.Time) map[string]*CaseSLA {
+	result := make(map[string]*CaseSLA)
+	if c == nil || c.OpenedDate == nil {
+		return result
+	}
+	target := p.Target(c.Priority)
+	for _, metric := range SLAMetrics {
+		limit := target.Limit(metric)
+		if limit <= 0 {
+			continue
+		}
+		var metDate *time.Time
+		switch metric {
+		case SLAMetricAccept:
+			metDate = c.AcceptedDate
+		case SLAMetricFirstResponse:
+			metDate = firstResponse
+		case SLAMetricResolve:
+			metDate = c.ClosedDate
+		}
+		dueDate := c.OpenedDate.Add(limit)
+		state := &CaseSLA{DueDate: &dueDate, MetDate: metDate}
+		switch {
+		case metDate != nil && !metDate.After(dueDate):
+			state.Status = SLAStatusMet
+		case metDate != nil:
+			state.Status = SLAStatusMissed
+		case now.After(dueDate):
+			state.Status = SLAStatusBreached
+		case now.Sub(*c.OpenedDate) >= limit*time.Duration(p.warningPercent())/100:
+			state.Status = SLAStatusWarning
+		default:
+			state.Status = SLAStatusOK
+		}
+		if prev, ok := previous[string(metric)]; ok && prev != nil {
+			state.EscalatedStatus = prev.EscalatedStatus
+			state.EscalatedDate = prev.EscalatedDate
+		}
+		result[string(metric)] = state
+	}
+	return result
+}
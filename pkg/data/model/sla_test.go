@@ -0,0 +1,85 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSLAPolicyEvaluate(t *testing.T) {
+	opened := time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		date := opened.Add(time.Duration(minutes) * time.Minute)
+		return &date
+	}
+	policy := &SLAPolicy{
+		Active:     true,
+		Default:    &SLATarget{AcceptTime: 10, FirstResponseTime: 20},
+		Priorities: []*SLATarget{{Priority: 2, AcceptTime: 5}},
+	}
+	escalated := at(9)
+
+	tests := []struct {
+		name          string
+		bizCase       *Case
+		firstResponse *time.Time
+		previous      map[string]*CaseSLA
+		now           *time.Time
+		want          map[SLAMetric]SLAStatus
+	}{
+		{"within the target", &Case{OpenedDate: &opened}, nil, nil, at(5),
+			map[SLAMetric]SLAStatus{SLAMetricAccept: SLAStatusOK, SLAMetricFirstResponse: SLAStatusOK}},
+		{"at the warning percent", &Case{OpenedDate: &opened}, nil, nil, at(8),
+			map[SLAMetric]SLAStatus{SLAMetricAccept: SLAStatusWarning, SLAMetricFirstResponse: SLAStatusOK}},
+		{"past the due date", &Case{OpenedDate: &opened}, nil, nil, at(11),
+			map[SLAMetric]SLAStatus{SLAMetricAccept: SLAStatusBreached, SLAMetricFirstResponse: SLAStatusOK}},
+		{"met in time and late", &Case{OpenedDate: &opened, AcceptedDate: at(10)}, at(25), nil, at(30),
+			map[SLAMetric]SLAStatus{SLAMetricAccept: SLAStatusMet, SLAMetricFirstResponse: SLAStatusMissed}},
+		{"target of the priority", &Case{OpenedDate: &opened, Priority: 2}, nil, nil, at(6),
+			map[SLAMetric]SLAStatus{SLAMetricAccept: SLAStatusBreached}},
+		{"not opened", &Case{}, nil, nil, at(60), map[SLAMetric]SLAStatus{}},
+		{"escalation kept", &Case{OpenedDate: &opened}, nil,
+			map[string]*CaseSLA{string(SLAMetricAccept): {EscalatedStatus: SLAStatusWarning, EscalatedDate: escalated}}, at(11),
+			map[SLAMetric]SLAStatus{SLAMetricAccept: SLAStatusBreached, SLAMetricFirstResponse: SLAStatusOK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.Evaluate(tt.bizCase, tt.firstResponse, tt.previous, *tt.now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d metrics, want %d", len(got), len(tt.want))
+			}
+			for metric, status := range tt.want {
+				state := got[string(metric)]
+				if state == nil || state.Status != status {
+					t.Errorf("%s = %+v, want %s", metric, state, status)
+				}
+			}
+			if prev := tt.previous[string(SLAMetricAccept)]; prev != nil {
+				if state := got[string(SLAMetricAccept)]; state.EscalatedStatus != prev.EscalatedStatus || state.EscalatedDate != prev.EscalatedDate {
+					t.Errorf("escalation = %s %v, want %s %v", state.EscalatedStatus, state.EscalatedDate, prev.EscalatedStatus, prev.EscalatedDate)
+				}
+			}
+		})
+	}
+}
+
+func TestCaseSLANeedsEscalation(t *testing.T) {
+	tests := []struct {
+		status, escalated SLAStatus
+		want              bool
+	}{
+		{SLAStatusOK, "", false},
+		{SLAStatusWarning, "", true},
+		{SLAStatusWarning, SLAStatusWarning, false},
+		{SLAStatusBreached, "", true},
+		{SLAStatusBreached, SLAStatusWarning, true},
+		{SLAStatusBreached, SLAStatusBreached, false},
+		{SLAStatusMet, "", false},
+		{SLAStatusMissed, "", false},
+	}
+	for _, tt := range tests {
+		state := &CaseSLA{Status: tt.status, EscalatedStatus: tt.escalated}
+		if got := state.NeedsEscalation(); got != tt.want {
+			t.Errorf("NeedsEscalation(%s, escalated %q) = %v, want %v", tt.status, tt.escalated, got, tt.want)
+		}
+	}
+}
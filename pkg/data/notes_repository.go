@@ -0,0 +1,48 @@
+package data
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+)
+
+type notesRepository struct {
+	db *db.Firestore
+}
+
+func NewNotesRepository(db *db.Firestore) definition.NotesRepository {
+	return &notesRepository{db}
+}
+
+func (r *notesRepository) FindCaseNotes(ctx context.Context, businessId string, caseId string) (_ []*model.Note, err error) {
+	ctx, done := instrument(ctx, "notes", "FindCaseNotes")
+	defer done(&err)
+	return findNotes(ctx, r.db.CaseNotes(businessId, caseId))
+}
+
+func (r *notesRepository) FindCustomerNotes(ctx context.Context, businessId string, customerId string) (_ []*model.Note, err error) {
+	ctx, done := instrument(ctx, "notes", "FindCustomerNotes")
+	defer done(&err)
+	return findNotes(ctx, r.db.BusinessCustomerNotes(businessId, customerId))
+}
+
+// findNotes returns the notes of the collection, oldest first. The notes which don't decode are
+// skipped.
+func findNotes(ctx context.Context, notesRef *firestore.CollectionRef) ([]*model.Note, error) {
+	snapshots, err := notesRef.OrderBy("createdDate", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	var notes []*model.Note
+	for _, snapshot := range snapshots {
+		var note *model.Note
+		if snapshot.DataTo(&note) != nil {
+			continue
+		}
+		notes = append(notes, note)
+	}
+	return notes, nil
+}
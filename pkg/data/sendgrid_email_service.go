@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"net/http"
+	"time"
 
 	"github.com/VinothKuppanna/pigeon-go/configs"
 	"github.com/VinothKuppanna/pigeon-go/internal/common"
+	"github.com/VinothKuppanna/pigeon-go/internal/metrics"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	def "github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 	"github.com/sendgrid/sendgrid-go"
@@ -24,6 +26,7 @@ const (
 	SubjectInvitation      = "Invitation to join business"
 	SubjectNewBusiness     = "New Business Account"
 	SubjectVerifyEmail     = "Email Verification"
+	SubjectSLAEscalation   = "Case #%d %s the %s SLA"
 
 	AssociateName     = "associateName"
 	CustomerName      = "customerName"
@@ -37,6 +40,10 @@ const (
 	BusinessName      = "businessName"
 	AuthLink          = "authLink"
 	Subject           = "Subject"
+	CaseNumber        = "caseNumber"
+	Metric            = "metric"
+	SLAStatus         = "status"
+	DueDate           = "dueDate"
 )
 
 var errorNoEmails = errors.New("no emails to send. aborting")
@@ -279,6 +286,45 @@ func (s *emailsService) SendBusinessAccountCreated(_ context.Context, req def.Se
 	return
 }
 
+func (s *emailsService) SendSLAEscalationAlert(_ context.Context, request def.SLAEscalationAlertRequest) (resp def.SendResponse) {
+	emails := common.DeDuplicateStrings(request.Emails)
+	var tos []*mail.Email
+	for _, email := range emails {
+		if email == "" {
+			continue
+		}
+		tos = append(tos, mail.NewEmail("", email))
+	}
+	if len(tos) == 0 {
+		resp.Error = errorNoEmails
+		return
+	}
+	data := map[string]interface{}{
+		BusinessName:  request.BusinessName,
+		CustomerName:  request.CustomerName,
+		AssociateName: request.AssociateName,
+		CaseNumber:    request.CaseNumber,
+		Metric:        request.Metric,
+		SLAStatus:     request.Status,
+		DueDate:       request.DueDate,
+		RequestLink:   request.RequestLink,
+		SupportEmail:  s.config.Smtp.SupportEmail,
+	}
+	p := s.makePersonalization(data, tos...)
+	subject := fmt.Sprintf(SubjectSLAEscalation, request.CaseNumber, slaStatusVerb(request.Status), request.Metric)
+	m := s.makeMail(subject, s.config.SendGrid.Templates.SLAEscalation, p)
+	err := s.send(m)
+	resp.Error = err
+	return
+}
+
+func slaStatusVerb(status string) string {
+	if status == string(model.SLAStatusBreached) {
+		return "breached"
+	}
+	return "is approaching"
+}
+
 func (s *emailsService) makePersonalization(data map[string]interface{}, tos ...*mail.Email) (p *mail.Personalization) {
 	p = mail.NewPersonalization()
 	p.AddTos(tos...)
@@ -305,11 +351,15 @@ func (s *emailsService) send(m *mail.SGMailV3) error {
 	request := sendgrid.GetRequest(sendGrid.APIKey, sendGrid.Endpoints.Send, sendGrid.Host)
 	request.Method = "POST"
 	request.Body = mail.GetRequestBody(m)
+	start := time.Now()
 	response, err := sendgrid.API(request)
+	metrics.ObserveProviderResult(metrics.ProviderSendGrid, "Send", start, err == nil && response.StatusCode < http.StatusBadRequest)
 	if err != nil {
 		return err
 	}
-	log.Println(response)
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("sendgrid: %d %s", response.StatusCode, response.Body)
+	}
 	return nil
 }
 
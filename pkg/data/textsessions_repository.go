@@ -6,24 +6,26 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+	"google.golang.org/api/iterator"
 )
 
 type textSessionsRepository struct {
-	firestoreClient *firestore.Client
+	db        *db.Firestore
+	publisher events.Publisher
 }
 
-func (r *textSessionsRepository) FindInnerTextSession(contactID string, secondContactID string) (*model.TextSession, error) {
-	panic("implement me")
+func NewTextSessionsRepo(db *db.Firestore, publisher events.Publisher) definition.TextSessionsRepository {
+	return &textSessionsRepository{db, publisher}
 }
 
-func NewTextSessionsRepo(firestoreClient *firestore.Client) definition.TextSessionsRepository {
-	return &textSessionsRepository{firestoreClient}
-}
-
-func (r *textSessionsRepository) Find(chatId string) (*model.TextSession, error) {
-	snapshot, err := r.firestoreClient.Collection("textSessions").Doc(chatId).Get(context.Background())
+func (r *textSessionsRepository) Find(chatId string) (_ *model.TextSession, err error) {
+	ctx, done := instrument(context.Background(), "text_sessions", "Find")
+	defer done(&err)
+	snapshot, err := r.db.Chats().Doc(chatId).Get(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -38,10 +40,12 @@ func (r *textSessionsRepository) Find(chatId string) (*model.TextSession, error)
 	return chat, nil
 }
 
-func (r *textSessionsRepository) Update(textSession *model.TextSession) error {
+func (r *textSessionsRepository) Update(textSession *model.TextSession) (err error) {
+	ctx, done := instrument(context.Background(), "text_sessions", "Update")
+	defer done(&err)
 	now := time.Now()
 	textSession.UpdatedDate = &now
-	_, err := r.firestoreClient.Collection("textSessions").Doc(textSession.Id).Set(context.Background(), textSession)
+	_, err = r.db.Chats().Doc(textSession.Id).Set(ctx, textSession)
 	if err != nil {
 		return err
 	}
@@ -50,92 +54,12 @@ func (r *textSessionsRepository) Update(textSession *model.TextSession) error {
 
 // todo: split to use cases
 func (r *textSessionsRepository) CreateActiveChatWithCase(customerContact *model.Customer, associateContact *model.Contact,
-	creator int, businessCase *model.Case) (*model.TextSession, error) {
-	isToPersonalContact := associateContact.Type == model.ContactTypePersonal
-	compoundId := model.CompoundID{customerContact.Id: associateContact.Id, associateContact.Id: customerContact.Id}
-
-	now := time.Now()
-	sessionData := &model.TextSession{
-		CompoundId: &compoundId,
-		Contact:    associateContact,
-		Business:   associateContact.Business,
-		Type:       model.SessionTypeActive,
-		From: &model.Person{
-			Id:          customerContact.Id,
-			Name:        customerContact.FullName,
-			Uid:         customerContact.Id,
-			Description: "Customer",
-			Position:    "Customer",
-			Type:        model.UserTypeCustomer,
-		},
-		To: &model.Person{
-			Id:          associateContact.Id,
-			Name:        associateContact.Name,
-			Description: associateContact.Position,
-			Position:    associateContact.Position,
-			Type:        model.UserTypeAssociate,
-		},
-		Members: &model.Members{
-			customerContact.Id: &model.Member{
-				Id:          customerContact.Id,
-				Name:        customerContact.FullName,
-				Uid:         customerContact.Id,
-				Type:        model.UserTypeCustomer,
-				Description: "Customer",
-				PhotoUrl:    customerContact.PhotoUrl,
-				Status:      customerContact.Status,
-			},
-		},
-		Customer: &model.CustomerContact{
-			Id:          customerContact.Id,
-			Uid:         customerContact.Id,
-			Name:        customerContact.FullName,
-			FullName:    customerContact.FullName,
-			Email:       customerContact.Email,
-			PhoneNumber: customerContact.PhoneNumber,
-			PhotoUrl:    customerContact.PhotoUrl,
-			Status:      customerContact.Status,
-			Permissions: &customerContact.Permissions,
-		},
-		Associate: &model.AssociateContact{
-			CustomerContact: model.CustomerContact{
-				Id:          associateContact.Id,
-				Name:        associateContact.Name,
-				FullName:    associateContact.FullName,
-				Email:       associateContact.Email,
-				PhoneNumber: associateContact.PhoneNumber,
-				PhotoUrl:    associateContact.PhotoUrl,
-				Status:      associateContact.Status(),
-			},
-			Position: associateContact.Position,
-		},
-		Creator:     creator, // todo: revise usage!
-		CreatedDate: &now,
-	}
-
-	if isToPersonalContact {
-		sessionData.Subtype = model.SessionSubtypeDirect
-		associate := associateContact.Associate
-		sessionData.To.Uid = associate.Id
-		sessionData.Associate.Uid = associate.Id
-		sessionData.Associate.FullName = associate.FullName
-	} else {
-		sessionData.Subtype = model.SessionSubtypeGroup
-	}
-
-	//todo: refactor with contact.associate
-	members := *sessionData.Members
-	if isToPersonalContact {
-		members[associateContact.Associate.Id] = associateContact.ToChatMemberLegacy()
-	} else if len(associateContact.Contacts) > 0 { //todo: refactor with contact.contacts[].associate
-		for _, contact := range associateContact.Contacts {
-			members[contact.Associate.Id] = contact.ToChatMemberLegacy()
-		}
-	}
+	creator int, businessCase *model.Case) (_ *model.TextSession, err error) {
+	ctx, done := instrument(context.Background(), "text_sessions", "CreateActiveChatWithCase")
+	defer done(&err)
+	sessionData := model.NewActiveTextSession(customerContact, associateContact, creator)
 
-	sessionData.MemberIDs = sessionData.Members.UIDs()
-
-	documentRef := r.firestoreClient.Collection("textSessions").NewDoc()
+	documentRef := r.db.Chats().NewDoc()
 	sessionData.Id = documentRef.ID
 
 	if businessCase != nil {
@@ -143,7 +67,7 @@ func (r *textSessionsRepository) CreateActiveChatWithCase(customerContact *model
 		sessionData.Case = businessCase
 	}
 
-	_, err := documentRef.Set(context.Background(), &sessionData)
+	_, err = documentRef.Set(ctx, &sessionData)
 	if err != nil {
 		return nil, err
 	}
@@ -154,9 +78,21 @@ func (r *textSessionsRepository) CreateActiveTextSession(customerContact *model.
 	return r.CreateActiveChatWithCase(customerContact, associateContact, creator, nil)
 }
 
-func (r *textSessionsRepository) FindActiveTextSession(customerId string, associateContactId string) (*model.TextSession, error) {
-	compoundId := model.CompoundID{customerId: associateContactId, associateContactId: customerId}
-	docIterator := r.firestoreClient.Collection("textSessions").Where("compoundId", "==", compoundId).Limit(1).Documents(context.Background())
+func (r *textSessionsRepository) FindActiveTextSession(customerId string, associateContactId string) (_ *model.TextSession, err error) {
+	ctx, done := instrument(context.Background(), "text_sessions", "FindActiveTextSession")
+	defer done(&err)
+	return r.findByCompoundId(ctx, model.CompoundID{customerId: associateContactId, associateContactId: customerId})
+}
+
+func (r *textSessionsRepository) FindInnerTextSession(contactID string, secondContactID string) (_ *model.TextSession, err error) {
+	ctx, done := instrument(context.Background(), "text_sessions", "FindInnerTextSession")
+	defer done(&err)
+	return r.findByCompoundId(ctx, model.CompoundID{contactID: secondContactID, secondContactID: contactID})
+}
+
+// findByCompoundId returns the chat between the two parties of the compound ID, or nil.
+func (r *textSessionsRepository) findByCompoundId(ctx context.Context, compoundId model.CompoundID) (*model.TextSession, error) {
+	docIterator := r.db.Chats().Where("compoundId", "==", compoundId).Limit(1).Documents(ctx)
 	snapshots, err := docIterator.GetAll()
 	if err != nil {
 		return nil, err
@@ -173,3 +109,103 @@ func (r *textSessionsRepository) FindActiveTextSession(customerId string, associ
 	}
 	return nil, nil
 }
+
+func (r *textSessionsRepository) Create(ctx context.Context, chat *model.TextSession) (_ *model.TextSession, err error) {
+	ctx, done := instrument(ctx, "text_sessions", "Create")
+	defer done(&err)
+	ref := r.db.Chats().NewDoc()
+	if _, err = ref.Set(ctx, chat); err != nil {
+		return nil, err
+	}
+	snapshot, err := ref.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var created *model.TextSession
+	if err = snapshot.DataTo(&created); err != nil {
+		return nil, err
+	}
+	created.Id = ref.ID
+	return created, nil
+}
+
+func (r *textSessionsRepository) Leave(ctx context.Context, chatId string, uid string, message *model.Message, posted definition.Posted) (err error) {
+	ctx, done := instrument(ctx, "text_sessions", "Leave")
+	defer done(&err)
+	ref := r.db.ChatMessages(chatId).NewDoc()
+	message.Id = ref.ID
+	batch := r.db.Batch().
+		Update(r.db.Chat(chatId), []firestore.Update{
+			{FieldPath: []string{"members", uid, "leaved"}, Value: true},
+			{FieldPath: []string{"members", uid, "left"}, Value: true},
+			{Path: "memberIDs", Value: firestore.ArrayRemove(uid)},
+		}).
+		Create(ref, &model.MessageLeaveChat{Message: *message})
+	return commit(ctx, r.publisher, batch, posted.Events(message)...)
+}
+
+func (r *textSessionsRepository) AddMembers(ctx context.Context, chatId string, members model.Members, title string) (_ *model.TextSession, err error) {
+	ctx, done := instrument(ctx, "text_sessions", "AddMembers")
+	defer done(&err)
+	chatRef := r.db.Chat(chatId)
+	var memberIDs []interface{}
+	for uid := range members {
+		memberIDs = append(memberIDs, uid)
+	}
+	updates := []firestore.Update{
+		{Path: "memberIDs", Value: firestore.ArrayUnion(memberIDs...)},
+		{Path: "compoundId", Value: firestore.Delete},
+		{Path: "subtype", Value: model.SessionSubtypeGroupExtended},
+	}
+	if len(title) > 0 {
+		updates = append(updates, firestore.Update{Path: "title", Value: title})
+	}
+	_, err = r.db.Batch().
+		Set(chatRef, map[string]interface{}{"members": members}, firestore.MergeAll).
+		Update(chatRef, updates).
+		Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := chatRef.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var chat *model.TextSession
+	if err = snapshot.DataTo(&chat); err != nil {
+		return nil, err
+	}
+	chat.Id = chatId
+	return chat, r.setMessagesMembers(ctx, chatId, chat.MemberIDs)
+}
+
+// setMessagesMembers sets the members of all the messages of the chat, in batches of the most
+// writes Firestore takes.
+func (r *textSessionsRepository) setMessagesMembers(ctx context.Context, chatId string, memberIDs []string) error {
+	documents := r.db.ChatMessages(chatId).Select().Documents(ctx)
+	defer documents.Stop()
+	updates := []firestore.Update{{Path: "memberIDs", Value: memberIDs}}
+	batch := r.db.Batch()
+	writes := 0
+	for {
+		snapshot, err := documents.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		batch.Update(snapshot.Ref, updates)
+		if writes++; writes == 500 {
+			if _, err = batch.Commit(ctx); err != nil {
+				return err
+			}
+			batch, writes = r.db.Batch(), 0
+		}
+	}
+	if writes == 0 {
+		return nil
+	}
+	_, err := batch.Commit(ctx)
+	return err
+}
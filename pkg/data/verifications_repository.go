@@ -0,0 +1,92 @@
+package data
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const CollectionVerifications = "verifications"
+
+type verificationsRepository struct {
+	firestoreClient *firestore.Client
+}
+
+func NewVerificationsRepository(firestoreClient *firestore.Client) definition.VerificationsRepository {
+	return &verificationsRepository{firestoreClient}
+}
+
+func (r *verificationsRepository) FindById(ctx context.Context, id string) (_ *model.Verification, err error) {
+	ctx, done := instrument(ctx, "verifications", "FindById")
+	defer done(&err)
+	snapshot, err := r.firestoreClient.Collection(CollectionVerifications).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toVerification(snapshot)
+}
+
+func (r *verificationsRepository) FindByEmail(ctx context.Context, email string) (_ *model.Verification, err error) {
+	ctx, done := instrument(ctx, "verifications", "FindByEmail")
+	defer done(&err)
+	return r.findFirst(ctx, "email", email)
+}
+
+func (r *verificationsRepository) FindByCompanyId(ctx context.Context, companyId string) (_ *model.Verification, err error) {
+	ctx, done := instrument(ctx, "verifications", "FindByCompanyId")
+	defer done(&err)
+	return r.findFirst(ctx, "companyId", companyId)
+}
+
+func (r *verificationsRepository) findFirst(ctx context.Context, path string, value string) (*model.Verification, error) {
+	snapshots, err := r.firestoreClient.Collection(CollectionVerifications).Where(path, "==", value).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no verification with %s %s", path, value)
+	}
+	return toVerification(snapshots[0])
+}
+
+func (r *verificationsRepository) Create(ctx context.Context, verification *model.Verification) (_ *model.Verification, err error) {
+	ctx, done := instrument(ctx, "verifications", "Create")
+	defer done(&err)
+	ref, _, err := r.firestoreClient.Collection(CollectionVerifications).Add(ctx, verification)
+	if err != nil {
+		return nil, err
+	}
+	verification.Id = ref.ID
+	return verification, nil
+}
+
+func (r *verificationsRepository) Verify(ctx context.Context, id string, revokeKey bool) (err error) {
+	ctx, done := instrument(ctx, "verifications", "Verify")
+	defer done(&err)
+	updates := []firestore.Update{{Path: "verified", Value: true}}
+	if revokeKey {
+		updates = append(updates, firestore.Update{Path: "skey", Value: firestore.Delete})
+	}
+	_, err = r.firestoreClient.Collection(CollectionVerifications).Doc(id).Update(ctx, updates)
+	return err
+}
+
+func (r *verificationsRepository) Delete(ctx context.Context, id string) (err error) {
+	ctx, done := instrument(ctx, "verifications", "Delete")
+	defer done(&err)
+	_, err = r.firestoreClient.Collection(CollectionVerifications).Doc(id).Delete(ctx)
+	return err
+}
+
+func toVerification(snapshot *firestore.DocumentSnapshot) (*model.Verification, error) {
+	var verification *model.Verification
+	if err := snapshot.DataTo(&verification); err != nil {
+		return nil, err
+	}
+	verification.Id = snapshot.Ref.ID
+	return verification, nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"firebase.google.com/go/v4/auth"
 	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	def "github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 )
@@ -19,6 +20,8 @@ type authService struct {
 }
 
 func (as *authService) ResetPasswordLink(ctx context.Context, request def.ResetPasswordRequest) (resp def.ResetPasswordResponse) {
+	ctx, span := tracing.Start(ctx, "AuthService.ResetPasswordLink")
+	defer func() { tracing.End(span, resp.Error) }()
 	var acs configs.ActionCodeSettings
 	if request.UserType == model.UserTypeAssociate {
 		acs = as.config.ActionCodeSettings
@@ -43,6 +46,8 @@ func (as *authService) ResetPasswordLink(ctx context.Context, request def.ResetP
 }
 
 func (as *authService) InviteLink(ctx context.Context, req def.InviteLinkRequest) (resp def.InviteLinkResponse) {
+	ctx, span := tracing.Start(ctx, "AuthService.InviteLink")
+	defer func() { tracing.End(span, resp.Error) }()
 	acs := as.config.ActionCodeSettings
 	actionCodeSettings := &auth.ActionCodeSettings{
 		URL:                fmt.Sprintf("%s?mode=%s&role=%d&bid=%s&bn=%s&uid=%s", acs.URL, req.AuthMode, req.Role, req.BusinessID, req.BusinessName, req.UserID),
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	def "github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/pkg/errors"
+)
+
+type claimsService struct {
+	db         *db.Firestore
+	authClient *auth.Client
+}
+
+func NewClaimsService(db *db.Firestore, authClient *auth.Client) def.ClaimsService {
+	return &claimsService{db, authClient}
+}
+
+func (s *claimsService) Claims(ctx context.Context, uid string) (_ *model.UserClaims, err error) {
+	ctx, span := tracing.Start(ctx, "ClaimsService.Claims")
+	defer func() { tracing.End(span, err) }()
+	snapshot, err := s.db.User(uid).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var associate *model.Associate
+	if err = snapshot.DataTo(&associate); err != nil {
+		return nil, err
+	}
+	return model.NewUserClaims(associate), nil
+}
+
+func (s *claimsService) Sync(ctx context.Context, uid string) (_ *model.UserClaims, err error) {
+	ctx, span := tracing.Start(ctx, "ClaimsService.Sync")
+	defer func() { tracing.End(span, err) }()
+	claims, err := s.Claims(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.authClient.GetUser(ctx, uid)
+	if err != nil {
+		return nil, errors.Wrap(err, "authClient.GetUser")
+	}
+	if err = s.authClient.SetCustomUserClaims(ctx, uid, claims.Map()); err != nil {
+		return nil, errors.Wrap(err, "authClient.SetCustomUserClaims")
+	}
+	if claims.Revokes(model.ClaimsFromToken(user.CustomClaims)) {
+		if err = s.authClient.RevokeRefreshTokens(ctx, uid); err != nil {
+			return nil, errors.Wrap(err, "authClient.RevokeRefreshTokens")
+		}
+	}
+	return claims, nil
+}
+
+func (s *claimsService) Clear(ctx context.Context, uid string) (err error) {
+	ctx, span := tracing.Start(ctx, "ClaimsService.Clear")
+	defer func() { tracing.End(span, err) }()
+	if err := s.authClient.SetCustomUserClaims(ctx, uid, nil); err != nil {
+		return errors.Wrap(err, "authClient.SetCustomUserClaims")
+	}
+	return errors.Wrap(s.authClient.RevokeRefreshTokens(ctx, uid), "authClient.RevokeRefreshTokens")
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 )
 
@@ -13,6 +14,8 @@ type customersService struct {
 }
 
 func (s *customersService) BlockCustomer(ctx context.Context, req definition.BlockCustomerRequest) definition.BlockCustomerResponse {
+	ctx, span := tracing.Start(ctx, "CustomersService.BlockCustomer")
+	defer span.End()
 	// todo: 1. block customer; businessCustomersRepository.updateBlocked()
 	_, err := s.db.Collection("businesses").Doc(req.BusinessId).Collection("businessCustomers").Doc(req.CustomerId).Update(ctx, []firestore.Update{
 		{
@@ -0,0 +1,34 @@
+package definition
+
+import (
+	"context"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+)
+
+type AppointmentsRepository interface {
+	FindById(ctx context.Context, businessId string, appointId string) (*model.Appointment, error)
+	// Save creates the appointment, and books its start date in the calendar of the associate.
+	// The events of booked are written with it.
+	Save(ctx context.Context, appointment *model.Appointment, booked Booked) (*model.Appointment, error)
+	// Update writes the appointment, and moves the booked date of a pending one to its start date.
+	Update(ctx context.Context, appointment *model.Appointment) error
+	// Cancel writes the canceled appointment, and frees its booked date.
+	Cancel(ctx context.Context, appointment *model.Appointment) error
+	DeleteById(ctx context.Context, businessId string, appointId string) error
+	// IsBooked reports whether the associate has an appointment starting at the date.
+	IsBooked(ctx context.Context, associateId string, date time.Time) (bool, error)
+}
+
+// Booked returns the events reporting the appointment, once its ID is set.
+type Booked func(appointment *model.Appointment) []events.Event
+
+// Events returns the events of the appointment, or none if b is nil.
+func (b Booked) Events(appointment *model.Appointment) []events.Event {
+	if b == nil {
+		return nil
+	}
+	return b(appointment)
+}
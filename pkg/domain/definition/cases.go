@@ -0,0 +1,52 @@
+package definition
+
+import (
+	"context"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrHandoffResolved is returned by the writes resolving a handoff which is no longer the pending
+// handoff of its case, because it was resolved, or replaced, meanwhile.
+var ErrHandoffResolved = status.Error(codes.FailedPrecondition, "handoff is no longer pending")
+
+type CasesRepository interface {
+	FindById(ctx context.Context, businessId string, caseId string) (*model.Case, error)
+	// Save writes the case, and its copies in the chat of the case, with the events reporting
+	// the change.
+	Save(ctx context.Context, businessId string, businessCase *model.Case, reported ...events.Event) error
+	// FindArchived returns the archived chats, with their closed cases, in the order of the IDs.
+	FindArchived(ctx context.Context, businessId string, ids []string) ([]*model.TextSession, error)
+	// LockHandoff records the handoff and attaches it to the case, unless another handoff of the
+	// case is pending. A pending handoff which has expired is closed on the way. It sets the ID
+	// of the handoff, and reports whether the case was locked.
+	LockHandoff(ctx context.Context, businessId string, caseId string, handoff *model.CaseHandoff) (bool, error)
+	// ResolveHandoff records the resolved handoff and detaches it from the case, in a transaction
+	// which fails with ErrHandoffResolved unless the handoff is still the pending one of the case.
+	ResolveHandoff(ctx context.Context, businessId string, caseId string, handoff *model.CaseHandoff) error
+	// ExpireHandoffs resolves the pending handoffs which expired before now as ResolveHandoff
+	// does, and returns their cases, each with the handoff it expired.
+	ExpireHandoffs(ctx context.Context, now time.Time) ([]*model.Case, error)
+	// Forward moves the case from its chat to the chat, which holds the forwarded case, and
+	// resolves the handoff locking the case, in a transaction which fails with ErrHandoffResolved
+	// unless the handoff is still the pending one of the case. The chat is saved as it is, and
+	// created if it has no ID yet. The forwarded chat loses its case and its last message. The
+	// events of forwarded are written with it.
+	Forward(ctx context.Context, businessId string, businessCase *model.Case, chat *model.TextSession, handoff *model.CaseHandoff,
+		forwarded Forwarded) error
+}
+
+// Forwarded returns the events reporting the forward of the case to the chat, once its ID is set.
+type Forwarded func(chat *model.TextSession) []events.Event
+
+// Events returns the events of the forward to the chat, or none if f is nil.
+func (f Forwarded) Events(chat *model.TextSession) []events.Event {
+	if f == nil {
+		return nil
+	}
+	return f(chat)
+}
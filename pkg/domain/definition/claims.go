@@ -0,0 +1,16 @@
+package definition
+
+import (
+	"context"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+)
+
+type ClaimsService interface {
+	// Claims reads the claims of the user from Firestore.
+	Claims(ctx context.Context, uid string) (*model.UserClaims, error)
+	// Sync writes the claims of the user to Firebase Auth. Disabled or downgraded users lose their refresh tokens.
+	Sync(ctx context.Context, uid string) (*model.UserClaims, error)
+	// Clear removes the claims and revokes the refresh tokens of a removed associate.
+	Clear(ctx context.Context, uid string) error
+}
@@ -1,5 +1,11 @@
 package definition
 
+import (
+	"context"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+)
+
 type CustomersService interface {
 	BlockCustomer(req BlockCustomerRequest) BlockCustomerResponse
 	UnblockCustomer(req BlockCustomerRequest) BlockCustomerResponse
@@ -15,3 +21,16 @@ type BlockCustomerResponse struct {
 	Result bool
 	Error  error
 }
+
+type CustomersRepository interface {
+	FindById(ctx context.Context, customerId string) (*model.Customer, error)
+	FindBusinessCustomer(ctx context.Context, businessId string, customerId string) (*model.Customer, error)
+	FindByEmail(ctx context.Context, email string) (*model.Customer, error)
+	// HasBlocked reports whether any of the users is in the block list of the user.
+	HasBlocked(ctx context.Context, userId string, userIDs []string) (bool, error)
+	// Block blocks the business customer for the associate, in the business, in their chats and
+	// in the block list of the associate.
+	Block(ctx context.Context, businessId string, customer *model.Customer, associateId string) error
+	// Unblock reverts Block.
+	Unblock(ctx context.Context, businessId string, customerId string, associateId string) error
+}
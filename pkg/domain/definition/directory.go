@@ -0,0 +1,17 @@
+package definition
+
+import (
+	"context"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+)
+
+type DirectoryRepository interface {
+	FindById(ctx context.Context, businessId string, contactId string) (*model.Contact, error)
+	// FindVisible returns the contacts visible to the customers, in the order of the directory.
+	FindVisible(ctx context.Context, businessId string) ([]*model.DirectoryContact, error)
+	// FindByAssociate returns the personal contact of the associate, or nil if there is none.
+	FindByAssociate(ctx context.Context, businessId string, uid string) (*model.Contact, error)
+	// FindByIds returns the contacts in the order of the IDs.
+	FindByIds(ctx context.Context, businessId string, ids []string) ([]*model.DirectoryContact, error)
+}
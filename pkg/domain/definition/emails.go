@@ -12,6 +12,7 @@ type EmailsService interface {
 	SendUnreadChatAlert(context.Context, UnreadChatAlertRequest) SendResponse
 	SendBusinessAccountCreated(context.Context, SendBusinessAccountCreatedRequest) SendResponse
 	SendBusinessEmailVerification(context.Context, SendBusinessEmailVerificationRequest) SendResponse
+	SendSLAEscalationAlert(context.Context, SLAEscalationAlertRequest) SendResponse
 }
 
 type SendInviteRequest struct {
@@ -74,6 +75,18 @@ type SendBusinessEmailVerificationRequest struct {
 	Link  string
 }
 
+type SLAEscalationAlertRequest struct {
+	Emails        []string
+	BusinessName  string
+	CustomerName  string
+	AssociateName string
+	CaseNumber    int64
+	Metric        string
+	Status        string
+	DueDate       string
+	RequestLink   string
+}
+
 type SendResponse struct {
 	Error error
 }
@@ -0,0 +1,37 @@
+package definition
+
+import (
+	"context"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	"github.com/VinothKuppanna/pigeon-go/pkg/events"
+)
+
+type MessagesRepository interface {
+	// Save creates the message, with the events of posted if it is set.
+	Save(ctx context.Context, chatId string, message *model.Message, posted Posted) (*model.Message, error)
+	// SaveAll creates the messages, with the events of posted if it is set.
+	SaveAll(ctx context.Context, chatId string, messages []*model.Message, posted Posted) ([]*model.Message, error)
+	Delete(ctx context.Context, chatId string, messageId string) (string, error)
+	// FindBetween returns the messages of the chat created after the first date and until the
+	// second one, oldest first. A nil date leaves that end open. The legacy messages which don't
+	// decode are skipped.
+	FindBetween(ctx context.Context, chatId string, after *time.Time, until *time.Time) ([]*model.Message, error)
+	// Move moves the messages of the chat created since the date, oldest first and at most 100,
+	// to the other chat, except those of the excluded types. The moved messages get the members
+	// of the other chat and are dated now. A nil date moves the messages from the first one.
+	Move(ctx context.Context, fromChatId string, toChatId string, since *time.Time, memberIDs []string, exclude []model.MessageType) error
+}
+
+// Posted returns the events reporting the messages, once their IDs are set. The repositories
+// write them with the messages, so they are published if and only if the messages are saved.
+type Posted func(messages ...*model.Message) []events.Event
+
+// Events returns the events of the messages, or none if p is nil.
+func (p Posted) Events(messages ...*model.Message) []events.Event {
+	if p == nil {
+		return nil
+	}
+	return p(messages...)
+}
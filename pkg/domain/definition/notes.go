@@ -0,0 +1,14 @@
+package definition
+
+import (
+	"context"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+)
+
+type NotesRepository interface {
+	// FindCaseNotes returns the notes of the case, oldest first.
+	FindCaseNotes(ctx context.Context, businessId string, caseId string) ([]*model.Note, error)
+	// FindCustomerNotes returns the notes of the business customer, oldest first.
+	FindCustomerNotes(ctx context.Context, businessId string, customerId string) ([]*model.Note, error)
+}
@@ -0,0 +1,15 @@
+package definition
+
+import "context"
+
+type PushService interface {
+	Send(context.Context, PushRequest) SendResponse
+}
+
+type PushRequest struct {
+	UIDs     []string
+	Title    string
+	Body     string
+	Category string
+	Data     map[string]string
+}
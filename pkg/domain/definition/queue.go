@@ -0,0 +1,38 @@
+package definition
+
+import (
+	"context"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+)
+
+type QueueService interface {
+	Queue(context.Context, QueueRequest) QueueResponse
+	Position(context.Context, QueuePositionRequest) QueuePositionResponse
+}
+
+type QueueRequest struct {
+	BusinessID string
+	ContactID  string // optional, the directory contact of a group or an associate
+}
+
+type QueueResponse struct {
+	Items            []*model.QueueItem
+	AvgAcceptTime    time.Duration
+	OnlineAssociates int
+	Error            error
+}
+
+type QueuePositionRequest struct {
+	BusinessID string
+	CaseID     string
+	CustomerID string
+}
+
+type QueuePositionResponse struct {
+	Position      int
+	QueueLength   int
+	EstimatedWait time.Duration
+	Error         error
+}
@@ -0,0 +1,24 @@
+package definition
+
+import (
+	"context"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+)
+
+type SLAService interface {
+	EvaluateAll(context.Context) error
+	EvaluateBusiness(context.Context, string) error
+	CaseSLA(context.Context, CaseSLARequest) CaseSLAResponse
+}
+
+type CaseSLARequest struct {
+	BusinessID string
+	CaseID     string
+}
+
+type CaseSLAResponse struct {
+	Case  *model.Case
+	SLA   map[string]*model.CaseSLA
+	Error error
+}
@@ -1,6 +1,10 @@
 package definition
 
-import "github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+import (
+	"context"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+)
 
 type TextSessionsRepository interface {
 	Find(chatId string) (*model.TextSession, error)
@@ -9,4 +13,12 @@ type TextSessionsRepository interface {
 	FindActiveTextSession(customerId string, associateContactId string) (*model.TextSession, error)
 	FindInnerTextSession(string, string) (*model.TextSession, error)
 	Update(textSession *model.TextSession) error
+	// Create saves the new chat with a new ID, and returns it as it was stored.
+	Create(ctx context.Context, chat *model.TextSession) (*model.TextSession, error)
+	// Leave marks the member as left and removes them from the members of the chat, and posts
+	// the message about it.
+	Leave(ctx context.Context, chatId string, uid string, message *model.Message, posted Posted) error
+	// AddMembers adds the members to the chat, which becomes an extended group with the title if
+	// it is set, and gives the messages of the chat to its new members. It returns the chat.
+	AddMembers(ctx context.Context, chatId string, members model.Members, title string) (*model.TextSession, error)
 }
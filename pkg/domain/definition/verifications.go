@@ -0,0 +1,17 @@
+package definition
+
+import (
+	"context"
+
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+)
+
+type VerificationsRepository interface {
+	FindById(ctx context.Context, id string) (*model.Verification, error)
+	FindByEmail(ctx context.Context, email string) (*model.Verification, error)
+	FindByCompanyId(ctx context.Context, companyId string) (*model.Verification, error)
+	Create(ctx context.Context, verification *model.Verification) (*model.Verification, error)
+	// Verify marks the verification as verified, and removes its key if revokeKey is set.
+	Verify(ctx context.Context, id string, revokeKey bool) error
+	Delete(ctx context.Context, id string) error
+}
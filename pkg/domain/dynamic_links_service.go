@@ -8,8 +8,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/metrics"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
 	def "github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 )
 
@@ -17,7 +20,9 @@ type dynamicLinksService struct {
 	config *configs.Config
 }
 
-func (d *dynamicLinksService) GenerateBusinessLink(ctx context.Context, request def.BusinessLinkRequest) def.LinkResponse {
+func (d *dynamicLinksService) GenerateBusinessLink(ctx context.Context, request def.BusinessLinkRequest) (resp def.LinkResponse) {
+	ctx, span := tracing.Start(ctx, "DynamicLinksService.GenerateBusinessLink")
+	defer func() { tracing.End(span, resp.Error) }()
 	suffix := map[string]interface{}{"option": "SHORT"}
 	iosInfo := map[string]interface{}{
 		"iosBundleId":   d.config.CustomerActionCodeSettings.IOSBundleID,
@@ -29,7 +34,9 @@ func (d *dynamicLinksService) GenerateBusinessLink(ctx context.Context, request
 	return d.obtainDynamicLink(ctx, uriPrefix, link, androidInfo, iosInfo, suffix)
 }
 
-func (d *dynamicLinksService) GenerateChatLink(ctx context.Context, request def.ChatLinkRequest) def.LinkResponse {
+func (d *dynamicLinksService) GenerateChatLink(ctx context.Context, request def.ChatLinkRequest) (resp def.LinkResponse) {
+	ctx, span := tracing.Start(ctx, "DynamicLinksService.GenerateChatLink")
+	defer func() { tracing.End(span, resp.Error) }()
 	suffix := map[string]interface{}{"option": "SHORT"}
 	iosInfo := map[string]interface{}{
 		"iosBundleId":   d.config.ActionCodeSettings.IOSBundleID,
@@ -70,7 +77,9 @@ func (d *dynamicLinksService) obtainDynamicLink(ctx context.Context, uriPrefix s
 	query.Set("key", d.config.WebApiKey)
 	req.URL.RawQuery = query.Encode()
 
+	start := time.Now()
 	response, err := http.DefaultClient.Do(req)
+	metrics.ObserveProviderResult(metrics.ProviderDynamicLinks, "ShortLinks", start, err == nil && response.StatusCode < http.StatusBadRequest)
 	if err != nil {
 		return def.LinkResponse{
 			Error: errors.New(fmt.Sprintf("the request failed: %v", err)),
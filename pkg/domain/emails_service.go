@@ -6,6 +6,7 @@ import (
 	"html/template"
 
 	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	def "github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 	"gopkg.in/gomail.v2"
@@ -20,6 +21,7 @@ const (
 	SubjectInvitation      = "Invitation to join business"
 	SubjectNewBusiness     = "New Business Account"
 	SubjectVerifyEmail     = "Email Verification"
+	SubjectSLAEscalation   = "A case needs your attention"
 
 	AssociateName     = "AssociateName"
 	CustomerName      = "CustomerName"
@@ -37,6 +39,10 @@ const (
 	To                = "To"
 	Subject           = "Subject"
 	TextHTML          = "text/html"
+	CaseNumber        = "CaseNumber"
+	Metric            = "Metric"
+	SLAStatus         = "Status"
+	DueDate           = "DueDate"
 )
 
 type emailsService struct {
@@ -49,7 +55,9 @@ func (es *emailsService) SendUnreadChatAlert(ctx context.Context, request def.Un
 	panic("implement me")
 }
 
-func (es *emailsService) SendBusinessEmailVerification(_ context.Context, req def.SendBusinessEmailVerificationRequest) (resp def.SendResponse) {
+func (es *emailsService) SendBusinessEmailVerification(ctx context.Context, req def.SendBusinessEmailVerificationRequest) (resp def.SendResponse) {
+	_, span := tracing.Start(ctx, "EmailsService.SendBusinessEmailVerification")
+	defer func() { tracing.End(span, resp.Error) }()
 	tmpl, err := es.executeTemplate(es.emailTemplates.VerifyEmailTmpl, map[string]string{
 		Email:        req.Email,
 		Link:         req.Link,
@@ -65,7 +73,9 @@ func (es *emailsService) SendBusinessEmailVerification(_ context.Context, req de
 	return
 }
 
-func (es *emailsService) SendIdleChatAlert(_ context.Context, request def.IdleChatAlertRequest) (resp def.SendResponse) {
+func (es *emailsService) SendIdleChatAlert(ctx context.Context, request def.IdleChatAlertRequest) (resp def.SendResponse) {
+	_, span := tracing.Start(ctx, "EmailsService.SendIdleChatAlert")
+	defer func() { tracing.End(span, resp.Error) }()
 	tmpl, err := es.executeTemplate(es.emailTemplates.IdleChatAlertTmpl, map[string]string{
 		SenderName:   request.SenderName,
 		ChatLink:     request.ChatLink,
@@ -81,7 +91,9 @@ func (es *emailsService) SendIdleChatAlert(_ context.Context, request def.IdleCh
 	return
 }
 
-func (es *emailsService) SendNewRequestAlert(_ context.Context, requests ...def.NewRequestAlertRequest) (resp def.SendResponse) {
+func (es *emailsService) SendNewRequestAlert(ctx context.Context, requests ...def.NewRequestAlertRequest) (resp def.SendResponse) {
+	_, span := tracing.Start(ctx, "EmailsService.SendNewRequestAlert")
+	defer func() { tracing.End(span, resp.Error) }()
 	var messages []*gomail.Message
 	for _, request := range requests {
 		body, err := es.executeTemplate(es.emailTemplates.NewRequestAlertTmpl, map[string]string{
@@ -102,7 +114,9 @@ func (es *emailsService) SendNewRequestAlert(_ context.Context, requests ...def.
 	return
 }
 
-func (es *emailsService) SendIdleRequestAlert(_ context.Context, request def.IdleRequestAlertRequest) (resp def.SendResponse) {
+func (es *emailsService) SendIdleRequestAlert(ctx context.Context, request def.IdleRequestAlertRequest) (resp def.SendResponse) {
+	_, span := tracing.Start(ctx, "EmailsService.SendIdleRequestAlert")
+	defer func() { tracing.End(span, resp.Error) }()
 	tmpl, err := es.executeTemplate(es.emailTemplates.IdleRequestAlertTmpl, map[string]string{
 		BusinessName: request.BusinessName,
 		CustomerName: request.CustomerName,
@@ -119,7 +133,9 @@ func (es *emailsService) SendIdleRequestAlert(_ context.Context, request def.Idl
 	return
 }
 
-func (es *emailsService) SendAcceptedRequestAlert(_ context.Context, requests ...def.AcceptedRequestAlertRequest) (resp def.SendResponse) {
+func (es *emailsService) SendAcceptedRequestAlert(ctx context.Context, requests ...def.AcceptedRequestAlertRequest) (resp def.SendResponse) {
+	_, span := tracing.Start(ctx, "EmailsService.SendAcceptedRequestAlert")
+	defer func() { tracing.End(span, resp.Error) }()
 	var messages []*gomail.Message
 	for _, request := range requests {
 		body, err := es.executeTemplate(es.emailTemplates.AcceptedRequestAlertTmpl, map[string]string{
@@ -141,7 +157,9 @@ func (es *emailsService) SendAcceptedRequestAlert(_ context.Context, requests ..
 	return
 }
 
-func (es *emailsService) SendPasswordReset(_ context.Context, req def.SendPasswordResetRequest) (resp def.SendResponse) {
+func (es *emailsService) SendPasswordReset(ctx context.Context, req def.SendPasswordResetRequest) (resp def.SendResponse) {
+	_, span := tracing.Start(ctx, "EmailsService.SendPasswordReset")
+	defer func() { tracing.End(span, resp.Error) }()
 	var htmlTmpl *template.Template
 	if req.UserType == model.UserTypeAssociate {
 		htmlTmpl = es.emailTemplates.ResetPasswordAssociateTmpl
@@ -163,7 +181,9 @@ func (es *emailsService) SendPasswordReset(_ context.Context, req def.SendPasswo
 	return
 }
 
-func (es *emailsService) SendInvite(_ context.Context, req def.SendInviteRequest) (resp def.SendResponse) {
+func (es *emailsService) SendInvite(ctx context.Context, req def.SendInviteRequest) (resp def.SendResponse) {
+	_, span := tracing.Start(ctx, "EmailsService.SendInvite")
+	defer func() { tracing.End(span, resp.Error) }()
 	tmpl, err := es.executeTemplate(es.emailTemplates.InviteUserTmpl, map[string]string{
 		Email:        req.Email,
 		BusinessName: req.BusinessName,
@@ -180,7 +200,9 @@ func (es *emailsService) SendInvite(_ context.Context, req def.SendInviteRequest
 	return
 }
 
-func (es *emailsService) SendBusinessAccountCreated(_ context.Context, req def.SendBusinessAccountCreatedRequest) (resp def.SendResponse) {
+func (es *emailsService) SendBusinessAccountCreated(ctx context.Context, req def.SendBusinessAccountCreatedRequest) (resp def.SendResponse) {
+	_, span := tracing.Start(ctx, "EmailsService.SendBusinessAccountCreated")
+	defer func() { tracing.End(span, resp.Error) }()
 	tmpl, err := es.executeTemplate(es.emailTemplates.NewBusinessAccountTmpl, map[string]string{
 		BusinessName: req.BusinessName,
 		Email:        req.OwnerEmail,
@@ -196,6 +218,30 @@ func (es *emailsService) SendBusinessAccountCreated(_ context.Context, req def.S
 	return
 }
 
+func (es *emailsService) SendSLAEscalationAlert(ctx context.Context, request def.SLAEscalationAlertRequest) (resp def.SendResponse) {
+	_, span := tracing.Start(ctx, "EmailsService.SendSLAEscalationAlert")
+	defer func() { tracing.End(span, resp.Error) }()
+	tmpl, err := es.executeTemplate(es.emailTemplates.SLAEscalationTmpl, map[string]interface{}{
+		BusinessName:  request.BusinessName,
+		CustomerName:  request.CustomerName,
+		AssociateName: request.AssociateName,
+		CaseNumber:    request.CaseNumber,
+		Metric:        request.Metric,
+		SLAStatus:     request.Status,
+		DueDate:       request.DueDate,
+		RequestLink:   request.RequestLink,
+		SupportEmail:  es.config.Smtp.SupportEmail,
+	})
+	if err != nil {
+		resp.Error = err
+		return
+	}
+	message := es.newMessage(request.Emails, SubjectSLAEscalation, tmpl)
+	err = es.send(message)
+	resp.Error = err
+	return
+}
+
 func (es *emailsService) executeTemplate(tmpl *template.Template, data interface{}) (string, error) {
 	buffer := new(bytes.Buffer)
 	if err := tmpl.Execute(buffer, data); err != nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"firebase.google.com/go/v4/auth"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
 )
 
@@ -14,6 +15,8 @@ type invitesService struct {
 }
 
 func (is *invitesService) Revoke(ctx context.Context, request *definition.RevokeInviteRequest) *definition.RevokeInviteResponse {
+	ctx, span := tracing.Start(ctx, "InvitesService.Revoke")
+	defer span.End()
 	invite, err := is.invitesRepository.Delete(ctx, request.BusinessId, request.InviteId)
 	if err != nil {
 		return &definition.RevokeInviteResponse{Result: false, Error: err.Error()}
@@ -0,0 +1,208 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	def "github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// acceptStatsDays is the number of the latest daily response time stats used for the wait estimate.
+const acceptStatsDays = 7
+
+// defaultAcceptTime is used when the business has no response time stats yet.
+const defaultAcceptTime = 5 * time.Minute
+
+var (
+	ErrCaseNotQueued = errors.New("case is not waiting in the queue")
+	ErrNotCaseOwner  = errors.New("case belongs to another customer")
+)
+
+type queueService struct {
+	db *db.Firestore
+}
+
+func NewQueueService(db *db.Firestore) def.QueueService {
+	return &queueService{db}
+}
+
+func (s *queueService) Queue(ctx context.Context, req def.QueueRequest) (resp def.QueueResponse) {
+	ctx, span := tracing.Start(ctx, "QueueService.Queue")
+	defer func() { tracing.End(span, resp.Error) }()
+	cases, err := s.requestedCases(ctx, req.BusinessID, req.ContactID)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+	avgAcceptTime := s.avgAcceptTime(ctx, req.BusinessID)
+	online, err := s.onlineAssociates(ctx, req.BusinessID, req.ContactID)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+	now := time.Now()
+	items := make([]*model.QueueItem, 0, len(cases))
+	for i, c := range cases {
+		item := &model.QueueItem{
+			Position:      i + 1,
+			CaseId:        c.Id,
+			CaseNumber:    c.Number,
+			Priority:      c.Priority,
+			TextSessionId: c.TextSessionId,
+			Customer:      c.Customer,
+			Contact:       c.Associate,
+			OpenedDate:    c.OpenedDate,
+			EstimatedWait: int64(model.EstimateWait(i+1, avgAcceptTime, online) / time.Second),
+		}
+		if c.OpenedDate != nil {
+			item.WaitingTime = int64(now.Sub(*c.OpenedDate) / time.Second)
+		}
+		items = append(items, item)
+	}
+	resp.Items = items
+	resp.AvgAcceptTime = avgAcceptTime
+	resp.OnlineAssociates = online
+	return
+}
+
+func (s *queueService) Position(ctx context.Context, req def.QueuePositionRequest) (resp def.QueuePositionResponse) {
+	ctx, span := tracing.Start(ctx, "QueueService.Position")
+	defer func() { tracing.End(span, resp.Error) }()
+	snapshot, err := s.db.BusinessCase(req.BusinessID, req.CaseID).Get(ctx)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+	var bizCase *model.Case
+	if err = snapshot.DataTo(&bizCase); err != nil {
+		resp.Error = err
+		return
+	}
+	if bizCase.Customer == nil || bizCase.Customer.Id != req.CustomerID {
+		resp.Error = ErrNotCaseOwner
+		return
+	}
+	if bizCase.Closed || bizCase.Status != model.CaseRequested {
+		resp.Error = ErrCaseNotQueued
+		return
+	}
+	contactID := ""
+	if bizCase.Associate != nil {
+		contactID = bizCase.Associate.Id
+	}
+	cases, err := s.requestedCases(ctx, req.BusinessID, contactID)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+	online, err := s.onlineAssociates(ctx, req.BusinessID, contactID)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+	resp.QueueLength = len(cases)
+	for i, c := range cases {
+		if c.Id == snapshot.Ref.ID {
+			resp.Position = i + 1
+			break
+		}
+	}
+	resp.EstimatedWait = model.EstimateWait(resp.Position, s.avgAcceptTime(ctx, req.BusinessID), online)
+	return
+}
+
+// requestedCases returns the open requests of the business, or of the directory contact, in the queue order.
+func (s *queueService) requestedCases(ctx context.Context, businessID string, contactID string) ([]*model.Case, error) {
+	query := s.db.BusinessCases(businessID).
+		Where("closed", "==", false).
+		Where("status", "==", model.CaseRequested)
+	if len(contactID) > 0 {
+		query = query.Where("associate.id", "==", contactID)
+	}
+	documents := query.Documents(ctx)
+	defer documents.Stop()
+	cases := make([]*model.Case, 0)
+	for {
+		snapshot, err := documents.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var bizCase *model.Case
+		if err = snapshot.DataTo(&bizCase); err != nil {
+			return nil, err
+		}
+		bizCase.Id = snapshot.Ref.ID
+		cases = append(cases, bizCase)
+	}
+	model.SortQueue(cases)
+	return cases, nil
+}
+
+// avgAcceptTime averages the daily acceptTime (milliseconds) of the latest response time stats.
+func (s *queueService) avgAcceptTime(ctx context.Context, businessID string) time.Duration {
+	documents, err := s.db.Business(businessID).Collection(db.ResponseTimeStats).
+		OrderBy("createdDate", firestore.Desc).
+		Limit(acceptStatsDays).
+		Select("acceptTime").
+		Documents(ctx).GetAll()
+	if err != nil {
+		return defaultAcceptTime
+	}
+	var total, count int64
+	for _, doc := range documents {
+		if acceptTime, ok := doc.Data()["acceptTime"].(int64); ok && acceptTime > 0 {
+			total += acceptTime
+			count++
+		}
+	}
+	if count == 0 {
+		return defaultAcceptTime
+	}
+	return time.Duration(total/count) * time.Millisecond
+}
+
+// onlineAssociates counts the online associates of the business, or of the directory contact.
+func (s *queueService) onlineAssociates(ctx context.Context, businessID string, contactID string) (int, error) {
+	var uids map[string]bool
+	if len(contactID) > 0 {
+		snapshot, err := s.db.BusinessDirectoryContact(businessID, contactID).Get(ctx)
+		if err != nil {
+			return 0, err
+		}
+		var contact *model.Contact
+		if err = snapshot.DataTo(&contact); err != nil {
+			return 0, err
+		}
+		uids = make(map[string]bool)
+		for _, uid := range contact.AssociateIDs {
+			uids[uid] = true
+		}
+		if contact.Associate != nil {
+			uids[contact.Associate.Id] = true
+		}
+	}
+	documents, err := s.db.Collection(db.Users).
+		Where("business.id", "==", businessID).
+		Where("status.online", "==", true).
+		Select().
+		Documents(ctx).GetAll()
+	if err != nil {
+		return 0, err
+	}
+	online := 0
+	for _, doc := range documents {
+		if uids == nil || uids[doc.Ref.ID] {
+			online++
+		}
+	}
+	return online, nil
+}
@@ -0,0 +1,245 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/logger"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	def "github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+const slaEscalationCategory = "SLA_ESCALATION_CATEGORY"
+
+type slaService struct {
+	db            *db.Firestore
+	config        *configs.Config
+	emailsService def.EmailsService
+	pushService   def.PushService
+}
+
+func NewSLAService(db *db.Firestore, config *configs.Config, emailsService def.EmailsService, pushService def.PushService) def.SLAService {
+	return &slaService{db, config, emailsService, pushService}
+}
+
+func (s *slaService) EvaluateAll(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "SLAService.EvaluateAll")
+	defer func() { tracing.End(span, err) }()
+	documents := s.db.Collection(db.Settings).Where("sla.active", "==", true).Documents(ctx)
+	defer documents.Stop()
+	for {
+		snapshot, err := documents.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err = s.EvaluateBusiness(ctx, snapshot.Ref.ID); err != nil {
+			logger.FromContext(ctx).Error(ctx, "SLA evaluation of the business failed", "slaBusinessId", snapshot.Ref.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+func (s *slaService) EvaluateBusiness(ctx context.Context, businessID string) (err error) {
+	ctx, span := tracing.Start(ctx, "SLAService.EvaluateBusiness")
+	defer func() { tracing.End(span, err) }()
+	policy, err := s.policy(ctx, businessID)
+	if err != nil {
+		return err
+	}
+	if policy == nil || !policy.Active {
+		return nil
+	}
+	businessName := ""
+	if snapshot, err := s.db.Business(businessID).Get(ctx); err == nil {
+		if name, ok := snapshot.Data()["name"].(string); ok {
+			businessName = name
+		}
+	}
+	documents := s.db.BusinessCases(businessID).Where("closed", "==", false).Documents(ctx)
+	defer documents.Stop()
+	now := time.Now()
+	for {
+		snapshot, err := documents.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var bizCase *model.Case
+		if err = snapshot.DataTo(&bizCase); err != nil {
+			logger.FromContext(ctx).Error(ctx, "failed to read the case", "error", err)
+			continue
+		}
+		bizCase.Id = snapshot.Ref.ID
+		states, escalated, err := s.claimEscalations(ctx, policy, snapshot.Ref, s.firstResponse(ctx, businessID, bizCase), now)
+		if err != nil {
+			logger.FromContext(ctx).Error(ctx, "failed to update the SLA state", "caseId", bizCase.Id, "error", err)
+			continue
+		}
+		for _, metric := range escalated {
+			s.escalate(ctx, policy, businessName, bizCase, metric, states[metric])
+		}
+	}
+	return nil
+}
+
+// claimEscalations evaluates the case again in a transaction and stores its SLA state, with the
+// metrics that reached a level not escalated yet marked escalated. It returns the state and those
+// metrics, so a level is escalated once even if several instances of the API evaluate the case.
+func (s *slaService) claimEscalations(ctx context.Context, policy *model.SLAPolicy, ref *firestore.DocumentRef,
+	firstResponse *time.Time, now time.Time) (states map[string]*model.CaseSLA, escalated []string, err error) {
+	err = s.db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		states, escalated = nil, nil
+		snapshot, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		var bizCase *model.Case
+		if err = snapshot.DataTo(&bizCase); err != nil {
+			return err
+		}
+		states = policy.Evaluate(bizCase, firstResponse, bizCase.SLA, now)
+		for metric, state := range states {
+			if !state.NeedsEscalation() {
+				continue
+			}
+			escalated = append(escalated, metric)
+			state.EscalatedStatus = state.Status
+			escalatedDate := now
+			state.EscalatedDate = &escalatedDate
+		}
+		return tx.Update(ref, []firestore.Update{{Path: "sla", Value: states}})
+	})
+	return
+}
+
+func (s *slaService) CaseSLA(ctx context.Context, req def.CaseSLARequest) (resp def.CaseSLAResponse) {
+	ctx, span := tracing.Start(ctx, "SLAService.CaseSLA")
+	defer func() { tracing.End(span, resp.Error) }()
+	snapshot, err := s.db.BusinessCase(req.BusinessID, req.CaseID).Get(ctx)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+	var bizCase *model.Case
+	if err = snapshot.DataTo(&bizCase); err != nil {
+		resp.Error = err
+		return
+	}
+	bizCase.Id = snapshot.Ref.ID
+	resp.Case = bizCase
+	policy, err := s.policy(ctx, req.BusinessID)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+	if policy == nil || !policy.Active {
+		resp.SLA = map[string]*model.CaseSLA{}
+		return
+	}
+	resp.SLA = policy.Evaluate(bizCase, s.firstResponse(ctx, req.BusinessID, bizCase), bizCase.SLA, time.Now())
+	return
+}
+
+func (s *slaService) policy(ctx context.Context, businessID string) (*model.SLAPolicy, error) {
+	snapshot, err := s.db.BusinessSettings(businessID).Get(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get business settings")
+	}
+	var settings *model.Settings
+	if err = snapshot.DataTo(&settings); err != nil {
+		return nil, err
+	}
+	return settings.SLA, nil
+}
+
+// firstResponse mirrors the response time stats query: the first message of the case associate after acceptance.
+func (s *slaService) firstResponse(ctx context.Context, businessID string, bizCase *model.Case) *time.Time {
+	if bizCase.AcceptedDate == nil || bizCase.Associate == nil || len(bizCase.Associate.Id) == 0 {
+		return nil
+	}
+	contactSnapshot, err := s.db.BusinessDirectoryContact(businessID, bizCase.Associate.Id).Get(ctx)
+	if err != nil || !contactSnapshot.Exists() {
+		return nil
+	}
+	var contact *model.Contact
+	if err = contactSnapshot.DataTo(&contact); err != nil || contact.Associate == nil {
+		return nil
+	}
+	messages, err := s.db.CollectionGroup(db.Messages).
+		Where("textSessionId", "==", bizCase.TextSessionId).
+		Where("sender.uid", "==", contact.Associate.Id).
+		Where("createdDate", ">=", bizCase.AcceptedDate).
+		OrderBy("createdDate", firestore.Asc).
+		Select("createdDate").
+		Limit(1).Documents(ctx).GetAll()
+	if err != nil || len(messages) == 0 {
+		return nil
+	}
+	createdDate, ok := messages[0].Data()["createdDate"].(time.Time)
+	if !ok {
+		return nil
+	}
+	return &createdDate
+}
+
+func (s *slaService) escalate(ctx context.Context, policy *model.SLAPolicy, businessName string,
+	bizCase *model.Case, metric string, state *model.CaseSLA) {
+	customerName := ""
+	if bizCase.Customer != nil {
+		customerName = bizCase.Customer.Name
+	}
+	businessID := ""
+	if bizCase.Business != nil {
+		businessID = bizCase.Business.Id
+	}
+	if len(businessName) == 0 && bizCase.Business != nil {
+		businessName = bizCase.Business.Name
+	}
+	dueDate := state.DueDate.Format(time.RFC1123)
+	if policy.EscalateByEmail {
+		resp := s.emailsService.SendSLAEscalationAlert(ctx, def.SLAEscalationAlertRequest{
+			Emails:        policy.SupervisorEmails(),
+			BusinessName:  businessName,
+			CustomerName:  customerName,
+			AssociateName: bizCase.AssociateName(),
+			CaseNumber:    bizCase.Number,
+			Metric:        metric,
+			Status:        string(state.Status),
+			DueDate:       dueDate,
+			RequestLink:   fmt.Sprintf("%s/businesses/%s/cases/%s", s.config.ActionCodeSettings.URL, businessID, bizCase.Id),
+		})
+		if !resp.OK() {
+			logger.FromContext(ctx).Error(ctx, "failed to send the SLA escalation email", "caseId", bizCase.Id, "error", resp.Error)
+		}
+	}
+	if policy.EscalateByPush {
+		resp := s.pushService.Send(ctx, def.PushRequest{
+			UIDs:     policy.SupervisorUIDs(),
+			Title:    fmt.Sprintf("Case #%d: %s SLA %s", bizCase.Number, metric, state.Status),
+			Body:     fmt.Sprintf("%s is waiting. Due %s", customerName, dueDate),
+			Category: slaEscalationCategory,
+			Data: map[string]string{
+				"businessId":    businessID,
+				"caseId":        bizCase.Id,
+				"textSessionId": bizCase.TextSessionId,
+				"metric":        metric,
+				"status":        string(state.Status),
+			},
+		})
+		if !resp.OK() {
+			logger.FromContext(ctx).Error(ctx, "failed to send the SLA escalation push", "caseId", bizCase.Id, "error", resp.Error)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/memfirestore"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
+	def "github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
+)
+
+// alerts records the SLA escalation alerts sent.
+type alerts struct {
+	def.EmailsService
+	mu   sync.Mutex
+	sent []def.SLAEscalationAlertRequest
+}
+
+func (a *alerts) SendSLAEscalationAlert(_ context.Context, req def.SLAEscalationAlertRequest) def.SendResponse {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sent = append(a.sent, req)
+	return def.SendResponse{}
+}
+
+// TestEvaluateAllEscalatesOnce evaluates the SLA of a breached case from two instances, and
+// checks the breach is escalated once.
+func TestEvaluateAllEscalatesOnce(t *testing.T) {
+	ctx := context.Background()
+	client, cleanup, err := memfirestore.NewClient(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	firestoreDb := &db.Firestore{Client: client}
+	if _, err = firestoreDb.BusinessSettings("b1").Set(ctx, &model.Settings{SLA: &model.SLAPolicy{
+		Active:          true,
+		Default:         &model.SLATarget{AcceptTime: 10},
+		Emails:          []string{"lead@example.com"},
+		EscalateByEmail: true,
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	opened := time.Now().Add(-time.Hour)
+	if _, err = firestoreDb.BusinessCase("b1", "case1").Set(ctx, &model.Case{
+		Business:   &model.BusinessItem{Id: "b1"},
+		Status:     model.CaseRequested,
+		OpenedDate: &opened,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := &alerts{}
+	config := &configs.Config{}
+	first := NewSLAService(firestoreDb, config, sent, nil)
+	second := NewSLAService(firestoreDb, config, sent, nil)
+	var wg sync.WaitGroup
+	for _, service := range []def.SLAService{first, second, first} {
+		wg.Add(1)
+		go func(service def.SLAService) {
+			defer wg.Done()
+			if err := service.EvaluateAll(ctx); err != nil {
+				t.Error(err)
+			}
+		}(service)
+	}
+	wg.Wait()
+
+	if len(sent.sent) != 1 {
+		t.Fatalf("alerts = %d, want the breach escalated once", len(sent.sent))
+	}
+	response := first.CaseSLA(ctx, def.CaseSLARequest{BusinessID: "b1", CaseID: "case1"})
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+	if state := response.SLA[string(model.SLAMetricAccept)]; state == nil || state.EscalatedStatus != model.SLAStatusBreached {
+		t.Errorf("accept SLA = %+v, want the breach marked escalated", state)
+	}
+}
@@ -1,11 +1,15 @@
 package domain
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/metrics"
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data"
 	"github.com/VinothKuppanna/pigeon-go/pkg/data/model"
 	"github.com/VinothKuppanna/pigeon-go/pkg/domain/definition"
@@ -16,7 +20,7 @@ type VideoCallService struct {
 	openTok                  *opentok.OpenTok
 	firestoreClient          *firestore.Client
 	textSessionRepository    definition.TextSessionsRepository
-	messagesRepository       data.MessagesRepository
+	messagesRepository       definition.MessagesRepository
 	chatVideoCallsRepository data.VideoCallsRepository
 }
 
@@ -24,7 +28,7 @@ func NewVideoCallService(
 	openTok *opentok.OpenTok,
 	firestoreClient *firestore.Client,
 	textSessionRepository definition.TextSessionsRepository,
-	messagesRepository data.MessagesRepository,
+	messagesRepository definition.MessagesRepository,
 	chatVideoCallsRepository data.VideoCallsRepository) *VideoCallService {
 	return &VideoCallService{
 		openTok,
@@ -36,12 +40,16 @@ func NewVideoCallService(
 }
 
 func (s *VideoCallService) InitVideoCall(uid string, textSessionId string) (apiKey string, sessionId string, token string, err error) {
+	_, span := tracing.Start(context.Background(), "VideoCallService.InitVideoCall")
+	defer func() { tracing.End(span, err) }()
 	textSession, err := s.textSessionRepository.Find(textSessionId)
 	if err != nil {
 		return "", "", "", err
 	}
 	if len(textSession.VideoSessionId) == 0 {
+		start := time.Now()
 		session, err := s.openTok.CreateSession(nil)
+		metrics.ObserveProvider(metrics.ProviderOpenTok, "CreateSession", start, err)
 		if err != nil {
 			return "", "", "", err
 		}
@@ -69,7 +77,9 @@ func (s *VideoCallService) InitVideoCall(uid string, textSessionId string) (apiK
 
 	sessionId = textSession.VideoSessionId
 	dataString := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s&%s&%s", uid, textSessionId, textSession.VideoCall.Id)))
+	start := time.Now()
 	token, err = s.openTok.GenerateToken(sessionId, map[string]interface{}{"data": dataString})
+	metrics.ObserveProvider(metrics.ProviderOpenTok, "GenerateToken", start, err)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -77,6 +87,8 @@ func (s *VideoCallService) InitVideoCall(uid string, textSessionId string) (apiK
 }
 
 func (s *VideoCallService) JoinVideoCall(uid string, textSessionId string, videoCallId string, videoSessionId string) (apiKey string, sessionId string, token string, err error) {
+	_, span := tracing.Start(context.Background(), "VideoCallService.JoinVideoCall")
+	defer func() { tracing.End(span, err) }()
 	sessionId = videoSessionId
 	if len(sessionId) == 0 {
 		textSession, err := s.textSessionRepository.Find(textSessionId)
@@ -92,7 +104,9 @@ func (s *VideoCallService) JoinVideoCall(uid string, textSessionId string, video
 	}
 
 	dataString := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s&%s&%s", uid, textSessionId, videoCallId)))
+	start := time.Now()
 	token, err = s.openTok.GenerateToken(sessionId, map[string]interface{}{"data": dataString})
+	metrics.ObserveProvider(metrics.ProviderOpenTok, "GenerateToken", start, err)
 	if err != nil {
 		return "", "", "", err
 	}
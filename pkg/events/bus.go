@@ -0,0 +1,182 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
+)
+
+// A Handler handles the events it subscribed to. It must be idempotent: an event may be
+// delivered again, e.g. to another worker, or after a restart, and the handler should check
+// record.ID against what it already did.
+type Handler func(ctx context.Context, record Record) error
+
+// consumedWindow is the number of the last envelopes consumed whose IDs are kept to drop the
+// duplicates. The outbox publishes an entry again only if the relay stops between publishing and
+// deleting it, so the duplicates follow the first delivery closely. The window is kept in memory
+// by each process, so it only spares the handlers most of the duplicates.
+const consumedWindow = 10000
+
+// A Bus is the registry of the subscribers. As a Publisher it delivers the events in process,
+// within the request emitting them, so the slow subscribers are better behind NSQ.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler // by event name, "" for all the events
+	errors   *log.Logger
+	consumed *window
+}
+
+func NewBus(errors *log.Logger) *Bus {
+	return &Bus{handlers: map[string][]Handler{}, errors: errors, consumed: newWindow(consumedWindow)}
+}
+
+// Subscribe subscribes the handler to the events of the names, or to all the events if there
+// are no names.
+func (b *Bus) Subscribe(handler Handler, names ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	for _, name := range names {
+		b.handlers[name] = append(b.handlers[name], handler)
+	}
+}
+
+// Subscribed returns the names of the events with subscribers, sorted.
+func (b *Bus) Subscribed() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.handlers[""]) > 0 {
+		names := Names()
+		sort.Strings(names)
+		return names
+	}
+	var names []string
+	for name := range b.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Dispatch calls the subscribers of the event. All of them are called, and the first error
+// is returned.
+func (b *Bus) Dispatch(ctx context.Context, record Record) error {
+	name := record.Event.EventName()
+	b.mu.RLock()
+	handlers := append(append([]Handler(nil), b.handlers[name]...), b.handlers[""]...)
+	b.mu.RUnlock()
+
+	var firstErr error
+	for _, handler := range handlers {
+		if err := handler(ctx, record); err != nil {
+			b.errors.Printf("failed to handle event %s, id=%s. error: %v\n", name, record.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Publish dispatches the events in process.
+func (b *Bus) Publish(ctx context.Context, events ...Event) error {
+	var firstErr error
+	for _, event := range events {
+		if err := b.Dispatch(ctx, newRecord(event)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// ErrMalformed is returned by Consume for a body that is not the envelope of a known event.
+var ErrMalformed = errors.New("malformed event")
+
+// Consume dispatches the event of an envelope consumed from NSQ. An envelope with the ID of one
+// being or already dispatched by this process is dropped. A failed dispatch is forgotten, for NSQ
+// to redeliver it.
+func (b *Bus) Consume(ctx context.Context, body []byte) error {
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	record, err := envelope.Record()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if len(record.ID) > 0 && !b.consumed.add(record.ID) {
+		return nil
+	}
+	if err = b.Dispatch(ctx, record); err != nil {
+		b.consumed.remove(record.ID)
+	}
+	return err
+}
+
+// A window is the set of the last IDs added, up to its size.
+type window struct {
+	mu    sync.Mutex
+	slots map[string]int // the slot of each ID in the ring
+	ring  []string       // the IDs in the order they were added
+	next  int
+}
+
+func newWindow(size int) *window {
+	return &window{slots: make(map[string]int, size), ring: make([]string, size)}
+}
+
+// add adds the ID, dropping the oldest one if the window is full. It reports whether the ID
+// was not in the window.
+func (w *window) add(id string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.slots[id]; ok {
+		return false
+	}
+	if oldest := w.ring[w.next]; len(oldest) > 0 {
+		delete(w.slots, oldest)
+	}
+	w.slots[id] = w.next
+	w.ring[w.next] = id
+	w.next = (w.next + 1) % len(w.ring)
+	return true
+}
+
+func (w *window) remove(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if slot, ok := w.slots[id]; ok {
+		w.ring[slot] = ""
+		delete(w.slots, id)
+	}
+}
+
+// Unwrap returns ctx with the trace context of the envelope, to continue the trace of the
+// request emitting the event. The body is returned as it is.
+func Unwrap(ctx context.Context, body []byte) (context.Context, []byte) {
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil || len(envelope.TraceContext) == 0 {
+		return ctx, body
+	}
+	return tracing.Extract(ctx, envelope.TraceContext), body
+}
+
+// Log returns a handler logging the events, e.g. for analytics.
+func Log(logger *log.Logger) Handler {
+	return func(ctx context.Context, record Record) error {
+		payload, err := json.Marshal(record.Event)
+		if err != nil {
+			return err
+		}
+		logger.Printf("event %s id=%s %s\n", record.Event.EventName(), record.ID, payload)
+		return nil
+	}
+}
@@ -0,0 +1,174 @@
+// Package events defines the domain events emitted by the handlers of the API, and delivers
+// them to the subscribers, in process or through NSQ. Alerts, webhooks and analytics subscribe
+// to the events instead of being wired into the handlers.
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/internal/tracing"
+)
+
+// An Event is a fact of the domain, named in the past tense.
+type Event interface {
+	EventName() string
+}
+
+// The names of the events.
+const (
+	NameCaseRequested      = "case_requested"
+	NameCaseAccepted       = "case_accepted"
+	NameCaseForwarded      = "case_forwarded"
+	NameAppointmentCreated = "appointment_created"
+	NameMessagePosted      = "message_posted"
+	NameAssociateInvited   = "associate_invited"
+)
+
+// types makes the event of each name, to decode the payloads.
+var types = map[string]func() Event{
+	NameCaseRequested:      func() Event { return &CaseRequested{} },
+	NameCaseAccepted:       func() Event { return &CaseAccepted{} },
+	NameCaseForwarded:      func() Event { return &CaseForwarded{} },
+	NameAppointmentCreated: func() Event { return &AppointmentCreated{} },
+	NameMessagePosted:      func() Event { return &MessagePosted{} },
+	NameAssociateInvited:   func() Event { return &AssociateInvited{} },
+}
+
+// CaseRequested is emitted when a case waits for an associate to accept it again.
+type CaseRequested struct {
+	BusinessID    string `json:"businessId"`
+	CaseID        string `json:"caseId"`
+	CustomerID    string `json:"customerId,omitempty"`
+	TextSessionID string `json:"textSessionId,omitempty"`
+}
+
+func (*CaseRequested) EventName() string { return NameCaseRequested }
+
+// CaseAccepted is emitted when an associate accepts a case.
+type CaseAccepted struct {
+	BusinessID    string `json:"businessId"`
+	CaseID        string `json:"caseId"`
+	CustomerID    string `json:"customerId,omitempty"`
+	AssociateID   string `json:"associateId,omitempty"`
+	TextSessionID string `json:"textSessionId,omitempty"`
+	AcceptedBy    string `json:"acceptedBy"` // uid
+}
+
+func (*CaseAccepted) EventName() string { return NameCaseAccepted }
+
+// CaseForwarded is emitted when a case is moved to the chat of another contact.
+type CaseForwarded struct {
+	BusinessID    string `json:"businessId"`
+	CaseID        string `json:"caseId"`
+	CustomerID    string `json:"customerId,omitempty"`
+	FromContactID string `json:"fromContactId,omitempty"`
+	ToContactID   string `json:"toContactId"`
+	TextSessionID string `json:"textSessionId"` // of the chat the case moved to
+	ForwardedBy   string `json:"forwardedBy"`   // uid
+}
+
+func (*CaseForwarded) EventName() string { return NameCaseForwarded }
+
+// AppointmentCreated is emitted when an appointment is booked.
+type AppointmentCreated struct {
+	BusinessID    string    `json:"businessId"`
+	AppointmentID string    `json:"appointmentId"`
+	CustomerID    string    `json:"customerId"`
+	ContactID     string    `json:"contactId"` // of the associate
+	StartDate     time.Time `json:"startDate"`
+	EndDate       time.Time `json:"endDate"`
+	CreatedBy     string    `json:"createdBy"` // uid
+}
+
+func (*AppointmentCreated) EventName() string { return NameAppointmentCreated }
+
+// MessagePosted is emitted when the API posts a message to a chat.
+type MessagePosted struct {
+	TextSessionID string `json:"textSessionId"`
+	MessageID     string `json:"messageId,omitempty"`
+	SenderUID     string `json:"senderUid"`
+	Type          int    `json:"type"` // model.MessageType
+}
+
+func (*MessagePosted) EventName() string { return NameMessagePosted }
+
+// AssociateInvited is emitted when an associate is invited to a business.
+type AssociateInvited struct {
+	BusinessID string `json:"businessId"`
+	InviteID   string `json:"inviteId"`
+	Email      string `json:"email"`
+	Role       int64  `json:"role"`
+	Mode       string `json:"mode,omitempty"`
+}
+
+func (*AssociateInvited) EventName() string { return NameAssociateInvited }
+
+// A Publisher delivers the events to the subscribers.
+type Publisher interface {
+	Publish(ctx context.Context, events ...Event) error
+}
+
+// A Record is an event as delivered to the subscribers. The ID lets them drop the events
+// delivered twice.
+type Record struct {
+	ID         string
+	OccurredAt time.Time
+	Event      Event
+}
+
+// An Envelope is the message of an event published to NSQ.
+type Envelope struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	OccurredAt   time.Time         `json:"occurredAt"`
+	TraceContext map[string]string `json:"traceContext,omitempty"`
+	Payload      json.RawMessage   `json:"payload"`
+}
+
+func newRecord(event Event) Record {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return Record{ID: hex.EncodeToString(id), OccurredAt: time.Now().UTC(), Event: event}
+}
+
+// NewEnvelope returns the envelope of the record with the trace context of ctx.
+func NewEnvelope(ctx context.Context, record Record) (*Envelope, error) {
+	payload, err := json.Marshal(record.Event)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{
+		ID:           record.ID,
+		Name:         record.Event.EventName(),
+		OccurredAt:   record.OccurredAt,
+		TraceContext: tracing.Inject(ctx),
+		Payload:      payload,
+	}, nil
+}
+
+// Record decodes the event of the envelope.
+func (e *Envelope) Record() (Record, error) {
+	newEvent, ok := types[e.Name]
+	if !ok {
+		return Record{}, fmt.Errorf("unknown event %q", e.Name)
+	}
+	event := newEvent()
+	if err := json.Unmarshal(e.Payload, event); err != nil {
+		return Record{}, fmt.Errorf("event %s: %w", e.Name, err)
+	}
+	return Record{ID: e.ID, OccurredAt: e.OccurredAt, Event: event}, nil
+}
+
+// Names are the names of all the events.
+func Names() []string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	return names
+}
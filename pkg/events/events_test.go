@@ -0,0 +1,173 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/VinothKuppanna/pigeon-go/configs"
+	"github.com/VinothKuppanna/pigeon-go/internal/memfirestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/outbox"
+	"github.com/VinothKuppanna/pigeon-go/internal/publishing"
+	"github.com/VinothKuppanna/pigeon-go/pkg/data/db"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus(log.New(io.Discard, "", 0))
+	var all, cases []string
+	bus.Subscribe(func(ctx context.Context, record Record) error {
+		all = append(all, record.Event.EventName())
+		return nil
+	})
+	bus.Subscribe(func(ctx context.Context, record Record) error {
+		cases = append(cases, record.Event.EventName())
+		return errors.New("unavailable")
+	}, NameCaseAccepted, NameCaseForwarded)
+
+	err := bus.Publish(context.Background(), &CaseAccepted{CaseID: "c1"}, &MessagePosted{TextSessionID: "t1"})
+	if err == nil || err.Error() != "unavailable" {
+		t.Errorf("Publish = %v, want the error of the subscriber", err)
+	}
+	if want := []string{NameCaseAccepted, NameMessagePosted}; !reflect.DeepEqual(all, want) {
+		t.Errorf("all the events: %v, want %v", all, want)
+	}
+	if want := []string{NameCaseAccepted}; !reflect.DeepEqual(cases, want) {
+		t.Errorf("case events: %v, want %v", cases, want)
+	}
+	if got := bus.Subscribed(); len(got) != len(types) {
+		t.Errorf("Subscribed = %v, want all the events", got)
+	}
+}
+
+func TestBusConsumeDropsDuplicates(t *testing.T) {
+	bus := NewBus(log.New(io.Discard, "", 0))
+	var consumed []string
+	fail := true
+	bus.Subscribe(func(ctx context.Context, record Record) error {
+		consumed = append(consumed, record.ID)
+		if fail {
+			fail = false
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	body := func(id string) []byte {
+		envelope, err := NewEnvelope(context.Background(), Record{ID: id, Event: &CaseAccepted{CaseID: "c1"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(envelope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	if err := bus.Consume(context.Background(), body("e1")); err == nil {
+		t.Fatal("Consume = nil, want the error of the subscriber")
+	}
+	for _, id := range []string{"e1", "e1", "e2", "e1"} {
+		if err := bus.Consume(context.Background(), body(id)); err != nil {
+			t.Fatalf("Consume(%s) = %v", id, err)
+		}
+	}
+	if want := []string{"e1", "e1", "e2"}; !reflect.DeepEqual(consumed, want) {
+		t.Errorf("consumed %v, want the failed envelope again and the duplicates dropped: %v", consumed, want)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	w := newWindow(2)
+	for _, id := range []string{"a", "b", "c"} {
+		if !w.add(id) {
+			t.Errorf("add(%s) = false, want it added", id)
+		}
+	}
+	if w.add("b") || w.add("c") {
+		t.Error("add of an ID in the window = true, want false")
+	}
+	if !w.add("a") {
+		t.Error("add of an ID out of the window = false, want true")
+	}
+	w.remove("a")
+	if !w.add("a") {
+		t.Error("add of a removed ID = false, want true")
+	}
+}
+
+func TestOutboxPublisher(t *testing.T) {
+	ctx := context.Background()
+	client, cleanup, err := memfirestore.NewClient(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	store := &db.Firestore{Client: client}
+	publisher := NewOutboxPublisher(outbox.New(store))
+
+	created := &AppointmentCreated{BusinessID: "b1", AppointmentID: "a1", CustomerID: "c1", CreatedBy: "u1"}
+	if err = publisher.Publish(ctx, created); err != nil {
+		t.Fatal(err)
+	}
+	batch := store.Batch()
+	invited := &AssociateInvited{BusinessID: "b1", InviteID: "i1", Email: "ada@example.com"}
+	pending, err := InBatch(ctx, publisher, batch, invited)
+	if err != nil || len(pending) > 0 {
+		t.Fatalf("InBatch = %v, %v, want the events written in the batch", pending, err)
+	}
+	if _, err = batch.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	discard := log.New(io.Discard, "", 0)
+	relay := outbox.NewRelay(store, publishing.NewSink(&buf), configs.Outbox{Interval: time.Second, BatchSize: 10, LeaseTTL: time.Minute}, discard, discard)
+	if _, err = relay.Relay(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	bus := NewBus(discard)
+	var topics []string
+	var consumed []Event
+	bus.Subscribe(func(ctx context.Context, record Record) error {
+		if len(record.ID) == 0 {
+			t.Errorf("no ID: %+v", record)
+		}
+		consumed = append(consumed, record.Event)
+		return nil
+	})
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var message struct {
+			Topic string          `json:"topic"`
+			Body  json.RawMessage `json:"body"`
+		}
+		if err = json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			t.Fatal(err)
+		}
+		topics = append(topics, message.Topic)
+		if err = bus.Consume(ctx, message.Body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"events.appointment_created", "events.associate_invited"}; !reflect.DeepEqual(topics, want) {
+		t.Errorf("topics %v, want %v", topics, want)
+	}
+	if want := []Event{created, invited}; !reflect.DeepEqual(consumed, want) {
+		t.Errorf("consumed %v, want %v", consumed, want)
+	}
+
+	if pending, _ = InBatch(ctx, bus, batch, invited); len(pending) != 1 {
+		t.Errorf("InBatch of the bus = %v, want the event left to publish", pending)
+	}
+	if err = bus.Consume(ctx, []byte(`{"name":"unknown"}`)); !errors.Is(err, ErrMalformed) {
+		t.Errorf("unknown event: got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"cloud.google.com/go/firestore"
+	"github.com/VinothKuppanna/pigeon-go/internal/outbox"
+)
+
+// Topic is the NSQ topic of the events of the name.
+func Topic(name string) string {
+	return "events." + name
+}
+
+// A BatchPublisher also writes the events in a Firestore batch.
+type BatchPublisher interface {
+	PublishInBatch(ctx context.Context, batch *firestore.WriteBatch, events ...Event) error
+}
+
+// InBatch writes the events in the batch of the change they report if the publisher can, so they
+// are published if and only if the batch is committed. It returns the events left to publish once
+// the batch is committed.
+func InBatch(ctx context.Context, publisher Publisher, batch *firestore.WriteBatch, events ...Event) ([]Event, error) {
+	if p, ok := publisher.(BatchPublisher); ok {
+		return nil, p.PublishInBatch(ctx, batch, events...)
+	}
+	return events, nil
+}
+
+// A TransactionPublisher also writes the events in a Firestore transaction.
+type TransactionPublisher interface {
+	PublishInTransaction(ctx context.Context, tx *firestore.Transaction, events ...Event) error
+}
+
+// InTransaction writes the events in the transaction of the change they report if the publisher
+// can, as InBatch does for a batch. It returns the events left to publish once the transaction is
+// committed.
+func InTransaction(ctx context.Context, publisher Publisher, tx *firestore.Transaction, events ...Event) ([]Event, error) {
+	if p, ok := publisher.(TransactionPublisher); ok {
+		return nil, p.PublishInTransaction(ctx, tx, events...)
+	}
+	return events, nil
+}
+
+// OutboxPublisher writes the events as envelopes to the outbox, from which the relay publishes
+// them to their topics for the subscribers of pigeon-worker. The ID of the envelope is the ID of
+// the entry.
+type OutboxPublisher struct {
+	outbox *outbox.Outbox
+}
+
+func NewOutboxPublisher(outbox *outbox.Outbox) *OutboxPublisher {
+	return &OutboxPublisher{outbox}
+}
+
+// Publish writes each event to the outbox on its own.
+func (p *OutboxPublisher) Publish(ctx context.Context, events ...Event) error {
+	var firstErr error
+	for _, event := range events {
+		envelope, body, err := marshalEnvelope(ctx, event)
+		if err == nil {
+			err = p.outbox.Add(ctx, envelope.ID, Topic(envelope.Name), body)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (p *OutboxPublisher) PublishInBatch(ctx context.Context, batch *firestore.WriteBatch, events ...Event) error {
+	for _, event := range events {
+		envelope, body, err := marshalEnvelope(ctx, event)
+		if err != nil {
+			return err
+		}
+		p.outbox.AddToBatch(batch, envelope.ID, Topic(envelope.Name), body)
+	}
+	return nil
+}
+
+func (p *OutboxPublisher) PublishInTransaction(ctx context.Context, tx *firestore.Transaction, events ...Event) error {
+	for _, event := range events {
+		envelope, body, err := marshalEnvelope(ctx, event)
+		if err != nil {
+			return err
+		}
+		if err = p.outbox.AddToTransaction(tx, envelope.ID, Topic(envelope.Name), body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func marshalEnvelope(ctx context.Context, event Event) (*Envelope, []byte, error) {
+	envelope, err := NewEnvelope(ctx, newRecord(event))
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := json.Marshal(envelope)
+	return envelope, body, err
+}